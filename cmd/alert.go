@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	"github.com/isovalent/corgi/pkg/alert"
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/report"
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+type typeAlertParams struct {
+	Repository           string
+	Branch               string
+	WindowStr            string
+	Window               time.Duration
+	MaxFailures          int
+	PassRateWindowStr    string
+	PassRateWindow       time.Duration
+	RequiredWorkflows    []string
+	MinPassRate          float64
+	TestCountBaselineStr string
+	TestCountBaseline    time.Duration
+	MaxTestCountDrop     float64
+	MinBaselineRuns      int
+	ResultMismatches     bool
+}
+
+var (
+	alertParams = &typeAlertParams{}
+	alertCmd    = &cobra.Command{
+		Use:   "alert",
+		Short: "Page when failures spike or the pass rate of required workflows drops",
+		Long: "Check the workflow runs on a branch for spikes in failed tests and for required workflows whose " +
+			"pass rate dropped below a threshold, and trigger or resolve alerts accordingly. Meant to be run " +
+			"periodically. Alerts are sent to PagerDuty if PAGERDUTY_ROUTING_KEY is set, to Opsgenie if " +
+			"OPSGENIE_API_KEY is set, and firing alerts are posted to the chat webhooks given by " +
+			"SLACK_WEBHOOK_URL, TEAMS_WEBHOOK_URL and DISCORD_WEBHOOK_URL. If none is set, alerts are only logged. " +
+			"Alerts also fire when a workflow or one of its suites reported far fewer tests than usual, as tests " +
+			"which silently stopped running otherwise look like tests which all pass. Such drops are also " +
+			"written as test_count_drop documents. With --result-mismatches, alerts fire for workflows with runs " +
+			"whose conclusion disagrees with their JUnit results, as these don't propagate test results.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			window, err := util.ParseDuration(alertParams.WindowStr)
+			if err != nil {
+				return fmt.Errorf("unable to parse window: %w", err)
+			}
+			alertParams.Window = window
+
+			passRateWindow, err := util.ParseDuration(alertParams.PassRateWindowStr)
+			if err != nil {
+				return fmt.Errorf("unable to parse pass rate window: %w", err)
+			}
+			alertParams.PassRateWindow = passRateWindow
+
+			testCountBaseline, err := util.ParseDuration(alertParams.TestCountBaselineStr)
+			if err != nil {
+				return fmt.Errorf("unable to parse test count baseline: %w", err)
+			}
+			alertParams.TestCountBaseline = testCountBaseline
+
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			logger := log.NewLogger(rootParams.Verbose)
+
+			notifiers, err := alertNotifiers()
+			if err != nil {
+				logger.Error("Unable to configure notifiers", "err", err)
+				os.Exit(1)
+			}
+
+			opensearchCfg, err := opensearch.NewClientConfig()
+			if err != nil {
+				logger.Error("Unable to load OpenSearch configuration", "err", err)
+				os.Exit(1)
+			}
+
+			opsClient, err := opensearchgo.NewClient(opensearchCfg)
+			if err != nil {
+				logger.Error("Unable to create opensearch client", "err", err)
+				os.Exit(1)
+			}
+
+			index := readIndex(types.TypeNameWorkflowRun, types.TypeNameTestcase)
+			now := time.Now()
+
+			failures, err := report.LoadBranchHealth(
+				ctx, opsClient, index, now.Add(-alertParams.Window), now, alertParams.Repository, alertParams.Branch,
+			)
+			if err != nil {
+				logger.Error("Unable to load failed tests", "err", err)
+				os.Exit(1)
+			}
+
+			passRates, err := report.LoadBranchHealth(
+				ctx, opsClient, index, now.Add(-alertParams.PassRateWindow), now, alertParams.Repository, alertParams.Branch,
+			)
+			if err != nil {
+				logger.Error("Unable to load pass rates", "err", err)
+				os.Exit(1)
+			}
+
+			thresholds := alert.Thresholds{
+				Repository:        alertParams.Repository,
+				Branch:            alertParams.Branch,
+				MaxFailures:       alertParams.MaxFailures,
+				RequiredWorkflows: alertParams.RequiredWorkflows,
+				MinPassRate:       alertParams.MinPassRate,
+				ResultMismatches:  alertParams.ResultMismatches,
+			}
+			alerts := alert.Evaluate(thresholds, failures, passRates)
+
+			if alertParams.MaxTestCountDrop > 0 {
+				counts, err := report.LoadTestCounts(
+					ctx, opsClient, readIndex(types.TypeNameWorkflowRun, types.TypeNameTestsuite),
+					alertParams.TestCountBaseline, now.Add(-alertParams.Window), now,
+					alertParams.Repository, alertParams.Branch, alertParams.MinBaselineRuns, alertParams.MaxTestCountDrop,
+				)
+				if err != nil {
+					logger.Error("Unable to load test counts", "err", err)
+					os.Exit(1)
+				}
+
+				alerts = append(alerts, alert.EvaluateTestCounts(thresholds, counts)...)
+
+				drops := []types.TestCountDrop{}
+				for _, c := range counts {
+					if c.Dropped {
+						drops = append(drops, c.Document())
+					}
+				}
+				if err := opensearch.BulkWriteObjects(
+					drops, indexFor(types.TypeNameTestCountDrop), rootParams.BulkOptions, bulkOutput,
+				); err != nil {
+					logger.Error("Unable to write test count drops", "err", err)
+					os.Exit(1)
+				}
+			}
+
+			failed := false
+			for _, a := range alerts {
+				l := logger.With("dedup-key", a.DedupKey, "firing", a.Firing)
+				if a.Firing {
+					l.Warn(a.Summary)
+				} else {
+					l.Debug(a.Summary)
+				}
+
+				for name, n := range notifiers {
+					if err := n.Send(ctx, a); err != nil {
+						l.Error("Unable to send alert", "notifier", name, "err", err)
+						failed = true
+					}
+				}
+			}
+
+			if failed {
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+// alertNotifiers returns the notifiers configured through the environment, by name.
+func alertNotifiers() (map[string]alert.Notifier, error) {
+	// secretNotifiers are the notifiers configured by a single secret.
+	secretNotifiers := []struct {
+		name string
+		env  string
+		new  func(secret *util.Secret) alert.Notifier
+	}{
+		{"pagerduty", "PAGERDUTY_ROUTING_KEY", func(s *util.Secret) alert.Notifier {
+			return alert.NewPagerDuty(alert.PagerDutyEventsURL, s, "corgi")
+		}},
+		{"opsgenie", "OPSGENIE_API_KEY", func(s *util.Secret) alert.Notifier {
+			url := alert.OpsgenieURL
+			if u := os.Getenv("OPSGENIE_URL"); u != "" {
+				url = u
+			}
+			return alert.NewOpsgenie(url, s, "corgi")
+		}},
+		{"slack", "SLACK_WEBHOOK_URL", func(s *util.Secret) alert.Notifier { return alert.NewSlack(s) }},
+		{"teams", "TEAMS_WEBHOOK_URL", func(s *util.Secret) alert.Notifier { return alert.NewTeams(s) }},
+		{"discord", "DISCORD_WEBHOOK_URL", func(s *util.Secret) alert.Notifier { return alert.NewDiscord(s) }},
+	}
+
+	notifiers := map[string]alert.Notifier{}
+	for _, n := range secretNotifiers {
+		if os.Getenv(n.env) == "" && os.Getenv(n.env+"_FILE") == "" {
+			continue
+		}
+
+		secret, err := util.NewSecret(n.env)
+		if err != nil {
+			return nil, err
+		}
+		notifiers[n.name] = n.new(secret)
+	}
+
+	return notifiers, nil
+}
+
+func init() {
+	alertCmd.PersistentFlags().StringVarP(
+		&alertParams.Repository, "repository", "r", "",
+		"Only look at workflow runs of the given repository, such as cilium/cilium",
+	)
+	alertCmd.PersistentFlags().StringVarP(
+		&alertParams.Branch, "branch", "b", "main",
+		"Branch whose workflow runs are checked",
+	)
+	alertCmd.PersistentFlags().StringVarP(
+		&alertParams.WindowStr, "window", "w", "1h",
+		"Time window, ending now, in which failed tests are counted",
+	)
+	alertCmd.PersistentFlags().IntVar(
+		&alertParams.MaxFailures, "max-failures", 10,
+		"Number of distinct failed tests of a workflow within the window above which an alert fires",
+	)
+	alertCmd.PersistentFlags().StringVar(
+		&alertParams.PassRateWindowStr, "pass-rate-window", "24h",
+		"Time window, ending now, over which the pass rate of required workflows is computed",
+	)
+	alertCmd.PersistentFlags().StringSliceVar(
+		&alertParams.RequiredWorkflows, "required-workflows", []string{},
+		"Names of the workflows whose pass rate is checked",
+	)
+	alertCmd.PersistentFlags().Float64Var(
+		&alertParams.MinPassRate, "min-pass-rate", 0.8,
+		"Pass rate of required workflows, between 0 and 1, below which an alert fires",
+	)
+
+	alertCmd.PersistentFlags().StringVar(
+		&alertParams.TestCountBaselineStr, "test-count-baseline", "7d",
+		"Time window before --window whose workflow runs determine the usual number of tests of each "+
+			"workflow and suite",
+	)
+	alertCmd.PersistentFlags().Float64Var(
+		&alertParams.MaxTestCountDrop, "max-test-count-drop", 0.5,
+		"Share of the usual number of tests, between 0 and 1, which may be missing from the latest run of a "+
+			"workflow or suite within --window before an alert fires. Zero disables the check.",
+	)
+	alertCmd.PersistentFlags().IntVar(
+		&alertParams.MinBaselineRuns, "min-baseline-runs", 5,
+		"Minimum number of runs of a workflow or suite within --test-count-baseline for its number of tests "+
+			"to be checked",
+	)
+
+	alertCmd.PersistentFlags().BoolVar(
+		&alertParams.ResultMismatches, "result-mismatches", false,
+		"Alert on workflows with runs within --window which succeeded although tests failed, or failed "+
+			"although none of their tests did",
+	)
+
+	rootCmd.AddCommand(alertCmd)
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/user"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/isovalent/corgi/pkg/metrics"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+var (
+	// auditEvent is the audit event of the current invocation, if it changes documents.
+	auditEvent *types.AuditEvent
+
+	// auditedRuns are the IDs of the workflow runs written by the current invocation.
+	auditedRunsMu sync.Mutex
+	auditedRuns   = map[int64]struct{}{}
+)
+
+// startAudit starts recording the audit event of the given command, which is written to
+// the audit index once it completes.
+func startAudit(cmd *cobra.Command) {
+	host, _ := os.Hostname()
+
+	flags := []string{}
+	config := sha256.New()
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Changed {
+			flags = append(flags, flag.Name)
+		}
+		fmt.Fprintf(config, "%s=%s\n", flag.Name, flag.Value.String())
+	})
+	for _, arg := range cmd.Flags().Args() {
+		fmt.Fprintf(config, "%s\n", arg)
+	}
+
+	auditEvent = &types.AuditEvent{
+		Type:       types.TypeNameAuditEvent,
+		Command:    cmd.CommandPath(),
+		Actor:      auditActor(),
+		Trigger:    auditTrigger(),
+		Host:       host,
+		Flags:      flags,
+		ConfigHash: hex.EncodeToString(config.Sum(nil)),
+		StartedAt:  time.Now(),
+	}
+}
+
+// auditActor returns who ran corgi: the user who triggered the GitHub workflow run it
+// runs in, or the local user otherwise.
+func auditActor() string {
+	if actor := os.Getenv("GITHUB_ACTOR"); actor != "" {
+		return actor
+	}
+
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+
+	return ""
+}
+
+// auditTrigger describes how corgi was run.
+func auditTrigger() string {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return fmt.Sprintf(
+			"github-actions %s %s/%s/actions/runs/%s",
+			os.Getenv("GITHUB_EVENT_NAME"), os.Getenv("GITHUB_SERVER_URL"),
+			os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID"),
+		)
+	case os.Getenv("KUBERNETES_SERVICE_HOST") != "":
+		return "kubernetes"
+	default:
+		return "cli"
+	}
+}
+
+// auditRun records that documents of the given workflow run were written.
+func auditRun(id int64) {
+	auditedRunsMu.Lock()
+	defer auditedRunsMu.Unlock()
+
+	auditedRuns[id] = struct{}{}
+}
+
+// finishAudit completes the audit event with what the invocation changed. It is called
+// before other documents about the invocation are written, so that they aren't counted.
+func finishAudit() {
+	e := auditEvent
+	e.FinishedAt = time.Now()
+	e.DocumentsWritten = metrics.Count(metrics.CounterDocumentsWritten)
+	e.DocumentsDeleted = metrics.Count(metrics.CounterDocumentsDeleted)
+
+	auditedRunsMu.Lock()
+	defer auditedRunsMu.Unlock()
+
+	e.WorkflowIDs = make([]int64, 0, len(auditedRuns))
+	for id := range auditedRuns {
+		e.WorkflowIDs = append(e.WorkflowIDs, id)
+	}
+	slices.Sort(e.WorkflowIDs)
+}
+
+func writeAuditEvent() error {
+	if err := opensearch.BulkWriteObjects(
+		[]types.AuditEvent{*auditEvent}, rootParams.AuditIndex, rootParams.BulkOptions, bulkOutput,
+	); err != nil {
+		return fmt.Errorf("unable to write audit event: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/metrics"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+type typeCleanupParams struct {
+	OlderThan string
+}
+
+var (
+	cleanupParams = &typeCleanupParams{}
+	cleanupCmd    = &cobra.Command{
+		Use:   "cleanup",
+		Short: "Delete the documents of old workflow runs",
+		Long: "Delete every document of the workflow runs created longer than --older-than ago from OPENSEARCH_URL, " +
+			"such as to enforce retention on indices which aren't rotated by date. With --id-prefix, only the " +
+			"documents of that deployment are deleted.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if cleanupParams.OlderThan == "" {
+				return fmt.Errorf("--older-than is required")
+			}
+			if _, err := util.ParseDuration(cleanupParams.OlderThan); err != nil {
+				return fmt.Errorf("unable to parse --older-than: %w", err)
+			}
+			if os.Getenv("OPENSEARCH_URL") == "" {
+				return fmt.Errorf("OPENSEARCH_URL must be set")
+			}
+
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			logger := log.NewLogger(rootParams.Verbose)
+
+			startAudit(cmd)
+
+			olderThan, _ := util.ParseDuration(cleanupParams.OlderThan)
+			before := time.Now().Add(-olderThan)
+
+			cfg, err := opensearch.NewClientConfig()
+			if err != nil {
+				logger.Error("Unable to create opensearch client config", "err", err)
+				os.Exit(1)
+			}
+
+			client, err := opensearchgo.NewClient(cfg)
+			if err != nil {
+				logger.Error("Unable to create opensearch client", "err", err)
+				os.Exit(1)
+			}
+
+			deleteCtx, cancel := util.WithTimeout(ctx, rootParams.Timeouts.BulkWrite)
+			defer cancel()
+
+			deleted, err := opensearch.DeleteByQuery(
+				deleteCtx, client, readIndex(runDocumentTypes...),
+				deploymentQuery(map[string]any{
+					"range": map[string]any{"workflow_created_at": map[string]any{"lt": before.UTC().Format(time.RFC3339)}},
+				}),
+			)
+			if err != nil {
+				logger.Error("Unable to delete documents of old workflow runs", "err", err)
+				os.Exit(1)
+			}
+
+			metrics.Add(metrics.CounterDocumentsDeleted, int64(deleted))
+			logger.Info("Deleted documents of old workflow runs", "before", before, "deleted", deleted)
+		},
+	}
+)
+
+func init() {
+	cleanupCmd.Flags().StringVar(
+		&cleanupParams.OlderThan, "older-than", "",
+		"Delete the documents of workflow runs created longer ago than this, such as 180d",
+	)
+
+	rootCmd.AddCommand(cleanupCmd)
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	gh "github.com/isovalent/corgi/pkg/github"
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/opensearch"
+)
+
+type typeDoctorParams struct {
+	Repository string
+}
+
+// doctorCheck is a single preflight check. A failed check is reported along
+// with its hint on how to fix it.
+type doctorCheck struct {
+	name string
+	hint string
+	run  func(ctx context.Context) error
+}
+
+// doctorDocumentID is the ID of the document written to verify write access to the index.
+const doctorDocumentID = "corgi-doctor"
+
+// githubDoctorChecks returns checks verifying that the GitHub token can access everything
+// needed to scrape the given repository.
+func githubDoctorChecks(client *github.Client, repoOwner, repoName string) []doctorCheck {
+	repo := fmt.Sprintf("%s/%s", repoOwner, repoName)
+	one := &github.ListOptions{PerPage: 1}
+
+	return []doctorCheck{
+		{
+			name: "GitHub token is set",
+			hint: "set GITHUB_TOKEN to a personal access token or GitHub App installation token",
+			run: func(ctx context.Context) error {
+				token, err := gh.GetGitHubAuthToken()
+				if err != nil {
+					return err
+				}
+				if token.Value() == "" {
+					return fmt.Errorf("GITHUB_TOKEN is empty")
+				}
+				return nil
+			},
+		},
+		{
+			name: "GitHub token is valid",
+			hint: "the token may have expired or been revoked, generate a new one",
+			run: func(ctx context.Context) error {
+				_, resp, err := client.RateLimit.Get(ctx)
+				if err != nil {
+					return err
+				}
+				if scopes := resp.Header.Get("X-OAuth-Scopes"); scopes != "" {
+					fmt.Printf("      token scopes: %s\n", scopes)
+				}
+				return nil
+			},
+		},
+		{
+			name: fmt.Sprintf("Read repository metadata of %s (metadata:read)", repo),
+			hint: "grant the token access to the repository, with the 'repo' or 'public_repo' scope for classic tokens",
+			run: func(ctx context.Context) error {
+				_, _, err := client.Repositories.Get(ctx, repoOwner, repoName)
+				return err
+			},
+		},
+		{
+			name: fmt.Sprintf("Read contents of %s (contents:read)", repo),
+			hint: "grant the token the 'Contents: Read-only' repository permission",
+			run: func(ctx context.Context) error {
+				_, _, err := client.Repositories.ListCommits(ctx, repoOwner, repoName, &github.CommitsListOptions{ListOptions: *one})
+				return err
+			},
+		},
+		{
+			name: fmt.Sprintf("Read workflow runs and artifacts of %s (actions:read)", repo),
+			hint: "grant the token the 'Actions: Read-only' repository permission",
+			run: func(ctx context.Context) error {
+				if _, _, err := client.Actions.ListRepositoryWorkflowRuns(
+					ctx, repoOwner, repoName, &github.ListWorkflowRunsOptions{ListOptions: *one},
+				); err != nil {
+					return err
+				}
+				_, _, err := client.Actions.ListArtifacts(ctx, repoOwner, repoName, &github.ListOptions{PerPage: 1})
+				return err
+			},
+		},
+		{
+			name: fmt.Sprintf("Read pull requests of %s (pull_requests:read)", repo),
+			hint: "grant the token the 'Pull requests: Read-only' repository permission, needed for --pr-reviews",
+			run: func(ctx context.Context) error {
+				_, _, err := client.PullRequests.List(ctx, repoOwner, repoName, &github.PullRequestListOptions{ListOptions: *one})
+				return err
+			},
+		},
+	}
+}
+
+// opensearchDoctorChecks returns checks verifying that the OpenSearch credentials can
+// write to the given index.
+func opensearchDoctorChecks(client *opensearchgo.Client, index string) []doctorCheck {
+	return []doctorCheck{
+		{
+			name: "Connect to OpenSearch",
+			hint: "check OPENSEARCH_URL, OPENSEARCH_USER and OPENSEARCH_PASS (or the AWS credentials with OPENSEARCH_SERVERLESS), and OPENSEARCH_TLS_INSECURE for self-signed certificates",
+			run: func(ctx context.Context) error {
+				return opensearch.Ping(ctx, client, index)
+			},
+		},
+		{
+			name: fmt.Sprintf("Create and write to index %s", index),
+			hint: "grant the OpenSearch user the 'create_index', 'index' and 'delete' permissions on the index pattern",
+			run: func(ctx context.Context) error {
+				if opensearch.OmitDocumentIDs() {
+					// Documents without IDs can't be cleaned up again, so don't write any.
+					return nil
+				}
+				if err := opensearch.IndexDocument(ctx, client, index, doctorDocumentID, map[string]any{
+					"type": "corgi_doctor",
+				}); err != nil {
+					return err
+				}
+				return opensearch.DeleteDocument(ctx, client, index, doctorDocumentID)
+			},
+		},
+	}
+}
+
+var (
+	doctorParams = &typeDoctorParams{}
+	doctorCmd    = &cobra.Command{
+		Use:   "doctor",
+		Short: "Check that the GitHub token and OpenSearch credentials have the needed permissions",
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			logger := log.NewLogger(rootParams.Verbose)
+
+			repoOwner, repoName, ok := strings.Cut(doctorParams.Repository, "/")
+			if !ok {
+				logger.Error("Unable to extract repo owner and name from given value", "given", doctorParams.Repository)
+				os.Exit(1)
+			}
+
+			token, err := gh.GetGitHubAuthToken()
+			if err != nil {
+				logger.Error("Unable to load GitHub token", "err", err)
+				os.Exit(1)
+			}
+
+			client, err := gh.NewGitHubClient(token, logger, rootParams.Timeouts.Request)
+			if err != nil {
+				logger.Error("Unable to create new GitHub Client", "err", err)
+				os.Exit(1)
+			}
+
+			checks := githubDoctorChecks(client, repoOwner, repoName)
+
+			opensearchCfg, err := opensearch.NewClientConfig()
+			if err != nil {
+				logger.Error("Unable to load OpenSearch configuration", "err", err)
+				os.Exit(1)
+			}
+			if opensearchCfg.Addresses[0] == "" {
+				fmt.Println("SKIP  OpenSearch checks, OPENSEARCH_URL is not set")
+			} else {
+				opsClient, err := opensearchgo.NewClient(opensearchCfg)
+				if err != nil {
+					logger.Error("Unable to create opensearch client", "err", err)
+					os.Exit(1)
+				}
+				checks = append(checks, opensearchDoctorChecks(opsClient, opensearch.ResolveIndexName(rootParams.Index, time.Now()))...)
+			}
+
+			failed := 0
+			for _, check := range checks {
+				if err := check.run(ctx); err != nil {
+					failed++
+					fmt.Printf("FAIL  %s\n      %s\n      hint: %s\n", check.name, err, check.hint)
+					continue
+				}
+				fmt.Printf("OK    %s\n", check.name)
+			}
+
+			if failed > 0 {
+				fmt.Printf("\n%d of %d checks failed\n", failed, len(checks))
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func init() {
+	doctorCmd.PersistentFlags().StringVarP(
+		&doctorParams.Repository, "repository", "r", "cilium/cilium",
+		"Repository to check access to in owner/name format",
+	)
+
+	rootCmd.AddCommand(doctorCmd)
+}
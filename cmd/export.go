@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	"github.com/isovalent/corgi/pkg/export"
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/report"
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+const (
+	exportFormatCSV     = "csv"
+	exportFormatParquet = "parquet"
+
+	// exportPageSize is the number of documents fetched per page when exporting.
+	exportPageSize = 1000
+)
+
+type typeExportParams struct {
+	Type       string
+	SinceStr   string
+	Since      time.Duration
+	Format     string
+	Output     string
+	Repository string
+}
+
+var (
+	exportParams = &typeExportParams{}
+	exportCmd    = &cobra.Command{
+		Use:   "export",
+		Short: "Export documents from OpenSearch as CSV or Parquet",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			since, err := util.ParseDuration(exportParams.SinceStr)
+			if err != nil {
+				return fmt.Errorf("unable to parse since: %w", err)
+			}
+			exportParams.Since = since
+
+			switch exportParams.Format {
+			case exportFormatCSV, exportFormatParquet:
+			default:
+				return fmt.Errorf("unknown format '%s', expected '%s' or '%s'", exportParams.Format, exportFormatCSV, exportFormatParquet)
+			}
+
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			logger := log.NewLogger(rootParams.Verbose)
+
+			opensearchCfg, err := opensearch.NewClientConfig()
+			if err != nil {
+				logger.Error("Unable to load OpenSearch configuration", "err", err)
+				os.Exit(1)
+			}
+
+			opsClient, err := opensearchgo.NewClient(opensearchCfg)
+			if err != nil {
+				logger.Error("Unable to create opensearch client", "err", err)
+				os.Exit(1)
+			}
+
+			typ := types.TypeName(exportParams.Type)
+			until := time.Now()
+			query := report.WindowQuery(until.Add(-exportParams.Since), until, exportParams.Repository, typ)
+
+			// Both formats need to know every column upfront, so the documents are searched
+			// twice: once to find the columns, and once to write them, so that they don't
+			// need to be held in memory.
+			schema := export.NewSchema()
+			err = opensearch.SearchAll(ctx, opsClient, readIndex(typ), query, exportPageSize, func(source map[string]any) error {
+				schema.Add(source)
+				return nil
+			})
+			if err != nil {
+				logger.Error("Unable to search documents", "err", err)
+				os.Exit(1)
+			}
+
+			var out io.Writer = os.Stdout
+			if exportParams.Output != "" {
+				f, err := os.Create(exportParams.Output)
+				if err != nil {
+					logger.Error("Unable to create output file", "err", err)
+					os.Exit(1)
+				}
+				defer f.Close()
+				out = f
+			}
+
+			var w export.Writer
+			if exportParams.Format == exportFormatParquet {
+				w = export.NewParquetWriter(out, schema)
+			} else {
+				w, err = export.NewCSVWriter(out, schema)
+				if err != nil {
+					logger.Error("Unable to write export", "err", err)
+					os.Exit(1)
+				}
+			}
+
+			documents := 0
+			err = opensearch.SearchAll(ctx, opsClient, readIndex(typ), query, exportPageSize, func(source map[string]any) error {
+				documents++
+				return w.Write(source)
+			})
+			if err == nil {
+				err = w.Close()
+			}
+			if err != nil {
+				logger.Error("Unable to write export", "err", err)
+				os.Exit(1)
+			}
+
+			logger.Info("Exported documents", "type", typ, "documents", documents)
+		},
+	}
+)
+
+func init() {
+	exportCmd.PersistentFlags().StringVarP(
+		&exportParams.Type, "type", "t", string(types.TypeNameTestcase),
+		"Type of documents to export, such as test_case, test_suite, job_run or workflow_run",
+	)
+	exportCmd.PersistentFlags().StringVarP(
+		&exportParams.SinceStr, "since", "s", "30d",
+		"Export documents of workflow runs created within this duration before now, such as 30d",
+	)
+	exportCmd.PersistentFlags().StringVarP(
+		&exportParams.Format, "format", "f", exportFormatCSV,
+		"Format to export in, either 'csv' or 'parquet'",
+	)
+	exportCmd.PersistentFlags().StringVarP(
+		&exportParams.Output, "output", "o", "",
+		"File to write the export to. Defaults to stdout.",
+	)
+	exportCmd.PersistentFlags().StringVarP(
+		&exportParams.Repository, "repository", "r", "",
+		"Only export documents of the given repository, such as cilium/cilium",
+	)
+
+	rootCmd.AddCommand(exportCmd)
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/report"
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+type typeFailureCooccurrenceParams struct {
+	WindowStr   string
+	Window      time.Duration
+	Repository  string
+	Branch      string
+	MinTogether int
+	MaxFailures int
+	Interval    time.Duration
+}
+
+// indexFailureCooccurrence finds the tests which failed together within the window
+// ending now and writes the pairs.
+func indexFailureCooccurrence(ctx context.Context, logger *slog.Logger, client *opensearchgo.Client) error {
+	until := time.Now().UTC()
+	since := until.Add(-failureCooccurrenceParams.Window)
+
+	b, err := report.LoadCooccurrenceBuilder(
+		ctx, client, readIndex(types.TypeNameWorkflowRun, types.TypeNameTestcase), since, until,
+		failureCooccurrenceParams.Repository, failureCooccurrenceParams.Branch,
+	)
+	if err != nil {
+		return err
+	}
+
+	pairs := b.Build(failureCooccurrenceParams.MinTogether, failureCooccurrenceParams.MaxFailures)
+
+	logger.Info(
+		"Computed failure co-occurrence, saving",
+		"num-results", len(pairs), "target-index", indexFor(types.TypeNameFailureCooccurrence),
+	)
+
+	if err := opensearch.BulkWriteObjects(
+		pairs, indexFor(types.TypeNameFailureCooccurrence), rootParams.BulkOptions, bulkOutput,
+	); err != nil {
+		return fmt.Errorf("unable to write failure co-occurrence: %w", err)
+	}
+
+	return nil
+}
+
+var (
+	failureCooccurrenceParams = &typeFailureCooccurrenceParams{}
+	failureCooccurrenceCmd    = &cobra.Command{
+		Use:   "failure-cooccurrence",
+		Short: "Index pairs of tests which tend to fail in the same run",
+		Long: "Find pairs of tests of a workflow which failed in the same run attempt within the window " +
+			"ending now, and write them as failure_cooccurrence documents with their confidence and lift. " +
+			"A lift well above one means the tests fail together more often than by chance, hinting at " +
+			"shared infrastructure or an ordering dependency between them. With --interval, the pairs " +
+			"are recomputed periodically until interrupted.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			window, err := util.ParseDuration(failureCooccurrenceParams.WindowStr)
+			if err != nil {
+				return fmt.Errorf("unable to parse window: %w", err)
+			}
+			failureCooccurrenceParams.Window = window
+
+			if failureCooccurrenceParams.Interval < 0 {
+				return fmt.Errorf("--interval must not be negative")
+			}
+
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := log.NewLogger(rootParams.Verbose)
+
+			opensearchCfg, err := opensearch.NewClientConfig()
+			if err != nil {
+				logger.Error("Unable to load OpenSearch configuration", "err", err)
+				os.Exit(1)
+			}
+
+			opsClient, err := opensearchgo.NewClient(opensearchCfg)
+			if err != nil {
+				logger.Error("Unable to create opensearch client", "err", err)
+				os.Exit(1)
+			}
+
+			if err := runPeriodically(logger, failureCooccurrenceParams.Interval, func(ctx context.Context) error {
+				return indexFailureCooccurrence(ctx, logger, opsClient)
+			}); err != nil {
+				logger.Error("Unable to index failure co-occurrence", "err", err)
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func init() {
+	failureCooccurrenceCmd.PersistentFlags().StringVarP(
+		&failureCooccurrenceParams.WindowStr, "window", "w", "30d",
+		"Time window to look for failures in, ending now, such as 30d",
+	)
+	failureCooccurrenceCmd.PersistentFlags().StringVarP(
+		&failureCooccurrenceParams.Repository, "repository", "r", "",
+		"Only look at workflow runs of the given repository, such as cilium/cilium",
+	)
+	failureCooccurrenceCmd.PersistentFlags().StringVarP(
+		&failureCooccurrenceParams.Branch, "branch", "b", "main",
+		"Only look at workflow runs on the given branch. An empty value looks at every branch.",
+	)
+	failureCooccurrenceCmd.PersistentFlags().IntVar(
+		&failureCooccurrenceParams.MinTogether, "min-together", 3,
+		"Minimum number of run attempts two tests failed together in for the pair to be written",
+	)
+	failureCooccurrenceCmd.PersistentFlags().IntVar(
+		&failureCooccurrenceParams.MaxFailures, "max-failures-per-run", 20,
+		"Leave out run attempts in which more tests failed, since they are usually broken as a whole. "+
+			"Zero keeps every attempt.",
+	)
+	failureCooccurrenceCmd.PersistentFlags().DurationVar(
+		&failureCooccurrenceParams.Interval, "interval", 0,
+		"Recompute the pairs this often until interrupted, such as 24h. Zero computes them once.",
+	)
+
+	rootCmd.AddCommand(failureCooccurrenceCmd)
+}
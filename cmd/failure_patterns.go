@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/report"
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+type typeFailurePatternsParams struct {
+	WindowStr  string
+	Window     time.Duration
+	Repository string
+	Branch     string
+	MinJobs    int
+	Interval   time.Duration
+}
+
+// indexFailurePatterns segments the failure rate of the jobs within the window ending now
+// and writes the patterns.
+func indexFailurePatterns(ctx context.Context, logger *slog.Logger, client *opensearchgo.Client) error {
+	until := time.Now().UTC()
+	since := until.Add(-failurePatternsParams.Window)
+
+	patterns, err := report.LoadPatterns(
+		ctx, client, readIndex(types.TypeNameJobRun), since, until,
+		failurePatternsParams.Repository, failurePatternsParams.Branch, failurePatternsParams.MinJobs,
+	)
+	if err != nil {
+		return err
+	}
+
+	logger.Info(
+		"Computed failure patterns, saving",
+		"num-results", len(patterns), "target-index", indexFor(types.TypeNameFailurePattern),
+	)
+
+	if err := opensearch.BulkWriteObjects(
+		patterns, indexFor(types.TypeNameFailurePattern), rootParams.BulkOptions, bulkOutput,
+	); err != nil {
+		return fmt.Errorf("unable to write failure patterns: %w", err)
+	}
+
+	return nil
+}
+
+var (
+	failurePatternsParams = &typeFailurePatternsParams{}
+	failurePatternsCmd    = &cobra.Command{
+		Use:   "failure-patterns",
+		Short: "Index the failure rate of jobs by hour of day, weekday and runner region",
+		Long: "Segment the jobs within the window ending now by the hour of the day and the weekday they " +
+			"started at, in UTC, and by the runner group they ran on, and write the failure rate of each " +
+			"segment, along with how it compares to that of all jobs, as failure_pattern documents. " +
+			"Segments failing notably more often, such as at peak hours when quotas run out, point at " +
+			"failures driven by the environment. With --interval, the patterns are recomputed " +
+			"periodically until interrupted.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			window, err := util.ParseDuration(failurePatternsParams.WindowStr)
+			if err != nil {
+				return fmt.Errorf("unable to parse window: %w", err)
+			}
+			failurePatternsParams.Window = window
+
+			if failurePatternsParams.Interval < 0 {
+				return fmt.Errorf("--interval must not be negative")
+			}
+
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := log.NewLogger(rootParams.Verbose)
+
+			opensearchCfg, err := opensearch.NewClientConfig()
+			if err != nil {
+				logger.Error("Unable to load OpenSearch configuration", "err", err)
+				os.Exit(1)
+			}
+
+			opsClient, err := opensearchgo.NewClient(opensearchCfg)
+			if err != nil {
+				logger.Error("Unable to create opensearch client", "err", err)
+				os.Exit(1)
+			}
+
+			if err := runPeriodically(logger, failurePatternsParams.Interval, func(ctx context.Context) error {
+				return indexFailurePatterns(ctx, logger, opsClient)
+			}); err != nil {
+				logger.Error("Unable to index failure patterns", "err", err)
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func init() {
+	failurePatternsCmd.PersistentFlags().StringVarP(
+		&failurePatternsParams.WindowStr, "window", "w", "28d",
+		"Time window to segment jobs over, ending now. Whole weeks weigh every weekday equally.",
+	)
+	failurePatternsCmd.PersistentFlags().StringVarP(
+		&failurePatternsParams.Repository, "repository", "r", "",
+		"Only look at workflow runs of the given repository, such as cilium/cilium",
+	)
+	failurePatternsCmd.PersistentFlags().StringVarP(
+		&failurePatternsParams.Branch, "branch", "b", "",
+		"Only look at workflow runs on the given branch. Empty includes all branches.",
+	)
+	failurePatternsCmd.PersistentFlags().IntVar(
+		&failurePatternsParams.MinJobs, "min-jobs", 20,
+		"Minimum number of jobs within a segment for its failure pattern to be written",
+	)
+	failurePatternsCmd.PersistentFlags().DurationVar(
+		&failurePatternsParams.Interval, "interval", 0,
+		"Recompute the patterns this often until interrupted, such as 24h. Zero computes them once.",
+	)
+
+	rootCmd.AddCommand(failurePatternsCmd)
+}
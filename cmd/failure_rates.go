@@ -95,7 +95,7 @@ func getFailureRate(
 
 	l.Info("Got results from OpenSearch, saving", "num-results", len(results), "target-index", targetIndex)
 
-	if err := ops.BulkWriteObjects[types.FailureRate](results, targetIndex, os.Stdout); err != nil {
+	if err := ops.BulkWriteObjects[types.FailureRate](results, targetIndex, rootParams.BulkOptions, bulkOutput); err != nil {
 		l.Error("Unexpected error while writing failure rate bulk entries", "err", err)
 		os.Exit(1)
 	}
@@ -154,7 +154,11 @@ var (
 			repoOwner := repoParts[0]
 			repoName := repoParts[1]
 
-			opensearchCfg := ops.NewClientConfig()
+			opensearchCfg, err := ops.NewClientConfig()
+			if err != nil {
+				logger.Error("Unable to load OpenSearch configuration", "err", err)
+				os.Exit(1)
+			}
 
 			opsClient, err := opensearch.NewClient(opensearchCfg)
 			if err != nil {
@@ -162,7 +166,13 @@ var (
 				os.Exit(1)
 			}
 
-			githubClient, err := github.NewGitHubClient(os.Getenv("GITHUB_TOKEN"), logger)
+			token, err := github.GetGitHubAuthToken()
+			if err != nil {
+				logger.Error("Unable to load GitHub token", "err", err)
+				os.Exit(1)
+			}
+
+			githubClient, err := github.NewGitHubClient(token, logger, rootParams.Timeouts.Request)
 			if err != nil {
 				logger.Error("Unable to create GitHub client", "err", err)
 				os.Exit(1)
@@ -184,7 +194,7 @@ var (
 						ctx, logger, opsClient,
 						failureRateParams.Since, failureRateParams.Until,
 						types.TypeName(typ),
-						repo, failureRateParams.RunsIndex, rootParams.Index,
+						repo, failureRateParams.RunsIndex, indexFor(types.TypeNameFailureRate),
 						failureRateParams.Branch, event,
 					)
 				}
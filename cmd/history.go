@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	"github.com/isovalent/corgi/pkg/junit"
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/report"
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+type typeHistoryParams struct {
+	Test       string
+	Branch     string
+	WindowStr  string
+	Window     time.Duration
+	Repository string
+}
+
+var (
+	historyParams = &typeHistoryParams{}
+	historyCmd    = &cobra.Command{
+		Use:   "history",
+		Short: "Print the pass/fail timeline of a test",
+		Long: "Print every run of a test within the window, oldest first, with its status, duration and " +
+			"a link to its workflow run, preceded by a one-character-per-run timeline: '.' for passes, " +
+			"'F' for failures and errors, and 's' for anything else.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if historyParams.Test == "" {
+				return fmt.Errorf("--test is required")
+			}
+
+			window, err := util.ParseDuration(historyParams.WindowStr)
+			if err != nil {
+				return fmt.Errorf("unable to parse window: %w", err)
+			}
+			historyParams.Window = window
+
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			logger := log.NewLogger(rootParams.Verbose)
+
+			opensearchCfg, err := opensearch.NewClientConfig()
+			if err != nil {
+				logger.Error("Unable to load OpenSearch configuration", "err", err)
+				os.Exit(1)
+			}
+
+			opsClient, err := opensearchgo.NewClient(opensearchCfg)
+			if err != nil {
+				logger.Error("Unable to create opensearch client", "err", err)
+				os.Exit(1)
+			}
+
+			until := time.Now()
+			cases, err := report.LoadTestcases(
+				ctx, opsClient, readIndex(types.TypeNameTestcase), until.Add(-historyParams.Window), until,
+				historyParams.Repository, historyParams.Branch, historyParams.Test,
+			)
+			if err != nil {
+				logger.Error("Unable to load test history", "err", err)
+				os.Exit(1)
+			}
+
+			printHistory(cases)
+		},
+	}
+)
+
+// historySymbol returns the character representing the given status in the timeline.
+func historySymbol(status string) string {
+	switch status {
+	case junit.StatusPassed:
+		return "."
+	case junit.StatusFailed, junit.StatusError:
+		return "F"
+	}
+
+	return "s"
+}
+
+// printHistory prints the timeline and the list of the given testcases, oldest first.
+func printHistory(cases []*types.Testcase) {
+	if len(cases) == 0 {
+		fmt.Printf("No runs of %s in the last %s\n", historyParams.Test, historyParams.WindowStr)
+		return
+	}
+
+	timeline := &strings.Builder{}
+	for _, tc := range cases {
+		timeline.WriteString(historySymbol(tc.Status))
+	}
+	fmt.Printf("%s\n%s\n\n", historyParams.Test, timeline)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "TIME\tSTATUS\tDURATION\tBRANCH\tRUN")
+	for _, tc := range cases {
+		fmt.Fprintf(
+			w, "%s\t%s\t%s\t%s\t%s\n",
+			tc.WorkflowRun.CreatedAt.UTC().Format(time.DateTime), tc.Status, tc.Duration.Round(time.Millisecond),
+			tc.WorkflowRun.HeadBranch, tc.WorkflowRun.Link,
+		)
+	}
+}
+
+func init() {
+	historyCmd.PersistentFlags().StringVarP(
+		&historyParams.Test, "test", "t", "",
+		"Name of the test to print the history of",
+	)
+	historyCmd.PersistentFlags().StringVarP(
+		&historyParams.Branch, "branch", "b", "main",
+		"Only include runs on the given branch. Empty includes all branches.",
+	)
+	historyCmd.PersistentFlags().StringVarP(
+		&historyParams.WindowStr, "window", "w", "90d",
+		"Time window to look at, ending now, such as 90d or 24h",
+	)
+	historyCmd.PersistentFlags().StringVarP(
+		&historyParams.Repository, "repository", "r", "",
+		"Only look at workflow runs of the given repository, such as cilium/cilium",
+	)
+
+	rootCmd.AddCommand(historyCmd)
+}
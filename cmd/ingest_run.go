@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	gh "github.com/isovalent/corgi/pkg/github"
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/metrics"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/report"
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+type typeIngestRunParams struct {
+	SummaryFile    string
+	FlakeWindowStr string
+	FlakeWindow    time.Duration
+	CommentOnPR    bool
+	CheckRun       bool
+}
+
+// analysisCheckName is the name of the check run the failure summary is published as.
+const analysisCheckName = "Corgi CI Analysis"
+
+var ingestRunParams = &typeIngestRunParams{}
+
+var reWorkflowRunURL = regexp.MustCompile(`^https://github\.com/([^/]+)/([^/]+)/actions/runs/(\d+)(/attempts/\d+)?/?$`)
+
+// parseWorkflowRunURL extracts the repository and run ID from the URL of a workflow run.
+func parseWorkflowRunURL(u string) (repoOwner, repoName string, runID int64, err error) {
+	match := reWorkflowRunURL.FindStringSubmatch(u)
+	if match == nil {
+		return "", "", 0, fmt.Errorf(
+			"expected url in the form of 'https://github.com/<owner>/<repo>/actions/runs/<id>', got '%s'", u,
+		)
+	}
+
+	runID, err = strconv.ParseInt(match[3], 10, 64)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("unable to parse run id '%s': %w", match[3], err)
+	}
+
+	return match[1], match[2], runID, nil
+}
+
+var ingestRunCmd = &cobra.Command{
+	Use:   "ingest-run <url>",
+	Short: "Ingest every attempt of a single workflow run",
+	Long: "Ingest every attempt of the workflow run with the given URL, regardless of whether it " +
+		"was already ingested. Tests are parsed using the defaults of the 'workflow runs' command.",
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if _, _, _, err := parseWorkflowRunURL(args[0]); err != nil {
+			return err
+		}
+
+		window, err := util.ParseDuration(ingestRunParams.FlakeWindowStr)
+		if err != nil {
+			return fmt.Errorf("unable to parse flake window: %w", err)
+		}
+		ingestRunParams.FlakeWindow = window
+
+		return compileTestParams()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		logger := log.NewLogger(rootParams.Verbose)
+
+		repoOwner, repoName, runID, _ := parseWorkflowRunURL(args[0])
+
+		startIngestStats(cmd)
+		startAudit(cmd)
+
+		token, err := gh.GetGitHubAuthToken()
+		if err != nil {
+			logger.Error("Unable to load GitHub token", "err", err)
+			os.Exit(1)
+		}
+
+		client, err := gh.NewGitHubClient(token, logger, rootParams.Timeouts.Request)
+		if err != nil {
+			logger.Error("Unable to create new GitHub Client", "err", err)
+			os.Exit(1)
+		}
+
+		runs, err := gh.GetWorkflowRunAttempts(ctx, logger, client, repoOwner, repoName, runID)
+		if err != nil {
+			logger.Error("Unable to pull workflow run", "err", err)
+			os.Exit(1)
+		}
+
+		logger.Info("Ingesting workflow run", "workflow-id", runID, "attempts", len(runs))
+
+		runsCh := make(chan *types.WorkflowRun, len(runs))
+		for _, run := range runs {
+			runsCh <- run
+		}
+		close(runsCh)
+
+		var latest *runResult
+
+		prefetched := prefetchArtifacts(ctx, logger, client, runsCh, workflowRunsParams.ArtifactPrefetchConcurrency)
+		for prefetch := range prefetched {
+			result := pullRun(ctx, logger, client, prefetch)
+			writeRunResult(ctx, logger, result)
+
+			if latest == nil || result.run.RunAttempt > latest.run.RunAttempt {
+				latest = result
+			}
+		}
+
+		metrics.LogSummary(logger)
+
+		if latest == nil ||
+			(ingestRunParams.SummaryFile == "" && !ingestRunParams.CommentOnPR && !ingestRunParams.CheckRun) {
+			return
+		}
+
+		summary := buildFailureSummary(ctx, logger, latest)
+
+		if ingestRunParams.SummaryFile != "" {
+			if err := writeFailureSummary(summary, ingestRunParams.SummaryFile); err != nil {
+				logger.Error("Unable to write failure summary", "err", err)
+				os.Exit(1)
+			}
+		}
+
+		if ingestRunParams.CommentOnPR {
+			if err := commentFailureSummary(ctx, logger, client, latest.run, summary); err != nil {
+				logger.Error("Unable to comment failure summary on pull request", "err", err)
+				os.Exit(1)
+			}
+		}
+
+		if ingestRunParams.CheckRun {
+			body := &strings.Builder{}
+			if err := report.RenderMarkdown(body, summary); err != nil {
+				logger.Error("Unable to render failure summary", "err", err)
+				os.Exit(1)
+			}
+
+			if err := gh.CreateCheckRun(
+				ctx, logger, client, repoOwner, repoName, latest.run.HeadSHA,
+				analysisCheckName, summary.CheckConclusion(), summary.CheckTitle(), body.String(),
+			); err != nil {
+				logger.Error("Unable to publish failure summary as check run", "err", err)
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+// buildFailureSummary summarizes the failed tests of the given run. If OPENSEARCH_URL is set,
+// tests which both failed and passed within the flake window before the run are flagged as
+// known flakes.
+func buildFailureSummary(ctx context.Context, logger *slog.Logger, result *runResult) *report.FailureSummary {
+	summary := report.NewFailureSummary(result.run, result.cases)
+
+	if os.Getenv("OPENSEARCH_URL") == "" {
+		logger.Info("OPENSEARCH_URL is not set, not flagging known flakes")
+		return summary
+	}
+
+	opensearchCfg, err := opensearch.NewClientConfig()
+	if err != nil {
+		logger.Warn("Unable to create opensearch client config, unable to flag known flakes", "err", err)
+		return summary
+	}
+
+	opsClient, err := opensearchgo.NewClient(opensearchCfg)
+	if err != nil {
+		logger.Warn("Unable to create opensearch client, unable to flag known flakes", "err", err)
+		return summary
+	}
+
+	until := result.run.CreatedAt
+	flakes, err := report.LoadFlakes(
+		ctx, opsClient, readIndex(types.TypeNameTestcase),
+		until.Add(-ingestRunParams.FlakeWindow), until, summary.Repository, summary.FailedTests(),
+	)
+	if err != nil {
+		logger.Warn("Unable to load test history, unable to flag known flakes", "err", err)
+		return summary
+	}
+	summary.MarkKnownFlakes(flakes)
+
+	return summary
+}
+
+// writeFailureSummary writes the given summary as JSON to the given path.
+func writeFailureSummary(summary *report.FailureSummary, path string) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal failure summary: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("unable to write failure summary to %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// commentFailureSummary posts the given summary on the pull request the given run was
+// triggered for, updating the previously posted summary if there is one.
+func commentFailureSummary(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	run *types.WorkflowRun,
+	summary *report.FailureSummary,
+) error {
+	if summary.PullRequest == 0 {
+		pr, err := gh.GetPullRequestForRun(ctx, logger, client, run)
+		if err != nil {
+			return err
+		}
+		if pr == nil {
+			logger.Info("Workflow run was not triggered for a pull request, not commenting", "workflow-id", run.ID)
+			return nil
+		}
+		summary.PullRequest = pr.Number
+	}
+
+	body := &strings.Builder{}
+	if err := report.RenderMarkdown(body, summary); err != nil {
+		return err
+	}
+
+	return gh.UpsertIssueComment(
+		ctx, logger, client, run.Repository.Owner.Login, run.Repository.Name,
+		summary.PullRequest, report.SummaryCommentMarker, body.String(),
+	)
+}
+
+func init() {
+	ingestRunCmd.PersistentFlags().StringVar(
+		&ingestRunParams.SummaryFile, "summary-file", "",
+		"If set, write a JSON summary of the failed tests of the latest attempt to the given file, "+
+			"for example to be commented on the pull request by a separate workflow.",
+	)
+	ingestRunCmd.PersistentFlags().StringVar(
+		&ingestRunParams.FlakeWindowStr, "flake-window", "14d",
+		"How far back to look for passes and failures of the failed tests, to flag them as known flakes "+
+			"in the summary. Requires OPENSEARCH_URL to be set.",
+	)
+	ingestRunCmd.PersistentFlags().BoolVar(
+		&ingestRunParams.CommentOnPR, "comment-on-pr", false,
+		"Post the failure summary of the latest attempt as a comment on the pull request the run was "+
+			"triggered for, grouped by owner. A previously posted summary is updated rather than duplicated. "+
+			"Requires a GitHub token which can write pull requests.",
+	)
+	ingestRunCmd.PersistentFlags().BoolVar(
+		&ingestRunParams.CheckRun, "check-run", false,
+		"Publish the failure summary of the latest attempt as a '"+analysisCheckName+"' check run on the head "+
+			"commit of the run. Check runs can only be created using the token of a GitHub App.",
+	)
+
+	rootCmd.AddCommand(ingestRunCmd)
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+type typeMigrateParams struct {
+	Mapping string
+}
+
+var (
+	migrateParams = &typeMigrateParams{}
+	migrateCmd    = &cobra.Command{
+		Use:   "migrate",
+		Short: "Add the fields of the mapping to existing indices",
+		Long: "Put the mapping given with --mapping onto the existing indices written into at OPENSEARCH_URL, so " +
+			"that fields added by newer versions of corgi are mapped as intended instead of dynamically. Fields " +
+			"which are already mapped differently can't be changed this way and need the indices to be reindexed.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if os.Getenv("OPENSEARCH_URL") == "" {
+				return fmt.Errorf("OPENSEARCH_URL must be set")
+			}
+
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			logger := log.NewLogger(rootParams.Verbose)
+
+			startAudit(cmd)
+
+			mapping, err := os.ReadFile(migrateParams.Mapping)
+			if err != nil {
+				logger.Error("Unable to read mapping", "err", err)
+				os.Exit(1)
+			}
+
+			cfg, err := opensearch.NewClientConfig()
+			if err != nil {
+				logger.Error("Unable to create opensearch client config", "err", err)
+				os.Exit(1)
+			}
+
+			client, err := opensearchgo.NewClient(cfg)
+			if err != nil {
+				logger.Error("Unable to create opensearch client", "err", err)
+				os.Exit(1)
+			}
+
+			indices := strings.Join(writtenIndexPatterns(), ",")
+
+			putCtx, cancel := util.WithTimeout(ctx, rootParams.Timeouts.Request)
+			defer cancel()
+
+			if err := opensearch.PutMapping(putCtx, client, indices, mapping); err != nil {
+				logger.Error("Unable to migrate mapping", "err", err)
+				os.Exit(1)
+			}
+
+			logger.Info("Migrated mapping", "indices", indices)
+		},
+	}
+)
+
+func init() {
+	migrateCmd.Flags().StringVar(
+		&migrateParams.Mapping, "mapping", "opensearch/mappings.json",
+		"Mapping to put onto the indices",
+	)
+
+	rootCmd.AddCommand(migrateCmd)
+}
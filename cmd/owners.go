@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/owners"
+	"github.com/isovalent/corgi/pkg/report"
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+type typeOwnersParams struct {
+	Test       string
+	WindowStr  string
+	Window     time.Duration
+	Repository string
+	Codeowners string
+	File       string
+}
+
+var (
+	ownersParams = &typeOwnersParams{}
+	ownersCmd    = &cobra.Command{
+		Use:   "owners",
+		Short: "Look up who owns a test and how it has been doing",
+		Long: "Look up the owners of a test, as recorded in the failure data of its past runs and, given " +
+			"its source file, according to a CODEOWNERS file, and print its recent failure stats.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if ownersParams.Test == "" {
+				return fmt.Errorf("--test is required")
+			}
+
+			window, err := util.ParseDuration(ownersParams.WindowStr)
+			if err != nil {
+				return fmt.Errorf("unable to parse window: %w", err)
+			}
+			ownersParams.Window = window
+
+			if (ownersParams.Codeowners == "") != (ownersParams.File == "") {
+				return fmt.Errorf("--codeowners and --file must be given together")
+			}
+
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			logger := log.NewLogger(rootParams.Verbose)
+
+			var codeownersOwners []string
+			if ownersParams.Codeowners != "" {
+				f, err := os.Open(ownersParams.Codeowners)
+				if err != nil {
+					logger.Error("Unable to open CODEOWNERS", "err", err)
+					os.Exit(1)
+				}
+				defer f.Close()
+
+				c, err := owners.ParseCodeowners(f)
+				if err != nil {
+					logger.Error("Unable to parse CODEOWNERS", "err", err)
+					os.Exit(1)
+				}
+				codeownersOwners = c.Owners(ownersParams.File)
+			}
+
+			opensearchCfg, err := opensearch.NewClientConfig()
+			if err != nil {
+				logger.Error("Unable to load OpenSearch configuration", "err", err)
+				os.Exit(1)
+			}
+
+			opsClient, err := opensearchgo.NewClient(opensearchCfg)
+			if err != nil {
+				logger.Error("Unable to create opensearch client", "err", err)
+				os.Exit(1)
+			}
+
+			until := time.Now()
+			cases, err := report.LoadTestcases(
+				ctx, opsClient, readIndex(types.TypeNameTestcase),
+				until.Add(-ownersParams.Window), until, ownersParams.Repository, "", ownersParams.Test,
+			)
+			if err != nil {
+				logger.Error("Unable to load test history", "err", err)
+				os.Exit(1)
+			}
+
+			printOwnership(report.NewTestOwnership(ownersParams.Test, cases), codeownersOwners)
+		},
+	}
+)
+
+// printOwnership prints the owners and failure stats of a test in a human readable form.
+func printOwnership(o *report.TestOwnership, codeownersOwners []string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "Test:\t%s\n", o.Test)
+	fmt.Fprintf(w, "Window:\t%s\n", ownersParams.WindowStr)
+
+	if len(o.Owners) == 0 {
+		fmt.Fprintf(w, "Owners (failure data):\tnone recorded\n")
+	}
+	for i, owner := range o.Owners {
+		label := ""
+		if i == 0 {
+			label = "Owners (failure data):"
+		}
+		fmt.Fprintf(w, "%s\t%s (%d testcases)\n", label, owner.Owner, owner.Testcases)
+	}
+
+	if ownersParams.Codeowners != "" {
+		names := "none"
+		if len(codeownersOwners) > 0 {
+			names = strings.Join(codeownersOwners, ", ")
+		}
+		fmt.Fprintf(w, "Owners (CODEOWNERS):\t%s\n", names)
+	}
+
+	rate := 0.0
+	if o.Runs > 0 {
+		rate = float64(o.Failures) / float64(o.Runs)
+	}
+	fmt.Fprintf(w, "Runs:\t%d\n", o.Runs)
+	fmt.Fprintf(w, "Failures:\t%d (%.1f%%)\n", o.Failures, rate*100)
+
+	if o.LastFailure != nil {
+		fmt.Fprintf(
+			w, "Last failure:\t%s %s\n",
+			o.LastFailure.WorkflowRun.CreatedAt.UTC().Format(time.RFC3339), o.LastFailure.WorkflowRun.Link,
+		)
+	}
+}
+
+func init() {
+	ownersCmd.PersistentFlags().StringVarP(
+		&ownersParams.Test, "test", "t", "",
+		"Name of the test to look up, such as no-errors-in-logs",
+	)
+	ownersCmd.PersistentFlags().StringVarP(
+		&ownersParams.WindowStr, "window", "w", "30d",
+		"Time window to look at, ending now, such as 30d or 24h",
+	)
+	ownersCmd.PersistentFlags().StringVarP(
+		&ownersParams.Repository, "repository", "r", "",
+		"Only look at workflow runs of the given repository, such as cilium/cilium",
+	)
+	ownersCmd.PersistentFlags().StringVar(
+		&ownersParams.Codeowners, "codeowners", "",
+		"Path to a CODEOWNERS file to look up the owners of --file in",
+	)
+	ownersCmd.PersistentFlags().StringVar(
+		&ownersParams.File, "file", "",
+		"Path of the source file of the test, relative to the root of the repository",
+	)
+
+	rootCmd.AddCommand(ownersCmd)
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"log/slog"
+
+	"github.com/isovalent/corgi/pkg/quota"
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// quotas tracks the usage of each repository when --quotas-file is given.
+var quotas *quota.Tracker
+
+// initQuotas loads the quotas of each repository and, if --quota-state-file is given,
+// their usage so far today, which is saved once everything was flushed.
+func initQuotas() error {
+	config, err := quota.LoadConfig(rootParams.QuotasFile)
+	if err != nil {
+		return err
+	}
+
+	if rootParams.QuotaStateFile == "" {
+		quotas = quota.NewTracker(config)
+		return nil
+	}
+
+	quotas, err = quota.LoadTracker(config, rootParams.QuotaStateFile)
+	if err != nil {
+		return err
+	}
+
+	flushHooks = append(flushHooks, func() error {
+		return quotas.Save(rootParams.QuotaStateFile)
+	})
+
+	return nil
+}
+
+// quotaExhausted returns true if the repository of the given run exhausted one of its
+// daily budgets, in which case the run should be left to be ingested another day.
+func quotaExhausted(logger *slog.Logger, run *types.WorkflowRun) bool {
+	if quotas == nil {
+		return false
+	}
+
+	reason := quotas.Exhausted(run.Repository.FullName)
+	if reason == "" {
+		return false
+	}
+
+	logger.Warn(
+		"Daily quota of repository is exhausted, skipping workflow run",
+		"workflow-id", run.ID, "repository", run.Repository.FullName, "quota", reason,
+	)
+
+	return true
+}
+
+// recordQuotaUsage records the documents written and artifact bytes downloaded for the
+// given run against the quotas of its repository.
+func recordQuotaUsage(result *runResult) {
+	if quotas == nil {
+		return
+	}
+
+	quotas.Add(result.run.Repository.FullName, int64(result.documents()), result.artifactBytes)
+}
+
+// limitRunsPerPoll returns the runs to pull in this polling cycle, in order, according
+// to the quota of the given repository, along with the runs left for the next cycle.
+func limitRunsPerPoll(repo string, runs []*types.WorkflowRun) (pull, deferred []*types.WorkflowRun) {
+	if quotas == nil {
+		return runs, nil
+	}
+
+	max := quotas.Limits(repo).MaxRunsPerPoll
+	if max <= 0 || len(runs) <= max {
+		return runs, nil
+	}
+
+	return runs[:max], runs[max:]
+}
@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	gh "github.com/isovalent/corgi/pkg/github"
+	"github.com/isovalent/corgi/pkg/junit"
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/metrics"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+type typeReingestParams struct {
+	Repository string
+	RunIDs     []int64
+	Force      bool
+}
+
+// runDocumentTypes are the types of the documents written for a workflow run, which are
+// those embedding types.WorkflowRun.
+var runDocumentTypes = []types.TypeName{
+	types.TypeNameWorkflowRun,
+	types.TypeNameJobRun,
+	types.TypeNameStepRun,
+	types.TypeNameTestsuite,
+	types.TypeNameTestcase,
+	types.TypeNameIngestError,
+	types.TypeNameArtifact,
+	types.TypeNameSysdump,
+	types.TypeNameLogSignature,
+	types.TypeNameTestCountDrop,
+}
+
+var (
+	reingestParams = &typeReingestParams{}
+	reingestCmd    = &cobra.Command{
+		Use:   "reingest",
+		Short: "Ingest specific workflow runs again",
+		Long: "Ingest every attempt of the given workflow runs again, for example after a parser bug was fixed. " +
+			"Documents are written with the same IDs as before, overwriting them. Documents which wouldn't be " +
+			"written again, such as testcases whose name was parsed differently, are only removed with --force, " +
+			"which deletes every document of the runs from OPENSEARCH_URL once they were pulled and parsed again, " +
+			"before writing them with --send-bulk. Runs whose artifacts expired aren't deleted. " +
+			"With --id-prefix, only the documents of that deployment are deleted. Tests are " +
+			"parsed using the defaults of the 'workflow runs' command.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(reingestParams.RunIDs) == 0 {
+				return fmt.Errorf("--run-id is required")
+			}
+			if len(strings.Split(reingestParams.Repository, "/")) != 2 {
+				return fmt.Errorf("expected repository in owner/name format, got '%s'", reingestParams.Repository)
+			}
+			// Without --send-bulk the documents would only be written to stdout, leaving the
+			// runs deleted until the output is sent.
+			if reingestParams.Force && !rootParams.SendBulk {
+				return fmt.Errorf("--force requires --send-bulk")
+			}
+
+			return compileTestParams()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			logger := log.NewLogger(rootParams.Verbose)
+
+			repoParts := strings.Split(reingestParams.Repository, "/")
+
+			startIngestStats(cmd)
+			startAudit(cmd)
+
+			token, err := gh.GetGitHubAuthToken()
+			if err != nil {
+				logger.Error("Unable to load GitHub token", "err", err)
+				os.Exit(1)
+			}
+
+			client, err := gh.NewGitHubClient(token, logger, rootParams.Timeouts.Request)
+			if err != nil {
+				logger.Error("Unable to create new GitHub Client", "err", err)
+				os.Exit(1)
+			}
+
+			// Pull every run before deleting anything, so that a typo in a run ID doesn't
+			// leave the other runs deleted but not ingested.
+			runs := []*types.WorkflowRun{}
+			for _, runID := range reingestParams.RunIDs {
+				attempts, err := gh.GetWorkflowRunAttempts(ctx, logger, client, repoParts[0], repoParts[1], runID)
+				if err != nil {
+					logger.Error("Unable to pull workflow run", "workflow-id", runID, "err", err)
+					os.Exit(1)
+				}
+				runs = append(runs, attempts...)
+			}
+
+			logger.Info("Ingesting workflow runs", "runs", len(reingestParams.RunIDs), "attempts", len(runs))
+
+			runsCh := make(chan *types.WorkflowRun, len(runs))
+			for _, run := range runs {
+				runsCh <- run
+			}
+			close(runsCh)
+
+			// Pull and parse every run before deleting anything, so that the documents of
+			// the runs are only replaced once the new ones are complete.
+			results := []*runResult{}
+			prefetched := prefetchArtifacts(ctx, logger, client, runsCh, workflowRunsParams.ArtifactPrefetchConcurrency)
+			for prefetch := range prefetched {
+				results = append(results, pullRun(ctx, logger, client, prefetch))
+			}
+
+			if reingestParams.Force {
+				for _, result := range results {
+					if reason := incompleteReason(result); reason != "" {
+						logger.Error(
+							"Refusing to delete documents of workflow run, as they can't be replaced. "+
+								"Ingest it again without --force.",
+							"workflow-id", result.run.ID, "run-attempt", result.run.RunAttempt, "reason", reason,
+						)
+						os.Exit(1)
+					}
+				}
+
+				cfg, err := opensearch.NewClientConfig()
+				if err != nil {
+					logger.Error("Unable to create opensearch client config", "err", err)
+					os.Exit(1)
+				}
+
+				opsClient, err := opensearchgo.NewClient(cfg)
+				if err != nil {
+					logger.Error("Unable to create opensearch client", "err", err)
+					os.Exit(1)
+				}
+
+				for _, runID := range reingestParams.RunIDs {
+					deleteCtx, cancel := util.WithTimeout(ctx, rootParams.Timeouts.BulkWrite)
+					deleted, err := opensearch.DeleteByQuery(
+						deleteCtx, opsClient, readIndex(runDocumentTypes...), runDocumentsQuery(runID),
+					)
+					cancel()
+					if err != nil {
+						logger.Error("Unable to delete documents of workflow run", "workflow-id", runID, "err", err)
+						os.Exit(1)
+					}
+
+					metrics.Add(metrics.CounterDocumentsDeleted, int64(deleted))
+					auditRun(runID)
+					logger.Info("Deleted documents of workflow run", "workflow-id", runID, "deleted", deleted)
+				}
+			}
+
+			for _, result := range results {
+				writeRunResult(ctx, logger, result)
+			}
+
+			metrics.LogSummary(logger)
+		},
+	}
+)
+
+// incompleteReason returns why the documents pulled for the given run don't replace the
+// ones already indexed, such as when its artifacts expired, or an empty string if they do.
+func incompleteReason(result *runResult) string {
+	if result.aborted {
+		return "its ingest was aborted"
+	}
+
+	for _, ingestError := range result.ingestErrors {
+		if ingestError.Reason == junit.SkipReasonArtifactExpired {
+			return fmt.Sprintf("its artifact %s expired", ingestError.ArtifactName)
+		}
+	}
+
+	return ""
+}
+
+// runDocumentsQuery returns a query matching the documents of the given run.
+func runDocumentsQuery(runID int64) map[string]any {
+	return deploymentQuery(map[string]any{"term": map[string]any{"workflow_id": runID}})
+}
+
+// deploymentQuery returns a query matching the documents matching every given filter
+// which were written with the configured ID prefix, so that the documents of other
+// deployments sharing the index are left alone.
+func deploymentQuery(filters ...map[string]any) map[string]any {
+	if rootParams.IDPrefix != "" {
+		filters = append(filters, map[string]any{"prefix": map[string]any{"_id": rootParams.IDPrefix + "-"}})
+	}
+
+	return map[string]any{"bool": map[string]any{"filter": filters}}
+}
+
+func init() {
+	reingestCmd.Flags().StringVarP(
+		&reingestParams.Repository, "repository", "r", "cilium/cilium",
+		"Repository of the workflow runs in owner/name format",
+	)
+	reingestCmd.Flags().Int64SliceVar(
+		&reingestParams.RunIDs, "run-id", nil,
+		"ID of a workflow run to ingest again. May be given multiple times.",
+	)
+	reingestCmd.Flags().BoolVar(
+		&reingestParams.Force, "force", false,
+		"Delete every existing document of the runs before ingesting them. Requires --send-bulk.",
+	)
+
+	rootCmd.AddCommand(reingestCmd)
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// TestRunDocumentTypes checks that every document type embedding types.WorkflowRun is
+// deleted by reingest --force. The struct of each type is named like its TypeName
+// constant, such as Testcase for TypeNameTestcase.
+func TestRunDocumentTypes(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "../pkg/types/types.go", nil, 0)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	embeds := map[string][]string{}
+	typeNames := map[string]types.TypeName{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.TypeSpec:
+			if st, ok := n.Type.(*ast.StructType); ok {
+				embeds[n.Name.Name] = []string{}
+				for _, f := range st.Fields.List {
+					if len(f.Names) > 0 {
+						continue
+					}
+					if star, ok := f.Type.(*ast.StarExpr); ok {
+						f.Type = star.X
+					}
+					if ident, ok := f.Type.(*ast.Ident); ok {
+						embeds[n.Name.Name] = append(embeds[n.Name.Name], ident.Name)
+					}
+				}
+			}
+		case *ast.ValueSpec:
+			ident, ok := n.Type.(*ast.Ident)
+			if !ok || ident.Name != "TypeName" {
+				break
+			}
+			for i, name := range n.Names {
+				value, _ := strconv.Unquote(n.Values[i].(*ast.BasicLit).Value)
+				typeNames[strings.TrimPrefix(name.Name, "TypeName")] = types.TypeName(value)
+			}
+		}
+		return true
+	})
+
+	var perRun func(string) bool
+	perRun = func(name string) bool {
+		return name == "WorkflowRun" || slices.ContainsFunc(embeds[name], perRun)
+	}
+
+	assert.NotEmpty(t, typeNames)
+	for name, typeName := range typeNames {
+		if !assert.Contains(t, embeds, name, "no struct for %s", typeName) {
+			continue
+		}
+		assert.Equal(t, perRun(name), slices.Contains(runDocumentTypes, typeName), typeName)
+	}
+}
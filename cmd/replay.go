@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/isovalent/corgi/pkg/archive"
+	gh "github.com/isovalent/corgi/pkg/github"
+	"github.com/isovalent/corgi/pkg/junit"
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/metrics"
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+type typeReplayParams struct {
+	FromArchive string
+	Repository  string
+	RunIDs      []int64
+}
+
+var (
+	replayParams = &typeReplayParams{}
+	replayCmd    = &cobra.Command{
+		Use:   "replay",
+		Short: "Parse archived artifacts of workflow runs again",
+		Long: "Parse the artifacts of workflow runs stored with 'workflow runs --archive' again, for example after " +
+			"a parser was improved, even once GitHub deleted them. Runs, test suites, testcases and the other " +
+			"documents parsed from artifacts are written with the same IDs as before, overwriting them, while " +
+			"jobs and steps are left as they are. Artifacts are routed and parsed using the defaults of the " +
+			"'workflow runs' command. Team owners aren't expanded and failures aren't embedded.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if replayParams.FromArchive == "" {
+				return fmt.Errorf("--from-archive is required")
+			}
+			if len(strings.Split(replayParams.Repository, "/")) != 2 {
+				return fmt.Errorf("expected repository in owner/name format, got '%s'", replayParams.Repository)
+			}
+
+			return compileTestParams()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			logger := log.NewLogger(rootParams.Verbose)
+
+			startIngestStats(cmd)
+			startAudit(cmd)
+
+			arch, err := archive.New(replayParams.FromArchive)
+			if err != nil {
+				logger.Error("Unable to open archive", "err", err)
+				os.Exit(1)
+			}
+
+			prefixes := []string{replayParams.Repository + "/"}
+			if len(replayParams.RunIDs) > 0 {
+				prefixes = prefixes[:0]
+				for _, runID := range replayParams.RunIDs {
+					prefixes = append(prefixes, archive.RunPrefix(replayParams.Repository, runID))
+				}
+			}
+
+			keys := []string{}
+			for _, prefix := range prefixes {
+				listCtx, cancel := util.WithTimeout(ctx, rootParams.Timeouts.Request)
+				k, err := arch.List(listCtx, prefix)
+				cancel()
+				if err != nil {
+					logger.Error("Unable to list archived workflow runs", "prefix", prefix, "err", err)
+					os.Exit(1)
+				}
+				keys = append(keys, k...)
+			}
+
+			runs := archive.Runs(keys)
+			logger.Info("Replaying archived workflow runs", "attempts", len(runs))
+
+			for _, archived := range runs {
+				writeRunResult(ctx, logger, replayRun(ctx, logger, arch, archived))
+			}
+
+			metrics.LogSummary(logger)
+		},
+	}
+)
+
+// replayRun parses the archived artifacts of the given workflow run attempt again.
+func replayRun(ctx context.Context, logger *slog.Logger, arch archive.Archive, archived archive.ArchivedRun) *runResult {
+	getCtx, cancel := util.WithTimeout(ctx, rootParams.Timeouts.Request)
+	run, err := archive.GetRun(getCtx, arch, archived.Key)
+	cancel()
+	if err != nil {
+		logger.Error("Unable to get archived workflow run", "key", archived.Key, "err", err)
+		os.Exit(1)
+	}
+
+	runLogger := logger.With("workflow-id", run.ID, "run-attempt", run.RunAttempt)
+
+	opts := parseOptions()
+
+	parsed := &gh.ArtifactResults{}
+	for _, a := range archived.Artifacts {
+		parser := gh.RouteArtifact(workflowRunsParams.ArtifactRoutes, a.Name)
+		if parser == "" {
+			continue
+		}
+
+		err := replayArtifact(ctx, runLogger, arch, run, a, parser, opts, parsed)
+		if errors.Is(err, junit.ErrParseErrorBudgetExceeded) {
+			runLogger.Error("Too many artifact files of workflow run couldn't be parsed, aborting its replay", "err", err)
+
+			return abortedRunResult(run, parsed.IngestErrors, junit.SkipReasonParseErrorBudgetExceeded, err)
+		}
+		if err != nil {
+			runLogger.Error("Unable to replay archived artifact", "artifact", a.Name, "err", err)
+			os.Exit(1)
+		}
+	}
+
+	run.SetResultMismatch(parsed.Suites)
+
+	return &runResult{
+		run:           run,
+		suites:        parsed.Suites,
+		cases:         parsed.Cases,
+		sysdumps:      parsed.Sysdumps,
+		logSignatures: parsed.LogSignatures,
+		ingestErrors:  parsed.IngestErrors,
+	}
+}
+
+// replayArtifact downloads the given archived artifact of a workflow run and parses it
+// with the given parser, adding the documents to results.
+func replayArtifact(
+	ctx context.Context,
+	logger *slog.Logger,
+	arch archive.Archive,
+	run *types.WorkflowRun,
+	a archive.ArchivedArtifact,
+	parser string,
+	opts *junit.Options,
+	results *gh.ArtifactResults,
+) error {
+	tmpFile, err := os.CreateTemp("", fmt.Sprintf("%s-%d-*", a.Name, run.ID))
+	if err != nil {
+		return fmt.Errorf("unable to create temp file: %w", err)
+	}
+	defer func() {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+	}()
+
+	downloadCtx, cancel := util.WithTimeout(ctx, rootParams.Timeouts.Download)
+	defer cancel()
+
+	r, err := arch.Get(downloadCtx, a.Key)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmpFile, r)
+	r.Close()
+	if err != nil {
+		return fmt.Errorf("unable to download archived artifact %s: %w", a.Key, err)
+	}
+
+	zipReader, err := zip.OpenReader(tmpFile.Name())
+	if err != nil {
+		return fmt.Errorf("unable to create zip reader for file %s: %w", tmpFile.Name(), err)
+	}
+	defer zipReader.Close()
+
+	artifact := &junit.Artifact{
+		ID:   a.ID,
+		Name: a.Name,
+		URL: fmt.Sprintf(
+			"https://github.com/%s/%s/actions/runs/%d/artifacts/%d",
+			run.Repository.Owner.Login, run.Repository.Name, run.ID, a.ID,
+		),
+	}
+
+	return gh.ParseArtifact(ctx, logger, zipReader, run, artifact, parser, rootParams.Timeouts, opts, results)
+}
+
+func init() {
+	replayCmd.Flags().StringVar(
+		&replayParams.FromArchive, "from-archive", "",
+		"Bucket the artifacts were archived in with 'workflow runs --archive', such as s3://corgi-artifacts",
+	)
+	replayCmd.Flags().StringVarP(
+		&replayParams.Repository, "repository", "r", "cilium/cilium",
+		"Repository of the workflow runs in owner/name format",
+	)
+	replayCmd.Flags().Int64SliceVar(
+		&replayParams.RunIDs, "run-id", nil,
+		"ID of a workflow run to replay. May be given multiple times. Every archived run of the repository "+
+			"is replayed if none is given.",
+	)
+
+	rootCmd.AddCommand(replayCmd)
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/report"
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+const (
+	// reportPatternMinJobs is the minimum number of jobs of a segment for its failure
+	// pattern to be shown in reports, so that rarely used runners don't stand out.
+	reportPatternMinJobs = 20
+	// reportPatternMinLift is how many times more often than all jobs the jobs of a
+	// segment need to fail for its failure pattern to be shown in reports.
+	reportPatternMinLift = 1.5
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Render reports from the data in OpenSearch",
+}
+
+// readIndex returns the comma-separated index patterns holding documents of the given types.
+func readIndex(typs ...types.TypeName) string {
+	patterns := []string{}
+	for _, typ := range typs {
+		if pattern := opensearch.IndexPattern(indexFor(typ)); !slices.Contains(patterns, pattern) {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	return strings.Join(patterns, ",")
+}
+
+// loadReportPatterns adds the notable failure patterns of the jobs within the window of
+// the given report to it.
+func loadReportPatterns(ctx context.Context, client *opensearchgo.Client, r *report.Report) error {
+	patterns, err := report.LoadPatterns(
+		ctx, client, readIndex(types.TypeNameJobRun), r.Since, r.Until, r.Repository, "", reportPatternMinJobs,
+	)
+	if err != nil {
+		return err
+	}
+	r.Patterns = report.NotablePatterns(patterns, reportPatternMinLift)
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+}
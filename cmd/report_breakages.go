@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/report"
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+type typeReportBreakagesParams struct {
+	WindowStr  string
+	Window     time.Duration
+	Repository string
+	Branch     string
+	JSON       bool
+}
+
+// breakageJSON is a Breakage as printed with --json.
+type breakageJSON struct {
+	Workflow      string         `json:"workflow"`
+	Test          string         `json:"test"`
+	Owners        []string       `json:"owners"`
+	FailedAt      time.Time      `json:"failed_at"`
+	FailedRun     string         `json:"failed_run"`
+	LastPassedRun string         `json:"last_passed_run"`
+	LastPassedSHA string         `json:"last_passed_sha"`
+	FailedSHA     string         `json:"failed_sha"`
+	Commits       []types.Commit `json:"commits"`
+}
+
+var (
+	reportBreakagesParams = &typeReportBreakagesParams{}
+	reportBreakagesCmd    = &cobra.Command{
+		Use:   "breakages",
+		Short: "Print the tests which started failing in push runs, with the commits pushed in between",
+		Long: "Print the tests which failed in a push run within the window after passing in the previous " +
+			"push run of the same workflow which ran them, most recent first, along with the commits pushed " +
+			"in between, to find which merge broke the branch. Commits are only known for runs ingested " +
+			"with --push-commits. Only the latest attempt of each run counts, so tests which passed when " +
+			"the run was retried aren't listed.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			window, err := util.ParseDuration(reportBreakagesParams.WindowStr)
+			if err != nil {
+				return fmt.Errorf("unable to parse window: %w", err)
+			}
+			reportBreakagesParams.Window = window
+
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			logger := log.NewLogger(rootParams.Verbose)
+
+			opensearchCfg, err := opensearch.NewClientConfig()
+			if err != nil {
+				logger.Error("Unable to load OpenSearch configuration", "err", err)
+				os.Exit(1)
+			}
+
+			opsClient, err := opensearchgo.NewClient(opensearchCfg)
+			if err != nil {
+				logger.Error("Unable to create opensearch client", "err", err)
+				os.Exit(1)
+			}
+
+			until := time.Now()
+			breakages, err := report.LoadBreakages(
+				ctx, opsClient, readIndex(types.TypeNameWorkflowRun, types.TypeNameTestcase),
+				until.Add(-reportBreakagesParams.Window), until,
+				reportBreakagesParams.Repository, reportBreakagesParams.Branch,
+			)
+			if err != nil {
+				logger.Error("Unable to load breakages", "err", err)
+				os.Exit(1)
+			}
+
+			if reportBreakagesParams.JSON {
+				if err := printBreakagesJSON(breakages); err != nil {
+					logger.Error("Unable to print breakages", "err", err)
+					os.Exit(1)
+				}
+				return
+			}
+
+			printBreakages(breakages)
+		},
+	}
+)
+
+// shortSHA returns the abbreviated form of the given commit SHA.
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+
+	return sha
+}
+
+func printBreakages(breakages []report.Breakage) {
+	fmt.Printf("%d tests started failing in push runs in the last %s\n\n", len(breakages), reportBreakagesParams.WindowStr)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "FAILED AT\tWORKFLOW\tTEST\tOWNERS\tCOMMITS\tRUN")
+	for _, b := range breakages {
+		commits := make([]string, 0, len(b.Commits))
+		for _, c := range b.Commits {
+			commits = append(commits, shortSHA(c.SHA)+" ("+c.Author.Login+")")
+		}
+		if len(commits) == 0 {
+			commits = append(commits, shortSHA(b.LastPassed.HeadSHA)+".."+shortSHA(b.Run.HeadSHA))
+		}
+
+		fmt.Fprintf(
+			w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			b.Run.CreatedAt.UTC().Format(time.DateTime), b.Workflow, b.Test, strings.Join(b.Owners, ","),
+			strings.Join(commits, ","), b.Run.Link,
+		)
+	}
+}
+
+func printBreakagesJSON(breakages []report.Breakage) error {
+	entries := make([]breakageJSON, 0, len(breakages))
+	for _, b := range breakages {
+		entries = append(entries, breakageJSON{
+			Workflow:      b.Workflow,
+			Test:          b.Test,
+			Owners:        b.Owners,
+			FailedAt:      b.Run.CreatedAt,
+			FailedRun:     b.Run.Link,
+			LastPassedRun: b.LastPassed.Link,
+			LastPassedSHA: b.LastPassed.HeadSHA,
+			FailedSHA:     b.Run.HeadSHA,
+			Commits:       b.Commits,
+		})
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(entries)
+}
+
+func init() {
+	reportBreakagesCmd.PersistentFlags().StringVarP(
+		&reportBreakagesParams.WindowStr, "window", "w", "7d",
+		"Time window to look for breakages in, ending now, such as 7d",
+	)
+	reportBreakagesCmd.PersistentFlags().StringVarP(
+		&reportBreakagesParams.Repository, "repository", "r", "",
+		"Only look at workflow runs of the given repository, such as cilium/cilium",
+	)
+	reportBreakagesCmd.PersistentFlags().StringVarP(
+		&reportBreakagesParams.Branch, "branch", "b", "main",
+		"Only look at push runs on the given branch. Empty includes all branches.",
+	)
+	reportBreakagesCmd.PersistentFlags().BoolVar(
+		&reportBreakagesParams.JSON, "json", false,
+		"Print the breakages as a JSON array, for consumption by other tools",
+	)
+
+	reportCmd.AddCommand(reportBreakagesCmd)
+}
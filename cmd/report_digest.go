@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/report"
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+type typeReportDigestParams struct {
+	WindowStr  string
+	Window     time.Duration
+	Repository string
+	Branch     string
+	Limit      int
+	JSON       bool
+}
+
+// digestEntryJSON is a DigestEntry as printed with --json.
+type digestEntryJSON struct {
+	Test        string    `json:"test"`
+	Signature   string    `json:"signature"`
+	Occurrences int       `json:"occurrences"`
+	Runs        int       `json:"runs"`
+	Owners      []string  `json:"owners"`
+	LastSeen    time.Time `json:"last_seen"`
+	LastRun     string    `json:"last_run"`
+}
+
+var (
+	reportDigestParams = &typeReportDigestParams{}
+	reportDigestCmd    = &cobra.Command{
+		Use:   "digest",
+		Short: "Print the unique failures of the last day",
+		Long: "Collapse the failures within the window into unique pairs of test and failure signature, " +
+			"with how often and in how many workflow runs each occurred and who owns it, most frequent first, " +
+			"so a single test failing many times doesn't drown out everything else. The signature is the " +
+			"infrastructure signature the failure matched, or else its normalized failure message.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			window, err := util.ParseDuration(reportDigestParams.WindowStr)
+			if err != nil {
+				return fmt.Errorf("unable to parse window: %w", err)
+			}
+			reportDigestParams.Window = window
+
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			logger := log.NewLogger(rootParams.Verbose)
+
+			opensearchCfg, err := opensearch.NewClientConfig()
+			if err != nil {
+				logger.Error("Unable to load OpenSearch configuration", "err", err)
+				os.Exit(1)
+			}
+
+			opsClient, err := opensearchgo.NewClient(opensearchCfg)
+			if err != nil {
+				logger.Error("Unable to create opensearch client", "err", err)
+				os.Exit(1)
+			}
+
+			until := time.Now()
+			cases, err := report.LoadFailures(
+				ctx, opsClient, readIndex(types.TypeNameTestcase), until.Add(-reportDigestParams.Window), until,
+				reportDigestParams.Repository, reportDigestParams.Branch,
+			)
+			if err != nil {
+				logger.Error("Unable to load failures", "err", err)
+				os.Exit(1)
+			}
+
+			digest := report.NewDigest(cases)
+			if reportDigestParams.Limit > 0 && len(digest) > reportDigestParams.Limit {
+				digest = digest[:reportDigestParams.Limit]
+			}
+
+			if reportDigestParams.JSON {
+				if err := printDigestJSON(digest); err != nil {
+					logger.Error("Unable to print digest", "err", err)
+					os.Exit(1)
+				}
+				return
+			}
+
+			printDigest(digest, len(cases))
+		},
+	}
+)
+
+func printDigest(digest []*report.DigestEntry, failures int) {
+	fmt.Printf("%d failures, %d unique in the last %s\n\n", failures, len(digest), reportDigestParams.WindowStr)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "COUNT\tRUNS\tTEST\tSIGNATURE\tOWNERS\tLAST RUN")
+	for _, e := range digest {
+		fmt.Fprintf(
+			w, "%d\t%d\t%s\t%s\t%s\t%s\n",
+			e.Occurrences, e.Runs, e.Test, e.Signature, strings.Join(e.Owners, ","), e.Last.WorkflowRun.Link,
+		)
+	}
+}
+
+func printDigestJSON(digest []*report.DigestEntry) error {
+	entries := make([]digestEntryJSON, 0, len(digest))
+	for _, e := range digest {
+		entries = append(entries, digestEntryJSON{
+			Test:        e.Test,
+			Signature:   e.Signature,
+			Occurrences: e.Occurrences,
+			Runs:        e.Runs,
+			Owners:      e.Owners,
+			LastSeen:    e.Last.WorkflowRun.CreatedAt,
+			LastRun:     e.Last.WorkflowRun.Link,
+		})
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(entries)
+}
+
+func init() {
+	reportDigestCmd.PersistentFlags().StringVarP(
+		&reportDigestParams.WindowStr, "window", "w", "24h",
+		"Time window to collapse failures over, ending now, such as 24h or 7d",
+	)
+	reportDigestCmd.PersistentFlags().StringVarP(
+		&reportDigestParams.Repository, "repository", "r", "",
+		"Only look at workflow runs of the given repository, such as cilium/cilium",
+	)
+	reportDigestCmd.PersistentFlags().StringVarP(
+		&reportDigestParams.Branch, "branch", "b", "",
+		"Only look at workflow runs on the given branch. Empty includes all branches.",
+	)
+	reportDigestCmd.PersistentFlags().IntVar(
+		&reportDigestParams.Limit, "limit", 0,
+		"Maximum number of unique failures to print. Zero prints all of them.",
+	)
+	reportDigestCmd.PersistentFlags().BoolVar(
+		&reportDigestParams.JSON, "json", false,
+		"Print the digest as a JSON array, for consumption by other tools",
+	)
+
+	reportCmd.AddCommand(reportDigestCmd)
+}
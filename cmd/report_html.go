@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/report"
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+type typeReportHTMLParams struct {
+	WindowStr  string
+	Window     time.Duration
+	OutputDir  string
+	Repository string
+	MaxFlakes  int
+}
+
+var (
+	reportHTMLParams = &typeReportHTMLParams{}
+	reportHTMLCmd    = &cobra.Command{
+		Use:   "html",
+		Short: "Render a self-contained HTML report of pass rates, flakes, owners and durations",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			window, err := util.ParseDuration(reportHTMLParams.WindowStr)
+			if err != nil {
+				return fmt.Errorf("unable to parse window: %w", err)
+			}
+			reportHTMLParams.Window = window
+
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			logger := log.NewLogger(rootParams.Verbose)
+
+			opensearchCfg, err := opensearch.NewClientConfig()
+			if err != nil {
+				logger.Error("Unable to load OpenSearch configuration", "err", err)
+				os.Exit(1)
+			}
+
+			opsClient, err := opensearchgo.NewClient(opensearchCfg)
+			if err != nil {
+				logger.Error("Unable to create opensearch client", "err", err)
+				os.Exit(1)
+			}
+
+			until := time.Now()
+			since := until.Add(-reportHTMLParams.Window)
+
+			r, err := report.Load(
+				ctx, opsClient, readIndex(types.TypeNameWorkflowRun, types.TypeNameTestcase),
+				since, until, reportHTMLParams.Repository, reportHTMLParams.MaxFlakes,
+			)
+			if err != nil {
+				logger.Error("Unable to load report", "err", err)
+				os.Exit(1)
+			}
+
+			if err := loadReportPatterns(ctx, opsClient, r); err != nil {
+				logger.Error("Unable to load failure patterns", "err", err)
+				os.Exit(1)
+			}
+
+			if err := os.MkdirAll(reportHTMLParams.OutputDir, 0o755); err != nil {
+				logger.Error("Unable to create output directory", "err", err)
+				os.Exit(1)
+			}
+
+			path := filepath.Join(reportHTMLParams.OutputDir, "index.html")
+			f, err := os.Create(path)
+			if err != nil {
+				logger.Error("Unable to create report", "err", err)
+				os.Exit(1)
+			}
+			defer f.Close()
+
+			if err := report.RenderHTML(f, r); err != nil {
+				logger.Error("Unable to render report", "err", err)
+				os.Exit(1)
+			}
+
+			logger.Info("Wrote report", "path", path)
+		},
+	}
+)
+
+func init() {
+	reportHTMLCmd.PersistentFlags().StringVarP(
+		&reportHTMLParams.WindowStr, "window", "w", "7d",
+		"Time window to report on, ending now, such as 7d or 24h",
+	)
+	reportHTMLCmd.PersistentFlags().StringVarP(
+		&reportHTMLParams.OutputDir, "output", "o", "report",
+		"Directory to write the report to, as index.html",
+	)
+	reportHTMLCmd.PersistentFlags().StringVarP(
+		&reportHTMLParams.Repository, "repository", "r", "",
+		"Only report on workflow runs of the given repository, such as cilium/cilium",
+	)
+	reportHTMLCmd.PersistentFlags().IntVar(
+		&reportHTMLParams.MaxFlakes, "max-flakes", 25,
+		"Maximum number of flaky tests to list",
+	)
+
+	reportCmd.AddCommand(reportHTMLCmd)
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	"github.com/isovalent/corgi/pkg/jira"
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/report"
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+type typeReportJiraParams struct {
+	WindowStr   string
+	Window      time.Duration
+	Repository  string
+	Project     string
+	IssueType   string
+	Fields      map[string]string
+	MinFailures int
+	MaxFlakes   int
+}
+
+var (
+	reportJiraParams = &typeReportJiraParams{}
+	reportJiraCmd    = &cobra.Command{
+		Use:   "jira",
+		Short: "Create or update a Jira issue for each flaky test",
+		Long: "Create a Jira issue for each test which both failed and passed within the window, or update " +
+			"the issue filed previously with the latest numbers. The Jira instance is configured through " +
+			"JIRA_URL, JIRA_USER and JIRA_TOKEN. If JIRA_USER is empty, JIRA_TOKEN is used as a personal " +
+			"access token.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			window, err := util.ParseDuration(reportJiraParams.WindowStr)
+			if err != nil {
+				return fmt.Errorf("unable to parse window: %w", err)
+			}
+			reportJiraParams.Window = window
+
+			if reportJiraParams.Project == "" {
+				return fmt.Errorf("--project is required")
+			}
+
+			for field, value := range reportJiraParams.Fields {
+				if !slices.Contains(jira.FlakeValues, value) {
+					return fmt.Errorf("unknown value %q for field %s, expected one of %v", value, field, jira.FlakeValues)
+				}
+			}
+
+			if os.Getenv("JIRA_URL") == "" {
+				return fmt.Errorf("JIRA_URL is required")
+			}
+
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			logger := log.NewLogger(rootParams.Verbose)
+
+			token, err := util.NewSecret("JIRA_TOKEN")
+			if err != nil {
+				logger.Error("Unable to load Jira token", "err", err)
+				os.Exit(1)
+			}
+			jiraClient := jira.NewClient(os.Getenv("JIRA_URL"), os.Getenv("JIRA_USER"), token)
+
+			opensearchCfg, err := opensearch.NewClientConfig()
+			if err != nil {
+				logger.Error("Unable to load OpenSearch configuration", "err", err)
+				os.Exit(1)
+			}
+
+			opsClient, err := opensearchgo.NewClient(opensearchCfg)
+			if err != nil {
+				logger.Error("Unable to create opensearch client", "err", err)
+				os.Exit(1)
+			}
+
+			until := time.Now()
+			since := until.Add(-reportJiraParams.Window)
+
+			r, err := report.Load(
+				ctx, opsClient, readIndex(types.TypeNameWorkflowRun, types.TypeNameTestcase),
+				since, until, reportJiraParams.Repository, reportJiraParams.MaxFlakes,
+			)
+			if err != nil {
+				logger.Error("Unable to load report", "err", err)
+				os.Exit(1)
+			}
+
+			cfg := jira.FlakeConfig{
+				Project:    reportJiraParams.Project,
+				IssueType:  reportJiraParams.IssueType,
+				Repository: reportJiraParams.Repository,
+				Fields:     reportJiraParams.Fields,
+			}
+
+			failed := false
+			for _, flake := range r.Flakes {
+				if flake.Failures < reportJiraParams.MinFailures {
+					continue
+				}
+
+				l := logger.With("test", flake.Name)
+
+				key, created, err := jira.SyncFlake(ctx, jiraClient, cfg, flake)
+				if err != nil {
+					l.Error("Unable to sync Jira issue", "err", err)
+					failed = true
+					continue
+				}
+
+				if created {
+					l.Info("Created Jira issue", "key", key)
+				} else {
+					l.Info("Updated Jira issue", "key", key)
+				}
+			}
+
+			if failed {
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func init() {
+	reportJiraCmd.PersistentFlags().StringVarP(
+		&reportJiraParams.WindowStr, "window", "w", "14d",
+		"Time window to look for flaky tests in, ending now, such as 14d or 24h",
+	)
+	reportJiraCmd.PersistentFlags().StringVarP(
+		&reportJiraParams.Repository, "repository", "r", "",
+		"Only look at workflow runs of the given repository, such as cilium/cilium",
+	)
+	reportJiraCmd.PersistentFlags().StringVar(
+		&reportJiraParams.Project, "project", "",
+		"Key of the Jira project to file issues in",
+	)
+	reportJiraCmd.PersistentFlags().StringVar(
+		&reportJiraParams.IssueType, "issue-type", "Bug",
+		"Name of the type of issues to create",
+	)
+	reportJiraCmd.PersistentFlags().StringToStringVar(
+		&reportJiraParams.Fields, "field", map[string]string{},
+		"Additional issue fields to set, mapping field IDs to one of name, owners, teams, components, "+
+			"failures, passes, failure_rate or repository, such as customfield_10010=owners. Teams and "+
+			"components come from the team mapping test cases were ingested with, such as components=components",
+	)
+	reportJiraCmd.PersistentFlags().IntVar(
+		&reportJiraParams.MinFailures, "min-failures", 2,
+		"Minimum number of failures within the window for a flaky test to be filed",
+	)
+	reportJiraCmd.PersistentFlags().IntVar(
+		&reportJiraParams.MaxFlakes, "max-flakes", 25,
+		"Maximum number of flaky tests to file or update, starting with the most failing ones",
+	)
+
+	reportCmd.AddCommand(reportJiraCmd)
+}
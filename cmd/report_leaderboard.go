@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/report"
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+type typeReportLeaderboardParams struct {
+	WindowStr  string
+	Window     time.Duration
+	Repository string
+	Limit      int
+}
+
+var (
+	reportLeaderboardParams = &typeReportLeaderboardParams{}
+	reportLeaderboardCmd    = &cobra.Command{
+		Use:   "leaderboard",
+		Short: "Rank owning teams by their flake debt and failures",
+		Long: "Print the failures and flake scores per owning team, highest flake score first. The flake " +
+			"score of a team is the number of failures of its flaky tests, that is tests which both failed " +
+			"and passed within the window. Retries are workflow run attempts which were retried after " +
+			"tests failed in them. Tests with several owners are split evenly between them.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			window, err := util.ParseDuration(reportLeaderboardParams.WindowStr)
+			if err != nil {
+				return fmt.Errorf("unable to parse window: %w", err)
+			}
+			reportLeaderboardParams.Window = window
+
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			logger := log.NewLogger(rootParams.Verbose)
+
+			opensearchCfg, err := opensearch.NewClientConfig()
+			if err != nil {
+				logger.Error("Unable to load OpenSearch configuration", "err", err)
+				os.Exit(1)
+			}
+
+			opsClient, err := opensearchgo.NewClient(opensearchCfg)
+			if err != nil {
+				logger.Error("Unable to create opensearch client", "err", err)
+				os.Exit(1)
+			}
+
+			until := time.Now()
+			b, err := report.LoadBuilder(
+				ctx, opsClient, readIndex(types.TypeNameWorkflowRun, types.TypeNameTestcase),
+				until.Add(-reportLeaderboardParams.Window), until, reportLeaderboardParams.Repository,
+			)
+			if err != nil {
+				logger.Error("Unable to load report", "err", err)
+				os.Exit(1)
+			}
+
+			scores := b.Leaderboard()
+			if reportLeaderboardParams.Limit > 0 && len(scores) > reportLeaderboardParams.Limit {
+				scores = scores[:reportLeaderboardParams.Limit]
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+			defer w.Flush()
+
+			fmt.Fprintln(w, "RANK\tOWNER\tFLAKE SCORE\tFLAKY TESTS\tRETRIES\tFAILURES\tTESTS\tFAILURE RATE\t")
+			for i, s := range scores {
+				rate := 0.0
+				if s.Tests > 0 {
+					rate = s.Failures / s.Tests
+				}
+				fmt.Fprintf(
+					w, "%d\t%s\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f%%\t\n",
+					i+1, s.Owner, s.FlakeScore, s.FlakyTests, s.Retries, s.Failures, s.Tests, rate*100,
+				)
+			}
+		},
+	}
+)
+
+func init() {
+	reportLeaderboardCmd.PersistentFlags().StringVarP(
+		&reportLeaderboardParams.WindowStr, "window", "w", "30d",
+		"Time window to rank teams over, ending now, such as 30d or 7d",
+	)
+	reportLeaderboardCmd.PersistentFlags().StringVarP(
+		&reportLeaderboardParams.Repository, "repository", "r", "",
+		"Only look at workflow runs of the given repository, such as cilium/cilium",
+	)
+	reportLeaderboardCmd.PersistentFlags().IntVar(
+		&reportLeaderboardParams.Limit, "limit", 0,
+		"Maximum number of teams to print. Zero prints all of them.",
+	)
+
+	reportCmd.AddCommand(reportLeaderboardCmd)
+}
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	gh "github.com/isovalent/corgi/pkg/github"
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/report"
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+type typeReportMarkdownParams struct {
+	WindowStr     string
+	Window        time.Duration
+	Output        string
+	Repository    string
+	MaxFlakes     int
+	ReportsRepo   string
+	ReportsPath   string
+	ReportsBranch string
+	PullRequest   bool
+}
+
+var (
+	reportMarkdownParams = &typeReportMarkdownParams{}
+	reportMarkdownCmd    = &cobra.Command{
+		Use:   "markdown",
+		Short: "Render a Markdown report of pass rates, flakes and owners, optionally committing it to a repository",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			window, err := util.ParseDuration(reportMarkdownParams.WindowStr)
+			if err != nil {
+				return fmt.Errorf("unable to parse window: %w", err)
+			}
+			reportMarkdownParams.Window = window
+
+			if reportMarkdownParams.ReportsRepo != "" && len(strings.Split(reportMarkdownParams.ReportsRepo, "/")) != 2 {
+				return fmt.Errorf("expected --reports-repo in the form of <owner>/<name>, got %q", reportMarkdownParams.ReportsRepo)
+			}
+
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			logger := log.NewLogger(rootParams.Verbose)
+
+			opensearchCfg, err := opensearch.NewClientConfig()
+			if err != nil {
+				logger.Error("Unable to load OpenSearch configuration", "err", err)
+				os.Exit(1)
+			}
+
+			opsClient, err := opensearchgo.NewClient(opensearchCfg)
+			if err != nil {
+				logger.Error("Unable to create opensearch client", "err", err)
+				os.Exit(1)
+			}
+
+			until := time.Now()
+			since := until.Add(-reportMarkdownParams.Window)
+
+			r, err := report.Load(
+				ctx, opsClient, readIndex(types.TypeNameWorkflowRun, types.TypeNameTestcase),
+				since, until, reportMarkdownParams.Repository, reportMarkdownParams.MaxFlakes,
+			)
+			if err != nil {
+				logger.Error("Unable to load report", "err", err)
+				os.Exit(1)
+			}
+
+			if err := loadReportPatterns(ctx, opsClient, r); err != nil {
+				logger.Error("Unable to load failure patterns", "err", err)
+				os.Exit(1)
+			}
+
+			buf := &bytes.Buffer{}
+			if err := report.RenderReportMarkdown(buf, r); err != nil {
+				logger.Error("Unable to render report", "err", err)
+				os.Exit(1)
+			}
+
+			if reportMarkdownParams.ReportsRepo == "" {
+				out := os.Stdout
+				if reportMarkdownParams.Output != "" {
+					out, err = os.Create(reportMarkdownParams.Output)
+					if err != nil {
+						logger.Error("Unable to create report", "err", err)
+						os.Exit(1)
+					}
+					defer out.Close()
+				}
+
+				if _, err := out.Write(buf.Bytes()); err != nil {
+					logger.Error("Unable to write report", "err", err)
+					os.Exit(1)
+				}
+				return
+			}
+
+			if err := publishReport(ctx, until, buf.Bytes()); err != nil {
+				logger.Error("Unable to publish report", "err", err)
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+// publishReport commits the given report to the reports repository, named after the ISO
+// week it ends in, either directly or through a pull request.
+func publishReport(ctx context.Context, until time.Time, content []byte) error {
+	logger := log.NewLogger(rootParams.Verbose)
+
+	token, err := gh.GetGitHubAuthToken()
+	if err != nil {
+		return err
+	}
+
+	client, err := gh.NewGitHubClient(token, logger, rootParams.Timeouts.Request)
+	if err != nil {
+		return err
+	}
+
+	repoOwner, repoName, _ := strings.Cut(reportMarkdownParams.ReportsRepo, "/")
+	year, week := until.UTC().ISOWeek()
+	name := fmt.Sprintf("%d-W%02d", year, week)
+	if reportMarkdownParams.Repository != "" {
+		name = strings.ReplaceAll(reportMarkdownParams.Repository, "/", "-") + "-" + name
+	}
+	filePath := path.Join(reportMarkdownParams.ReportsPath, name+".md")
+	message := "Add CI report " + name
+
+	branch := reportMarkdownParams.ReportsBranch
+	if reportMarkdownParams.PullRequest {
+		branch = "corgi/report-" + name
+		if err := gh.EnsureBranch(
+			ctx, logger, client, repoOwner, repoName, reportMarkdownParams.ReportsBranch, branch,
+		); err != nil {
+			return err
+		}
+	}
+
+	if err := gh.CommitFile(ctx, logger, client, repoOwner, repoName, branch, filePath, message, content); err != nil {
+		return err
+	}
+
+	if !reportMarkdownParams.PullRequest {
+		logger.Info("Committed report", "repo", reportMarkdownParams.ReportsRepo, "path", filePath)
+		return nil
+	}
+
+	url, err := gh.EnsurePullRequest(
+		ctx, logger, client, repoOwner, repoName, reportMarkdownParams.ReportsBranch, branch,
+		message, "CI report for the "+reportMarkdownParams.WindowStr+" ending "+until.UTC().Format(time.DateOnly)+".",
+	)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Opened pull request for report", "url", url)
+
+	return nil
+}
+
+func init() {
+	reportMarkdownCmd.PersistentFlags().StringVarP(
+		&reportMarkdownParams.WindowStr, "window", "w", "7d",
+		"Time window to report on, ending now, such as 7d or 24h",
+	)
+	reportMarkdownCmd.PersistentFlags().StringVarP(
+		&reportMarkdownParams.Output, "output", "o", "",
+		"File to write the report to, instead of stdout. Ignored if --reports-repo is set.",
+	)
+	reportMarkdownCmd.PersistentFlags().StringVarP(
+		&reportMarkdownParams.Repository, "repository", "r", "",
+		"Only report on workflow runs of the given repository, such as cilium/cilium",
+	)
+	reportMarkdownCmd.PersistentFlags().IntVar(
+		&reportMarkdownParams.MaxFlakes, "max-flakes", 25,
+		"Maximum number of flaky tests to list",
+	)
+	reportMarkdownCmd.PersistentFlags().StringVar(
+		&reportMarkdownParams.ReportsRepo, "reports-repo", "",
+		"If set, commit the report to the given repository, such as cilium/ci-reports, named after the "+
+			"ISO week the window ends in",
+	)
+	reportMarkdownCmd.PersistentFlags().StringVar(
+		&reportMarkdownParams.ReportsPath, "reports-path", "reports",
+		"Directory within --reports-repo to commit reports to",
+	)
+	reportMarkdownCmd.PersistentFlags().StringVar(
+		&reportMarkdownParams.ReportsBranch, "reports-branch", "main",
+		"Branch of --reports-repo to commit reports to, or to open pull requests against",
+	)
+	reportMarkdownCmd.PersistentFlags().BoolVar(
+		&reportMarkdownParams.PullRequest, "pull-request", false,
+		"Open a pull request with the report instead of committing it to --reports-branch directly",
+	)
+
+	reportCmd.AddCommand(reportMarkdownCmd)
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/report"
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+type typeReportNewTestsParams struct {
+	SinceStr   string
+	Since      time.Time
+	UntilStr   string
+	Until      time.Time
+	Repository string
+	Branch     string
+	JSON       bool
+}
+
+// newTestJSON is a NewTest as printed with --json.
+type newTestJSON struct {
+	Workflow  string    `json:"workflow"`
+	Test      string    `json:"test"`
+	Owners    []string  `json:"owners"`
+	FirstSeen time.Time `json:"first_seen"`
+	FirstRun  string    `json:"first_run"`
+	Runs      int       `json:"runs"`
+}
+
+var (
+	reportNewTestsParams = &typeReportNewTestsParams{}
+	reportNewTestsCmd    = &cobra.Command{
+		Use:   "new-tests",
+		Short: "Print the tests which ran for the first time within a date range",
+		Long: "Print the tests of each workflow which ran for the first time between --since and --until, " +
+			"oldest first, with their owners and the workflow run they first ran in, such as to list the " +
+			"test coverage added in a release. Tests are only known from the indices, so --since needs to " +
+			"be within their retention, or tests which are older will be listed too.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			tz := time.Now().Local().Location()
+
+			since, err := time.ParseInLocation(timeFormatYearMonthDay, reportNewTestsParams.SinceStr, tz)
+			if err != nil {
+				return fmt.Errorf("unable to parse '%s' in to format of '%s': %w", reportNewTestsParams.SinceStr, timeFormatYearMonthDay, err)
+			}
+			reportNewTestsParams.Since = since
+
+			until, err := time.ParseInLocation(timeFormatYearMonthDay, reportNewTestsParams.UntilStr, tz)
+			if err != nil {
+				return fmt.Errorf("unable to parse '%s' in to format of '%s': %w", reportNewTestsParams.UntilStr, timeFormatYearMonthDay, err)
+			}
+			// The until date is inclusive.
+			reportNewTestsParams.Until = until.AddDate(0, 0, 1)
+
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			logger := log.NewLogger(rootParams.Verbose)
+
+			opensearchCfg, err := opensearch.NewClientConfig()
+			if err != nil {
+				logger.Error("Unable to load OpenSearch configuration", "err", err)
+				os.Exit(1)
+			}
+
+			opsClient, err := opensearchgo.NewClient(opensearchCfg)
+			if err != nil {
+				logger.Error("Unable to create opensearch client", "err", err)
+				os.Exit(1)
+			}
+
+			tests, err := report.LoadNewTests(
+				ctx, opsClient, readIndex(types.TypeNameTestcase),
+				reportNewTestsParams.Since, reportNewTestsParams.Until,
+				reportNewTestsParams.Repository, reportNewTestsParams.Branch,
+			)
+			if err != nil {
+				logger.Error("Unable to load new tests", "err", err)
+				os.Exit(1)
+			}
+
+			if reportNewTestsParams.JSON {
+				if err := printNewTestsJSON(tests); err != nil {
+					logger.Error("Unable to print new tests", "err", err)
+					os.Exit(1)
+				}
+				return
+			}
+
+			printNewTests(tests)
+		},
+	}
+)
+
+func printNewTests(tests []report.NewTest) {
+	fmt.Printf(
+		"%d new tests from %s to %s\n\n", len(tests), reportNewTestsParams.SinceStr, reportNewTestsParams.UntilStr,
+	)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "FIRST SEEN\tWORKFLOW\tTEST\tOWNERS\tRUNS\tFIRST RUN")
+	for _, t := range tests {
+		fmt.Fprintf(
+			w, "%s\t%s\t%s\t%s\t%d\t%s\n",
+			t.FirstSeen.UTC().Format(time.DateOnly), t.Workflow, t.Test, strings.Join(t.Owners, ","), t.Runs, t.FirstRun,
+		)
+	}
+}
+
+func printNewTestsJSON(tests []report.NewTest) error {
+	entries := make([]newTestJSON, 0, len(tests))
+	for _, t := range tests {
+		entries = append(entries, newTestJSON{
+			Workflow:  t.Workflow,
+			Test:      t.Test,
+			Owners:    t.Owners,
+			FirstSeen: t.FirstSeen,
+			FirstRun:  t.FirstRun,
+			Runs:      t.Runs,
+		})
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(entries)
+}
+
+func init() {
+	reportNewTestsCmd.PersistentFlags().StringVarP(
+		&reportNewTestsParams.SinceStr, "since", "s", time.Now().AddDate(0, 0, -30).Format(timeFormatYearMonthDay),
+		"First day of the date range, such as the day of the previous release. Expected format is YYYY-MM-DD.",
+	)
+	reportNewTestsCmd.PersistentFlags().StringVarP(
+		&reportNewTestsParams.UntilStr, "until", "u", time.Now().Format(timeFormatYearMonthDay),
+		"Last day of the date range, inclusive. Expected format is YYYY-MM-DD.",
+	)
+	reportNewTestsCmd.PersistentFlags().StringVarP(
+		&reportNewTestsParams.Repository, "repository", "r", "",
+		"Only look at workflow runs of the given repository, such as cilium/cilium",
+	)
+	reportNewTestsCmd.PersistentFlags().StringVarP(
+		&reportNewTestsParams.Branch, "branch", "b", "main",
+		"Only look at workflow runs on the given branch. Empty includes all branches.",
+	)
+	reportNewTestsCmd.PersistentFlags().BoolVar(
+		&reportNewTestsParams.JSON, "json", false,
+		"Print the new tests as a JSON array, for consumption by other tools",
+	)
+
+	reportCmd.AddCommand(reportNewTestsCmd)
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/report"
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+type typeReportReleaseParams struct {
+	WindowStr  string
+	Window     time.Duration
+	Repository string
+	Branches   []string
+	Limit      int
+}
+
+var (
+	reportReleaseParams = &typeReportReleaseParams{}
+	reportReleaseCmd    = &cobra.Command{
+		Use:   "release",
+		Short: "Compare the failures and flakes of release branches",
+		Long: "Print the pass rate, failures and flaky tests of each of the given branches within the window, " +
+			"followed by the tests which failed on any of them with their failures and passes per branch. " +
+			"Tests which failed on only one branch, while running on another without failing, are listed " +
+			"first, as they likely point at a problem specific to that branch, such as a missing backport.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			window, err := util.ParseDuration(reportReleaseParams.WindowStr)
+			if err != nil {
+				return fmt.Errorf("unable to parse window: %w", err)
+			}
+			reportReleaseParams.Window = window
+
+			if len(reportReleaseParams.Branches) < 2 {
+				return fmt.Errorf("--branches needs at least two branches to compare")
+			}
+
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			logger := log.NewLogger(rootParams.Verbose)
+
+			opensearchCfg, err := opensearch.NewClientConfig()
+			if err != nil {
+				logger.Error("Unable to load OpenSearch configuration", "err", err)
+				os.Exit(1)
+			}
+
+			opsClient, err := opensearchgo.NewClient(opensearchCfg)
+			if err != nil {
+				logger.Error("Unable to create opensearch client", "err", err)
+				os.Exit(1)
+			}
+
+			until := time.Now()
+			r, err := report.LoadRelease(
+				ctx, opsClient, readIndex(types.TypeNameWorkflowRun, types.TypeNameTestcase),
+				until.Add(-reportReleaseParams.Window), until,
+				reportReleaseParams.Repository, reportReleaseParams.Branches,
+			)
+			if err != nil {
+				logger.Error("Unable to load release report", "err", err)
+				os.Exit(1)
+			}
+
+			failures := r.Failures
+			if reportReleaseParams.Limit > 0 && len(failures) > reportReleaseParams.Limit {
+				failures = failures[:reportReleaseParams.Limit]
+			}
+
+			printRelease(r, failures)
+		},
+	}
+)
+
+func printRelease(r *report.ReleaseReport, failures []report.BranchFailures) {
+	fmt.Printf("Branches over the last %s\n\n", reportReleaseParams.WindowStr)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "BRANCH\tRUNS\tPASS RATE\tTESTCASES\tFAILURES\tFAILED TESTS\tFLAKY TESTS")
+	for _, p := range r.Branches {
+		fmt.Fprintf(
+			w, "%s\t%d\t%.1f%%\t%d\t%d\t%d\t%d\n",
+			p.Branch, p.Runs, p.PassRate*100, p.Testcases, p.Failures, p.FailedTests, p.FlakyTests,
+		)
+	}
+	w.Flush()
+
+	unique := 0
+	for _, f := range r.Failures {
+		if f.UniqueTo != "" {
+			unique++
+		}
+	}
+	fmt.Printf("\n%d failed tests, %d failing on only one branch\n\n", len(r.Failures), unique)
+
+	w = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer w.Flush()
+
+	header := []string{"ONLY ON", "TEST", "OWNERS"}
+	for _, p := range r.Branches {
+		header = append(header, strings.ToUpper(p.Branch)+" FAILED/PASSED")
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, f := range failures {
+		row := []string{f.UniqueTo, f.Test, strings.Join(f.Owners, ",")}
+		if f.UniqueTo == "" {
+			row[0] = "-"
+		}
+		for i := range f.Failures {
+			row = append(row, fmt.Sprintf("%d/%d", f.Failures[i], f.Passes[i]))
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+}
+
+func init() {
+	reportReleaseCmd.PersistentFlags().StringVarP(
+		&reportReleaseParams.WindowStr, "window", "w", "14d",
+		"Time window to compare the branches over, ending now, such as 14d",
+	)
+	reportReleaseCmd.PersistentFlags().StringVarP(
+		&reportReleaseParams.Repository, "repository", "r", "",
+		"Only look at workflow runs of the given repository, such as cilium/cilium",
+	)
+	reportReleaseCmd.PersistentFlags().StringSliceVarP(
+		&reportReleaseParams.Branches, "branches", "b", []string{"main"},
+		"Comma-separated branches to compare, such as v1.15,v1.16,main",
+	)
+	reportReleaseCmd.PersistentFlags().IntVar(
+		&reportReleaseParams.Limit, "limit", 0,
+		"Maximum number of failed tests to print. Zero prints all of them.",
+	)
+
+	reportCmd.AddCommand(reportReleaseCmd)
+}
@@ -1,34 +1,931 @@
 package cmd
 
 import (
+	"context"
+	"expvar"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
 	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
 
+	opensearchgo "github.com/opensearch-project/opensearch-go"
 	"github.com/spf13/cobra"
+
+	"github.com/isovalent/corgi/pkg/junit"
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/metrics"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/plugin"
+	"github.com/isovalent/corgi/pkg/secrets"
+	"github.com/isovalent/corgi/pkg/tracing"
+	"github.com/isovalent/corgi/pkg/transform"
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
 )
 
 type typeRootParams struct {
-	Index   string
-	Verbose bool
+	Index             string
+	Verbose           bool
+	MaxDocsPerSecond  float64
+	MaxBytesPerSecond float64
+	PprofAddress      string
+	BufferMemoryBytes int
+	SpillDir          string
+	MaxSpillBytes     int64
+	SendBulk          bool
+	BulkBatchDocs     int
+	DeadLetterFile    string
+	IndexTemplates    map[string]string
+	RoutingStr        string
+	AllowFields       []string
+	DenyFields        []string
+	RawIndex          string
+	ConfigFile        string
+	TransformsFile    string
+	FiltersFile       string
+	ExecHook          string
+	PluginsStr        []string
+	IDPrefix          string
+	SnapshotRepo      string
+	WebhookURL        string
+	WebhookBatchDocs  int
+	WebhookTypes      []string
+	KafkaRESTURL      string
+	KafkaTopicPrefix  string
+	KafkaBatchDocs    int
+	KafkaOnly         bool
+	TimeoutsStr       map[string]string
+	ValidateMapping   string
+	MaxFieldBytes     int
+	OTLPEndpoint      string
+	MetaIndex         string
+	AuditIndex        string
+	LeaseIndex        string
+	QuotasFile        string
+	QuotaStateFile    string
+	LeaseTTL          time.Duration
+	SecretsRefresh    time.Duration
+	Tenant            string
+	TenantRetention   string
+	// BulkOptions is compiled from the routing and field flags.
+	BulkOptions opensearch.BulkOptions
+	// Timeouts is parsed from the timeouts flag.
+	Timeouts util.Timeouts
+	// Converters are the artifact parsers of the loaded plugins, by name.
+	Converters map[string]junit.Converter
 }
 
 const (
 	timeFormatYearMonthDayHour = "2006-01-02T15"
 	timeFormatYearMonthDay     = "2006-01-02"
+
+	// spillFileBytes is the size at which a new spill file is started when
+	// buffering bulk requests to disk.
+	spillFileBytes = 64 * 1024 * 1024
 )
 
 var (
-	rootCmd    = &cobra.Command{}
-	rootParams = &typeRootParams{}
+	// bulkOutput is where bulk requests for OpenSearch are written to.
+	bulkOutput io.Writer = os.Stdout
+	rootParams           = &typeRootParams{}
+	rootCmd              = &cobra.Command{
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if err := util.SetFlagsFromEnv(cmd.Flags()); err != nil {
+				log.NewLogger(rootParams.Verbose).Error("Invalid environment variable", "err", err)
+				os.Exit(1)
+			}
+			if rootParams.ConfigFile != "" {
+				if err := util.SetFlagsFromConfig(cmd.Flags(), rootParams.ConfigFile); err != nil {
+					log.NewLogger(rootParams.Verbose).Error("Invalid config", "err", err)
+					os.Exit(1)
+				}
+			}
+
+			routing, err := opensearch.ParseRouting(rootParams.RoutingStr)
+			if err != nil {
+				log.NewLogger(rootParams.Verbose).Error("Invalid routing", "err", err)
+				os.Exit(1)
+			}
+			fields, err := opensearch.ParseFieldFilter(rootParams.AllowFields, rootParams.DenyFields)
+			if err != nil {
+				log.NewLogger(rootParams.Verbose).Error("Invalid field filter", "err", err)
+				os.Exit(1)
+			}
+			if rootParams.RawIndex != "" && fields == nil {
+				log.NewLogger(rootParams.Verbose).Error("--raw-index requires --allow-fields or --deny-fields")
+				os.Exit(1)
+			}
+			rootParams.BulkOptions = opensearch.BulkOptions{
+				Routing:  routing,
+				Fields:   fields,
+				RawIndex: rootParams.RawIndex,
+				IDPrefix: rootParams.IDPrefix,
+			}
+			rootParams.Timeouts, err = util.ParseTimeouts(rootParams.TimeoutsStr)
+			if err != nil {
+				log.NewLogger(rootParams.Verbose).Error("Invalid timeouts", "err", err)
+				os.Exit(1)
+			}
+			if err := applyTenant(cmd); err != nil {
+				log.NewLogger(rootParams.Verbose).Error("Invalid tenant", "err", err)
+				os.Exit(1)
+			}
+			if rootParams.TransformsFile != "" {
+				transforms, err := transform.LoadConfig(rootParams.TransformsFile, log.NewLogger(rootParams.Verbose))
+				if err != nil {
+					log.NewLogger(rootParams.Verbose).Error("Invalid transforms", "err", err)
+					os.Exit(1)
+				}
+				rootParams.BulkOptions.Hooks = append(rootParams.BulkOptions.Hooks, transforms)
+			}
+			if rootParams.FiltersFile != "" {
+				filters, err := transform.LoadFilters(rootParams.FiltersFile, log.NewLogger(rootParams.Verbose))
+				if err != nil {
+					log.NewLogger(rootParams.Verbose).Error("Invalid filters", "err", err)
+					os.Exit(1)
+				}
+				rootParams.BulkOptions.Hooks = append(rootParams.BulkOptions.Hooks, filters)
+			}
+			hook, err := opensearch.NewExecHook(rootParams.ExecHook, rootParams.Timeouts.ExecHook)
+			if err != nil {
+				log.NewLogger(rootParams.Verbose).Error("Invalid exec hook", "err", err)
+				os.Exit(1)
+			}
+			if hook != nil {
+				rootParams.BulkOptions.Hooks = append(rootParams.BulkOptions.Hooks, hook)
+			}
+			if err := loadPlugins(context.Background()); err != nil {
+				log.NewLogger(rootParams.Verbose).Error("Unable to load plugin", "err", err)
+				os.Exit(1)
+			}
+			if rootParams.ValidateMapping != "" {
+				rootParams.BulkOptions.Validator, err = opensearch.LoadValidator(
+					rootParams.ValidateMapping, rootParams.MaxFieldBytes, log.NewLogger(rootParams.Verbose),
+				)
+				if err != nil {
+					log.NewLogger(rootParams.Verbose).Error("Unable to load mapping to validate documents", "err", err)
+					os.Exit(1)
+				}
+			}
+
+			if rootParams.TenantRetention != "" && !rootParams.SendBulk {
+				log.NewLogger(rootParams.Verbose).Error("--tenant-retention requires --send-bulk")
+				os.Exit(1)
+			}
+
+			if rootParams.KafkaOnly && (rootParams.KafkaRESTURL == "" || rootParams.SendBulk) {
+				log.NewLogger(rootParams.Verbose).Error("--kafka-only requires --kafka-rest-url and excludes --send-bulk")
+				os.Exit(1)
+			}
+
+			var target io.Writer = os.Stdout
+			if rootParams.KafkaOnly {
+				target = io.Discard
+			}
+
+			if rootParams.SendBulk {
+				logger := log.NewLogger(rootParams.Verbose)
+
+				cfg, err := opensearch.NewClientConfig()
+				if err != nil {
+					logger.Error("Unable to load OpenSearch configuration", "err", err)
+					os.Exit(1)
+				}
+
+				client, err := opensearchgo.NewClient(cfg)
+				if err != nil {
+					logger.Error("Unable to create OpenSearch client", "err", err)
+					os.Exit(1)
+				}
+
+				if rootParams.TenantRetention != "" {
+					if err := ensureTenantRetention(client); err != nil {
+						logger.Error("Unable to set up retention of tenant", "err", err)
+						os.Exit(1)
+					}
+				}
+
+				bulkSender = opensearch.NewBulkSender(
+					client, logger, rootParams.BulkBatchDocs, rootParams.DeadLetterFile, rootParams.Timeouts.BulkWrite,
+				)
+				target = bulkSender
+			}
+
+			if rootParams.WebhookURL != "" {
+				secret, err := util.NewSecret("WEBHOOK_SECRET")
+				if err != nil {
+					log.NewLogger(rootParams.Verbose).Error("Unable to load webhook secret", "err", err)
+					os.Exit(1)
+				}
+
+				webhookWriter = opensearch.NewWebhookWriter(
+					target, log.NewLogger(rootParams.Verbose), rootParams.WebhookURL, secret,
+					rootParams.WebhookBatchDocs, rootParams.WebhookTypes,
+				)
+				target = webhookWriter
+			}
+
+			if rootParams.KafkaRESTURL != "" {
+				kafkaWriter = opensearch.NewKafkaWriter(
+					target, log.NewLogger(rootParams.Verbose), rootParams.KafkaRESTURL,
+					rootParams.KafkaTopicPrefix, rootParams.KafkaBatchDocs,
+				)
+				target = kafkaWriter
+			}
+
+			bulkOutput = opensearch.NewRateLimitedWriter(
+				target, rootParams.MaxDocsPerSecond, rootParams.MaxBytesPerSecond,
+			)
+
+			if rootParams.BufferMemoryBytes > 0 {
+				bulkOutput = opensearch.NewSpillWriter(
+					bulkOutput, rootParams.SpillDir, rootParams.BufferMemoryBytes,
+					spillFileBytes, rootParams.MaxSpillBytes,
+				)
+			}
+
+			if rootParams.PprofAddress != "" {
+				go servePprof(rootParams.PprofAddress)
+			}
+
+			if rootParams.LeaseTTL > 0 {
+				if err := initLeases(); err != nil {
+					log.NewLogger(rootParams.Verbose).Error("Unable to set up leases", "err", err)
+					os.Exit(1)
+				}
+			}
+
+			if rootParams.QuotasFile != "" {
+				if err := initQuotas(); err != nil {
+					log.NewLogger(rootParams.Verbose).Error("Unable to load quotas", "err", err)
+					os.Exit(1)
+				}
+			}
+
+			if rootParams.SecretsRefresh > 0 {
+				go refreshSecrets(log.NewLogger(rootParams.Verbose), rootParams.SecretsRefresh)
+			}
+
+			if rootParams.OTLPEndpoint != "" {
+				tracer = tracing.Init(rootParams.OTLPEndpoint, "corgi", log.NewLogger(rootParams.Verbose))
+			}
+		},
+		PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+			if auditEvent != nil {
+				finishAudit()
+			}
+
+			if ingestStats != nil && rootParams.MetaIndex != "" {
+				if err := writeIngestStats(); err != nil {
+					return err
+				}
+			}
+
+			if auditEvent != nil && rootParams.AuditIndex != "" {
+				if err := writeAuditEvent(); err != nil {
+					return err
+				}
+			}
+
+			if closer, ok := bulkOutput.(io.Closer); ok {
+				if err := closer.Close(); err != nil {
+					return fmt.Errorf("unable to flush bulk requests: %w", err)
+				}
+			}
+
+			if webhookWriter != nil {
+				webhookWriter.Close()
+			}
+
+			if kafkaWriter != nil {
+				if err := kafkaWriter.Close(); err != nil {
+					return err
+				}
+			}
+
+			if bulkSender != nil {
+				if err := bulkSender.Close(); err != nil {
+					return fmt.Errorf("unable to send bulk requests: %w", err)
+				}
+			}
+
+			if v := rootParams.BulkOptions.Validator; v != nil && v.Rejected() > 0 {
+				log.NewLogger(rootParams.Verbose).Warn(
+					"Skipped documents which don't match the mapping", "documents", v.Rejected(),
+				)
+			}
+
+			for _, hook := range flushHooks {
+				if err := hook(); err != nil {
+					return err
+				}
+			}
+
+			if tracer != nil {
+				if err := tracer.Shutdown(); err != nil {
+					log.NewLogger(rootParams.Verbose).Warn("Unable to export trace spans", "err", err)
+				}
+			}
+
+			if rootParams.SnapshotRepo != "" {
+				return snapshotIndices()
+			}
+
+			return nil
+		},
+	}
+
+	// bulkSender sends bulk requests to OpenSearch when --send-bulk is given.
+	bulkSender *opensearch.BulkSender
+
+	// webhookWriter posts documents to the webhook given by --webhook-url.
+	webhookWriter *opensearch.WebhookWriter
+
+	// kafkaWriter publishes documents to Kafka when --kafka-rest-url is given.
+	kafkaWriter *opensearch.KafkaWriter
+
+	// ingestStats are the stats of the current invocation, if it ingests workflow runs.
+	ingestStats *types.IngestStats
+
+	// tenantIndices is set if documents are written to the indices of --tenant, see
+	// opensearch.TenantIndex.
+	tenantIndices bool
+
+	// leaseClient acquires leases on workflow runs when --lease-ttl is given.
+	leaseClient *opensearchgo.Client
+	// leaseHolder identifies this process as the holder of leases.
+	leaseHolder string
+
+	// tracer exports trace spans when --otlp-endpoint is given.
+	tracer *tracing.Exporter
+
+	// flushHooks are run once all documents were written and flushed successfully,
+	// such as to record what was ingested.
+	flushHooks []func() error
 )
 
+// initLeases creates the client to acquire leases on workflow runs with, so that
+// multiple replicas don't ingest the same run.
+func initLeases() error {
+	if os.Getenv("OPENSEARCH_URL") == "" {
+		return fmt.Errorf("--lease-ttl requires OPENSEARCH_URL to be set")
+	}
+
+	cfg, err := opensearch.NewClientConfig()
+	if err != nil {
+		return fmt.Errorf("unable to load OpenSearch configuration: %w", err)
+	}
+
+	leaseClient, err = opensearchgo.NewClient(cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create OpenSearch client: %w", err)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("unable to get hostname: %w", err)
+	}
+	leaseHolder = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+
+	return nil
+}
+
+// applyTenant expands the tenant placeholder in the index flags. If a tenant is given
+// without an --index, each document type of the tenant is written to its own indices.
+func applyTenant(cmd *cobra.Command) error {
+	if rootParams.Tenant != "" {
+		if err := opensearch.ValidateTenant(rootParams.Tenant); err != nil {
+			return err
+		}
+
+		tenantIndices = !cmd.Flags().Changed("index")
+	}
+
+	expand := func(index string) (string, error) {
+		if rootParams.Tenant == "" && strings.Contains(index, opensearch.TenantPlaceholder) {
+			return "", fmt.Errorf("index '%s' contains %s, but --tenant isn't set", index, opensearch.TenantPlaceholder)
+		}
+
+		return opensearch.ExpandTenant(index, rootParams.Tenant), nil
+	}
+
+	var err error
+	for _, index := range []*string{
+		&rootParams.Index, &rootParams.MetaIndex, &rootParams.AuditIndex, &rootParams.LeaseIndex, &rootParams.RawIndex,
+	} {
+		if *index, err = expand(*index); err != nil {
+			return err
+		}
+	}
+	for typ, index := range rootParams.IndexTemplates {
+		if rootParams.IndexTemplates[typ], err = expand(index); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ensureTenantRetention creates the retention policy deleting the indices of the tenant
+// once they are older than --tenant-retention.
+func ensureTenantRetention(client *opensearchgo.Client) error {
+	if rootParams.Tenant == "" {
+		return fmt.Errorf("--tenant-retention requires --tenant")
+	}
+
+	retention, err := util.ParseDuration(rootParams.TenantRetention)
+	if err != nil {
+		return fmt.Errorf("unable to parse tenant retention: %w", err)
+	}
+
+	patterns := []string{opensearch.TenantIndexPattern(rootParams.Tenant)}
+	if !tenantIndices {
+		patterns = strings.Split(readIndex(runDocumentTypes...), ",")
+	}
+
+	// Never delete indices shared with other tenants.
+	prefix := opensearch.TenantIndexPrefix(rootParams.Tenant)
+	for _, pattern := range patterns {
+		if !strings.HasPrefix(pattern, prefix) {
+			return fmt.Errorf(
+				"index '%s' doesn't start with %s, refusing to apply retention of tenant %s to it",
+				pattern, prefix, rootParams.Tenant,
+			)
+		}
+	}
+
+	ctx, cancel := util.WithTimeout(context.Background(), rootParams.Timeouts.Request)
+	defer cancel()
+
+	return opensearch.EnsureRetentionPolicy(ctx, client, "corgi-"+rootParams.Tenant+"-retention", patterns, retention)
+}
+
+// refreshSecrets reloads every secret at the given interval, so that credentials rotated
+// in a secrets manager are picked up without a restart.
+func refreshSecrets(logger *slog.Logger, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := util.ReloadSecrets(); err != nil {
+			logger.Warn("Unable to refresh credentials, keeping the previous ones", "err", err)
+		}
+	}
+}
+
+// runPeriodically calls fn once if interval is zero. Otherwise, it calls fn every interval
+// until interrupted, logging failures instead of stopping, so that they are retried on
+// the next tick, and flushing the bulk output after each call. It returns the error of
+// fn when only calling it once.
+func runPeriodically(
+	logger *slog.Logger,
+	interval time.Duration,
+	fn func(ctx context.Context) error,
+) error {
+	ctx := context.Background()
+	if interval == 0 {
+		return fn(ctx)
+	}
+
+	stop, cancelStop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	defer cancelStop()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := fn(stop); err != nil {
+			logger.Error("Unable to complete periodic run, retrying on the next one", "err", err)
+		}
+
+		// Otherwise the few documents of a run could stay in the batch of the bulk
+		// output for many intervals, until enough of them were written to fill it.
+		if err := opensearch.Flush(bulkOutput); err != nil {
+			logger.Error("Unable to flush documents of periodic run", "err", err)
+		}
+
+		select {
+		case <-stop.Done():
+			logger.Info("Received shutdown signal, stopping")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// startIngestStats starts recording the operational stats of the given command, which
+// are written to the meta index once it completes.
+func startIngestStats(cmd *cobra.Command) {
+	host, _ := os.Hostname()
+
+	ingestStats = &types.IngestStats{
+		Type:      types.TypeNameIngestStats,
+		Command:   cmd.CommandPath(),
+		Host:      host,
+		StartedAt: time.Now(),
+	}
+}
+
+// writeIngestStats writes a bulk entry for the stats of the current invocation into the
+// meta index, so that the health of the ingest can be monitored next to the CI data.
+func writeIngestStats() error {
+	s := ingestStats
+	s.FinishedAt = time.Now()
+	s.Duration = s.FinishedAt.Sub(s.StartedAt)
+	s.RunsIngested = metrics.Count(metrics.CounterRunsIngested)
+	s.RunsAborted = metrics.Count(metrics.CounterRunsAborted)
+	s.DocumentsWritten = metrics.Count(metrics.CounterDocumentsWritten)
+	s.IngestErrors = metrics.Count(metrics.CounterIngestErrors)
+	s.GitHubRequests = metrics.Count(metrics.CounterGitHubRequests)
+
+	if v := rootParams.BulkOptions.Validator; v != nil {
+		s.DocumentsRejected = v.Rejected()
+	}
+
+	s.Stages = map[string]types.IngestStage{}
+	for stage, summary := range metrics.Stages() {
+		s.Stages[stage] = types.IngestStage{Operations: summary.Operations, Duration: summary.Duration}
+	}
+
+	if rate, ok := metrics.LastGitHubRate(); ok {
+		s.GitHubRateLimit = rate.Limit
+		s.GitHubRateLimitRemaining = rate.Remaining
+		s.GitHubRateLimitUsed = rate.Used
+	}
+
+	if err := opensearch.BulkWriteObjects(
+		[]types.IngestStats{*s}, rootParams.MetaIndex, rootParams.BulkOptions, bulkOutput,
+	); err != nil {
+		return fmt.Errorf("unable to write ingest stats: %w", err)
+	}
+
+	return nil
+}
+
+// indexFor returns the index name template to write documents of the given type to.
+func indexFor(typ types.TypeName) string {
+	if index, ok := rootParams.IndexTemplates[string(typ)]; ok {
+		return index
+	}
+
+	if tenantIndices {
+		return opensearch.TenantIndex(rootParams.Tenant, typ)
+	}
+
+	return rootParams.Index
+}
+
+// writtenIndexPatterns returns patterns matching the indices documents are written into.
+func writtenIndexPatterns() []string {
+	// With tenant indices, every type is written to an index of the tenant unless it
+	// has an index template.
+	indices := []string{opensearch.IndexPattern(rootParams.Index)}
+	if tenantIndices {
+		indices = []string{opensearch.TenantIndexPattern(rootParams.Tenant)}
+	}
+	for _, template := range rootParams.IndexTemplates {
+		if pattern := opensearch.IndexPattern(template); !slices.Contains(indices, pattern) {
+			indices = append(indices, pattern)
+		}
+	}
+	if rootParams.RawIndex != "" {
+		if pattern := opensearch.IndexPattern(rootParams.RawIndex); !slices.Contains(indices, pattern) {
+			indices = append(indices, pattern)
+		}
+	}
+
+	return indices
+}
+
+// snapshotIndices snapshots the indices written to into the configured snapshot repository,
+// once all bulk requests were sent.
+func snapshotIndices() error {
+	logger := log.NewLogger(rootParams.Verbose)
+
+	if bulkSender == nil {
+		logger.Warn("Bulk requests were written to stdout instead of sent with --send-bulk, not creating a snapshot")
+		return nil
+	}
+
+	cfg, err := opensearch.NewClientConfig()
+	if err != nil {
+		return fmt.Errorf("unable to load OpenSearch configuration: %w", err)
+	}
+
+	client, err := opensearchgo.NewClient(cfg)
+	if err != nil {
+		return fmt.Errorf("unable to create OpenSearch client: %w", err)
+	}
+
+	indices := writtenIndexPatterns()
+
+	name := "corgi-" + strings.ToLower(time.Now().UTC().Format("2006.01.02-150405"))
+	if err := opensearch.CreateSnapshot(
+		context.Background(), client, rootParams.SnapshotRepo, name, indices,
+	); err != nil {
+		return err
+	}
+
+	logger.Info("Started snapshot", "repository", rootParams.SnapshotRepo, "snapshot", name, "indices", indices)
+
+	return nil
+}
+
+// servePprof serves pprof profiles and the pipeline metrics exported through expvar.
+func servePprof(address string) {
+	logger := log.NewLogger(rootParams.Verbose).With("address", address)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+
+	logger.Info("Serving pprof and pipeline metrics")
+
+	if err := http.ListenAndServe(address, mux); err != nil {
+		logger.Error("Unable to serve pprof", "err", err)
+	}
+}
+
+// loadPlugins loads the plugins given with --plugin, registering their parsers and
+// enrichers.
+func loadPlugins(ctx context.Context) error {
+	rootParams.Converters = map[string]junit.Converter{}
+	loaded := map[string]bool{}
+	for _, spec := range rootParams.PluginsStr {
+		name, path, err := plugin.ParseSpec(spec)
+		if err != nil {
+			return err
+		}
+		if loaded[name] {
+			return fmt.Errorf("plugin %s is given multiple times", name)
+		}
+		loaded[name] = true
+
+		p, err := plugin.Load(ctx, name, path, rootParams.Timeouts.ExecHook)
+		if err != nil {
+			return err
+		}
+
+		if p.IsParser() {
+			rootParams.Converters[name] = p
+		}
+		if p.IsEnricher() {
+			rootParams.BulkOptions.Hooks = append(rootParams.BulkOptions.Hooks, p)
+		}
+	}
+
+	return nil
+}
+
 func init() {
-	rootCmd.PersistentFlags().StringVarP(&rootParams.Index, "index", "i", "runs", "OpenSearch index to target")
+	secrets.Register()
+
+	rootCmd.PersistentFlags().StringVarP(
+		&rootParams.Index, "index", "i", "runs",
+		"OpenSearch index to target. May contain date-math placeholders resolved from the event time "+
+			"of each document, such as corgi-{yyyy.MM}.",
+	)
+	rootCmd.PersistentFlags().StringToStringVar(
+		&rootParams.IndexTemplates, "index-templates", map[string]string{},
+		"Index to target per document type instead of --index, such as "+
+			"test_case=corgi-testcases-{yyyy.MM}",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootParams.RoutingStr, "routing", "",
+		"Route documents to shards by the primary owner of tests ('owner') or by the workflow name "+
+			"('workflow'), so that queries filtering on them hit fewer shards. By default documents "+
+			"are routed by their ID.",
+	)
+	rootCmd.PersistentFlags().StringSliceVar(
+		&rootParams.AllowFields, "allow-fields", []string{},
+		"Fields to keep in documents, of the form [<type>=]<field>. If given for a document type, "+
+			"all other fields are dropped from documents of that type.",
+	)
+	rootCmd.PersistentFlags().StringSliceVar(
+		&rootParams.DenyFields, "deny-fields", []string{},
+		"Fields to drop from documents before they are written, of the form [<type>=]<field>, "+
+			"for example test_case=test_case_failure_text",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootParams.RawIndex, "raw-index", "",
+		"Index name template to also write documents to in full, before --allow-fields and --deny-fields "+
+			"drop fields from them, such as corgi-raw-{yyyy.MM}",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootParams.ConfigFile, "config", "",
+		"JSON file mapping flag names to their values, such as {\"send-bulk\": true, \"events\": [\"push\"]}. "+
+			"Flags and environment variables take precedence over it.",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootParams.TransformsFile, "transforms-file", "",
+		"JSON file with a list of fields to derive from CEL expressions before documents are written, "+
+			"such as [{\"type\": \"test_case\", \"field\": \"is_upgrade_test\", "+
+			"\"expression\": \"doc.test_case_name.contains('upgrade')\"}]",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootParams.FiltersFile, "filters-file", "",
+		"JSON file with a list of CEL expressions dropping the documents they match before they are written, "+
+			"after the transforms, such as [{\"type\": \"test_case\", "+
+			"\"expression\": \"doc.test_case_status == 'passed' && doc.workflow_name.glob('*-smoke')\"}]",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootParams.ExecHook, "exec-hook", "",
+		"Command to enrich documents with before they are written, such as './enrich.py --team-map teams.json'. "+
+			"Each batch of documents is written to its stdin as JSON, one per line, and the modified documents, "+
+			"or null to drop them, are read back from its stdout in the same order.",
+	)
+	rootCmd.PersistentFlags().StringArrayVar(
+		&rootParams.PluginsStr, "plugin", []string{},
+		"WebAssembly plugin to load in the form of '<name>=<path>'. May be given multiple times. Parser plugins "+
+			"are routed artifacts with --artifact-parsers '<pattern>=wasm:<name>', while enricher plugins enrich "+
+			"documents after --exec-hook.",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootParams.IDPrefix, "id-prefix", "",
+		"Prefix for document IDs, such as the name of the deployment, so that multiple deployments "+
+			"can write into the same index without overwriting each other's documents.",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootParams.SnapshotRepo, "snapshot-repository", "",
+		"If set, snapshot the target indices into the given snapshot repository once all bulk requests "+
+			"were sent, to protect large backfills. Requires --send-bulk.",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootParams.WebhookURL, "webhook-url", "",
+		"If set, also POST written documents as JSON arrays to the given URL. Requests are signed with "+
+			"WEBHOOK_SECRET, if set, in the "+opensearch.WebhookSignatureHeader+" header.",
+	)
+	rootCmd.PersistentFlags().IntVar(
+		&rootParams.WebhookBatchDocs, "webhook-batch-docs", 1,
+		"Number of documents to post to the webhook per request",
+	)
+	rootCmd.PersistentFlags().StringSliceVar(
+		&rootParams.WebhookTypes, "webhook-types", []string{},
+		"Only post documents of the given types to the webhook, such as ingest_error,failure_rate",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootParams.KafkaRESTURL, "kafka-rest-url", "",
+		"If set, also publish written documents to Kafka through the Kafka REST Proxy at the given URL, "+
+			"to one topic per document type, keyed by workflow run ID. Credentials may be given in the URL.",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootParams.KafkaTopicPrefix, "kafka-topic-prefix", "corgi.",
+		"Prefix of the Kafka topics, which are named after the document types, such as corgi.test_case",
+	)
+	rootCmd.PersistentFlags().IntVar(
+		&rootParams.KafkaBatchDocs, "kafka-batch-docs", 500,
+		"Maximum number of documents per produce request to the Kafka REST Proxy",
+	)
+	rootCmd.PersistentFlags().BoolVar(
+		&rootParams.KafkaOnly, "kafka-only", false,
+		"Publish documents to Kafka instead of writing bulk requests for OpenSearch",
+	)
 	rootCmd.PersistentFlags().BoolVarP(&rootParams.Verbose, "verbose", "v", false, "Enable debug logging")
+	rootCmd.PersistentFlags().Float64Var(
+		&rootParams.MaxDocsPerSecond, "max-docs-per-second", 0,
+		"Maximum number of documents per second to write bulk requests for. Zero means unlimited.",
+	)
+	rootCmd.PersistentFlags().Float64Var(
+		&rootParams.MaxBytesPerSecond, "max-bytes-per-second", 0,
+		"Maximum number of bytes per second to write bulk requests for. Zero means unlimited.",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootParams.MetaIndex, "meta-index", "",
+		"Index to write the operational stats of each ingest into, such as corgi-meta, with the number of runs "+
+			"and documents written, errors, time spent per stage and GitHub API quota used. May contain date "+
+			"placeholders like the index. Stats aren't written unless it's set.",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootParams.AuditIndex, "audit-index", "",
+		"Index to write an audit event into for each invocation which writes, deletes or migrates documents, "+
+			"such as corgi-audit, recording who triggered it, a hash of its configuration, the runs it touched "+
+			"and the number of documents written and deleted. May contain date placeholders like the index. "+
+			"Audit events aren't written unless it's set.",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootParams.QuotasFile, "quotas-file", "",
+		"If set, limit how much each repository may ingest according to the given JSON file, with the maximum "+
+			"number of workflow runs per polling cycle, documents per day and artifact bytes per day, so that "+
+			"one repository can't exhaust the GitHub quota or cluster capacity shared with others",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootParams.QuotaStateFile, "quota-state-file", "",
+		"File to keep the daily usage of each repository in, so that consecutive invocations share the "+
+			"daily quotas, see --quotas-file",
+	)
+	rootCmd.PersistentFlags().DurationVar(
+		&rootParams.LeaseTTL, "lease-ttl", 0,
+		"If set, acquire a lease in --lease-index on each workflow run before ingesting it, so that replicas "+
+			"or overlapping jobs don't ingest the same run twice. Leases expire after this duration, which "+
+			"must exceed the time to ingest a run and flush its documents. Requires OPENSEARCH_URL.",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootParams.LeaseIndex, "lease-index", "corgi-leases",
+		"Index to store the leases on workflow runs in, see --lease-ttl",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootParams.Tenant, "tenant", "",
+		"Tenant, such as an organization or team, to write documents for. Unless --index is given, documents "+
+			"are written to indices of the tenant, such as corgi-<tenant>-testcases-{yyyy.MM}. Index flags may "+
+			"contain a "+opensearch.TenantPlaceholder+" placeholder, which is replaced by the tenant.",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootParams.TenantRetention, "tenant-retention", "",
+		"If set, create an index state management policy deleting the indices of --tenant once they are older "+
+			"than the given duration, such as 90d. Only applies to indices created afterwards. Requires --send-bulk.",
+	)
+	rootCmd.PersistentFlags().DurationVar(
+		&rootParams.SecretsRefresh, "secrets-refresh-interval", 0,
+		"If set, fetch credentials again at the given interval, such as ones stored in HashiCorp Vault or "+
+			"AWS Secrets Manager, so that rotated credentials are picked up without a restart",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootParams.OTLPEndpoint, "otlp-endpoint", tracing.EndpointFromEnv(),
+		"If set, export traces of the pipeline stages to the given OTLP/HTTP traces endpoint, for example "+
+			"http://localhost:4318/v1/traces. Defaults to the standard OTEL_EXPORTER_OTLP_TRACES_ENDPOINT "+
+			"and OTEL_EXPORTER_OTLP_ENDPOINT variables.",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootParams.PprofAddress, "pprof-address", "",
+		"If set, serve pprof profiles and pipeline metrics (under /debug/vars) on the given address, "+
+			"for example localhost:6060",
+	)
+	rootCmd.PersistentFlags().IntVar(
+		&rootParams.BufferMemoryBytes, "buffer-memory-bytes", 0,
+		"If set, bulk requests are buffered so that pulling data doesn't wait on writing them. "+
+			"Up to this many bytes are kept in memory, after which bulk requests are spilled to disk.",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootParams.SpillDir, "spill-dir", os.TempDir(),
+		"Directory to spill buffered bulk requests to, see --buffer-memory-bytes",
+	)
+	rootCmd.PersistentFlags().Int64Var(
+		&rootParams.MaxSpillBytes, "max-spill-bytes", 0,
+		"Maximum number of bytes of bulk requests to spill to disk before waiting for them to be "+
+			"written. Zero means unlimited.",
+	)
+	rootCmd.PersistentFlags().BoolVar(
+		&rootParams.SendBulk, "send-bulk", false,
+		"Send bulk requests to OPENSEARCH_URL instead of writing them to stdout. Items which were "+
+			"rejected are retried, items which failed permanently are written to --dead-letter-file.",
+	)
+	rootCmd.PersistentFlags().IntVar(
+		&rootParams.BulkBatchDocs, "bulk-batch-docs", 500,
+		"Number of documents to send per bulk request, see --send-bulk",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootParams.DeadLetterFile, "dead-letter-file", "dead-letter.json",
+		"File to append bulk entries to which OpenSearch failed to index permanently, see --send-bulk",
+	)
+	rootCmd.PersistentFlags().StringVar(
+		&rootParams.ValidateMapping, "validate-mapping", "",
+		"If set, validate documents against the index mapping in the given file, such as "+
+			"opensearch/mappings.json, before writing them. Documents with missing required fields, "+
+			"values which don't match the type of their field or oversized strings are logged and skipped.",
+	)
+	rootCmd.PersistentFlags().IntVar(
+		&rootParams.MaxFieldBytes, "max-field-bytes", 1024*1024,
+		"Maximum size of string fields of documents, see --validate-mapping. Zero means unlimited.",
+	)
+	rootCmd.PersistentFlags().StringToStringVar(
+		&rootParams.TimeoutsStr, "timeouts", map[string]string{},
+		fmt.Sprintf(
+			"Timeouts of single operations per pipeline stage in the form of '<stage>=<duration>', such as "+
+				"download=20m. Zero disables the timeout of a stage. Valid stages and their defaults are: "+
+				"%s=%s, %s=%s, %s=%s, %s=%s, %s=%s",
+			util.TimeoutStageRequest, util.DefaultTimeouts.Request,
+			util.TimeoutStageDownload, util.DefaultTimeouts.Download,
+			util.TimeoutStageParse, util.DefaultTimeouts.Parse,
+			util.TimeoutStageBulkWrite, util.DefaultTimeouts.BulkWrite,
+			util.TimeoutStageExecHook, util.DefaultTimeouts.ExecHook,
+		),
+	)
+}
 
+// documentFlagEnv documents the environment variables of the flags of the given command
+// and its sub-commands.
+func documentFlagEnv(cmd *cobra.Command) {
+	util.DocumentFlagEnv(cmd.LocalFlags())
+	util.DocumentFlagEnv(cmd.PersistentFlags())
+	for _, sub := range cmd.Commands() {
+		documentFlagEnv(sub)
+	}
 }
 
 func Execute() {
+	documentFlagEnv(rootCmd)
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+	"github.com/spf13/cobra"
+
+	gh "github.com/isovalent/corgi/pkg/github"
+	"github.com/isovalent/corgi/pkg/junit"
+	"github.com/isovalent/corgi/pkg/log"
+)
+
+type typeTailParams struct {
+	Repository string
+	Workflow   string
+	Branch     string
+	Interval   time.Duration
+	Steps      bool
+}
+
+var (
+	tailParams = &typeTailParams{}
+	tailCmd    = &cobra.Command{
+		Use:   "tail",
+		Short: "Follow the in-progress runs of a workflow",
+		Long: "Follow the in-progress runs of a workflow, printing jobs and steps as they complete and " +
+			"artifacts as they are uploaded, until interrupted. JUnit artifacts uploaded while a run is " +
+			"still in progress are downloaded and their failed tests printed right away.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if tailParams.Workflow == "" {
+				return fmt.Errorf("--workflow is required")
+			}
+			if tailParams.Interval <= 0 {
+				return fmt.Errorf("--interval must be positive")
+			}
+
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			logger := log.NewLogger(rootParams.Verbose)
+
+			repoParts := strings.Split(tailParams.Repository, "/")
+			if len(repoParts) != 2 {
+				logger.Error("Unable to extract repo owner and name from given value", "given", tailParams.Repository)
+				os.Exit(1)
+			}
+
+			token, err := gh.GetGitHubAuthToken()
+			if err != nil {
+				logger.Error("Unable to load GitHub token", "err", err)
+				os.Exit(1)
+			}
+
+			client, err := gh.NewGitHubClient(token, logger, rootParams.Timeouts.Request)
+			if err != nil {
+				logger.Error("Unable to create new GitHub Client", "err", err)
+				os.Exit(1)
+			}
+
+			tail := gh.NewRunTail(client, repoParts[0], repoParts[1], tailParams.Workflow, tailParams.Branch)
+
+			ticker := time.NewTicker(tailParams.Interval)
+			defer ticker.Stop()
+
+			for {
+				events, err := tail.Poll(ctx, logger)
+				if err != nil {
+					logger.Error("Unable to poll in-progress workflow runs", "err", err)
+				}
+
+				for _, event := range events {
+					printTailEvent(ctx, logger, client, event)
+				}
+
+				<-ticker.C
+			}
+		},
+	}
+)
+
+func init() {
+	tailCmd.Flags().StringVarP(
+		&tailParams.Repository, "repository", "r", "cilium/cilium",
+		"Repository of the workflow in owner/name format",
+	)
+	tailCmd.Flags().StringVarP(
+		&tailParams.Workflow, "workflow", "w", "",
+		"Workflow to follow, either its ID or the file name of its definition, such as ci.yaml",
+	)
+	tailCmd.Flags().StringVarP(
+		&tailParams.Branch, "branch", "b", "",
+		"Only follow runs on the given branch",
+	)
+	tailCmd.Flags().DurationVar(
+		&tailParams.Interval, "interval", 30*time.Second,
+		"How often to poll for progress",
+	)
+	tailCmd.Flags().BoolVar(
+		&tailParams.Steps, "steps", false,
+		"Also print individual steps as they complete",
+	)
+
+	rootCmd.AddCommand(tailCmd)
+}
+
+// printTailEvent prints a single line for the given event. Failed tests found in
+// newly uploaded JUnit artifacts are printed below it.
+func printTailEvent(ctx context.Context, logger *slog.Logger, client *github.Client, event gh.TailEvent) {
+	prefix := fmt.Sprintf("%s %s #%d", time.Now().Format(time.TimeOnly), event.Run.Name, event.Run.RunNumber)
+
+	switch event.Kind {
+	case gh.TailEventRunStarted:
+		fmt.Printf("%s started: %s %s\n", prefix, event.Run.DisplayTitle, event.Run.Link)
+	case gh.TailEventJobCompleted:
+		fmt.Printf("%s job %q %s\n", prefix, event.Job, event.Conclusion)
+	case gh.TailEventStepCompleted:
+		if tailParams.Steps || event.Conclusion == "failure" {
+			fmt.Printf("%s step %q of job %q %s\n", prefix, event.Step, event.Job, event.Conclusion)
+		}
+	case gh.TailEventRunCompleted:
+		fmt.Printf("%s completed: %s\n", prefix, event.Conclusion)
+	case gh.TailEventArtifact:
+		fmt.Printf("%s artifact %q uploaded\n", prefix, event.Artifact.GetName())
+
+		if !strings.Contains(event.Artifact.GetName(), "junit") {
+			return
+		}
+
+		_, cases, _, err := gh.GetTestsForArtifact(
+			ctx, logger, client, event.Run, event.Artifact, rootParams.Timeouts,
+			&junit.Options{
+				AllowedTestConclusions: []string{junit.StatusFailed, junit.StatusError},
+				MaxCompressionRatio:    junit.DefaultMaxCompressionRatio,
+			},
+		)
+		if err != nil {
+			logger.Error("Unable to parse junit artifact", "artifact", event.Artifact.GetName(), "err", err)
+			return
+		}
+
+		for _, tc := range cases {
+			fmt.Printf("%s   %s %s\n", prefix, tc.Status, tc.Name)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/report"
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+type typeTeamFlakinessParams struct {
+	WindowStr  string
+	Window     time.Duration
+	Repository string
+	Interval   time.Duration
+}
+
+// indexTeamFlakiness scores the owning teams within the window ending now and the window
+// before it, and writes the flakiness documents of the teams.
+func indexTeamFlakiness(ctx context.Context, logger *slog.Logger, client *opensearchgo.Client) error {
+	until := time.Now().UTC()
+	since := until.Add(-teamFlakinessParams.Window)
+	index := readIndex(types.TypeNameWorkflowRun, types.TypeNameTestcase)
+
+	current, err := report.LoadBuilder(ctx, client, index, since, until, teamFlakinessParams.Repository)
+	if err != nil {
+		return err
+	}
+
+	previous, err := report.LoadBuilder(
+		ctx, client, index, since.Add(-teamFlakinessParams.Window), since, teamFlakinessParams.Repository,
+	)
+	if err != nil {
+		return err
+	}
+
+	flakiness := report.NewTeamFlakiness(
+		current.Leaderboard(), previous.Leaderboard(), since, until, teamFlakinessParams.Repository,
+	)
+
+	logger.Info(
+		"Computed team flakiness, saving",
+		"num-results", len(flakiness), "target-index", indexFor(types.TypeNameTeamFlakiness),
+	)
+
+	if err := opensearch.BulkWriteObjects(
+		flakiness, indexFor(types.TypeNameTeamFlakiness), rootParams.BulkOptions, bulkOutput,
+	); err != nil {
+		return fmt.Errorf("unable to write team flakiness: %w", err)
+	}
+
+	return nil
+}
+
+var (
+	teamFlakinessParams = &typeTeamFlakinessParams{}
+	teamFlakinessCmd    = &cobra.Command{
+		Use:   "team-flakiness",
+		Short: "Index the flake debt of each owning team",
+		Long: "Compute the tests owned, flaky tests, flake score and retries of each owning team within " +
+			"the window ending now, along with those of the window before it, and write them as " +
+			"team_flakiness documents. The flake score is computed as for 'report leaderboard'. Retries " +
+			"are workflow run attempts which were retried after tests failed in them, split between the " +
+			"owners of the failed tests. With --interval, they are recomputed periodically until interrupted.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			window, err := util.ParseDuration(teamFlakinessParams.WindowStr)
+			if err != nil {
+				return fmt.Errorf("unable to parse window: %w", err)
+			}
+			teamFlakinessParams.Window = window
+
+			if teamFlakinessParams.Interval < 0 {
+				return fmt.Errorf("--interval must not be negative")
+			}
+
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := log.NewLogger(rootParams.Verbose)
+
+			opensearchCfg, err := opensearch.NewClientConfig()
+			if err != nil {
+				logger.Error("Unable to load OpenSearch configuration", "err", err)
+				os.Exit(1)
+			}
+
+			opsClient, err := opensearchgo.NewClient(opensearchCfg)
+			if err != nil {
+				logger.Error("Unable to create opensearch client", "err", err)
+				os.Exit(1)
+			}
+
+			if err := runPeriodically(logger, teamFlakinessParams.Interval, func(ctx context.Context) error {
+				return indexTeamFlakiness(ctx, logger, opsClient)
+			}); err != nil {
+				logger.Error("Unable to index team flakiness", "err", err)
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func init() {
+	teamFlakinessCmd.PersistentFlags().StringVarP(
+		&teamFlakinessParams.WindowStr, "window", "w", "7d",
+		"Time window to score teams over, ending now, such as 7d. The trend compares it to the window before it.",
+	)
+	teamFlakinessCmd.PersistentFlags().StringVarP(
+		&teamFlakinessParams.Repository, "repository", "r", "",
+		"Only look at workflow runs of the given repository, such as cilium/cilium",
+	)
+	teamFlakinessCmd.PersistentFlags().DurationVar(
+		&teamFlakinessParams.Interval, "interval", 0,
+		"Recompute the scores this often until interrupted, such as 24h. Zero computes them once.",
+	)
+
+	rootCmd.AddCommand(teamFlakinessCmd)
+}
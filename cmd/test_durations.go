@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/report"
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+type typeTestDurationsParams struct {
+	WindowStr  string
+	Window     time.Duration
+	Repository string
+	Branch     string
+	MinSamples int
+	Interval   time.Duration
+}
+
+// indexTestDurations computes the duration percentiles of the tests within the window
+// ending now and writes them.
+func indexTestDurations(ctx context.Context, logger *slog.Logger, client *opensearchgo.Client) error {
+	until := time.Now().UTC()
+	since := until.Add(-testDurationsParams.Window)
+
+	cases, err := report.LoadPassedTestcases(
+		ctx, client, readIndex(types.TypeNameTestcase), since, until,
+		testDurationsParams.Repository, testDurationsParams.Branch,
+	)
+	if err != nil {
+		return err
+	}
+
+	durations := report.NewTestDurations(
+		cases, since, until, testDurationsParams.Branch, testDurationsParams.MinSamples,
+	)
+
+	logger.Info(
+		"Computed test durations, saving",
+		"testcases", len(cases), "num-results", len(durations), "target-index", indexFor(types.TypeNameTestDuration),
+	)
+
+	if err := opensearch.BulkWriteObjects(
+		durations, indexFor(types.TypeNameTestDuration), rootParams.BulkOptions, bulkOutput,
+	); err != nil {
+		return fmt.Errorf("unable to write test durations: %w", err)
+	}
+
+	return nil
+}
+
+var (
+	testDurationsParams = &typeTestDurationsParams{}
+	testDurationsCmd    = &cobra.Command{
+		Use:   "test-durations",
+		Short: "Index the p50, p90 and p99 duration of each test per workflow",
+		Long: "Compute the p50, p90 and p99 duration of each test per workflow from its passed runs " +
+			"within the window ending now, and write them as test_duration documents. Failed runs and " +
+			"runs with clamped durations are left out, so that the percentiles are robust baselines. " +
+			"With --interval, the percentiles are recomputed periodically until interrupted.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			window, err := util.ParseDuration(testDurationsParams.WindowStr)
+			if err != nil {
+				return fmt.Errorf("unable to parse window: %w", err)
+			}
+			testDurationsParams.Window = window
+
+			if testDurationsParams.Interval < 0 {
+				return fmt.Errorf("--interval must not be negative")
+			}
+
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := log.NewLogger(rootParams.Verbose)
+
+			opensearchCfg, err := opensearch.NewClientConfig()
+			if err != nil {
+				logger.Error("Unable to load OpenSearch configuration", "err", err)
+				os.Exit(1)
+			}
+
+			opsClient, err := opensearchgo.NewClient(opensearchCfg)
+			if err != nil {
+				logger.Error("Unable to create opensearch client", "err", err)
+				os.Exit(1)
+			}
+
+			if err := runPeriodically(logger, testDurationsParams.Interval, func(ctx context.Context) error {
+				return indexTestDurations(ctx, logger, opsClient)
+			}); err != nil {
+				logger.Error("Unable to index test durations", "err", err)
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func init() {
+	testDurationsCmd.PersistentFlags().StringVarP(
+		&testDurationsParams.WindowStr, "window", "w", "14d",
+		"Time window to compute the percentiles over, ending now, such as 14d or 30d",
+	)
+	testDurationsCmd.PersistentFlags().StringVarP(
+		&testDurationsParams.Repository, "repository", "r", "",
+		"Only look at workflow runs of the given repository, such as cilium/cilium",
+	)
+	testDurationsCmd.PersistentFlags().StringVarP(
+		&testDurationsParams.Branch, "branch", "b", "main",
+		"Only look at workflow runs on the given branch. An empty value looks at every branch.",
+	)
+	testDurationsCmd.PersistentFlags().IntVar(
+		&testDurationsParams.MinSamples, "min-samples", 5,
+		"Minimum number of passed runs of a test within the window for its percentiles to be written",
+	)
+	testDurationsCmd.PersistentFlags().DurationVar(
+		&testDurationsParams.Interval, "interval", 0,
+		"Recompute the percentiles this often until interrupted, such as 6h. Zero computes them once.",
+	)
+
+	rootCmd.AddCommand(testDurationsCmd)
+}
@@ -0,0 +1,295 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/report"
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+type typeTriageParams struct {
+	Repository string
+	Branch     string
+	WindowStr  string
+	Window     time.Duration
+	User       string
+}
+
+const triageHelp = `Commands:
+  l                 list failures
+  o [owner]         only list failures of the given owner or team member, or all failures without an owner
+  a                 also list failures marked as known or ignored
+  v <n>             view the failure text of the latest failure of item n
+  k <n> [note]      mark item n as a known failure
+  i <n> [note]      mark item n as ignored
+  u <n>             mark item n as open again
+  w <n>             open the workflow run of the latest failure of item n
+  q                 quit
+`
+
+var (
+	triageParams = &typeTriageParams{}
+	triageCmd    = &cobra.Command{
+		Use:   "triage",
+		Short: "Interactively triage the current failures of a branch",
+		Long: "List the tests which failed on a branch within the window, most frequent first, and triage " +
+			"them interactively. Tests can be marked as known failures or ignored, which is written back to " +
+			"OpenSearch as triage documents, so that everyone triaging shares the same state.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			window, err := util.ParseDuration(triageParams.WindowStr)
+			if err != nil {
+				return fmt.Errorf("unable to parse window: %w", err)
+			}
+			triageParams.Window = window
+
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			logger := log.NewLogger(rootParams.Verbose)
+
+			opensearchCfg, err := opensearch.NewClientConfig()
+			if err != nil {
+				logger.Error("Unable to load OpenSearch configuration", "err", err)
+				os.Exit(1)
+			}
+
+			opsClient, err := opensearchgo.NewClient(opensearchCfg)
+			if err != nil {
+				logger.Error("Unable to create opensearch client", "err", err)
+				os.Exit(1)
+			}
+
+			until := time.Now()
+			cases, err := report.LoadFailures(
+				ctx, opsClient, readIndex(types.TypeNameTestcase), until.Add(-triageParams.Window), until,
+				triageParams.Repository, triageParams.Branch,
+			)
+			if err != nil {
+				logger.Error("Unable to load failures", "err", err)
+				os.Exit(1)
+			}
+
+			triage, err := report.LoadTriage(ctx, opsClient, readIndex(types.TypeNameTriage), triageParams.Repository)
+			if err != nil {
+				logger.Error("Unable to load triage", "err", err)
+				os.Exit(1)
+			}
+
+			s := &triageSession{
+				ctx:    ctx,
+				client: opsClient,
+				out:    os.Stdout,
+				items:  report.NewTriageItems(cases, triage),
+			}
+			s.run(os.Stdin)
+		},
+	}
+)
+
+// triageSession holds the state of an interactive triage session.
+type triageSession struct {
+	ctx    context.Context
+	client *opensearchgo.Client
+	out    io.Writer
+
+	items []*report.TriageItem
+	// owner only lists items of the given owner, if set.
+	owner string
+	// all also lists items which were marked as known or ignored.
+	all bool
+	// listed are the items shown by the last list, which commands refer to by number.
+	listed []*report.TriageItem
+}
+
+func (s *triageSession) run(in io.Reader) {
+	s.list()
+
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(s.out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(s.out)
+			return
+		}
+
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		cmd, args := fields[0], fields[1:]
+
+		var err error
+		switch cmd {
+		case "l":
+			s.list()
+		case "o":
+			s.owner = strings.Join(args, " ")
+			s.list()
+		case "a":
+			s.all = !s.all
+			s.list()
+		case "v":
+			err = s.withItem(args, s.view)
+		case "k":
+			err = s.withItem(args, func(item *report.TriageItem) error {
+				return s.mark(item, types.TriageStateKnown, strings.Join(args[1:], " "))
+			})
+		case "i":
+			err = s.withItem(args, func(item *report.TriageItem) error {
+				return s.mark(item, types.TriageStateIgnored, strings.Join(args[1:], " "))
+			})
+		case "u":
+			err = s.withItem(args, func(item *report.TriageItem) error {
+				return s.mark(item, types.TriageStateOpen, "")
+			})
+		case "w":
+			err = s.withItem(args, s.open)
+		case "q":
+			return
+		default:
+			fmt.Fprint(s.out, triageHelp)
+		}
+
+		if err != nil {
+			fmt.Fprintf(s.out, "error: %s\n", err)
+		}
+	}
+}
+
+// withItem calls fn with the listed item whose number is the first argument.
+func (s *triageSession) withItem(args []string, fn func(item *report.TriageItem) error) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected the number of an item")
+	}
+
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 || n > len(s.listed) {
+		return fmt.Errorf("expected the number of a listed item, got %q", args[0])
+	}
+
+	return fn(s.listed[n-1])
+}
+
+func (s *triageSession) list() {
+	s.listed = s.listed[:0]
+	for _, item := range s.items {
+		if !s.all && item.State() != types.TriageStateOpen {
+			continue
+		}
+		if s.owner != "" && !item.OwnedBy(s.owner) {
+			continue
+		}
+		s.listed = append(s.listed, item)
+	}
+
+	if len(s.listed) == 0 {
+		fmt.Fprintln(s.out, "No failures to triage")
+		return
+	}
+
+	w := tabwriter.NewWriter(s.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tTEST\tFAILURES\tSTATE\tOWNERS\tLAST FAILURE")
+	for i, item := range s.listed {
+		fmt.Fprintf(
+			w, "%d\t%s\t%d\t%s\t%s\t%s\n",
+			i+1, item.Test, item.Occurrences, item.State(), strings.Join(item.Owners, ", "),
+			item.Last.WorkflowRun.CreatedAt.UTC().Format(time.DateTime),
+		)
+	}
+	w.Flush()
+}
+
+func (s *triageSession) view(item *report.TriageItem) error {
+	fmt.Fprintf(s.out, "%s\n%s\n\n", item.Test, item.Last.WorkflowRun.Link)
+	if item.Last.FailureMessage != "" {
+		fmt.Fprintln(s.out, item.Last.FailureMessage)
+	}
+	fmt.Fprintln(s.out, item.Last.FailureText)
+	if item.Triage != nil && item.Triage.Note != "" {
+		fmt.Fprintf(s.out, "\nTriage note by %s: %s\n", item.Triage.User, item.Triage.Note)
+	}
+
+	return nil
+}
+
+// mark records the given triage state of the item in OpenSearch.
+func (s *triageSession) mark(item *report.TriageItem, state, note string) error {
+	t := types.Triage{
+		Type:       types.TypeNameTriage,
+		Repository: item.Last.WorkflowRun.Repository.FullName,
+		TestName:   item.Test,
+		State:      state,
+		Note:       note,
+		User:       triageParams.User,
+		Timestamp:  time.Now(),
+	}
+
+	id, err := rootParams.BulkOptions.DocumentID(t)
+	if err != nil {
+		return err
+	}
+
+	index := opensearch.ResolveIndexName(indexFor(types.TypeNameTriage), t.Timestamp)
+	if err := opensearch.IndexDocument(s.ctx, s.client, index, id, t); err != nil {
+		return err
+	}
+
+	item.Triage = &t
+	fmt.Fprintf(s.out, "Marked %s as %s\n", item.Test, state)
+
+	return nil
+}
+
+// open opens the workflow run of the latest failure of the item in the browser.
+func (s *triageSession) open(item *report.TriageItem) error {
+	url := item.Last.WorkflowRun.Link
+
+	opener := "xdg-open"
+	if runtime.GOOS == "darwin" {
+		opener = "open"
+	}
+
+	if err := exec.Command(opener, url).Start(); err != nil {
+		fmt.Fprintf(s.out, "Unable to open browser, the run is at %s\n", url)
+	}
+
+	return nil
+}
+
+func init() {
+	triageCmd.PersistentFlags().StringVarP(
+		&triageParams.Repository, "repository", "r", "",
+		"Only triage failures of the given repository, such as cilium/cilium",
+	)
+	triageCmd.PersistentFlags().StringVarP(
+		&triageParams.Branch, "branch", "b", "main",
+		"Branch to triage the failures of",
+	)
+	triageCmd.PersistentFlags().StringVarP(
+		&triageParams.WindowStr, "window", "w", "24h",
+		"Time window to list failures of, ending now, such as 24h or 7d",
+	)
+	triageCmd.PersistentFlags().StringVar(
+		&triageParams.User, "user", os.Getenv("USER"),
+		"Name to record triage decisions under",
+	)
+
+	rootCmd.AddCommand(triageCmd)
+}
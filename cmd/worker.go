@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+	"github.com/spf13/cobra"
+
+	gh "github.com/isovalent/corgi/pkg/github"
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/metrics"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/queue"
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+// workerRetryInterval is how long to wait before receiving again after receiving failed.
+const workerRetryInterval = 10 * time.Second
+
+type typeWorkerParams struct {
+	QueueURL string
+}
+
+var (
+	workerParams = &typeWorkerParams{}
+	workerCmd    = &cobra.Command{
+		Use:   "worker",
+		Short: "Ingest workflow runs as jobs arrive on a queue",
+		Long: "Consume ingestion jobs from an SQS queue or a Google Pub/Sub subscription until interrupted, " +
+			"ingesting every attempt of the workflow run of each job. Jobs are JSON messages such as " +
+			`{"repository": "cilium/cilium", "run_id": 123}. Messages are acknowledged once their run was ` +
+			"ingested and its documents were flushed, so that jobs which fail are delivered again, possibly to another worker. Tests are parsed using the defaults of the 'workflow runs' command.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if workerParams.QueueURL == "" {
+				return fmt.Errorf("--queue-url is required")
+			}
+
+			return compileTestParams()
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ctx := context.Background()
+			logger := log.NewLogger(rootParams.Verbose)
+
+			startIngestStats(cmd)
+			startAudit(cmd)
+
+			// On SIGTERM or interrupt, stop receiving jobs but finish the ones received, so
+			// that their documents are flushed before exiting.
+			stop, cancelStop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+			defer cancelStop()
+
+			q, err := queue.New(workerParams.QueueURL)
+			if err != nil {
+				logger.Error("Unable to create queue consumer", "err", err)
+				os.Exit(1)
+			}
+
+			token, err := gh.GetGitHubAuthToken()
+			if err != nil {
+				logger.Error("Unable to load GitHub token", "err", err)
+				os.Exit(1)
+			}
+
+			client, err := gh.NewGitHubClient(token, logger, rootParams.Timeouts.Request)
+			if err != nil {
+				logger.Error("Unable to create new GitHub Client", "err", err)
+				os.Exit(1)
+			}
+
+			logger.Info("Waiting for ingestion jobs", "queue", workerParams.QueueURL)
+
+			for stop.Err() == nil {
+				msgs, err := q.Receive(stop)
+				if err != nil {
+					if stop.Err() == nil {
+						logger.Error("Unable to receive ingestion jobs", "err", err)
+						select {
+						case <-time.After(workerRetryInterval):
+						case <-stop.Done():
+						}
+					}
+					continue
+				}
+
+				for _, msg := range msgs {
+					processJob(ctx, logger, client, q, msg)
+				}
+			}
+
+			logger.Info("Received shutdown signal, flushing documents of the jobs processed so far")
+			metrics.LogSummary(logger)
+		},
+	}
+)
+
+func init() {
+	workerCmd.Flags().StringVar(
+		&workerParams.QueueURL, "queue-url", "",
+		"URL of the SQS queue, such as https://sqs.us-east-1.amazonaws.com/123456789012/corgi-jobs, or name of "+
+			"the Pub/Sub subscription, such as projects/my-project/subscriptions/corgi-jobs, to consume jobs from",
+	)
+
+	rootCmd.AddCommand(workerCmd)
+}
+
+// processJob ingests the workflow run of the job in the given message, acknowledging
+// the message once its documents were flushed. Messages which don't contain a valid job are
+// acknowledged as well, as they would never succeed.
+func processJob(ctx context.Context, logger *slog.Logger, client *github.Client, q queue.Queue, msg queue.Message) {
+	logger = logger.With("message-id", msg.ID)
+
+	job, err := msg.Job()
+	if err != nil {
+		logger.Error("Dropping invalid ingestion job", "err", err)
+		ackJob(ctx, logger, q, msg)
+		return
+	}
+
+	logger = logger.With("repository", job.Repository, "workflow-id", job.RunID)
+
+	runs, err := gh.GetWorkflowRunAttempts(ctx, logger, client, job.Owner(), job.Name(), job.RunID)
+	if err != nil {
+		logger.Error("Unable to pull workflow run, leaving job to be delivered again", "err", err)
+		return
+	}
+
+	logger.Info("Ingesting workflow run", "attempts", len(runs))
+
+	runsCh := make(chan *types.WorkflowRun, len(runs))
+	for _, run := range runs {
+		runsCh <- run
+	}
+	close(runsCh)
+
+	prefetched := prefetchArtifacts(
+		ctx, logger, client, claimRuns(ctx, logger, runsCh), workflowRunsParams.ArtifactPrefetchConcurrency,
+	)
+	exhausted := false
+	for prefetch := range prefetched {
+		if quotaExhausted(logger, prefetch.run) {
+			releaseRunLease(ctx, logger, prefetch.run)
+			exhausted = true
+			continue
+		}
+
+		if !renewRunLease(ctx, logger, prefetch.run) {
+			continue
+		}
+
+		writeRunResult(ctx, logger, pullRun(ctx, logger, client, prefetch))
+	}
+
+	// Jobs of repositories which exhausted their quota are delivered again later.
+	if exhausted {
+		return
+	}
+
+	// Documents which are still buffered by the bulk output would be lost if the
+	// worker stopped after acknowledging the job.
+	if err := opensearch.Flush(bulkOutput); err != nil {
+		logger.Error("Unable to flush documents of ingestion job, leaving it to be delivered again", "err", err)
+		return
+	}
+
+	ackJob(ctx, logger, q, msg)
+}
+
+func ackJob(ctx context.Context, logger *slog.Logger, q queue.Queue, msg queue.Message) {
+	ackCtx, cancel := util.WithTimeout(ctx, rootParams.Timeouts.Request)
+	defer cancel()
+
+	if err := q.Ack(ackCtx, msg); err != nil {
+		logger.Warn("Unable to acknowledge ingestion job, it will be delivered again", "err", err)
+	}
+}
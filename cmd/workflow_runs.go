@@ -2,19 +2,33 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"regexp"
+	"slices"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/google/go-github/v60/github"
+	opensearchgo "github.com/opensearch-project/opensearch-go"
 	"github.com/spf13/cobra"
 
+	"github.com/isovalent/corgi/pkg/archive"
+	"github.com/isovalent/corgi/pkg/embedding"
 	gh "github.com/isovalent/corgi/pkg/github"
+	"github.com/isovalent/corgi/pkg/junit"
 	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/metrics"
 	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/owners"
+	"github.com/isovalent/corgi/pkg/tracing"
 	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
 )
 
 type typeWorkflowRunsParams struct {
@@ -29,11 +43,215 @@ type typeWorkflowRunsParams struct {
 	JobConclusions              []string
 	TestConclusions             []string
 	RunStatuses                 []string
+	RunConclusionRulesStr       []string
+	RunConclusionRules          []gh.RunConclusionRule
 	OnlyFailedSteps             bool
 	IncludeTestsuites           bool
 	IncludeErrorLogs            bool
 	ParseWorkflowDispatchInputs bool
+	WorkflowDispatchInputsJob   string
+	IncludePullRequestReviews   bool
+	IncludePushCommits          bool
+	IncludeEventDetails         bool
+	PushCommitsBranches         []string
+	IncludeCacheStats           bool
+	MarkRequiredChecks          bool
+	PollInterval                time.Duration
+	CheckpointFile              string
+	Strict                      bool
+	MaxSuitesPerFile            int
+	MaxTestcasesPerSuite        int
+	MaxDocsPerRun               int
+	MaxCompressionRatio         int
+	MaxArtifactBytes            int64
+	MaxParseErrors              int
+	RequiredChecks              []string
 	WorkflowID                  int64
+	Force                       bool
+	MaxFailureTextBytes         int
+	CompressFailureText         bool
+	ArtifactPrefetchConcurrency int
+	SystemErrPatternsStr        []string
+	SystemErrPatterns           []*regexp.Regexp
+	OwnerWeighting              string
+	CodeownersPath              string
+	Codeowners                  *owners.Codeowners
+	TeamsPath                   string
+	Teams                       *owners.Teams
+	ExpandTeamOwners            bool
+	TeamMembersTTL              time.Duration
+	TeamMembers                 *gh.TeamMembers
+	RedactionsStr               []string
+	Redactions                  []junit.Redaction
+	SecretRedactionsStr         []string
+	SecretRedactions            []junit.Redaction
+	RedactIPs                   bool
+	FailureDataFormats          []string
+	TestStatusAliases           map[string]string
+	FailureSignaturesStr        []string
+	FailureSignatures           []junit.FailureSignature
+	FailureDataParsers          []junit.FailureDataParser
+	ArtifactRoutesStr           []string
+	ArtifactRoutes              []gh.ArtifactRoute
+	ArchiveURL                  string
+	Archive                     archive.Archive
+	EmbeddingURL                string
+	EmbeddingModel              string
+	EmbeddingPipeline           string
+}
+
+// compileTestParams validates and compiles the parameters controlling how tests are parsed.
+func compileTestParams() error {
+	switch junit.OwnerWeighting(workflowRunsParams.OwnerWeighting) {
+	case junit.OwnerWeightingEven, junit.OwnerWeightingPrimary:
+	default:
+		return fmt.Errorf("unknown owner weighting: %s", workflowRunsParams.OwnerWeighting)
+	}
+
+	for _, p := range workflowRunsParams.SystemErrPatternsStr {
+		pattern, err := regexp.Compile(p)
+		if err != nil {
+			return fmt.Errorf("unable to compile system-err pattern '%s': %w", p, err)
+		}
+		workflowRunsParams.SystemErrPatterns = append(workflowRunsParams.SystemErrPatterns, pattern)
+	}
+
+	parsers, err := junit.FailureDataParsers(workflowRunsParams.FailureDataFormats)
+	if err != nil {
+		return fmt.Errorf("%w, valid formats are: %s", err, strings.Join(junit.FailureDataFormats(), ", "))
+	}
+	workflowRunsParams.FailureDataParsers = parsers
+
+	if workflowRunsParams.CodeownersPath != "" {
+		f, err := os.Open(workflowRunsParams.CodeownersPath)
+		if err != nil {
+			return fmt.Errorf("unable to open CODEOWNERS: %w", err)
+		}
+		defer f.Close()
+
+		c, err := owners.ParseCodeowners(f)
+		if err != nil {
+			return err
+		}
+		workflowRunsParams.Codeowners = c
+	}
+
+	if workflowRunsParams.TeamsPath != "" {
+		teams, err := owners.LoadTeams(workflowRunsParams.TeamsPath)
+		if err != nil {
+			return err
+		}
+		workflowRunsParams.Teams = teams
+	}
+
+	if workflowRunsParams.ExpandTeamOwners {
+		workflowRunsParams.TeamMembers = gh.NewTeamMembers(workflowRunsParams.TeamMembersTTL)
+	}
+
+	for _, sig := range workflowRunsParams.FailureSignaturesStr {
+		signature, err := junit.ParseFailureSignature(sig)
+		if err != nil {
+			return err
+		}
+		workflowRunsParams.FailureSignatures = append(workflowRunsParams.FailureSignatures, signature)
+	}
+
+	for _, r := range workflowRunsParams.RedactionsStr {
+		redaction, err := junit.ParseRedaction(r)
+		if err != nil {
+			return err
+		}
+		workflowRunsParams.Redactions = append(workflowRunsParams.Redactions, redaction)
+	}
+
+	secretRedactions := workflowRunsParams.SecretRedactionsStr
+	if workflowRunsParams.RedactIPs {
+		secretRedactions = append(slices.Clone(secretRedactions), junit.IPRedaction)
+	}
+	for _, r := range secretRedactions {
+		redaction, err := junit.ParseRedaction(r)
+		if err != nil {
+			return err
+		}
+		workflowRunsParams.SecretRedactions = append(workflowRunsParams.SecretRedactions, redaction)
+	}
+
+	for _, r := range workflowRunsParams.ArtifactRoutesStr {
+		route, err := gh.ParseArtifactRoute(r)
+		if err != nil {
+			return err
+		}
+		if name, ok := strings.CutPrefix(route.Parser, gh.ArtifactParserPluginPrefix); ok && rootParams.Converters[name] == nil {
+			return fmt.Errorf("artifact route '%s' needs a parser plugin %s, see --plugin", r, name)
+		}
+		workflowRunsParams.ArtifactRoutes = append(workflowRunsParams.ArtifactRoutes, route)
+	}
+
+	for _, r := range workflowRunsParams.RunConclusionRulesStr {
+		rule, err := gh.ParseRunConclusionRule(r)
+		if err != nil {
+			return err
+		}
+		workflowRunsParams.RunConclusionRules = append(workflowRunsParams.RunConclusionRules, rule)
+	}
+
+	if workflowRunsParams.ArchiveURL != "" {
+		a, err := archive.New(workflowRunsParams.ArchiveURL)
+		if err != nil {
+			return err
+		}
+		workflowRunsParams.Archive = a
+	}
+
+	return nil
+}
+
+// parseOptions returns the options artifacts of runs are parsed with.
+func parseOptions() *junit.Options {
+	return &junit.Options{
+		AllowedTestConclusions: workflowRunsParams.TestConclusions,
+		MaxFailureTextBytes:    workflowRunsParams.MaxFailureTextBytes,
+		CompressFailureText:    workflowRunsParams.CompressFailureText,
+		SystemErrPatterns:      workflowRunsParams.SystemErrPatterns,
+		OwnerWeighting:         junit.OwnerWeighting(workflowRunsParams.OwnerWeighting),
+		Redactions:             workflowRunsParams.Redactions,
+		SecretRedactions:       workflowRunsParams.SecretRedactions,
+		FailureDataParsers:     workflowRunsParams.FailureDataParsers,
+		Codeowners:             workflowRunsParams.Codeowners,
+		Teams:                  workflowRunsParams.Teams,
+		StatusAliases:          workflowRunsParams.TestStatusAliases,
+		FailureSignatures:      workflowRunsParams.FailureSignatures,
+		Strict:                 workflowRunsParams.Strict,
+		MaxSuitesPerFile:       workflowRunsParams.MaxSuitesPerFile,
+		MaxTestcasesPerSuite:   workflowRunsParams.MaxTestcasesPerSuite,
+		MaxCompressionRatio:    workflowRunsParams.MaxCompressionRatio,
+		MaxArtifactBytes:       workflowRunsParams.MaxArtifactBytes,
+		MaxParseErrors:         workflowRunsParams.MaxParseErrors,
+		Converters:             rootParams.Converters,
+	}
+}
+
+// isRunIngested returns true if the given workflow run has already been indexed
+// into the target index in a completed state.
+func isRunIngested(ctx context.Context, client *opensearchgo.Client, run *types.WorkflowRun) (bool, error) {
+	id, err := rootParams.BulkOptions.DocumentID(run)
+	if err != nil {
+		return false, err
+	}
+
+	index := opensearch.IndexPattern(indexFor(types.TypeNameWorkflowRun))
+	routing := opensearch.GetDocumentRouting(run, rootParams.BulkOptions.Routing)
+
+	ctx, cancel := util.WithTimeout(ctx, rootParams.Timeouts.Request)
+	defer cancel()
+
+	doc, err := opensearch.GetDocument(ctx, client, index, id, routing)
+	if err != nil {
+		return false, err
+	}
+
+	// Runs which were ingested while still in progress need to be pulled again.
+	return doc != nil && doc["workflow_status"] == "completed", nil
 }
 
 func setTestedFields(
@@ -54,88 +272,808 @@ func setTestedFields(
 		return
 	}
 
-	if !workflowRunsParams.ParseWorkflowDispatchInputs {
-		return
+	if !workflowRunsParams.ParseWorkflowDispatchInputs {
+		return
+	}
+
+	var echoJob *types.JobRun
+
+	for _, job := range *jobs {
+		if job.Name == "Echo Workflow Dispatch Inputs" {
+			echoJob = &job
+			break
+		}
+	}
+
+	if echoJob == nil {
+		if run.HeadBranch == "main" {
+			// The echo-inputs job is currently being backported to other stable branches.
+			// Do not fail if we are pulling jobs for a different branch.
+			logger.Error("Got workflow_dispatch event with no echo-inputs job")
+			os.Exit(1)
+		}
+		return
+	}
+
+	if echoJob.Conclusion != "success" {
+		logger.Error("echo-inputs job was not successful")
+		os.Exit(1)
+	}
+
+	logs, err := gh.GetLogsForJob(
+		ctx, logger, client, echoJob.ID, run.Repository.Owner.Login, run.Repository.Name, rootParams.Timeouts.Download,
+	)
+	if err != nil {
+		logger.Error(
+			"Unable to pull logs for echo-inputs job",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+
+	inputs := gh.ParseEchoInputsLogs(logs)
+
+	if len(inputs) == 0 {
+		logger.Error("Parsed no inputs for workflow_dispatch workflow")
+		os.Exit(1)
+	}
+
+	logger.Debug(
+		"Got inputs for workflow_dispatch workflow",
+		"inputs", inputs,
+	)
+	run.WorkflowDispatchInputs = inputs
+
+	sha, ok := inputs["SHA"]
+	if !ok {
+		logger.Error("Parsed inputs for workflow_dispatch workflow do not contain SHA")
+		os.Exit(1)
+	}
+
+	run.TestedSHA = sha
+
+	commit, err := gh.GetCommitBySHA(
+		ctx, logger, run.Repository.Owner.Login,
+		run.Repository.Name, client, sha,
+	)
+	if err != nil {
+		logger.Error("Unable to get commit info for sha", "sha", sha)
+		os.Exit(1)
+	}
+
+	run.TestedCommit = *commit
+
+	contextRef, ok := inputs["context-ref"]
+	if !ok {
+		logger.Error("Parsed inputs for workflow_dispatch workflow do not contain context-ref")
+		os.Exit(1)
+	}
+
+	run.TestedBranch = contextRef
+}
+
+// setWorkflowDispatchInputs sets the inputs of the given workflow_dispatch run, parsed from
+// the logs of its job named --wd-inputs-job, which echoes them as a JSON object. GitHub
+// doesn't expose the inputs of runs through its API. Runs without such a job are left
+// without inputs.
+func setWorkflowDispatchInputs(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	run *types.WorkflowRun,
+	jobs []types.JobRun,
+) {
+	if run.Event != "workflow_dispatch" || !workflowRunsParams.ParseWorkflowDispatchInputs {
+		return
+	}
+
+	var echoJob *types.JobRun
+	for i := range jobs {
+		if jobs[i].Name == workflowRunsParams.WorkflowDispatchInputsJob {
+			echoJob = &jobs[i]
+			break
+		}
+	}
+
+	if echoJob == nil {
+		logger.Debug("Got workflow_dispatch event with no echo-inputs job")
+		return
+	}
+
+	if echoJob.Conclusion != "success" {
+		logger.Warn("echo-inputs job was not successful, not parsing inputs", "conclusion", echoJob.Conclusion)
+		return
+	}
+
+	logs, err := gh.GetLogsForJob(
+		ctx, logger, client, echoJob.ID, run.Repository.Owner.Login, run.Repository.Name, rootParams.Timeouts.Download,
+	)
+	if err != nil {
+		logger.Warn("Unable to pull logs for echo-inputs job, not parsing inputs", "err", err)
+		return
+	}
+
+	inputs := gh.ParseEchoInputsLogs(logs)
+
+	if len(inputs) == 0 {
+		logger.Warn("Parsed no inputs for workflow_dispatch workflow")
+		return
+	}
+
+	logger.Debug(
+		"Got inputs for workflow_dispatch workflow",
+		"inputs", inputs,
+	)
+	run.WorkflowDispatchInputs = inputs
+}
+
+// pipelineBufferSize is the number of items that may be waiting between each
+// stage of the ingestion pipeline. Keeping this bounded ensures memory stays
+// flat regardless of the number of runs being processed.
+const pipelineBufferSize = 10
+
+// runResult holds the documents pulled for a single workflow run.
+type runResult struct {
+	run    *types.WorkflowRun
+	jobs   []types.JobRun
+	steps  []types.StepRun
+	suites []types.Testsuite
+	cases  []types.Testcase
+	// sysdumps and logSignatures are parsed from the sysdump and log artifacts of the run.
+	sysdumps      []types.Sysdump
+	logSignatures []types.LogSignature
+	// ingestErrors record the artifact files which had to be skipped.
+	ingestErrors []types.IngestError
+	artifacts    []types.Artifact
+	// artifactBytes is the size of the artifacts downloaded for the run.
+	artifactBytes int64
+	// aborted is set for runs whose ingest was aborted. Only their ingest errors are
+	// written, so that they are pulled again by the next ingest.
+	aborted bool
+}
+
+// documents returns the number of documents written for the run.
+func (r *runResult) documents() int {
+	if r.aborted {
+		return len(r.ingestErrors)
+	}
+
+	return 1 + len(r.jobs) + len(r.steps) + len(r.suites) + len(r.cases) + len(r.sysdumps) + len(r.logSignatures) +
+		len(r.ingestErrors) + len(r.artifacts)
+}
+
+// filterRunConclusions forwards the runs received on the given channel to the returned
+// channel, leaving out runs whose conclusion isn't allowed for their workflow by
+// --workflow-run-conclusions.
+func filterRunConclusions(logger *slog.Logger, runs <-chan *types.WorkflowRun) <-chan *types.WorkflowRun {
+	out := make(chan *types.WorkflowRun, pipelineBufferSize)
+
+	go func() {
+		defer close(out)
+
+		for run := range runs {
+			if !gh.RunConclusionAllowed(workflowRunsParams.RunConclusionRules, run) {
+				logger.Debug(
+					"Conclusion of workflow run isn't ingested for its workflow, skipping",
+					"workflow-id", run.ID, "workflow", run.Name, "conclusion", run.Conclusion,
+				)
+				continue
+			}
+
+			out <- run
+		}
+	}()
+
+	return out
+}
+
+// filterIngestedRuns forwards the runs received on the given channel to the returned
+// channel, leaving out runs which were already ingested according to the checkpoint or
+// OpenSearch. If neither a checkpoint nor an OpenSearch client is given, all runs are forwarded.
+func filterIngestedRuns(
+	ctx context.Context,
+	logger *slog.Logger,
+	opsClient *opensearchgo.Client,
+	runs <-chan *types.WorkflowRun,
+) <-chan *types.WorkflowRun {
+	out := make(chan *types.WorkflowRun, pipelineBufferSize)
+
+	go func() {
+		defer close(out)
+
+		for run := range runs {
+			metrics.SetQueueDepth("runs", len(runs))
+
+			if runsCheckpoint != nil && !workflowRunsParams.Force && runsCheckpoint.Has(run) {
+				logger.Info(
+					"Workflow run was already ingested according to the checkpoint, skipping",
+					"workflow-id", run.ID, "run-attempt", run.RunAttempt,
+				)
+				continue
+			}
+
+			if opsClient != nil {
+				runLogger := logger.With("workflow-id", run.ID)
+
+				ingested, err := isRunIngested(ctx, opsClient, run)
+				if err != nil {
+					runLogger.Error(
+						"Unable to check if workflow run was already ingested",
+						"err", err,
+					)
+					os.Exit(1)
+				}
+
+				if ingested {
+					runLogger.Info("Workflow run was already ingested, skipping", "run-attempt", run.RunAttempt)
+					continue
+				}
+			}
+
+			out <- run
+		}
+	}()
+
+	return out
+}
+
+// claimRuns forwards the runs received on the given channel to the returned channel,
+// leaving out runs whose lease is held by another replica. Leases are renewed once the
+// run is pulled, see renewRunLease. Leases of runs which were ingested aren't released,
+// but expire after --lease-ttl, so that other replicas see the documents of the run by
+// then. If --lease-ttl isn't set, all runs are forwarded.
+func claimRuns(ctx context.Context, logger *slog.Logger, runs <-chan *types.WorkflowRun) <-chan *types.WorkflowRun {
+	if leaseClient == nil {
+		return runs
+	}
+
+	out := make(chan *types.WorkflowRun, pipelineBufferSize)
+
+	go func() {
+		defer close(out)
+
+		for run := range runs {
+			runLogger := logger.With("workflow-id", run.ID, "run-attempt", run.RunAttempt)
+
+			leaseCtx, cancel := util.WithTimeout(ctx, rootParams.Timeouts.Request)
+			lease, err := opensearch.AcquireLease(
+				leaseCtx, leaseClient, rootParams.LeaseIndex, runLeaseID(run), leaseHolder, rootParams.LeaseTTL,
+			)
+			cancel()
+			if errors.Is(err, opensearch.ErrLeaseHeld) {
+				runLogger.Info("Workflow run is being ingested by another replica, skipping", "err", err)
+				continue
+			}
+			if err != nil {
+				runLogger.Error("Unable to acquire lease of workflow run", "err", err)
+				os.Exit(1)
+			}
+
+			runLeasesMu.Lock()
+			runLeases[runLeaseID(run)] = lease
+			runLeasesMu.Unlock()
+
+			out <- run
+		}
+	}()
+
+	return out
+}
+
+// runLeaseID returns the ID of the lease of the given run attempt.
+func runLeaseID(run *types.WorkflowRun) string {
+	return fmt.Sprintf("workflow-run-%d-%d", run.ID, run.RunAttempt)
+}
+
+// renewRunLease renews the lease of the given run, if it holds one, right before the run
+// is pulled, so that the time it waited to be pulled doesn't count against --lease-ttl.
+// False is returned if another replica took over the lease while the run waited.
+func renewRunLease(ctx context.Context, logger *slog.Logger, run *types.WorkflowRun) bool {
+	runLeasesMu.Lock()
+	_, ok := runLeases[runLeaseID(run)]
+	runLeasesMu.Unlock()
+
+	if !ok {
+		return true
+	}
+
+	leaseCtx, cancel := util.WithTimeout(ctx, rootParams.Timeouts.Request)
+	lease, err := opensearch.AcquireLease(
+		leaseCtx, leaseClient, rootParams.LeaseIndex, runLeaseID(run), leaseHolder, rootParams.LeaseTTL,
+	)
+	cancel()
+	if errors.Is(err, opensearch.ErrLeaseHeld) {
+		logger.Info("Lease of workflow run was taken over by another replica, skipping", "workflow-id", run.ID, "err", err)
+		forgetRunLease(run)
+
+		return false
+	}
+	if err != nil {
+		logger.Error("Unable to renew lease of workflow run", "workflow-id", run.ID, "err", err)
+		os.Exit(1)
+	}
+
+	runLeasesMu.Lock()
+	runLeases[runLeaseID(run)] = lease
+	runLeasesMu.Unlock()
+
+	return true
+}
+
+// forgetRunLease stops tracking the lease of the given run once it was handled, leaving
+// the lease to expire.
+func forgetRunLease(run *types.WorkflowRun) {
+	runLeasesMu.Lock()
+	delete(runLeases, runLeaseID(run))
+	runLeasesMu.Unlock()
+}
+
+// releaseRunLease releases the lease of the given run, if it holds one, so that another
+// replica can ingest it right away.
+func releaseRunLease(ctx context.Context, logger *slog.Logger, run *types.WorkflowRun) {
+	runLeasesMu.Lock()
+	lease := runLeases[runLeaseID(run)]
+	delete(runLeases, runLeaseID(run))
+	runLeasesMu.Unlock()
+
+	if lease == nil {
+		return
+	}
+
+	releaseCtx, cancel := util.WithTimeout(ctx, rootParams.Timeouts.Request)
+	defer cancel()
+
+	if err := lease.Release(releaseCtx); err != nil {
+		logger.Warn("Unable to release lease of workflow run", "workflow-id", run.ID, "err", err)
+	}
+}
+
+// artifactPrefetch holds the artifact listing for a workflow run, which
+// is available once done is closed.
+type artifactPrefetch struct {
+	run       *types.WorkflowRun
+	artifacts []*github.Artifact
+	err       error
+	done      chan struct{}
+}
+
+// prefetchArtifacts lists the artifacts for the runs received on the given channel, with up
+// to the given number of requests in flight. Listings are sent to the returned channel in the
+// same order the runs were received, so that runs can be pulled without waiting on metadata.
+func prefetchArtifacts(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	runs <-chan *types.WorkflowRun,
+	concurrency int,
+) <-chan *artifactPrefetch {
+	out := make(chan *artifactPrefetch, pipelineBufferSize)
+	inFlight := make(chan struct{}, max(1, concurrency))
+
+	go func() {
+		defer close(out)
+
+		for run := range runs {
+			p := &artifactPrefetch{run: run, done: make(chan struct{})}
+
+			inFlight <- struct{}{}
+			go func() {
+				defer func() {
+					<-inFlight
+					close(p.done)
+				}()
+
+				defer metrics.TimeStage(metrics.StageFetch)()
+
+				p.artifacts, p.err = gh.ListArtifactsForRun(ctx, logger, client, run)
+			}()
+
+			out <- p
+		}
+	}()
+
+	return out
+}
+
+// pullRun pulls the jobs, steps and tests for the given workflow run.
+func pullRun(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	prefetch *artifactPrefetch,
+) *runResult {
+	run := prefetch.run
+	runLogger := logger.With("workflow-id", run.ID)
+
+	ctx, span := tracing.Start(
+		ctx, "pull-run",
+		tracing.AttrWorkflowID, run.ID, tracing.AttrRunAttempt, run.RunAttempt,
+	)
+	defer span.End()
+
+	stopFetchTimer := metrics.TimeStage(metrics.StageFetch)
+	jobs, steps, err := gh.GetJobsAndStepsForRun(
+		ctx, logger, client, run,
+		workflowRunsParams.JobConclusions,
+		workflowRunsParams.StepConclusions,
+		workflowRunsParams.IncludeErrorLogs,
+		workflowRunsParams.IncludeCacheStats,
+		rootParams.Timeouts,
+	)
+	stopFetchTimer()
+	if err != nil {
+		runLogger.Error("Unable to pull job and steps for workflow run, aborting its ingest", "err", err)
+		span.RecordError(err)
+
+		return abortedRunResult(run, nil, junit.SkipReasonPullFailed, err)
+	}
+
+	if workflowRunsParams.IncludePullRequestReviews &&
+		(run.Event == "pull_request" || run.Event == "pull_request_target") {
+		pr, err := gh.GetPullRequestForRun(ctx, logger, client, run)
+		if err != nil {
+			runLogger.Error("Unable to pull pull request for workflow run, aborting its ingest", "err", err)
+			span.RecordError(err)
+
+			return abortedRunResult(run, nil, junit.SkipReasonPullFailed, err)
+		}
+		run.PullRequest = pr
+	}
+
+	if workflowRunsParams.IncludeEventDetails {
+		if err := gh.AddEventDetails(ctx, logger, client, run); err != nil {
+			runLogger.Error("Unable to pull event details for workflow run, aborting its ingest", "err", err)
+			span.RecordError(err)
+
+			return abortedRunResult(run, nil, junit.SkipReasonPullFailed, err)
+		}
+	}
+
+	if workflowRunsParams.IncludePushCommits && run.Event == "push" &&
+		slices.Contains(workflowRunsParams.PushCommitsBranches, run.HeadBranch) {
+		base, commits, err := gh.GetPushCommits(ctx, logger, client, run)
+		if err != nil {
+			runLogger.Error("Unable to pull pushed commits for workflow run, aborting its ingest", "err", err)
+			span.RecordError(err)
+
+			return abortedRunResult(run, nil, junit.SkipReasonPullFailed, err)
+		}
+		run.PushBaseSHA, run.PushCommits = base, commits
+	}
+
+	// Logs are written by the code of pull requests, which may leak secrets.
+	for i := range jobs {
+		for j, line := range jobs[i].ErrorLogs {
+			jobs[i].ErrorLogs[j] = junit.Redact(line, workflowRunsParams.SecretRedactions)
+		}
+	}
+
+	run.TerminationReason = gh.RunTerminationReason(run, jobs)
+
+	if workflowRunsParams.MarkRequiredChecks {
+		for i := range jobs {
+			if slices.Contains(workflowRunsParams.RequiredChecks, jobs[i].Name) {
+				jobs[i].Required = true
+				run.Required = true
+			}
+		}
+	}
+
+	setWorkflowDispatchInputs(ctx, runLogger, client, run, jobs)
+
+	// Fields that start with Tested* represent information regarding the tested ref.
+	// These fields require special, context-aware handling.
+	// TODO: Modify this function to determine if a workflow_dispatch run was scheduled by
+	// ariane or executed as part of a PR. Add a flag to ignore PRs.
+	// setTestedFields(ctx, runLogger, client, event, repoOwner, repoName, run, &jobs)
+
+	<-prefetch.done
+	if prefetch.err != nil {
+		runLogger.Error("Unable to list artifacts for workflow run, aborting its ingest", "err", prefetch.err)
+		span.RecordError(prefetch.err)
+
+		return abortedRunResult(run, nil, junit.SkipReasonPullFailed, prefetch.err)
+	}
+
+	artifacts := make([]types.Artifact, 0, len(prefetch.artifacts))
+	for _, a := range prefetch.artifacts {
+		artifacts = append(artifacts, types.NewArtifactFromRaw(run, a))
+	}
+	run.SetArtifactTotals(artifacts)
+
+	parsed, err := gh.ParseArtifactsForWorkflowRun(
+		ctx, logger, client, run, prefetch.artifacts, workflowRunsParams.ArtifactRoutes, workflowRunsParams.Archive,
+		rootParams.Timeouts, parseOptions(),
+	)
+	if errors.Is(err, junit.ErrParseErrorBudgetExceeded) {
+		runLogger.Error("Too many artifact files of workflow run couldn't be parsed, aborting its ingest", "err", err)
+		span.RecordError(err)
+
+		result := abortedRunResult(run, parsed.IngestErrors, junit.SkipReasonParseErrorBudgetExceeded, err)
+		result.artifactBytes = parsed.DownloadedBytes
+
+		return result
+	}
+	if err != nil {
+		runLogger.Error("Unable to parse artifacts for workflow run, aborting its ingest", "err", err)
+		span.RecordError(err)
+
+		// Artifacts which failed to download or archive leave no results.
+		var ingestErrors []types.IngestError
+		if parsed != nil {
+			ingestErrors = parsed.IngestErrors
+		}
+
+		return abortedRunResult(run, ingestErrors, junit.SkipReasonParseFailed, err)
+	}
+	suites, cases, ingestErrors := parsed.Suites, parsed.Cases, parsed.IngestErrors
+	run.SetResultMismatch(suites)
+	if run.ResultMismatch != "" {
+		runLogger.Warn("Conclusion of workflow run disagrees with its junit results", "mismatch", run.ResultMismatch)
+	}
+
+	// Documents of the run other than its tests are bounded by the size of the workflow,
+	// so only tests are dropped if the run exceeds the limit.
+	docs := 1 + len(jobs) + len(steps) + len(artifacts) + len(ingestErrors) + len(suites) + len(cases) +
+		len(parsed.Sysdumps) + len(parsed.LogSignatures)
+	if max := workflowRunsParams.MaxDocsPerRun; max > 0 && docs > max {
+		runLogger.Warn(
+			"Workflow run exceeds the maximum number of documents, skipping its tests",
+			"documents", docs, "max", max,
+		)
+		ingestErrors = append(ingestErrors, types.IngestError{
+			WorkflowRun: run,
+			Type:        types.TypeNameIngestError,
+			Reason:      junit.SkipReasonLimitExceeded,
+			Message: fmt.Sprintf(
+				"workflow run has %d documents, more than the limit of %d, skipped %d test suites and %d testcases",
+				docs, max, len(suites), len(cases),
+			),
+		})
+		suites, cases = nil, nil
+	}
+
+	if workflowRunsParams.TeamMembers != nil {
+		for i := range cases {
+			if len(cases[i].Owners) == 0 {
+				continue
+			}
+
+			members, err := workflowRunsParams.TeamMembers.Expand(ctx, runLogger, client, cases[i].Owners)
+			if err != nil {
+				runLogger.Error(
+					"Unable to expand team owners of test case, aborting ingest of workflow run",
+					"testcase", cases[i].Name,
+					"err", err,
+				)
+				span.RecordError(err)
+
+				return abortedRunResult(run, ingestErrors, junit.SkipReasonPullFailed, err)
+			}
+			cases[i].OwnerMembers = members
+		}
+	}
+
+	if workflowRunsParams.EmbeddingURL != "" || workflowRunsParams.EmbeddingPipeline != "" {
+		embedFailures(ctx, runLogger, cases)
+	}
+
+	return &runResult{
+		run:           run,
+		jobs:          jobs,
+		steps:         steps,
+		suites:        suites,
+		cases:         cases,
+		sysdumps:      parsed.Sysdumps,
+		logSignatures: parsed.LogSignatures,
+		ingestErrors:  ingestErrors,
+		artifacts:     artifacts,
+		artifactBytes: parsed.DownloadedBytes,
+	}
+}
+
+// abortedRunResult returns the result of the given run whose ingest was aborted because
+// of the given error, recording it as an ingest error with the given reason in addition
+// to the given ingest errors.
+func abortedRunResult(run *types.WorkflowRun, ingestErrors []types.IngestError, reason string, err error) *runResult {
+	return &runResult{
+		run: run,
+		ingestErrors: append(ingestErrors, types.IngestError{
+			WorkflowRun: run,
+			Type:        types.TypeNameIngestError,
+			Reason:      reason,
+			Message:     err.Error(),
+		}),
+		aborted: true,
+	}
+}
+
+// embeddingBatchSize is the number of failures to compute embeddings for per request.
+const embeddingBatchSize = 64
+
+// embedFailures sets the normalized failure of failed testcases, and computes their embeddings
+// if an embedding endpoint is configured. Otherwise they are computed by the ingest pipeline.
+// Failing to compute embeddings is logged, but doesn't fail the run.
+func embedFailures(ctx context.Context, logger *slog.Logger, cases []types.Testcase) {
+	failed := []int{}
+	for i := range cases {
+		text := strings.TrimSpace(cases[i].FailureMessage + "\n" + cases[i].FailureText)
+		if text == "" {
+			continue
+		}
+
+		cases[i].FailureNormalized = embedding.Normalize(text)
+		failed = append(failed, i)
+	}
+
+	if workflowRunsParams.EmbeddingURL == "" {
+		return
+	}
+
+	embedder := embedding.NewHTTPEmbedder(workflowRunsParams.EmbeddingURL, workflowRunsParams.EmbeddingModel)
+
+	for batch := range slices.Chunk(failed, embeddingBatchSize) {
+		texts := make([]string, 0, len(batch))
+		for _, i := range batch {
+			texts = append(texts, cases[i].FailureNormalized)
+		}
+
+		embeddings, err := embedder.Embed(ctx, texts)
+		if err != nil {
+			logger.Warn("Unable to compute embeddings of test failures", "err", err)
+			return
+		}
+
+		for j, i := range batch {
+			cases[i].FailureEmbedding = embeddings[j]
+		}
 	}
+}
 
-	var echoJob *types.JobRun
+// writeRunResult writes bulk entries for the documents pulled for a workflow run.
+// The workflow run itself is written last, so that its presence in the index
+// signals the run was fully ingested.
+func writeRunResult(ctx context.Context, logger *slog.Logger, result *runResult) {
+	runLogger := logger.With("workflow-id", result.run.ID)
 
-	for _, job := range *jobs {
-		if job.Name == "Echo Workflow Dispatch Inputs" {
-			echoJob = &job
-			break
-		}
+	defer forgetRunLease(result.run)
+
+	_, span := tracing.Start(
+		ctx, "write-run",
+		tracing.AttrWorkflowID, result.run.ID, tracing.AttrRunAttempt, result.run.RunAttempt,
+		tracing.AttrDocuments, result.documents(),
+	)
+	defer span.End()
+
+	auditRun(result.run.ID)
+	recordQuotaUsage(result)
+	metrics.Add(metrics.CounterIngestErrors, int64(len(result.ingestErrors)))
+	if result.aborted {
+		metrics.Add(metrics.CounterRunsAborted, 1)
+	} else {
+		metrics.Add(metrics.CounterRunsIngested, 1)
 	}
 
-	if echoJob == nil {
-		if run.HeadBranch == "main" {
-			// The echo-inputs job is currently being backported to other stable branches.
-			// Do not fail if we are pulling jobs for a different branch.
-			logger.Error("Got workflow_dispatch event with no echo-inputs job")
+	if result.aborted {
+		if err := opensearch.BulkWriteObjects[types.IngestError](result.ingestErrors, indexFor(types.TypeNameIngestError), rootParams.BulkOptions, bulkOutput); err != nil {
+			runLogger.Error(
+				"Unexepected error while writing ingest error bulk entries",
+				"err", err,
+			)
 			os.Exit(1)
 		}
+
 		return
 	}
 
-	if echoJob.Conclusion != "success" {
-		logger.Error("echo-inputs job was not successful")
+	if err := opensearch.BulkWriteObjects[types.JobRun](result.jobs, indexFor(types.TypeNameJobRun), rootParams.BulkOptions, bulkOutput); err != nil {
+		runLogger.Error(
+			"Unexepected error while writing job run bulk entries",
+			"err", err,
+		)
 		os.Exit(1)
 	}
 
-	logs, err := gh.GetLogsForJob(ctx, logger, client, echoJob.ID, run.Repository.Owner.Login, run.Repository.Name)
-	if err != nil {
-		logger.Error(
-			"Unable to pull logs for echo-inputs job",
+	if err := opensearch.BulkWriteObjects[types.StepRun](result.steps, indexFor(types.TypeNameStepRun), rootParams.BulkOptions, bulkOutput); err != nil {
+		runLogger.Error(
+			"Unexepected error while writing step run bulk entries",
 			"err", err,
 		)
 		os.Exit(1)
 	}
 
-	inputs := gh.ParseEchoInputsLogs(logs)
+	if err := opensearch.BulkWriteObjects[types.Testsuite](result.suites, indexFor(types.TypeNameTestsuite), rootParams.BulkOptions, bulkOutput); err != nil {
+		runLogger.Error(
+			"Unexepected error while writing test suite bulk entries",
+			"err", err,
+		)
+		os.Exit(1)
+	}
 
-	if len(inputs) == 0 {
-		logger.Error("Parsed no inputs for workflow_dispatch workflow")
+	testcaseOpts := rootParams.BulkOptions
+	testcaseOpts.Pipeline = workflowRunsParams.EmbeddingPipeline
+	if err := opensearch.BulkWriteObjects[types.Testcase](result.cases, indexFor(types.TypeNameTestcase), testcaseOpts, bulkOutput); err != nil {
+		runLogger.Error(
+			"Unexepected error while writing test case bulk entries",
+			"err", err,
+		)
 		os.Exit(1)
 	}
 
-	logger.Debug(
-		"Got inputs for workflow_dispatch workflow",
-		"inputs", inputs,
-	)
-	run.WorkflowDispatchInputs = inputs
+	if err := opensearch.BulkWriteObjects[types.Sysdump](result.sysdumps, indexFor(types.TypeNameSysdump), rootParams.BulkOptions, bulkOutput); err != nil {
+		runLogger.Error(
+			"Unexepected error while writing sysdump bulk entries",
+			"err", err,
+		)
+		os.Exit(1)
+	}
 
-	sha, ok := inputs["SHA"]
-	if !ok {
-		logger.Error("Parsed inputs for workflow_dispatch workflow do not contain SHA")
+	if err := opensearch.BulkWriteObjects[types.LogSignature](result.logSignatures, indexFor(types.TypeNameLogSignature), rootParams.BulkOptions, bulkOutput); err != nil {
+		runLogger.Error(
+			"Unexepected error while writing log signature bulk entries",
+			"err", err,
+		)
 		os.Exit(1)
 	}
 
-	run.TestedSHA = sha
+	if err := opensearch.BulkWriteObjects[types.IngestError](result.ingestErrors, indexFor(types.TypeNameIngestError), rootParams.BulkOptions, bulkOutput); err != nil {
+		runLogger.Error(
+			"Unexepected error while writing ingest error bulk entries",
+			"err", err,
+		)
+		os.Exit(1)
+	}
 
-	commit, err := gh.GetCommitBySHA(
-		ctx, logger, run.Repository.Owner.Login,
-		run.Repository.Name, client, sha,
-	)
-	if err != nil {
-		logger.Error("Unable to get commit info for sha", "sha", sha)
+	if err := opensearch.BulkWriteObjects[types.Artifact](result.artifacts, indexFor(types.TypeNameArtifact), rootParams.BulkOptions, bulkOutput); err != nil {
+		runLogger.Error(
+			"Unexepected error while writing artifact bulk entries",
+			"err", err,
+		)
 		os.Exit(1)
 	}
 
-	run.TestedCommit = *commit
+	if err := opensearch.BulkWriteObjects[*types.WorkflowRun]([]*types.WorkflowRun{result.run}, indexFor(types.TypeNameWorkflowRun), rootParams.BulkOptions, bulkOutput); err != nil {
+		runLogger.Error(
+			"Unexepected error while writing workflow run bulk entries",
+			"err", err,
+		)
+		os.Exit(1)
+	}
+}
 
-	contextRef, ok := inputs["context-ref"]
-	if !ok {
-		logger.Error("Parsed inputs for workflow_dispatch workflow do not contain context-ref")
+// writeCacheUsage writes a bulk entry for the current Actions cache usage of the given repository.
+func writeCacheUsage(ctx context.Context, logger *slog.Logger, client *github.Client, repoOwner, repoName string) {
+	repo, err := gh.GetRepository(ctx, logger, client, repoOwner, repoName)
+	if err != nil {
+		logger.Error("Unable to get repository", "err", err)
 		os.Exit(1)
 	}
 
-	run.TestedBranch = contextRef
+	usage, err := gh.GetCacheUsage(ctx, logger, client, repo)
+	if err != nil {
+		logger.Error("Unable to get cache usage for repository", "err", err)
+		os.Exit(1)
+	}
+
+	if err := opensearch.BulkWriteObjects[types.CacheUsage]([]types.CacheUsage{*usage}, indexFor(types.TypeNameCacheUsage), rootParams.BulkOptions, bulkOutput); err != nil {
+		logger.Error(
+			"Unexepected error while writing cache usage bulk entries",
+			"err", err,
+		)
+		os.Exit(1)
+	}
 }
 
+// pullRunsWithEventAndStatus pulls and writes workflow runs through a pipeline of
+// stages connected by bounded channels: listing workflow runs, skipping runs which
+// were already ingested, prefetching artifact listings, pulling the documents for
+// each run, and writing bulk entries for them.
 func pullRunsWithEventAndStatus(
 	ctx context.Context,
+	stop context.Context,
 	logger *slog.Logger,
 	client *github.Client,
+	opsClient *opensearchgo.Client,
 	repoOwner,
 	repoName,
 	event,
@@ -147,104 +1085,221 @@ func pullRunsWithEventAndStatus(
 		"status", status,
 	)
 
-	runs, err := gh.GetWorkflowRuns(
-		ctx, logger, client,
-		repoOwner, repoName, workflowRunsParams.Branch,
-		status, event, workflowRunsParams.Since, workflowRunsParams.Until,
-		workflowID,
-	)
-	if err != nil {
-		eventLogger.Error(
-			"Unable to pull workflow runs",
-			"err", err,
-		)
-		if strings.Contains(err.Error(), "404 Not Found") {
-			return
-		}
-		os.Exit(1)
-	}
+	runs := make(chan *types.WorkflowRun, pipelineBufferSize)
 
-	for _, run := range runs {
-		runLogger := eventLogger.With("workflow-id", run.ID)
+	go func() {
+		defer close(runs)
 
-		jobs, steps, err := gh.GetJobsAndStepsForRun(
-			ctx, logger, client, run,
-			workflowRunsParams.JobConclusions,
-			workflowRunsParams.StepConclusions,
-			workflowRunsParams.IncludeErrorLogs,
+		err := gh.StreamWorkflowRuns(
+			stop, logger, client,
+			repoOwner, repoName, workflowRunsParams.Branch,
+			status, event, workflowRunsParams.Since, workflowRunsParams.Until,
+			workflowID, runs,
 		)
-		if err != nil {
-			runLogger.Error(
-				"Unable to pull job and steps for workflow run",
+		if err != nil && stop.Err() == nil {
+			eventLogger.Error(
+				"Unable to pull workflow runs",
 				"err", err,
 			)
+			if strings.Contains(err.Error(), "404 Not Found") {
+				return
+			}
 			os.Exit(1)
 		}
+	}()
 
-		// Fields that start with Tested* represent information regarding the tested ref.
-		// These fields require special, context-aware handling.
-		// TODO: Modify this function to determine if a workflow_dispatch run was scheduled by
-		// ariane or executed as part of a PR. Add a flag to ignore PRs.
-		// setTestedFields(ctx, runLogger, client, event, repoOwner, repoName, run, &jobs)
+	processRuns(ctx, stop, eventLogger, client, opsClient, runs)
+}
 
-		if err := opensearch.BulkWriteObjects[types.JobRun](jobs, rootParams.Index, os.Stdout); err != nil {
-			runLogger.Error(
-				"Unexepected error while writing job run bulk entries",
-				"err", err,
-			)
-			os.Exit(1)
-		}
+// pollRuns polls for newly completed workflow runs every interval, and processes the ones
+// matching the given parameters which weren't seen in the previous poll.
+func pollRuns(
+	ctx context.Context,
+	stop context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	opsClient *opensearchgo.Client,
+	repoOwner,
+	repoName string,
+	interval time.Duration,
+) {
+	poller := gh.NewRunPoller(client, repoOwner, repoName, workflowRunsParams.Branch)
+	seen := map[string]struct{}{}
 
-		if err := opensearch.BulkWriteObjects[types.StepRun](steps, rootParams.Index, os.Stdout); err != nil {
-			runLogger.Error(
-				"Unexepected error while writing step run bulk entries",
-				"err", err,
-			)
-			os.Exit(1)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Reload credentials on SIGHUP, so rotated secrets are picked up without a restart.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-hup:
+			if err := util.ReloadSecrets(); err != nil {
+				logger.Error("Unable to reload credentials", "err", err)
+			} else {
+				logger.Info("Reloaded credentials")
+			}
+			continue
+		case <-stop.Done():
+			return
 		}
 
-		suites, cases, err := gh.GetTestsForWorkflowRun(
-			ctx, logger, client, run,
-			workflowRunsParams.TestConclusions,
-		)
+		polled, err := poller.Poll(ctx, logger)
 		if err != nil {
-			runLogger.Error(
-				"Unable to parse test cases for workflow run",
-				"run", run.ID,
-				"err", err,
-			)
-			os.Exit(1)
+			logger.Error("Unable to poll workflow runs", "err", err)
+			continue
+		}
+		if polled == nil {
+			continue
 		}
 
-		if err := opensearch.BulkWriteObjects[types.Testsuite](suites, rootParams.Index, os.Stdout); err != nil {
-			runLogger.Error(
-				"Unexepected error while writing job run bulk entries",
-				"err", err,
-			)
-			os.Exit(1)
+		newRuns := []*types.WorkflowRun{}
+		nextSeen := make(map[string]struct{}, len(polled))
+		for _, run := range polled {
+			key := fmt.Sprintf("%d-%d", run.ID, run.RunAttempt)
+			nextSeen[key] = struct{}{}
+
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			if !slices.Contains(workflowRunsParams.Events, run.Event) ||
+				!slices.Contains(workflowRunsParams.RunStatuses, run.Conclusion) ||
+				(workflowRunsParams.WorkflowID != 0 && run.ParentWorkflowID != workflowRunsParams.WorkflowID) {
+				continue
+			}
+
+			newRuns = append(newRuns, run)
 		}
 
-		if err := opensearch.BulkWriteObjects[types.Testcase](cases, rootParams.Index, os.Stdout); err != nil {
-			runLogger.Error(
-				"Unexepected error while writing step run bulk entries",
-				"err", err,
-			)
-			os.Exit(1)
+		// Runs over the quota are left for the next cycle by not marking them as seen.
+		newRuns, deferred := limitRunsPerPoll(repoOwner+"/"+repoName, newRuns)
+		for _, run := range deferred {
+			delete(nextSeen, fmt.Sprintf("%d-%d", run.ID, run.RunAttempt))
+		}
+		seen = nextSeen
+
+		logger.Info("Polled workflow runs", "total", len(polled), "new", len(newRuns), "deferred", len(deferred))
+
+		runs := make(chan *types.WorkflowRun, len(newRuns))
+		for _, run := range newRuns {
+			duration, err := gh.GetWorkflowRunDuration(ctx, logger, client, run)
+			if err != nil {
+				logger.Error("Unable to get duration of workflow run", "workflow-id", run.ID, "err", err)
+				os.Exit(1)
+			}
+			run.WorkflowDuration = duration
+
+			runs <- run
+		}
+		close(runs)
+
+		// Runs skipped since the quota of the repository is exhausted are pulled again
+		// once they are polled after the quota was reset.
+		for _, run := range processRuns(ctx, stop, logger, client, opsClient, runs) {
+			delete(seen, fmt.Sprintf("%d-%d", run.ID, run.RunAttempt))
 		}
 	}
+}
 
-	if err := opensearch.BulkWriteObjects[*types.WorkflowRun](runs, rootParams.Index, os.Stdout); err != nil {
-		eventLogger.Error(
-			"Unexepected error while writing workflow run bulk entries",
-			"err", err,
-		)
-		os.Exit(1)
+// processRuns skips the runs received on the given channel which were already ingested,
+// and pulls and writes the documents for the rest, until the channel is closed. Once stop
+// is done, runs which weren't started yet are skipped, while runs being pulled are finished.
+// The runs skipped since the quota of their repository is exhausted are returned.
+func processRuns(
+	ctx context.Context,
+	stop context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	opsClient *opensearchgo.Client,
+	runs <-chan *types.WorkflowRun,
+) []*types.WorkflowRun {
+	results := make(chan *runResult, pipelineBufferSize)
+
+	ctx, span := tracing.Start(ctx, "process-runs")
+	defer span.End()
+
+	prefetched := prefetchArtifacts(
+		ctx, logger, client,
+		claimRuns(ctx, logger, filterIngestedRuns(ctx, logger, opsClient, filterRunConclusions(logger, runs))),
+		workflowRunsParams.ArtifactPrefetchConcurrency,
+	)
+
+	exhausted := []*types.WorkflowRun{}
+	go func() {
+		defer close(results)
+
+		skipped := 0
+		for prefetch := range prefetched {
+			metrics.SetQueueDepth("prefetched", len(prefetched))
+
+			if stop.Err() != nil {
+				releaseRunLease(ctx, logger, prefetch.run)
+				skipped++
+				continue
+			}
+
+			if quotaExhausted(logger, prefetch.run) {
+				releaseRunLease(ctx, logger, prefetch.run)
+				exhausted = append(exhausted, prefetch.run)
+				continue
+			}
+
+			if !renewRunLease(ctx, logger, prefetch.run) {
+				continue
+			}
+
+			results <- pullRun(ctx, logger, client, prefetch)
+		}
+
+		if skipped > 0 {
+			logger.Info("Shutting down, skipped workflow runs which weren't started yet", "skipped", skipped)
+		}
+	}()
+
+	for result := range results {
+		metrics.SetQueueDepth("results", len(results))
+
+		writeRunResult(ctx, logger, result)
+
+		if runsCheckpoint != nil && !result.aborted {
+			runsCheckpoint.Add(result.run)
+		}
 	}
+
+	// The results are closed once every prefetched run was handled.
+	return exhausted
 }
 
 var (
+	// defaultRedactions replace the random suffixes of Cilium's pod names and UUIDs.
+	defaultRedactions = []string{
+		`\b([a-z0-9][-a-z0-9]*)-[bcdfghjklmnpqrstvwxz2456789]{6,10}-[bcdfghjklmnpqrstvwxz2456789]{5}\b=${1}-<pod>`,
+		`\b(cilium|cilium-envoy|cilium-node-init|clustermesh-apiserver|hubble-relay|hubble-ui|kube-proxy)-[bcdfghjklmnpqrstvwxz2456789]{5}\b=${1}-<pod>`,
+		`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b=<uuid>`,
+	}
+
+	// defaultFailureSignatures identify common infrastructure failures in Cilium's CI.
+	defaultFailureSignatures = []string{
+		`runner-lost=The runner has received a shutdown signal|lost communication with the server`,
+		`disk-full=(?i)no space left on device`,
+		`docker-rate-limit=toomanyrequests|(?i)pull rate limit`,
+		`cluster-provision-timeout=(?i)(timed out|timeout) waiting for (the )?cluster|cluster (creation|provisioning) (timed out|failed)`,
+	}
+
+	// runsCheckpoint records the ingested workflow runs when --checkpoint-file is given.
+	runsCheckpoint *gh.Checkpoint
+
+	// runLeases are the leases held on workflow runs by run attempt, see claimRuns.
+	runLeasesMu sync.Mutex
+	runLeases   = map[string]*opensearch.Lease{}
+
 	defaultGitHubConclusions = []string{"success", "failure", "timed_out", "cancelled", "skipped"}
 	defaultJUnitConclusions  = []string{"passed", "failed", "skipped"}
+	defaultSystemErrPatterns = []string{`level=(error|fatal)`, `panic:`, `(?i)\berror:`, `\bFAIL\b`}
 	workflowRunsParams       = &typeWorkflowRunsParams{}
 	workflowRunsCmd          = &cobra.Command{
 		Use: "runs",
@@ -265,12 +1320,20 @@ var (
 
 			workflowRunsParams.Until = u
 
-			return nil
+			return compileTestParams()
 		},
 		Run: func(cmd *cobra.Command, args []string) {
 			ctx := context.Background()
 			logger := log.NewLogger(rootParams.Verbose)
 
+			startIngestStats(cmd)
+			startAudit(cmd)
+
+			// On SIGTERM or interrupt, stop taking on new workflow runs but finish the ones being
+			// pulled, so that their documents are flushed and checkpointed before exiting.
+			stop, cancelStop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+			defer cancelStop()
+
 			repoParts := strings.Split(workflowRunsParams.Repository, "/")
 			if len(repoParts) != 2 {
 				logger.Error("Unable to extract repo owner and name from given value", "given", workflowRunsParams.Repository)
@@ -279,12 +1342,52 @@ var (
 			repoOwner := repoParts[0]
 			repoName := repoParts[1]
 
-			client, err := gh.NewGitHubClient(gh.GetGitHubAuthToken(), logger)
+			token, err := gh.GetGitHubAuthToken()
+			if err != nil {
+				logger.Error("Unable to load GitHub token", "err", err)
+				os.Exit(1)
+			}
+
+			client, err := gh.NewGitHubClient(token, logger, rootParams.Timeouts.Request)
 			if err != nil {
 				logger.Error("Unable to create new GitHub Client", "err", err)
 				os.Exit(1)
 			}
 
+			var opsClient *opensearchgo.Client
+
+			opensearchCfg, err := opensearch.NewClientConfig()
+			if err != nil {
+				logger.Error("Unable to load OpenSearch configuration", "err", err)
+				os.Exit(1)
+			}
+			if workflowRunsParams.Force {
+				logger.Info("Force given, workflow runs which were already ingested will be pulled again")
+			} else if opensearchCfg.Addresses[0] == "" {
+				logger.Warn("OPENSEARCH_URL is not set, unable to skip workflow runs which were already ingested")
+			} else if opensearch.OmitDocumentIDs() {
+				logger.Warn("Documents are written without IDs, unable to skip workflow runs which were already ingested")
+			} else {
+				opsClient, err = opensearchgo.NewClient(opensearchCfg)
+				if err != nil {
+					logger.Error("Unable to create opensearch client", "err", err)
+					os.Exit(1)
+				}
+			}
+
+			if workflowRunsParams.CheckpointFile != "" {
+				runsCheckpoint, err = gh.LoadCheckpoint(workflowRunsParams.CheckpointFile)
+				if err != nil {
+					logger.Error("Unable to load checkpoint", "err", err)
+					os.Exit(1)
+				}
+				logger.Info("Loaded checkpoint", "path", workflowRunsParams.CheckpointFile, "runs", runsCheckpoint.Len())
+
+				flushHooks = append(flushHooks, func() error {
+					return runsCheckpoint.Save(workflowRunsParams.CheckpointFile, workflowRunsParams.Since)
+				})
+			}
+
 			logger.Info(
 				"Will pull workflows for the following parameters",
 				"repoOwner", repoOwner,
@@ -294,14 +1397,41 @@ var (
 				"workflowID", workflowRunsParams.WorkflowID,
 			)
 
+			if workflowRunsParams.MarkRequiredChecks {
+				workflowRunsParams.RequiredChecks, err = gh.GetRequiredChecks(
+					ctx, logger, client, repoOwner, repoName, workflowRunsParams.Branch,
+				)
+				if err != nil {
+					logger.Error("Unable to get required checks", "err", err)
+					os.Exit(1)
+				}
+			}
+
+			if workflowRunsParams.IncludeCacheStats {
+				writeCacheUsage(ctx, logger, client, repoOwner, repoName)
+			}
+
 			for _, event := range workflowRunsParams.Events {
 				for _, status := range workflowRunsParams.RunStatuses {
+					if stop.Err() != nil {
+						break
+					}
 					pullRunsWithEventAndStatus(
-						ctx, logger, client, repoOwner, repoName, event, status, workflowRunsParams.WorkflowID,
+						ctx, stop, logger, client, opsClient, repoOwner, repoName, event, status, workflowRunsParams.WorkflowID,
 					)
 				}
 			}
 
+			metrics.LogSummary(logger)
+
+			if workflowRunsParams.PollInterval > 0 && stop.Err() == nil {
+				logger.Info("Polling for newly completed workflow runs", "interval", workflowRunsParams.PollInterval)
+				pollRuns(ctx, stop, logger, client, opsClient, repoOwner, repoName, workflowRunsParams.PollInterval)
+			}
+
+			if stop.Err() != nil {
+				logger.Info("Received shutdown signal, flushing documents of the workflow runs pulled so far")
+			}
 		},
 	}
 )
@@ -341,12 +1471,24 @@ func init() {
 	)
 	workflowRunsCmd.PersistentFlags().StringSliceVar(
 		&workflowRunsParams.TestConclusions, "test-conclusions", defaultJUnitConclusions,
-		"Only export test cases with one of the given conclusions. Valid options are 'passed', 'skipped', 'failed'.",
+		"Only export test cases with one of the given conclusions. Valid options are 'passed', 'skipped', 'failed', 'error'.",
+	)
+	workflowRunsCmd.PersistentFlags().StringToStringVar(
+		&workflowRunsParams.TestStatusAliases, "test-status-aliases", nil,
+		"Additional aliases of test case statuses in the form of '<alias>=<status>'. Statuses are normalized "+
+			"before filtering and indexing, with common aliases such as 'failure', 'pass' and 'errored' mapped by default.",
 	)
 	workflowRunsCmd.PersistentFlags().StringSliceVar(
 		&workflowRunsParams.RunStatuses, "run-statuses", defaultGitHubConclusions,
 		"Only export runs with one of the given conclusions or statuses",
 	)
+	workflowRunsCmd.PersistentFlags().StringArrayVar(
+		&workflowRunsParams.RunConclusionRulesStr, "workflow-run-conclusions", nil,
+		"Conclusions of the runs to export of the workflows whose name matches a pattern, in the form of "+
+			"'<workflow pattern>=<conclusion>[,<conclusion>...]', such as 'Conformance*=failure,timed_out'. The "+
+			"first matching pattern applies, and runs of other workflows are exported according to --run-statuses. "+
+			"Conclusions not given in --run-statuses aren't pulled at all.",
+	)
 	workflowRunsCmd.PersistentFlags().BoolVar(
 		&workflowRunsParams.IncludeTestsuites, "test-suites", true,
 		"Download, parse and attach JUnit artifacts if available",
@@ -355,14 +1497,197 @@ func init() {
 		&workflowRunsParams.IncludeErrorLogs, "error-logs", true,
 		"Download logs for each job and include relevant error-specific logs",
 	)
+	workflowRunsCmd.PersistentFlags().BoolVar(
+		&workflowRunsParams.IncludeCacheStats, "cache-stats", false,
+		"Record the Actions cache usage of the repository, and download logs for each job "+
+			"to count cache hits and misses",
+	)
+	workflowRunsCmd.PersistentFlags().BoolVar(
+		&workflowRunsParams.MarkRequiredChecks, "required-checks", false,
+		"Mark jobs which are required checks of the target branch, according to its branch "+
+			"protection and rulesets, and the runs containing them",
+	)
+	workflowRunsCmd.PersistentFlags().DurationVar(
+		&workflowRunsParams.PollInterval, "poll-interval", 0,
+		"If set, keep polling for newly completed workflow runs at the given interval after pulling "+
+			"the given time range, as an alternative to webhooks. Polls which find no changes don't "+
+			"count towards GitHub's rate limit.",
+	)
+	workflowRunsCmd.PersistentFlags().StringVar(
+		&workflowRunsParams.CheckpointFile, "checkpoint-file", "",
+		"File recording which workflow runs were ingested, written once all documents were flushed on exit, "+
+			"including on SIGTERM. Runs recorded in it are skipped on the next start, unless --force is given.",
+	)
+	workflowRunsCmd.PersistentFlags().StringVar(
+		&workflowRunsParams.EmbeddingURL, "embedding-url", "",
+		"OpenAI compatible embeddings endpoint, such as a local model server, used to compute embeddings "+
+			"of test failures for similarity search.",
+	)
+	workflowRunsCmd.PersistentFlags().StringVar(
+		&workflowRunsParams.EmbeddingModel, "embedding-model", "",
+		"Model to request embeddings from, see --embedding-url",
+	)
+	workflowRunsCmd.PersistentFlags().StringVar(
+		&workflowRunsParams.EmbeddingPipeline, "embedding-pipeline", "",
+		"Ingest pipeline to write test cases through, which computes the embeddings of "+
+			"test_case_failure_normalized into test_case_failure_embedding using an OpenSearch ML connector, "+
+			"as an alternative to --embedding-url.",
+	)
 	workflowRunsCmd.PersistentFlags().BoolVar(
 		&workflowRunsParams.ParseWorkflowDispatchInputs, "parse-wd-inputs", true,
-		"For workflow runs triggered by workflow_dispatch that have a job named echo-inputs"+
-			"parse logs to determine the inputs given to the trigger. See cilium/cilium#31424",
+		"For workflow runs triggered by workflow_dispatch that have a job named --wd-inputs-job, "+
+			"parse its logs to determine the inputs given to the trigger. See cilium/cilium#31424",
+	)
+	workflowRunsCmd.PersistentFlags().StringVar(
+		&workflowRunsParams.WorkflowDispatchInputsJob, "wd-inputs-job", "Echo Workflow Dispatch Inputs",
+		"Name of the job of workflow_dispatch runs which echoes their inputs as a JSON object, see --parse-wd-inputs",
+	)
+	workflowRunsCmd.PersistentFlags().BoolVar(
+		&workflowRunsParams.IncludePullRequestReviews, "pr-reviews", false,
+		"For workflow runs triggered by pull requests, include the review state of the "+
+			"pull request as of when the run started",
+	)
+	workflowRunsCmd.PersistentFlags().BoolVar(
+		&workflowRunsParams.IncludePushCommits, "push-commits", false,
+		"For workflow runs triggered by pushes to one of --push-commits-branches, include the commits "+
+			"of the push, or those pushed since the previous push run of the workflow if the push event isn't "+
+			"available anymore, so that new failures can be attributed to them",
+	)
+	workflowRunsCmd.PersistentFlags().BoolVar(
+		&workflowRunsParams.IncludeEventDetails, "event-details", false,
+		"Include the cron expression which triggered scheduled runs and the label which triggered "+
+			"pull request runs in their event summary, at the cost of further GitHub requests",
+	)
+	workflowRunsCmd.PersistentFlags().StringSliceVar(
+		&workflowRunsParams.PushCommitsBranches, "push-commits-branches", []string{"main"},
+		"Branches to include the pushed commits of push runs for, see --push-commits",
 	)
 	workflowRunsCmd.PersistentFlags().Int64VarP(
 		&workflowRunsParams.WorkflowID, "workflow-id", "w", 0,
 		"Only pull the specified workflow ID and not all workflow runs",
 	)
+	workflowRunsCmd.PersistentFlags().BoolVar(
+		&workflowRunsParams.Force, "force", false,
+		"Pull workflow runs even if they were already ingested into the target index. "+
+			"Without this flag, OpenSearch is queried using OPENSEARCH_URL to skip known runs.",
+	)
+	workflowRunsCmd.PersistentFlags().BoolVar(
+		&workflowRunsParams.Strict, "strict", false,
+		"Fail on JUnit files with unknown root elements, elements or attributes which aren't parsed, "+
+			"or test suites without test cases, instead of skipping or dropping them. Meant for "+
+			"developing the parser and catching silent data loss early.",
+	)
+	workflowRunsCmd.PersistentFlags().IntVar(
+		&workflowRunsParams.MaxSuitesPerFile, "max-suites-per-file", 10000,
+		"Skip JUnit files with more test suites than this, recording them as ingest errors. Zero means unlimited.",
+	)
+	workflowRunsCmd.PersistentFlags().IntVar(
+		&workflowRunsParams.MaxTestcasesPerSuite, "max-testcases-per-suite", 100000,
+		"Skip JUnit files with a test suite with more test cases than this, recording them as ingest errors. "+
+			"Zero means unlimited.",
+	)
+	workflowRunsCmd.PersistentFlags().IntVar(
+		&workflowRunsParams.MaxParseErrors, "max-parse-errors", 0,
+		"Abort the ingest of a workflow run once more than this many of its JUnit files couldn't be parsed, "+
+			"recording an ingest error and continuing with the next run. Zero means unlimited.",
+	)
+	workflowRunsCmd.PersistentFlags().Int64Var(
+		&workflowRunsParams.MaxArtifactBytes, "max-artifact-bytes", 1<<30,
+		"Skip artifacts larger than this many bytes instead of downloading them, recording them as ingest "+
+			"errors. Zero means unlimited.",
+	)
+	workflowRunsCmd.PersistentFlags().IntVar(
+		&workflowRunsParams.MaxCompressionRatio, "max-compression-ratio", junit.DefaultMaxCompressionRatio,
+		"Skip files in artifacts which decompress to more than this many times their compressed size, "+
+			"recording them as ingest errors. Zero means unlimited.",
+	)
+	workflowRunsCmd.PersistentFlags().IntVar(
+		&workflowRunsParams.MaxDocsPerRun, "max-docs-per-run", 500000,
+		"Skip the tests of workflow runs which would be written as more documents than this, recording "+
+			"an ingest error instead. Zero means unlimited.",
+	)
+	workflowRunsCmd.PersistentFlags().StringArrayVar(
+		&workflowRunsParams.SystemErrPatternsStr, "system-err-patterns", defaultSystemErrPatterns,
+		"Regular expressions matching error lines in the system-err output of failed test suites. "+
+			"The first matching line is stored as a summary for suites whose test cases have no failure details.",
+	)
+	workflowRunsCmd.PersistentFlags().StringArrayVar(
+		&workflowRunsParams.FailureSignaturesStr, "failure-signatures", defaultFailureSignatures,
+		"Signatures of infrastructure failures in the form of '<name>=<pattern>'. Failed test cases and suites "+
+			"matching any of them are classified as infrastructure failures, and as product failures otherwise.",
+	)
+	workflowRunsCmd.PersistentFlags().StringArrayVar(
+		&workflowRunsParams.ArtifactRoutesStr, "artifact-parsers", gh.DefaultArtifactRoutes,
+		"Routes of artifacts to parsers in the form of '<name pattern>=<parser>'. Each artifact of a run is "+
+			"parsed by the parser of the first route whose pattern matches its name, and ignored if none does. "+
+			"Valid parsers are: "+strings.Join(gh.ArtifactParsers, ", ")+", and "+gh.ArtifactParserPluginPrefix+
+			"<name> for parser plugins loaded with --plugin",
+	)
+	workflowRunsCmd.PersistentFlags().StringVar(
+		&workflowRunsParams.ArchiveURL, "archive", "",
+		"Bucket the raw artifacts of runs are stored in before they are parsed, such as s3://corgi-artifacts "+
+			"or gs://corgi-artifacts/cilium, so that they can be parsed again with the replay command after "+
+			"GitHub deleted them",
+	)
+	workflowRunsCmd.PersistentFlags().StringSliceVar(
+		&workflowRunsParams.FailureDataFormats, "failure-data-formats", junit.DefaultFailureDataFormats,
+		"Formats tried in order to extract owners from the failure data of test cases. "+
+			"Valid formats are: "+strings.Join(junit.FailureDataFormats(), ", "),
+	)
+	workflowRunsCmd.PersistentFlags().StringArrayVar(
+		&workflowRunsParams.RedactionsStr, "redactions", defaultRedactions,
+		"Redactions applied to test names and failure bodies, in the form of '<pattern>=<replacement>'. "+
+			"Use these to replace dynamic identifiers such as pod names, so that failures can be grouped. "+
+			"The original values are kept in the corresponding raw fields.",
+	)
+	workflowRunsCmd.PersistentFlags().StringArrayVar(
+		&workflowRunsParams.SecretRedactionsStr, "secret-redactions", junit.DefaultSecretRedactions,
+		"Redactions of sensitive data, such as tokens and cloud account IDs, applied to failure messages, "+
+			"failure bodies, system-err summaries and job error logs before they are indexed, in the form of "+
+			"'<pattern>=<replacement>'. Unlike --redactions, the original values aren't kept.",
+	)
+	workflowRunsCmd.PersistentFlags().BoolVar(
+		&workflowRunsParams.RedactIPs, "redact-ips", false,
+		"Also replace IPv4 addresses along with --secret-redactions",
+	)
+	workflowRunsCmd.PersistentFlags().StringVar(
+		&workflowRunsParams.OwnerWeighting, "owner-weighting", string(junit.OwnerWeightingEven),
+		"How test case failures are split between multiple owners. Valid values are: "+
+			"even, which gives each owner an equal share, and primary, which gives the full share "+
+			"to owners listed for the failing test itself if there are any",
+	)
+	workflowRunsCmd.PersistentFlags().StringVar(
+		&workflowRunsParams.CodeownersPath, "codeowners", "",
+		"Path to a CODEOWNERS file of the repository. Owners of test cases whose JUnit file attribute "+
+			"matches a rule are added to the owners parsed from their failure data.",
+	)
+	workflowRunsCmd.PersistentFlags().StringVar(
+		&workflowRunsParams.TeamsPath, "teams", "",
+		"Path to a JSON file mapping owner handles to canonical team names, Slack channels and Jira "+
+			"components, which are added to test cases along with their owners",
+	)
+	workflowRunsCmd.PersistentFlags().BoolVar(
+		&workflowRunsParams.ExpandTeamOwners, "expand-team-owners", false,
+		"Expand the GitHub teams owning test cases into their members through the Teams API, so that "+
+			"tests can be filtered by individual engineers. Needs a token with read:org.",
+	)
+	workflowRunsCmd.PersistentFlags().DurationVar(
+		&workflowRunsParams.TeamMembersTTL, "team-members-ttl", time.Hour,
+		"How long the members of a team are cached for, see --expand-team-owners",
+	)
+	workflowRunsCmd.PersistentFlags().IntVar(
+		&workflowRunsParams.ArtifactPrefetchConcurrency, "artifact-prefetch-concurrency", 4,
+		"Number of workflow runs to list artifacts for concurrently, ahead of pulling the runs",
+	)
+	workflowRunsCmd.PersistentFlags().IntVar(
+		&workflowRunsParams.MaxFailureTextBytes, "max-failure-text-bytes", 32*1024,
+		"Maximum size of test failure text to store in full. Larger failure text is replaced "+
+			"with an excerpt of its head and tail, along with a hash of the full text. Zero means unlimited.",
+	)
+	workflowRunsCmd.PersistentFlags().BoolVar(
+		&workflowRunsParams.CompressFailureText, "compress-failure-text", false,
+		"Store test failure text exceeding --max-failure-text-bytes in full, gzip compressed, "+
+			"next to its excerpt",
+	)
 	workflowCmd.AddCommand(workflowRunsCmd)
 }
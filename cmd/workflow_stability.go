@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/spf13/cobra"
+
+	"github.com/isovalent/corgi/pkg/log"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/report"
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+type typeWorkflowStabilityParams struct {
+	WindowStr  string
+	Window     time.Duration
+	Repository string
+	Branch     string
+	MinRuns    int
+	Interval   time.Duration
+}
+
+// indexWorkflowStability computes the stability of each workflow within the window ending
+// now and writes it.
+func indexWorkflowStability(ctx context.Context, logger *slog.Logger, client *opensearchgo.Client) error {
+	until := time.Now().UTC()
+	since := until.Add(-workflowStabilityParams.Window)
+
+	b, err := report.LoadStabilityBuilder(
+		ctx, client, readIndex(types.TypeNameWorkflowRun, types.TypeNameTestcase), since, until,
+		workflowStabilityParams.Repository, workflowStabilityParams.Branch,
+	)
+	if err != nil {
+		return err
+	}
+
+	stability := b.Build(workflowStabilityParams.MinRuns)
+
+	logger.Info(
+		"Computed workflow stability, saving",
+		"num-results", len(stability), "target-index", indexFor(types.TypeNameWorkflowStability),
+	)
+
+	if err := opensearch.BulkWriteObjects(
+		stability, indexFor(types.TypeNameWorkflowStability), rootParams.BulkOptions, bulkOutput,
+	); err != nil {
+		return fmt.Errorf("unable to write workflow stability: %w", err)
+	}
+
+	return nil
+}
+
+var (
+	workflowStabilityParams = &typeWorkflowStabilityParams{}
+	workflowStabilityCmd    = &cobra.Command{
+		Use:   "workflow-stability",
+		Short: "Index a composite stability score of each workflow",
+		Long: "Compute the pass rate, flake rate, infrastructure failure rate and duration variance of each " +
+			"workflow within the window ending now, combine them into a stability score from 0 to 100, " +
+			"and write them as workflow_stability documents. With --interval, such as 24h, the scores " +
+			"are recomputed periodically until interrupted.",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			window, err := util.ParseDuration(workflowStabilityParams.WindowStr)
+			if err != nil {
+				return fmt.Errorf("unable to parse window: %w", err)
+			}
+			workflowStabilityParams.Window = window
+
+			if workflowStabilityParams.Interval < 0 {
+				return fmt.Errorf("--interval must not be negative")
+			}
+
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			logger := log.NewLogger(rootParams.Verbose)
+
+			opensearchCfg, err := opensearch.NewClientConfig()
+			if err != nil {
+				logger.Error("Unable to load OpenSearch configuration", "err", err)
+				os.Exit(1)
+			}
+
+			opsClient, err := opensearchgo.NewClient(opensearchCfg)
+			if err != nil {
+				logger.Error("Unable to create opensearch client", "err", err)
+				os.Exit(1)
+			}
+
+			if err := runPeriodically(logger, workflowStabilityParams.Interval, func(ctx context.Context) error {
+				return indexWorkflowStability(ctx, logger, opsClient)
+			}); err != nil {
+				logger.Error("Unable to index workflow stability", "err", err)
+				os.Exit(1)
+			}
+		},
+	}
+)
+
+func init() {
+	workflowStabilityCmd.PersistentFlags().StringVarP(
+		&workflowStabilityParams.WindowStr, "window", "w", "14d",
+		"Rolling time window to score workflows over, ending now, such as 14d",
+	)
+	workflowStabilityCmd.PersistentFlags().StringVarP(
+		&workflowStabilityParams.Repository, "repository", "r", "",
+		"Only look at workflow runs of the given repository, such as cilium/cilium",
+	)
+	workflowStabilityCmd.PersistentFlags().StringVarP(
+		&workflowStabilityParams.Branch, "branch", "b", "main",
+		"Only look at workflow runs on the given branch. An empty value looks at every branch.",
+	)
+	workflowStabilityCmd.PersistentFlags().IntVar(
+		&workflowStabilityParams.MinRuns, "min-runs", 5,
+		"Minimum number of completed run attempts of a workflow within the window for it to be scored",
+	)
+	workflowStabilityCmd.PersistentFlags().DurationVar(
+		&workflowStabilityParams.Interval, "interval", 0,
+		"Recompute the scores this often until interrupted, such as 24h. Zero computes them once.",
+	)
+
+	rootCmd.AddCommand(workflowStabilityCmd)
+}
@@ -0,0 +1,136 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/isovalent/corgi/pkg/report"
+)
+
+// Alert is the state of a single condition which may page someone.
+type Alert struct {
+	// DedupKey identifies the condition, so that repeated alerts for the same
+	// condition are grouped and can be resolved.
+	DedupKey string
+	Summary  string
+	Details  map[string]any
+	// Firing is false if the condition is resolved.
+	Firing bool
+}
+
+// Notifier delivers alerts to an alerting service.
+type Notifier interface {
+	// Send triggers or resolves the given alert, depending on whether it is firing.
+	Send(ctx context.Context, a Alert) error
+}
+
+// Thresholds configures when alerts fire.
+type Thresholds struct {
+	Repository string
+	Branch     string
+	// MaxFailures is the number of distinct failed tests of a workflow above which
+	// an alert fires.
+	MaxFailures int
+	// RequiredWorkflows are the workflows whose pass rate is checked.
+	RequiredWorkflows []string
+	// MinPassRate is the pass rate of required workflows below which an alert fires.
+	MinPassRate float64
+	// ResultMismatches enables alerts for workflows with runs whose conclusion disagrees
+	// with their JUnit results.
+	ResultMismatches bool
+}
+
+func (t Thresholds) dedupKey(workflow, condition string) string {
+	return strings.Join([]string{"corgi", t.Repository, t.Branch, workflow, condition}, "/")
+}
+
+// Evaluate returns the state of the alerts for the failed tests in failures and the
+// pass rates in passRates, which are usually loaded over different time windows. An
+// alert is returned for every workflow seen, so that alerts which stopped firing are
+// resolved.
+func Evaluate(t Thresholds, failures, passRates *report.BranchHealth) []Alert {
+	alerts := []Alert{}
+
+	workflows := make([]string, 0, len(failures.FailedTests))
+	for workflow := range failures.FailedTests {
+		workflows = append(workflows, workflow)
+	}
+	slices.Sort(workflows)
+
+	for _, workflow := range workflows {
+		failed := failures.FailedTests[workflow]
+		alerts = append(alerts, Alert{
+			DedupKey: t.dedupKey(workflow, "failures"),
+			Summary: fmt.Sprintf(
+				"%d distinct tests failed in %s on %s of %s", len(failed), workflow, t.Branch, t.Repository,
+			),
+			Details: map[string]any{"workflow": workflow, "failed_tests": failed},
+			Firing:  len(failed) > t.MaxFailures,
+		})
+	}
+
+	for _, rate := range passRates.PassRates {
+		if !slices.Contains(t.RequiredWorkflows, rate.Workflow) {
+			continue
+		}
+
+		alerts = append(alerts, Alert{
+			DedupKey: t.dedupKey(rate.Workflow, "pass-rate"),
+			Summary: fmt.Sprintf(
+				"Pass rate of %s on %s of %s dropped to %.0f%%", rate.Workflow, t.Branch, t.Repository, rate.Rate*100,
+			),
+			Details: map[string]any{
+				"workflow": rate.Workflow, "runs": rate.Total, "passed": rate.Passed, "cancelled": rate.Cancelled,
+			},
+			// Workflows whose runs were all cancelled have no pass rate.
+			Firing: rate.Total > 0 && rate.Rate < t.MinPassRate,
+		})
+	}
+
+	if t.ResultMismatches {
+		for _, rate := range failures.PassRates {
+			runs := failures.ResultMismatches[rate.Workflow]
+			alerts = append(alerts, Alert{
+				DedupKey: t.dedupKey(rate.Workflow, "result-mismatch"),
+				Summary: fmt.Sprintf(
+					"%d runs of %s on %s of %s concluded differently than their JUnit results",
+					len(runs), rate.Workflow, t.Branch, t.Repository,
+				),
+				Details: map[string]any{"workflow": rate.Workflow, "runs": runs},
+				Firing:  len(runs) > 0,
+			})
+		}
+	}
+
+	return alerts
+}
+
+// EvaluateTestCounts returns the state of the alerts for the given test counts, which fire
+// if the tests of a workflow or of one of its suites dropped, as such tests silently stop
+// running rather than fail.
+func EvaluateTestCounts(t Thresholds, counts []report.TestCount) []Alert {
+	alerts := make([]Alert, 0, len(counts))
+	for _, c := range counts {
+		condition, what := "test-count", c.Run.Name
+		if c.Suite != "" {
+			condition, what = "test-count/"+c.Suite, fmt.Sprintf("suite %s of %s", c.Suite, c.Run.Name)
+		}
+
+		alerts = append(alerts, Alert{
+			DedupKey: t.dedupKey(c.Run.Name, condition),
+			Summary: fmt.Sprintf(
+				"%s on %s of %s reported %d tests instead of the usual %d",
+				what, t.Branch, t.Repository, c.Actual, c.Expected,
+			),
+			Details: map[string]any{
+				"workflow": c.Run.Name, "suite": c.Suite, "workflow_url": c.Run.URL,
+				"expected": c.Expected, "actual": c.Actual, "baseline_runs": c.BaselineRuns,
+			},
+			Firing: c.Dropped,
+		})
+	}
+
+	return alerts
+}
@@ -0,0 +1,130 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/isovalent/corgi/pkg/report"
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+func TestEvaluate(t *testing.T) {
+	thresholds := Thresholds{
+		Repository: "cilium/cilium", Branch: "main", MaxFailures: 1,
+		RequiredWorkflows: []string{"ci"}, MinPassRate: 0.8,
+	}
+
+	alerts := Evaluate(thresholds, &report.BranchHealth{
+		FailedTests: map[string][]string{"ci": {"a", "b"}, "e2e": {"c"}},
+	}, &report.BranchHealth{
+		PassRates: []report.PassRate{
+			{Workflow: "ci", Total: 10, Passed: 7, Rate: 0.7},
+			{Workflow: "other", Total: 10, Passed: 1, Rate: 0.1},
+		},
+	})
+
+	assert.Len(t, alerts, 3)
+	assert.Equal(t, "corgi/cilium/cilium/main/ci/failures", alerts[0].DedupKey)
+	assert.True(t, alerts[0].Firing)
+	assert.Equal(t, "corgi/cilium/cilium/main/e2e/failures", alerts[1].DedupKey)
+	assert.False(t, alerts[1].Firing)
+	assert.Equal(t, "corgi/cilium/cilium/main/ci/pass-rate", alerts[2].DedupKey)
+	assert.True(t, alerts[2].Firing)
+}
+
+func TestEvaluateResultMismatches(t *testing.T) {
+	thresholds := Thresholds{Repository: "cilium/cilium", Branch: "main", ResultMismatches: true}
+
+	alerts := Evaluate(thresholds, &report.BranchHealth{
+		PassRates: []report.PassRate{{Workflow: "ci"}, {Workflow: "e2e"}},
+		ResultMismatches: map[string][]string{
+			"e2e": {"https://github.com/cilium/cilium/actions/runs/1"},
+		},
+	}, &report.BranchHealth{})
+
+	assert.Len(t, alerts, 2)
+	assert.Equal(t, "corgi/cilium/cilium/main/ci/result-mismatch", alerts[0].DedupKey)
+	assert.False(t, alerts[0].Firing)
+	assert.Equal(t, "corgi/cilium/cilium/main/e2e/result-mismatch", alerts[1].DedupKey)
+	assert.Equal(t, "1 runs of e2e on main of cilium/cilium concluded differently than their JUnit results", alerts[1].Summary)
+	assert.True(t, alerts[1].Firing)
+
+	thresholds.ResultMismatches = false
+	assert.Empty(t, Evaluate(thresholds, &report.BranchHealth{PassRates: []report.PassRate{{Workflow: "ci"}}}, &report.BranchHealth{}))
+}
+
+func TestEvaluateTestCounts(t *testing.T) {
+	thresholds := Thresholds{Repository: "cilium/cilium", Branch: "main"}
+	run := &types.WorkflowRun{Name: "ci"}
+
+	alerts := EvaluateTestCounts(thresholds, []report.TestCount{
+		{Run: run, Expected: 110, Actual: 110},
+		{Run: run, Suite: "e2e", Expected: 10, Actual: 2, Dropped: true},
+	})
+
+	assert.Len(t, alerts, 2)
+	assert.Equal(t, "corgi/cilium/cilium/main/ci/test-count", alerts[0].DedupKey)
+	assert.False(t, alerts[0].Firing)
+	assert.Equal(t, "corgi/cilium/cilium/main/ci/test-count/e2e", alerts[1].DedupKey)
+	assert.Equal(t, "suite e2e of ci on main of cilium/cilium reported 2 tests instead of the usual 10", alerts[1].Summary)
+	assert.True(t, alerts[1].Firing)
+}
+
+func TestPagerDuty(t *testing.T) {
+	events := []map[string]any{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event := map[string]any{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		events = append(events, event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	t.Setenv("PAGERDUTY_TEST_KEY", "key")
+	key, err := util.NewSecret("PAGERDUTY_TEST_KEY")
+	assert.NoError(t, err)
+
+	p := NewPagerDuty(srv.URL, key, "corgi")
+	assert.NoError(t, p.Send(context.Background(), Alert{DedupKey: "k", Summary: "s", Firing: true}))
+	assert.NoError(t, p.Send(context.Background(), Alert{DedupKey: "k"}))
+
+	assert.Len(t, events, 2)
+	assert.Equal(t, "trigger", events[0]["event_action"])
+	assert.Equal(t, "key", events[0]["routing_key"])
+	assert.Equal(t, "s", events[0]["payload"].(map[string]any)["summary"])
+	assert.Equal(t, "resolve", events[1]["event_action"])
+	assert.Equal(t, "k", events[1]["dedup_key"])
+}
+
+func TestChatWebhook(t *testing.T) {
+	bodies := []map[string]any{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]any{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		bodies = append(bodies, body)
+	}))
+	defer srv.Close()
+
+	t.Setenv("CHAT_TEST_URL", srv.URL)
+	url, err := util.NewSecret("CHAT_TEST_URL")
+	assert.NoError(t, err)
+
+	a := Alert{Summary: "2 distinct tests failed", Details: map[string]any{"failed_tests": []string{"a", "b"}}, Firing: true}
+	for _, n := range []Notifier{NewSlack(url), NewDiscord(url), NewTeams(url)} {
+		assert.NoError(t, n.Send(context.Background(), a))
+		assert.NoError(t, n.Send(context.Background(), Alert{Summary: "resolved"}))
+	}
+
+	text := "CI alert: 2 distinct tests failed\nfailed_tests: a, b"
+	assert.Len(t, bodies, 3)
+	assert.Equal(t, text, bodies[0]["text"])
+	assert.Equal(t, text, bodies[1]["content"])
+	card := bodies[2]["attachments"].([]any)[0].(map[string]any)["content"].(map[string]any)
+	assert.Equal(t, text, card["body"].([]any)[0].(map[string]any)["text"])
+}
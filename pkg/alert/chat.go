@@ -0,0 +1,102 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+// maxDiscordContent is the maximum length of the content of a Discord message.
+const maxDiscordContent = 2000
+
+// ChatWebhook posts firing alerts as messages to the incoming webhook of a chat platform.
+// Chat messages can't be resolved, so alerts which aren't firing are not posted.
+type ChatWebhook struct {
+	url        *util.Secret
+	payload    func(text string) any
+	httpClient *http.Client
+}
+
+// NewSlack creates a notifier posting to a Slack incoming webhook.
+func NewSlack(url *util.Secret) *ChatWebhook {
+	return &ChatWebhook{
+		url:        url,
+		payload:    func(text string) any { return map[string]any{"text": text} },
+		httpClient: http.DefaultClient,
+	}
+}
+
+// NewDiscord creates a notifier posting to a Discord webhook.
+func NewDiscord(url *util.Secret) *ChatWebhook {
+	return &ChatWebhook{
+		url: url,
+		payload: func(text string) any {
+			if len(text) > maxDiscordContent {
+				text = text[:maxDiscordContent]
+			}
+			return map[string]any{"content": text}
+		},
+		httpClient: http.DefaultClient,
+	}
+}
+
+// NewTeams creates a notifier posting to a Microsoft Teams webhook, as created by the
+// "Post to a channel when a webhook request is received" workflow.
+func NewTeams(url *util.Secret) *ChatWebhook {
+	return &ChatWebhook{
+		url: url,
+		payload: func(text string) any {
+			return map[string]any{
+				"type": "message",
+				"attachments": []any{map[string]any{
+					"contentType": "application/vnd.microsoft.card.adaptive",
+					"content": map[string]any{
+						"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+						"type":    "AdaptiveCard",
+						"version": "1.4",
+						"body": []any{map[string]any{
+							"type": "TextBlock",
+							"text": text,
+							"wrap": true,
+						}},
+					},
+				}},
+			}
+		},
+		httpClient: http.DefaultClient,
+	}
+}
+
+func (c *ChatWebhook) Send(ctx context.Context, a Alert) error {
+	if !a.Firing {
+		return nil
+	}
+
+	return postJSON(ctx, c.httpClient, c.url.Value(), nil, c.payload(chatText(a)))
+}
+
+// chatText formats the given alert as a plain text message.
+func chatText(a Alert) string {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "CI alert: %s", a.Summary)
+
+	keys := make([]string, 0, len(a.Details))
+	for k := range a.Details {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	for _, k := range keys {
+		v := a.Details[k]
+		if list, ok := v.([]string); ok {
+			v = strings.Join(list, ", ")
+		}
+		fmt.Fprintf(b, "\n%s: %v", k, v)
+	}
+
+	return b.String()
+}
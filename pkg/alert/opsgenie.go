@@ -0,0 +1,72 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+// OpsgenieURL is the endpoint of the Opsgenie API. Accounts in the EU region use
+// https://api.eu.opsgenie.com instead.
+const OpsgenieURL = "https://api.opsgenie.com"
+
+// maxOpsgenieMessage is the maximum length of the message of an Opsgenie alert.
+const maxOpsgenieMessage = 130
+
+// Opsgenie creates and closes Opsgenie alerts, using the dedup key as alias.
+type Opsgenie struct {
+	url        string
+	apiKey     *util.Secret
+	source     string
+	httpClient *http.Client
+}
+
+// NewOpsgenie creates a notifier using the Opsgenie API at baseURL with the given API
+// key, reporting the given source.
+func NewOpsgenie(baseURL string, apiKey *util.Secret, source string) *Opsgenie {
+	return &Opsgenie{
+		url:        strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+		source:     source,
+		httpClient: http.DefaultClient,
+	}
+}
+
+func (o *Opsgenie) Send(ctx context.Context, a Alert) error {
+	headers := map[string]string{"Authorization": "GenieKey " + o.apiKey.Value()}
+
+	if !a.Firing {
+		return postJSON(
+			ctx, o.httpClient,
+			o.url+"/v2/alerts/"+url.PathEscape(a.DedupKey)+"/close?identifierType=alias",
+			headers, map[string]any{"source": o.source},
+		)
+	}
+
+	// Opsgenie only accepts string details, and messages up to 130 characters.
+	details := map[string]string{}
+	for k, v := range a.Details {
+		if list, ok := v.([]string); ok {
+			details[k] = strings.Join(list, ", ")
+		} else {
+			details[k] = fmt.Sprint(v)
+		}
+	}
+
+	message := a.Summary
+	if len(message) > maxOpsgenieMessage {
+		message = message[:maxOpsgenieMessage]
+	}
+
+	return postJSON(ctx, o.httpClient, o.url+"/v2/alerts", headers, map[string]any{
+		"message":     message,
+		"description": a.Summary,
+		"alias":       a.DedupKey,
+		"source":      o.source,
+		"details":     details,
+	})
+}
@@ -0,0 +1,45 @@
+package alert
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+// PagerDutyEventsURL is the endpoint of the PagerDuty Events API v2.
+const PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDuty sends alerts as events to a PagerDuty service through the Events API v2.
+type PagerDuty struct {
+	url        string
+	routingKey *util.Secret
+	source     string
+	httpClient *http.Client
+}
+
+// NewPagerDuty creates a notifier sending events with the given integration key to
+// the Events API at url, reporting the given source.
+func NewPagerDuty(url string, routingKey *util.Secret, source string) *PagerDuty {
+	return &PagerDuty{url: url, routingKey: routingKey, source: source, httpClient: http.DefaultClient}
+}
+
+func (p *PagerDuty) Send(ctx context.Context, a Alert) error {
+	event := map[string]any{
+		"routing_key":  p.routingKey.Value(),
+		"event_action": "resolve",
+		"dedup_key":    a.DedupKey,
+	}
+
+	if a.Firing {
+		event["event_action"] = "trigger"
+		event["payload"] = map[string]any{
+			"summary":        a.Summary,
+			"source":         p.source,
+			"severity":       "error",
+			"custom_details": a.Details,
+		}
+	}
+
+	return postJSON(ctx, p.httpClient, p.url, nil, event)
+}
@@ -0,0 +1,164 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// Archive stores the raw artifacts of workflow runs, so that they can be parsed again
+// after GitHub deleted them.
+type Archive interface {
+	// Put stores the object read from r, which has the given size, at the given key.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Get returns the object at the given key, which the caller needs to close.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// List returns the keys of the objects whose key starts with the given prefix.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// New returns the archive with the given URL, which is either an S3 bucket, such as
+// s3://corgi-artifacts, or a Google Cloud Storage bucket, such as gs://corgi-artifacts.
+// A path after the bucket is used as a prefix of the keys of all objects.
+func New(archiveURL string) (Archive, error) {
+	u, err := url.Parse(archiveURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse archive url '%s': %w", archiveURL, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("expected bucket in archive url '%s'", archiveURL)
+	}
+
+	prefix := strings.Trim(u.Path, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	switch u.Scheme {
+	case "s3":
+		return NewS3(u.Host, prefix)
+	case "gs":
+		return NewGCS(u.Host, prefix)
+	default:
+		return nil, fmt.Errorf("expected archive url in the form of 's3://<bucket>' or 'gs://<bucket>', got '%s'", archiveURL)
+	}
+}
+
+// RunPrefix returns the prefix of the keys of the objects of every attempt of the given
+// workflow run of a repository, such as cilium/cilium.
+func RunPrefix(repository string, runID int64) string {
+	return fmt.Sprintf("%s/%d/", repository, runID)
+}
+
+func attemptPrefix(run *types.WorkflowRun) string {
+	return fmt.Sprintf("%s%d/", RunPrefix(run.Repository.FullName, run.ID), run.RunAttempt)
+}
+
+// RunKey returns the key of the given workflow run attempt.
+func RunKey(run *types.WorkflowRun) string {
+	return attemptPrefix(run) + "run.json"
+}
+
+// ArtifactKey returns the key of the given artifact of a workflow run attempt.
+func ArtifactKey(run *types.WorkflowRun, artifactID int64, artifactName string) string {
+	return fmt.Sprintf("%sartifacts/%d/%s.zip", attemptPrefix(run), artifactID, artifactName)
+}
+
+// PutRun stores the given workflow run attempt, so that documents can be written for
+// it when its artifacts are parsed again.
+func PutRun(ctx context.Context, a Archive, run *types.WorkflowRun) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("unable to marshal workflow run: %w", err)
+	}
+
+	return a.Put(ctx, RunKey(run), bytes.NewReader(data), int64(len(data)))
+}
+
+// GetRun returns the workflow run attempt stored at the given key.
+func GetRun(ctx context.Context, a Archive, key string) (*types.WorkflowRun, error) {
+	r, err := a.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	run := &types.WorkflowRun{}
+	if err := json.NewDecoder(r).Decode(run); err != nil {
+		return nil, fmt.Errorf("unable to decode workflow run %s: %w", key, err)
+	}
+
+	return run, nil
+}
+
+// ArchivedArtifact is an artifact of a workflow run attempt in the archive.
+type ArchivedArtifact struct {
+	Key  string
+	ID   int64
+	Name string
+}
+
+// ArchivedRun is a workflow run attempt in the archive.
+type ArchivedRun struct {
+	// Key is the key of the workflow run attempt itself.
+	Key       string
+	Artifacts []ArchivedArtifact
+}
+
+// Runs groups the given keys, as returned by List, into workflow run attempts, ordered
+// by key. Artifacts of attempts which weren't stored themselves are left out, along with
+// keys which weren't written by PutRun or for ArtifactKey.
+func Runs(keys []string) []ArchivedRun {
+	runs := map[string]*ArchivedRun{}
+	run := func(prefix string) *ArchivedRun {
+		r, ok := runs[prefix]
+		if !ok {
+			r = &ArchivedRun{}
+			runs[prefix] = r
+		}
+
+		return r
+	}
+
+	for _, key := range keys {
+		if prefix, ok := strings.CutSuffix(key, "/run.json"); ok {
+			run(prefix).Key = key
+			continue
+		}
+
+		prefix, rest, ok := strings.Cut(key, "/artifacts/")
+		if !ok {
+			continue
+		}
+		idStr, file, ok := strings.Cut(rest, "/")
+		if !ok {
+			continue
+		}
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		name, isZip := strings.CutSuffix(file, ".zip")
+		if err != nil || !isZip {
+			continue
+		}
+
+		r := run(prefix)
+		r.Artifacts = append(r.Artifacts, ArchivedArtifact{Key: key, ID: id, Name: name})
+	}
+
+	result := []ArchivedRun{}
+	for _, r := range runs {
+		if r.Key != "" {
+			result = append(result, *r)
+		}
+	}
+	slices.SortFunc(result, func(a, b ArchivedRun) int { return strings.Compare(a.Key, b.Key) })
+
+	return result
+}
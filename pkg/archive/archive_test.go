@@ -0,0 +1,212 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+var dummyRun = &types.WorkflowRun{
+	ID:         42,
+	RunAttempt: 2,
+	Name:       "ci",
+	Repository: types.Repository{FullName: "cilium/cilium"},
+}
+
+func TestNew(t *testing.T) {
+	t.Setenv("AWS_REGION", "eu-west-1")
+	t.Setenv("AWS_ENDPOINT_URL_S3", "")
+
+	a, err := New("s3://corgi-artifacts/cilium/")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://corgi-artifacts.s3.eu-west-1.amazonaws.com", a.(*S3).endpoint)
+	assert.Equal(t, "cilium/", a.(*S3).prefix)
+
+	t.Setenv("STORAGE_EMULATOR_HOST", "localhost:4443")
+	a, err = New("gs://corgi-artifacts")
+	assert.NoError(t, err)
+	assert.Equal(t, "corgi-artifacts", a.(*GCS).bucket)
+	assert.Equal(t, "", a.(*GCS).prefix)
+
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	_, err = New("s3://corgi-artifacts")
+	assert.ErrorContains(t, err, "unable to determine region")
+
+	for _, u := range []string{"corgi-artifacts", "s3:///prefix", "ftp://corgi-artifacts"} {
+		_, err = New(u)
+		assert.Error(t, err, u)
+	}
+}
+
+func TestRuns(t *testing.T) {
+	other := *dummyRun
+	other.RunAttempt = 1
+
+	runs := Runs([]string{
+		ArtifactKey(dummyRun, 7, "cilium-junits"),
+		RunKey(dummyRun),
+		ArtifactKey(dummyRun, 8, "cilium-sysdumps"),
+		RunKey(&other),
+		ArtifactKey(&types.WorkflowRun{ID: 43, Repository: dummyRun.Repository}, 9, "cilium-junits"),
+		"cilium/cilium/42/2/artifacts/x/cilium-junits.zip",
+		"cilium/cilium/42/2/notes.txt",
+	})
+
+	assert.Equal(t, []ArchivedRun{
+		{Key: "cilium/cilium/42/1/run.json"},
+		{
+			Key: "cilium/cilium/42/2/run.json",
+			Artifacts: []ArchivedArtifact{
+				{Key: "cilium/cilium/42/2/artifacts/7/cilium-junits.zip", ID: 7, Name: "cilium-junits"},
+				{Key: "cilium/cilium/42/2/artifacts/8/cilium-sysdumps.zip", ID: 8, Name: "cilium-sysdumps"},
+			},
+		},
+	}, runs)
+}
+
+// fakeBucket is an in-memory bucket, serving the objects in it.
+type fakeBucket map[string]string
+
+func (b fakeBucket) list(prefix string) []string {
+	keys := []string{}
+	for key := range b {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	return keys
+}
+
+// roundTrip stores an archived run and artifact in a, and reads them back.
+func roundTrip(t *testing.T, a Archive) {
+	ctx := context.Background()
+
+	assert.NoError(t, PutRun(ctx, a, dummyRun))
+	key := ArtifactKey(dummyRun, 7, "cilium-junits")
+	assert.NoError(t, a.Put(ctx, key, strings.NewReader("zip"), 3))
+
+	keys, err := a.List(ctx, RunPrefix("cilium/cilium", 42))
+	assert.NoError(t, err)
+	runs := Runs(keys)
+	if !assert.Len(t, runs, 1) {
+		return
+	}
+
+	run, err := GetRun(ctx, a, runs[0].Key)
+	assert.NoError(t, err)
+	assert.Equal(t, dummyRun.Name, run.Name)
+	assert.Equal(t, dummyRun.RunAttempt, run.RunAttempt)
+
+	r, err := a.Get(ctx, runs[0].Artifacts[0].Key)
+	assert.NoError(t, err)
+	data, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	r.Close()
+	assert.Equal(t, "zip", string(data))
+
+	_, err = a.Get(ctx, "missing")
+	assert.ErrorContains(t, err, "404")
+}
+
+func TestS3(t *testing.T) {
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	bucket := fakeBucket{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/"))
+
+		key, ok := strings.CutPrefix(r.URL.Path, "/corgi-artifacts/")
+		switch {
+		case r.Method == http.MethodPut && ok:
+			assert.Equal(t, "UNSIGNED-PAYLOAD", r.Header.Get("X-Amz-Content-Sha256"))
+			data, _ := io.ReadAll(r.Body)
+			bucket[key] = string(data)
+		case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+			// Return one key per page, to cover pagination.
+			keys := bucket.list(r.URL.Query().Get("prefix"))
+			i := 0
+			if token := r.URL.Query().Get("continuation-token"); token != "" {
+				fmt.Sscan(token, &i)
+			}
+			fmt.Fprintf(w, "<ListBucketResult>")
+			if i < len(keys) {
+				fmt.Fprintf(w, "<Contents><Key>%s</Key></Contents>", keys[i])
+			}
+			if i+1 < len(keys) {
+				fmt.Fprintf(w, "<IsTruncated>true</IsTruncated><NextContinuationToken>%d</NextContinuationToken>", i+1)
+			}
+			fmt.Fprintf(w, "</ListBucketResult>")
+		case r.Method == http.MethodGet && ok:
+			data, found := bucket[key]
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			fmt.Fprint(w, data)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("AWS_ENDPOINT_URL_S3", server.URL)
+	a, err := New("s3://corgi-artifacts/archive")
+	assert.NoError(t, err)
+
+	roundTrip(t, a)
+	assert.Contains(t, bucket, "archive/cilium/cilium/42/2/run.json")
+}
+
+func TestGCS(t *testing.T) {
+	bucket := fakeBucket{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"))
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/upload/storage/v1/b/corgi-artifacts/o":
+			assert.Equal(t, "media", r.URL.Query().Get("uploadType"))
+			data, _ := io.ReadAll(r.Body)
+			bucket[r.URL.Query().Get("name")] = string(data)
+			fmt.Fprint(w, `{}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/storage/v1/b/corgi-artifacts/o":
+			items := []map[string]string{}
+			for _, key := range bucket.list(r.URL.Query().Get("prefix")) {
+				items = append(items, map[string]string{"name": key})
+			}
+			assert.NoError(t, json.NewEncoder(w).Encode(map[string]any{"items": items}))
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/storage/v1/b/corgi-artifacts/o/"):
+			assert.Equal(t, "media", r.URL.Query().Get("alt"))
+			data, found := bucket[strings.TrimPrefix(r.URL.Path, "/storage/v1/b/corgi-artifacts/o/")]
+			if !found {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			fmt.Fprint(w, data)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("STORAGE_EMULATOR_HOST", strings.TrimPrefix(server.URL, "http://"))
+	a, err := New("gs://corgi-artifacts")
+	assert.NoError(t, err)
+
+	roundTrip(t, a)
+	assert.Contains(t, bucket, "cilium/cilium/42/2/artifacts/7/cilium-junits.zip")
+}
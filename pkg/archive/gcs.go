@@ -0,0 +1,227 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+const (
+	gcsEndpoint = "https://storage.googleapis.com"
+	// metadataTokenURL returns access tokens of the service account of the GCE instance or
+	// GKE workload corgi runs as.
+	metadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// GCS stores objects in a Google Cloud Storage bucket through its JSON API. Requests are
+// authenticated with the access token in GOOGLE_OAUTH_ACCESS_TOKEN, or with a token of
+// the service account of the instance from the metadata server otherwise. If
+// STORAGE_EMULATOR_HOST is set, the emulator is used without authentication.
+type GCS struct {
+	bucket     string
+	prefix     string
+	endpoint   string
+	token      *util.Secret
+	httpClient *http.Client
+
+	// metadata caches the access token from the metadata server.
+	metadataMu      sync.Mutex
+	metadataToken   string
+	metadataExpires time.Time
+}
+
+// NewGCS creates an archive in the given bucket, prefixing the keys of all objects with
+// prefix.
+func NewGCS(bucket, prefix string) (*GCS, error) {
+	g := &GCS{
+		bucket:     bucket,
+		prefix:     prefix,
+		endpoint:   gcsEndpoint,
+		httpClient: &http.Client{},
+	}
+
+	if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+		g.endpoint = "http://" + host
+		return g, nil
+	}
+
+	if os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN") != "" || os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN_FILE") != "" {
+		token, err := util.NewSecret("GOOGLE_OAUTH_ACCESS_TOKEN")
+		if err != nil {
+			return nil, err
+		}
+		g.token = token
+	}
+
+	return g, nil
+}
+
+// do authenticates and sends the given request, returning its response if it succeeded.
+func (g *GCS) do(req *http.Request) (*http.Response, error) {
+	if g.endpoint == gcsEndpoint {
+		token, err := g.accessToken(req.Context())
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("bad http code %s: %s", resp.Status, msg)
+	}
+
+	return resp, nil
+}
+
+// Put implements Archive.
+func (g *GCS) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	query := url.Values{"uploadType": {"media"}, "name": {g.prefix + key}}
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost,
+		fmt.Sprintf("%s/upload/storage/v1/b/%s/o?%s", g.endpoint, url.PathEscape(g.bucket), query.Encode()),
+		r,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := g.do(req)
+	if err != nil {
+		return fmt.Errorf("unable to put object %s: %w", key, err)
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// Get implements Archive.
+func (g *GCS) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodGet,
+		fmt.Sprintf(
+			"%s/storage/v1/b/%s/o/%s?alt=media",
+			g.endpoint, url.PathEscape(g.bucket), url.PathEscape(g.prefix+key),
+		),
+		nil,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request: %w", err)
+	}
+
+	resp, err := g.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get object %s: %w", key, err)
+	}
+
+	return resp.Body, nil
+}
+
+// List implements Archive.
+func (g *GCS) List(ctx context.Context, prefix string) ([]string, error) {
+	keys := []string{}
+	pageToken := ""
+
+	for {
+		query := url.Values{"prefix": {g.prefix + prefix}, "fields": {"items(name),nextPageToken"}}
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+
+		req, err := http.NewRequestWithContext(
+			ctx, http.MethodGet,
+			fmt.Sprintf("%s/storage/v1/b/%s/o?%s", g.endpoint, url.PathEscape(g.bucket), query.Encode()),
+			nil,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create request: %w", err)
+		}
+
+		resp, err := g.do(req)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list objects with prefix %s: %w", prefix, err)
+		}
+
+		result := struct {
+			Items []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}{}
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode objects with prefix %s: %w", prefix, err)
+		}
+
+		for _, item := range result.Items {
+			keys = append(keys, strings.TrimPrefix(item.Name, g.prefix))
+		}
+
+		if result.NextPageToken == "" {
+			return keys, nil
+		}
+		pageToken = result.NextPageToken
+	}
+}
+
+// accessToken returns the token to authenticate requests with.
+func (g *GCS) accessToken(ctx context.Context) (string, error) {
+	if g.token != nil {
+		return g.token.Value(), nil
+	}
+
+	g.metadataMu.Lock()
+	defer g.metadataMu.Unlock()
+
+	// Refresh the token a minute before it expires, so that it doesn't expire in flight.
+	if g.metadataToken != "" && time.Now().Add(time.Minute).Before(g.metadataExpires) {
+		return g.metadataToken, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataTokenURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to create metadata token request: %w", err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("unable to get access token from metadata server, set GOOGLE_OAUTH_ACCESS_TOKEN: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unable to get access token from metadata server, bad http code %s", resp.Status)
+	}
+
+	token := struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("unable to decode access token from metadata server: %w", err)
+	}
+
+	g.metadataToken = token.AccessToken
+	g.metadataExpires = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+
+	return g.metadataToken, nil
+}
@@ -0,0 +1,158 @@
+package archive
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/isovalent/corgi/pkg/opensearch"
+)
+
+// S3 stores objects in an Amazon S3 bucket through its REST API. Requests are signed
+// with the credentials in AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+type S3 struct {
+	// endpoint is the URL of the bucket, which object keys are appended to.
+	endpoint   string
+	prefix     string
+	signer     *opensearch.AWSSigner
+	httpClient *http.Client
+}
+
+// NewS3 creates an archive in the given bucket, prefixing the keys of all objects with
+// prefix. The region is taken from AWS_REGION or AWS_DEFAULT_REGION. If AWS_ENDPOINT_URL_S3
+// is set, such as for MinIO, the bucket is addressed as a path of that endpoint.
+func NewS3(bucket, prefix string) (*S3, error) {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		return nil, fmt.Errorf("unable to determine region of bucket '%s', set AWS_REGION", bucket)
+	}
+
+	endpoint := fmt.Sprintf("https://%s.s3.%s.amazonaws.com", bucket, region)
+	if e := os.Getenv("AWS_ENDPOINT_URL_S3"); e != "" {
+		endpoint = strings.TrimSuffix(e, "/") + "/" + bucket
+	}
+
+	return &S3{
+		endpoint: endpoint,
+		prefix:   prefix,
+		signer: &opensearch.AWSSigner{
+			Region:       region,
+			Service:      "s3",
+			AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		},
+		httpClient: &http.Client{},
+	}, nil
+}
+
+// do signs and sends the given request, returning its response if it succeeded.
+func (s *S3) do(req *http.Request) (*http.Response, error) {
+	if err := s.signer.SignRequest(req); err != nil {
+		return nil, fmt.Errorf("unable to sign request: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("bad http code %s: %s", resp.Status, msg)
+	}
+
+	return resp, nil
+}
+
+func (s *S3) objectURL(key string) string {
+	return s.endpoint + "/" + (&url.URL{Path: s.prefix + key}).EscapedPath()
+}
+
+// Put implements Archive.
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(key), r)
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+	req.ContentLength = size
+	// Artifacts may be too large to be read into memory to sign them.
+	req.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+
+	resp, err := s.do(req)
+	if err != nil {
+		return fmt.Errorf("unable to put object %s: %w", key, err)
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// Get implements Archive.
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(key), nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create request: %w", err)
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get object %s: %w", key, err)
+	}
+
+	return resp.Body, nil
+}
+
+// List implements Archive.
+func (s *S3) List(ctx context.Context, prefix string) ([]string, error) {
+	keys := []string{}
+	continuationToken := ""
+
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.prefix + prefix}}
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.endpoint+"/?"+query.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create request: %w", err)
+		}
+
+		resp, err := s.do(req)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list objects with prefix %s: %w", prefix, err)
+		}
+
+		result := struct {
+			Contents []struct {
+				Key string `xml:"Key"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}{}
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode objects with prefix %s: %w", prefix, err)
+		}
+
+		for _, c := range result.Contents {
+			keys = append(keys, strings.TrimPrefix(c.Key, s.prefix))
+		}
+
+		if !result.IsTruncated {
+			return keys, nil
+		}
+		continuationToken = result.NextContinuationToken
+	}
+}
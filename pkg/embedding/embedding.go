@@ -0,0 +1,129 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxNormalizedBytes caps the size of normalized texts, as embedding models only
+// look at the beginning of their input anyway.
+const maxNormalizedBytes = 2048
+
+// normalizations replace the parts of failure messages which differ between
+// occurrences of the same failure, such as addresses and durations, so that the
+// same failure is embedded into nearby vectors.
+var normalizations = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`), "<time>"},
+	{regexp.MustCompile(`(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`), "<uuid>"},
+	{regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}(:\d+)?\b`), "<ip>"},
+	{regexp.MustCompile(`(?i)\b0x[0-9a-f]+\b|\b[0-9a-f]{12,}\b`), "<hex>"},
+	{regexp.MustCompile(`\d+`), "<n>"},
+	{regexp.MustCompile(`\s+`), " "},
+}
+
+// Normalize prepares the given failure message for embedding.
+func Normalize(text string) string {
+	for _, n := range normalizations {
+		text = n.pattern.ReplaceAllString(text, n.replacement)
+	}
+
+	text = strings.TrimSpace(text)
+	if len(text) > maxNormalizedBytes {
+		text = strings.ToValidUTF8(text[:maxNormalizedBytes], "")
+	}
+
+	return text
+}
+
+// Embedder computes embeddings of texts.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// HTTPEmbedder computes embeddings through an OpenAI compatible embeddings endpoint,
+// as served by most local model servers.
+type HTTPEmbedder struct {
+	url    string
+	model  string
+	client *http.Client
+}
+
+// NewHTTPEmbedder creates a new HTTPEmbedder sending requests for the given model
+// to the given URL, such as http://localhost:8080/v1/embeddings.
+func NewHTTPEmbedder(url, model string) *HTTPEmbedder {
+	return &HTTPEmbedder{
+		url:    url,
+		model:  model,
+		client: &http.Client{Timeout: time.Minute},
+	}
+}
+
+type embeddingsRequest struct {
+	Model string   `json:"model,omitempty"`
+	Input []string `json:"input"`
+}
+
+type embeddingsResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// Embed returns the embeddings of the given texts, in the same order.
+func (e *HTTPEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(embeddingsRequest{Model: e.model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal embeddings request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create embeddings request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("unable to request embeddings: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read embeddings response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d in embeddings response: %s", resp.StatusCode, respBody)
+	}
+
+	parsed := embeddingsResponse{}
+	if err := json.Unmarshal(respBody, &parsed); err != nil {
+		return nil, fmt.Errorf("unable to parse embeddings response: %w", err)
+	}
+
+	if len(parsed.Data) != len(texts) {
+		return nil, fmt.Errorf("embeddings response contains %d embeddings for %d texts", len(parsed.Data), len(texts))
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for _, d := range parsed.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embeddings response contains unexpected index %d", d.Index)
+		}
+		embeddings[d.Index] = d.Embedding
+	}
+
+	return embeddings, nil
+}
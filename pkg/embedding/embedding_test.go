@@ -0,0 +1,18 @@
+package embedding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t,
+		"Get \"http://<ip>/healthz\": dial tcp: i/o timeout after <n>s",
+		Normalize("Get \"http://10.0.0.12:8080/healthz\": dial tcp:\n\ti/o timeout after 30s"),
+	)
+	assert.Equal(t,
+		"pod <uuid> not ready at <time> (<hex>)",
+		Normalize("pod 123e4567-e89b-12d3-a456-426614174000 not ready at 2024-03-07T13:00:00Z (0xdeadbeef)"),
+	)
+}
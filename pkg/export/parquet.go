@@ -0,0 +1,98 @@
+package export
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetRowGroupRows is the number of rows buffered into each row group before it is
+// written, which bounds the memory used by Parquet exports.
+var parquetRowGroupRows int64 = 10000
+
+// ParquetWriter writes documents as a Snappy compressed Parquet file. Every column is
+// optional, so missing fields are null. Columns are doubles or booleans if all their
+// values are, and strings otherwise. Values which don't match the type of their column,
+// such as of documents which changed since they were added to the schema, are null.
+type ParquetWriter struct {
+	w       *parquet.Writer
+	columns []string
+	types   []parquet.Kind
+	row     parquet.Row
+}
+
+// NewParquetWriter creates a writer of Parquet files with the columns of the given schema.
+func NewParquetWriter(w io.Writer, schema *Schema) *ParquetWriter {
+	p := &ParquetWriter{columns: schema.Columns()}
+
+	group := parquet.Group{}
+	for _, name := range p.columns {
+		c := schema.columns[name]
+
+		var node parquet.Node
+		switch {
+		case c.values > 0 && c.numbers == c.values:
+			node = parquet.Leaf(parquet.DoubleType)
+		case c.values > 0 && c.bools == c.values:
+			node = parquet.Leaf(parquet.BooleanType)
+		default:
+			node = parquet.String()
+		}
+		group[name] = parquet.Optional(node)
+		p.types = append(p.types, node.Type().Kind())
+	}
+
+	// The fields of a group are ordered by name, like the columns.
+	p.w = parquet.NewWriter(
+		w, parquet.NewSchema("corgi", group),
+		parquet.Compression(&parquet.Snappy), parquet.MaxRowsPerRowGroup(parquetRowGroupRows),
+		parquet.CreatedBy("corgi", "", ""),
+	)
+	p.row = make(parquet.Row, len(p.columns))
+
+	return p
+}
+
+func (p *ParquetWriter) Write(source map[string]any) error {
+	row := map[string]any{}
+	flatten("", source, row)
+
+	for i, name := range p.columns {
+		value := row[name]
+		switch p.types[i] {
+		case parquet.ByteArray:
+			if value != nil {
+				value = formatValue(value)
+			}
+		case parquet.Double:
+			if _, ok := value.(float64); !ok {
+				value = nil
+			}
+		case parquet.Boolean:
+			if _, ok := value.(bool); !ok {
+				value = nil
+			}
+		}
+
+		if value == nil {
+			p.row[i] = parquet.NullValue().Level(0, 0, i)
+		} else {
+			p.row[i] = parquet.ValueOf(value).Level(0, 1, i)
+		}
+	}
+
+	if _, err := p.w.WriteRows([]parquet.Row{p.row}); err != nil {
+		return fmt.Errorf("unable to write Parquet row: %w", err)
+	}
+
+	return nil
+}
+
+func (p *ParquetWriter) Close() error {
+	if err := p.w.Close(); err != nil {
+		return fmt.Errorf("unable to write Parquet file: %w", err)
+	}
+
+	return nil
+}
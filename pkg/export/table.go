@@ -0,0 +1,138 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+)
+
+// Writer writes documents in an export format.
+type Writer interface {
+	// Write writes the given document source as a row.
+	Write(source map[string]any) error
+	// Close flushes the rows written so far, completing the export.
+	Close() error
+}
+
+// column counts the values of a column by their JSON type, to pick its type.
+type column struct {
+	values, numbers, bools int
+}
+
+// Schema holds the columns of the flattened documents to export, with a column per
+// field found in any of them. Documents are added to it before they are written, so
+// that every column is known upfront without holding the documents in memory.
+type Schema struct {
+	columns map[string]*column
+}
+
+func NewSchema() *Schema {
+	return &Schema{columns: map[string]*column{}}
+}
+
+// flatten adds the fields of the given document to row, joining the keys of nested
+// objects with dots. Arrays are kept as JSON, as neither format has a simple
+// representation of them.
+func flatten(prefix string, doc map[string]any, row map[string]any) {
+	for key, value := range doc {
+		switch v := value.(type) {
+		case map[string]any:
+			flatten(prefix+key+".", v, row)
+		case []any:
+			data, _ := json.Marshal(v)
+			row[prefix+key] = string(data)
+		default:
+			row[prefix+key] = v
+		}
+	}
+}
+
+// Add adds the fields of the given document source to the schema.
+func (s *Schema) Add(source map[string]any) {
+	row := map[string]any{}
+	flatten("", source, row)
+
+	for name, value := range row {
+		c, ok := s.columns[name]
+		if !ok {
+			c = &column{}
+			s.columns[name] = c
+		}
+
+		switch value.(type) {
+		case nil:
+			continue
+		case float64:
+			c.numbers++
+		case bool:
+			c.bools++
+		}
+		c.values++
+	}
+}
+
+// Columns returns the sorted columns of the schema.
+func (s *Schema) Columns() []string {
+	columns := make([]string, 0, len(s.columns))
+	for name := range s.columns {
+		columns = append(columns, name)
+	}
+	slices.Sort(columns)
+
+	return columns
+}
+
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// CSVWriter writes documents as CSV with a header row. Missing fields are left empty.
+type CSVWriter struct {
+	w       *csv.Writer
+	columns []string
+	record  []string
+}
+
+// NewCSVWriter writes the header row of the given schema.
+func NewCSVWriter(w io.Writer, schema *Schema) (*CSVWriter, error) {
+	c := &CSVWriter{w: csv.NewWriter(w), columns: schema.Columns()}
+	c.record = make([]string, len(c.columns))
+
+	if err := c.w.Write(c.columns); err != nil {
+		return nil, fmt.Errorf("unable to write CSV header: %w", err)
+	}
+
+	return c, nil
+}
+
+func (c *CSVWriter) Write(source map[string]any) error {
+	row := map[string]any{}
+	flatten("", source, row)
+
+	for i, name := range c.columns {
+		c.record[i] = formatValue(row[name])
+	}
+	if err := c.w.Write(c.record); err != nil {
+		return fmt.Errorf("unable to write CSV record: %w", err)
+	}
+
+	return nil
+}
+
+func (c *CSVWriter) Close() error {
+	c.w.Flush()
+
+	return c.w.Error()
+}
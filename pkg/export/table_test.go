@@ -0,0 +1,158 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+)
+
+var testDocs = []map[string]any{
+	{"name": "a", "duration": 1.5, "passed": true, "repository": map[string]any{"full_name": "cilium/cilium"}},
+	{"name": "b,c", "owners": []any{"x", "y"}, "passed": false, "run": 3.0},
+	{"name": "d", "run": "3"},
+}
+
+func newTestSchema() *Schema {
+	s := NewSchema()
+	for _, doc := range testDocs {
+		s.Add(doc)
+	}
+
+	return s
+}
+
+func writeAll(t *testing.T, w Writer, docs []map[string]any) {
+	for _, doc := range docs {
+		assert.NoError(t, w.Write(doc))
+	}
+	assert.NoError(t, w.Close())
+}
+
+// readParquet reads the rows of the given file, with null values as nil.
+func readParquet(t *testing.T, f *parquet.File) []map[string]any {
+	columns := f.Schema().Columns()
+	rows := []map[string]any{}
+
+	reader := parquet.NewReader(f)
+	defer reader.Close()
+
+	buf := make([]parquet.Row, 1)
+	for {
+		n, err := reader.ReadRows(buf)
+		if n == 0 {
+			assert.ErrorIs(t, err, io.EOF)
+			return rows
+		}
+
+		row := map[string]any{}
+		for _, v := range buf[0] {
+			name := columns[v.Column()][0]
+			switch {
+			case v.IsNull():
+				row[name] = nil
+			case v.Kind() == parquet.Double:
+				row[name] = v.Double()
+			case v.Kind() == parquet.Boolean:
+				row[name] = v.Boolean()
+			default:
+				row[name] = string(v.ByteArray())
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w, err := NewCSVWriter(buf, newTestSchema())
+	if !assert.NoError(t, err) {
+		return
+	}
+	writeAll(t, w, testDocs)
+
+	assert.Equal(t,
+		"duration,name,owners,passed,repository.full_name,run\n"+
+			"1.5,a,,true,cilium/cilium,\n"+
+			",\"b,c\",\"[\"\"x\"\",\"\"y\"\"]\",false,,3\n"+
+			",d,,,,3\n",
+		buf.String(),
+	)
+}
+
+func TestWriteParquet(t *testing.T) {
+	buf := &bytes.Buffer{}
+	// The last document doesn't match the types of the schema, nor has all its columns.
+	writeAll(t, NewParquetWriter(buf, newTestSchema()), append(testDocs, map[string]any{"duration": "slow", "new": 1.0}))
+
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	types := map[string]parquet.Kind{}
+	for _, field := range f.Schema().Fields() {
+		assert.True(t, field.Optional(), field.Name())
+		types[field.Name()] = field.Type().Kind()
+	}
+	assert.Equal(t, map[string]parquet.Kind{
+		"duration":             parquet.Double,
+		"name":                 parquet.ByteArray,
+		"owners":               parquet.ByteArray,
+		"passed":               parquet.Boolean,
+		"repository.full_name": parquet.ByteArray,
+		// Columns with values of mixed types are strings.
+		"run": parquet.ByteArray,
+	}, types)
+
+	rows := readParquet(t, f)
+	if assert.Len(t, rows, 4) {
+		assert.Equal(t, 1.5, rows[0]["duration"])
+		assert.Equal(t, "a", rows[0]["name"])
+		assert.Equal(t, true, rows[0]["passed"])
+		assert.Equal(t, "cilium/cilium", rows[0]["repository.full_name"])
+		assert.Nil(t, rows[0]["owners"])
+
+		assert.Equal(t, `["x","y"]`, rows[1]["owners"])
+		assert.Equal(t, false, rows[1]["passed"])
+		assert.Equal(t, "3", rows[1]["run"])
+		assert.Nil(t, rows[1]["duration"])
+
+		assert.Nil(t, rows[2]["passed"])
+
+		assert.Nil(t, rows[3]["duration"])
+		assert.NotContains(t, rows[3], "new")
+	}
+}
+
+func TestWriteParquetRowGroups(t *testing.T) {
+	defer func(rows int64) { parquetRowGroupRows = rows }(parquetRowGroupRows)
+	parquetRowGroupRows = 4
+
+	docs := []map[string]any{}
+	for i := range 10 {
+		docs = append(docs, map[string]any{"name": fmt.Sprint(i), "run": float64(i)})
+	}
+	schema := NewSchema()
+	for _, doc := range docs {
+		schema.Add(doc)
+	}
+
+	buf := &bytes.Buffer{}
+	writeAll(t, NewParquetWriter(buf, schema), docs)
+
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, int64(10), f.NumRows())
+	assert.Len(t, f.RowGroups(), 3)
+
+	rows := readParquet(t, f)
+	if assert.Len(t, rows, 10) {
+		assert.Equal(t, map[string]any{"name": "9", "run": 9.0}, rows[9])
+	}
+}
@@ -0,0 +1,73 @@
+package github
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// Parsers artifacts of workflow runs can be routed to.
+const (
+	// ArtifactParserJUnit parses JUnit files into test suites and testcases.
+	ArtifactParserJUnit = "junit"
+	// ArtifactParserSysdumpMetadata records the number and size of files of sysdumps.
+	ArtifactParserSysdumpMetadata = "sysdump-metadata"
+	// ArtifactParserLogSignatures matches the lines of log files against failure signatures.
+	ArtifactParserLogSignatures = "log-signatures"
+	// ArtifactParserPluginPrefix is followed by the name of a plugin, which converts the
+	// files of artifacts into JUnit files.
+	ArtifactParserPluginPrefix = "wasm:"
+)
+
+// ArtifactParsers are the parsers artifacts can be routed to.
+var ArtifactParsers = []string{ArtifactParserJUnit, ArtifactParserSysdumpMetadata, ArtifactParserLogSignatures}
+
+// DefaultArtifactRoutes route the artifacts uploaded by Cilium's workflows.
+var DefaultArtifactRoutes = []string{
+	JUnitArtifactName + "=" + ArtifactParserJUnit,
+	"cilium-sysdumps=" + ArtifactParserSysdumpMetadata,
+	"*-logs=" + ArtifactParserLogSignatures,
+}
+
+// ArtifactRoute routes artifacts with a name matching Pattern, in path.Match syntax,
+// to Parser.
+type ArtifactRoute struct {
+	Pattern string
+	Parser  string
+}
+
+// ParseArtifactRoute parses an artifact route in the form of '<pattern>=<parser>'.
+func ParseArtifactRoute(s string) (ArtifactRoute, error) {
+	pattern, parser, ok := strings.Cut(s, "=")
+	if !ok || pattern == "" {
+		return ArtifactRoute{}, fmt.Errorf("expected '<pattern>=<parser>', got '%s'", s)
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return ArtifactRoute{}, fmt.Errorf("invalid pattern of artifact route '%s': %w", s, err)
+	}
+
+	if name, ok := strings.CutPrefix(parser, ArtifactParserPluginPrefix); ok && name == "" {
+		return ArtifactRoute{}, fmt.Errorf("missing plugin name in parser of artifact route '%s'", s)
+	} else if !ok && !slices.Contains(ArtifactParsers, parser) {
+		return ArtifactRoute{}, fmt.Errorf(
+			"unknown parser of artifact route '%s', expected one of %s or %s<plugin>",
+			s, strings.Join(ArtifactParsers, ", "), ArtifactParserPluginPrefix,
+		)
+	}
+
+	return ArtifactRoute{Pattern: pattern, Parser: parser}, nil
+}
+
+// RouteArtifact returns the parser of the first of the given routes matching the name
+// of an artifact, or an empty string if none matches.
+func RouteArtifact(routes []ArtifactRoute, name string) string {
+	for _, r := range routes {
+		if ok, _ := path.Match(r.Pattern, name); ok {
+			return r.Parser
+		}
+	}
+
+	return ""
+}
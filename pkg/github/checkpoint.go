@@ -0,0 +1,112 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/isovalent/corgi/pkg/types"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+// Checkpoint records which workflow run attempts were processed, so that a restarted
+// ingestion neither pulls them again nor has to ask OpenSearch about each of them.
+// It is safe for concurrent use.
+type Checkpoint struct {
+	mu sync.Mutex
+	// runs maps the key of each processed run attempt to when the run was created.
+	runs map[string]time.Time
+}
+
+// checkpointFile is the on-disk format of a Checkpoint.
+type checkpointFile struct {
+	Runs    map[string]time.Time `json:"runs"`
+	SavedAt time.Time            `json:"saved_at"`
+}
+
+func checkpointKey(run *types.WorkflowRun) string {
+	return fmt.Sprintf("%d-%d", run.ID, run.RunAttempt)
+}
+
+// NewCheckpoint creates an empty Checkpoint.
+func NewCheckpoint() *Checkpoint {
+	return &Checkpoint{runs: map[string]time.Time{}}
+}
+
+// LoadCheckpoint reads the Checkpoint at the given path. A missing file results in
+// an empty Checkpoint, as on the first start.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewCheckpoint(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read checkpoint: %w", err)
+	}
+
+	f := checkpointFile{}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("unable to parse checkpoint %s: %w", path, err)
+	}
+
+	c := NewCheckpoint()
+	for key, created := range f.Runs {
+		c.runs[key] = created
+	}
+
+	return c, nil
+}
+
+// Has returns true if the given run attempt was processed.
+func (c *Checkpoint) Has(run *types.WorkflowRun) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.runs[checkpointKey(run)]
+	return ok
+}
+
+// Add records the given run attempt as processed.
+func (c *Checkpoint) Add(run *types.WorkflowRun) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.runs[checkpointKey(run)] = run.CreatedAt
+}
+
+// Len returns the number of processed run attempts.
+func (c *Checkpoint) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.runs)
+}
+
+// Save writes the Checkpoint to the given path, leaving out runs created before the
+// given time, which won't be pulled again anyway. The file is replaced atomically,
+// so that it is never left half-written.
+func (c *Checkpoint) Save(path string, before time.Time) error {
+	c.mu.Lock()
+	f := checkpointFile{Runs: make(map[string]time.Time, len(c.runs)), SavedAt: time.Now().UTC()}
+	for key, created := range c.runs {
+		if !created.Before(before) {
+			f.Runs[key] = created
+		}
+	}
+	c.mu.Unlock()
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return fmt.Errorf("unable to encode checkpoint: %w", err)
+	}
+
+	if err := util.WriteFileAtomic(path, data); err != nil {
+		return fmt.Errorf("unable to save checkpoint file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+)
+
+// maxCheckRunSummaryBytes is the maximum size of the summary of a check run
+// accepted by GitHub.
+const maxCheckRunSummaryBytes = 65535
+
+// CreateCheckRun publishes a completed check run with the given name, conclusion and
+// output on the given commit. Summaries exceeding the size GitHub accepts are truncated.
+// Note that check runs can only be created using the token of a GitHub App.
+func CreateCheckRun(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	repoOwner, repoName, headSHA string,
+	name, conclusion, title, summary string,
+) error {
+	l := logger.With("repo", repoOwner+"/"+repoName, "head-sha", headSHA, "check", name)
+
+	if len(summary) > maxCheckRunSummaryBytes {
+		summary = strings.ToValidUTF8(summary[:maxCheckRunSummaryBytes], "")
+	}
+
+	opts := github.CreateCheckRunOptions{
+		Name:        name,
+		HeadSHA:     headSHA,
+		Status:      github.String("completed"),
+		Conclusion:  &conclusion,
+		CompletedAt: &github.Timestamp{Time: time.Now()},
+		Output: &github.CheckRunOutput{
+			Title:   &title,
+			Summary: &summary,
+		},
+	}
+
+	l.Info("Creating check run", "conclusion", conclusion)
+	_, _, err := WrapWithRateLimitRetry[github.CheckRun](
+		ctx, l,
+		func() (*github.CheckRun, *github.Response, error) {
+			return client.Checks.CreateCheckRun(ctx, repoOwner, repoName, opts)
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("unable to create check run %q on %s: %w", name, headSHA, err)
+	}
+
+	return nil
+}
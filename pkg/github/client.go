@@ -6,17 +6,40 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
-	"os"
 	"strconv"
 	"time"
 
 	ratelimit "github.com/gofri/go-github-ratelimit/github_ratelimit"
 	"github.com/google/go-github/v60/github"
 	"github.com/hashicorp/go-retryablehttp"
+
+	"github.com/isovalent/corgi/pkg/metrics"
+	"github.com/isovalent/corgi/pkg/util"
 )
 
-func GetGitHubAuthToken() string {
-	return os.Getenv("GITHUB_TOKEN")
+// GetGitHubAuthToken loads the GitHub token from GITHUB_TOKEN, or from the file
+// named by GITHUB_TOKEN_FILE.
+func GetGitHubAuthToken() (*util.Secret, error) {
+	return util.NewSecret("GITHUB_TOKEN")
+}
+
+// authTransport authenticates requests with the current value of the token, so
+// that a reloaded token is used without recreating the client.
+type authTransport struct {
+	base  http.RoundTripper
+	token *util.Secret
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token := t.token.Value()
+	if token == "" {
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	return t.base.RoundTrip(req)
 }
 
 func WrapWithRateLimitRetry[T any](
@@ -71,7 +94,35 @@ func WrapWithRateLimitRetry[T any](
 	}
 }
 
-func NewGitHubClient(authToken string, logger *slog.Logger) (*github.Client, error) {
+// metricsTransport counts requests made to the GitHub API, and records the state of
+// the rate limit given in their responses.
+type metricsTransport struct {
+	base http.RoundTripper
+}
+
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	metrics.Add(metrics.CounterGitHubRequests, 1)
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	limit, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Limit"), 10, 64)
+	if err != nil {
+		return resp, nil
+	}
+	remaining, _ := strconv.ParseInt(resp.Header.Get("X-RateLimit-Remaining"), 10, 64)
+	used, _ := strconv.ParseInt(resp.Header.Get("X-RateLimit-Used"), 10, 64)
+	metrics.SetGitHubRate(metrics.GitHubRate{Limit: limit, Remaining: remaining, Used: used})
+
+	return resp, nil
+}
+
+// NewGitHubClient creates a GitHub client authenticating with the given token. Each attempt
+// of a request is bounded by requestTimeout, zero meaning no timeout, while retries and
+// waiting for rate limits to reset aren't.
+func NewGitHubClient(authToken *util.Secret, logger *slog.Logger, requestTimeout time.Duration) (*github.Client, error) {
 	// GitHub resets API requests on the top of every hour, so
 	// use a two hour delay to handle waiting for the reset.
 	// Two hours acts as an extra buffer.
@@ -84,6 +135,7 @@ func NewGitHubClient(authToken string, logger *slog.Logger) (*github.Client, err
 	retryClient.RetryMax = 30
 	retryClient.RetryWaitMax = 2 * time.Hour
 	retryClient.Logger = logger.With("subsys", "github-http-client")
+	retryClient.HTTPClient.Timeout = requestTimeout
 
 	// This fixes the DownloadArtifact function for the GitHub client.
 	// The retryClient will automatically follow redirects, which the GitHub client
@@ -113,7 +165,9 @@ func NewGitHubClient(authToken string, logger *slog.Logger) (*github.Client, err
 		return nil, fmt.Errorf("unable to create github rate limiter: %w", err)
 	}
 
-	client := github.NewClient(rateLimiter).WithAuthToken(authToken)
+	client := github.NewClient(&http.Client{
+		Transport: &metricsTransport{base: &authTransport{base: rateLimiter.Transport, token: authToken}},
+	})
 
 	return client, nil
 }
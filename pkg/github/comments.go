@@ -0,0 +1,81 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/google/go-github/v60/github"
+)
+
+// UpsertIssueComment posts the given body as a comment on the given issue or pull request.
+// If a comment containing the given marker already exists, it is updated instead, so that
+// repeated calls don't clutter the conversation.
+func UpsertIssueComment(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	repoOwner, repoName string,
+	number int,
+	marker, body string,
+) error {
+	l := logger.With("repo", repoOwner+"/"+repoName, "number", number)
+
+	var existing *github.IssueComment
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: PER_PAGE}}
+	for existing == nil {
+		comments, resp, err := WrapWithRateLimitRetry[[]*github.IssueComment](
+			ctx, l,
+			func() (*[]*github.IssueComment, *github.Response, error) {
+				c, resp, err := client.Issues.ListComments(ctx, repoOwner, repoName, number, opts)
+				return &c, resp, err
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("unable to list comments of #%d: %w", number, err)
+		}
+
+		for _, c := range *comments {
+			if strings.Contains(c.GetBody(), marker) {
+				existing = c
+				break
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	comment := &github.IssueComment{Body: &body}
+
+	if existing != nil {
+		l.Info("Updating comment", "comment-id", existing.GetID())
+		_, _, err := WrapWithRateLimitRetry[github.IssueComment](
+			ctx, l,
+			func() (*github.IssueComment, *github.Response, error) {
+				return client.Issues.EditComment(ctx, repoOwner, repoName, existing.GetID(), comment)
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("unable to update comment %d of #%d: %w", existing.GetID(), number, err)
+		}
+
+		return nil
+	}
+
+	l.Info("Creating comment")
+	_, _, err := WrapWithRateLimitRetry[github.IssueComment](
+		ctx, l,
+		func() (*github.IssueComment, *github.Response, error) {
+			return client.Issues.CreateComment(ctx, repoOwner, repoName, number, comment)
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("unable to create comment on #%d: %w", number, err)
+	}
+
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/google/go-github/v60/github"
 	"github.com/isovalent/corgi/pkg/types"
@@ -51,3 +53,198 @@ func GetCommitBySHA(
 
 	return commit, nil
 }
+
+// maxPushBasePages is the number of pages of earlier push runs searched for the previous
+// run of a workflow, before giving up on finding the commits of a push.
+const maxPushBasePages = 5
+
+const (
+	// maxPushEventPages is the number of pages of repository events searched for the push
+	// which triggered a run. The events API returns at most 300 events.
+	maxPushEventPages = 3
+	// pushEventLookback is how long before a run was created the push which triggered it
+	// is searched for.
+	pushEventLookback = time.Hour
+	// maxPushCommits is the maximum number of commits returned for a push.
+	maxPushCommits = 250
+)
+
+// GetPushCommits returns the commits pushed to the branch of the given push run, along
+// with the base they were compared against. The base is the commit the branch pointed to
+// before the push, according to the payload of the push event. Since only recent events
+// are available, the head of the previous push run of the same workflow on that branch
+// is used otherwise, which covers the commits of all pushes which weren't tested on
+// their own, such as when a run was skipped or cancelled in between. If neither is
+// found, no commits are returned. At most maxPushCommits commits are returned.
+func GetPushCommits(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	run *types.WorkflowRun,
+) (string, []types.Commit, error) {
+	l := logger.With("workflow-id", run.ID, "head-sha", run.HeadSHA)
+
+	l.Debug("Pulling pushed commits for workflow run")
+
+	owner, repo := run.Repository.Owner.Login, run.Repository.Name
+
+	base, err := getPushBefore(ctx, l, client, run)
+	if err != nil {
+		return "", nil, err
+	}
+	if base == "" {
+		base, err = getPreviousPushRunHead(ctx, l, client, run)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	if base == "" {
+		l.Debug("No push event or previous push run found for workflow run")
+		return "", nil, nil
+	}
+
+	opts := &github.ListOptions{PerPage: PER_PAGE}
+	commits := []types.Commit{}
+	for len(commits) < maxPushCommits {
+		comparison, resp, err := WrapWithRateLimitRetry[github.CommitsComparison](
+			ctx, l,
+			func() (*github.CommitsComparison, *github.Response, error) {
+				return client.Repositories.CompareCommits(ctx, owner, repo, base, run.HeadSHA, opts)
+			},
+		)
+		if err != nil {
+			return "", nil, fmt.Errorf("unable to compare %s...%s: %w", base, run.HeadSHA, err)
+		}
+
+		for _, c := range comparison.Commits {
+			commits = append(commits, types.Commit{
+				SHA:     c.GetSHA(),
+				Message: c.GetCommit().GetMessage(),
+				Author: types.User{
+					Login: c.GetAuthor().GetLogin(),
+					Name:  c.GetCommit().GetAuthor().GetName(),
+					Email: c.GetCommit().GetAuthor().GetEmail(),
+				},
+				URL: c.GetHTMLURL(),
+			})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	if len(commits) > maxPushCommits {
+		commits = commits[:maxPushCommits]
+	}
+
+	l.Debug("Got pushed commits for workflow run", "base-sha", base, "commits", len(commits))
+
+	return base, commits, nil
+}
+
+// getPushBefore returns the commit the branch of the given push run pointed to before the
+// push which triggered it, or an empty string if the push event isn't available anymore or
+// created the branch.
+func getPushBefore(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	run *types.WorkflowRun,
+) (string, error) {
+	owner, repo := run.Repository.Owner.Login, run.Repository.Name
+	ref := "refs/heads/" + run.HeadBranch
+	oldest := run.CreatedAt.Add(-pushEventLookback)
+
+	opts := &github.ListOptions{PerPage: PER_PAGE}
+	for page := 0; page < maxPushEventPages; page++ {
+		events, resp, err := WrapWithRateLimitRetry[[]*github.Event](
+			ctx, logger,
+			func() (*[]*github.Event, *github.Response, error) {
+				events, resp, err := client.Activity.ListRepositoryEvents(ctx, owner, repo, opts)
+				return &events, resp, err
+			},
+		)
+		if err != nil {
+			return "", fmt.Errorf("unable to list events of repository %s/%s: %w", owner, repo, err)
+		}
+
+		// Events are listed most recent first.
+		for _, e := range *events {
+			if e.GetCreatedAt().Before(oldest) {
+				return "", nil
+			}
+			if e.GetType() != "PushEvent" {
+				continue
+			}
+
+			payload, err := e.ParsePayload()
+			if err != nil {
+				logger.Debug("Unable to parse payload of push event", "event-id", e.GetID(), "err", err)
+				continue
+			}
+
+			push, ok := payload.(*github.PushEvent)
+			if !ok || push.GetHead() != run.HeadSHA || push.GetRef() != ref {
+				continue
+			}
+
+			// Pushes creating the branch have no previous commit.
+			if strings.Trim(push.GetBefore(), "0") == "" {
+				return "", nil
+			}
+
+			return push.GetBefore(), nil
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return "", nil
+}
+
+// getPreviousPushRunHead returns the head of the previous push run of the workflow of the
+// given run on its branch, or an empty string if none is found.
+func getPreviousPushRunHead(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	run *types.WorkflowRun,
+) (string, error) {
+	owner, repo := run.Repository.Owner.Login, run.Repository.Name
+
+	opts := &github.ListWorkflowRunsOptions{
+		Branch:      run.HeadBranch,
+		Event:       "push",
+		Created:     "<" + run.CreatedAt.UTC().Format(time.RFC3339),
+		ListOptions: github.ListOptions{PerPage: PER_PAGE},
+	}
+
+	for page := 0; page < maxPushBasePages; page++ {
+		runs, resp, err := WrapWithRateLimitRetry[github.WorkflowRuns](
+			ctx, logger,
+			func() (*github.WorkflowRuns, *github.Response, error) {
+				return client.Actions.ListRepositoryWorkflowRuns(ctx, owner, repo, opts)
+			},
+		)
+		if err != nil {
+			return "", fmt.Errorf("unable to list push runs before workflow run %d: %w", run.ID, err)
+		}
+
+		for _, r := range runs.WorkflowRuns {
+			if r.GetName() == run.Name && r.GetID() != run.ID && r.GetHeadSHA() != run.HeadSHA {
+				return r.GetHeadSHA(), nil
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return "", nil
+}
@@ -0,0 +1,101 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// fakeCompare serves the given number of commits between any two commits in pages, along
+// with the given repository events and workflow runs.
+type fakeCompare struct {
+	commits int
+	events  []map[string]any
+	runs    []map[string]any
+	base    string
+}
+
+func (f *fakeCompare) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/repos/cilium/cilium/events":
+		_ = json.NewEncoder(w).Encode(f.events)
+	case r.URL.Path == "/repos/cilium/cilium/actions/runs":
+		_ = json.NewEncoder(w).Encode(map[string]any{"total_count": len(f.runs), "workflow_runs": f.runs})
+	default:
+		f.base = r.URL.Path
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		page = max(1, page)
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+
+		start := (page - 1) * perPage
+		end := min(start+perPage, f.commits)
+		if end < f.commits {
+			w.Header().Set("Link", fmt.Sprintf(`<http://%s%s?page=%d&per_page=%d>; rel="next"`, r.Host, r.URL.Path, page+1, perPage))
+		}
+
+		commits := []map[string]any{}
+		for i := start; i < end; i++ {
+			commits = append(commits, map[string]any{"sha": strconv.Itoa(i)})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"total_commits": f.commits, "commits": commits})
+	}
+}
+
+func TestGetPushCommits(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	run := &types.WorkflowRun{
+		ID: 2, Name: "CI", HeadSHA: "head", HeadBranch: "main", CreatedAt: created,
+		Repository: types.Repository{Owner: types.User{Login: "cilium"}, Name: "cilium"},
+	}
+
+	fake := &fakeCompare{
+		commits: 300,
+		events: []map[string]any{
+			{"id": "2", "type": "PushEvent", "created_at": created, "payload": map[string]any{
+				"ref": "refs/heads/main", "head": "other", "before": "wrong",
+			}},
+			{"id": "1", "type": "PushEvent", "created_at": created, "payload": map[string]any{
+				"ref": "refs/heads/main", "head": "head", "before": "before",
+			}},
+		},
+		runs: []map[string]any{{"id": 1, "name": "CI", "head_sha": "previous"}},
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	// The push event tells the base, and commits are paged up to the limit.
+	base, commits, err := GetPushCommits(ctx, logger, client, run)
+	assert.NoError(t, err)
+	assert.Equal(t, "before", base)
+	assert.Equal(t, "/repos/cilium/cilium/compare/before...head", fake.base)
+	assert.Len(t, commits, maxPushCommits)
+	assert.Equal(t, "249", commits[249].SHA)
+
+	// Without the push event, the head of the previous run is the base.
+	fake.events = nil
+	fake.commits = 3
+	base, commits, err = GetPushCommits(ctx, logger, client, run)
+	assert.NoError(t, err)
+	assert.Equal(t, "previous", base)
+	assert.Len(t, commits, 3)
+}
@@ -0,0 +1,63 @@
+package github
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// RunConclusions are the conclusions GitHub reports for completed workflow runs.
+var RunConclusions = []string{
+	"success", "failure", "cancelled", "timed_out", "skipped", "neutral", "action_required", "stale",
+	"startup_failure",
+}
+
+// RunConclusionRule limits the runs of workflows whose name matches Pattern, in
+// path.Match syntax, to the ones with one of Conclusions.
+type RunConclusionRule struct {
+	Pattern     string
+	Conclusions []string
+}
+
+// ParseRunConclusionRule parses a run conclusion rule in the form of
+// '<workflow pattern>=<conclusion>[,<conclusion>...]'.
+func ParseRunConclusionRule(s string) (RunConclusionRule, error) {
+	pattern, conclusions, ok := strings.Cut(s, "=")
+	if !ok || pattern == "" || conclusions == "" {
+		return RunConclusionRule{}, fmt.Errorf("expected '<workflow pattern>=<conclusion>[,<conclusion>...]', got '%s'", s)
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return RunConclusionRule{}, fmt.Errorf("invalid pattern of run conclusion rule '%s': %w", s, err)
+	}
+
+	rule := RunConclusionRule{Pattern: pattern}
+	for _, c := range strings.Split(conclusions, ",") {
+		c = strings.TrimSpace(c)
+		if !slices.Contains(RunConclusions, c) {
+			return RunConclusionRule{}, fmt.Errorf(
+				"unknown conclusion '%s' of run conclusion rule '%s', expected one of %s",
+				c, s, strings.Join(RunConclusions, ", "),
+			)
+		}
+		rule.Conclusions = append(rule.Conclusions, c)
+	}
+
+	return rule, nil
+}
+
+// RunConclusionAllowed returns whether the conclusion of the given run is one of the
+// conclusions of the first of the given rules matching the name of its workflow. Runs of
+// workflows matching no rule are allowed.
+func RunConclusionAllowed(rules []RunConclusionRule, run *types.WorkflowRun) bool {
+	for _, r := range rules {
+		if ok, _ := path.Match(r.Pattern, run.Name); ok {
+			return slices.Contains(r.Conclusions, run.Conclusion)
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,122 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/go-github/v60/github"
+)
+
+// CommitFile creates or updates the file at path on the given branch with the given content.
+func CommitFile(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	repoOwner, repoName, branch, path, message string,
+	content []byte,
+) error {
+	l := logger.With("repo", repoOwner+"/"+repoName, "branch", branch, "path", path)
+
+	opts := &github.RepositoryContentFileOptions{
+		Message: &message,
+		Content: content,
+		Branch:  &branch,
+	}
+
+	existing, _, resp, err := client.Repositories.GetContents(
+		ctx, repoOwner, repoName, path, &github.RepositoryContentGetOptions{Ref: branch},
+	)
+	switch {
+	case err == nil && existing != nil:
+		opts.SHA = existing.SHA
+	case err == nil:
+		return fmt.Errorf("%s is a directory", path)
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+	default:
+		return fmt.Errorf("unable to get %s: %w", path, err)
+	}
+
+	l.Info("Committing file")
+	_, _, err = WrapWithRateLimitRetry[github.RepositoryContentResponse](
+		ctx, l,
+		func() (*github.RepositoryContentResponse, *github.Response, error) {
+			if opts.SHA != nil {
+				return client.Repositories.UpdateFile(ctx, repoOwner, repoName, path, opts)
+			}
+			return client.Repositories.CreateFile(ctx, repoOwner, repoName, path, opts)
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("unable to commit %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// EnsureBranch creates the given branch from the head of base, unless it already exists.
+func EnsureBranch(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	repoOwner, repoName, base, branch string,
+) error {
+	_, resp, err := client.Git.GetRef(ctx, repoOwner, repoName, "heads/"+branch)
+	if err == nil {
+		return nil
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("unable to get branch %s: %w", branch, err)
+	}
+
+	baseRef, _, err := client.Git.GetRef(ctx, repoOwner, repoName, "heads/"+base)
+	if err != nil {
+		return fmt.Errorf("unable to get branch %s: %w", base, err)
+	}
+
+	logger.Info("Creating branch", "repo", repoOwner+"/"+repoName, "branch", branch, "base", base)
+	_, _, err = client.Git.CreateRef(ctx, repoOwner, repoName, &github.Reference{
+		Ref:    github.String("refs/heads/" + branch),
+		Object: baseRef.Object,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to create branch %s: %w", branch, err)
+	}
+
+	return nil
+}
+
+// EnsurePullRequest opens a pull request from head into base, unless one is already open,
+// returning its URL.
+func EnsurePullRequest(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	repoOwner, repoName, base, head, title, body string,
+) (string, error) {
+	open, _, err := client.PullRequests.List(ctx, repoOwner, repoName, &github.PullRequestListOptions{
+		State: "open",
+		Head:  repoOwner + ":" + head,
+		Base:  base,
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to list pull requests for %s: %w", head, err)
+	}
+	if len(open) > 0 {
+		return open[0].GetHTMLURL(), nil
+	}
+
+	logger.Info("Opening pull request", "repo", repoOwner+"/"+repoName, "head", head, "base", base)
+	pr, _, err := client.PullRequests.Create(ctx, repoOwner, repoName, &github.NewPullRequest{
+		Title: &title,
+		Head:  &head,
+		Base:  &base,
+		Body:  &body,
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to open pull request for %s: %w", head, err)
+	}
+
+	return pr.GetHTMLURL(), nil
+}
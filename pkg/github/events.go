@@ -0,0 +1,260 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+const (
+	// labelEventWindow is how long before a pull request run was created a label may have
+	// been added for the run to be considered triggered by it.
+	labelEventWindow = time.Minute
+	// cronLookback is how far before a scheduled run was created its schedule is searched
+	// for, since GitHub delays scheduled runs under load.
+	cronLookback = 6 * time.Hour
+)
+
+// cronRegex matches the cron expressions of the schedule triggers of a workflow file.
+var cronRegex = regexp.MustCompile(`(?m)^\s*-?\s*cron:\s*['"]?([^'"#\n]+?)['"]?\s*(?:#.*)?$`)
+
+// AddEventDetails completes the event summary of the given run with the details which
+// need further requests: the cron expression which triggered a scheduled run, and the
+// label which triggered a pull request run. GitHub doesn't expose the payload of the
+// triggering event, so the label is the one added to the pull request by the triggering
+// actor shortly before the run was created, which may not have triggered the run if
+// another event happened at the same time.
+func AddEventDetails(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	run *types.WorkflowRun,
+) error {
+	if run.EventSummary == nil {
+		return nil
+	}
+
+	l := logger.With("workflow-id", run.ID, "event", run.Event)
+
+	switch run.Event {
+	case "schedule":
+		schedule, err := getScheduleForRun(ctx, l, client, run)
+		if err != nil {
+			return err
+		}
+		run.EventSummary.Schedule = schedule
+	case "pull_request", "pull_request_target":
+		label, err := getLabelForRun(ctx, l, client, run)
+		if err != nil {
+			return err
+		}
+		run.EventSummary.Label = label
+	}
+
+	return nil
+}
+
+// getScheduleForRun returns the cron expression of the workflow of the given run, as of
+// its head commit, which most recently fired before the run was created.
+func getScheduleForRun(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	run *types.WorkflowRun,
+) (string, error) {
+	owner, repo := run.Repository.Owner.Login, run.Repository.Name
+
+	logger.Debug("Pulling workflow file for scheduled workflow run")
+
+	workflow, _, err := WrapWithRateLimitRetry[github.Workflow](
+		ctx, logger,
+		func() (*github.Workflow, *github.Response, error) {
+			return client.Actions.GetWorkflowByID(ctx, owner, repo, run.ParentWorkflowID)
+		},
+	)
+	if err != nil {
+		return "", fmt.Errorf("unable to get workflow %d: %w", run.ParentWorkflowID, err)
+	}
+
+	file, _, _, err := client.Repositories.GetContents(
+		ctx, owner, repo, workflow.GetPath(), &github.RepositoryContentGetOptions{Ref: run.HeadSHA},
+	)
+	if err != nil {
+		return "", fmt.Errorf("unable to get workflow file %s at %s: %w", workflow.GetPath(), run.HeadSHA, err)
+	}
+	if file == nil {
+		return "", fmt.Errorf("workflow file %s is a directory", workflow.GetPath())
+	}
+
+	content, err := file.GetContent()
+	if err != nil {
+		return "", fmt.Errorf("unable to decode workflow file %s: %w", workflow.GetPath(), err)
+	}
+
+	crons := []string{}
+	for _, m := range cronRegex.FindAllStringSubmatch(content, -1) {
+		crons = append(crons, strings.TrimSpace(m[1]))
+	}
+
+	return matchSchedule(crons, run.CreatedAt), nil
+}
+
+// matchSchedule returns the cron expression which most recently fired at or before t,
+// within cronLookback. If there is only one, it is returned as is.
+func matchSchedule(crons []string, t time.Time) string {
+	if len(crons) == 1 {
+		return crons[0]
+	}
+
+	start := t.UTC().Truncate(time.Minute)
+	for at := start; !at.Before(start.Add(-cronLookback)); at = at.Add(-time.Minute) {
+		for _, c := range crons {
+			if cronMatches(c, at) {
+				return c
+			}
+		}
+	}
+
+	return ""
+}
+
+// cronMatches returns whether the given five-field cron expression fires at t. Expressions
+// which can't be parsed never fire.
+func cronMatches(expr string, t time.Time) bool {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return false
+	}
+
+	minute, ok1 := cronFieldMatches(fields[0], t.Minute(), 0, 59)
+	hour, ok2 := cronFieldMatches(fields[1], t.Hour(), 0, 23)
+	dom, ok3 := cronFieldMatches(fields[2], t.Day(), 1, 31)
+	month, ok4 := cronFieldMatches(fields[3], int(t.Month()), 1, 12)
+	// Sunday is both 0 and 7.
+	dow, ok5 := cronFieldMatches(fields[4], int(t.Weekday()), 0, 7)
+	if t.Weekday() == time.Sunday {
+		sunday, _ := cronFieldMatches(fields[4], 7, 0, 7)
+		dow = dow || sunday
+	}
+	if !ok1 || !ok2 || !ok3 || !ok4 || !ok5 {
+		return false
+	}
+
+	day := dom && dow
+	// If both the day of month and the day of week are restricted, either may match.
+	if fields[2] != "*" && fields[4] != "*" {
+		day = dom || dow
+	}
+
+	return minute && hour && month && day
+}
+
+// cronFieldMatches returns whether the given cron field, consisting of values, ranges and
+// steps separated by commas, includes v, and whether it could be parsed.
+func cronFieldMatches(field string, v, first, last int) (bool, bool) {
+	matches := false
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			s, err := strconv.Atoi(stepStr)
+			if err != nil || s <= 0 {
+				return false, false
+			}
+			step = s
+		}
+
+		lo, hi := first, last
+		if rng != "*" {
+			loStr, hiStr, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(loStr); err != nil {
+				return false, false
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(hiStr); err != nil {
+					return false, false
+				}
+			} else if hasStep {
+				hi = last
+			}
+		}
+
+		if v >= lo && v <= hi && (v-lo)%step == 0 {
+			matches = true
+		}
+	}
+
+	return matches, true
+}
+
+// getLabelForRun returns the label added by the triggering actor of the given run to its
+// pull request within labelEventWindow before the run was created, if any.
+func getLabelForRun(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	run *types.WorkflowRun,
+) (string, error) {
+	owner, repo := run.Repository.Owner.Login, run.Repository.Name
+
+	number := 0
+	if run.PullRequest != nil {
+		number = run.PullRequest.Number
+	} else {
+		pull, err := findPullRequestForRun(ctx, logger, client, run)
+		if err != nil {
+			return "", err
+		}
+		number = pull.GetNumber()
+	}
+	if number == 0 {
+		logger.Debug("No pull request found for workflow run")
+		return "", nil
+	}
+
+	logger.Debug("Pulling label events for pull request of workflow run", "pull-request", number)
+
+	label := ""
+	var labeledAt time.Time
+	opts := &github.ListOptions{PerPage: PER_PAGE}
+	for {
+		events, resp, err := WrapWithRateLimitRetry[[]*github.IssueEvent](
+			ctx, logger,
+			func() (*[]*github.IssueEvent, *github.Response, error) {
+				e, resp, err := client.Issues.ListIssueEvents(ctx, owner, repo, number, opts)
+				return &e, resp, err
+			},
+		)
+		if err != nil {
+			return "", fmt.Errorf("unable to list events for pull request %d: %w", number, err)
+		}
+
+		for _, e := range *events {
+			at := e.GetCreatedAt().Time
+			if e.GetEvent() != "labeled" || e.GetActor().GetLogin() != run.TriggeringActor.Login ||
+				at.After(run.CreatedAt) || at.Before(run.CreatedAt.Add(-labelEventWindow)) {
+				continue
+			}
+			if !at.Before(labeledAt) {
+				label, labeledAt = e.GetLabel().GetName(), at
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return label, nil
+}
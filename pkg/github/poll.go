@@ -0,0 +1,114 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// RunPoller polls the most recently completed workflow runs of a repository.
+// Conditional requests are used, so that polls which find nothing new don't count
+// towards GitHub's rate limit.
+//
+// GitHub's repository events API would be the natural feed for this, but it doesn't
+// include workflow_run events, therefore the workflow runs listing is polled instead.
+type RunPoller struct {
+	client    *github.Client
+	repoOwner string
+	repoName  string
+	branch    string
+	etag      string
+	// cursor is the creation time of the most recent run returned by the previous poll.
+	cursor time.Time
+}
+
+func NewRunPoller(client *github.Client, repoOwner, repoName, branch string) *RunPoller {
+	return &RunPoller{
+		client:    client,
+		repoOwner: repoOwner,
+		repoName:  repoName,
+		branch:    branch,
+	}
+}
+
+// Poll returns the most recently completed workflow runs, or nil if nothing changed
+// since the previous poll. Pages are fetched until one reaches a run created at or
+// before the most recent run of the previous poll, so that runs aren't missed when more
+// than a page of them completed in between. The first poll only fetches the first page.
+func (p *RunPoller) Poll(ctx context.Context, logger *slog.Logger) ([]*types.WorkflowRun, error) {
+	result := []*types.WorkflowRun{}
+	etag := ""
+
+	for page := 1; page != 0; {
+		runs, resp, err := p.pollPage(ctx, page)
+		if resp != nil && resp.StatusCode == http.StatusNotModified {
+			logger.Debug("No changes to workflow runs since last poll")
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to poll workflow runs for repo %s/%s: %w", p.repoOwner, p.repoName, err)
+		}
+
+		if page == 1 {
+			etag = resp.Header.Get("ETag")
+		}
+
+		reached := p.cursor.IsZero()
+		for _, runRaw := range runs.WorkflowRuns {
+			run := types.NewWorkflowRunFromRaw(runRaw)
+			if !run.CreatedAt.After(p.cursor) {
+				reached = true
+			}
+			result = append(result, run)
+		}
+		if reached {
+			break
+		}
+
+		page = resp.NextPage
+	}
+
+	p.etag = etag
+	if len(result) > 0 {
+		p.cursor = result[0].CreatedAt
+	}
+
+	return result, nil
+}
+
+// pollPage fetches the given page of completed workflow runs. Only the first page is
+// requested conditionally, since later pages shift whenever a run completes.
+func (p *RunPoller) pollPage(ctx context.Context, page int) (*github.WorkflowRuns, *github.Response, error) {
+	query := url.Values{}
+	query.Set("status", "completed")
+	query.Set("per_page", fmt.Sprint(PER_PAGE))
+	query.Set("page", fmt.Sprint(page))
+	if p.branch != "" {
+		query.Set("branch", p.branch)
+	}
+
+	req, err := p.client.NewRequest(
+		http.MethodGet,
+		fmt.Sprintf("repos/%s/%s/actions/runs?%s", p.repoOwner, p.repoName, query.Encode()),
+		nil,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create request to poll workflow runs: %w", err)
+	}
+
+	if page == 1 && p.etag != "" {
+		req.Header.Set("If-None-Match", p.etag)
+	}
+
+	runs := &github.WorkflowRuns{}
+	resp, err := p.client.Do(ctx, req, runs)
+
+	return runs, resp, err
+}
@@ -0,0 +1,106 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// fakeRuns serves completed workflow runs, most recent first, in pages of pageSize,
+// answering conditional requests of the first page with 304 Not Modified.
+type fakeRuns struct {
+	ids      []int64
+	pageSize int
+	requests int
+}
+
+func (f *fakeRuns) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests++
+
+	etag := fmt.Sprintf(`"%d"`, f.ids[0])
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page == 1 && r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	start := (page - 1) * f.pageSize
+	end := min(start+f.pageSize, len(f.ids))
+	if end < len(f.ids) {
+		next := *r.URL
+		q := next.Query()
+		q.Set("page", strconv.Itoa(page+1))
+		next.RawQuery = q.Encode()
+		w.Header().Set("Link", fmt.Sprintf(`<http://%s%s>; rel="next"`, r.Host, next.String()))
+	}
+
+	runs := []map[string]any{}
+	for _, id := range f.ids[start:end] {
+		// Runs are created a minute apart, in the order of their IDs.
+		ts := time.Date(2024, 1, 1, 0, int(id), 0, 0, time.UTC)
+		runs = append(runs, map[string]any{
+			"id": id, "run_attempt": 1, "created_at": ts, "updated_at": ts, "run_started_at": ts,
+		})
+	}
+
+	w.Header().Set("ETag", etag)
+	_ = json.NewEncoder(w).Encode(map[string]any{"total_count": len(f.ids), "workflow_runs": runs})
+}
+
+func polledIDs(runs []*types.WorkflowRun) []int64 {
+	ids := []int64{}
+	for _, run := range runs {
+		ids = append(ids, run.ID)
+	}
+
+	return ids
+}
+
+func TestRunPoller(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{}))
+
+	fake := &fakeRuns{ids: []int64{5, 4, 3, 2, 1}, pageSize: 2}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := github.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+	poller := NewRunPoller(client, "cilium", "cilium", "")
+
+	// The first poll only fetches the first page.
+	runs, err := poller.Poll(ctx, logger)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{5, 4}, polledIDs(runs))
+
+	// Nothing changed, which the conditional request tells without a body.
+	runs, err = poller.Poll(ctx, logger)
+	assert.NoError(t, err)
+	assert.Nil(t, runs)
+	assert.Equal(t, 2, fake.requests)
+
+	// More than a page of runs completed, so pages are fetched until the most recent
+	// run of the previous poll.
+	fake.ids = []int64{10, 9, 8, 7, 6, 5, 4, 3, 2, 1}
+	runs, err = poller.Poll(ctx, logger)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{10, 9, 8, 7, 6, 5}, polledIDs(runs))
+	assert.Equal(t, 5, fake.requests)
+
+	runs, err = poller.Poll(ctx, logger)
+	assert.NoError(t, err)
+	assert.Nil(t, runs)
+}
@@ -0,0 +1,141 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/go-github/v60/github"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+const (
+	reviewStateApproved         = "APPROVED"
+	reviewStateChangesRequested = "CHANGES_REQUESTED"
+)
+
+// GetPullRequestForRun returns the pull request the given run was triggered for, including
+// its review state as of when the run started. Nil is returned if no pull request is found.
+// Note that GitHub only returns the currently requested reviewers, rather than the ones
+// requested when the run started.
+func GetPullRequestForRun(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	run *types.WorkflowRun,
+) (*types.PullRequest, error) {
+	l := logger.With("workflow-id", run.ID, "head-sha", run.HeadSHA)
+
+	l.Debug("Pulling pull request for workflow run")
+
+	owner, repo := run.Repository.Owner.Login, run.Repository.Name
+
+	pull, err := findPullRequestForRun(ctx, l, client, run)
+	if err != nil {
+		return nil, err
+	}
+	if pull == nil {
+		l.Debug("No pull request found for workflow run")
+		return nil, nil
+	}
+
+	reviews := []*github.PullRequestReview{}
+	reviewOpts := &github.ListOptions{PerPage: PER_PAGE}
+	for {
+		page, resp, err := WrapWithRateLimitRetry[[]*github.PullRequestReview](
+			ctx, l,
+			func() (*[]*github.PullRequestReview, *github.Response, error) {
+				r, resp, err := client.PullRequests.ListReviews(ctx, owner, repo, pull.GetNumber(), reviewOpts)
+				return &r, resp, err
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list reviews for pull request %d: %w", pull.GetNumber(), err)
+		}
+
+		reviews = append(reviews, *page...)
+
+		if resp.NextPage == 0 {
+			break
+		}
+		reviewOpts.Page = resp.NextPage
+	}
+
+	result := &types.PullRequest{
+		Number: pull.GetNumber(),
+		URL:    pull.GetHTMLURL(),
+		Author: pull.GetUser().GetLogin(),
+	}
+
+	for _, u := range pull.RequestedReviewers {
+		result.RequestedReviewers = append(result.RequestedReviewers, u.GetLogin())
+	}
+	for _, t := range pull.RequestedTeams {
+		result.RequestedReviewers = append(result.RequestedReviewers, t.GetSlug())
+	}
+
+	// Only the latest approval or change request of each reviewer counts, comments
+	// don't dismiss either.
+	latest := map[string]string{}
+	for _, r := range reviews {
+		if !r.GetSubmittedAt().Before(run.RunStartedAt) {
+			continue
+		}
+		if s := r.GetState(); s == reviewStateApproved || s == reviewStateChangesRequested {
+			latest[r.GetUser().GetLogin()] = s
+		}
+	}
+
+	for _, s := range latest {
+		switch s {
+		case reviewStateApproved:
+			result.Approvals++
+		case reviewStateChangesRequested:
+			result.ChangesRequested++
+		}
+	}
+
+	switch {
+	case result.ChangesRequested > 0:
+		result.ReviewState = types.ReviewStateChangesRequested
+	case result.Approvals > 0:
+		result.ReviewState = types.ReviewStateApproved
+	default:
+		result.ReviewState = types.ReviewStatePending
+	}
+
+	return result, nil
+}
+
+// findPullRequestForRun returns the pull request whose head is the head commit of the
+// given run, or nil if there is none.
+func findPullRequestForRun(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	run *types.WorkflowRun,
+) (*github.PullRequest, error) {
+	owner, repo := run.Repository.Owner.Login, run.Repository.Name
+
+	pulls, _, err := WrapWithRateLimitRetry[[]*github.PullRequest](
+		ctx, logger,
+		func() (*[]*github.PullRequest, *github.Response, error) {
+			p, resp, err := client.PullRequests.ListPullRequestsWithCommit(
+				ctx, owner, repo, run.HeadSHA, &github.ListOptions{PerPage: PER_PAGE},
+			)
+			return &p, resp, err
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list pull requests for commit %s: %w", run.HeadSHA, err)
+	}
+
+	for _, p := range *pulls {
+		if p.GetHead().GetSHA() == run.HeadSHA {
+			return p, nil
+		}
+	}
+
+	return nil, nil
+}
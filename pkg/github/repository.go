@@ -2,8 +2,12 @@ package github
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"slices"
+	"time"
 
 	"github.com/google/go-github/v60/github"
 	"github.com/isovalent/corgi/pkg/types"
@@ -31,3 +35,95 @@ func GetRepository(
 
 	return types.NewRepositoryFromRaw(repo), nil
 }
+
+// GetCacheUsage returns the current GitHub Actions cache usage of the given repository.
+func GetCacheUsage(
+	ctx context.Context, logger *slog.Logger, client *github.Client, repo *types.Repository,
+) (*types.CacheUsage, error) {
+	l := logger.With("repoName", repo.Name, "repoOwner", repo.Owner.Login)
+
+	l.Info("Querying GitHub Actions cache usage")
+
+	usage, _, err := WrapWithRateLimitRetry[github.ActionsCacheUsage](
+		ctx, l,
+		func() (*github.ActionsCacheUsage, *github.Response, error) {
+			return client.Actions.GetCacheUsageForRepo(ctx, repo.Owner.Login, repo.Name)
+		},
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to get cache usage: %w", err)
+	}
+
+	return &types.CacheUsage{
+		Type:                    types.TypeNameCacheUsage,
+		Repository:              *repo,
+		ActiveCachesSizeInBytes: usage.ActiveCachesSizeInBytes,
+		ActiveCachesCount:       usage.ActiveCachesCount,
+		Timestamp:               time.Now().UTC(),
+	}, nil
+}
+
+// GetRequiredChecks returns the names of the status checks required to merge into the given
+// branch, combining branch protection and rulesets. Branches without protection have none.
+func GetRequiredChecks(
+	ctx context.Context, logger *slog.Logger, client *github.Client, repoOwner, repoName, branch string,
+) ([]string, error) {
+	l := logger.With("repoName", repoName, "repoOwner", repoOwner, "branch", branch)
+
+	l.Info("Querying required status checks for branch")
+
+	required := []string{}
+
+	checks, resp, err := WrapWithRateLimitRetry[github.RequiredStatusChecks](
+		ctx, l,
+		func() (*github.RequiredStatusChecks, *github.Response, error) {
+			return client.Repositories.GetRequiredStatusChecks(ctx, repoOwner, repoName, branch)
+		},
+	)
+	switch {
+	case err == nil:
+		if checks.Contexts != nil {
+			required = append(required, *checks.Contexts...)
+		}
+		if checks.Checks != nil {
+			for _, c := range *checks.Checks {
+				required = append(required, c.Context)
+			}
+		}
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+		l.Debug("Branch is not protected")
+	default:
+		return nil, fmt.Errorf("unable to get required status checks for branch %s: %w", branch, err)
+	}
+
+	rules, _, err := WrapWithRateLimitRetry[[]*github.RepositoryRule](
+		ctx, l,
+		func() (*[]*github.RepositoryRule, *github.Response, error) {
+			r, resp, err := client.Repositories.GetRulesForBranch(ctx, repoOwner, repoName, branch)
+			return &r, resp, err
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get rules for branch %s: %w", branch, err)
+	}
+
+	for _, rule := range *rules {
+		if rule.Type != "required_status_checks" || rule.Parameters == nil {
+			continue
+		}
+
+		params := github.RequiredStatusChecksRuleParameters{}
+		if err := json.Unmarshal(*rule.Parameters, &params); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal required status checks rule: %w", err)
+		}
+
+		for _, c := range params.RequiredStatusChecks {
+			required = append(required, c.Context)
+		}
+	}
+
+	slices.Sort(required)
+
+	return slices.Compact(required), nil
+}
@@ -0,0 +1,247 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/google/go-github/v60/github"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// TailEventKind is the kind of progress reported by a RunTail.
+type TailEventKind string
+
+const (
+	TailEventRunStarted    TailEventKind = "run-started"
+	TailEventJobCompleted  TailEventKind = "job-completed"
+	TailEventStepCompleted TailEventKind = "step-completed"
+	TailEventArtifact      TailEventKind = "artifact"
+	TailEventRunCompleted  TailEventKind = "run-completed"
+)
+
+// TailEvent is a single piece of progress of an in-progress workflow run.
+// Job and Step are only set for job and step completions, Artifact only for
+// newly uploaded artifacts.
+type TailEvent struct {
+	Kind       TailEventKind
+	Run        *types.WorkflowRun
+	Job        string
+	Step       string
+	Conclusion string
+	Artifact   *github.Artifact
+}
+
+// tailedRun is what a RunTail already reported for a run.
+type tailedRun struct {
+	run       *types.WorkflowRun
+	jobs      map[int64]struct{}
+	steps     map[string]struct{}
+	artifacts map[int64]struct{}
+}
+
+// RunTail follows the in-progress runs of a workflow, reporting jobs and steps
+// as they complete and artifacts as they are uploaded. Runs are followed from
+// the first poll they are seen in progress until they complete.
+type RunTail struct {
+	client    *github.Client
+	repoOwner string
+	repoName  string
+	workflow  string
+	branch    string
+	runs      map[int64]*tailedRun
+}
+
+// NewRunTail creates a RunTail for the given workflow, which is either its
+// numeric ID or the file name of its definition, such as "ci.yaml".
+func NewRunTail(client *github.Client, repoOwner, repoName, workflow, branch string) *RunTail {
+	return &RunTail{
+		client:    client,
+		repoOwner: repoOwner,
+		repoName:  repoName,
+		workflow:  workflow,
+		branch:    branch,
+		runs:      map[int64]*tailedRun{},
+	}
+}
+
+// Poll returns the progress made by the followed runs since the previous poll.
+func (t *RunTail) Poll(ctx context.Context, logger *slog.Logger) ([]TailEvent, error) {
+	inProgress, err := t.listInProgressRuns(ctx, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []TailEvent{}
+	listed := make(map[int64]struct{}, len(inProgress))
+
+	for _, run := range inProgress {
+		listed[run.ID] = struct{}{}
+
+		tailed, ok := t.runs[run.ID]
+		if !ok {
+			tailed = &tailedRun{
+				jobs:      map[int64]struct{}{},
+				steps:     map[string]struct{}{},
+				artifacts: map[int64]struct{}{},
+			}
+			t.runs[run.ID] = tailed
+			events = append(events, TailEvent{Kind: TailEventRunStarted, Run: run})
+		}
+		tailed.run = run
+	}
+
+	for id, tailed := range t.runs {
+		if _, ok := listed[id]; !ok {
+			runRaw, _, err := WrapWithRateLimitRetry[github.WorkflowRun](
+				ctx, logger,
+				func() (*github.WorkflowRun, *github.Response, error) {
+					return t.client.Actions.GetWorkflowRunByID(ctx, t.repoOwner, t.repoName, id)
+				},
+			)
+			if err != nil {
+				return nil, fmt.Errorf("unable to get workflow run %d: %w", id, err)
+			}
+			tailed.run = types.NewWorkflowRunFromRaw(runRaw)
+		}
+
+		progress, err := t.pollRun(ctx, logger, tailed)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, progress...)
+
+		// Runs which dropped out of the listing but aren't completed yet, such as
+		// runs waiting for an approval, are kept until they complete.
+		if tailed.run.Status == "completed" {
+			events = append(events, TailEvent{
+				Kind:       TailEventRunCompleted,
+				Run:        tailed.run,
+				Conclusion: tailed.run.Conclusion,
+			})
+			delete(t.runs, id)
+		}
+	}
+
+	return events, nil
+}
+
+// listInProgressRuns returns the in-progress runs of the workflow.
+func (t *RunTail) listInProgressRuns(ctx context.Context, logger *slog.Logger) ([]*types.WorkflowRun, error) {
+	opts := &github.ListWorkflowRunsOptions{
+		Status: "in_progress",
+		Branch: t.branch,
+		ListOptions: github.ListOptions{
+			PerPage: PER_PAGE,
+		},
+	}
+
+	result := []*types.WorkflowRun{}
+
+	for {
+		runs, resp, err := WrapWithRateLimitRetry[github.WorkflowRuns](
+			ctx, logger,
+			func() (*github.WorkflowRuns, *github.Response, error) {
+				if id, err := strconv.ParseInt(t.workflow, 10, 64); err == nil {
+					return t.client.Actions.ListWorkflowRunsByID(ctx, t.repoOwner, t.repoName, id, opts)
+				}
+				return t.client.Actions.ListWorkflowRunsByFileName(ctx, t.repoOwner, t.repoName, t.workflow, opts)
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list in-progress runs of workflow %s: %w", t.workflow, err)
+		}
+
+		for _, runRaw := range runs.WorkflowRuns {
+			result = append(result, types.NewWorkflowRunFromRaw(runRaw))
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		opts.Page = resp.NextPage
+	}
+
+	return result, nil
+}
+
+// pollRun returns the jobs and steps of the given run which completed, and the
+// artifacts which were uploaded, since they were last polled.
+func (t *RunTail) pollRun(ctx context.Context, logger *slog.Logger, tailed *tailedRun) ([]TailEvent, error) {
+	l := logger.With("workflow-id", tailed.run.ID)
+	events := []TailEvent{}
+
+	jobOpts := &github.ListWorkflowJobsOptions{
+		Filter: "latest",
+		ListOptions: github.ListOptions{
+			PerPage: PER_PAGE,
+		},
+	}
+
+	for {
+		jobs, resp, err := WrapWithRateLimitRetry[github.Jobs](
+			ctx, l,
+			func() (*github.Jobs, *github.Response, error) {
+				return t.client.Actions.ListWorkflowJobs(ctx, t.repoOwner, t.repoName, tailed.run.ID, jobOpts)
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to pull jobs for workflow with ID %d: %w", tailed.run.ID, err)
+		}
+
+		for _, job := range jobs.Jobs {
+			for _, step := range job.Steps {
+				key := fmt.Sprintf("%d/%d", job.GetID(), step.GetNumber())
+				if _, ok := tailed.steps[key]; ok || step.GetStatus() != "completed" {
+					continue
+				}
+				tailed.steps[key] = struct{}{}
+
+				events = append(events, TailEvent{
+					Kind:       TailEventStepCompleted,
+					Run:        tailed.run,
+					Job:        job.GetName(),
+					Step:       step.GetName(),
+					Conclusion: step.GetConclusion(),
+				})
+			}
+
+			if _, ok := tailed.jobs[job.GetID()]; ok || job.GetStatus() != "completed" {
+				continue
+			}
+			tailed.jobs[job.GetID()] = struct{}{}
+
+			events = append(events, TailEvent{
+				Kind:       TailEventJobCompleted,
+				Run:        tailed.run,
+				Job:        job.GetName(),
+				Conclusion: job.GetConclusion(),
+			})
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+
+		jobOpts.Page = resp.NextPage
+	}
+
+	artifacts, err := ListArtifactsForRun(ctx, l, t.client, tailed.run)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, artifact := range artifacts {
+		if _, ok := tailed.artifacts[artifact.GetID()]; ok {
+			continue
+		}
+		tailed.artifacts[artifact.GetID()] = struct{}{}
+
+		events = append(events, TailEvent{Kind: TailEventArtifact, Run: tailed.run, Artifact: artifact})
+	}
+
+	return events, nil
+}
@@ -0,0 +1,107 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v60/github"
+)
+
+type teamMembersEntry struct {
+	members []string
+	expires time.Time
+}
+
+// TeamMembers expands owner handles of GitHub teams, such as @cilium/sig-datapath, into
+// the handles of their members. The members of each team are cached for a TTL, since
+// the same few teams own most tests. It is safe for concurrent use.
+type TeamMembers struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]teamMembersEntry
+}
+
+// NewTeamMembers creates a new TeamMembers caching the members of teams for the given TTL.
+func NewTeamMembers(ttl time.Duration) *TeamMembers {
+	return &TeamMembers{ttl: ttl, entries: map[string]teamMembersEntry{}}
+}
+
+// listTeamMembers returns the handles of the members of the given team. Teams which
+// don't exist or aren't visible to the token have no members.
+func listTeamMembers(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	org, slug string,
+) ([]string, error) {
+	members := []string{}
+	opts := &github.TeamListTeamMembersOptions{ListOptions: github.ListOptions{PerPage: 100}}
+
+	for {
+		users, resp, err := WrapWithRateLimitRetry(ctx, logger, func() (*[]*github.User, *github.Response, error) {
+			users, resp, err := client.Teams.ListTeamMembersBySlug(ctx, org, slug, opts)
+			return &users, resp, err
+		})
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			logger.Warn("Team not found, the token may lack read:org", "org", org, "team", slug)
+			return members, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to list members of team %s/%s: %w", org, slug, err)
+		}
+
+		for _, u := range *users {
+			members = append(members, "@"+u.GetLogin())
+		}
+
+		if resp.NextPage == 0 {
+			return members, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// Expand returns the given owner handles with the handles of the members of teams, in
+// @org/team format, expanded. Handles of individuals are kept as they are. The result is
+// sorted and has no duplicates.
+func (t *TeamMembers) Expand(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	handles []string,
+) ([]string, error) {
+	// Teams are listed while holding the lock, so that concurrent runs owned by the same
+	// team don't list it at the same time.
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	members := []string{}
+	for _, handle := range handles {
+		org, slug, isTeam := strings.Cut(strings.TrimPrefix(handle, "@"), "/")
+		if !isTeam {
+			members = append(members, handle)
+			continue
+		}
+
+		key := strings.ToLower(org + "/" + slug)
+		entry, ok := t.entries[key]
+		if !ok || time.Now().After(entry.expires) {
+			teamMembers, err := listTeamMembers(ctx, logger, client, org, slug)
+			if err != nil {
+				return nil, err
+			}
+			entry = teamMembersEntry{members: teamMembers, expires: time.Now().Add(t.ttl)}
+			t.entries[key] = entry
+		}
+		members = append(members, entry.members...)
+	}
+
+	return slices.Compact(slices.Sorted(slices.Values(members))), nil
+}
@@ -0,0 +1,103 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/google/go-github/v60/github"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// Messages of the annotations GitHub adds to jobs when they are terminated.
+const (
+	// annotationSuperseded is added when a job is cancelled by a newer run in the same
+	// concurrency group, such as when a branch is pushed to again.
+	annotationSuperseded = "higher priority waiting request"
+	// annotationTimeout is added when a job exceeds its timeout-minutes.
+	annotationTimeout = "has exceeded the maximum execution time"
+)
+
+// terminationReason returns the reason a job with the given conclusion and annotation
+// messages was terminated, or an empty string if it ran to completion.
+func terminationReason(conclusion string, annotations []string) string {
+	switch conclusion {
+	case "timed_out":
+		return types.TerminationReasonTimeout
+	case "stale":
+		return types.TerminationReasonStale
+	case "cancelled":
+	default:
+		return ""
+	}
+
+	for _, a := range annotations {
+		if strings.Contains(a, annotationSuperseded) {
+			return types.TerminationReasonSuperseded
+		}
+		if strings.Contains(a, annotationTimeout) {
+			return types.TerminationReasonTimeout
+		}
+	}
+
+	return types.TerminationReasonCancelled
+}
+
+// GetJobTerminationReason returns the reason the given job was terminated, or an empty
+// string if it ran to completion. The job's annotations are only pulled for cancelled jobs.
+func GetJobTerminationReason(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	job *types.JobRun,
+) (string, error) {
+	if job.Conclusion != "cancelled" {
+		return terminationReason(job.Conclusion, nil), nil
+	}
+
+	l := logger.With("job-id", job.ID)
+
+	l.Debug("Pulling annotations for cancelled job")
+
+	// Jobs are check runs, so their IDs can be used to get their annotations.
+	annotations, _, err := WrapWithRateLimitRetry[[]*github.CheckRunAnnotation](
+		ctx, l,
+		func() (*[]*github.CheckRunAnnotation, *github.Response, error) {
+			a, resp, err := client.Checks.ListCheckRunAnnotations(
+				ctx, job.Repository.Owner.Login, job.Repository.Name, job.ID,
+				&github.ListOptions{PerPage: PER_PAGE},
+			)
+			return &a, resp, err
+		},
+	)
+	if err != nil {
+		return "", fmt.Errorf("unable to list annotations for job %d: %w", job.ID, err)
+	}
+
+	messages := make([]string, 0, len(*annotations))
+	for _, a := range *annotations {
+		messages = append(messages, a.GetMessage())
+	}
+
+	return terminationReason(job.Conclusion, messages), nil
+}
+
+// RunTerminationReason returns the reason the given run was terminated, based on the
+// termination reasons of its jobs, or an empty string if it ran to completion.
+func RunTerminationReason(run *types.WorkflowRun, jobs []types.JobRun) string {
+	reason := terminationReason(run.Conclusion, nil)
+	if reason != types.TerminationReasonCancelled {
+		return reason
+	}
+
+	for _, job := range jobs {
+		switch job.TerminationReason {
+		case types.TerminationReasonSuperseded, types.TerminationReasonTimeout:
+			return job.TerminationReason
+		}
+	}
+
+	return reason
+}
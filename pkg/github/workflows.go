@@ -4,28 +4,37 @@ import (
 	"archive/zip"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/google/go-github/v60/github"
 
+	"github.com/isovalent/corgi/pkg/archive"
 	"github.com/isovalent/corgi/pkg/junit"
+	"github.com/isovalent/corgi/pkg/metrics"
+	"github.com/isovalent/corgi/pkg/tracing"
 	"github.com/isovalent/corgi/pkg/types"
 	"github.com/isovalent/corgi/pkg/util"
 )
 
 const PER_PAGE = 100
 
-// GetWorkflowRuns returns a list of workflow runs as determined by the given arguments.
+// JUnitArtifactName is the name of the artifact JUnit files are read from.
+const JUnitArtifactName = "cilium-junits"
+
+// StreamWorkflowRuns sends the workflow runs determined by the given arguments to the
+// given channel, one page at a time, blocking while the channel is full.
 // These workflows can be passed to other functions to retrieve sub-objects, such jobs
 // and steps.
-func GetWorkflowRuns(
+func StreamWorkflowRuns(
 	ctx context.Context,
 	logger *slog.Logger,
 	client *github.Client,
@@ -37,7 +46,8 @@ func GetWorkflowRuns(
 	since time.Time,
 	until time.Time,
 	workflowID int64,
-) ([]*types.WorkflowRun, error) {
+	out chan<- *types.WorkflowRun,
+) error {
 	baseLogger := logger.With(
 		"repoOwner", repoOwner,
 		"repoName", repoName,
@@ -46,8 +56,6 @@ func GetWorkflowRuns(
 		"until", until,
 	)
 
-	workflowRuns := []*types.WorkflowRun{}
-
 	runOpts := github.ListOptions{
 		PerPage: PER_PAGE,
 	}
@@ -73,6 +81,9 @@ func GetWorkflowRuns(
 		}
 
 		l := baseLogger.With("dateQuery", dateQuery, "page", runOpts.Page)
+
+		stopFetchTimer := metrics.TimeStage(metrics.StageFetch)
+
 		l.Info("Pulling workflow runs for repository", "event", event, "status", status, "workflowID", workflowID)
 
 		var (
@@ -124,7 +135,8 @@ func GetWorkflowRuns(
 		}
 
 		if err != nil {
-			return nil, fmt.Errorf(
+			stopFetchTimer()
+			return fmt.Errorf(
 				"unable to pull workflow runs for repo %s/%s on branch %s: %w",
 				repoOwner, repoName, branch, err,
 			)
@@ -132,17 +144,30 @@ func GetWorkflowRuns(
 
 		l.Info("Processing workflow runs", "total", runs.GetTotalCount(), "count", len(runs.WorkflowRuns))
 
+		page := make([]*types.WorkflowRun, 0, len(runs.WorkflowRuns))
+
 		for _, runRaw := range runs.WorkflowRuns {
 			run := types.NewWorkflowRunFromRaw(runRaw)
 
 			duration, err := GetWorkflowRunDuration(ctx, l, client, run)
 			if err != nil {
-				return nil, err
+				stopFetchTimer()
+				return err
 			}
 
 			run.WorkflowDuration = duration
 
-			workflowRuns = append(workflowRuns, run)
+			page = append(page, run)
+		}
+
+		stopFetchTimer()
+
+		for _, run := range page {
+			select {
+			case out <- run:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
 		}
 
 		if runResp.NextPage == 0 {
@@ -152,7 +177,65 @@ func GetWorkflowRuns(
 		runOpts.Page = runResp.NextPage
 	}
 
-	return workflowRuns, nil
+	return nil
+}
+
+// GetWorkflowRunAttempts returns every attempt of the workflow run with the given ID.
+func GetWorkflowRunAttempts(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	repoOwner string,
+	repoName string,
+	runID int64,
+) ([]*types.WorkflowRun, error) {
+	l := logger.With("workflow-id", runID)
+
+	l.Info("Pulling workflow run")
+
+	latest, _, err := WrapWithRateLimitRetry[github.WorkflowRun](
+		ctx, l,
+		func() (*github.WorkflowRun, *github.Response, error) {
+			return client.Actions.GetWorkflowRunByID(ctx, repoOwner, repoName, runID)
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to pull workflow run with ID %d: %w", runID, err)
+	}
+
+	rawRuns := []*github.WorkflowRun{}
+	for attempt := 1; attempt < latest.GetRunAttempt(); attempt++ {
+		l.Debug("Pulling previous attempt of workflow run", "run-attempt", attempt)
+
+		rawRun, _, err := WrapWithRateLimitRetry[github.WorkflowRun](
+			ctx, l,
+			func() (*github.WorkflowRun, *github.Response, error) {
+				return client.Actions.GetWorkflowRunAttempt(ctx, repoOwner, repoName, runID, attempt, nil)
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to pull attempt %d of workflow run with ID %d: %w", attempt, runID, err)
+		}
+
+		rawRuns = append(rawRuns, rawRun)
+	}
+	rawRuns = append(rawRuns, latest)
+
+	runs := make([]*types.WorkflowRun, 0, len(rawRuns))
+	for _, rawRun := range rawRuns {
+		run := types.NewWorkflowRunFromRaw(rawRun)
+
+		duration, err := GetWorkflowRunDuration(ctx, l, client, run)
+		if err != nil {
+			return nil, err
+		}
+
+		run.WorkflowDuration = duration
+
+		runs = append(runs, run)
+	}
+
+	return runs, nil
 }
 
 // GetWorkflowRunDuration gets the total amount of time that a workflow run took.
@@ -183,117 +266,462 @@ func GetWorkflowRunDuration(
 	return time.Duration(usage.GetRunDurationMS() * 1000000), nil
 }
 
-// GetTestsForWorkflowRun checks if the given WorkflowRun contains a known JUnit artifact.
-// If a JUnit file is found and is recognized, it will be downloaded and parsed into a set of TestSuite
-// and Testcase objects.
-func GetTestsForWorkflowRun(
+// ListArtifactsForRun returns the artifacts uploaded by the given workflow run.
+func ListArtifactsForRun(
 	ctx context.Context,
 	logger *slog.Logger,
 	client *github.Client,
 	run *types.WorkflowRun,
-	allowedTestConclusions []string,
-) ([]types.Testsuite, []types.Testcase, error) {
+) ([]*github.Artifact, error) {
 	l := logger.With("workflow-id", run.ID)
 
 	l.Debug("Pulling artifacts for workflow")
 
-	// Don't expect more than 10 artifacts per workflow run. Cilium
-	// runs normally have one to two.
-	artifacts, _, err := WrapWithRateLimitRetry[github.ArtifactList](
-		ctx, l,
-		func() (*github.ArtifactList, *github.Response, error) {
-			return client.Actions.ListWorkflowRunArtifacts(
-				ctx, run.Repository.Owner.Login, run.Repository.Name, run.ID,
-				&github.ListOptions{
-					PerPage: 10,
-				},
-			)
-		},
-	)
-	if err != nil {
-		return nil, nil, fmt.Errorf("unable to list artifacts for workflow %d: %w", run.ID, err)
+	artifactOpts := &github.ListOptions{
+		PerPage: PER_PAGE,
+	}
+
+	result := []*github.Artifact{}
+
+	for {
+		artifacts, artifactsResp, err := WrapWithRateLimitRetry[github.ArtifactList](
+			ctx, l,
+			func() (*github.ArtifactList, *github.Response, error) {
+				return client.Actions.ListWorkflowRunArtifacts(
+					ctx, run.Repository.Owner.Login, run.Repository.Name, run.ID,
+					artifactOpts,
+				)
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list artifacts for workflow %d: %w", run.ID, err)
+		}
+
+		result = append(result, artifacts.Artifacts...)
+
+		if artifactsResp.NextPage == 0 {
+			break
+		}
+
+		artifactOpts.Page = artifactsResp.NextPage
+	}
+
+	return result, nil
+}
+
+// ArtifactResults are the documents parsed from the artifacts of a workflow run.
+type ArtifactResults struct {
+	Suites        []types.Testsuite
+	Cases         []types.Testcase
+	Sysdumps      []types.Sysdump
+	LogSignatures []types.LogSignature
+	// IngestErrors record the artifacts and files which had to be skipped.
+	IngestErrors []types.IngestError
+	// DownloadedBytes is the size of the artifacts which were downloaded.
+	DownloadedBytes int64
+}
+
+// ParseArtifactsForWorkflowRun downloads each of the given artifacts of a WorkflowRun which
+// is routed to a parser by the given routes, and parses it with that parser, so that all
+// artifacts of the run are processed in one pass. Artifacts which aren't routed are
+// ignored. If an archive is given, the run and its downloaded artifacts are stored in it
+// before they are parsed. On junit.ErrParseErrorBudgetExceeded, the IngestErrors so far
+// are returned along with the error.
+func ParseArtifactsForWorkflowRun(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	run *types.WorkflowRun,
+	artifacts []*github.Artifact,
+	routes []ArtifactRoute,
+	arch archive.Archive,
+	timeouts util.Timeouts,
+	opts *junit.Options,
+) (*ArtifactResults, error) {
+	l := logger.With("workflow-id", run.ID)
+	l.Debug("Routing artifacts to parsers", "count", len(artifacts))
+
+	archivedRun := false
+	results := &ArtifactResults{}
+	for _, a := range artifacts {
+		parser := RouteArtifact(routes, a.GetName())
+		if parser == "" {
+			continue
+		}
+
+		if arch != nil && !archivedRun {
+			archiveCtx, cancel := util.WithTimeout(ctx, timeouts.Request)
+			err := archive.PutRun(archiveCtx, arch, run)
+			cancel()
+			if err != nil {
+				return nil, fmt.Errorf("unable to archive workflow run: %w", err)
+			}
+			archivedRun = true
+		}
+
+		zipReader, artifact, ingestErrors, err := downloadWorkflowRunArtifact(ctx, logger, client, run, a, arch, timeouts, opts)
+		results.IngestErrors = append(results.IngestErrors, ingestErrors...)
+		if err != nil {
+			return nil, err
+		}
+		if zipReader == nil {
+			continue
+		}
+		results.DownloadedBytes += a.GetSizeInBytes()
+
+		err = ParseArtifact(ctx, logger, zipReader, run, artifact, parser, timeouts, opts, results)
+		zipReader.Close()
+		if errors.Is(err, junit.ErrParseErrorBudgetExceeded) {
+			return results, err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse artifact %s with %s: %w", a.GetName(), parser, err)
+		}
 	}
 
-	l.Debug("Checking artifacts for junit file", "count", artifacts.GetTotalCount())
+	return results, nil
+}
 
-	var junitArtifact *github.Artifact
-	for _, artifact := range artifacts.Artifacts {
-		if artifact.GetName() == "cilium-junits" {
-			junitArtifact = artifact
+// ParseArtifact parses the files of a downloaded artifact with the given parser, adding
+// the documents to results.
+func ParseArtifact(
+	ctx context.Context,
+	logger *slog.Logger,
+	zipReader *zip.ReadCloser,
+	run *types.WorkflowRun,
+	artifact *junit.Artifact,
+	parser string,
+	timeouts util.Timeouts,
+	opts *junit.Options,
+	results *ArtifactResults,
+) error {
+	defer metrics.TimeStage(metrics.StageParse)()
+
+	parseCtx, cancelParse := util.WithTimeout(ctx, timeouts.Parse)
+	defer cancelParse()
+
+	var ingestErrors []types.IngestError
+	var err error
+	switch parser {
+	case ArtifactParserJUnit:
+		var suites []types.Testsuite
+		var cases []types.Testcase
+		suites, cases, ingestErrors, err = junit.ParseFiles(parseCtx, zipReader.File, run, artifact, opts, logger)
+		results.Suites = append(results.Suites, suites...)
+		results.Cases = append(results.Cases, cases...)
+	case ArtifactParserSysdumpMetadata:
+		var sysdumps []types.Sysdump
+		sysdumps, ingestErrors, err = junit.ParseSysdumps(parseCtx, zipReader.File, run, artifact, opts, logger)
+		results.Sysdumps = append(results.Sysdumps, sysdumps...)
+	case ArtifactParserLogSignatures:
+		var signatures []types.LogSignature
+		signatures, ingestErrors, err = junit.ParseLogSignatures(parseCtx, zipReader.File, run, artifact, opts, logger)
+		results.LogSignatures = append(results.LogSignatures, signatures...)
+	default:
+		name, ok := strings.CutPrefix(parser, ArtifactParserPluginPrefix)
+		converter := opts.Converters[name]
+		if !ok || converter == nil {
+			return fmt.Errorf("unknown artifact parser %q", parser)
 		}
+
+		var suites []types.Testsuite
+		var cases []types.Testcase
+		suites, cases, ingestErrors, err = junit.ParseConvertedFiles(
+			parseCtx, zipReader.File, converter, run, artifact, opts, logger,
+		)
+		results.Suites = append(results.Suites, suites...)
+		results.Cases = append(results.Cases, cases...)
+	}
+	results.IngestErrors = append(results.IngestErrors, ingestErrors...)
+
+	return err
+}
+
+// GetTestsForArtifact downloads the given artifact of a WorkflowRun, which is expected to be a
+// zip archive of JUnit files, and parses it into a set of TestSuite and Testcase objects, along
+// with IngestError objects for the files which had to be skipped. Downloading and parsing are
+// bounded by the respective timeouts.
+func GetTestsForArtifact(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	run *types.WorkflowRun,
+	junitArtifact *github.Artifact,
+	timeouts util.Timeouts,
+	opts *junit.Options,
+) ([]types.Testsuite, []types.Testcase, []types.IngestError, error) {
+	zipReader, artifact, ingestErrors, err := downloadWorkflowRunArtifact(
+		ctx, logger, client, run, junitArtifact, nil, timeouts, opts,
+	)
+	if err != nil || zipReader == nil {
+		return nil, nil, ingestErrors, err
 	}
+	defer zipReader.Close()
 
-	if junitArtifact == nil {
-		l.Debug("No junit artifact found for workflow run, ignoring")
+	defer metrics.TimeStage(metrics.StageParse)()
 
-		return nil, nil, nil
+	parseCtx, cancelParse := util.WithTimeout(ctx, timeouts.Parse)
+	defer cancelParse()
+
+	return junit.ParseFiles(parseCtx, zipReader.File, run, artifact, opts, logger)
+}
+
+// downloadWorkflowRunArtifact downloads the given artifact of a WorkflowRun and opens it as
+// a zip archive, which the caller needs to close. Artifacts which expired or are too
+// large aren't downloaded, and an IngestError is returned for them instead of an archive.
+// If an archive is given, the downloaded artifact is stored in it. Downloading and
+// storing are each bounded by the download timeout.
+func downloadWorkflowRunArtifact(
+	ctx context.Context,
+	logger *slog.Logger,
+	client *github.Client,
+	run *types.WorkflowRun,
+	runArtifact *github.Artifact,
+	arch archive.Archive,
+	timeouts util.Timeouts,
+	opts *junit.Options,
+) (*zip.ReadCloser, *junit.Artifact, []types.IngestError, error) {
+	l := logger.With("workflow-id", run.ID, "artifact", runArtifact.GetName())
+
+	defer metrics.TimeStage(metrics.StageFetch)()
+
+	_, fetchSpan := tracing.Start(
+		ctx, "fetch-artifact",
+		tracing.AttrWorkflowID, run.ID, tracing.AttrArtifact, runArtifact.GetName(),
+	)
+	defer fetchSpan.End()
+
+	if runArtifact.GetExpired() {
+		l.Warn("Artifact for workflow run has expired")
+
+		return nil, nil, []types.IngestError{newArtifactExpiredError(run, runArtifact)}, nil
+	}
+
+	if opts.MaxArtifactBytes > 0 && runArtifact.GetSizeInBytes() > opts.MaxArtifactBytes {
+		l.Warn(
+			"Artifact for workflow run is too large, skipping",
+			"size", runArtifact.GetSizeInBytes(), "max", opts.MaxArtifactBytes,
+		)
+
+		return nil, nil, []types.IngestError{newArtifactTooLargeError(run, runArtifact, opts.MaxArtifactBytes)}, nil
 	}
 
-	tmpFile, err := os.CreateTemp("", fmt.Sprintf("cilium-junits-%d-*", run.ID))
+	tmpFile, err := os.CreateTemp("", fmt.Sprintf("%s-%d-*", runArtifact.GetName(), run.ID))
 	if err != nil {
-		return nil, nil, fmt.Errorf("unable to create temp file: %w", err)
+		return nil, nil, nil, fmt.Errorf("unable to create temp file: %w", err)
 	}
 	tmpFilePath := tmpFile.Name()
+	// The archive stays readable through its own file descriptor once the file is removed.
 	defer func() {
 		tmpFile.Close()
 		os.Remove(tmpFilePath)
 	}()
 
-	l.Info("Junit artifact found for workflow run, downloading", "url", junitArtifact.GetURL())
+	l.Info("Artifact found for workflow run, downloading", "url", runArtifact.GetURL())
 
 	downloadURL, downloadURLResp, err := WrapWithRateLimitRetry[url.URL](
 		ctx, l,
 		func() (*url.URL, *github.Response, error) {
 			return client.Actions.DownloadArtifact(
 				ctx, run.Repository.Owner.Login, run.Repository.Name,
-				junitArtifact.GetID(), 10,
+				runArtifact.GetID(), 10,
 			)
 		},
 	)
 	if err != nil {
-		if downloadURLResp.StatusCode == 410 {
-			l.Warn("Artiftacts for workflow run are unavailable, received status 410 Gone")
+		if downloadURLResp != nil && isArtifactExpiredStatus(downloadURLResp.StatusCode) {
+			l.Warn("Artifacts for workflow run are unavailable", "status", downloadURLResp.StatusCode)
 
-			return nil, nil, nil
+			return nil, nil, []types.IngestError{newArtifactExpiredError(run, runArtifact)}, nil
 		}
 
-		l.Debug("err", "err", err, "status", downloadURLResp.StatusCode, "status-code", downloadURLResp.StatusCode, "equal", downloadURLResp.StatusCode == 200, "body", func() string {
-			b := []byte{}
-			downloadURLResp.Body.Read(b)
-			return string(b)
-		}(), "resp", downloadURLResp.Response)
-
-		return nil, nil, fmt.Errorf("unable to get download url for artifact %d: %w", junitArtifact.GetID(), err)
+		return nil, nil, nil, fmt.Errorf("unable to get download url for artifact %d: %w", runArtifact.GetID(), err)
 	}
 
-	l.Debug("Downloading cilium-junits artifact", "url", downloadURL, "dest", tmpFilePath)
+	l.Debug("Downloading artifact", "url", downloadURL, "dest", tmpFilePath)
+
+	downloadCtx, cancelDownload := util.WithTimeout(ctx, timeouts.Download)
+	err = downloadArtifact(downloadCtx, l, downloadURL.String(), tmpFile, opts.MaxArtifactBytes)
+	cancelDownload()
+	if errors.Is(err, errArtifactExpired) {
+		l.Warn("Artifacts for workflow run are unavailable", "err", err)
+
+		return nil, nil, []types.IngestError{newArtifactExpiredError(run, runArtifact)}, nil
+	}
+	if errors.Is(err, errArtifactTooLarge) {
+		l.Warn("Artifact for workflow run is larger than reported, skipping", "max", opts.MaxArtifactBytes)
 
-	resp, err := http.Get(downloadURL.String())
+		return nil, nil, []types.IngestError{newArtifactTooLargeError(run, runArtifact, opts.MaxArtifactBytes)}, nil
+	}
 	if err != nil {
-		return nil, nil, fmt.Errorf("unable to download cilium-junits artifact from %s: %w", downloadURL, err)
+		fetchSpan.RecordError(err)
+		return nil, nil, nil, fmt.Errorf("unable to download artifact %s: %w", runArtifact.GetName(), err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, nil, fmt.Errorf(
-			"unable to download cilium-junits artifact from %s, bad http code: %s", downloadURL, resp.Status,
-		)
+	if arch != nil {
+		if err := archiveArtifact(ctx, l, arch, run, runArtifact, tmpFile, timeouts); err != nil {
+			return nil, nil, nil, err
+		}
 	}
 
-	_, err = io.Copy(tmpFile, resp.Body)
+	l.Debug("Successfully downloaded artifact, reading", "path", tmpFilePath)
+
+	zipReader, err := zip.OpenReader(tmpFilePath)
 	if err != nil {
-		return nil, nil, fmt.Errorf("unable to write cilium-junits artifact file: %w", err)
+		return nil, nil, nil, fmt.Errorf("unable to create zip reader for file %s: %w", tmpFilePath, err)
 	}
 
-	l.Debug("Successfully downloaded cilium-junits file, reading", "path", tmpFilePath)
+	artifact := &junit.Artifact{
+		ID:   runArtifact.GetID(),
+		Name: runArtifact.GetName(),
+		URL: fmt.Sprintf(
+			"https://github.com/%s/%s/actions/runs/%d/artifacts/%d",
+			run.Repository.Owner.Login, run.Repository.Name, run.ID, runArtifact.GetID(),
+		),
+	}
 
-	zipReader, err := zip.OpenReader(tmpFilePath)
+	return zipReader, artifact, nil, nil
+}
+
+// archiveArtifact stores the given downloaded artifact of a WorkflowRun in the archive.
+func archiveArtifact(
+	ctx context.Context,
+	l *slog.Logger,
+	arch archive.Archive,
+	run *types.WorkflowRun,
+	runArtifact *github.Artifact,
+	tmpFile *os.File,
+	timeouts util.Timeouts,
+) error {
+	info, err := tmpFile.Stat()
 	if err != nil {
-		return nil, nil, fmt.Errorf("unable to create zip reader for file %s: %w", tmpFilePath, err)
+		return fmt.Errorf("unable to stat artifact %s: %w", runArtifact.GetName(), err)
 	}
-	defer zipReader.Close()
+	if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("unable to rewind artifact %s: %w", runArtifact.GetName(), err)
+	}
+
+	key := archive.ArtifactKey(run, runArtifact.GetID(), runArtifact.GetName())
+	l.Debug("Archiving artifact", "key", key, "size", info.Size())
+
+	archiveCtx, cancel := util.WithTimeout(ctx, timeouts.Download)
+	defer cancel()
+	if err := arch.Put(archiveCtx, key, tmpFile, info.Size()); err != nil {
+		return fmt.Errorf("unable to archive artifact %s: %w", runArtifact.GetName(), err)
+	}
+
+	return nil
+}
+
+const maxArtifactDownloadAttempts = 5
+
+var (
+	errArtifactExpired  = errors.New("artifact expired")
+	errArtifactTooLarge = errors.New("artifact too large")
+)
+
+// isArtifactExpiredStatus returns true if the given HTTP status code signals that
+// an artifact is no longer available.
+func isArtifactExpiredStatus(code int) bool {
+	return code == http.StatusGone || code == http.StatusNotFound
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is either a number
+// of seconds or an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t), true
+	}
+
+	return 0, false
+}
+
+// downloadArtifact writes the artifact at the given URL to dst. Rate limited requests are
+// retried after the duration given in their Retry-After header. errArtifactExpired is
+// returned if the artifact is no longer available, and errArtifactTooLarge if it is
+// larger than maxBytes, unless that is zero.
+func downloadArtifact(ctx context.Context, l *slog.Logger, downloadURL string, dst io.Writer, maxBytes int64) error {
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+		if err != nil {
+			return fmt.Errorf("unable to create request for %s: %w", downloadURL, err)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("unable to download artifact from %s: %w", downloadURL, err)
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			body := io.Reader(resp.Body)
+			if maxBytes > 0 {
+				body = io.LimitReader(resp.Body, maxBytes+1)
+			}
+
+			n, err := io.Copy(dst, body)
+			resp.Body.Close()
+			if err != nil {
+				return fmt.Errorf("unable to write artifact: %w", err)
+			}
+			if maxBytes > 0 && n > maxBytes {
+				return fmt.Errorf("%w: more than %d bytes", errArtifactTooLarge, maxBytes)
+			}
+
+			return nil
+		}
+
+		resp.Body.Close()
+
+		if isArtifactExpiredStatus(resp.StatusCode) {
+			return fmt.Errorf("%w: %s", errArtifactExpired, resp.Status)
+		}
+
+		retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"))
+		if resp.StatusCode != http.StatusTooManyRequests || attempt >= maxArtifactDownloadAttempts {
+			return fmt.Errorf("unable to download artifact from %s, bad http code: %s", downloadURL, resp.Status)
+		}
+		if !ok {
+			retryAfter = time.Duration(attempt) * time.Minute
+		}
 
-	return junit.ParseFiles(zipReader.File, run, allowedTestConclusions, logger)
+		l.Warn("Artifact download was rate limited, waiting it out", "sleepTime", retryAfter, "attempt", attempt)
+
+		select {
+		case <-time.After(retryAfter):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// newArtifactExpiredError records that the given artifact of the given run could
+// not be downloaded since it expired.
+func newArtifactExpiredError(run *types.WorkflowRun, artifact *github.Artifact) types.IngestError {
+	return types.IngestError{
+		WorkflowRun:  run,
+		Type:         types.TypeNameIngestError,
+		ArtifactID:   artifact.GetID(),
+		ArtifactName: artifact.GetName(),
+		Reason:       junit.SkipReasonArtifactExpired,
+		Message:      fmt.Sprintf("artifact %s expired before it was ingested", artifact.GetName()),
+	}
+}
+
+// newArtifactTooLargeError records that the given artifact of the given run was not
+// downloaded since it is larger than the maximum size.
+func newArtifactTooLargeError(run *types.WorkflowRun, artifact *github.Artifact, max int64) types.IngestError {
+	return types.IngestError{
+		WorkflowRun:  run,
+		Type:         types.TypeNameIngestError,
+		ArtifactID:   artifact.GetID(),
+		ArtifactName: artifact.GetName(),
+		Reason:       junit.SkipReasonArtifactTooLarge,
+		Message:      fmt.Sprintf("artifact %s is larger than the maximum of %d bytes", artifact.GetName(), max),
+	}
 }
 
 // GetLogsForJob returns a string containing the logs for the given job.
@@ -304,6 +732,7 @@ func GetLogsForJob(
 	jobID int64,
 	repoOwner string,
 	repoName string,
+	downloadTimeout time.Duration,
 ) (string, error) {
 	l := logger.With("job-id", jobID)
 	l.Info("Pulling logs for job")
@@ -327,7 +756,15 @@ func GetLogsForJob(
 		return "", fmt.Errorf("unable to get log URL for job with ID %d: %w", jobID, err)
 	}
 
-	resp, err := http.Get(logURL.String())
+	ctx, cancel := util.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, logURL.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to create request for logs of job with ID %d: %w", jobID, err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("unable to download logs for job with ID %d: %w", jobID, err)
 	}
@@ -430,6 +867,19 @@ func IsErrorLog(line string) bool {
 		strings.Contains(line, "FAIL!")
 }
 
+// CountCacheHits counts the caches restored and not found by actions/cache in the given job logs.
+func CountCacheHits(logs string) (hits, misses int) {
+	for _, line := range strings.Split(logs, "\n") {
+		if strings.Contains(line, "Cache restored from key:") {
+			hits++
+		} else if strings.Contains(line, "Cache not found for input keys:") {
+			misses++
+		}
+	}
+
+	return hits, misses
+}
+
 // GetJobsAndStepsForRun returns a list of jobs and a list of steps that are contained within the given workflow run.
 // Jobs and Steps must be parsed together due to the way the GitHub API couples them together.
 func GetJobsAndStepsForRun(
@@ -440,9 +890,14 @@ func GetJobsAndStepsForRun(
 	allowedConclusions []string,
 	allowedStepConclusions []string,
 	includeErrorLogs bool,
+	includeCacheStats bool,
+	timeouts util.Timeouts,
 ) ([]types.JobRun, []types.StepRun, error) {
 	l := logger.With("workflow-id", run.ID)
 
+	_, span := tracing.Start(ctx, "fetch-jobs", tracing.AttrWorkflowID, run.ID)
+	defer span.End()
+
 	l.Info("Pulling jobs for workflow run")
 
 	jobOpts := &github.ListOptions{
@@ -483,13 +938,21 @@ func GetJobsAndStepsForRun(
 
 			job := types.NewJobRunFromRaw(run, jobRaw)
 
-			if job.Conclusion != "success" && includeErrorLogs {
-				logs, err := GetLogsForJob(ctx, logger, client, job.ID, run.Repository.Owner.Login, run.Repository.Name)
+			job.TerminationReason, err = GetJobTerminationReason(ctx, logger, client, job)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			wantErrorLogs := job.Conclusion != "success" && includeErrorLogs
+			if wantErrorLogs || includeCacheStats {
+				logs, err := GetLogsForJob(
+					ctx, logger, client, job.ID, run.Repository.Owner.Login, run.Repository.Name, timeouts.Download,
+				)
 				if err != nil {
 					return nil, nil, err
 				}
 
-				if logs != "" {
+				if logs != "" && wantErrorLogs {
 					job.ErrorLogs = []string{}
 
 					lines := strings.Split(logs, "\n")
@@ -499,6 +962,10 @@ func GetJobsAndStepsForRun(
 						}
 					}
 				}
+
+				if includeCacheStats {
+					job.CacheHits, job.CacheMisses = CountCacheHits(logs)
+				}
 			}
 
 			jobRuns = append(jobRuns, *job)
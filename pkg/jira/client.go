@@ -0,0 +1,126 @@
+package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+// Client is a minimal client for the REST API (v2) of Jira Cloud and Jira Data Center.
+type Client struct {
+	baseURL    string
+	user       string
+	token      *util.Secret
+	httpClient *http.Client
+}
+
+// Issue is a Jira issue as returned by the search API.
+type Issue struct {
+	ID  string `json:"id"`
+	Key string `json:"key"`
+}
+
+// NewClient creates a client for the Jira instance at baseURL. If user is set, requests
+// authenticate with basic auth using the token as password, as needed for API tokens of
+// Jira Cloud. Otherwise the token is sent as a bearer token, as needed for personal
+// access tokens of Jira Data Center.
+func NewClient(baseURL, user string, token *util.Secret) *Client {
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		user:       user,
+		token:      token,
+		httpClient: http.DefaultClient,
+	}
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("unable to marshal request body: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.user != "" {
+		req.SetBasicAuth(c.user, c.token.Value())
+	} else {
+		req.Header.Set("Authorization", "Bearer "+c.token.Value())
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read response of %s %s: %w", method, path, err)
+	}
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status %d for %s %s: %s", resp.StatusCode, method, path, data)
+	}
+
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("unable to parse response of %s %s: %w", method, path, err)
+		}
+	}
+
+	return nil
+}
+
+// FindIssue returns the first issue matching the given JQL query, or nil if there is none.
+func (c *Client) FindIssue(ctx context.Context, jql string) (*Issue, error) {
+	query := url.Values{"jql": {jql}, "maxResults": {"1"}, "fields": {"key"}}
+
+	result := struct {
+		Issues []Issue `json:"issues"`
+	}{}
+	if err := c.do(ctx, http.MethodGet, "/rest/api/2/search?"+query.Encode(), nil, &result); err != nil {
+		return nil, fmt.Errorf("unable to search issues: %w", err)
+	}
+
+	if len(result.Issues) == 0 {
+		return nil, nil
+	}
+
+	return &result.Issues[0], nil
+}
+
+// CreateIssue creates an issue with the given fields.
+func (c *Client) CreateIssue(ctx context.Context, fields map[string]any) (*Issue, error) {
+	issue := &Issue{}
+	if err := c.do(ctx, http.MethodPost, "/rest/api/2/issue", map[string]any{"fields": fields}, issue); err != nil {
+		return nil, fmt.Errorf("unable to create issue: %w", err)
+	}
+
+	return issue, nil
+}
+
+// UpdateIssue sets the given fields of the issue with the given key.
+func (c *Client) UpdateIssue(ctx context.Context, key string, fields map[string]any) error {
+	if err := c.do(ctx, http.MethodPut, "/rest/api/2/issue/"+url.PathEscape(key), map[string]any{"fields": fields}, nil); err != nil {
+		return fmt.Errorf("unable to update issue %s: %w", key, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package jira
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/isovalent/corgi/pkg/report"
+)
+
+// flakeLabel is the label of all issues filed for flaky tests.
+const flakeLabel = "corgi-flake"
+
+// FlakeValues are the attributes of a flaky test which can be mapped to issue fields.
+// The components value sets the Jira components of the teams behind the owners, for the
+// components field.
+var FlakeValues = []string{"name", "owners", "teams", "components", "failures", "passes", "failure_rate", "repository"}
+
+// FlakeConfig configures how issues for flaky tests are filed.
+type FlakeConfig struct {
+	Project    string
+	IssueType  string
+	Repository string
+	// Fields maps the IDs of additional issue fields, such as customfield_10010,
+	// to one of FlakeValues.
+	Fields map[string]string
+}
+
+// FlakeLabel returns the label identifying the issue of the flaky test with the given
+// name. Labels can't contain spaces, so the name is hashed.
+func FlakeLabel(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return flakeLabel + "-" + hex.EncodeToString(sum[:6])
+}
+
+func flakeValue(cfg FlakeConfig, flake report.Flake, value string) (any, error) {
+	switch value {
+	case "name":
+		return flake.Name, nil
+	case "owners":
+		return strings.Join(flake.Owners, ", "), nil
+	case "teams":
+		return strings.Join(flake.Teams, ", "), nil
+	case "components":
+		components := make([]map[string]any, 0, len(flake.JiraComponents))
+		for _, c := range flake.JiraComponents {
+			components = append(components, map[string]any{"name": c})
+		}
+		return components, nil
+	case "failures":
+		return flake.Failures, nil
+	case "passes":
+		return flake.Passes, nil
+	case "failure_rate":
+		return flake.FailureRate, nil
+	case "repository":
+		return cfg.Repository, nil
+	}
+
+	return nil, fmt.Errorf("unknown value %q, expected one of %s", value, strings.Join(FlakeValues, ", "))
+}
+
+// FlakeFields returns the issue fields describing the given flaky test, which are set both
+// when creating and updating its issue.
+func FlakeFields(cfg FlakeConfig, flake report.Flake) (map[string]any, error) {
+	description := &strings.Builder{}
+	fmt.Fprintf(description, "The test {{%s}} is flaky", flake.Name)
+	if cfg.Repository != "" {
+		fmt.Fprintf(description, " in %s", cfg.Repository)
+	}
+	fmt.Fprintf(
+		description, ": it failed %d times and passed %d times (failure rate %.1f%%).\n\n",
+		flake.Failures, flake.Passes, flake.FailureRate*100,
+	)
+	if len(flake.Owners) > 0 {
+		fmt.Fprintf(description, "Owners: %s\n\n", strings.Join(flake.Owners, ", "))
+	}
+	if len(flake.Teams) > 0 {
+		fmt.Fprintf(description, "Teams: %s\n\n", strings.Join(flake.Teams, ", "))
+	}
+	description.WriteString("This issue is kept up to date by corgi.")
+
+	fields := map[string]any{"description": description.String()}
+	for field, value := range cfg.Fields {
+		v, err := flakeValue(cfg, flake, value)
+		if err != nil {
+			return nil, fmt.Errorf("unable to map field %s: %w", field, err)
+		}
+		fields[field] = v
+	}
+
+	return fields, nil
+}
+
+// SyncFlake creates the issue for the given flaky test, or updates it if it was already
+// filed, returning the key of the issue and whether it was created.
+func SyncFlake(ctx context.Context, client *Client, cfg FlakeConfig, flake report.Flake) (string, bool, error) {
+	fields, err := FlakeFields(cfg, flake)
+	if err != nil {
+		return "", false, err
+	}
+
+	label := FlakeLabel(flake.Name)
+	existing, err := client.FindIssue(ctx, fmt.Sprintf("project = %q AND labels = %q", cfg.Project, label))
+	if err != nil {
+		return "", false, err
+	}
+
+	if existing != nil {
+		return existing.Key, false, client.UpdateIssue(ctx, existing.Key, fields)
+	}
+
+	fields["project"] = map[string]any{"key": cfg.Project}
+	fields["issuetype"] = map[string]any{"name": cfg.IssueType}
+	fields["summary"] = "Flaky test: " + flake.Name
+	fields["labels"] = []string{flakeLabel, label}
+
+	issue, err := client.CreateIssue(ctx, fields)
+	if err != nil {
+		return "", false, err
+	}
+
+	return issue.Key, true, nil
+}
@@ -0,0 +1,84 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/isovalent/corgi/pkg/report"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+func TestSyncFlake(t *testing.T) {
+	flake := report.Flake{Name: "TestFoo", Owners: []string{"@cilium/sig-foo"}, Failures: 3, Passes: 7, FailureRate: 0.3}
+	cfg := FlakeConfig{
+		Project: "CI", IssueType: "Bug", Repository: "cilium/cilium",
+		Fields: map[string]string{"customfield_1": "owners"},
+	}
+
+	var existing []Issue
+	var created, updated map[string]any
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		body := struct {
+			Fields map[string]any `json:"fields"`
+		}{}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/rest/api/2/search":
+			assert.Equal(t, `project = "CI" AND labels = "`+FlakeLabel("TestFoo")+`"`, r.URL.Query().Get("jql"))
+			json.NewEncoder(w).Encode(map[string]any{"issues": existing})
+		case r.Method == http.MethodPost && r.URL.Path == "/rest/api/2/issue":
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			created = body.Fields
+			json.NewEncoder(w).Encode(Issue{ID: "1", Key: "CI-1"})
+		case r.Method == http.MethodPut && r.URL.Path == "/rest/api/2/issue/CI-1":
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			updated = body.Fields
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer srv.Close()
+
+	t.Setenv("JIRA_TEST_TOKEN", "token")
+	token, err := util.NewSecret("JIRA_TEST_TOKEN")
+	assert.NoError(t, err)
+	client := NewClient(srv.URL+"/", "", token)
+
+	key, isNew, err := SyncFlake(context.Background(), client, cfg, flake)
+	assert.NoError(t, err)
+	assert.Equal(t, "CI-1", key)
+	assert.True(t, isNew)
+	assert.Equal(t, "Flaky test: TestFoo", created["summary"])
+	assert.Equal(t, "@cilium/sig-foo", created["customfield_1"])
+	assert.Equal(t, []any{flakeLabel, FlakeLabel("TestFoo")}, created["labels"])
+
+	existing = []Issue{{ID: "1", Key: "CI-1"}}
+	key, isNew, err = SyncFlake(context.Background(), client, cfg, flake)
+	assert.NoError(t, err)
+	assert.Equal(t, "CI-1", key)
+	assert.False(t, isNew)
+	assert.Contains(t, updated["description"], "failed 3 times and passed 7 times")
+	assert.NotContains(t, updated, "summary")
+}
+
+func TestFlakeFieldsTeams(t *testing.T) {
+	flake := report.Flake{
+		Name: "TestFoo", Owners: []string{"@cilium/sig-foo", "@cilium/sig-bar"},
+		Teams: []string{"foo"}, JiraComponents: []string{"Foo"},
+	}
+	cfg := FlakeConfig{Fields: map[string]string{"components": "components", "customfield_1": "teams"}}
+
+	fields, err := FlakeFields(cfg, flake)
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]any{{"name": "Foo"}}, fields["components"])
+	assert.Equal(t, "foo", fields["customfield_1"])
+	assert.Contains(t, fields["description"], "Teams: foo")
+}
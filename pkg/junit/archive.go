@@ -0,0 +1,66 @@
+package junit
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+const (
+	// DefaultMaxCompressionRatio is the default for Options.MaxCompressionRatio. Even
+	// highly repetitive JUnit files compress well below it, while decompression bombs
+	// are far above it.
+	DefaultMaxCompressionRatio = 200
+	// minCompressionRatioSize is the uncompressed size below which the compression
+	// ratio isn't checked, since tiny files can have any ratio without doing harm.
+	minCompressionRatioSize = 1 << 20
+)
+
+// checkArchiveEntry returns a *skipError if the given file of an artifact is unsafe
+// to read. Artifacts are produced by the code of pull requests, so entries may be
+// crafted to escape the archive, point elsewhere on the runner, or expand to far more
+// data than was downloaded.
+func checkArchiveEntry(fil file, opts *Options) error {
+	// Converted files are checked as the entry they were converted from.
+	if c, ok := fil.(*convertedFile); ok {
+		fil = c.file
+	}
+
+	name := filePath(fil)
+
+	// Backslashes are path separators on Windows runners, so they could be used for
+	// traversal when the archive is extracted there.
+	if !fs.ValidPath(strings.TrimSuffix(name, "/")) || strings.Contains(name, `\`) {
+		return &skipError{
+			reason: SkipReasonUnsafeEntry,
+			err:    fmt.Errorf("path %q escapes the archive", name),
+		}
+	}
+
+	if mode := fil.FileInfo().Mode(); !mode.IsRegular() && !mode.IsDir() {
+		return &skipError{
+			reason: SkipReasonUnsafeEntry,
+			err:    fmt.Errorf("%q is not a regular file but has mode %s", name, mode),
+		}
+	}
+
+	// The size read from an entry is checked against its header by archive/zip, so the
+	// header can be trusted here.
+	zipFile, ok := fil.(*zip.File)
+	if !ok || opts.MaxCompressionRatio <= 0 || zipFile.UncompressedSize64 < minCompressionRatioSize {
+		return nil
+	}
+
+	if zipFile.CompressedSize64 == 0 || zipFile.UncompressedSize64/zipFile.CompressedSize64 > uint64(opts.MaxCompressionRatio) {
+		return &skipError{
+			reason: SkipReasonUnsafeEntry,
+			err: fmt.Errorf(
+				"%q decompresses from %d to %d bytes, more than the maximum ratio of %d",
+				name, zipFile.CompressedSize64, zipFile.UncompressedSize64, opts.MaxCompressionRatio,
+			),
+		}
+	}
+
+	return nil
+}
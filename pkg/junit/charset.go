@@ -0,0 +1,63 @@
+package junit
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/ianaindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+var reXMLDeclEncoding = regexp.MustCompile(`^(\s*<\?xml[^>]*?encoding\s*=\s*["'])([A-Za-z0-9._:-]+)(["'])`)
+
+// detectUTF16 returns the UTF-16 encoding used by the given XML document, based on
+// its byte order mark or the encoding of the '<' character it starts with.
+// See https://www.w3.org/TR/xml/#sec-guessing-no-ext-info.
+func detectUTF16(data []byte) encoding.Encoding {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}), bytes.HasPrefix(data, []byte{'<', 0x00}):
+		return unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}), bytes.HasPrefix(data, []byte{0x00, '<'}):
+		return unicode.UTF16(unicode.BigEndian, unicode.UseBOM)
+	}
+
+	return nil
+}
+
+// toUTF8 converts the given XML document to UTF-8, based on its byte order mark or
+// its declared encoding. The encoding in the XML declaration is rewritten to match,
+// since the XML decoder rejects documents declaring encodings other than UTF-8.
+func toUTF8(data []byte) ([]byte, error) {
+	if enc := detectUTF16(data); enc != nil {
+		decoded, err := enc.NewDecoder().Bytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode UTF-16: %w", err)
+		}
+		data = decoded
+	} else {
+		data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+
+		if m := reXMLDeclEncoding.FindSubmatch(data); m != nil && !isUTF8Label(string(m[2])) {
+			enc, err := ianaindex.IANA.Encoding(string(m[2]))
+			if err != nil || enc == nil {
+				return nil, fmt.Errorf("unsupported encoding %q", m[2])
+			}
+
+			decoded, err := enc.NewDecoder().Bytes(data)
+			if err != nil {
+				return nil, fmt.Errorf("unable to decode %s: %w", m[2], err)
+			}
+			data = decoded
+		}
+	}
+
+	return reXMLDeclEncoding.ReplaceAll(data, []byte("${1}UTF-8${3}")), nil
+}
+
+func isUTF8Label(label string) bool {
+	label = strings.ToLower(label)
+	return label == "utf-8" || label == "utf8"
+}
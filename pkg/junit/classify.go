@@ -0,0 +1,50 @@
+package junit
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Classes of failures.
+const (
+	// FailureClassInfrastructure is a failure caused by the CI environment, such as
+	// a lost runner or a full disk, rather than the code under test.
+	FailureClassInfrastructure = "infrastructure"
+	// FailureClassProduct is any failure which doesn't match an infrastructure signature.
+	FailureClassProduct = "product"
+)
+
+// FailureSignature identifies infrastructure failures by matching Pattern against
+// failure messages and bodies.
+type FailureSignature struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// ParseFailureSignature parses a failure signature in the form of '<name>=<pattern>'.
+func ParseFailureSignature(s string) (FailureSignature, error) {
+	name, pattern, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return FailureSignature{}, fmt.Errorf("expected '<name>=<pattern>', got '%s'", s)
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return FailureSignature{}, fmt.Errorf("unable to compile pattern of failure signature '%s': %w", name, err)
+	}
+
+	return FailureSignature{Name: name, Pattern: re}, nil
+}
+
+// classifyFailure returns the class of the failure with the given output, and the name of
+// the signature it matched, if any.
+func classifyFailure(output string, signatures []FailureSignature) (class, signature string) {
+	for _, sig := range signatures {
+		if sig.Pattern.MatchString(output) {
+			return FailureClassInfrastructure, sig.Name
+		}
+	}
+
+	return FailureClassProduct, ""
+}
@@ -0,0 +1,89 @@
+package junit
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+const (
+	// clockSkewTolerance is how far suite end times may lie outside of their workflow
+	// run before they are considered suspect, allowing for small differences between
+	// the clocks of runners and GitHub.
+	clockSkewTolerance = 5 * time.Minute
+	// maxPlausibleDuration bounds durations when the span of the workflow run is
+	// unknown. GitHub cancels workflow runs after 35 days.
+	maxPlausibleDuration = 35 * 24 * time.Hour
+)
+
+// runBounds returns the window within which the suites of the given run must have
+// ended, widened by clockSkewTolerance. The start is zero if it is unknown.
+func runBounds(run *types.WorkflowRun, now time.Time) (time.Time, time.Time) {
+	if run == nil {
+		return time.Time{}, now.Add(clockSkewTolerance)
+	}
+
+	end := now
+	if run.Status == "completed" && !run.UpdatedAt.IsZero() && run.UpdatedAt.Before(now) {
+		end = run.UpdatedAt
+	}
+
+	start := time.Time{}
+	if !run.CreatedAt.IsZero() {
+		start = run.CreatedAt.Add(-clockSkewTolerance)
+	}
+
+	return start, end.Add(clockSkewTolerance)
+}
+
+// maxDuration returns the longest duration a suite or testcase of the given run can
+// plausibly have taken.
+func maxDuration(run *types.WorkflowRun, now time.Time) time.Duration {
+	start, end := runBounds(run, now)
+	if start.IsZero() {
+		return maxPlausibleDuration
+	}
+
+	return end.Sub(start)
+}
+
+// checkSuiteTimes clamps the end time and duration of the given suite to what is
+// plausible for its workflow run, marking the suite as TimeSuspect if they had to be
+// changed. Runner clock skew otherwise produces end times in the future and negative
+// or absurd durations, which break date histograms.
+func checkSuiteTimes(s *types.Testsuite, now time.Time, l *slog.Logger) {
+	start, end := runBounds(s.WorkflowRun, now)
+
+	if !s.EndTime.IsZero() {
+		if s.EndTime.After(end) {
+			l.Warn("Test suite ends after its workflow run, clamping", "suite", s.Name, "end-time", s.EndTime)
+			s.EndTime = end
+			s.TimeSuspect = true
+		} else if !start.IsZero() && s.EndTime.Before(start) {
+			l.Warn("Test suite ends before its workflow run started, clamping", "suite", s.Name, "end-time", s.EndTime)
+			s.EndTime = start
+			s.TimeSuspect = true
+		}
+	}
+
+	if d := clampDuration(s.Duration, maxDuration(s.WorkflowRun, now)); d != s.Duration {
+		l.Warn("Test suite has an implausible duration, clamping", "suite", s.Name, "duration", s.Duration)
+		s.Duration = d
+		s.TimeSuspect = true
+	}
+}
+
+// checkCaseDuration clamps the duration of the given testcase to what is plausible for
+// its workflow run, marking the testcase as TimeSuspect if it had to be changed.
+func checkCaseDuration(tc *types.Testcase, now time.Time, l *slog.Logger) {
+	if d := clampDuration(tc.Duration, maxDuration(tc.WorkflowRun, now)); d != tc.Duration {
+		l.Warn("Test case has an implausible duration, clamping", "testcase", tc.Name, "duration", tc.Duration)
+		tc.Duration = d
+		tc.TimeSuspect = true
+	}
+}
+
+func clampDuration(d, limit time.Duration) time.Duration {
+	return min(max(d, 0), limit)
+}
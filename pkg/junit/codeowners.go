@@ -0,0 +1,69 @@
+package junit
+
+import (
+	"slices"
+
+	"github.com/jstemmer/go-junit-report/v2/junit"
+
+	"github.com/isovalent/corgi/pkg/owners"
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// The vendored JUnit types don't parse the file attribute of testcases, which some
+// generators set to the source file of the test, so testsuites are unmarshalled into
+// these types, whose testcases shadow the ones of junit.Testsuite, to read it along
+// with the rest of the document.
+type fileTestcase struct {
+	junit.Testcase
+	File string `xml:"file,attr"`
+}
+
+type fileTestsuite struct {
+	junit.Testsuite
+	Testcases []fileTestcase `xml:"testcase"`
+}
+
+type fileTestsuites struct {
+	Suites []fileTestsuite `xml:"testsuite"`
+}
+
+// split returns the testsuite as parsed by the vendored types and the file attribute of
+// each of its testcases.
+func (s *fileTestsuite) split() (junit.Testsuite, []string) {
+	suite := s.Testsuite
+	suite.Testcases = make([]junit.Testcase, 0, len(s.Testcases))
+	files := make([]string, 0, len(s.Testcases))
+	for _, tc := range s.Testcases {
+		suite.Testcases = append(suite.Testcases, tc.Testcase)
+		files = append(files, tc.File)
+	}
+
+	return suite, files
+}
+
+// addCodeowners merges the owners of the testcase's file according to the given
+// CODEOWNERS with the owners parsed from its failure data, which are listed for the
+// tests at the same index of ownerTests, and recomputes the owner weights. The
+// CODEOWNERS owners are listed for the testcase itself.
+func addCodeowners(
+	tc *types.Testcase,
+	testcaseName string,
+	ownerTests []string,
+	codeowners *owners.Codeowners,
+	weighting OwnerWeighting,
+) {
+	added := false
+	for _, o := range codeowners.Owners(tc.File) {
+		if slices.Contains(tc.Owners, o) {
+			continue
+		}
+
+		tc.Owners = append(tc.Owners, o)
+		ownerTests = append(ownerTests, testcaseName)
+		added = true
+	}
+
+	if added {
+		tc.OwnerWeights = ownerWeights(testcaseName, tc.Owners, ownerTests, weighting)
+	}
+}
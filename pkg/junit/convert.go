@@ -0,0 +1,115 @@
+package junit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"strings"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// Converter converts files of a custom test report format into JUnit XML, so that they
+// are parsed like any other JUnit file. An empty result means the file isn't in the
+// format of the converter and is ignored.
+type Converter interface {
+	Convert(ctx context.Context, path string, data []byte) ([]byte, error)
+}
+
+// convertedFile is a file of an artifact converted into JUnit XML. Errors converting it
+// are returned when it is opened, so that it is skipped like unreadable JUnit files.
+type convertedFile struct {
+	file file
+	data []byte
+	err  error
+}
+
+func (f *convertedFile) Open() (io.ReadCloser, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return io.NopCloser(bytes.NewReader(f.data)), nil
+}
+
+func (f *convertedFile) FileInfo() fs.FileInfo {
+	return convertedFileInfo{FileInfo: f.file.FileInfo(), size: int64(len(f.data))}
+}
+
+// convertedFileInfo describes a converted file as the JUnit file it was converted into.
+type convertedFileInfo struct {
+	fs.FileInfo
+	size int64
+}
+
+func (i convertedFileInfo) Name() string {
+	if strings.HasSuffix(i.FileInfo.Name(), ".xml") {
+		return i.FileInfo.Name()
+	}
+
+	return i.FileInfo.Name() + ".xml"
+}
+
+func (i convertedFileInfo) Size() int64 {
+	return i.size
+}
+
+// convertFile reads the given file and converts it with the given converter.
+func convertFile(ctx context.Context, fil file, converter Converter) ([]byte, error) {
+	r, err := fil.Open()
+	if err != nil {
+		return nil, fmt.Errorf("unable to open file %q: %w", filePath(fil), err)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file %q: %w", filePath(fil), err)
+	}
+
+	converted, err := converter.Convert(ctx, filePath(fil), data)
+	if err != nil {
+		return nil, fmt.Errorf("unable to convert file %q: %w", filePath(fil), err)
+	}
+
+	return converted, nil
+}
+
+// ParseConvertedFiles converts the given files with the given converter and parses the
+// results like ParseFiles. Files the converter ignores are left out, while files which
+// can't be converted are skipped with an IngestError.
+func ParseConvertedFiles[F file](
+	ctx context.Context,
+	files []F,
+	converter Converter,
+	run *types.WorkflowRun,
+	artifact *Artifact,
+	opts *Options,
+	l *slog.Logger,
+) ([]types.Testsuite, []types.Testcase, []types.IngestError, error) {
+	converted := []*convertedFile{}
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, nil, fmt.Errorf("stopped converting files at %s: %w", filePath(f), err)
+		}
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		c := &convertedFile{file: f}
+		// Unsafe entries aren't read, but skipped when parsing.
+		if checkArchiveEntry(f, opts) == nil {
+			c.data, c.err = convertFile(ctx, f, converter)
+			if c.err == nil && len(c.data) == 0 {
+				l.Debug("Ignoring file which the converter doesn't handle", "file", filePath(f))
+				continue
+			}
+		}
+		converted = append(converted, c)
+	}
+
+	return ParseFiles(ctx, converted, run, artifact, opts, l)
+}
@@ -0,0 +1,134 @@
+package junit
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// FailureDataParser extracts owners from the failure data of a testcase, along
+// with the name of the test each owner is listed for. Parsers return
+// ErrInvalidFailureData if the data is not in their format, in which case
+// the next parser is tried.
+type FailureDataParser interface {
+	Parse(data string) (owners, testNames []string, err error)
+}
+
+// FailureDataParserFunc adapts a function to a FailureDataParser.
+type FailureDataParserFunc func(data string) (owners, testNames []string, err error)
+
+func (f FailureDataParserFunc) Parse(data string) (owners, testNames []string, err error) {
+	return f(data)
+}
+
+const (
+	// FailureDataFormatCiliumMetadata is the format used by cilium-cli, where owners
+	// follow a ';metadata;' delimiter as '@<owner> (<test>)'.
+	FailureDataFormatCiliumMetadata = "cilium-metadata"
+	// FailureDataFormatOwnersLine matches a line starting with 'Owners:' listing
+	// owners as '@<owner>' or '@<owner> (<test>)'.
+	FailureDataFormatOwnersLine = "owners-line"
+)
+
+var (
+	failureDataParsersMu sync.RWMutex
+	failureDataParsers   = map[string]FailureDataParser{
+		FailureDataFormatCiliumMetadata: FailureDataParserFunc(parseFailureData),
+		FailureDataFormatOwnersLine:     FailureDataParserFunc(parseOwnersLine),
+	}
+
+	// DefaultFailureDataFormats are the formats tried if none are configured.
+	DefaultFailureDataFormats = []string{FailureDataFormatCiliumMetadata}
+)
+
+// RegisterFailureDataParser makes a failure data parser available under the given
+// format name, replacing any parser previously registered under that name.
+func RegisterFailureDataParser(format string, parser FailureDataParser) {
+	failureDataParsersMu.Lock()
+	defer failureDataParsersMu.Unlock()
+
+	failureDataParsers[format] = parser
+}
+
+// FailureDataFormats returns the names of all registered failure data formats.
+func FailureDataFormats() []string {
+	failureDataParsersMu.RLock()
+	defer failureDataParsersMu.RUnlock()
+
+	formats := make([]string, 0, len(failureDataParsers))
+	for format := range failureDataParsers {
+		formats = append(formats, format)
+	}
+	slices.Sort(formats)
+
+	return formats
+}
+
+// FailureDataParsers returns the parsers registered for the given format names, in order.
+func FailureDataParsers(formats []string) ([]FailureDataParser, error) {
+	failureDataParsersMu.RLock()
+	defer failureDataParsersMu.RUnlock()
+
+	parsers := make([]FailureDataParser, 0, len(formats))
+	for _, format := range formats {
+		parser, ok := failureDataParsers[format]
+		if !ok {
+			return nil, fmt.Errorf("unknown failure data format '%s'", format)
+		}
+		parsers = append(parsers, parser)
+	}
+
+	return parsers, nil
+}
+
+// parseFailureDataWith tries each of the given parsers in order, returning the result
+// of the first one which recognizes the data.
+func parseFailureDataWith(parsers []FailureDataParser, data string) (owners, testNames []string, err error) {
+	if len(parsers) == 0 {
+		parsers, err = FailureDataParsers(DefaultFailureDataFormats)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, parser := range parsers {
+		owners, testNames, err = parser.Parse(data)
+		if !errors.Is(err, ErrInvalidFailureData) {
+			return owners, testNames, err
+		}
+	}
+
+	return nil, nil, ErrInvalidFailureData
+}
+
+func parseOwnersLine(data string) (owners, testNames []string, err error) {
+	// Expected input:
+	// Owners: @ci/owner1 (no-errors-in-logs), @ci/owner2
+	for _, line := range strings.Split(data, "\n") {
+		rest, ok := strings.CutPrefix(strings.TrimSpace(line), "Owners:")
+		if !ok {
+			continue
+		}
+
+		for _, entry := range strings.Split(rest, ",") {
+			entryOwners := parseOwners(entry)
+			if len(entryOwners) != 1 {
+				return nil, nil, fmt.Errorf("%w: found '%s'", ErrUnbalancedOwners, entry)
+			}
+
+			testName := ""
+			if tests := parseTestNames(entry); len(tests) > 0 {
+				testName = tests[0]
+			}
+
+			owners = append(owners, entryOwners[0])
+			testNames = append(testNames, testName)
+		}
+
+		return owners, testNames, nil
+	}
+
+	return nil, nil, ErrInvalidFailureData
+}
@@ -0,0 +1,89 @@
+package junit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// excerpt returns the head and tail of the given text, such that the result
+// is at most maxBytes long, excluding the marker noting how much was left out.
+func excerpt(text string, maxBytes int) string {
+	headEnd := maxBytes / 2
+	tailStart := len(text) - maxBytes/2
+
+	// Avoid splitting multi-byte characters.
+	for headEnd > 0 && !utf8.RuneStart(text[headEnd]) {
+		headEnd--
+	}
+	for tailStart < len(text) && !utf8.RuneStart(text[tailStart]) {
+		tailStart++
+	}
+
+	return fmt.Sprintf(
+		"%s\n... [%d bytes truncated] ...\n%s",
+		text[:headEnd], tailStart-headEnd, text[tailStart:],
+	)
+}
+
+func compress(text string) (string, error) {
+	buf := &bytes.Buffer{}
+
+	w := gzip.NewWriter(buf)
+	if _, err := w.Write([]byte(text)); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// capFailureText returns the given text, or an excerpt of it if it exceeds
+// the size configured in the given options.
+func capFailureText(text string, opts *Options) string {
+	if opts.MaxFailureTextBytes <= 0 || len(text) <= opts.MaxFailureTextBytes {
+		return text
+	}
+
+	return excerpt(text, opts.MaxFailureTextBytes)
+}
+
+// setFailureText stores the given failure body on the testcase, redacting it and
+// capping its size as configured in the given options.
+func setFailureText(tc *types.Testcase, text string, opts *Options) error {
+	// Secrets are redacted first, so that they don't end up in the raw or compressed body.
+	text = Redact(strings.TrimSpace(text), opts.SecretRedactions)
+
+	tc.FailureTextSize = len(text)
+	tc.FailureText = capFailureText(Redact(text, opts.Redactions), opts)
+
+	if raw := capFailureText(text, opts); raw != tc.FailureText {
+		tc.FailureTextRaw = raw
+	}
+
+	if opts.MaxFailureTextBytes <= 0 || len(text) <= opts.MaxFailureTextBytes {
+		return nil
+	}
+
+	sum := sha256.Sum256([]byte(text))
+	tc.FailureTextHash = hex.EncodeToString(sum[:])
+
+	if opts.CompressFailureText {
+		compressed, err := compress(text)
+		if err != nil {
+			return fmt.Errorf("unable to compress failure text: %w", err)
+		}
+		tc.FailureTextGzip = compressed
+	}
+
+	return nil
+}
@@ -1,7 +1,9 @@
 package junit
 
 import (
+	"archive/zip"
 	"bytes"
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
@@ -16,6 +18,8 @@ import (
 
 	"github.com/jstemmer/go-junit-report/v2/junit"
 
+	"github.com/isovalent/corgi/pkg/owners"
+	"github.com/isovalent/corgi/pkg/tracing"
 	"github.com/isovalent/corgi/pkg/types"
 	"github.com/isovalent/corgi/pkg/util"
 )
@@ -29,6 +33,70 @@ var (
 	reFailureDataTests  = regexp.MustCompile(`\(([-a-zA-Z\/0-9.]*)\)`)
 )
 
+// Options controls how JUnit files are parsed.
+type Options struct {
+	// AllowedTestConclusions are the statuses of testcases to keep. Testcases with
+	// other statuses are skipped. Both are normalized using StatusAliases first.
+	AllowedTestConclusions []string
+	// StatusAliases maps statuses to canonical statuses, in addition to DefaultStatusAliases.
+	StatusAliases map[string]string
+	// FailureSignatures classify failed testcases and suites as infrastructure failures.
+	// Failures which match none of them are classified as product failures.
+	FailureSignatures []FailureSignature
+	// MaxFailureTextBytes is the maximum size of a failure body which is stored in full.
+	// Larger failure bodies are replaced with an excerpt of their head and tail.
+	// Zero means unlimited.
+	MaxFailureTextBytes int
+	// CompressFailureText stores failure bodies larger than MaxFailureTextBytes
+	// in compressed form next to their excerpt.
+	CompressFailureText bool
+	// SystemErrPatterns are matched against each line of a failed suite's system-err
+	// output when none of its testcases have failure details. The first matching
+	// line is used as the suite's error summary.
+	SystemErrPatterns []*regexp.Regexp
+	// OwnerWeighting determines how testcases are attributed to their owners.
+	OwnerWeighting OwnerWeighting
+	// Redactions replace dynamic identifiers, such as pod names, in test names and
+	// failure bodies, so that the same failure is grouped together across runs.
+	// The original values are kept in separate raw fields.
+	Redactions []Redaction
+	// SecretRedactions replace sensitive data, such as tokens, in failure messages,
+	// bodies and system-err summaries before anything else is done with them. Unlike
+	// Redactions, the original values aren't kept anywhere, since test output of pull
+	// requests may leak secrets.
+	SecretRedactions []Redaction
+	// FailureDataParsers are tried in order to extract owners from the failure data
+	// of testcases. If empty, DefaultFailureDataFormats are used.
+	FailureDataParsers []FailureDataParser
+	// Strict fails parsing on JUnit files with unknown root elements, elements or
+	// attributes which aren't parsed, or suites without testcases, instead of
+	// skipping or dropping them silently.
+	Strict bool
+	// MaxSuitesPerFile and MaxTestcasesPerSuite are safety limits against broken
+	// generators. Files exceeding either are skipped. Zero means unlimited.
+	MaxSuitesPerFile     int
+	MaxTestcasesPerSuite int
+	// MaxCompressionRatio is the maximum ratio between the uncompressed and compressed
+	// size of files in an artifact. Files above it are skipped as decompression bombs.
+	// Zero means unlimited.
+	MaxCompressionRatio int
+	// Codeowners resolves the owners of testcases with a file attribute from the path
+	// of the file, in addition to the owners parsed from their failure data. Nil skips it.
+	Codeowners *owners.Codeowners
+	// Teams maps the owners of testcases to the teams behind them, whose names, Slack
+	// channels and Jira components are added to the testcases. Nil skips it.
+	Teams *owners.Teams
+	// MaxArtifactBytes is the maximum size of an artifact which is downloaded to be
+	// parsed. Larger artifacts are skipped. Zero means unlimited.
+	MaxArtifactBytes int64
+	// MaxParseErrors is the number of JUnit files of a workflow run which may be skipped
+	// before parsing is aborted with ErrParseErrorBudgetExceeded. Zero means unlimited.
+	MaxParseErrors int
+	// Converters convert artifacts of custom formats into JUnit files, by the name of
+	// the plugin providing them.
+	Converters map[string]Converter
+}
+
 func parseOwners(data string) []string {
 	return reFailureDataOwners.FindAllString(data, -1)
 }
@@ -79,22 +147,110 @@ func filterTestOwners(owners, tests []string) []string {
 	return filterOwners(".github", owners, tests, false)
 }
 
+// filterTestNames returns the test names which filterTestOwners keeps owners for.
+func filterTestNames(tests []string) []string {
+	return filterOwners(".github", tests, tests, false)
+}
+
+// OwnerWeighting determines how a testcase's failure is attributed to its owners.
+type OwnerWeighting string
+
+const (
+	// OwnerWeightingEven gives each owner an equal share.
+	OwnerWeightingEven OwnerWeighting = "even"
+	// OwnerWeightingPrimary gives the full weight to the owners listed for the
+	// test with the same name as the testcase, falling back to an even split if
+	// there are none.
+	OwnerWeightingPrimary OwnerWeighting = "primary"
+)
+
+// ownerWeights splits a testcase between the given owners, each listed for the test
+// at the same index, such that the weights sum up to one. This allows failures to be
+// counted per owner without counting a failure multiple times.
+func ownerWeights(testcaseName string, owners, tests []string, weighting OwnerWeighting) []types.OwnerWeight {
+	candidates := []string{}
+
+	if weighting == OwnerWeightingPrimary {
+		for i, o := range owners {
+			if tests[i] == testcaseName {
+				candidates = append(candidates, o)
+			}
+		}
+	}
+
+	if len(candidates) == 0 {
+		candidates = owners
+	}
+
+	unique := slices.Compact(slices.Sorted(slices.Values(candidates)))
+
+	weights := make([]types.OwnerWeight, 0, len(unique))
+	for _, o := range unique {
+		weights = append(weights, types.OwnerWeight{
+			Owner:  o,
+			Weight: 1 / float64(len(unique)),
+		})
+	}
+
+	return weights
+}
+
+// failureResult returns the failure or error result of the given testcase, if any.
+func failureResult(testcase *junit.Testcase) *junit.Result {
+	if testcase.Failure != nil {
+		return testcase.Failure
+	}
+
+	return testcase.Error
+}
+
+// hasFailureDetail returns true if any testcase in the suite has a failure or
+// error with a body or message.
+func hasFailureDetail(suite *junit.Testsuite) bool {
+	for _, testcase := range suite.Testcases {
+		if result := failureResult(&testcase); result != nil && (result.Data != "" || result.Message != "") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// summarizeSystemErr returns the first line of the given output which matches
+// any of the given patterns.
+func summarizeSystemErr(output string, patterns []*regexp.Regexp) string {
+	for _, line := range strings.Split(output, "\n") {
+		for _, pattern := range patterns {
+			if pattern.MatchString(line) {
+				return strings.TrimSpace(line)
+			}
+		}
+	}
+
+	return ""
+}
+
 func parseTestsuite(
 	suite *junit.Testsuite,
+	files []string,
 	run *types.WorkflowRun,
-	allowedTestConclusions []string,
+	opts *Options,
 	l *slog.Logger,
 ) (*types.Testsuite, []types.Testcase, error) {
 	s := &types.Testsuite{
 		WorkflowRun:   run,
 		Type:          types.TypeNameTestsuite,
-		Name:          suite.Name,
+		Name:          Redact(suite.Name, opts.Redactions),
 		TotalTests:    suite.Tests,
 		TotalFailures: suite.Failures,
 		TotalErrors:   suite.Errors,
 		TotalSkipped:  suite.Skipped,
 	}
 
+	if s.Name != suite.Name {
+		s.NameRaw = suite.Name
+	}
+
 	if suite.Time != "" {
 		duration, err := time.ParseDuration(fmt.Sprintf("%ss", suite.Time))
 		if err != nil {
@@ -115,14 +271,27 @@ func parseTestsuite(
 		s.EndTime = endTime
 	}
 
+	now := time.Now()
+	checkSuiteTimes(s, now, l)
+
+	allowedConclusions := normalizeStatuses(opts.AllowedTestConclusions, opts.StatusAliases)
+
 	cases := []types.Testcase{}
 	allOwners := make(map[string]struct{})
 
-	for _, testcase := range suite.Testcases {
+	for i, testcase := range suite.Testcases {
 		tc := types.Testcase{
 			Testsuite: s,
 			Type:      types.TypeNameTestcase,
-			Name:      testcase.Name,
+			Name:      Redact(testcase.Name, opts.Redactions),
+		}
+
+		if i < len(files) {
+			tc.File = files[i]
+		}
+
+		if tc.Name != testcase.Name {
+			tc.NameRaw = testcase.Name
 		}
 
 		// There are a couple of formats for the cilium-junits. Sometimes
@@ -134,17 +303,19 @@ func parseTestsuite(
 			tc.Status = testcase.Status
 		} else {
 			if testcase.Error != nil {
-				tc.Status = "error"
+				tc.Status = StatusError
 			} else if testcase.Failure != nil {
-				tc.Status = "failure"
+				tc.Status = StatusFailed
 			} else if testcase.Skipped != nil {
-				tc.Status = "skipped"
+				tc.Status = StatusSkipped
 			} else {
-				tc.Status = "passed"
+				tc.Status = StatusPassed
 			}
 		}
 
-		if !util.Contains(allowedTestConclusions, tc.Status) {
+		tc.Status = NormalizeStatus(tc.Status, opts.StatusAliases)
+
+		if !util.Contains(allowedConclusions, tc.Status) {
 			l.Debug(
 				"Skipping test case for workflow, does not meet status criteria",
 				"testcase-name", testcase.Name, "testcase-status", testcase.Status,
@@ -159,13 +330,27 @@ func parseTestsuite(
 				return nil, nil, fmt.Errorf("unable to parse duration '%ss': %w", testcase.Time, err)
 			}
 			tc.Duration = duration
+			checkCaseDuration(&tc, now, l)
+		}
+
+		if result := failureResult(&testcase); result != nil {
+			tc.FailureMessage = Redact(result.Message, opts.SecretRedactions)
+			tc.FailureClass, tc.FailureSignature = classifyFailure(
+				result.Message+"\n"+result.Data, opts.FailureSignatures,
+			)
+			if err := setFailureText(&tc, result.Data, opts); err != nil {
+				return nil, nil, fmt.Errorf("unable to set failure text for testcase '%s': %w", testcase.Name, err)
+			}
 		}
 
+		var ownerTests []string
 		if testcase.Failure != nil {
 			// Parse owners
-			owners, testNames, err := parseFailureData(testcase.Failure.Data)
+			owners, testNames, err := parseFailureDataWith(opts.FailureDataParsers, testcase.Failure.Data)
 			if err == nil {
 				tc.Owners = filterTestOwners(owners, testNames)
+				ownerTests = filterTestNames(testNames)
+				tc.OwnerWeights = ownerWeights(testcase.Name, tc.Owners, ownerTests, opts.OwnerWeighting)
 				for _, o := range filterWorkflowOwners(owners, testNames) {
 					allOwners[o] = struct{}{}
 				}
@@ -174,28 +359,178 @@ func parseTestsuite(
 			}
 		}
 
+		if tc.File != "" && opts.Codeowners != nil {
+			addCodeowners(&tc, testcase.Name, ownerTests, opts.Codeowners, opts.OwnerWeighting)
+		}
+
+		if opts.Teams != nil {
+			setTeams(&tc, opts.Teams)
+		}
+
 		cases = append(cases, tc)
 	}
 
 	s.Owners = slices.Sorted(maps.Keys(allOwners))
 
+	if suite.Failures > 0 || suite.Errors > 0 {
+		s.FailureClass, s.FailureSignature = classifySuite(suite, cases, opts.FailureSignatures)
+	}
+
+	// Some suites only report failures through their system-err output, so extract
+	// a summary from it to have something to search for.
+	if suite.SystemErr != nil && (suite.Failures > 0 || suite.Errors > 0) && !hasFailureDetail(suite) {
+		s.SystemErrSummary = Redact(
+			summarizeSystemErr(suite.SystemErr.Data, opts.SystemErrPatterns), opts.SecretRedactions,
+		)
+	}
+
 	return s, cases, nil
 }
 
+// classifySuite classifies a failed suite as an infrastructure failure if any of its
+// testcases or its system-err output match an infrastructure signature.
+func classifySuite(
+	suite *junit.Testsuite,
+	cases []types.Testcase,
+	signatures []FailureSignature,
+) (class, signature string) {
+	for _, tc := range cases {
+		if tc.FailureClass == FailureClassInfrastructure {
+			return tc.FailureClass, tc.FailureSignature
+		}
+	}
+
+	if suite.SystemErr != nil {
+		return classifyFailure(suite.SystemErr.Data, signatures)
+	}
+
+	return FailureClassProduct, ""
+}
+
+// rootElementName returns the local name of the root element in the given XML document.
+func rootElementName(data []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+// Artifact describes the artifact that JUnit files were extracted from.
+type Artifact struct {
+	ID   int64
+	Name string
+	// URL is a browsable link to download the artifact.
+	URL string
+}
+
 type file interface {
 	Open() (io.ReadCloser, error)
 	FileInfo() fs.FileInfo
 }
 
+// filePath returns the path of the given file within its archive.
+func filePath(fil file) string {
+	if c, ok := fil.(*convertedFile); ok {
+		return filePath(c.file)
+	}
+	if zipFile, ok := fil.(*zip.File); ok {
+		return zipFile.Name
+	}
+
+	return fil.FileInfo().Name()
+}
+
+// Reasons for skipping a JUnit file.
+const (
+	SkipReasonEmpty           = "empty"
+	SkipReasonInvalidEncoding = "invalid-encoding"
+	SkipReasonMalformed       = "malformed"
+	SkipReasonUnsupportedRoot = "unsupported-root"
+	SkipReasonUnreadable      = "unreadable"
+	// SkipReasonLimitExceeded is used when a file or workflow run exceeds one of
+	// the configured safety limits.
+	SkipReasonLimitExceeded = "limit-exceeded"
+	// SkipReasonUnsafeEntry is used for entries of an artifact which are unsafe to
+	// read, such as symlinks or paths escaping the archive.
+	SkipReasonUnsafeEntry = "unsafe-entry"
+	// SkipReasonArtifactExpired is used when the artifact containing the JUnit files
+	// could not be downloaded since it expired.
+	SkipReasonArtifactExpired = "artifact-expired"
+	// SkipReasonArtifactTooLarge is used when the artifact containing the JUnit files
+	// was not downloaded since it is larger than Options.MaxArtifactBytes.
+	SkipReasonArtifactTooLarge = "artifact-too-large"
+	// SkipReasonParseErrorBudgetExceeded is used when the ingest of a workflow run was
+	// aborted, since more of its JUnit files were skipped than Options.MaxParseErrors.
+	SkipReasonParseErrorBudgetExceeded = "parse-error-budget-exceeded"
+	// SkipReasonPullFailed is used when the ingest of a workflow run was aborted, since
+	// its jobs, event details or artifact listing couldn't be pulled from GitHub.
+	SkipReasonPullFailed = "pull-failed"
+	// SkipReasonParseFailed is used when the ingest of a workflow run was aborted, since
+	// its artifacts couldn't be downloaded or parsed, such as in strict mode or on a timeout.
+	SkipReasonParseFailed = "parse-failed"
+)
+
+// ErrParseErrorBudgetExceeded is returned by ParseFiles when more files were skipped
+// than Options.MaxParseErrors.
+var ErrParseErrorBudgetExceeded = errors.New("parse error budget exceeded")
+
+// skipError is returned for JUnit files which cannot be parsed, but shouldn't fail
+// parsing the rest of the artifact.
+type skipError struct {
+	reason string
+	err    error
+}
+
+func (e *skipError) Error() string {
+	return fmt.Sprintf("%s: %s", e.reason, e.err)
+}
+
+func (e *skipError) Unwrap() error {
+	return e.err
+}
+
+// parseFile parses the given JUnit file, ignoring it if it needs to be skipped.
 func parseFile(
 	fil file,
 	run *types.WorkflowRun,
-	allowedTestConclusions []string,
+	artifact *Artifact,
+	opts *Options,
+	l *slog.Logger,
+) ([]types.Testsuite, []types.Testcase, error) {
+	suites, cases, err := parseFileOrSkip(fil, run, artifact, opts, l)
+
+	var skipErr *skipError
+	if errors.As(err, &skipErr) {
+		l.Warn("Skipping junit file", "file", filePath(fil), "reason", skipErr.reason, "err", skipErr.err)
+		return nil, nil, nil
+	}
+
+	return suites, cases, err
+}
+
+// parseFileOrSkip parses the given JUnit file, returning a *skipError if it needs to be skipped.
+func parseFileOrSkip(
+	fil file,
+	run *types.WorkflowRun,
+	artifact *Artifact,
+	opts *Options,
 	l *slog.Logger,
 ) ([]types.Testsuite, []types.Testcase, error) {
 	suites := []types.Testsuite{}
 	cases := []types.Testcase{}
 
+	if err := checkArchiveEntry(fil, opts); err != nil {
+		return nil, nil, err
+	}
+
 	if !strings.HasSuffix(fil.FileInfo().Name(), ".xml") || fil.FileInfo().IsDir() {
 		l.Debug("ignoring non-xml file in cilium-junits archive", "file", fil.FileInfo().Name())
 		return nil, nil, nil
@@ -205,50 +540,127 @@ func parseFile(
 
 	fileReader, err := fil.Open()
 	if err != nil {
-		return nil, nil, fmt.Errorf("unable to open file %q: %w", fil.FileInfo().Name(), err)
+		return nil, nil, &skipError{
+			reason: SkipReasonUnreadable,
+			err:    fmt.Errorf("unable to open file %q: %w", fil.FileInfo().Name(), err),
+		}
 	}
 	defer fileReader.Close()
 
-	buf := &bytes.Buffer{}
-
-	_, err = io.Copy(buf, fileReader)
+	raw, err := io.ReadAll(fileReader)
 	if err != nil {
-		return nil, nil, fmt.Errorf("unable to read junit file %q: %w", fil.FileInfo().Name(), err)
+		return nil, nil, &skipError{
+			reason: SkipReasonUnreadable,
+			err:    fmt.Errorf("unable to read junit file %q: %w", fil.FileInfo().Name(), err),
+		}
 	}
 
 	// Sometimes a JUnit file can be empty, so we need to rule out empty files.
-	if buf.Len() == 0 {
-		l.Debug("ignoring empty xml file", "file", fil.FileInfo().Name())
-		return nil, nil, nil
+	if len(raw) == 0 {
+		return nil, nil, &skipError{reason: SkipReasonEmpty, err: errors.New("file is empty")}
+	}
+
+	utf8Data, err := toUTF8(raw)
+	if err != nil {
+		return nil, nil, &skipError{
+			reason: SkipReasonInvalidEncoding,
+			err:    fmt.Errorf("unable to convert junit file %q to UTF-8: %w", fil.FileInfo().Name(), err),
+		}
+	}
+
+	// Sanitizing must happen after converting to UTF-8, since other encodings
+	// would be seen as invalid UTF-8.
+	buf := &bytes.Buffer{}
+	if _, err := io.Copy(buf, newSanitizingReader(bytes.NewReader(utf8Data))); err != nil {
+		return nil, nil, fmt.Errorf("unable to sanitize junit file %q: %w", fil.FileInfo().Name(), err)
 	}
 
 	// A JUnit file may either be:
 	// 1. A junit.Testsuites object with multiple junit.Testsuite objects.
 	// 2. A junit.Testsuites object with a single junit.Testsuite object.
 	// 3. A single junit.Testsuite.
-	// Try all options when unmarshalling.
 	// Note that the XML parser thinks the Testsuites object is a valid Testsuite object, so
-	// we have to try parsing into a Testsuites first.
-	toParse := []junit.Testsuite{}
-	s := junit.Testsuites{}
-	if err := xml.Unmarshal(buf.Bytes(), &s); err != nil {
-		s := junit.Testsuite{}
-		if err2 := xml.Unmarshal(buf.Bytes(), &s); err2 != nil {
-			e := errors.Join(err, err2)
-			return nil, nil, fmt.Errorf("unable to unmarshal junit file '%s' in artifact to Testsuite or Testsuites object: %w", fil.FileInfo().Name(), e)
+	// peek at the root element to determine which one to unmarshal into, rather than
+	// unmarshalling the whole file multiple times.
+	root, err := rootElementName(buf.Bytes())
+	if err != nil {
+		return nil, nil, &skipError{
+			reason: SkipReasonMalformed,
+			err:    fmt.Errorf("unable to read root element of junit file '%s' in artifact: %w", fil.FileInfo().Name(), err),
+		}
+	}
+
+	if opts.Strict {
+		if err := checkStructure(buf.Bytes()); err != nil {
+			return nil, nil, fmt.Errorf("strict: unexpected structure of junit file '%s': %w", fil.FileInfo().Name(), err)
+		}
+	}
+
+	toParse := []fileTestsuite{}
+
+	switch root {
+	case "testsuites":
+		s := fileTestsuites{}
+		if err := xml.Unmarshal(buf.Bytes(), &s); err != nil {
+			return nil, nil, &skipError{
+				reason: SkipReasonMalformed,
+				err:    fmt.Errorf("unable to unmarshal junit file '%s' in artifact to Testsuites object: %w", fil.FileInfo().Name(), err),
+			}
 		}
-		toParse = append(toParse, s)
-	} else {
 		toParse = s.Suites
+	case "testsuite":
+		s := fileTestsuite{}
+		if err := xml.Unmarshal(buf.Bytes(), &s); err != nil {
+			return nil, nil, &skipError{
+				reason: SkipReasonMalformed,
+				err:    fmt.Errorf("unable to unmarshal junit file '%s' in artifact to Testsuite object: %w", fil.FileInfo().Name(), err),
+			}
+		}
+		toParse = append(toParse, s)
+	default:
+		return nil, nil, &skipError{
+			reason: SkipReasonUnsupportedRoot,
+			err:    fmt.Errorf("unknown root element '%s'", root),
+		}
 	}
 
-	for _, s := range toParse {
-		parsedSuite, parsedCases, err := parseTestsuite(&s, run, allowedTestConclusions, l)
+	if opts.MaxSuitesPerFile > 0 && len(toParse) > opts.MaxSuitesPerFile {
+		return nil, nil, &skipError{
+			reason: SkipReasonLimitExceeded,
+			err:    fmt.Errorf("file has %d test suites, more than the limit of %d", len(toParse), opts.MaxSuitesPerFile),
+		}
+	}
+
+	for _, fs := range toParse {
+		s, suiteFiles := fs.split()
+		if opts.MaxTestcasesPerSuite > 0 && len(s.Testcases) > opts.MaxTestcasesPerSuite {
+			return nil, nil, &skipError{
+				reason: SkipReasonLimitExceeded,
+				err: fmt.Errorf(
+					"test suite '%s' has %d testcases, more than the limit of %d",
+					s.Name, len(s.Testcases), opts.MaxTestcasesPerSuite,
+				),
+			}
+		}
+
+		if opts.Strict && len(s.Testcases) == 0 {
+			return nil, nil, fmt.Errorf(
+				"strict: test suite '%s' in junit file '%s' has no testcases", s.Name, fil.FileInfo().Name(),
+			)
+		}
+
+		parsedSuite, parsedCases, err := parseTestsuite(&s, suiteFiles, run, opts, l)
 		if err != nil {
 			return nil, nil, fmt.Errorf("unable to parse test suite in junit file '%s': %w", fil.FileInfo().Name(), err)
 		}
 
 		parsedSuite.JUnitFilename = fil.FileInfo().Name()
+		parsedSuite.JUnitPath = filePath(fil)
+		if artifact != nil {
+			parsedSuite.ArtifactID = artifact.ID
+			parsedSuite.ArtifactName = artifact.Name
+			parsedSuite.ArtifactURL = artifact.URL
+		}
 		suites = append(suites, *parsedSuite)
 		cases = append(cases, parsedCases...)
 	}
@@ -256,23 +668,79 @@ func parseFile(
 	return suites, cases, nil
 }
 
+// ParseFiles parses the given JUnit files. Files which cannot be parsed are skipped,
+// and an IngestError is returned for each of them. Parsing stops with an error once
+// the given context is done, or with ErrParseErrorBudgetExceeded and the IngestErrors
+// so far once more files were skipped than Options.MaxParseErrors.
 func ParseFiles[F file](
+	ctx context.Context,
 	files []F,
 	run *types.WorkflowRun,
-	allowedTestConclusions []string,
+	artifact *Artifact,
+	opts *Options,
 	l *slog.Logger,
-) ([]types.Testsuite, []types.Testcase, error) {
+) ([]types.Testsuite, []types.Testcase, []types.IngestError, error) {
 	suites := []types.Testsuite{}
 	cases := []types.Testcase{}
+	ingestErrors := []types.IngestError{}
+
+	ctx, span := tracing.Start(ctx, "parse-artifact", tracing.AttrWorkflowID, run.ID)
+	defer span.End()
+	if artifact != nil {
+		span.SetAttributes(tracing.AttrArtifact, artifact.Name)
+	}
 
 	for _, f := range files {
-		s, c, err := parseFile(f, run, allowedTestConclusions, l)
+		if err := ctx.Err(); err != nil {
+			span.RecordError(err)
+			return nil, nil, nil, fmt.Errorf("stopped parsing junit files at %s: %w", filePath(f), err)
+		}
+
+		_, fileSpan := tracing.Start(ctx, "parse-file", tracing.AttrFile, filePath(f))
+		s, c, err := parseFileOrSkip(f, run, artifact, opts, l)
+		fileSpan.RecordError(err)
+		fileSpan.End()
+
+		var skipErr *skipError
+		if errors.As(err, &skipErr) {
+			l.Warn("Skipping junit file", "file", filePath(f), "reason", skipErr.reason, "err", skipErr.err)
+			ingestErrors = append(ingestErrors, newIngestError(run, artifact, filePath(f), skipErr))
+
+			if opts.MaxParseErrors > 0 && len(ingestErrors) > opts.MaxParseErrors {
+				err := fmt.Errorf(
+					"%w: skipped %d junit files, more than the limit of %d",
+					ErrParseErrorBudgetExceeded, len(ingestErrors), opts.MaxParseErrors,
+				)
+				span.RecordError(err)
+				return nil, nil, ingestErrors, err
+			}
+
+			continue
+		}
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
 		}
+
 		suites = append(suites, s...)
 		cases = append(cases, c...)
 	}
 
-	return suites, cases, nil
+	return suites, cases, ingestErrors, nil
+}
+
+func newIngestError(run *types.WorkflowRun, artifact *Artifact, path string, skipErr *skipError) types.IngestError {
+	ingestError := types.IngestError{
+		WorkflowRun: run,
+		Type:        types.TypeNameIngestError,
+		JUnitPath:   path,
+		Reason:      skipErr.reason,
+		Message:     skipErr.err.Error(),
+	}
+
+	if artifact != nil {
+		ingestError.ArtifactID = artifact.ID
+		ingestError.ArtifactName = artifact.Name
+	}
+
+	return ingestError
 }
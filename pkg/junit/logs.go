@@ -0,0 +1,132 @@
+package junit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+const (
+	// maxLogLineBytes is the maximum length of a log line which is matched. The rest of
+	// a log file with a longer line isn't read.
+	maxLogLineBytes = 1 << 20
+	// maxLogSignatureLineBytes is the maximum length of the matching line stored.
+	maxLogSignatureLineBytes = 1024
+)
+
+// scanLog matches the lines of the given log file of an artifact against the given
+// signatures, returning a LogSignature for each signature which matched.
+func scanLog(fil file, run *types.WorkflowRun, artifact *Artifact, opts *Options, l *slog.Logger) ([]types.LogSignature, error) {
+	r, err := fil.Open()
+	if err != nil {
+		return nil, &skipError{
+			reason: SkipReasonUnreadable,
+			err:    fmt.Errorf("unable to open log file %q: %w", filePath(fil), err),
+		}
+	}
+	defer r.Close()
+
+	matches := map[string]*types.LogSignature{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLogLineBytes)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		for _, sig := range opts.FailureSignatures {
+			if !sig.Pattern.MatchString(line) {
+				continue
+			}
+
+			m, ok := matches[sig.Name]
+			if !ok {
+				first := Redact(line, opts.SecretRedactions)
+				if len(first) > maxLogSignatureLineBytes {
+					first = excerpt(first, maxLogSignatureLineBytes)
+				}
+
+				m = &types.LogSignature{
+					WorkflowRun:     run,
+					Type:            types.TypeNameLogSignature,
+					Path:            filePath(fil),
+					Signature:       sig.Name,
+					FirstLine:       first,
+					FirstLineNumber: lineNumber,
+				}
+				if artifact != nil {
+					m.ArtifactID = artifact.ID
+					m.ArtifactName = artifact.Name
+				}
+				matches[sig.Name] = m
+			}
+			m.Matches++
+		}
+	}
+	if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+		l.Debug("Log file has a line which is too long, skipping the rest", "file", filePath(fil))
+	} else if err := scanner.Err(); err != nil {
+		return nil, &skipError{
+			reason: SkipReasonUnreadable,
+			err:    fmt.Errorf("unable to read log file %q: %w", filePath(fil), err),
+		}
+	}
+
+	// Signatures are returned in the order they are configured in.
+	result := []types.LogSignature{}
+	for _, sig := range opts.FailureSignatures {
+		if m, ok := matches[sig.Name]; ok {
+			result = append(result, *m)
+		}
+	}
+
+	return result, nil
+}
+
+// ParseLogSignatures matches the lines of the given log files of an artifact against
+// Options.FailureSignatures, so that infrastructure failures which don't show up in
+// JUnit files are found as well. Files which are unsafe or can't be read are skipped,
+// and an IngestError is returned for each of them.
+func ParseLogSignatures[F file](
+	ctx context.Context,
+	files []F,
+	run *types.WorkflowRun,
+	artifact *Artifact,
+	opts *Options,
+	l *slog.Logger,
+) ([]types.LogSignature, []types.IngestError, error) {
+	signatures := []types.LogSignature{}
+	ingestErrors := []types.IngestError{}
+
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, fmt.Errorf("stopped reading log files at %s: %w", filePath(f), err)
+		}
+
+		err := checkArchiveEntry(f, opts)
+		if err == nil && f.FileInfo().IsDir() {
+			continue
+		}
+
+		var s []types.LogSignature
+		if err == nil {
+			s, err = scanLog(f, run, artifact, opts, l)
+		}
+
+		var skipErr *skipError
+		if errors.As(err, &skipErr) {
+			l.Warn("Skipping log file", "file", filePath(f), "reason", skipErr.reason, "err", skipErr.err)
+			ingestErrors = append(ingestErrors, newIngestError(run, artifact, filePath(f), skipErr))
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		signatures = append(signatures, s...)
+	}
+
+	return signatures, ingestErrors, nil
+}
@@ -0,0 +1,60 @@
+package junit
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultSecretRedactions replace common credentials and identifiers of infrastructure,
+// in the form accepted by ParseRedaction. Replacements name what was redacted, so that
+// failures caused by it remain understandable. Rules are anchored to the syntax
+// credentials appear in, such as authorization headers and assignments, so that prose
+// mentioning tokens or secrets is left as it is.
+var DefaultSecretRedactions = []string{
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----=<private-key>`,
+	`(?i)\b(authorization["']?:\s*["']?(?:bearer|basic|token)\s+)[A-Za-z0-9\-._~+/]+=*=${1}<redacted>`,
+	`\b(gh[pousr]_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b=<github-token>`,
+	`\b(AKIA|ASIA)[0-9A-Z]{16}\b=<aws-access-key>`,
+	`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+=<jwt>`,
+	`(?i)\b((?:password|passwd|secret|api[_-]?key|access[_-]?key|token)\s*=\s*["']?)[^\s"',;]+=${1}<redacted>`,
+	`(?i)(["'](?:password|passwd|secret|api[_-]?key|access[_-]?key|token)["']\s*:\s*["']?)[^\s"',;]+=${1}<redacted>`,
+	`\b(arn:aws[a-z-]*:[a-z0-9-]*:[a-z0-9-]*:)\d{12}:=${1}<account-id>:`,
+	`\b\d{12}(\.dkr\.ecr\.)=<account-id>${1}`,
+}
+
+// IPRedaction replaces IPv4 addresses. It isn't part of DefaultSecretRedactions, since
+// addresses are often needed to understand failures of networking tests.
+const IPRedaction = `\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b=<ip>`
+
+// Redaction replaces matches of Pattern with Replacement, which may refer to
+// submatches of the pattern as described in regexp.Regexp.Expand.
+type Redaction struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// ParseRedaction parses a redaction in the form of '<pattern>=<replacement>'. The pattern
+// may contain '=' itself, since the replacement is taken from after the last '='.
+func ParseRedaction(s string) (Redaction, error) {
+	i := strings.LastIndex(s, "=")
+	if i < 0 {
+		return Redaction{}, fmt.Errorf("expected '<pattern>=<replacement>', got '%s'", s)
+	}
+
+	pattern, err := regexp.Compile(s[:i])
+	if err != nil {
+		return Redaction{}, fmt.Errorf("unable to compile redaction pattern '%s': %w", s[:i], err)
+	}
+
+	return Redaction{Pattern: pattern, Replacement: s[i+1:]}, nil
+}
+
+// Redact applies the given redactions to the given text in order.
+func Redact(text string, redactions []Redaction) string {
+	for _, r := range redactions {
+		text = r.Pattern.ReplaceAllString(text, r.Replacement)
+	}
+
+	return text
+}
@@ -0,0 +1,60 @@
+package junit
+
+import (
+	"bufio"
+	"io"
+	"unicode/utf8"
+)
+
+// isValidXMLChar returns true if the given rune is allowed in an XML 1.0 document.
+// See https://www.w3.org/TR/xml/#charsets.
+func isValidXMLChar(r rune) bool {
+	return r == 0x09 || r == 0x0A || r == 0x0D ||
+		(r >= 0x20 && r <= 0xD7FF) ||
+		(r >= 0xE000 && r <= 0xFFFD) ||
+		(r >= 0x10000 && r <= 0x10FFFF)
+}
+
+// sanitizingReader strips characters which are illegal in XML, such as control
+// characters, and replaces invalid UTF-8 sequences with the unicode replacement
+// character. Logs embedded in JUnit files sometimes contain these, causing the
+// whole file to be rejected by the XML decoder.
+type sanitizingReader struct {
+	src     *bufio.Reader
+	pending []byte
+}
+
+func newSanitizingReader(src io.Reader) io.Reader {
+	return &sanitizingReader{src: bufio.NewReader(src)}
+}
+
+func (r *sanitizingReader) Read(p []byte) (int, error) {
+	n := 0
+
+	for n < len(p) {
+		if len(r.pending) > 0 {
+			c := copy(p[n:], r.pending)
+			r.pending = r.pending[c:]
+			n += c
+			continue
+		}
+
+		// Invalid UTF-8 sequences are returned by ReadRune as utf8.RuneError,
+		// which is a valid XML character.
+		char, _, err := r.src.ReadRune()
+		if err != nil {
+			if n > 0 && err == io.EOF {
+				return n, nil
+			}
+			return n, err
+		}
+
+		if !isValidXMLChar(char) {
+			continue
+		}
+
+		r.pending = utf8.AppendRune(r.pending[:0], char)
+	}
+
+	return n, nil
+}
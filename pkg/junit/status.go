@@ -0,0 +1,49 @@
+package junit
+
+import "strings"
+
+// Canonical statuses of testcases.
+const (
+	StatusPassed  = "passed"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+	StatusError   = "error"
+)
+
+// DefaultStatusAliases maps the statuses emitted by different JUnit producers
+// to the canonical statuses.
+var DefaultStatusAliases = map[string]string{
+	"pass":     StatusPassed,
+	"success":  StatusPassed,
+	"ok":       StatusPassed,
+	"fail":     StatusFailed,
+	"failure":  StatusFailed,
+	"skip":     StatusSkipped,
+	"disabled": StatusSkipped,
+	"errored":  StatusError,
+}
+
+// NormalizeStatus returns the canonical status for the given status, looking it up
+// in the given aliases before the default ones. Unknown statuses are returned lowercased.
+func NormalizeStatus(status string, aliases map[string]string) string {
+	status = strings.ToLower(strings.TrimSpace(status))
+
+	if canonical, ok := aliases[status]; ok {
+		return canonical
+	}
+	if canonical, ok := DefaultStatusAliases[status]; ok {
+		return canonical
+	}
+
+	return status
+}
+
+// normalizeStatuses normalizes each of the given statuses.
+func normalizeStatuses(statuses []string, aliases map[string]string) []string {
+	result := make([]string, 0, len(statuses))
+	for _, s := range statuses {
+		result = append(result, NormalizeStatus(s, aliases))
+	}
+
+	return result
+}
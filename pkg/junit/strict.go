@@ -0,0 +1,87 @@
+package junit
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// element lists the attributes and child elements of a JUnit element which are parsed.
+type element struct {
+	attrs    []string
+	children []string
+}
+
+// schema describes the JUnit structure which is parsed. Anything else is dropped
+// silently when parsing, which strict mode turns into an error.
+var schema = map[string]element{
+	"testsuites": {
+		attrs:    []string{"name", "time", "tests", "errors", "failures", "skipped", "disabled"},
+		children: []string{"testsuite"},
+	},
+	"testsuite": {
+		attrs: []string{
+			"name", "tests", "failures", "errors", "id", "disabled", "hostname", "package",
+			"skipped", "time", "timestamp", "file",
+		},
+		children: []string{"properties", "testcase", "system-out", "system-err"},
+	},
+	"properties": {children: []string{"property"}},
+	"property":   {attrs: []string{"name", "value"}},
+	"testcase": {
+		attrs:    []string{"name", "classname", "time", "status", "file"},
+		children: []string{"skipped", "error", "failure", "system-out", "system-err"},
+	},
+	"skipped":    {attrs: []string{"message", "type"}},
+	"error":      {attrs: []string{"message", "type"}},
+	"failure":    {attrs: []string{"message", "type"}},
+	"system-out": {},
+	"system-err": {},
+}
+
+// checkStructure returns an error describing the first element or attribute of the
+// given JUnit document which isn't parsed, and would therefore be dropped silently.
+func checkStructure(data []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	stack := []string{}
+
+	for {
+		tok, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			line, _ := decoder.InputPos()
+			name := t.Name.Local
+
+			if len(stack) == 0 {
+				if name != "testsuites" && name != "testsuite" {
+					return fmt.Errorf("line %d: unknown root element <%s>", line, name)
+				}
+			} else if parent := stack[len(stack)-1]; !slices.Contains(schema[parent].children, name) {
+				return fmt.Errorf("line %d: unexpected element <%s> in <%s>", line, name, parent)
+			}
+
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+					continue
+				}
+				if !slices.Contains(schema[name].attrs, attr.Name.Local) {
+					return fmt.Errorf("line %d: unexpected attribute %q of <%s>", line, attr.Name.Local, name)
+				}
+			}
+
+			stack = append(stack, name)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+}
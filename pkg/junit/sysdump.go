@@ -0,0 +1,152 @@
+package junit
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// sysdumpPath returns the path of the sysdump the given file of an artifact belongs to.
+// Sysdumps are uploaded either as zip archives, or extracted into a directory.
+func sysdumpPath(name string) string {
+	if path.Ext(name) == ".zip" {
+		return name
+	}
+
+	dir, _, ok := strings.Cut(name, "/")
+	if !ok {
+		return "."
+	}
+
+	return dir
+}
+
+// countZip returns the number and uncompressed size of the files in the given zip archive
+// within an artifact. The archive is extracted to a temporary file, since zip archives
+// can't be read from a stream.
+func countZip(fil file) (int, int64, error) {
+	r, err := fil.Open()
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to open %q: %w", filePath(fil), err)
+	}
+	defer r.Close()
+
+	tmpFile, err := os.CreateTemp("", "sysdump-*.zip")
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to create temp file: %w", err)
+	}
+	defer func() {
+		tmpFile.Close()
+		os.Remove(tmpFile.Name())
+	}()
+
+	if _, err := io.Copy(tmpFile, r); err != nil {
+		return 0, 0, fmt.Errorf("unable to extract %q: %w", filePath(fil), err)
+	}
+
+	zipReader, err := zip.OpenReader(tmpFile.Name())
+	if err != nil {
+		return 0, 0, &skipError{
+			reason: SkipReasonMalformed,
+			err:    fmt.Errorf("unable to read %q as zip archive: %w", filePath(fil), err),
+		}
+	}
+	defer zipReader.Close()
+
+	files, bytes := 0, int64(0)
+	for _, f := range zipReader.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		files++
+		bytes += int64(f.UncompressedSize64)
+	}
+
+	return files, bytes, nil
+}
+
+// ParseSysdumps returns the metadata of the sysdumps in the given files of an artifact.
+// Each zip archive is a sysdump, and other files are grouped into sysdumps by their
+// top-level directory. Files which are unsafe to read are skipped, and an IngestError is
+// returned for each of them.
+func ParseSysdumps[F file](
+	ctx context.Context,
+	files []F,
+	run *types.WorkflowRun,
+	artifact *Artifact,
+	opts *Options,
+	l *slog.Logger,
+) ([]types.Sysdump, []types.IngestError, error) {
+	sysdumps := map[string]*types.Sysdump{}
+	ingestErrors := []types.IngestError{}
+
+	sysdump := func(p string) *types.Sysdump {
+		s, ok := sysdumps[p]
+		if !ok {
+			s = &types.Sysdump{WorkflowRun: run, Type: types.TypeNameSysdump, Path: p}
+			if artifact != nil {
+				s.ArtifactID = artifact.ID
+				s.ArtifactName = artifact.Name
+			}
+			sysdumps[p] = s
+		}
+
+		return s
+	}
+
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, fmt.Errorf("stopped reading sysdumps at %s: %w", filePath(f), err)
+		}
+
+		name := filePath(f)
+		err := checkArchiveEntry(f, opts)
+		if err == nil && f.FileInfo().IsDir() {
+			continue
+		}
+
+		p := sysdumpPath(name)
+		if err == nil && p != name {
+			s := sysdump(p)
+			s.Files++
+			s.Bytes += f.FileInfo().Size()
+			continue
+		}
+
+		var count int
+		var size int64
+		if err == nil {
+			count, size, err = countZip(f)
+		}
+
+		var skipErr *skipError
+		if errors.As(err, &skipErr) {
+			l.Warn("Skipping sysdump file", "file", name, "reason", skipErr.reason, "err", skipErr.err)
+			ingestErrors = append(ingestErrors, newIngestError(run, artifact, name, skipErr))
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		s := sysdump(p)
+		s.Files, s.Bytes = count, size
+	}
+
+	result := make([]types.Sysdump, 0, len(sysdumps))
+	for _, p := range slices.Sorted(maps.Keys(sysdumps)) {
+		result = append(result, *sysdumps[p])
+	}
+
+	return result, ingestErrors, nil
+}
@@ -0,0 +1,27 @@
+package junit
+
+import (
+	"slices"
+
+	"github.com/isovalent/corgi/pkg/owners"
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// setTeams sets the team names, Slack channels and Jira components of the teams behind
+// the owners of the given testcase.
+func setTeams(tc *types.Testcase, teams *owners.Teams) {
+	for _, team := range teams.For(tc.Owners) {
+		tc.Teams = appendUnique(tc.Teams, team.Name)
+		tc.SlackChannels = appendUnique(tc.SlackChannels, team.SlackChannel)
+		tc.JiraComponents = appendUnique(tc.JiraComponents, team.JiraComponent)
+	}
+}
+
+// appendUnique appends the given value unless it's empty or already contained.
+func appendUnique(values []string, value string) []string {
+	if value == "" || slices.Contains(values, value) {
+		return values
+	}
+
+	return append(values, value)
+}
@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"expvar"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Stages of the ingestion pipeline.
+const (
+	// StageFetch covers requests made to GitHub, including artifact downloads.
+	StageFetch = "fetch"
+	// StageParse covers parsing of downloaded artifacts.
+	StageParse = "parse"
+	// StageIndex covers writing of bulk requests for OpenSearch.
+	StageIndex = "index"
+)
+
+// Counters of the ingestion pipeline.
+const (
+	CounterRunsIngested     = "runs_ingested"
+	CounterRunsAborted      = "runs_aborted"
+	CounterDocumentsWritten = "documents_written"
+	CounterDocumentsDeleted = "documents_deleted"
+	// CounterDocumentsFiltered counts documents dropped by filters before indexing.
+	CounterDocumentsFiltered = "documents_filtered"
+	CounterIngestErrors      = "ingest_errors"
+	CounterGitHubRequests    = "github_requests"
+)
+
+// The metrics are published through expvar, so they are available under
+// /debug/vars when the pprof server is enabled.
+var (
+	stageCount    = expvar.NewMap("corgi_stage_count")
+	stageDuration = expvar.NewMap("corgi_stage_duration_seconds")
+	queueDepth    = expvar.NewMap("corgi_queue_depth")
+	counters      = expvar.NewMap("corgi_counters")
+	githubRate    = expvar.NewMap("corgi_github_rate")
+)
+
+// StageSummary is the number of operations and total time spent in a stage.
+type StageSummary struct {
+	Operations int64         `json:"operations"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// GitHubRate is the state of the GitHub API rate limit, as of the last response.
+type GitHubRate struct {
+	Limit     int64
+	Remaining int64
+	Used      int64
+}
+
+// TimeStage starts timing an operation in the given stage. The returned function
+// records the elapsed time when first called, further calls are no-ops. This allows
+// it to be deferred while still stopping the timer early in the happy path.
+func TimeStage(stage string) func() {
+	start := time.Now()
+	once := sync.Once{}
+
+	return func() {
+		once.Do(func() {
+			stageCount.Add(stage, 1)
+			stageDuration.AddFloat(stage, time.Since(start).Seconds())
+		})
+	}
+}
+
+// SetQueueDepth records the number of items waiting to be processed in the given queue.
+func SetQueueDepth(queue string, depth int) {
+	v := new(expvar.Int)
+	v.Set(int64(depth))
+	queueDepth.Set(queue, v)
+}
+
+// Add adds delta to the given counter.
+func Add(counter string, delta int64) {
+	counters.Add(counter, delta)
+}
+
+// Count returns the value of the given counter.
+func Count(counter string) int64 {
+	if v, ok := counters.Get(counter).(*expvar.Int); ok {
+		return v.Value()
+	}
+
+	return 0
+}
+
+// SetGitHubRate records the state of the GitHub API rate limit.
+func SetGitHubRate(rate GitHubRate) {
+	for key, value := range map[string]int64{"limit": rate.Limit, "remaining": rate.Remaining, "used": rate.Used} {
+		v := new(expvar.Int)
+		v.Set(value)
+		githubRate.Set(key, v)
+	}
+}
+
+// LastGitHubRate returns the state of the GitHub API rate limit as of the last response,
+// or false if no response was recorded.
+func LastGitHubRate() (GitHubRate, bool) {
+	value := func(key string) int64 {
+		if v, ok := githubRate.Get(key).(*expvar.Int); ok {
+			return v.Value()
+		}
+		return 0
+	}
+
+	if githubRate.Get("limit") == nil {
+		return GitHubRate{}, false
+	}
+
+	return GitHubRate{Limit: value("limit"), Remaining: value("remaining"), Used: value("used")}, true
+}
+
+// Stages returns the number of operations and total time spent in each stage.
+func Stages() map[string]StageSummary {
+	stages := map[string]StageSummary{}
+	for _, stage := range []string{StageFetch, StageParse, StageIndex} {
+		var count int64
+		var seconds float64
+
+		if v, ok := stageCount.Get(stage).(*expvar.Int); ok {
+			count = v.Value()
+		}
+
+		if v, ok := stageDuration.Get(stage).(*expvar.Float); ok {
+			seconds = v.Value()
+		}
+
+		stages[stage] = StageSummary{Operations: count, Duration: time.Duration(seconds * float64(time.Second))}
+	}
+
+	return stages
+}
+
+// LogSummary logs the number of operations and total time spent in each stage.
+func LogSummary(logger *slog.Logger) {
+	stages := Stages()
+	for _, stage := range []string{StageFetch, StageParse, StageIndex} {
+		logger.Info(
+			"Pipeline stage summary",
+			"stage", stage, "operations", stages[stage].Operations,
+			"duration", stages[stage].Duration,
+		)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"strings"
 
+	"github.com/isovalent/corgi/pkg/metrics"
 	"github.com/isovalent/corgi/pkg/types"
 )
 
@@ -14,11 +15,16 @@ type BulkEntry struct {
 	ID    string
 	Verb  string
 	Data  []byte
+
+	// Routing is the routing value of the entry. If empty, it is routed by its ID.
+	Routing string
+	// Pipeline is the ingest pipeline to process the entry with, if set.
+	Pipeline string
 }
 
-func (b *BulkEntry) Write(target io.Writer) {
+func (b *BulkEntry) Write(target io.Writer) error {
 	if b == nil {
-		return
+		return nil
 	}
 
 	builder := strings.Builder{}
@@ -26,13 +32,49 @@ func (b *BulkEntry) Write(target io.Writer) {
 	builder.WriteString(b.Verb)
 	builder.WriteString("\" : { \"_index\": \"")
 	builder.WriteString(b.Index)
-	builder.WriteString("\", \"_id\": \"")
-	builder.WriteString(b.ID)
-	builder.WriteString("\" } }\n")
+	builder.WriteString("\"")
+	if b.ID != "" {
+		builder.WriteString(", \"_id\": \"")
+		builder.WriteString(b.ID)
+		builder.WriteString("\"")
+	}
+	if b.Routing != "" {
+		builder.WriteString(", \"routing\": \"")
+		builder.WriteString(b.Routing)
+		builder.WriteString("\"")
+	}
+	if b.Pipeline != "" {
+		builder.WriteString(", \"pipeline\": \"")
+		builder.WriteString(b.Pipeline)
+		builder.WriteString("\"")
+	}
+	builder.WriteString(" } }\n")
+	builder.Write(b.Data)
+	builder.WriteString("\n")
+
+	// Write the entry with a single call, so writers wrapping the target
+	// can treat each call as one document.
+	if _, err := target.Write([]byte(builder.String())); err != nil {
+		return fmt.Errorf("unable to write bulk entry for index '%s': %w", b.Index, err)
+	}
+
+	return nil
+}
+
+// Flusher is implemented by bulk outputs which buffer entries before writing them on.
+type Flusher interface {
+	// Flush writes the buffered entries on, and flushes the output they are written to.
+	Flush() error
+}
 
-	target.Write([]byte(builder.String()))
-	target.Write(b.Data)
-	target.Write([]byte("\n"))
+// Flush flushes the given bulk output if it buffers entries, so that all entries
+// written to it so far reached their destination.
+func Flush(w io.Writer) error {
+	if f, ok := w.(Flusher); ok {
+		return f.Flush()
+	}
+
+	return nil
 }
 
 func jsonEscapeString(i string) (string, error) {
@@ -69,9 +111,125 @@ func GetDocumentID(obj any) (string, error) {
 		if err != nil {
 			return "", fmt.Errorf("unable to get document id for Testsuite in Testcase: %v", err)
 		}
+		// Testcases which only differ in redacted parts of their names, such as
+		// pod suffixes, have the same name, so the ID is built from the raw name.
+		name := o.Name
+		if o.NameRaw != "" {
+			name = o.NameRaw
+		}
+		name, err = jsonEscapeString(name)
+		if err != nil {
+			return "", fmt.Errorf("unable to get document id for Testcase: %v", err)
+		}
 		return fmt.Sprintf(
 			"%d-%d-%s-%s",
-			o.WorkflowRun.ID, o.WorkflowRun.RunAttempt, junitFilename, o.Name,
+			o.WorkflowRun.ID, o.WorkflowRun.RunAttempt, junitFilename, name,
+		), nil
+	case types.IngestError:
+		junitPath, err := jsonEscapeString(o.JUnitPath)
+		if err != nil {
+			return "", fmt.Errorf("unable to get document id for IngestError: %v", err)
+		}
+		return fmt.Sprintf(
+			"ingest-error-%d-%d-%d-%s",
+			o.WorkflowRun.ID, o.WorkflowRun.RunAttempt, o.ArtifactID, junitPath,
+		), nil
+	case types.Artifact:
+		return fmt.Sprintf("%d-%d-artifact-%d", o.WorkflowRun.ID, o.WorkflowRun.RunAttempt, o.ID), nil
+	case types.CacheUsage:
+		return fmt.Sprintf("cache-usage-%d-%s", o.Repository.ID, o.Timestamp.Format("2006-01-02T15")), nil
+	case types.Triage:
+		testName, err := jsonEscapeString(o.TestName)
+		if err != nil {
+			return "", fmt.Errorf("unable to get document id for triage: %v", err)
+		}
+		return fmt.Sprintf("triage-%s-%s", o.Repository, testName), nil
+	case types.IngestStats:
+		command, err := jsonEscapeString(o.Command)
+		if err != nil {
+			return "", fmt.Errorf("unable to get document id for ingest stats: %v", err)
+		}
+		return fmt.Sprintf("ingest-stats-%s-%s-%d", o.Host, command, o.StartedAt.UnixNano()), nil
+	case types.AuditEvent:
+		command, err := jsonEscapeString(o.Command)
+		if err != nil {
+			return "", fmt.Errorf("unable to get document id for audit event: %v", err)
+		}
+		return fmt.Sprintf("audit-event-%s-%s-%d", o.Host, command, o.StartedAt.UnixNano()), nil
+	case types.TestDuration:
+		workflow, err := jsonEscapeString(o.Workflow)
+		if err != nil {
+			return "", fmt.Errorf("unable to get document id for test duration: %v", err)
+		}
+		testName, err := jsonEscapeString(o.TestName)
+		if err != nil {
+			return "", fmt.Errorf("unable to get document id for test duration: %v", err)
+		}
+		return fmt.Sprintf(
+			"test-duration-%s-%s-%s-%s-%s-%s",
+			o.Repository, o.HeadBranch, o.Since.Format("2006-01-02"), o.Until.Format("2006-01-02"),
+			workflow, testName,
+		), nil
+	case types.TeamFlakiness:
+		return fmt.Sprintf(
+			"team-flakiness-%s-%s-%s-%s",
+			o.Repository, o.Since.Format("2006-01-02"), o.Until.Format("2006-01-02"), o.Owner,
+		), nil
+	case types.FailureCooccurrence:
+		workflow, err := jsonEscapeString(o.Workflow)
+		if err != nil {
+			return "", fmt.Errorf("unable to get document id for failure co-occurrence: %v", err)
+		}
+		testA, err := jsonEscapeString(o.TestA)
+		if err != nil {
+			return "", fmt.Errorf("unable to get document id for failure co-occurrence: %v", err)
+		}
+		testB, err := jsonEscapeString(o.TestB)
+		if err != nil {
+			return "", fmt.Errorf("unable to get document id for failure co-occurrence: %v", err)
+		}
+		return fmt.Sprintf(
+			"failure-cooccurrence-%s-%s-%s-%s-%s-%s-%s",
+			o.Repository, o.HeadBranch, o.Since.Format("2006-01-02"), o.Until.Format("2006-01-02"),
+			workflow, testA, testB,
+		), nil
+	case types.TestCountDrop:
+		suite, err := jsonEscapeString(o.Suite)
+		if err != nil {
+			return "", fmt.Errorf("unable to get document id for test count drop: %v", err)
+		}
+		return fmt.Sprintf("test-count-drop-%d-%d-%s", o.WorkflowRun.ID, o.WorkflowRun.RunAttempt, suite), nil
+	case types.WorkflowStability:
+		workflow, err := jsonEscapeString(o.Workflow)
+		if err != nil {
+			return "", fmt.Errorf("unable to get document id for workflow stability: %v", err)
+		}
+		return fmt.Sprintf(
+			"workflow-stability-%s-%s-%s-%s", o.Repository, o.HeadBranch, o.Until.Format("2006-01-02"), workflow,
+		), nil
+	case types.Sysdump:
+		path, err := jsonEscapeString(o.Path)
+		if err != nil {
+			return "", fmt.Errorf("unable to get document id for Sysdump: %v", err)
+		}
+		return fmt.Sprintf(
+			"sysdump-%d-%d-%d-%s", o.WorkflowRun.ID, o.WorkflowRun.RunAttempt, o.ArtifactID, path,
+		), nil
+	case types.LogSignature:
+		path, err := jsonEscapeString(o.Path)
+		if err != nil {
+			return "", fmt.Errorf("unable to get document id for LogSignature: %v", err)
+		}
+		return fmt.Sprintf(
+			"log-signature-%d-%d-%d-%s-%s", o.WorkflowRun.ID, o.WorkflowRun.RunAttempt, o.ArtifactID, path, o.Signature,
+		), nil
+	case types.FailurePattern:
+		segment, err := jsonEscapeString(o.Segment)
+		if err != nil {
+			return "", fmt.Errorf("unable to get document id for failure pattern: %v", err)
+		}
+		return fmt.Sprintf(
+			"failure-pattern-%s-%s-%s-%s-%s", o.Repository, o.HeadBranch, o.Until.Format("2006-01-02"), o.Dimension, segment,
 		), nil
 	case types.FailureRate:
 		docIdentifier, err := jsonEscapeString(o.DocumentIdentifier)
@@ -89,24 +247,126 @@ func GetDocumentID(obj any) (string, error) {
 	return "", fmt.Errorf("unable to determine document ID for object '%v'", obj)
 }
 
-func BulkWriteObjects[T any](objs []T, index string, target io.Writer) error {
-	for _, obj := range objs {
+// BulkOptions configures how objects are written by BulkWriteObjects.
+type BulkOptions struct {
+	// Routing selects the routing value of each document.
+	Routing Routing
+	// Fields drops fields from documents before they are written, if set.
+	Fields *FieldFilter
+	// RawIndex is the index name template documents are also written to before Fields
+	// drops fields from them, if set, so that the full documents stay available.
+	RawIndex string
+	// Pipeline is the ingest pipeline to process documents with, if set.
+	Pipeline string
+	// IDPrefix is prepended to document IDs, so that documents of multiple
+	// deployments written to the same index don't collide.
+	IDPrefix string
+	// Validator checks documents against the index mapping, if set. Documents
+	// which don't match it are skipped.
+	Validator *Validator
+	// Hooks enrich documents in order before their fields are filtered.
+	Hooks []DocumentHook
+}
+
+// DocumentID returns the ID of the given object, including the configured prefix.
+func (o BulkOptions) DocumentID(obj any) (string, error) {
+	id, err := GetDocumentID(obj)
+	if err != nil {
+		return "", err
+	}
+
+	if o.IDPrefix == "" {
+		return id, nil
+	}
+
+	return o.IDPrefix + "-" + id, nil
+}
+
+// BulkWriteObjects writes bulk entries indexing the given objects into the given index,
+// which may be an index name template, see ResolveIndexName.
+func BulkWriteObjects[T any](objs []T, index string, opts BulkOptions, target io.Writer) error {
+	defer metrics.TimeStage(metrics.StageIndex)()
+
+	omitIDs := OmitDocumentIDs()
+
+	pipeline, err := jsonEscapeString(opts.Pipeline)
+	if err != nil {
+		return fmt.Errorf("unable to escape pipeline: %v", err)
+	}
+
+	docs := make([][]byte, len(objs))
+	for i, obj := range objs {
 		d, err := json.Marshal(obj)
 		if err != nil {
 			return fmt.Errorf("unable to marshal obj '%v': %v", obj, err)
 		}
+		docs[i] = d
+	}
+
+	docs, err = applyHooks(opts.Hooks, index, docs)
+	if err != nil {
+		return fmt.Errorf("unable to enrich documents for index '%s': %w", index, err)
+	}
+
+	for i, obj := range objs {
+		// Documents dropped by the hook are nil.
+		if docs[i] == nil {
+			continue
+		}
 
-		id, err := GetDocumentID(obj)
+		d, err := opts.Fields.Apply(docs[i])
+		if err != nil {
+			return fmt.Errorf("unable to filter fields of obj '%v': %v", obj, err)
+		}
+
+		id, err := opts.DocumentID(obj)
 		if err != nil {
 			return err
 		}
+		if opts.Validator != nil && !opts.Validator.Check(d, id, opts.Fields) {
+			continue
+		}
+		if omitIDs {
+			id = ""
+		}
 
-		(&BulkEntry{
-			Index: index,
+		routingValue, err := jsonEscapeString(GetDocumentRouting(obj, opts.Routing))
+		if err != nil {
+			return fmt.Errorf("unable to get routing for obj '%v': %v", obj, err)
+		}
+
+		err = (&BulkEntry{
+			Index: ResolveIndexName(index, GetDocumentTime(obj)),
 			ID:    id,
 			Verb:  "index",
 			Data:  d,
+
+			Routing:  routingValue,
+			Pipeline: pipeline,
+		}).Write(target)
+		if err != nil {
+			return err
+		}
+
+		metrics.Add(metrics.CounterDocumentsWritten, 1)
+
+		if opts.RawIndex == "" {
+			continue
+		}
+
+		err = (&BulkEntry{
+			Index: ResolveIndexName(opts.RawIndex, GetDocumentTime(obj)),
+			ID:    id,
+			Verb:  "index",
+			Data:  docs[i],
+
+			Routing: routingValue,
 		}).Write(target)
+		if err != nil {
+			return err
+		}
+
+		metrics.Add(metrics.CounterDocumentsWritten, 1)
 	}
 
 	return nil
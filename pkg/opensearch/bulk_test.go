@@ -0,0 +1,67 @@
+package opensearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+func TestGetDocumentIDTestcase(t *testing.T) {
+	suite := &types.Testsuite{
+		WorkflowRun:   &types.WorkflowRun{ID: 1, RunAttempt: 2},
+		JUnitFilename: "junit.xml",
+	}
+
+	// Both names are redacted to the same name.
+	a := types.Testcase{Testsuite: suite, Name: "Test pod <redacted>", NameRaw: "Test pod cilium-abcde"}
+	b := types.Testcase{Testsuite: suite, Name: "Test pod <redacted>", NameRaw: "Test pod cilium-fghij"}
+
+	idA, err := GetDocumentID(a)
+	assert.NoError(t, err)
+	idB, err := GetDocumentID(b)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "1-2-junit.xml-Test pod cilium-abcde", idA)
+	assert.NotEqual(t, idA, idB)
+
+	id, err := GetDocumentID(types.Testcase{Testsuite: suite, Name: `Test "quoted"`})
+	assert.NoError(t, err)
+	assert.Equal(t, `1-2-junit.xml-Test \"quoted\"`, id)
+}
+
+func TestBulkWriteObjectsEscapesPipeline(t *testing.T) {
+	buf := &bytes.Buffer{}
+	run := &types.WorkflowRun{ID: 1, RunAttempt: 1}
+
+	err := BulkWriteObjects([]*types.WorkflowRun{run}, "runs", BulkOptions{Pipeline: `embed"\`}, buf)
+	assert.NoError(t, err)
+
+	action, _, _ := strings.Cut(buf.String(), "\n")
+	meta := map[string]map[string]string{}
+	assert.NoError(t, json.Unmarshal([]byte(action), &meta))
+	assert.Equal(t, `embed"\`, meta["index"]["pipeline"])
+}
+
+func TestBulkWriteObjectsRawIndex(t *testing.T) {
+	buf := &bytes.Buffer{}
+	run := &types.WorkflowRun{ID: 1, RunAttempt: 1, Type: types.TypeNameWorkflowRun, Name: "CI"}
+
+	fields, err := ParseFieldFilter(nil, []string{"workflow_name"})
+	assert.NoError(t, err)
+
+	err = BulkWriteObjects([]*types.WorkflowRun{run}, "runs", BulkOptions{Fields: fields, RawIndex: "raw"}, buf)
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if assert.Len(t, lines, 4) {
+		assert.Contains(t, lines[0], `"_index": "runs"`)
+		assert.NotContains(t, lines[1], `"workflow_name"`)
+		assert.Contains(t, lines[2], `"_index": "raw"`)
+		assert.Contains(t, lines[3], `"workflow_name":"CI"`)
+	}
+}
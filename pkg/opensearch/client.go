@@ -4,22 +4,87 @@ import (
 	"crypto/tls"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/opensearch-project/opensearch-go"
+
+	"github.com/isovalent/corgi/pkg/util"
 )
 
-func NewClientConfig() opensearch.Config {
+// CollectionTypeTimeSeries is the type of OpenSearch Serverless collections which
+// don't support custom document IDs.
+const CollectionTypeTimeSeries = "timeseries"
+
+// IsServerless returns true if OPENSEARCH_SERVERLESS is set, in which case APIs which
+// Amazon OpenSearch Serverless doesn't support are avoided.
+func IsServerless() bool {
+	return os.Getenv("OPENSEARCH_SERVERLESS") != ""
+}
+
+// OmitDocumentIDs returns true if documents should be written without IDs, which is
+// required for time series collections of Amazon OpenSearch Serverless, as configured
+// through OPENSEARCH_COLLECTION_TYPE. Note that documents can then no longer be
+// updated when they are ingested again.
+func OmitDocumentIDs() bool {
+	return IsServerless() && strings.EqualFold(os.Getenv("OPENSEARCH_COLLECTION_TYPE"), CollectionTypeTimeSeries)
+}
+
+// basicAuthTransport authenticates requests with the current value of the password,
+// so that a reloaded password is used without recreating the client.
+type basicAuthTransport struct {
+	base http.RoundTripper
+	user string
+	pass *util.Secret
+}
+
+func (t *basicAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.user == "" {
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.SetBasicAuth(t.user, t.pass.Value())
+
+	return t.base.RoundTrip(req)
+}
+
+// NewClientConfig returns the configuration of the OpenSearch client from the environment.
+// The password is read from OPENSEARCH_PASS, or from the file named by OPENSEARCH_PASS_FILE.
+func NewClientConfig() (opensearch.Config, error) {
 	insecureSkipVerify := os.Getenv("OPENSEARCH_TLS_INSECURE") != ""
 	address := os.Getenv("OPENSEARCH_URL")
 	user := os.Getenv("OPENSEARCH_USER")
-	pass := os.Getenv("OPENSEARCH_PASS")
+
+	pass, err := util.NewSecret("OPENSEARCH_PASS")
+	if err != nil {
+		return opensearch.Config{}, err
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: insecureSkipVerify},
+	}
+
+	if IsServerless() {
+		region := os.Getenv("AWS_REGION")
+		if region == "" {
+			region = os.Getenv("AWS_DEFAULT_REGION")
+		}
+
+		return opensearch.Config{
+			Transport: transport,
+			Addresses: []string{address},
+			Signer: &AWSSigner{
+				Region:       region,
+				Service:      "aoss",
+				AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+				SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+				SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+			},
+		}, nil
+	}
 
 	return opensearch.Config{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: insecureSkipVerify},
-		},
+		Transport: &basicAuthTransport{base: transport, user: user, pass: pass},
 		Addresses: []string{address},
-		Username:  user,
-		Password:  pass,
-	}
+	}, nil
 }
@@ -0,0 +1,198 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/opensearch-project/opensearch-go/opensearchapi"
+
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+// GetDocument returns the source of the document with the given ID and routing value in
+// the given index. If the document or the index does not exist, nil is returned without an error.
+func GetDocument(ctx context.Context, client *opensearchgo.Client, index, id, routing string) (map[string]any, error) {
+	if IsServerless() || strings.Contains(index, "*") {
+		// Getting documents by ID isn't supported by all collection types, nor for
+		// index patterns.
+		return searchDocument(ctx, client, index, id, routing)
+	}
+
+	resp, err := doGenericRequest(ctx, client, &opensearchapi.GetRequest{
+		Index:      index,
+		DocumentID: id,
+		Routing:    routing,
+	})
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to get document %s from index %s: %w", id, index, err)
+	}
+
+	if found, _ := resp["found"].(bool); !found {
+		return nil, nil
+	}
+
+	source, ok := resp["_source"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("key '_source' in response for document %s is not of type map[string]any", id)
+	}
+
+	return source, nil
+}
+
+// searchDocument returns the source of the document with the given ID in the given index,
+// using the search API. If the document or the index does not exist, nil is returned.
+func searchDocument(ctx context.Context, client *opensearchgo.Client, index, id, routing string) (map[string]any, error) {
+	query, err := json.Marshal(map[string]any{
+		"size":  1,
+		"query": map[string]any{"ids": map[string]any{"values": []string{id}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal query for document %s: %w", id, err)
+	}
+
+	req := &opensearchapi.SearchRequest{
+		Index: []string{index},
+		Body:  bytes.NewReader(query),
+	}
+	if routing != "" {
+		req.Routing = []string{routing}
+	}
+
+	resp, err := doGenericRequest(ctx, client, req)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to search for document %s in index %s: %w", id, index, err)
+	}
+
+	hits, err := util.TraverseUnstructured("hits.hits", resp)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get hits for document %s: %w", id, err)
+	}
+
+	hitList, ok := hits.([]any)
+	if !ok || len(hitList) == 0 {
+		return nil, nil
+	}
+
+	hit, ok := hitList[0].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("hit for document %s is not of type map[string]any", id)
+	}
+
+	source, ok := hit["_source"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("key '_source' in hit for document %s is not of type map[string]any", id)
+	}
+
+	return source, nil
+}
+
+// IndexDocument writes the given document with the given ID into the given index.
+func IndexDocument(ctx context.Context, client *opensearchgo.Client, index, id string, doc any) error {
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("unable to marshal document %s: %w", id, err)
+	}
+
+	if _, err := doGenericRequest(ctx, client, &opensearchapi.IndexRequest{
+		Index:      index,
+		DocumentID: id,
+		Body:       bytes.NewReader(body),
+	}); err != nil {
+		return fmt.Errorf("unable to index document %s into index %s: %w", id, index, err)
+	}
+
+	return nil
+}
+
+// DeleteDocument deletes the document with the given ID from the given index.
+func DeleteDocument(ctx context.Context, client *opensearchgo.Client, index, id string) error {
+	if _, err := doGenericRequest(ctx, client, &opensearchapi.DeleteRequest{
+		Index:      index,
+		DocumentID: id,
+	}); err != nil {
+		return fmt.Errorf("unable to delete document %s from index %s: %w", id, index, err)
+	}
+
+	return nil
+}
+
+// DeleteByQuery deletes the documents matching the given query from the given index,
+// which may be a comma-separated list of index patterns, and returns the number of
+// deleted documents. Indices which don't exist are ignored.
+func DeleteByQuery(ctx context.Context, client *opensearchgo.Client, index string, query map[string]any) (int, error) {
+	if IsServerless() {
+		return 0, errors.New("deleting by query is not supported by serverless collections")
+	}
+
+	body, err := json.Marshal(map[string]any{"query": query})
+	if err != nil {
+		return 0, fmt.Errorf("unable to marshal query: %w", err)
+	}
+
+	resp, err := doGenericRequest(ctx, client, &opensearchapi.DeleteByQueryRequest{
+		Index:          strings.Split(index, ","),
+		Body:           bytes.NewReader(body),
+		AllowNoIndices: opensearchapi.BoolPtr(true),
+		Conflicts:      "proceed",
+		Refresh:        opensearchapi.BoolPtr(true),
+	})
+	if errors.Is(err, ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("unable to delete documents from index %s: %w", index, err)
+	}
+
+	deleted, _ := resp["deleted"].(float64)
+
+	return int(deleted), nil
+}
+
+// PutMapping adds the fields of the given mapping, such as opensearch/mappings.json, to
+// the given indices, which may be a comma-separated list of index patterns. Fields which
+// are already mapped differently are rejected by OpenSearch. Indices which don't exist
+// are ignored.
+func PutMapping(ctx context.Context, client *opensearchgo.Client, index string, mapping []byte) error {
+	if _, err := doGenericRequest(ctx, client, &opensearchapi.IndicesPutMappingRequest{
+		Index:          strings.Split(index, ","),
+		Body:           bytes.NewReader(mapping),
+		AllowNoIndices: opensearchapi.BoolPtr(true),
+	}); err != nil {
+		return fmt.Errorf("unable to put mapping of index %s: %w", index, err)
+	}
+
+	return nil
+}
+
+// Ping verifies that the cluster can be reached with the configured credentials. Serverless
+// collections don't provide cluster information, so a search on the given index is used instead.
+func Ping(ctx context.Context, client *opensearchgo.Client, index string) error {
+	if IsServerless() {
+		_, err := doGenericRequest(ctx, client, &opensearchapi.SearchRequest{
+			Index: []string{index},
+			Size:  opensearchapi.IntPtr(0),
+		})
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("unable to search index %s: %w", index, err)
+		}
+
+		return nil
+	}
+
+	if _, err := doGenericRequest(ctx, client, &opensearchapi.InfoRequest{}); err != nil {
+		return fmt.Errorf("unable to get cluster info: %w", err)
+	}
+
+	return nil
+}
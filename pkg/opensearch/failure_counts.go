@@ -57,6 +57,12 @@ type DocumentCountQueryTemplateParams struct {
 	// ConclusionFailure determines the name of a failed conclusion in the document, for
 	// example "failure".
 	ConclusionFailure string
+	// TerminationReasonField determines the field which holds the document's termination
+	// reason, for example "workflow_termination_reason".
+	TerminationReasonField string
+	// TerminationReasonExcluded is the termination reason of documents which are not
+	// counted at all, for example "superseded".
+	TerminationReasonExcluded string
 }
 
 func NewDocumentCountQueryTemplateParams(
@@ -70,17 +76,21 @@ func NewDocumentCountQueryTemplateParams(
 ) (*DocumentCountQueryTemplateParams, error) {
 	var groupByField string
 	var conclusionField string
+	var terminationReasonField string
 
 	switch typ {
 	case types.TypeNameWorkflowRun:
 		groupByField = "workflow_name.keyword"
 		conclusionField = "workflow_conclusion"
+		terminationReasonField = "workflow_termination_reason"
 	case types.TypeNameJobRun:
 		groupByField = "job_name.keyword"
 		conclusionField = "job_conclusion"
+		terminationReasonField = "job_termination_reason"
 	case types.TypeNameStepRun:
 		groupByField = "step_name.keyword"
 		conclusionField = "step_conclusion"
+		terminationReasonField = "job_termination_reason"
 	default:
 		return nil, fmt.Errorf("unknown document type: %s", typ)
 	}
@@ -96,6 +106,10 @@ func NewDocumentCountQueryTemplateParams(
 		ConclusionFailure: "failure",
 		GroupByField:      groupByField,
 		ConclusionField:   conclusionField,
+		// Runs cancelled by newer runs, such as after a force-push, say nothing
+		// about the reliability of the workflow.
+		TerminationReasonField:    terminationReasonField,
+		TerminationReasonExcluded: types.TerminationReasonSuperseded,
 	}, nil
 }
 
@@ -121,6 +135,8 @@ const (
 					{ "term": { "head_branch.keyword": "{{ .Branch }}" } },
 					{ "term": { "repository.full_name.keyword": "{{ .Repository }}" } },
 					{ "term": { "event.keyword": "{{ .Event }}" } }
+				], "must_not": [
+					{ "term": { "{{ .TerminationReasonField }}": "{{ .TerminationReasonExcluded }}" } }
 				] } },
 				"aggs": {
 					"total": { "terms": {
@@ -0,0 +1,106 @@
+package opensearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// FieldFilter drops fields from documents before they are written, to control
+// the size of indices and which data is exposed through them.
+type FieldFilter struct {
+	// allow and deny hold the top-level fields to keep or drop per document
+	// type. Fields under the empty type apply to every document type.
+	allow map[string]map[string]struct{}
+	deny  map[string]map[string]struct{}
+}
+
+func parseFieldList(fields []string) (map[string]map[string]struct{}, error) {
+	parsed := map[string]map[string]struct{}{}
+
+	for _, f := range fields {
+		typ, field, ok := strings.Cut(f, "=")
+		if !ok {
+			typ, field = "", f
+		}
+		if field == "" {
+			return nil, fmt.Errorf("field in '%s' is empty, expected [<type>=]<field>", f)
+		}
+
+		if parsed[typ] == nil {
+			parsed[typ] = map[string]struct{}{}
+		}
+		parsed[typ][field] = struct{}{}
+	}
+
+	return parsed, nil
+}
+
+// ParseFieldFilter parses the given allowed and denied fields, each of the form
+// [<type>=]<field>, such as test_case=test_case_failure_text. If fields are allowed
+// for a document type, all other fields are dropped from documents of that type,
+// apart from the type itself.
+func ParseFieldFilter(allow, deny []string) (*FieldFilter, error) {
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil, nil
+	}
+
+	allowed, err := parseFieldList(allow)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse allowed fields: %w", err)
+	}
+
+	denied, err := parseFieldList(deny)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse denied fields: %w", err)
+	}
+
+	return &FieldFilter{allow: allowed, deny: denied}, nil
+}
+
+func (f *FieldFilter) contains(fields map[string]map[string]struct{}, typ, field string) bool {
+	if _, ok := fields[""][field]; ok {
+		return true
+	}
+
+	_, ok := fields[typ][field]
+	return ok
+}
+
+// Apply drops the filtered fields from the given marshalled document.
+func (f *FieldFilter) Apply(doc []byte) ([]byte, error) {
+	if f == nil {
+		return doc, nil
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(doc, &fields); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal document to filter its fields: %w", err)
+	}
+
+	typ := ""
+	if raw, ok := fields["type"]; ok {
+		if err := json.Unmarshal(raw, &typ); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal type of document: %w", err)
+		}
+	}
+
+	for field := range fields {
+		if f.Drops(typ, field) {
+			delete(fields, field)
+		}
+	}
+
+	return json.Marshal(fields)
+}
+
+// Drops returns true if the given field is dropped from documents of the given type.
+func (f *FieldFilter) Drops(typ, field string) bool {
+	if f == nil || field == "type" {
+		return false
+	}
+
+	hasAllowed := len(f.allow[""]) > 0 || len(f.allow[typ]) > 0
+
+	return f.contains(f.deny, typ, field) || (hasAllowed && !f.contains(f.allow, typ, field))
+}
@@ -0,0 +1,32 @@
+package opensearch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldFilter(t *testing.T) {
+	f, err := ParseFieldFilter(
+		[]string{"test_case=test_case_name", "test_case=workflow_id"},
+		[]string{"test_case_failure_text", "job_run=job_name"},
+	)
+	assert.NoError(t, err)
+
+	doc, err := f.Apply([]byte(`{"type":"test_case","test_case_name":"a","test_case_failure_text":"b","workflow_id":1}`))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type":"test_case","test_case_name":"a","workflow_id":1}`, string(doc))
+
+	doc, err = f.Apply([]byte(`{"type":"job_run","job_name":"a","job_id":1}`))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type":"job_run","job_id":1}`, string(doc))
+
+	none, err := ParseFieldFilter(nil, nil)
+	assert.NoError(t, err)
+	doc, err = none.Apply([]byte(`{"type":"job_run"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"job_run"}`, string(doc))
+
+	_, err = ParseFieldFilter([]string{"test_case="}, nil)
+	assert.Error(t, err)
+}
@@ -4,13 +4,23 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 
 	opensearchgo "github.com/opensearch-project/opensearch-go"
 	"github.com/opensearch-project/opensearch-go/opensearchapi"
 )
 
+var (
+	// ErrNotFound is returned when OpenSearch responds with a 404 status code.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when OpenSearch responds with a 409 status code, such as when
+	// a document was changed concurrently.
+	ErrConflict = errors.New("conflict")
+)
+
 func doGenericRequest(ctx context.Context, client *opensearchgo.Client, req opensearchapi.Request) (map[string]any, error) {
 	resp, err := req.Do(ctx, client)
 	if err != nil {
@@ -26,6 +36,14 @@ func doGenericRequest(ctx context.Context, client *opensearchgo.Client, req open
 
 	body := bodyBuf.Bytes()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, body)
+	}
+
+	if resp.StatusCode == http.StatusConflict {
+		return nil, fmt.Errorf("%w: %s", ErrConflict, body)
+	}
+
 	if resp.IsError() {
 		return nil, fmt.Errorf("unexpected error in response from OpenSearch: %s", body)
 	}
@@ -0,0 +1,127 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+// DocumentHook enriches marshalled documents before they are written, returning them
+// in the same order. Documents it drops are nil.
+type DocumentHook interface {
+	Apply(index string, docs [][]byte) ([][]byte, error)
+}
+
+// EncodeHookInput encodes the given documents for a hook, one per line.
+func EncodeHookInput(docs [][]byte) []byte {
+	return append(bytes.Join(docs, []byte("\n")), '\n')
+}
+
+// DecodeHookOutput decodes the documents written back by a hook, one per line, which
+// must be as many as the hook was given. Lines with null are returned as nil documents.
+func DecodeHookOutput(output []byte, expected int) ([][]byte, error) {
+	lines := bytes.Split(bytes.TrimSuffix(output, []byte("\n")), []byte("\n"))
+	if len(lines) != expected {
+		return nil, fmt.Errorf("hook returned %d documents, expected %d", len(lines), expected)
+	}
+
+	docs := make([][]byte, len(lines))
+	for i, line := range lines {
+		line = bytes.TrimSpace(line)
+		if string(line) == "null" {
+			continue
+		}
+
+		doc := map[string]json.RawMessage{}
+		if err := json.Unmarshal(line, &doc); err != nil {
+			return nil, fmt.Errorf("hook returned invalid document on line %d: %w", i+1, err)
+		}
+		docs[i] = line
+	}
+
+	return docs, nil
+}
+
+// applyHooks applies the given hooks in order to the documents which weren't dropped yet.
+func applyHooks(hooks []DocumentHook, index string, docs [][]byte) ([][]byte, error) {
+	for _, h := range hooks {
+		kept := make([][]byte, 0, len(docs))
+		for _, d := range docs {
+			if d != nil {
+				kept = append(kept, d)
+			}
+		}
+
+		enriched, err := h.Apply(index, kept)
+		if err != nil {
+			return nil, err
+		}
+
+		j := 0
+		for i := range docs {
+			if docs[i] != nil {
+				docs[i] = enriched[j]
+				j++
+			}
+		}
+	}
+
+	return docs, nil
+}
+
+// ExecHook enriches documents with an external command before they are written, so that
+// custom enrichment doesn't require changes to corgi. The command is run once per batch of
+// documents, which are written to its stdin as JSON, one document per line. It must write
+// the modified documents to its stdout in the same order, one per line, or null for
+// documents to drop. The index the documents are written into is passed in CORGI_INDEX.
+type ExecHook struct {
+	name    string
+	args    []string
+	timeout time.Duration
+}
+
+// NewExecHook creates a hook running the given command, split into its arguments at
+// whitespace. Nil is returned if the command is empty.
+func NewExecHook(command string, timeout time.Duration) (*ExecHook, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	if _, err := exec.LookPath(fields[0]); err != nil {
+		return nil, fmt.Errorf("unable to find exec hook command: %w", err)
+	}
+
+	return &ExecHook{name: fields[0], args: fields[1:], timeout: timeout}, nil
+}
+
+// Apply pipes the given marshalled documents through the command, returning the
+// documents it wrote back. Documents it dropped are nil.
+func (h *ExecHook) Apply(index string, docs [][]byte) ([][]byte, error) {
+	if h == nil || len(docs) == 0 {
+		return docs, nil
+	}
+
+	ctx, cancel := util.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+
+	stdout, stderr := bytes.Buffer{}, bytes.Buffer{}
+	cmd := exec.CommandContext(ctx, h.name, h.args...)
+	cmd.Env = append(os.Environ(), "CORGI_INDEX="+index)
+	cmd.Stdin = bytes.NewReader(EncodeHookInput(docs))
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("exec hook failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return DecodeHookOutput(stdout.Bytes(), len(docs))
+}
@@ -0,0 +1,60 @@
+package opensearch
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+func TestExecHook(t *testing.T) {
+	none, err := NewExecHook(" ", time.Minute)
+	assert.NoError(t, err)
+	docs, err := none.Apply("index", [][]byte{[]byte(`{"a":1}`)})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte(`{"a":1}`)}, docs)
+
+	_, err = NewExecHook("corgi-missing-hook", time.Minute)
+	assert.Error(t, err)
+
+	h, err := NewExecHook(`sed -e s/"team":"a"/"team":"sig-a"/ -e s/.*"drop":true.*/null/`, time.Minute)
+	assert.NoError(t, err)
+	docs, err = h.Apply("index", [][]byte{
+		[]byte(`{"team":"a"}`),
+		[]byte(`{"team":"b","drop":true}`),
+		[]byte(`{"team":"c"}`),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte(`{"team":"sig-a"}`), nil, []byte(`{"team":"c"}`)}, docs)
+
+	h, err = NewExecHook("head -n 1", time.Minute)
+	assert.NoError(t, err)
+	_, err = h.Apply("index", [][]byte{[]byte(`{}`), []byte(`{}`)})
+	assert.ErrorContains(t, err, "hook returned 1 documents, expected 2")
+
+	h, err = NewExecHook("sh -c exit", time.Minute)
+	assert.NoError(t, err)
+	_, err = h.Apply("index", [][]byte{[]byte(`{}`)})
+	assert.Error(t, err)
+}
+
+func TestBulkWriteObjectsExecHook(t *testing.T) {
+	h, err := NewExecHook(`sed -e s/"job_name":"lint"/"job_name":"lint","team":"sig-ci"/ -e s/.*"build".*/null/`, time.Minute)
+	assert.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	run := &types.WorkflowRun{ID: 42, RunAttempt: 1}
+	jobs := []types.JobRun{
+		{WorkflowRun: run, Type: types.TypeNameJobRun, ID: 1, Name: "lint"},
+		{WorkflowRun: run, Type: types.TypeNameJobRun, ID: 2, Name: "build"},
+	}
+	assert.NoError(t, BulkWriteObjects(jobs, "jobs", BulkOptions{Hooks: []DocumentHook{h}}, &buf))
+
+	assert.Contains(t, buf.String(), `"team":"sig-ci"`)
+	assert.NotContains(t, buf.String(), `"build"`)
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+}
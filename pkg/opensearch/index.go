@@ -0,0 +1,88 @@
+package opensearch
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// reIndexDateMath matches the date-math placeholders of an index name template,
+// such as the {yyyy.MM} in corgi-testcases-{yyyy.MM}.
+var reIndexDateMath = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// indexDateFormat translates the date format of index name templates, which
+// follows the Java date formats used by OpenSearch, into a Go time layout.
+var indexDateFormat = strings.NewReplacer(
+	"yyyy", "2006",
+	"yy", "06",
+	"MM", "01",
+	"dd", "02",
+	"HH", "15",
+)
+
+// ResolveIndexName resolves the date-math placeholders in the given index name
+// template using the given time in UTC. Index names without placeholders are
+// returned as-is.
+func ResolveIndexName(template string, t time.Time) string {
+	return reIndexDateMath.ReplaceAllStringFunc(template, func(placeholder string) string {
+		format := placeholder[1 : len(placeholder)-1]
+		return t.UTC().Format(indexDateFormat.Replace(format))
+	})
+}
+
+// IndexPattern returns a pattern matching every index the given index name
+// template resolves to, for use in search requests.
+func IndexPattern(template string) string {
+	return reIndexDateMath.ReplaceAllString(template, "*")
+}
+
+// GetDocumentTime returns the event time of the given object, which is used to
+// resolve index name templates.
+func GetDocumentTime(obj any) time.Time {
+	switch o := obj.(type) {
+	case *types.WorkflowRun:
+		return o.CreatedAt
+	case types.JobRun:
+		return o.CreatedAt
+	case types.StepRun:
+		return o.StartedAt
+	case types.Testsuite:
+		return o.WorkflowRun.CreatedAt
+	case types.Testcase:
+		return o.WorkflowRun.CreatedAt
+	case types.IngestError:
+		return o.WorkflowRun.CreatedAt
+	case types.Sysdump:
+		return o.WorkflowRun.CreatedAt
+	case types.LogSignature:
+		return o.WorkflowRun.CreatedAt
+	case types.Artifact:
+		return o.CreatedAt
+	case types.CacheUsage:
+		return o.Timestamp
+	case types.FailureRate:
+		return o.Until
+	case types.TestDuration:
+		return o.Until
+	case types.TeamFlakiness:
+		return o.Until
+	case types.FailureCooccurrence:
+		return o.Until
+	case types.TestCountDrop:
+		return o.WorkflowRun.CreatedAt
+	case types.WorkflowStability:
+		return o.Until
+	case types.FailurePattern:
+		return o.Until
+	case types.Triage:
+		return o.Timestamp
+	case types.IngestStats:
+		return o.StartedAt
+	case types.AuditEvent:
+		return o.StartedAt
+	}
+
+	return time.Now()
+}
@@ -0,0 +1,33 @@
+package opensearch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+func TestResolveIndexName(t *testing.T) {
+	ts := time.Date(2024, time.March, 7, 13, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, "runs", ResolveIndexName("runs", ts))
+	assert.Equal(t, "corgi-testcases-2024.03", ResolveIndexName("corgi-testcases-{yyyy.MM}", ts))
+	assert.Equal(t, "corgi-24-03-07-13", ResolveIndexName("corgi-{yy-MM-dd-HH}", ts))
+	assert.Equal(t, "corgi-testcases-*", IndexPattern("corgi-testcases-{yyyy.MM}"))
+}
+
+func TestTenantIndex(t *testing.T) {
+	assert.NoError(t, ValidateTenant("team_a1"))
+	assert.Error(t, ValidateTenant("Team"))
+	assert.Error(t, ValidateTenant("_a"))
+	assert.Error(t, ValidateTenant("a/b"))
+	// Otherwise the indices of team-a would match the pattern of team.
+	assert.Error(t, ValidateTenant("team-a"))
+
+	assert.Equal(t, "corgi-team_a-testcases-{yyyy.MM}", TenantIndex("team_a", types.TypeNameTestcase))
+	assert.Equal(t, "corgi-team_a-ingeststats-{yyyy.MM}", TenantIndex("team_a", types.TypeNameIngestStats))
+	assert.Equal(t, "corgi-team_a-*", TenantIndexPattern("team_a"))
+	assert.Equal(t, "runs-team_a-{yyyy}", ExpandTenant("runs-{tenant}-{yyyy}", "team_a"))
+}
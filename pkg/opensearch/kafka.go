@@ -0,0 +1,223 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// maxKafkaAttempts is how often a batch is published before it is dropped.
+	maxKafkaAttempts = 5
+
+	// kafkaContentType is the content type of produce requests with JSON records of
+	// the Kafka REST Proxy v2 API.
+	kafkaContentType = "application/vnd.kafka.json.v2+json"
+)
+
+// KafkaRecord is a record published to Kafka.
+type KafkaRecord struct {
+	Key   string          `json:"key,omitempty"`
+	Value json.RawMessage `json:"value"`
+}
+
+// KafkaWriter passes bulk entries through to a target writer, while also publishing the
+// documents they contain to Kafka through a Kafka REST Proxy, so that other consumers
+// such as data lakes or stream processors can subscribe to CI events. Documents are
+// published to one topic per document type, keyed by the ID of their workflow run so
+// that the documents of a run end up in the same partition, or by their document ID if
+// they don't belong to a run.
+type KafkaWriter struct {
+	target      io.Writer
+	logger      *slog.Logger
+	url         string
+	topicPrefix string
+	batchDocs   int
+	backoff     time.Duration
+	httpClient  *http.Client
+
+	batches map[string][]KafkaRecord
+	dropped int
+}
+
+// NewKafkaWriter creates a new KafkaWriter publishing batches of up to batchDocs documents
+// per topic to the REST Proxy at restURL, while writing all entries to target. Topics are
+// named after the type of the documents, prefixed with topicPrefix. Pass io.Discard as the
+// target to publish documents to Kafka instead of OpenSearch.
+func NewKafkaWriter(target io.Writer, logger *slog.Logger, restURL, topicPrefix string, batchDocs int) *KafkaWriter {
+	return &KafkaWriter{
+		target:      target,
+		logger:      logger.With("kafka", redactURL(restURL)),
+		url:         strings.TrimSuffix(restURL, "/"),
+		topicPrefix: topicPrefix,
+		batchDocs:   max(1, batchDocs),
+		backoff:     time.Second,
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+		batches:     map[string][]KafkaRecord{},
+	}
+}
+
+func (w *KafkaWriter) Write(p []byte) (int, error) {
+	n, err := w.target.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	action, doc, ok := bytes.Cut(bytes.TrimSuffix(p, []byte("\n")), []byte("\n"))
+	if !ok {
+		return n, nil
+	}
+
+	fields := struct {
+		Type       string      `json:"type"`
+		WorkflowID json.Number `json:"workflow_id"`
+	}{}
+	if err := json.Unmarshal(doc, &fields); err != nil || fields.Type == "" {
+		w.logger.Warn("Unable to get type of document, not publishing it to Kafka", "err", err)
+		return n, nil
+	}
+
+	key := fields.WorkflowID.String()
+	if key == "" {
+		meta := map[string]struct {
+			ID string `json:"_id"`
+		}{}
+		if err := json.Unmarshal(action, &meta); err == nil {
+			for _, m := range meta {
+				key = m.ID
+			}
+		}
+	}
+
+	topic := w.topicPrefix + fields.Type
+	w.batches[topic] = append(w.batches[topic], KafkaRecord{Key: key, Value: bytes.Clone(doc)})
+
+	if len(w.batches[topic]) >= w.batchDocs {
+		w.flush(topic)
+	}
+
+	return n, nil
+}
+
+// Flush publishes the buffered documents and flushes the target. An error is returned
+// if any of the documents could not be published.
+func (w *KafkaWriter) Flush() error {
+	dropped := w.dropped
+	w.flushAll()
+	if w.dropped > dropped {
+		return fmt.Errorf("unable to publish %d documents to Kafka", w.dropped-dropped)
+	}
+
+	return Flush(w.target)
+}
+
+// Close publishes the remaining documents. An error is returned if any documents could
+// not be published. The target is not closed.
+func (w *KafkaWriter) Close() error {
+	w.flushAll()
+
+	if w.dropped > 0 {
+		return fmt.Errorf("unable to publish %d documents to Kafka", w.dropped)
+	}
+
+	return nil
+}
+
+func (w *KafkaWriter) flushAll() {
+	topics := make([]string, 0, len(w.batches))
+	for topic := range w.batches {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	for _, topic := range topics {
+		w.flush(topic)
+	}
+}
+
+func (w *KafkaWriter) flush(topic string) {
+	records := w.batches[topic]
+	delete(w.batches, topic)
+	if len(records) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(map[string]any{"records": records})
+	if err != nil {
+		w.logger.Error("Unable to marshal Kafka batch, dropping it", "topic", topic, "err", err)
+		w.dropped += len(records)
+		return
+	}
+
+	for attempt := 1; attempt <= maxKafkaAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(w.backoff << (attempt - 2))
+		}
+
+		if err = w.produce(topic, body); err == nil {
+			return
+		}
+		w.logger.Warn("Unable to publish batch to Kafka", "topic", topic, "attempt", attempt, "err", err)
+	}
+
+	w.logger.Error("Dropping batch which couldn't be published to Kafka", "topic", topic, "documents", len(records), "err", err)
+	w.dropped += len(records)
+}
+
+func (w *KafkaWriter) produce(topic string, body []byte) error {
+	req, err := http.NewRequestWithContext(
+		context.Background(), http.MethodPost, w.url+"/topics/"+url.PathEscape(topic), bytes.NewReader(body),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", kafkaContentType)
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Records can fail individually even if the request succeeded, which is reported
+	// through the error code of their offsets.
+	result := struct {
+		Offsets []struct {
+			ErrorCode *int   `json:"error_code"`
+			Error     string `json:"error"`
+		} `json:"offsets"`
+		Message string `json:"message"`
+	}{}
+	_ = json.NewDecoder(resp.Body).Decode(&result)
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, result.Message)
+	}
+
+	for _, offset := range result.Offsets {
+		if offset.ErrorCode != nil {
+			return fmt.Errorf("record was rejected with error code %d: %s", *offset.ErrorCode, offset.Error)
+		}
+	}
+
+	return nil
+}
+
+// redactURL removes credentials from the given URL, so that it can be logged.
+func redactURL(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return ""
+	}
+
+	return parsed.Redacted()
+}
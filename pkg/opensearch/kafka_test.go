@@ -0,0 +1,72 @@
+package opensearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKafkaWriter(t *testing.T) {
+	topics := map[string][][]KafkaRecord{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, kafkaContentType, r.Header.Get("Content-Type"))
+
+		body := struct {
+			Records []KafkaRecord `json:"records"`
+		}{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		topic := r.URL.Path[len("/topics/"):]
+		topics[topic] = append(topics[topic], body.Records)
+
+		fmt.Fprint(w, `{"offsets": [{"partition": 0, "offset": 1}]}`)
+	}))
+	defer server.Close()
+
+	target := &bytes.Buffer{}
+	w := NewKafkaWriter(target, slog.New(slog.NewTextHandler(io.Discard, nil)), server.URL, "corgi.", 2)
+
+	for i, doc := range []string{
+		`{"type":"test_case","workflow_id":1}`,
+		`{"type":"workflow_run","workflow_id":1}`,
+		`{"type":"test_case","workflow_id":2}`,
+		`{"type":"triage"}`,
+	} {
+		entry := &BulkEntry{Index: "runs", ID: string(rune('a' + i)), Verb: "index", Data: []byte(doc)}
+		assert.NoError(t, entry.Write(w))
+	}
+
+	// The batch of test cases is full, the others are only published on close.
+	assert.Len(t, topics, 1)
+	assert.NoError(t, w.Close())
+
+	assert.Equal(t, 4, bytes.Count(target.Bytes(), []byte(`"_index"`)))
+	assert.Equal(t, [][]KafkaRecord{{
+		{Key: "1", Value: json.RawMessage(`{"type":"test_case","workflow_id":1}`)},
+		{Key: "2", Value: json.RawMessage(`{"type":"test_case","workflow_id":2}`)},
+	}}, topics["corgi.test_case"])
+	assert.Equal(t, "1", topics["corgi.workflow_run"][0][0].Key)
+	assert.Equal(t, "d", topics["corgi.triage"][0][0].Key)
+}
+
+func TestKafkaWriterRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"offsets": [{"error_code": 50002, "error": "topic authorization failed"}]}`)
+	}))
+	defer server.Close()
+
+	w := NewKafkaWriter(io.Discard, slog.New(slog.NewTextHandler(io.Discard, nil)), server.URL, "", 10)
+	w.backoff = 0
+
+	entry := &BulkEntry{Index: "runs", ID: "a", Verb: "index", Data: []byte(`{"type":"test_case"}`)}
+	assert.NoError(t, entry.Write(w))
+
+	assert.ErrorContains(t, w.Close(), "unable to publish 1 documents to Kafka")
+}
@@ -0,0 +1,121 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/opensearch-project/opensearch-go/opensearchapi"
+)
+
+// ErrLeaseHeld is returned when a lease is held by another holder.
+var ErrLeaseHeld = errors.New("lease is held by another holder")
+
+// Lease is an exclusive claim on a resource, such as a workflow run, so that only one of
+// multiple replicas processes it at a time. Leases are documents in an index, which are
+// only changed if they weren't changed since they were read, so that two replicas can't
+// both take over an expired lease.
+type Lease struct {
+	client *opensearchgo.Client
+	index  string
+	id     string
+
+	seqNo       int
+	primaryTerm int
+}
+
+// leaseDocument is the document a lease is stored as.
+type leaseDocument struct {
+	Holder    string    `json:"holder"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AcquireLease acquires the lease with the given ID in the given index on behalf of the
+// given holder, for the given duration. ErrLeaseHeld is returned if the lease is held by
+// another holder and didn't expire yet. Leases held by the same holder are renewed.
+func AcquireLease(
+	ctx context.Context,
+	client *opensearchgo.Client,
+	index, id, holder string,
+	ttl time.Duration,
+) (*Lease, error) {
+	if IsServerless() {
+		return nil, errors.New("leases are not supported by serverless collections")
+	}
+
+	body, err := json.Marshal(leaseDocument{Holder: holder, ExpiresAt: time.Now().Add(ttl).UTC()})
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal lease %s: %w", id, err)
+	}
+
+	req := &opensearchapi.IndexRequest{
+		Index:      index,
+		DocumentID: id,
+		Body:       bytes.NewReader(body),
+		OpType:     "create",
+	}
+
+	resp, err := doGenericRequest(ctx, client, &opensearchapi.GetRequest{Index: index, DocumentID: id})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("unable to get lease %s: %w", id, err)
+	}
+
+	if found, _ := resp["found"].(bool); found {
+		current := leaseDocument{}
+		if source, err := json.Marshal(resp["_source"]); err == nil {
+			_ = json.Unmarshal(source, &current)
+		}
+
+		if current.Holder != holder && time.Now().Before(current.ExpiresAt) {
+			return nil, fmt.Errorf("%w: %s until %s", ErrLeaseHeld, current.Holder, current.ExpiresAt.Format(time.RFC3339))
+		}
+
+		// Take over the lease only if nobody else did since it was read.
+		seqNo, _ := resp["_seq_no"].(float64)
+		primaryTerm, _ := resp["_primary_term"].(float64)
+		req.OpType = ""
+		req.IfSeqNo = opensearchapi.IntPtr(int(seqNo))
+		req.IfPrimaryTerm = opensearchapi.IntPtr(int(primaryTerm))
+	}
+
+	resp, err = doGenericRequest(ctx, client, req)
+	if errors.Is(err, ErrConflict) {
+		return nil, fmt.Errorf("%w: lease %s was acquired concurrently", ErrLeaseHeld, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to write lease %s: %w", id, err)
+	}
+
+	seqNo, _ := resp["_seq_no"].(float64)
+	primaryTerm, _ := resp["_primary_term"].(float64)
+
+	return &Lease{
+		client:      client,
+		index:       index,
+		id:          id,
+		seqNo:       int(seqNo),
+		primaryTerm: int(primaryTerm),
+	}, nil
+}
+
+// Release releases the lease, unless it was taken over by another holder in the meantime.
+func (l *Lease) Release(ctx context.Context) error {
+	_, err := doGenericRequest(ctx, l.client, &opensearchapi.DeleteRequest{
+		Index:         l.index,
+		DocumentID:    l.id,
+		IfSeqNo:       opensearchapi.IntPtr(l.seqNo),
+		IfPrimaryTerm: opensearchapi.IntPtr(l.primaryTerm),
+	})
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrConflict) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("unable to release lease %s: %w", l.id, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package opensearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// leaseServer stores a single document and implements optimistic concurrency control
+// like OpenSearch does.
+type leaseServer struct {
+	mu     sync.Mutex
+	source []byte
+	seqNo  int
+}
+
+func (s *leaseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	query := r.URL.Query()
+	matches := query.Get("if_seq_no") == "" || (s.source != nil && query.Get("if_seq_no") == strconv.Itoa(s.seqNo))
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/leases/_doc/run-1":
+		if s.source == nil {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"found": false}`)
+			return
+		}
+		fmt.Fprintf(w, `{"found": true, "_seq_no": %d, "_primary_term": 1, "_source": %s}`, s.seqNo, s.source)
+	case r.Method == http.MethodPut && r.URL.Path == "/leases/_create/run-1",
+		r.Method == http.MethodPut && r.URL.Path == "/leases/_doc/run-1":
+		if (r.URL.Path == "/leases/_create/run-1" && s.source != nil) || !matches {
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"error": "version_conflict_engine_exception"}`)
+			return
+		}
+		s.source, _ = io.ReadAll(r.Body)
+		s.seqNo++
+		fmt.Fprintf(w, `{"result": "created", "_seq_no": %d, "_primary_term": 1}`, s.seqNo)
+	case r.Method == http.MethodDelete && r.URL.Path == "/leases/_doc/run-1":
+		if !matches {
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprint(w, `{"error": "version_conflict_engine_exception"}`)
+			return
+		}
+		s.source = nil
+		s.seqNo++
+		fmt.Fprint(w, `{"result": "deleted"}`)
+	default:
+		// Answer the product check of the client.
+		fmt.Fprint(w, `{"version": {"number": "2.11.0", "distribution": "opensearch"}}`)
+	}
+}
+
+func TestLease(t *testing.T) {
+	store := &leaseServer{}
+	server := httptest.NewServer(store)
+	defer server.Close()
+
+	client, err := opensearchgo.NewClient(opensearchgo.Config{Addresses: []string{server.URL}})
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+
+	lease, err := AcquireLease(ctx, client, "leases", "run-1", "a", time.Hour)
+	assert.NoError(t, err)
+
+	_, err = AcquireLease(ctx, client, "leases", "run-1", "b", time.Hour)
+	assert.ErrorIs(t, err, ErrLeaseHeld)
+
+	// The holder renews its own lease.
+	lease, err = AcquireLease(ctx, client, "leases", "run-1", "a", time.Hour)
+	assert.NoError(t, err)
+
+	assert.NoError(t, lease.Release(ctx))
+	assert.Nil(t, store.source)
+
+	// Expired leases are taken over, after which the previous holder can't release them.
+	expired, err := AcquireLease(ctx, client, "leases", "run-1", "a", -time.Minute)
+	assert.NoError(t, err)
+
+	lease, err = AcquireLease(ctx, client, "leases", "run-1", "b", time.Hour)
+	assert.NoError(t, err)
+
+	assert.NoError(t, expired.Release(ctx))
+	doc := leaseDocument{}
+	assert.NoError(t, json.Unmarshal(store.source, &doc))
+	assert.Equal(t, "b", doc.Holder)
+
+	assert.NoError(t, lease.Release(ctx))
+}
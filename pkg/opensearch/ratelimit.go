@@ -0,0 +1,53 @@
+package opensearch
+
+import (
+	"io"
+
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+// RateLimitedWriter limits the rate at which bulk entries are written to the
+// wrapped writer. Each call to Write is counted as a single document, which
+// matches how BulkEntry.Write outputs an entry.
+type RateLimitedWriter struct {
+	target io.Writer
+	docs   *util.TokenBucket
+	bytes  *util.TokenBucket
+}
+
+// NewRateLimitedWriter wraps the given writer, limiting it to the given number
+// of documents and bytes per second. A limit of zero disables it. If both limits
+// are disabled, the target is returned as-is.
+func NewRateLimitedWriter(target io.Writer, docsPerSecond, bytesPerSecond float64) io.Writer {
+	if docsPerSecond <= 0 && bytesPerSecond <= 0 {
+		return target
+	}
+
+	w := &RateLimitedWriter{target: target}
+
+	if docsPerSecond > 0 {
+		w.docs = util.NewTokenBucket(docsPerSecond, max(1, docsPerSecond))
+	}
+
+	if bytesPerSecond > 0 {
+		w.bytes = util.NewTokenBucket(bytesPerSecond, bytesPerSecond)
+	}
+
+	return w
+}
+
+func (w *RateLimitedWriter) Write(p []byte) (int, error) {
+	if w.docs != nil {
+		w.docs.Take(1)
+	}
+
+	if w.bytes != nil {
+		w.bytes.Take(float64(len(p)))
+	}
+
+	return w.target.Write(p)
+}
+
+func (w *RateLimitedWriter) Flush() error {
+	return Flush(w.target)
+}
@@ -0,0 +1,85 @@
+package opensearch
+
+import (
+	"fmt"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// Routing selects the value documents are routed to shards by. Routing documents
+// of the same team or workflow to the same shard means queries filtering on them
+// only need to hit a few shards, if they pass the same routing value.
+type Routing string
+
+const (
+	// RoutingNone routes documents by their ID, which is the default of OpenSearch.
+	RoutingNone Routing = ""
+	// RoutingOwner routes tests by their primary owner.
+	RoutingOwner Routing = "owner"
+	// RoutingWorkflow routes documents by the name of their workflow.
+	RoutingWorkflow Routing = "workflow"
+)
+
+// ParseRouting validates the given routing.
+func ParseRouting(routing string) (Routing, error) {
+	switch r := Routing(routing); r {
+	case RoutingNone, RoutingOwner, RoutingWorkflow:
+		return r, nil
+	}
+
+	return RoutingNone, fmt.Errorf(
+		"unknown routing '%s', expected one of '%s' or '%s'", routing, RoutingOwner, RoutingWorkflow,
+	)
+}
+
+func primaryOwner(owners []string) string {
+	if len(owners) == 0 {
+		return ""
+	}
+
+	return owners[0]
+}
+
+// GetDocumentRouting returns the routing value of the given object, or an empty
+// string if it should be routed by its ID.
+func GetDocumentRouting(obj any, routing Routing) string {
+	switch routing {
+	case RoutingOwner:
+		switch o := obj.(type) {
+		case types.Testsuite:
+			return primaryOwner(o.Owners)
+		case types.Testcase:
+			if owner := primaryOwner(o.Owners); owner != "" {
+				return owner
+			}
+			return primaryOwner(o.Testsuite.Owners)
+		}
+	case RoutingWorkflow:
+		switch o := obj.(type) {
+		case *types.WorkflowRun:
+			return o.Name
+		case types.JobRun:
+			return o.WorkflowRun.Name
+		case types.StepRun:
+			return o.WorkflowRun.Name
+		case types.Testsuite:
+			return o.WorkflowRun.Name
+		case types.Testcase:
+			return o.WorkflowRun.Name
+		case types.IngestError:
+			return o.WorkflowRun.Name
+		case types.Artifact:
+			return o.WorkflowRun.Name
+		case types.TestCountDrop:
+			return o.WorkflowRun.Name
+		case types.TestDuration:
+			return o.Workflow
+		case types.FailureCooccurrence:
+			return o.Workflow
+		case types.WorkflowStability:
+			return o.Workflow
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,258 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/opensearch-project/opensearch-go/opensearchapi"
+)
+
+// pitKeepAlive is how long a point in time is kept alive between two pages.
+const pitKeepAlive = "5m"
+
+// rawRequest is a request for an API which isn't covered by opensearchapi, such
+// as the point in time API.
+type rawRequest struct {
+	method string
+	path   string
+	query  url.Values
+	body   []byte
+}
+
+func (r *rawRequest) Do(ctx context.Context, transport opensearchapi.Transport) (*opensearchapi.Response, error) {
+	u := &url.URL{Path: r.path, RawQuery: r.query.Encode()}
+
+	req, err := http.NewRequestWithContext(ctx, r.method, u.String(), bytes.NewReader(r.body))
+	if err != nil {
+		return nil, err
+	}
+	if r.body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := transport.Perform(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &opensearchapi.Response{
+		StatusCode: resp.StatusCode,
+		Body:       resp.Body,
+		Header:     resp.Header,
+	}, nil
+}
+
+func createPIT(ctx context.Context, client *opensearchgo.Client, index string) (string, error) {
+	resp, err := doGenericRequest(ctx, client, &rawRequest{
+		method: http.MethodPost,
+		path:   "/" + index + "/_search/point_in_time",
+		query:  url.Values{"keep_alive": []string{pitKeepAlive}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to create point in time for index %s: %w", index, err)
+	}
+
+	id, ok := resp["pit_id"].(string)
+	if !ok {
+		return "", fmt.Errorf("point in time for index %s has no pit_id", index)
+	}
+
+	return id, nil
+}
+
+func deletePIT(ctx context.Context, client *opensearchgo.Client, id string) error {
+	body, err := json.Marshal(map[string]any{"pit_id": []string{id}})
+	if err != nil {
+		return err
+	}
+
+	if _, err := doGenericRequest(ctx, client, &rawRequest{
+		method: http.MethodDelete,
+		path:   "/_search/point_in_time",
+		body:   body,
+	}); err != nil {
+		return fmt.Errorf("unable to delete point in time: %w", err)
+	}
+
+	return nil
+}
+
+// searchPage is the part of a search response needed to page through it.
+type searchPage struct {
+	PitID string `json:"pit_id"`
+	Hits  struct {
+		Hits []struct {
+			Source map[string]any `json:"_source"`
+			Sort   []any          `json:"sort"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+// SearchAll calls fn with the source of every document in the given index matching the
+// given query. Unlike from and size, which are limited to the first 10,000 hits, this pages
+// through all hits using a point in time and search_after, so the hits stay consistent
+// while documents are being written.
+func SearchAll(
+	ctx context.Context,
+	client *opensearchgo.Client,
+	index string,
+	query map[string]any,
+	pageSize int,
+	fn func(source map[string]any) error,
+) (err error) {
+	if pageSize <= 0 {
+		return fmt.Errorf("expected positive page size, got %d", pageSize)
+	}
+
+	pitID, err := createPIT(ctx, client, index)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if deleteErr := deletePIT(ctx, client, pitID); deleteErr != nil && err == nil {
+			err = deleteErr
+		}
+	}()
+
+	var searchAfter []any
+
+	for {
+		body := map[string]any{
+			"size":  pageSize,
+			"query": query,
+			"pit":   map[string]any{"id": pitID, "keep_alive": pitKeepAlive},
+			"sort":  []any{map[string]any{"_shard_doc": "asc"}},
+		}
+		if searchAfter != nil {
+			body["search_after"] = searchAfter
+		}
+
+		reqBody, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("unable to marshal search request: %w", err)
+		}
+
+		// Searches with a point in time must not target an index.
+		resp, err := doGenericRequest(ctx, client, &rawRequest{
+			method: http.MethodPost,
+			path:   "/_search",
+			body:   reqBody,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to search index %s: %w", index, err)
+		}
+
+		// Round-trip the response to decode only the fields needed for paging.
+		data, err := json.Marshal(resp)
+		if err != nil {
+			return fmt.Errorf("unable to marshal search response: %w", err)
+		}
+
+		page := searchPage{}
+		if err := json.Unmarshal(data, &page); err != nil {
+			return fmt.Errorf("unable to parse search response: %w", err)
+		}
+
+		for _, hit := range page.Hits.Hits {
+			if err := fn(hit.Source); err != nil {
+				return err
+			}
+		}
+
+		if len(page.Hits.Hits) < pageSize {
+			return nil
+		}
+
+		searchAfter = page.Hits.Hits[len(page.Hits.Hits)-1].Sort
+		if page.PitID != "" {
+			pitID = page.PitID
+		}
+	}
+}
+
+// compositePage is the part of a composite aggregation response needed for paging.
+type compositePage struct {
+	Aggregations struct {
+		Composite struct {
+			AfterKey map[string]any   `json:"after_key"`
+			Buckets  []map[string]any `json:"buckets"`
+		} `json:"composite"`
+	} `json:"aggregations"`
+}
+
+// AggregateAll calls fn with every bucket of a composite aggregation with the given
+// sources and sub-aggregations, over the documents in the given index matching the
+// given query. Unlike terms aggregations, which return a bounded number of buckets,
+// this pages through all buckets using after_key.
+func AggregateAll(
+	ctx context.Context,
+	client *opensearchgo.Client,
+	index string,
+	query map[string]any,
+	sources []any,
+	aggs map[string]any,
+	pageSize int,
+	fn func(bucket map[string]any) error,
+) error {
+	if pageSize <= 0 {
+		return fmt.Errorf("expected positive page size, got %d", pageSize)
+	}
+
+	var afterKey map[string]any
+
+	for {
+		composite := map[string]any{"size": pageSize, "sources": sources}
+		if afterKey != nil {
+			composite["after"] = afterKey
+		}
+
+		agg := map[string]any{"composite": composite}
+		if len(aggs) > 0 {
+			agg["aggs"] = aggs
+		}
+
+		body, err := json.Marshal(map[string]any{
+			"size":  0,
+			"query": query,
+			"aggs":  map[string]any{"composite": agg},
+		})
+		if err != nil {
+			return fmt.Errorf("unable to marshal aggregation request: %w", err)
+		}
+
+		resp, err := doGenericRequest(ctx, client, &opensearchapi.SearchRequest{
+			Index: []string{index},
+			Body:  bytes.NewReader(body),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to aggregate index %s: %w", index, err)
+		}
+
+		// Round-trip the response to decode only the fields needed for paging.
+		data, err := json.Marshal(resp)
+		if err != nil {
+			return fmt.Errorf("unable to marshal aggregation response: %w", err)
+		}
+
+		page := compositePage{}
+		if err := json.Unmarshal(data, &page); err != nil {
+			return fmt.Errorf("unable to parse aggregation response: %w", err)
+		}
+
+		for _, bucket := range page.Aggregations.Composite.Buckets {
+			if err := fn(bucket); err != nil {
+				return err
+			}
+		}
+
+		afterKey = page.Aggregations.Composite.AfterKey
+		if len(page.Aggregations.Composite.Buckets) < pageSize || afterKey == nil {
+			return nil
+		}
+	}
+}
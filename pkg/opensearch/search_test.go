@@ -0,0 +1,135 @@
+package opensearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchAllPagesWithSearchAfter(t *testing.T) {
+	const total = 5
+	deleted := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/runs/_search/point_in_time":
+			fmt.Fprint(w, `{"pit_id": "pit"}`)
+		case r.Method == http.MethodDelete && r.URL.Path == "/_search/point_in_time":
+			deleted = true
+			fmt.Fprint(w, `{"pits": []}`)
+		case r.URL.Path == "/_search":
+			req := struct {
+				Size        int   `json:"size"`
+				SearchAfter []int `json:"search_after"`
+			}{}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+			start := 0
+			if len(req.SearchAfter) > 0 {
+				start = req.SearchAfter[0] + 1
+			}
+
+			hits := []string{}
+			for i := start; i < min(start+req.Size, total); i++ {
+				hits = append(hits, fmt.Sprintf(`{"_source": {"n": %d}, "sort": [%d]}`, i, i))
+			}
+			fmt.Fprintf(w, `{"pit_id": "pit", "hits": {"hits": [%s]}}`, strings.Join(hits, ","))
+		default:
+			// Answer the product check of the client.
+			fmt.Fprint(w, `{"version": {"number": "2.11.0", "distribution": "opensearch"}}`)
+		}
+	}))
+	defer server.Close()
+
+	client, err := opensearchgo.NewClient(opensearchgo.Config{Addresses: []string{server.URL}})
+	assert.NoError(t, err)
+
+	seen := []float64{}
+	err = SearchAll(context.Background(), client, "runs", map[string]any{"match_all": map[string]any{}}, 2,
+		func(source map[string]any) error {
+			seen = append(seen, source["n"].(float64))
+			return nil
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{0, 1, 2, 3, 4}, seen)
+	assert.True(t, deleted)
+}
+
+func TestAggregateAllPagesWithAfterKey(t *testing.T) {
+	const total = 5
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.URL.Path != "/runs/_search" {
+			// Answer the product check of the client.
+			fmt.Fprint(w, `{"version": {"number": "2.11.0", "distribution": "opensearch"}}`)
+			return
+		}
+
+		req := struct {
+			Aggs struct {
+				Composite struct {
+					Composite struct {
+						Size  int `json:"size"`
+						After struct {
+							N *int `json:"n"`
+						} `json:"after"`
+					} `json:"composite"`
+				} `json:"composite"`
+			} `json:"aggs"`
+		}{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		composite := req.Aggs.Composite.Composite
+		start := 0
+		if composite.After.N != nil {
+			start = *composite.After.N + 1
+		}
+
+		buckets := []string{}
+		for i := start; i < min(start+composite.Size, total); i++ {
+			buckets = append(buckets, fmt.Sprintf(`{"key": {"n": %d}, "doc_count": 1}`, i))
+		}
+		fmt.Fprintf(
+			w, `{"aggregations": {"composite": {"after_key": {"n": %d}, "buckets": [%s]}}}`,
+			start+len(buckets)-1, strings.Join(buckets, ","),
+		)
+	}))
+	defer server.Close()
+
+	client, err := opensearchgo.NewClient(opensearchgo.Config{Addresses: []string{server.URL}})
+	assert.NoError(t, err)
+
+	seen := []float64{}
+	err = AggregateAll(
+		context.Background(), client, "runs", map[string]any{"match_all": map[string]any{}},
+		[]any{map[string]any{"n": map[string]any{"terms": map[string]any{"field": "n"}}}}, nil, 2,
+		func(bucket map[string]any) error {
+			seen = append(seen, bucket["key"].(map[string]any)["n"].(float64))
+			return nil
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []float64{0, 1, 2, 3, 4}, seen)
+}
+
+func TestSearchAllRejectsPageSize(t *testing.T) {
+	for _, pageSize := range []int{0, -1} {
+		err := SearchAll(context.Background(), nil, "runs", nil, pageSize, func(map[string]any) error { return nil })
+		assert.ErrorContains(t, err, "expected positive page size")
+
+		err = AggregateAll(context.Background(), nil, "runs", nil, nil, nil, pageSize, func(map[string]any) error { return nil })
+		assert.ErrorContains(t, err, "expected positive page size")
+	}
+}
@@ -0,0 +1,254 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/opensearch-project/opensearch-go/opensearchapi"
+
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+const (
+	// maxBulkAttempts is how often a batch is sent before its remaining
+	// retryable items are given up on.
+	maxBulkAttempts = 5
+
+	// errorTypeRejectedExecution is the error type of items which were rejected
+	// because the write queue of a node was full.
+	errorTypeRejectedExecution = "es_rejected_execution_exception"
+)
+
+// BulkSender sends bulk entries directly to OpenSearch in batches. Each call
+// to Write is expected to contain a single entry, as written by BulkEntry.Write.
+// Items which failed with a retryable error are sent again with exponential
+// backoff, while items which failed permanently, such as on mapping errors, and
+// batches whose request failed as a whole are written to a dead letter file from
+// which they can be inspected and sent again.
+type BulkSender struct {
+	client         *opensearchgo.Client
+	logger         *slog.Logger
+	batchDocs      int
+	deadLetterPath string
+	timeout        time.Duration
+	backoff        time.Duration
+
+	batch      [][]byte
+	deadLetter *os.File
+}
+
+// bulkResponse is the part of the response of the bulk API which is needed to
+// find out which items failed.
+type bulkResponse struct {
+	Errors bool                          `json:"errors"`
+	Items  []map[string]bulkResponseItem `json:"items"`
+}
+
+type bulkResponseItem struct {
+	Status int `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// NewBulkSender creates a new BulkSender sending batches of up to batchDocs entries
+// through the given client. Permanently failed entries are appended to the file at
+// deadLetterPath, which is only created once an entry fails. Each bulk request is
+// bounded by timeout, zero meaning no timeout.
+func NewBulkSender(
+	client *opensearchgo.Client, logger *slog.Logger, batchDocs int, deadLetterPath string, timeout time.Duration,
+) *BulkSender {
+	return &BulkSender{
+		client:         client,
+		logger:         logger,
+		batchDocs:      max(1, batchDocs),
+		deadLetterPath: deadLetterPath,
+		timeout:        timeout,
+		backoff:        time.Second,
+	}
+}
+
+func (s *BulkSender) Write(p []byte) (int, error) {
+	s.batch = append(s.batch, bytes.Clone(p))
+
+	if len(s.batch) >= s.batchDocs {
+		if err := s.flush(); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+// Flush sends the buffered entries.
+func (s *BulkSender) Flush() error {
+	return s.flush()
+}
+
+// Close sends the remaining entries and closes the dead letter file.
+func (s *BulkSender) Close() error {
+	err := s.flush()
+
+	if s.deadLetter != nil {
+		if closeErr := s.deadLetter.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("unable to close dead letter file: %w", closeErr)
+		}
+	}
+
+	return err
+}
+
+// isRetryableItem returns true if the given failed item may succeed when sent again.
+func isRetryableItem(item bulkResponseItem) bool {
+	if item.Status == http.StatusTooManyRequests {
+		return true
+	}
+
+	return item.Error != nil && item.Error.Type == errorTypeRejectedExecution
+}
+
+func (s *BulkSender) flush() error {
+	pending := s.batch
+	s.batch = nil
+
+	for attempt := 1; len(pending) > 0; attempt++ {
+		if attempt > 1 {
+			wait := s.backoff << (attempt - 2)
+			s.logger.Warn("Retrying bulk items", "items", len(pending), "attempt", attempt, "sleepTime", wait)
+			time.Sleep(wait)
+		}
+
+		resp, status, err := s.send(pending)
+		if err != nil {
+			return s.writeDeadLetterBatch(pending, err)
+		}
+
+		if status == http.StatusTooManyRequests || status >= http.StatusInternalServerError {
+			if attempt == maxBulkAttempts {
+				return s.writeDeadLetterBatch(
+					pending, fmt.Errorf("bulk request failed with status %d after %d attempts", status, attempt),
+				)
+			}
+			continue
+		}
+
+		if len(resp.Items) != len(pending) {
+			return s.writeDeadLetterBatch(pending, fmt.Errorf(
+				"bulk response contains %d items for %d entries", len(resp.Items), len(pending),
+			))
+		}
+
+		if !resp.Errors {
+			return nil
+		}
+
+		retry := [][]byte{}
+		for i, result := range resp.Items {
+			for _, item := range result {
+				if item.Status < http.StatusMultipleChoices {
+					continue
+				}
+
+				if isRetryableItem(item) && attempt < maxBulkAttempts {
+					retry = append(retry, pending[i])
+					continue
+				}
+
+				if err := s.writeDeadLetter(pending[i], item); err != nil {
+					return err
+				}
+			}
+		}
+
+		pending = retry
+	}
+
+	return nil
+}
+
+// send sends the given entries as a single bulk request, returning the status of
+// the response and, if the request succeeded, the parsed response.
+func (s *BulkSender) send(entries [][]byte) (*bulkResponse, int, error) {
+	ctx, cancel := util.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	resp, err := opensearchapi.BulkRequest{
+		Body: bytes.NewReader(bytes.Join(entries, nil)),
+	}.Do(ctx, s.client)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unexpected error sending bulk request to OpenSearch: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("unexpected error while reading bulk response from OpenSearch: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return nil, resp.StatusCode, nil
+	}
+
+	if resp.IsError() {
+		return nil, resp.StatusCode, fmt.Errorf("unexpected error in bulk response from OpenSearch: %s", body)
+	}
+
+	parsed := &bulkResponse{}
+	if err := json.Unmarshal(body, parsed); err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("unable to parse bulk response from OpenSearch: %w", err)
+	}
+
+	return parsed, resp.StatusCode, nil
+}
+
+func (s *BulkSender) writeDeadLetter(entry []byte, item bulkResponseItem) error {
+	l := s.logger.With("status", item.Status, "deadLetterPath", s.deadLetterPath)
+	if item.Error != nil {
+		l = l.With("errorType", item.Error.Type, "reason", item.Error.Reason)
+	}
+	l.Error("Bulk item failed permanently, writing it to the dead letter file")
+
+	return s.appendDeadLetter(entry)
+}
+
+// writeDeadLetterBatch writes the given entries of a bulk request which failed as
+// a whole to the dead letter file, returning the error the request failed with.
+func (s *BulkSender) writeDeadLetterBatch(entries [][]byte, cause error) error {
+	s.logger.Error(
+		"Bulk request failed, writing its items to the dead letter file",
+		"items", len(entries), "deadLetterPath", s.deadLetterPath, "err", cause,
+	)
+
+	for _, entry := range entries {
+		if err := s.appendDeadLetter(entry); err != nil {
+			return err
+		}
+	}
+
+	return cause
+}
+
+func (s *BulkSender) appendDeadLetter(entry []byte) error {
+	if s.deadLetter == nil {
+		f, err := os.OpenFile(s.deadLetterPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("unable to open dead letter file: %w", err)
+		}
+		s.deadLetter = f
+	}
+
+	if _, err := s.deadLetter.Write(entry); err != nil {
+		return fmt.Errorf("unable to write to dead letter file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package opensearch
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkSenderRetriesFailedItems(t *testing.T) {
+	// Documents are indexed on the first attempt unless listed here, in which case
+	// the given response is returned for them.
+	failures := map[string]string{
+		"rejected": `{"status": 429, "error": {"type": "es_rejected_execution_exception"}}`,
+		"mapping":  `{"status": 400, "error": {"type": "mapper_parsing_exception"}}`,
+	}
+	requests := [][]string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" {
+			// Answer the product check of the client.
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"version": {"number": "2.11.0", "distribution": "opensearch"}}`)
+			return
+		}
+
+		ids := []string{}
+		items := []string{}
+
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			id := strings.TrimSpace(scanner.Text())
+			scanner.Scan()
+			ids = append(ids, id)
+
+			item := `{"status": 201}`
+			if failure, ok := failures[id]; ok {
+				item = failure
+			}
+			items = append(items, fmt.Sprintf(`{"index": %s}`, item))
+		}
+		requests = append(requests, ids)
+
+		// Rejected items succeed once they were retried.
+		delete(failures, "rejected")
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"errors": true, "items": [%s]}`, strings.Join(items, ","))
+	}))
+	defer server.Close()
+
+	client, err := opensearchgo.NewClient(opensearchgo.Config{Addresses: []string{server.URL}})
+	assert.NoError(t, err)
+
+	deadLetterPath := filepath.Join(t.TempDir(), "dead-letter.json")
+	s := NewBulkSender(client, slog.New(slog.NewTextHandler(io.Discard, nil)), 10, deadLetterPath, 0)
+	s.backoff = 0
+
+	for _, id := range []string{"ok", "rejected", "mapping"} {
+		_, err := s.Write([]byte(id + "\n{}\n"))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, s.Close())
+
+	assert.Equal(t, [][]string{{"ok", "rejected", "mapping"}, {"rejected"}}, requests)
+
+	deadLetter, err := os.ReadFile(deadLetterPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "mapping\n{}\n", string(deadLetter))
+}
+
+func TestBulkSenderDeadLettersFailedRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"version": {"number": "2.11.0", "distribution": "opensearch"}}`)
+			return
+		}
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client, err := opensearchgo.NewClient(opensearchgo.Config{Addresses: []string{server.URL}})
+	assert.NoError(t, err)
+
+	deadLetterPath := filepath.Join(t.TempDir(), "dead-letter.json")
+	s := NewBulkSender(client, slog.New(slog.NewTextHandler(io.Discard, nil)), 2, deadLetterPath, 0)
+	s.backoff = 0
+
+	_, err = s.Write([]byte("a\n{}\n"))
+	assert.NoError(t, err)
+	_, err = s.Write([]byte("b\n{}\n"))
+	assert.ErrorContains(t, err, "bulk request failed with status 503 after 5 attempts")
+	assert.NoError(t, s.Close())
+
+	deadLetter, err := os.ReadFile(deadLetterPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "a\n{}\nb\n{}\n", string(deadLetter))
+}
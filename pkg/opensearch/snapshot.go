@@ -0,0 +1,41 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+	"github.com/opensearch-project/opensearch-go/opensearchapi"
+)
+
+// CreateSnapshot starts a snapshot with the given name of the given indices, which may
+// be patterns, into the given snapshot repository. It doesn't wait for the snapshot to
+// complete, which can be followed through the snapshot status API.
+func CreateSnapshot(
+	ctx context.Context,
+	client *opensearchgo.Client,
+	repository, name string,
+	indices []string,
+) error {
+	body, err := json.Marshal(map[string]any{
+		"indices":              strings.Join(indices, ","),
+		"ignore_unavailable":   true,
+		"include_global_state": false,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to marshal snapshot request: %w", err)
+	}
+
+	if _, err := doGenericRequest(ctx, client, &opensearchapi.SnapshotCreateRequest{
+		Repository: repository,
+		Snapshot:   name,
+		Body:       bytes.NewReader(body),
+	}); err != nil {
+		return fmt.Errorf("unable to create snapshot %s in repository %s: %w", name, repository, err)
+	}
+
+	return nil
+}
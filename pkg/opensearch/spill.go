@@ -0,0 +1,303 @@
+package opensearch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// SpillWriter decouples producing bulk entries from writing them to a slower
+// target, such as a RateLimitedWriter. Entries are buffered in memory up to a
+// limit, after which they are spilled into files on disk, so memory usage stays
+// flat when entries are produced faster than they can be written.
+// Entries are written to the target in the order they were received, each
+// through a single call to Write.
+type SpillWriter struct {
+	target        io.Writer
+	dir           string
+	maxMemBytes   int
+	maxFileBytes  int64
+	maxSpillBytes int64
+
+	mu         sync.Mutex
+	cond       *sync.Cond
+	mem        [][]byte
+	memBytes   int
+	spillFiles []*spillFile
+	spillBytes int64
+	closed     bool
+	err        error
+	done       chan struct{}
+	// writing is set while an entry taken from memory is written to the target.
+	writing bool
+	// targetMu serializes writing to and flushing the target.
+	targetMu sync.Mutex
+}
+
+// recordHeaderSize is the size of the length prefix of each entry in a spill file.
+const recordHeaderSize = 4
+
+func recordSize(entry []byte) int64 {
+	return int64(recordHeaderSize + len(entry))
+}
+
+// spillFile holds length-prefixed entries which were spilled to disk.
+type spillFile struct {
+	file   *os.File
+	writer *bufio.Writer
+	size   int64
+	// sealed is set once the file is being drained, after which no more
+	// entries may be appended to it.
+	sealed bool
+}
+
+// NewSpillWriter creates a new SpillWriter writing to the given target. Up to
+// maxMemBytes of entries are buffered in memory before spilling into files of
+// at most maxFileBytes in dir. If maxSpillBytes is greater than zero, writes block
+// once the spill files reach that size in total.
+func NewSpillWriter(target io.Writer, dir string, maxMemBytes int, maxFileBytes, maxSpillBytes int64) *SpillWriter {
+	s := &SpillWriter{
+		target:        target,
+		dir:           dir,
+		maxMemBytes:   maxMemBytes,
+		maxFileBytes:  maxFileBytes,
+		maxSpillBytes: maxSpillBytes,
+		done:          make(chan struct{}),
+	}
+	s.cond = sync.NewCond(&s.mu)
+
+	go s.drain()
+
+	return s
+}
+
+func (s *SpillWriter) Write(p []byte) (int, error) {
+	entry := bytes.Clone(p)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		if s.err != nil {
+			return 0, s.err
+		}
+
+		if s.closed {
+			return 0, errors.New("spill writer is closed")
+		}
+
+		// Once entries are spilled, keep spilling until the spill files are
+		// drained, so that entries are written in order.
+		if len(s.spillFiles) == 0 && s.memBytes+len(entry) <= s.maxMemBytes {
+			s.mem = append(s.mem, entry)
+			s.memBytes += len(entry)
+			s.cond.Broadcast()
+
+			return len(p), nil
+		}
+
+		if s.maxSpillBytes <= 0 || len(s.spillFiles) == 0 || s.spillBytes+recordSize(entry) <= s.maxSpillBytes {
+			break
+		}
+
+		s.cond.Wait()
+	}
+
+	if err := s.spill(entry); err != nil {
+		return 0, err
+	}
+
+	s.cond.Broadcast()
+
+	return len(p), nil
+}
+
+// spill appends the entry to the newest spill file, creating a new one if needed.
+// Must be called with the lock held.
+func (s *SpillWriter) spill(entry []byte) error {
+	var current *spillFile
+	if n := len(s.spillFiles); n > 0 {
+		current = s.spillFiles[n-1]
+	}
+
+	if current == nil || current.sealed || current.size >= s.maxFileBytes {
+		f, err := os.CreateTemp(s.dir, "corgi-spill-*")
+		if err != nil {
+			return fmt.Errorf("unable to create spill file: %w", err)
+		}
+
+		current = &spillFile{file: f, writer: bufio.NewWriter(f)}
+		s.spillFiles = append(s.spillFiles, current)
+	}
+
+	length := make([]byte, recordHeaderSize)
+	binary.BigEndian.PutUint32(length, uint32(len(entry)))
+
+	if _, err := current.writer.Write(length); err != nil {
+		return fmt.Errorf("unable to write to spill file %s: %w", current.file.Name(), err)
+	}
+
+	if _, err := current.writer.Write(entry); err != nil {
+		return fmt.Errorf("unable to write to spill file %s: %w", current.file.Name(), err)
+	}
+
+	current.size += recordSize(entry)
+	s.spillBytes += recordSize(entry)
+
+	return nil
+}
+
+func (s *SpillWriter) drain() {
+	defer close(s.done)
+
+	for {
+		s.mu.Lock()
+
+		for len(s.mem) == 0 && len(s.spillFiles) == 0 && !s.closed {
+			s.cond.Wait()
+		}
+
+		// Entries in memory are always older than the ones in spill files.
+		if len(s.mem) > 0 {
+			entry := s.mem[0]
+			s.mem[0] = nil
+			s.mem = s.mem[1:]
+			s.memBytes -= len(entry)
+			s.writing = true
+			s.cond.Broadcast()
+			s.mu.Unlock()
+
+			s.write(entry)
+
+			s.mu.Lock()
+			s.writing = false
+			s.cond.Broadcast()
+			s.mu.Unlock()
+
+			continue
+		}
+
+		if len(s.spillFiles) > 0 {
+			f := s.spillFiles[0]
+			f.sealed = true
+			flushErr := f.writer.Flush()
+			s.mu.Unlock()
+
+			if flushErr != nil {
+				s.setErr(fmt.Errorf("unable to flush spill file %s: %w", f.file.Name(), flushErr))
+			} else {
+				s.drainFile(f)
+			}
+
+			f.file.Close()
+			os.Remove(f.file.Name())
+
+			s.mu.Lock()
+			s.spillFiles = s.spillFiles[1:]
+			s.spillBytes -= f.size
+			s.cond.Broadcast()
+			s.mu.Unlock()
+
+			continue
+		}
+
+		s.mu.Unlock()
+
+		return
+	}
+}
+
+func (s *SpillWriter) drainFile(f *spillFile) {
+	if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+		s.setErr(fmt.Errorf("unable to seek spill file %s: %w", f.file.Name(), err))
+		return
+	}
+
+	reader := bufio.NewReader(f.file)
+	length := make([]byte, recordHeaderSize)
+
+	for {
+		if _, err := io.ReadFull(reader, length); err != nil {
+			if !errors.Is(err, io.EOF) {
+				s.setErr(fmt.Errorf("unable to read spill file %s: %w", f.file.Name(), err))
+			}
+			return
+		}
+
+		entry := make([]byte, binary.BigEndian.Uint32(length))
+		if _, err := io.ReadFull(reader, entry); err != nil {
+			s.setErr(fmt.Errorf("unable to read spill file %s: %w", f.file.Name(), err))
+			return
+		}
+
+		s.write(entry)
+	}
+}
+
+// write writes the entry to the target, unless a previous error occurred.
+func (s *SpillWriter) write(entry []byte) {
+	s.mu.Lock()
+	failed := s.err != nil
+	s.mu.Unlock()
+
+	if failed {
+		return
+	}
+
+	s.targetMu.Lock()
+	_, err := s.target.Write(entry)
+	s.targetMu.Unlock()
+
+	if err != nil {
+		s.setErr(fmt.Errorf("unable to write buffered entry: %w", err))
+	}
+}
+
+func (s *SpillWriter) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err == nil {
+		s.err = err
+	}
+
+	s.cond.Broadcast()
+}
+
+// Flush waits for the entries buffered so far to be written to the target, and
+// flushes the target.
+func (s *SpillWriter) Flush() error {
+	s.mu.Lock()
+	for s.err == nil && (len(s.mem) > 0 || len(s.spillFiles) > 0 || s.writing) {
+		s.cond.Wait()
+	}
+	err := s.err
+	s.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	s.targetMu.Lock()
+	defer s.targetMu.Unlock()
+
+	return Flush(s.target)
+}
+
+// Close waits for all buffered entries to be written to the target, and
+// removes any remaining spill files.
+func (s *SpillWriter) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.cond.Broadcast()
+	s.mu.Unlock()
+
+	<-s.done
+
+	return s.err
+}
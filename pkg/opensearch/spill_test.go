@@ -0,0 +1,104 @@
+package opensearch
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// slowWriter records each call to Write, sleeping beforehand to simulate
+// a target which is slower than the producer.
+type slowWriter struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(time.Millisecond)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.entries = append(w.entries, string(p))
+
+	return len(p), nil
+}
+
+func TestSpillWriterPreservesOrder(t *testing.T) {
+	dir := t.TempDir()
+	target := &slowWriter{}
+
+	w := NewSpillWriter(target, dir, 64, 128, 0)
+
+	expected := []string{}
+	for i := 0; i < 100; i++ {
+		entry := fmt.Sprintf("entry-%03d\n", i)
+		expected = append(expected, entry)
+
+		n, err := w.Write([]byte(entry))
+		assert.NoError(t, err)
+		assert.Equal(t, len(entry), n)
+	}
+
+	assert.NoError(t, w.Close())
+	assert.Equal(t, expected, target.entries)
+
+	remaining, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, remaining)
+}
+
+func TestSpillWriterBoundsSpillSize(t *testing.T) {
+	target := &slowWriter{}
+
+	w := NewSpillWriter(target, t.TempDir(), 16, 32, 64)
+
+	for i := 0; i < 50; i++ {
+		_, err := w.Write([]byte(fmt.Sprintf("entry-%03d\n", i)))
+		assert.NoError(t, err)
+
+		w.mu.Lock()
+		assert.LessOrEqual(t, w.spillBytes, int64(64))
+		w.mu.Unlock()
+	}
+
+	assert.NoError(t, w.Close())
+	assert.Len(t, target.entries, 50)
+}
+
+// flushWriter is a slowWriter recording how many entries it received when it was flushed.
+type flushWriter struct {
+	slowWriter
+	flushed []int
+}
+
+func (w *flushWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.flushed = append(w.flushed, len(w.entries))
+
+	return nil
+}
+
+func TestSpillWriterFlush(t *testing.T) {
+	target := &flushWriter{}
+
+	w := NewSpillWriter(NewRateLimitedWriter(target, 0, 1e9), t.TempDir(), 64, 128, 0)
+
+	for i := 0; i < 50; i++ {
+		_, err := w.Write([]byte(fmt.Sprintf("entry-%03d\n", i)))
+		assert.NoError(t, err)
+	}
+
+	// All entries written so far reach the target before it is flushed.
+	assert.NoError(t, w.Flush())
+	assert.Len(t, target.entries, 50)
+	assert.Equal(t, []int{50}, target.flushed)
+
+	assert.NoError(t, w.Close())
+}
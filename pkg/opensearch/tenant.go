@@ -0,0 +1,130 @@
+package opensearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// TenantPlaceholder is replaced by the name of the tenant in index names.
+const TenantPlaceholder = "{tenant}"
+
+// reTenant matches valid tenant names, which must be usable in index names. Names
+// can't contain '-', so that the indices of one tenant never match the pattern of
+// another, such as corgi-cilium-* matching the indices of cilium-enterprise.
+var reTenant = regexp.MustCompile(`^[a-z0-9][a-z0-9_]*$`)
+
+// ValidateTenant returns an error if the given tenant can't be used in index names.
+func ValidateTenant(tenant string) error {
+	if !reTenant.MatchString(tenant) {
+		return fmt.Errorf(
+			"expected tenant to consist of lowercase letters, digits and '_', got '%s'", tenant,
+		)
+	}
+
+	return nil
+}
+
+// ExpandTenant replaces the tenant placeholder in the given index name template.
+func ExpandTenant(template, tenant string) string {
+	return strings.ReplaceAll(template, TenantPlaceholder, tenant)
+}
+
+// TenantIndex returns the index name template for documents of the given type of the
+// given tenant, such as corgi-cilium-testcases-{yyyy.MM}, so that the documents of each
+// tenant are kept in their own set of monthly indices.
+func TenantIndex(tenant string, typ types.TypeName) string {
+	name := strings.ReplaceAll(string(typ), "_", "")
+	if !strings.HasSuffix(name, "s") {
+		name += "s"
+	}
+
+	return fmt.Sprintf("corgi-%s-%s-{yyyy.MM}", tenant, name)
+}
+
+// TenantIndexPrefix returns the prefix of the indices returned by TenantIndex, which
+// no index of another tenant starts with.
+func TenantIndexPrefix(tenant string) string {
+	return fmt.Sprintf("corgi-%s-", tenant)
+}
+
+// TenantIndexPattern returns a pattern matching every index of the given tenant, when
+// using the indices returned by TenantIndex.
+func TenantIndexPattern(tenant string) string {
+	return TenantIndexPrefix(tenant) + "*"
+}
+
+// EnsureRetentionPolicy creates or updates the Index State Management policy with the
+// given name, which deletes indices matching the given patterns once they are older
+// than the given retention. The policy is only applied to indices created afterwards.
+func EnsureRetentionPolicy(
+	ctx context.Context,
+	client *opensearchgo.Client,
+	name string,
+	patterns []string,
+	retention time.Duration,
+) error {
+	if IsServerless() {
+		return errors.New("index state management is not supported by serverless collections")
+	}
+
+	age := fmt.Sprintf("%dm", int64(retention.Minutes()))
+	body, err := json.Marshal(map[string]any{"policy": map[string]any{
+		"description":   fmt.Sprintf("Delete indices matching %s after %s", strings.Join(patterns, ","), age),
+		"default_state": "hot",
+		"states": []map[string]any{
+			{
+				"name":    "hot",
+				"actions": []any{},
+				"transitions": []map[string]any{
+					{"state_name": "delete", "conditions": map[string]any{"min_index_age": age}},
+				},
+			},
+			{
+				"name":        "delete",
+				"actions":     []map[string]any{{"delete": map[string]any{}}},
+				"transitions": []any{},
+			},
+		},
+		"ism_template": []map[string]any{{"index_patterns": patterns, "priority": 100}},
+	}})
+	if err != nil {
+		return fmt.Errorf("unable to marshal retention policy %s: %w", name, err)
+	}
+
+	path := "/_plugins/_ism/policies/" + url.PathEscape(name)
+
+	// Existing policies are only updated if they weren't changed since they were read.
+	query := url.Values{}
+	resp, err := doGenericRequest(ctx, client, &rawRequest{method: http.MethodGet, path: path})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("unable to get retention policy %s: %w", name, err)
+	}
+	if err == nil {
+		seqNo, _ := resp["_seq_no"].(float64)
+		primaryTerm, _ := resp["_primary_term"].(float64)
+		query.Set("if_seq_no", fmt.Sprint(int64(seqNo)))
+		query.Set("if_primary_term", fmt.Sprint(int64(primaryTerm)))
+	}
+
+	if _, err := doGenericRequest(ctx, client, &rawRequest{
+		method: http.MethodPut,
+		path:   path,
+		query:  query,
+		body:   body,
+	}); err != nil {
+		return fmt.Errorf("unable to write retention policy %s: %w", name, err)
+	}
+
+	return nil
+}
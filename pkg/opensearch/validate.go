@@ -0,0 +1,291 @@
+package opensearch
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// maxTermBytes is the maximum length of a single term Lucene can index. Longer
+// keyword values without ignore_above fail the whole document.
+const maxTermBytes = 32766
+
+// requiredFields are the fields every document of a type needs, for it to be found by
+// dashboards and reports. The type field is required for every document.
+var requiredFields = map[types.TypeName][]string{
+	types.TypeNameWorkflowRun:       {"workflow_id", "workflow_created_at"},
+	types.TypeNameJobRun:            {"workflow_id", "job_id"},
+	types.TypeNameStepRun:           {"workflow_id", "job_id", "step_name"},
+	types.TypeNameTestsuite:         {"workflow_id", "test_suite_name"},
+	types.TypeNameTestcase:          {"workflow_id", "test_case_name"},
+	types.TypeNameIngestError:       {"workflow_id", "ingest_error_reason"},
+	types.TypeNameArtifact:          {"workflow_id", "artifact_id"},
+	types.TypeNameCacheUsage:        {"cache_usage_timestamp"},
+	types.TypeNameTriage:            {"triage_test_name", "triage_state"},
+	types.TypeNameIngestStats:       {"ingest_stats_command", "ingest_stats_started_at"},
+	types.TypeNameAuditEvent:        {"audit_event_command", "audit_event_started_at"},
+	types.TypeNameTestDuration:      {"test_duration_test_name", "test_duration_until"},
+	types.TypeNameTeamFlakiness:     {"team_flakiness_owner", "team_flakiness_until"},
+	types.TypeNameTestCountDrop:     {"workflow_id", "test_count_drop_expected"},
+	types.TypeNameWorkflowStability: {"workflow_stability_workflow", "workflow_stability_until"},
+	types.TypeNameFailurePattern:    {"failure_pattern_dimension", "failure_pattern_segment", "failure_pattern_until"},
+	types.TypeNameSysdump:           {"workflow_id", "sysdump_path"},
+	types.TypeNameLogSignature:      {"workflow_id", "log_signature_path", "log_signature_name"},
+	types.TypeNameFailureCooccurrence: {
+		"failure_cooccurrence_test_a", "failure_cooccurrence_test_b", "failure_cooccurrence_until",
+	},
+}
+
+// mappingField is a field of an index mapping.
+type mappingField struct {
+	Type        string                   `json:"type"`
+	IgnoreAbove int                      `json:"ignore_above"`
+	Dimension   int                      `json:"dimension"`
+	Properties  map[string]*mappingField `json:"properties"`
+}
+
+// Violation is a problem with a field of a document, which would either fail the
+// document in OpenSearch or store something which can't be queried.
+type Violation struct {
+	Field  string
+	Reason string
+}
+
+func (v Violation) String() string {
+	return v.Field + ": " + v.Reason
+}
+
+// Validator checks documents against an index mapping before they are written, so
+// that problems are reported with the offending field instead of surfacing as
+// mapper_parsing_exceptions. Fields which aren't in the mapping are left to
+// dynamic mapping and not checked.
+type Validator struct {
+	properties     map[string]*mappingField
+	maxStringBytes int
+	logger         *slog.Logger
+	rejected       atomic.Int64
+}
+
+// LoadValidator creates a Validator for the mapping in the file at the given path,
+// such as opensearch/mappings.json. Strings larger than maxStringBytes are rejected,
+// zero meaning unlimited.
+func LoadValidator(path string, maxStringBytes int, logger *slog.Logger) (*Validator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read mapping: %w", err)
+	}
+
+	v, err := NewValidator(data, maxStringBytes, logger)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse mapping %s: %w", path, err)
+	}
+
+	return v, nil
+}
+
+// NewValidator creates a Validator for the given mapping, which is either a bare
+// mapping with properties, or wrapped in mappings as in index creation requests.
+func NewValidator(mapping []byte, maxStringBytes int, logger *slog.Logger) (*Validator, error) {
+	m := struct {
+		Properties map[string]*mappingField `json:"properties"`
+		Mappings   struct {
+			Properties map[string]*mappingField `json:"properties"`
+		} `json:"mappings"`
+	}{}
+	if err := json.Unmarshal(mapping, &m); err != nil {
+		return nil, err
+	}
+
+	properties := m.Properties
+	if len(properties) == 0 {
+		properties = m.Mappings.Properties
+	}
+	if len(properties) == 0 {
+		return nil, fmt.Errorf("mapping has no properties")
+	}
+
+	return &Validator{properties: properties, maxStringBytes: maxStringBytes, logger: logger}, nil
+}
+
+// Rejected returns the number of documents which failed validation.
+func (v *Validator) Rejected() int64 {
+	return v.rejected.Load()
+}
+
+// Check validates the given marshalled document, logging the violations if there are
+// any, and returns whether it is valid. Required fields dropped by the given filter
+// aren't reported as missing.
+func (v *Validator) Check(doc []byte, id string, filter *FieldFilter) bool {
+	violations, err := v.Validate(doc, filter)
+	if err != nil {
+		violations = []Violation{{Field: "", Reason: err.Error()}}
+	}
+	if len(violations) == 0 {
+		return true
+	}
+
+	v.rejected.Add(1)
+
+	reasons := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		reasons = append(reasons, violation.String())
+	}
+	v.logger.Error("Skipping document which doesn't match the mapping", "id", id, "violations", reasons)
+
+	return false
+}
+
+// Validate returns the violations of the mapping in the given marshalled document,
+// sorted by field.
+func (v *Validator) Validate(doc []byte, filter *FieldFilter) ([]Violation, error) {
+	dec := json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+
+	fields := map[string]any{}
+	if err := dec.Decode(&fields); err != nil {
+		return nil, fmt.Errorf("unable to decode document: %w", err)
+	}
+
+	violations := []Violation{}
+
+	typ, _ := fields["type"].(string)
+	if typ == "" {
+		violations = append(violations, Violation{Field: "type", Reason: "required field is missing"})
+	}
+	for _, field := range requiredFields[types.TypeName(typ)] {
+		if _, ok := fields[field]; !ok && !filter.Drops(typ, field) {
+			violations = append(violations, Violation{Field: field, Reason: "required field is missing"})
+		}
+	}
+
+	for name, value := range fields {
+		violations = append(violations, v.validateField(name, v.properties[name], value)...)
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool { return violations[i].Field < violations[j].Field })
+
+	return violations, nil
+}
+
+func (v *Validator) validateField(path string, field *mappingField, value any) []Violation {
+	if field == nil || value == nil {
+		return nil
+	}
+
+	typ := field.Type
+	if typ == "" && field.Properties != nil {
+		typ = "object"
+	}
+
+	if s, ok := value.(string); ok && v.maxStringBytes > 0 && len(s) > v.maxStringBytes {
+		return []Violation{{
+			Field:  path,
+			Reason: fmt.Sprintf("string of %d bytes exceeds the maximum of %d bytes", len(s), v.maxStringBytes),
+		}}
+	}
+
+	if values, ok := value.([]any); ok && typ != "knn_vector" {
+		violations := []Violation{}
+		for _, item := range values {
+			violations = append(violations, v.validateField(path, field, item)...)
+		}
+		return violations
+	}
+
+	violation := func(format string, args ...any) []Violation {
+		return []Violation{{Field: path, Reason: fmt.Sprintf(format, args...)}}
+	}
+
+	switch typ {
+	case "long", "integer", "short", "byte":
+		n, ok := value.(json.Number)
+		if !ok {
+			return violation("expected an integer for %s field, got %T", typ, value)
+		}
+		if _, err := n.Int64(); err != nil {
+			return violation("expected an integer for %s field, got %s", typ, n)
+		}
+	case "double", "float", "half_float", "scaled_float":
+		if _, ok := value.(json.Number); !ok {
+			return violation("expected a number for %s field, got %T", typ, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return violation("expected a boolean, got %T", value)
+		}
+	case "date":
+		switch d := value.(type) {
+		case json.Number:
+		case string:
+			if !isDate(d) {
+				return violation("unable to parse %q as a date", d)
+			}
+		default:
+			return violation("expected a date, got %T", value)
+		}
+	case "keyword":
+		if s, ok := value.(string); ok && field.IgnoreAbove == 0 && len(s) > maxTermBytes {
+			return violation("keyword of %d bytes exceeds the maximum term length of %d bytes", len(s), maxTermBytes)
+		}
+		if _, ok := value.(map[string]any); ok {
+			return violation("expected a value for keyword field, got an object")
+		}
+	case "text":
+		if _, ok := value.(map[string]any); ok {
+			return violation("expected a value for text field, got an object")
+		}
+	case "binary":
+		s, ok := value.(string)
+		if !ok {
+			return violation("expected a base64 string for binary field, got %T", value)
+		}
+		if _, err := base64.StdEncoding.DecodeString(s); err != nil {
+			return violation("expected a base64 string for binary field: %v", err)
+		}
+	case "knn_vector":
+		values, ok := value.([]any)
+		if !ok {
+			return violation("expected an array of numbers for knn_vector field, got %T", value)
+		}
+		if field.Dimension > 0 && len(values) != field.Dimension {
+			return violation("expected a vector of dimension %d, got %d", field.Dimension, len(values))
+		}
+		for _, item := range values {
+			if _, ok := item.(json.Number); !ok {
+				return violation("expected an array of numbers for knn_vector field, got an item of %T", item)
+			}
+		}
+	case "object", "nested":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return violation("expected an object for %s field, got %T", typ, value)
+		}
+		violations := []Violation{}
+		for name, item := range obj {
+			violations = append(violations, v.validateField(path+"."+name, field.Properties[name], item)...)
+		}
+		return violations
+	}
+
+	return nil
+}
+
+// isDate returns true if the given string matches OpenSearch's default date format,
+// which is an ISO 8601 date with an optional time.
+func isDate(s string) bool {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02"} {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,102 @@
+package opensearch
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+func TestValidator(t *testing.T) {
+	mapping := `{"mappings": {"properties": {
+		"type": {"type": "keyword"},
+		"workflow_id": {"type": "long"},
+		"test_case_name": {"type": "text", "fields": {"keyword": {"type": "keyword", "ignore_above": 256}}},
+		"test_case_status": {"type": "keyword"},
+		"workflow_created_at": {"type": "date"},
+		"test_case_failure_embedding": {"type": "knn_vector", "dimension": 2},
+		"test_case_owner_weights": {"type": "nested", "properties": {"weight": {"type": "double"}}}
+	}}}`
+
+	v, err := NewValidator([]byte(mapping), 64, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.NoError(t, err)
+
+	violations, err := v.Validate([]byte(`{
+		"type": "test_case", "workflow_id": 1, "test_case_name": "a", "unmapped": {"x": 1},
+		"workflow_created_at": "2024-03-01T10:00:00.5Z", "test_case_failure_embedding": [0.1, 0.2],
+		"test_case_owner_weights": [{"weight": 0.5}, {"weight": 0.5}]
+	}`), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, violations)
+
+	violations, err = v.Validate([]byte(`{
+		"type": "test_case", "workflow_id": "x", "test_case_status": "`+strings.Repeat("a", 65)+`",
+		"workflow_created_at": "yesterday", "test_case_failure_embedding": [0.1],
+		"test_case_owner_weights": [{"weight": "heavy"}]
+	}`), nil)
+	assert.NoError(t, err)
+
+	fields := []string{}
+	for _, violation := range violations {
+		fields = append(fields, violation.Field)
+	}
+	assert.Equal(t, []string{
+		"test_case_failure_embedding", "test_case_name", "test_case_owner_weights.weight",
+		"test_case_status", "workflow_created_at", "workflow_id",
+	}, fields)
+
+	filter, err := ParseFieldFilter(nil, []string{"test_case_name"})
+	assert.NoError(t, err)
+	assert.True(t, v.Check([]byte(`{"type": "test_case", "workflow_id": 1}`), "id", filter))
+	assert.False(t, v.Check([]byte(`{"type": "test_case", "workflow_id": 1}`), "id", nil))
+	assert.Equal(t, int64(1), v.Rejected())
+}
+
+func TestValidatorIngestStats(t *testing.T) {
+	v, err := LoadValidator("../../opensearch/mappings.json", 0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.NoError(t, err)
+
+	doc, err := json.Marshal(types.IngestStats{
+		Type:      types.TypeNameIngestStats,
+		Command:   "corgi workflow runs",
+		StartedAt: time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC),
+		Duration:  time.Hour,
+		Stages:    map[string]types.IngestStage{"fetch": {Operations: 3, Duration: time.Minute}},
+	})
+	assert.NoError(t, err)
+
+	violations, err := v.Validate(doc, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, violations)
+}
+
+func TestValidatorAuditEvent(t *testing.T) {
+	v, err := LoadValidator("../../opensearch/mappings.json", 0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	assert.NoError(t, err)
+
+	event := types.AuditEvent{
+		Type:        types.TypeNameAuditEvent,
+		Command:     "corgi reingest",
+		Actor:       "octocat",
+		Flags:       []string{"run-id", "force"},
+		ConfigHash:  "abc",
+		StartedAt:   time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC),
+		WorkflowIDs: []int64{1, 2},
+	}
+	doc, err := json.Marshal(event)
+	assert.NoError(t, err)
+
+	violations, err := v.Validate(doc, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, violations)
+
+	id, err := GetDocumentID(event)
+	assert.NoError(t, err)
+	assert.Equal(t, "audit-event--corgi reingest-1709287200000000000", id)
+}
@@ -0,0 +1,186 @@
+package opensearch
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+const (
+	// maxWebhookAttempts is how often a batch is posted to the webhook before
+	// it is dropped.
+	maxWebhookAttempts = 3
+
+	// WebhookSignatureHeader holds the HMAC-SHA256 of the body of webhook requests,
+	// in the form sha256=<hex>, in the same way as GitHub signs its webhooks.
+	WebhookSignatureHeader = "X-Corgi-Signature-256"
+)
+
+// WebhookDocument is a document posted to the webhook.
+type WebhookDocument struct {
+	Index    string          `json:"index"`
+	ID       string          `json:"id,omitempty"`
+	Document json.RawMessage `json:"document"`
+}
+
+// WebhookWriter passes bulk entries through to a target writer, while also posting
+// the documents they contain in batches to a webhook, so that other systems can react
+// to ingested data without polling OpenSearch. Each batch is posted as a JSON array of
+// WebhookDocument. As the webhook is a side channel, batches which can't be delivered
+// are logged and dropped rather than failing the ingestion.
+type WebhookWriter struct {
+	target     io.Writer
+	logger     *slog.Logger
+	url        string
+	secret     *util.Secret
+	batchDocs  int
+	types      []string
+	backoff    time.Duration
+	httpClient *http.Client
+
+	batch []WebhookDocument
+}
+
+// NewWebhookWriter creates a new WebhookWriter posting batches of up to batchDocs documents
+// to url, while writing all entries to target. If secret is set, requests are signed with
+// it. If typs is not empty, only documents of the given types are posted.
+func NewWebhookWriter(
+	target io.Writer,
+	logger *slog.Logger,
+	url string,
+	secret *util.Secret,
+	batchDocs int,
+	typs []string,
+) *WebhookWriter {
+	return &WebhookWriter{
+		target:     target,
+		logger:     logger.With("webhook", url),
+		url:        url,
+		secret:     secret,
+		batchDocs:  max(1, batchDocs),
+		types:      typs,
+		backoff:    time.Second,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (w *WebhookWriter) Write(p []byte) (int, error) {
+	n, err := w.target.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	action, doc, ok := bytes.Cut(bytes.TrimSuffix(p, []byte("\n")), []byte("\n"))
+	if !ok {
+		return n, nil
+	}
+
+	if len(w.types) > 0 {
+		typ := struct {
+			Type string `json:"type"`
+		}{}
+		if err := json.Unmarshal(doc, &typ); err != nil || !slices.Contains(w.types, typ.Type) {
+			return n, nil
+		}
+	}
+
+	meta := map[string]struct {
+		Index string `json:"_index"`
+		ID    string `json:"_id"`
+	}{}
+	if err := json.Unmarshal(action, &meta); err != nil {
+		w.logger.Warn("Unable to parse bulk action, not posting document to webhook", "err", err)
+		return n, nil
+	}
+
+	for _, m := range meta {
+		w.batch = append(w.batch, WebhookDocument{Index: m.Index, ID: m.ID, Document: bytes.Clone(doc)})
+	}
+
+	if len(w.batch) >= w.batchDocs {
+		w.flush()
+	}
+
+	return n, nil
+}
+
+// Flush posts the buffered documents and flushes the target.
+func (w *WebhookWriter) Flush() error {
+	w.flush()
+	return Flush(w.target)
+}
+
+// Close posts the remaining documents. The target is not closed.
+func (w *WebhookWriter) Close() error {
+	w.flush()
+	return nil
+}
+
+// Sign returns the value of the signature header of a request with the given body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (w *WebhookWriter) flush() {
+	if len(w.batch) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(w.batch)
+	w.batch = nil
+	if err != nil {
+		w.logger.Error("Unable to marshal webhook batch, dropping it", "err", err)
+		return
+	}
+
+	for attempt := 1; attempt <= maxWebhookAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(w.backoff << (attempt - 2))
+		}
+
+		if err = w.post(body); err == nil {
+			return
+		}
+		w.logger.Warn("Unable to post batch to webhook", "attempt", attempt, "err", err)
+	}
+
+	w.logger.Error("Dropping batch which couldn't be posted to webhook", "bytes", len(body), "err", err)
+}
+
+func (w *WebhookWriter) post(body []byte) error {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.secret != nil && w.secret.Value() != "" {
+		req.Header.Set(WebhookSignatureHeader, Sign(w.secret.Value(), body))
+	}
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
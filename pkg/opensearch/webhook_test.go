@@ -0,0 +1,50 @@
+package opensearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+func TestWebhookWriter(t *testing.T) {
+	batches := [][]WebhookDocument{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, Sign("secret", body), r.Header.Get(WebhookSignatureHeader))
+
+		batch := []WebhookDocument{}
+		assert.NoError(t, json.Unmarshal(body, &batch))
+		batches = append(batches, batch)
+	}))
+	defer server.Close()
+
+	t.Setenv("WEBHOOK_TEST_SECRET", "secret")
+	secret, err := util.NewSecret("WEBHOOK_TEST_SECRET")
+	assert.NoError(t, err)
+
+	target := &bytes.Buffer{}
+	w := NewWebhookWriter(target, slog.New(slog.NewTextHandler(io.Discard, nil)), server.URL, secret, 2, []string{"test_case"})
+
+	for i, typ := range []string{"test_case", "workflow_run", "test_case", "test_case"} {
+		entry := &BulkEntry{Index: "runs", ID: string(rune('a' + i)), Verb: "index", Data: []byte(`{"type":"` + typ + `"}`)}
+		assert.NoError(t, entry.Write(w))
+	}
+	assert.NoError(t, w.Close())
+
+	assert.Equal(t, 4, bytes.Count(target.Bytes(), []byte("\n"))/2)
+	assert.Len(t, batches, 2)
+	assert.Equal(t, []WebhookDocument{
+		{Index: "runs", ID: "a", Document: json.RawMessage(`{"type":"test_case"}`)},
+		{Index: "runs", ID: "c", Document: json.RawMessage(`{"type":"test_case"}`)},
+	}, batches[0])
+	assert.Equal(t, "d", batches[1][0].ID)
+}
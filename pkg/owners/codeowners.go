@@ -0,0 +1,133 @@
+package owners
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// CodeownersRule assigns owners to the paths matching a pattern.
+type CodeownersRule struct {
+	Pattern string
+	Owners  []string
+}
+
+// Codeowners is a parsed CODEOWNERS file.
+type Codeowners struct {
+	Rules []CodeownersRule
+}
+
+// ParseCodeowners parses a CODEOWNERS file, skipping comments and empty lines.
+func ParseCodeowners(r io.Reader) (*Codeowners, error) {
+	c := &Codeowners{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(stripComment(scanner.Text()))
+		if len(fields) == 0 {
+			continue
+		}
+
+		c.Rules = append(c.Rules, CodeownersRule{Pattern: fields[0], Owners: fields[1:]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read CODEOWNERS: %w", err)
+	}
+
+	return c, nil
+}
+
+// Owners returns the owners of the given path, relative to the root of the repository,
+// according to the last matching rule.
+func (c *Codeowners) Owners(p string) []string {
+	p = strings.TrimPrefix(path.Clean("/"+p), "/")
+
+	for i := len(c.Rules) - 1; i >= 0; i-- {
+		if matchPattern(c.Rules[i].Pattern, p) {
+			return c.Rules[i].Owners
+		}
+	}
+
+	return nil
+}
+
+// stripComment removes a comment from a line of a CODEOWNERS file. Comments start with a
+// '#' at the beginning of the line or after whitespace, so that escaped '\#' in patterns
+// are kept.
+func stripComment(line string) string {
+	for i, r := range line {
+		if r == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t') {
+			return line[:i]
+		}
+	}
+
+	return line
+}
+
+// matchPattern returns true if the given CODEOWNERS pattern matches the path or one of
+// its parent directories, following the gitignore syntax: patterns with a slash at the
+// beginning or in the middle are relative to the root, others match at any depth. A
+// trailing slash only matches directories, '*' and '?' don't match slashes, and '**'
+// matches any number of directories. Unlike in gitignore, a trailing '/*' only matches
+// the direct children of a directory, as documented for CODEOWNERS by GitHub.
+func matchPattern(pattern, p string) bool {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	childrenOnly := strings.HasSuffix(pattern, "/*")
+
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	patternSegments := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+
+	// A pattern matching a directory matches everything within it.
+	segments := strings.Split(p, "/")
+	for end := 1; end <= len(segments); end++ {
+		if dirOnly && end == len(segments) {
+			break
+		}
+		if childrenOnly && end < len(segments) {
+			continue
+		}
+
+		if matchSegments(patternSegments, segments[:end]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchSegments returns true if the segments of a pattern match those of a path.
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+
+	if pattern[0] == "**" {
+		// A trailing '**' matches everything within a directory, but not the directory.
+		if len(pattern) == 1 {
+			return len(segments) > 0
+		}
+
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if len(segments) == 0 {
+		return false
+	}
+
+	if ok, err := path.Match(pattern[0], segments[0]); !ok || err != nil {
+		return false
+	}
+
+	return matchSegments(pattern[1:], segments[1:])
+}
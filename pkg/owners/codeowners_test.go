@@ -0,0 +1,85 @@
+package owners
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeowners(t *testing.T) {
+	c, err := ParseCodeowners(strings.NewReader(`
+# Comment
+*                  @cilium/tophat
+*.md               @cilium/docs
+/pkg/              @cilium/sig-agent
+/pkg/datapath/     @cilium/sig-datapath @cilium/sig-lb # trailing comment
+test/              @cilium/ci
+`))
+	assert.NoError(t, err)
+
+	for p, expected := range map[string][]string{
+		"main.go":                        {"@cilium/tophat"},
+		"Documentation/index.md":         {"@cilium/docs"},
+		"pkg/policy/api.go":              {"@cilium/sig-agent"},
+		"pkg/datapath/linux/route.go":    {"@cilium/sig-datapath", "@cilium/sig-lb"},
+		"operator/test/e2e.go":           {"@cilium/ci"},
+		"test":                           {"@cilium/tophat"},
+		"/pkg/datapath/../policy/api.go": {"@cilium/sig-agent"},
+	} {
+		assert.Equal(t, expected, c.Owners(p), p)
+	}
+}
+
+func TestCodeownersGitignorePatterns(t *testing.T) {
+	c, err := ParseCodeowners(strings.NewReader(`
+*                  @cilium/tophat
+/docs/*            @cilium/docs
+**/logs            @cilium/logs
+/build/**/gen/     @cilium/build
+/vendor/**         @cilium/vendor
+api/v?/*.proto     @cilium/api
+\#notes            @cilium/notes
+`))
+	assert.NoError(t, err)
+
+	for p, expected := range map[string][]string{
+		"docs/index.md":                {"@cilium/docs"},
+		"docs/guides/index.md":         {"@cilium/tophat"},
+		"logs/agent.log":               {"@cilium/logs"},
+		"pkg/monitor/logs":             {"@cilium/logs"},
+		"build/gen/types.go":           {"@cilium/build"},
+		"build/images/base/gen/x.go":   {"@cilium/build"},
+		"build/images/gen":             {"@cilium/tophat"},
+		"vendor/github.com/foo/foo.go": {"@cilium/vendor"},
+		"vendor":                       {"@cilium/tophat"},
+		"api/v1/flow.proto":            {"@cilium/api"},
+		"api/v1/flow/flow.proto":       {"@cilium/tophat"},
+		"pkg/api/v1/flow.proto":        {"@cilium/tophat"},
+		"#notes":                       {"@cilium/notes"},
+	} {
+		assert.Equal(t, expected, c.Owners(p), p)
+	}
+}
+
+func TestTeams(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "teams.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"teams": {
+		"@cilium/sig-datapath": {"name": "datapath", "slack_channel": "#sig-datapath", "jira_component": "Datapath"},
+		"@Cilium/SIG-BPF": {"name": "datapath", "slack_channel": "#sig-datapath", "jira_component": "Datapath"},
+		"@cilium/sig-policy": {"name": "policy", "slack_channel": "#sig-policy"}
+	}}`), 0o644))
+
+	teams, err := LoadTeams(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []Team{
+		{Name: "datapath", SlackChannel: "#sig-datapath", JiraComponent: "Datapath"},
+		{Name: "policy", SlackChannel: "#sig-policy"},
+	}, teams.For([]string{"@cilium/sig-bpf", "@cilium/unknown", "@cilium/sig-datapath", "@cilium/sig-policy"}))
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"teams": {"@cilium/sig-foo": {"slack_channel": "#foo"}}}`), 0o644))
+	_, err = LoadTeams(path)
+	assert.ErrorContains(t, err, "team of @cilium/sig-foo")
+}
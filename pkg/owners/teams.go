@@ -0,0 +1,73 @@
+package owners
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Team describes the team behind an owner handle.
+type Team struct {
+	// Name is the canonical name of the team, such as datapath.
+	Name string `json:"name"`
+	// SlackChannel is the channel the team is reached at, such as #sig-datapath.
+	SlackChannel string `json:"slack_channel"`
+	// JiraComponent is the component of issues assigned to the team.
+	JiraComponent string `json:"jira_component"`
+}
+
+// Teams maps owner handles, such as GitHub teams, to the teams behind them.
+type Teams struct {
+	Teams map[string]Team `json:"teams"`
+}
+
+// LoadTeams reads the team mapping at the given path, such as:
+//
+//	{
+//	  "teams": {
+//	    "@cilium/sig-datapath": {"name": "datapath", "slack_channel": "#sig-datapath", "jira_component": "Datapath"},
+//	    "@cilium/sig-bpf": {"name": "datapath", "slack_channel": "#sig-datapath", "jira_component": "Datapath"}
+//	  }
+//	}
+//
+// Handles are matched case-insensitively, since GitHub handles are.
+func LoadTeams(path string) (*Teams, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read teams: %w", err)
+	}
+
+	t := &Teams{}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("unable to parse teams %s: %w", path, err)
+	}
+
+	teams := make(map[string]Team, len(t.Teams))
+	for handle, team := range t.Teams {
+		if team.Name == "" {
+			return nil, fmt.Errorf("team of %s in %s has no name", handle, path)
+		}
+		teams[strings.ToLower(handle)] = team
+	}
+	t.Teams = teams
+
+	return t, nil
+}
+
+// For returns the teams behind the given owner handles, in the order of the handles.
+// Teams behind multiple handles are returned once, and handles without a team are
+// skipped.
+func (t *Teams) For(handles []string) []Team {
+	teams := []Team{}
+	for _, handle := range handles {
+		team, ok := t.Teams[strings.ToLower(handle)]
+		if !ok || slices.Contains(teams, team) {
+			continue
+		}
+		teams = append(teams, team)
+	}
+
+	return teams
+}
@@ -0,0 +1,192 @@
+// Package plugin loads WebAssembly modules which add artifact parsers and document
+// enrichers to corgi, so that custom formats can be supported without forking it. Modules
+// run sandboxed: they only get WASI stdio, without access to files, the network or the
+// environment.
+//
+// A module must export its memory as "memory" and a "corgi_alloc" function taking a size
+// and returning the address of that many bytes it allocated, which corgi writes the input
+// of the following call to. It then exports one or both of:
+//
+//   - corgi_parse(ptr, len i32) i64, which converts the file at ptr of an artifact into
+//     JUnit XML, or returns no output for files it doesn't handle.
+//   - corgi_enrich(ptr, len i32) i64, which enriches the documents at ptr, given and
+//     returned as JSON, one per line, in the same order. Lines with null drop documents.
+//
+// Both return the address of their output in the upper 32 bits and its length in the
+// lower 32 bits of the result. A module fails a call by trapping, for example with
+// unreachable, after writing the reason to stderr. Each call runs in a fresh instance of
+// the module, so modules don't need to free memory or be safe for concurrent use.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+const (
+	exportAlloc  = "corgi_alloc"
+	exportParse  = "corgi_parse"
+	exportEnrich = "corgi_enrich"
+
+	// memoryLimitPages caps the memory of each instance at 1GiB, in pages of 64KiB.
+	memoryLimitPages = 16384
+)
+
+// Plugin is a compiled WebAssembly module implementing the plugin ABI.
+type Plugin struct {
+	Name string
+
+	runtime  wazero.Runtime
+	compiled wazero.CompiledModule
+	// timeout bounds each call of corgi_enrich. Calls of corgi_parse are bounded by the
+	// context they are given.
+	timeout time.Duration
+}
+
+// ParseSpec parses a plugin given in the form of '<name>=<path>'.
+func ParseSpec(s string) (name, path string, err error) {
+	name, path, ok := strings.Cut(s, "=")
+	if !ok || name == "" || path == "" {
+		return "", "", fmt.Errorf("expected '<name>=<path>', got '%s'", s)
+	}
+
+	return name, path, nil
+}
+
+// Load compiles the module at the given path. Calls of corgi_enrich are bounded by the
+// given timeout.
+func Load(ctx context.Context, name, path string, timeout time.Duration) (*Plugin, error) {
+	binary, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read plugin %s: %w", name, err)
+	}
+
+	runtime := wazero.NewRuntimeWithConfig(
+		ctx, wazero.NewRuntimeConfig().WithCloseOnContextDone(true).WithMemoryLimitPages(memoryLimitPages),
+	)
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("unable to instantiate WASI for plugin %s: %w", name, err)
+	}
+
+	compiled, err := runtime.CompileModule(ctx, binary)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("unable to compile plugin %s: %w", name, err)
+	}
+
+	p := &Plugin{Name: name, runtime: runtime, compiled: compiled, timeout: timeout}
+
+	if _, ok := compiled.ExportedMemories()["memory"]; !ok {
+		p.Close(ctx)
+		return nil, fmt.Errorf("plugin %s doesn't export its memory", name)
+	}
+	if !p.exports(exportAlloc) {
+		p.Close(ctx)
+		return nil, fmt.Errorf("plugin %s doesn't export %s", name, exportAlloc)
+	}
+	if !p.IsParser() && !p.IsEnricher() {
+		p.Close(ctx)
+		return nil, fmt.Errorf("plugin %s exports neither %s nor %s", name, exportParse, exportEnrich)
+	}
+
+	return p, nil
+}
+
+// Close releases the compiled module.
+func (p *Plugin) Close(ctx context.Context) error {
+	return p.runtime.Close(ctx)
+}
+
+func (p *Plugin) exports(function string) bool {
+	_, ok := p.compiled.ExportedFunctions()[function]
+	return ok
+}
+
+// IsParser returns whether the plugin converts artifacts into JUnit files.
+func (p *Plugin) IsParser() bool {
+	return p.exports(exportParse)
+}
+
+// IsEnricher returns whether the plugin enriches documents.
+func (p *Plugin) IsEnricher() bool {
+	return p.exports(exportEnrich)
+}
+
+// call runs the given function of a new instance of the module with the given input,
+// returning its output.
+func (p *Plugin) call(ctx context.Context, function string, input []byte) ([]byte, error) {
+	stderr := &bytes.Buffer{}
+	// Reactor modules, such as built by TinyGo or Rust, initialize themselves in
+	// _initialize, while _start would run a command module to completion.
+	mod, err := p.runtime.InstantiateModule(
+		ctx, p.compiled,
+		wazero.NewModuleConfig().WithName("").WithStartFunctions("_initialize").WithStderr(stderr),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to instantiate plugin %s: %w", p.Name, err)
+	}
+	defer mod.Close(ctx)
+
+	fail := func(err error) error {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("plugin %s failed in %s: %w: %s", p.Name, function, err, msg)
+		}
+		return fmt.Errorf("plugin %s failed in %s: %w", p.Name, function, err)
+	}
+
+	res, err := mod.ExportedFunction(exportAlloc).Call(ctx, uint64(len(input)))
+	if err != nil {
+		return nil, fail(err)
+	}
+	ptr := uint32(res[0])
+	if !mod.Memory().Write(ptr, input) {
+		return nil, fail(fmt.Errorf("allocated %d bytes out of memory at %d", len(input), ptr))
+	}
+
+	res, err = mod.ExportedFunction(function).Call(ctx, uint64(ptr), uint64(len(input)))
+	if err != nil {
+		return nil, fail(err)
+	}
+
+	outPtr, outLen := uint32(res[0]>>32), uint32(res[0])
+	out, ok := mod.Memory().Read(outPtr, outLen)
+	if !ok {
+		return nil, fail(fmt.Errorf("returned %d bytes out of memory at %d", outLen, outPtr))
+	}
+
+	// The memory is released once the instance is closed.
+	return bytes.Clone(out), nil
+}
+
+// Convert implements junit.Converter.
+func (p *Plugin) Convert(ctx context.Context, path string, data []byte) ([]byte, error) {
+	return p.call(ctx, exportParse, data)
+}
+
+// Apply implements opensearch.DocumentHook.
+func (p *Plugin) Apply(index string, docs [][]byte) ([][]byte, error) {
+	if len(docs) == 0 {
+		return docs, nil
+	}
+
+	ctx, cancel := util.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	out, err := p.call(ctx, exportEnrich, opensearch.EncodeHookInput(docs))
+	if err != nil {
+		return nil, err
+	}
+
+	return opensearch.DecodeHookOutput(out, len(docs))
+}
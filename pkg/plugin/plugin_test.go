@@ -0,0 +1,100 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// echoModule is the binary of the following module, which returns its input from both
+// corgi_parse and corgi_enrich:
+//
+//	(module
+//	  (memory (export "memory") 2)
+//	  (func (export "corgi_alloc") (param i32) (result i32) i32.const 1024)
+//	  (func $echo (param i32 i32) (result i64)
+//	    local.get 0 i64.extend_i32_u i64.const 32 i64.shl
+//	    local.get 1 i64.extend_i32_u i64.or)
+//	  (export "corgi_parse" (func $echo))
+//	  (export "corgi_enrich" (func $echo)))
+var echoModule = []byte{
+	0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00,
+	// Types: (i32) -> i32 and (i32, i32) -> i64.
+	0x01, 0x0c, 0x02, 0x60, 0x01, 0x7f, 0x01, 0x7f, 0x60, 0x02, 0x7f, 0x7f, 0x01, 0x7e,
+	// Functions.
+	0x03, 0x03, 0x02, 0x00, 0x01,
+	// Memory of two pages.
+	0x05, 0x03, 0x01, 0x00, 0x02,
+	// Exports.
+	0x07, 0x35, 0x04,
+	0x06, 'm', 'e', 'm', 'o', 'r', 'y', 0x02, 0x00,
+	0x0b, 'c', 'o', 'r', 'g', 'i', '_', 'a', 'l', 'l', 'o', 'c', 0x00, 0x00,
+	0x0b, 'c', 'o', 'r', 'g', 'i', '_', 'p', 'a', 'r', 's', 'e', 0x00, 0x01,
+	0x0c, 'c', 'o', 'r', 'g', 'i', '_', 'e', 'n', 'r', 'i', 'c', 'h', 0x00, 0x01,
+	// Code.
+	0x0a, 0x14, 0x02,
+	0x05, 0x00, 0x41, 0x80, 0x08, 0x0b,
+	0x0c, 0x00, 0x20, 0x00, 0xad, 0x42, 0x20, 0x86, 0x20, 0x01, 0xad, 0x84, 0x0b,
+}
+
+func writeModule(t *testing.T, binary []byte) string {
+	path := filepath.Join(t.TempDir(), "plugin.wasm")
+	assert.NoError(t, os.WriteFile(path, binary, 0o600))
+
+	return path
+}
+
+func TestParseSpec(t *testing.T) {
+	name, path, err := ParseSpec("tap=plugins/tap.wasm")
+	assert.NoError(t, err)
+	assert.Equal(t, "tap", name)
+	assert.Equal(t, "plugins/tap.wasm", path)
+
+	for _, s := range []string{"tap", "=tap.wasm", "tap="} {
+		_, _, err := ParseSpec(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	ctx := context.Background()
+
+	p, err := Load(ctx, "echo", writeModule(t, echoModule), time.Minute)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer p.Close(ctx)
+
+	assert.True(t, p.IsParser())
+	assert.True(t, p.IsEnricher())
+
+	out, err := p.Convert(ctx, "results.tap", []byte("<testsuite></testsuite>"))
+	assert.NoError(t, err)
+	assert.Equal(t, "<testsuite></testsuite>", string(out))
+
+	docs, err := p.Apply("index", [][]byte{[]byte(`{"a":1}`), []byte(`{"b":2}`)})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte(`{"a":1}`), []byte(`{"b":2}`)}, docs)
+
+	// The input doesn't fit into the memory of the module.
+	_, err = p.Convert(ctx, "results.tap", make([]byte, 1<<20))
+	assert.ErrorContains(t, err, "out of memory")
+}
+
+func TestLoadInvalid(t *testing.T) {
+	ctx := context.Background()
+
+	_, err := Load(ctx, "missing", filepath.Join(t.TempDir(), "missing.wasm"), time.Minute)
+	assert.Error(t, err)
+
+	_, err = Load(ctx, "garbage", writeModule(t, []byte("not wasm")), time.Minute)
+	assert.ErrorContains(t, err, "unable to compile")
+
+	// A module without exports.
+	_, err = Load(ctx, "empty", writeModule(t, echoModule[:8]), time.Minute)
+	assert.ErrorContains(t, err, "doesn't export its memory")
+}
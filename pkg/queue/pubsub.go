@@ -0,0 +1,188 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+const (
+	pubSubEndpoint = "https://pubsub.googleapis.com"
+	// metadataTokenURL returns access tokens of the service account of the GCE instance or
+	// GKE workload corgi runs as.
+	metadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	// pubSubMaxMessages is the maximum number of messages received at once.
+	pubSubMaxMessages = 10
+)
+
+// PubSub consumes jobs from a Google Pub/Sub subscription through its REST API. Requests
+// are authenticated with the access token in GOOGLE_OAUTH_ACCESS_TOKEN, or with a token
+// of the service account of the instance from the metadata server otherwise. If
+// PUBSUB_EMULATOR_HOST is set, the emulator is used without authentication.
+type PubSub struct {
+	subscription string
+	endpoint     string
+	token        *util.Secret
+	httpClient   *http.Client
+
+	// metadata caches the access token from the metadata server.
+	metadataMu      sync.Mutex
+	metadataToken   string
+	metadataExpires time.Time
+}
+
+// NewPubSub creates a consumer of the given subscription, such as
+// projects/my-project/subscriptions/corgi-jobs.
+func NewPubSub(subscription string) (*PubSub, error) {
+	q := &PubSub{
+		subscription: subscription,
+		endpoint:     pubSubEndpoint,
+		httpClient:   &http.Client{Timeout: 2 * time.Minute},
+	}
+
+	if host := os.Getenv("PUBSUB_EMULATOR_HOST"); host != "" {
+		q.endpoint = "http://" + host
+		return q, nil
+	}
+
+	if os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN") != "" || os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN_FILE") != "" {
+		token, err := util.NewSecret("GOOGLE_OAUTH_ACCESS_TOKEN")
+		if err != nil {
+			return nil, err
+		}
+		q.token = token
+	}
+
+	return q, nil
+}
+
+// Receive implements Queue.
+func (q *PubSub) Receive(ctx context.Context) ([]Message, error) {
+	result := struct {
+		ReceivedMessages []struct {
+			AckID   string `json:"ackId"`
+			Message struct {
+				MessageID string `json:"messageId"`
+				Data      string `json:"data"`
+			} `json:"message"`
+		} `json:"receivedMessages"`
+	}{}
+	if err := q.call(ctx, "pull", map[string]any{"maxMessages": pubSubMaxMessages}, &result); err != nil {
+		return nil, err
+	}
+
+	msgs := make([]Message, 0, len(result.ReceivedMessages))
+	for _, m := range result.ReceivedMessages {
+		body, err := base64.StdEncoding.DecodeString(m.Message.Data)
+		if err != nil {
+			// Keep the raw data, so that the message is rejected as an invalid job.
+			body = []byte(m.Message.Data)
+		}
+		msgs = append(msgs, Message{ID: m.Message.MessageID, Body: body, ackID: m.AckID})
+	}
+
+	return msgs, nil
+}
+
+// Ack implements Queue.
+func (q *PubSub) Ack(ctx context.Context, msg Message) error {
+	return q.call(ctx, "acknowledge", map[string]any{"ackIds": []string{msg.ackID}}, nil)
+}
+
+// call calls the given method of the subscription, decoding its response into result.
+func (q *PubSub) call(ctx context.Context, method string, params map[string]any, result any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("unable to marshal %s request: %w", method, err)
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, q.endpoint+"/v1/"+q.subscription+":"+method, bytes.NewReader(body),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to create %s request: %w", method, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if q.endpoint == pubSubEndpoint {
+		token, err := q.accessToken(ctx)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := q.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to call %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unable to call %s, bad http code %s: %s", method, resp.Status, msg)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("unable to decode %s response: %w", method, err)
+	}
+
+	return nil
+}
+
+// accessToken returns the token to authenticate requests with.
+func (q *PubSub) accessToken(ctx context.Context) (string, error) {
+	if q.token != nil {
+		return q.token.Value(), nil
+	}
+
+	q.metadataMu.Lock()
+	defer q.metadataMu.Unlock()
+
+	// Refresh the token a minute before it expires, so that it doesn't expire in flight.
+	if q.metadataToken != "" && time.Now().Add(time.Minute).Before(q.metadataExpires) {
+		return q.metadataToken, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataTokenURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("unable to create metadata token request: %w", err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	resp, err := q.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("unable to get access token from metadata server, set GOOGLE_OAUTH_ACCESS_TOKEN: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unable to get access token from metadata server, bad http code %s", resp.Status)
+	}
+
+	token := struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("unable to decode access token from metadata server: %w", err)
+	}
+
+	q.metadataToken = token.AccessToken
+	q.metadataExpires = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+
+	return q.metadataToken, nil
+}
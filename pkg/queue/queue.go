@@ -0,0 +1,79 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Job asks for every attempt of a workflow run to be ingested. Jobs are published to
+// the queue as JSON, such as {"repository": "cilium/cilium", "run_id": 123}.
+type Job struct {
+	Repository string `json:"repository"`
+	RunID      int64  `json:"run_id"`
+}
+
+// Owner returns the owner of the repository of the job.
+func (j Job) Owner() string {
+	owner, _, _ := strings.Cut(j.Repository, "/")
+	return owner
+}
+
+// Name returns the name of the repository of the job.
+func (j Job) Name() string {
+	_, name, _ := strings.Cut(j.Repository, "/")
+	return name
+}
+
+// Message is a message received from a queue. It is delivered again unless it is
+// acknowledged before the visibility timeout or ack deadline of the queue expires.
+type Message struct {
+	ID   string
+	Body []byte
+
+	// ackID is the receipt handle or ack ID used to acknowledge the message.
+	ackID string
+}
+
+// Job decodes the job contained in the message.
+func (m Message) Job() (Job, error) {
+	job := Job{}
+	if err := json.Unmarshal(m.Body, &job); err != nil {
+		return Job{}, fmt.Errorf("unable to decode job: %w", err)
+	}
+
+	if owner, name, ok := strings.Cut(job.Repository, "/"); !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return Job{}, fmt.Errorf("expected repository in owner/name format, got '%s'", job.Repository)
+	}
+	if job.RunID <= 0 {
+		return Job{}, fmt.Errorf("expected positive run_id, got %d", job.RunID)
+	}
+
+	return job, nil
+}
+
+// Queue is a queue ingestion jobs are consumed from.
+type Queue interface {
+	// Receive waits for messages, returning an empty slice if none arrived in time.
+	Receive(ctx context.Context) ([]Message, error)
+	// Ack acknowledges the given message, so that it isn't delivered again.
+	Ack(ctx context.Context, msg Message) error
+}
+
+// New returns the queue with the given URL, which is either the URL of an SQS queue,
+// such as https://sqs.us-east-1.amazonaws.com/123456789012/corgi-jobs, or the name of a
+// Google Pub/Sub subscription, such as projects/my-project/subscriptions/corgi-jobs.
+func New(queueURL string) (Queue, error) {
+	switch {
+	case strings.HasPrefix(queueURL, "https://") || strings.HasPrefix(queueURL, "http://"):
+		return NewSQS(queueURL)
+	case strings.HasPrefix(queueURL, "projects/") && strings.Contains(queueURL, "/subscriptions/"):
+		return NewPubSub(queueURL)
+	default:
+		return nil, fmt.Errorf(
+			"expected url of an SQS queue or name of a Pub/Sub subscription in the form of "+
+				"'projects/<project>/subscriptions/<subscription>', got '%s'", queueURL,
+		)
+	}
+}
@@ -0,0 +1,136 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageJob(t *testing.T) {
+	job, err := Message{Body: []byte(`{"repository": "cilium/cilium", "run_id": 123}`)}.Job()
+	assert.NoError(t, err)
+	assert.Equal(t, Job{Repository: "cilium/cilium", RunID: 123}, job)
+	assert.Equal(t, "cilium", job.Owner())
+	assert.Equal(t, "cilium", job.Name())
+
+	for _, body := range []string{
+		`not json`,
+		`{"repository": "cilium", "run_id": 123}`,
+		`{"repository": "cilium/cilium/x", "run_id": 123}`,
+		`{"repository": "cilium/cilium"}`,
+	} {
+		_, err := Message{Body: []byte(body)}.Job()
+		assert.Error(t, err, body)
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+
+	q, err := New("https://sqs.eu-west-1.amazonaws.com/123456789012/corgi-jobs")
+	assert.NoError(t, err)
+	assert.Equal(t, "eu-west-1", q.(*SQS).signer.Region)
+
+	_, err = New("http://localhost:9324/000000000000/corgi-jobs")
+	assert.ErrorContains(t, err, "unable to determine region")
+
+	t.Setenv("PUBSUB_EMULATOR_HOST", "localhost:8085")
+	q, err = New("projects/corgi/subscriptions/jobs")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://localhost:8085", q.(*PubSub).endpoint)
+
+	_, err = New("corgi-jobs")
+	assert.Error(t, err)
+}
+
+func TestSQS(t *testing.T) {
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	deleted := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/x-amz-json-1.0", r.Header.Get("Content-Type"))
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/"))
+
+		params := map[string]any{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&params))
+		assert.Equal(t, "http://"+r.Host+"/000000000000/corgi-jobs", params["QueueUrl"])
+
+		switch r.Header.Get("X-Amz-Target") {
+		case "AmazonSQS.ReceiveMessage":
+			fmt.Fprint(w, `{"Messages": [{"MessageId": "a", "ReceiptHandle": "handle-a", "Body": "{\"repository\": \"cilium/cilium\", \"run_id\": 1}"}]}`)
+		case "AmazonSQS.DeleteMessage":
+			deleted = append(deleted, params["ReceiptHandle"].(string))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"__type": "com.amazonaws.sqs#InvalidAction", "message": "unknown action"}`)
+		}
+	}))
+	defer server.Close()
+
+	q, err := NewSQS(server.URL + "/000000000000/corgi-jobs")
+	assert.NoError(t, err)
+
+	msgs, err := q.Receive(context.Background())
+	assert.NoError(t, err)
+	if !assert.Len(t, msgs, 1) {
+		return
+	}
+	job, err := msgs[0].Job()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), job.RunID)
+
+	assert.NoError(t, q.Ack(context.Background(), msgs[0]))
+	assert.Equal(t, []string{"handle-a"}, deleted)
+
+	err = q.call(context.Background(), "Unknown", map[string]any{"QueueUrl": q.queueURL}, nil)
+	assert.ErrorContains(t, err, "InvalidAction")
+}
+
+func TestPubSub(t *testing.T) {
+	acked := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"))
+
+		switch r.URL.Path {
+		case "/v1/projects/corgi/subscriptions/jobs:pull":
+			// {"repository": "cilium/cilium", "run_id": 2}, base64 encoded.
+			fmt.Fprint(w, `{"receivedMessages": [{"ackId": "ack-b", "message": {"messageId": "b", "data": "eyJyZXBvc2l0b3J5IjogImNpbGl1bS9jaWxpdW0iLCAicnVuX2lkIjogMn0="}}]}`)
+		case "/v1/projects/corgi/subscriptions/jobs:acknowledge":
+			body := struct {
+				AckIDs []string `json:"ackIds"`
+			}{}
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			acked = append(acked, body.AckIDs...)
+			fmt.Fprint(w, `{}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("PUBSUB_EMULATOR_HOST", strings.TrimPrefix(server.URL, "http://"))
+
+	q, err := NewPubSub("projects/corgi/subscriptions/jobs")
+	assert.NoError(t, err)
+
+	msgs, err := q.Receive(context.Background())
+	assert.NoError(t, err)
+	if !assert.Len(t, msgs, 1) {
+		return
+	}
+	job, err := msgs[0].Job()
+	assert.NoError(t, err)
+	assert.Equal(t, Job{Repository: "cilium/cilium", RunID: 2}, job)
+
+	assert.NoError(t, q.Ack(context.Background(), msgs[0]))
+	assert.Equal(t, []string{"ack-b"}, acked)
+}
@@ -0,0 +1,152 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/isovalent/corgi/pkg/opensearch"
+)
+
+const (
+	// sqsWaitSeconds is how long a receive long polls for messages, which is the
+	// maximum allowed by SQS.
+	sqsWaitSeconds = 20
+	// sqsMaxMessages is the maximum number of messages received at once.
+	sqsMaxMessages = 10
+)
+
+// SQS consumes jobs from an Amazon SQS queue through its JSON API. Requests are signed
+// with the credentials in AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+type SQS struct {
+	queueURL   string
+	endpoint   string
+	signer     *opensearch.AWSSigner
+	httpClient *http.Client
+}
+
+// NewSQS creates a consumer of the SQS queue with the given URL. The region is taken
+// from the host of the URL, falling back to AWS_REGION and AWS_DEFAULT_REGION for
+// endpoints which don't contain it.
+func NewSQS(queueURL string) (*SQS, error) {
+	u, err := url.Parse(queueURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse queue url '%s': %w", queueURL, err)
+	}
+
+	region := ""
+	if parts := strings.Split(u.Hostname(), "."); len(parts) >= 4 && parts[0] == "sqs" {
+		region = parts[1]
+	}
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		return nil, fmt.Errorf("unable to determine region of queue '%s', set AWS_REGION", queueURL)
+	}
+
+	return &SQS{
+		queueURL: queueURL,
+		endpoint: u.Scheme + "://" + u.Host + "/",
+		signer: &opensearch.AWSSigner{
+			Region:       region,
+			Service:      "sqs",
+			AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		},
+		httpClient: &http.Client{Timeout: (sqsWaitSeconds + 10) * time.Second},
+	}, nil
+}
+
+// Receive implements Queue.
+func (q *SQS) Receive(ctx context.Context) ([]Message, error) {
+	result := struct {
+		Messages []struct {
+			MessageID     string `json:"MessageId"`
+			ReceiptHandle string `json:"ReceiptHandle"`
+			Body          string `json:"Body"`
+		} `json:"Messages"`
+	}{}
+	err := q.call(ctx, "ReceiveMessage", map[string]any{
+		"QueueUrl":            q.queueURL,
+		"MaxNumberOfMessages": sqsMaxMessages,
+		"WaitTimeSeconds":     sqsWaitSeconds,
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := make([]Message, 0, len(result.Messages))
+	for _, m := range result.Messages {
+		msgs = append(msgs, Message{ID: m.MessageID, Body: []byte(m.Body), ackID: m.ReceiptHandle})
+	}
+
+	return msgs, nil
+}
+
+// Ack implements Queue.
+func (q *SQS) Ack(ctx context.Context, msg Message) error {
+	return q.call(ctx, "DeleteMessage", map[string]any{
+		"QueueUrl":      q.queueURL,
+		"ReceiptHandle": msg.ackID,
+	}, nil)
+}
+
+// call calls the given action of the SQS API, decoding its response into result.
+func (q *SQS) call(ctx context.Context, action string, params map[string]any, result any) error {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("unable to marshal %s request: %w", action, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, q.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to create %s request: %w", action, err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	req.Header.Set("X-Amz-Target", "AmazonSQS."+action)
+
+	if err := q.signer.SignRequest(req); err != nil {
+		return fmt.Errorf("unable to sign %s request: %w", action, err)
+	}
+
+	resp, err := q.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to call %s: %w", action, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		apiErr := struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}{}
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if json.Unmarshal(msg, &apiErr) == nil && apiErr.Type != "" {
+			return fmt.Errorf("unable to call %s, bad http code %s: %s: %s", action, resp.Status, apiErr.Type, apiErr.Message)
+		}
+
+		return fmt.Errorf("unable to call %s, bad http code %s: %s", action, resp.Status, msg)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("unable to decode %s response: %w", action, err)
+	}
+
+	return nil
+}
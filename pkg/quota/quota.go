@@ -0,0 +1,212 @@
+package quota
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+const (
+	// ReasonDocsPerDay is reported when the daily budget of documents is exhausted.
+	ReasonDocsPerDay = "max-docs-per-day"
+	// ReasonArtifactBytesPerDay is reported when the daily budget of artifact bytes is
+	// exhausted.
+	ReasonArtifactBytesPerDay = "max-artifact-bytes-per-day"
+)
+
+// Limits bound how much a single repository may ingest. Zero means unlimited.
+type Limits struct {
+	// MaxRunsPerPoll is the maximum number of workflow runs pulled per polling cycle.
+	MaxRunsPerPoll int `json:"max_runs_per_poll"`
+	// MaxDocsPerDay is the maximum number of documents written per day, in UTC.
+	MaxDocsPerDay int64 `json:"max_docs_per_day"`
+	// MaxArtifactBytesPerDay is the maximum number of bytes of artifacts downloaded per
+	// day, in UTC.
+	MaxArtifactBytesPerDay int64 `json:"max_artifact_bytes_per_day"`
+}
+
+// Config holds the limits of each repository. Repositories without their own limits
+// use the default limits.
+type Config struct {
+	Default      Limits            `json:"default"`
+	Repositories map[string]Limits `json:"repositories"`
+}
+
+// LoadConfig reads the quota configuration at the given path, such as:
+//
+//	{
+//	  "default": {"max_runs_per_poll": 50, "max_docs_per_day": 1000000},
+//	  "repositories": {"cilium/cilium": {"max_docs_per_day": 5000000}}
+//	}
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read quotas: %w", err)
+	}
+
+	c := &Config{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("unable to parse quotas %s: %w", path, err)
+	}
+
+	return c, nil
+}
+
+// For returns the limits of the given repository, in owner/name format.
+func (c *Config) For(repo string) Limits {
+	if limits, ok := c.Repositories[repo]; ok {
+		return limits
+	}
+
+	return c.Default
+}
+
+// Usage is what a repository ingested on a day.
+type Usage struct {
+	Docs          int64 `json:"docs"`
+	ArtifactBytes int64 `json:"artifact_bytes"`
+}
+
+// Tracker tracks the daily usage of each repository against its limits. It is safe
+// for concurrent use.
+type Tracker struct {
+	config *Config
+
+	mu sync.Mutex
+	// day is the UTC date the usage was recorded on, in YYYY-MM-DD format.
+	day   string
+	usage map[string]*Usage
+
+	// now is overridden in tests.
+	now func() time.Time
+}
+
+// trackerFile is the on-disk format of a Tracker.
+type trackerFile struct {
+	Day   string            `json:"day"`
+	Usage map[string]*Usage `json:"usage"`
+}
+
+// NewTracker creates a Tracker for the given configuration without any usage.
+func NewTracker(config *Config) *Tracker {
+	return &Tracker{config: config, usage: map[string]*Usage{}, now: time.Now}
+}
+
+// LoadTracker creates a Tracker with the usage saved at the given path, so that the
+// daily budgets are shared by consecutive invocations. A missing file results in a
+// Tracker without any usage.
+func LoadTracker(config *Config, path string) (*Tracker, error) {
+	t := NewTracker(config)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read quota usage: %w", err)
+	}
+
+	f := trackerFile{}
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("unable to parse quota usage %s: %w", path, err)
+	}
+
+	t.day = f.Day
+	for repo, usage := range f.Usage {
+		t.usage[repo] = usage
+	}
+
+	return t, nil
+}
+
+// Limits returns the limits of the given repository.
+func (t *Tracker) Limits(repo string) Limits {
+	return t.config.For(repo)
+}
+
+// rollover resets the usage once a new day started. t.mu must be held.
+func (t *Tracker) rollover() {
+	day := t.now().UTC().Format(time.DateOnly)
+	if day != t.day {
+		t.day = day
+		t.usage = map[string]*Usage{}
+	}
+}
+
+// Exhausted returns the reason why the given repository may not ingest any more runs
+// today, or an empty string if it may.
+func (t *Tracker) Exhausted(repo string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rollover()
+
+	limits := t.config.For(repo)
+	usage, ok := t.usage[repo]
+	if !ok {
+		return ""
+	}
+
+	switch {
+	case limits.MaxDocsPerDay > 0 && usage.Docs >= limits.MaxDocsPerDay:
+		return ReasonDocsPerDay
+	case limits.MaxArtifactBytesPerDay > 0 && usage.ArtifactBytes >= limits.MaxArtifactBytesPerDay:
+		return ReasonArtifactBytesPerDay
+	}
+
+	return ""
+}
+
+// Add records that the given repository wrote the given number of documents and
+// downloaded the given number of artifact bytes.
+func (t *Tracker) Add(repo string, docs, artifactBytes int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rollover()
+
+	usage, ok := t.usage[repo]
+	if !ok {
+		usage = &Usage{}
+		t.usage[repo] = usage
+	}
+	usage.Docs += docs
+	usage.ArtifactBytes += artifactBytes
+}
+
+// Usage returns today's usage of the given repository.
+func (t *Tracker) Usage(repo string) Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rollover()
+
+	if usage, ok := t.usage[repo]; ok {
+		return *usage
+	}
+
+	return Usage{}
+}
+
+// Save writes the usage to the given path. The file is replaced atomically, so that
+// it is never left half-written.
+func (t *Tracker) Save(path string) error {
+	t.mu.Lock()
+	data, err := json.Marshal(trackerFile{Day: t.day, Usage: t.usage})
+	t.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("unable to encode quota usage: %w", err)
+	}
+
+	if err := util.WriteFileAtomic(path, data); err != nil {
+		return fmt.Errorf("unable to save quota usage file: %w", err)
+	}
+
+	return nil
+}
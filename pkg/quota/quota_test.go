@@ -0,0 +1,55 @@
+package quota
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quotas.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{
+		"default": {"max_runs_per_poll": 10, "max_docs_per_day": 100},
+		"repositories": {"cilium/cilium": {"max_artifact_bytes_per_day": 1000}}
+	}`), 0o644))
+
+	config, err := LoadConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, Limits{MaxRunsPerPoll: 10, MaxDocsPerDay: 100}, config.For("cilium/tetragon"))
+	assert.Equal(t, Limits{MaxArtifactBytesPerDay: 1000}, config.For("cilium/cilium"))
+
+	now := time.Date(2024, time.March, 1, 23, 0, 0, 0, time.UTC)
+	tracker := NewTracker(config)
+	tracker.now = func() time.Time { return now }
+
+	assert.Empty(t, tracker.Exhausted("cilium/tetragon"))
+	tracker.Add("cilium/tetragon", 99, 5000)
+	assert.Empty(t, tracker.Exhausted("cilium/tetragon"))
+	tracker.Add("cilium/tetragon", 1, 0)
+	assert.Equal(t, ReasonDocsPerDay, tracker.Exhausted("cilium/tetragon"))
+
+	tracker.Add("cilium/cilium", 1000, 1000)
+	assert.Equal(t, ReasonArtifactBytesPerDay, tracker.Exhausted("cilium/cilium"))
+
+	// Usage is saved and loaded for the same day.
+	state := filepath.Join(t.TempDir(), "usage.json")
+	assert.NoError(t, tracker.Save(state))
+
+	loaded, err := LoadTracker(config, state)
+	assert.NoError(t, err)
+	loaded.now = tracker.now
+	assert.Equal(t, Usage{Docs: 100, ArtifactBytes: 5000}, loaded.Usage("cilium/tetragon"))
+	assert.Equal(t, ReasonDocsPerDay, loaded.Exhausted("cilium/tetragon"))
+
+	// Budgets are reset on the next day.
+	now = now.Add(2 * time.Hour)
+	assert.Empty(t, loaded.Exhausted("cilium/tetragon"))
+	assert.Equal(t, Usage{}, loaded.Usage("cilium/cilium"))
+
+	missing, err := LoadTracker(config, filepath.Join(t.TempDir(), "missing.json"))
+	assert.NoError(t, err)
+	assert.Empty(t, missing.Exhausted("cilium/tetragon"))
+}
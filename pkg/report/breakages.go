@@ -0,0 +1,199 @@
+package report
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+
+	"github.com/isovalent/corgi/pkg/junit"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// Breakage is a test which failed in a push run after passing in the previous push run of
+// the same workflow which ran it, attributed to the commits pushed in between.
+type Breakage struct {
+	Workflow string
+	Test     string
+	Owners   []string
+	// Run is the first push run the test failed in, and LastPassed the push run it last
+	// passed in before that.
+	Run        *types.WorkflowRun
+	LastPassed *types.WorkflowRun
+	// Commits are the commits pushed after LastPassed up to Run, taken from the pushed
+	// commits of the runs in between, see types.WorkflowRun.PushCommits.
+	Commits []types.Commit
+}
+
+// runTests are the failed and passed tests of the latest attempt of a workflow run.
+type runTests struct {
+	run    *types.WorkflowRun
+	failed map[string][]string
+	passed map[string]struct{}
+}
+
+// BreakageBuilder finds the tests which started failing in push runs, in the order the
+// runs of each workflow were created in.
+type BreakageBuilder struct {
+	attempts map[runAttempt]*runTests
+}
+
+// NewBreakageBuilder creates a new BreakageBuilder.
+func NewBreakageBuilder() *BreakageBuilder {
+	return &BreakageBuilder{attempts: map[runAttempt]*runTests{}}
+}
+
+func (b *BreakageBuilder) attempt(run *types.WorkflowRun) *runTests {
+	key := runAttempt{id: run.ID, attempt: run.RunAttempt}
+	t, ok := b.attempts[key]
+	if !ok {
+		t = &runTests{run: run, failed: map[string][]string{}, passed: map[string]struct{}{}}
+		b.attempts[key] = t
+	}
+
+	return t
+}
+
+// AddWorkflowRun adds a completed push run attempt.
+func (b *BreakageBuilder) AddWorkflowRun(run *types.WorkflowRun) {
+	if run.Event != "push" || run.Status != "completed" {
+		return
+	}
+
+	b.attempt(run).run = run
+}
+
+// AddTestcase adds a testcase of a push run.
+func (b *BreakageBuilder) AddTestcase(tc *types.Testcase) {
+	if tc.Testsuite == nil || tc.WorkflowRun == nil || tc.WorkflowRun.Event != "push" {
+		return
+	}
+
+	switch {
+	case isFailed(tc.Status):
+		b.attempt(tc.WorkflowRun).failed[tc.Name] = tc.Owners
+	case tc.Status == junit.StatusPassed:
+		b.attempt(tc.WorkflowRun).passed[tc.Name] = struct{}{}
+	}
+}
+
+// Build returns the tests which failed in a push run after last passing in an earlier
+// push run of the same workflow, most recent first. Only the latest attempt of each run
+// counts, so tests which passed when the run was retried aren't blamed on the push.
+// Tests which never passed within the runs added aren't known to have been broken by a
+// push, so they are left out.
+func (b *BreakageBuilder) Build() []Breakage {
+	latest := map[int64]*runTests{}
+	for _, t := range b.attempts {
+		if l, ok := latest[t.run.ID]; !ok || t.run.RunAttempt > l.run.RunAttempt {
+			latest[t.run.ID] = t
+		}
+	}
+
+	workflows := map[string][]*runTests{}
+	for _, t := range latest {
+		// Attempts which weren't completed when they were ingested don't count.
+		if t.run.Status != "completed" {
+			continue
+		}
+		workflows[t.run.Name] = append(workflows[t.run.Name], t)
+	}
+
+	breakages := []Breakage{}
+	for workflow, runs := range workflows {
+		slices.SortFunc(runs, func(a, b *runTests) int {
+			return cmp.Or(a.run.CreatedAt.Compare(b.run.CreatedAt), cmp.Compare(a.run.ID, b.run.ID))
+		})
+
+		// lastPassed is the index of the run each test last passed in, for tests which
+		// didn't fail since.
+		lastPassed := map[string]int{}
+		for i, t := range runs {
+			for test, owners := range t.failed {
+				passed, ok := lastPassed[test]
+				if !ok {
+					continue
+				}
+				delete(lastPassed, test)
+
+				commits := []types.Commit{}
+				for _, between := range runs[passed+1 : i+1] {
+					commits = append(commits, between.run.PushCommits...)
+				}
+				breakages = append(breakages, Breakage{
+					Workflow:   workflow,
+					Test:       test,
+					Owners:     owners,
+					Run:        t.run,
+					LastPassed: runs[passed].run,
+					Commits:    commits,
+				})
+			}
+
+			for test := range t.passed {
+				if _, failed := t.failed[test]; !failed {
+					lastPassed[test] = i
+				}
+			}
+		}
+	}
+
+	slices.SortFunc(breakages, func(a, b Breakage) int {
+		return cmp.Or(
+			b.Run.CreatedAt.Compare(a.Run.CreatedAt),
+			cmp.Compare(a.Workflow, b.Workflow),
+			cmp.Compare(a.Test, b.Test),
+		)
+	})
+
+	return breakages
+}
+
+// LoadBreakages finds the tests which started failing in push runs on the given branch
+// created within the given time window, from the workflow runs and testcases in the given
+// index, see BreakageBuilder.Build.
+func LoadBreakages(
+	ctx context.Context,
+	client *opensearchgo.Client,
+	index string,
+	since, until time.Time,
+	repository, branch string,
+) ([]Breakage, error) {
+	b := NewBreakageBuilder()
+
+	filters := append(
+		windowFilters(since, until, repository, types.TypeNameWorkflowRun, types.TypeNameTestcase),
+		map[string]any{"term": map[string]any{"event.keyword": "push"}},
+	)
+	if branch != "" {
+		filters = append(filters, map[string]any{"term": map[string]any{"head_branch.keyword": branch}})
+	}
+
+	err := opensearch.SearchAll(ctx, client, index, filterQuery(filters), searchPageSize, func(source map[string]any) error {
+		switch types.TypeName(fmt.Sprint(source["type"])) {
+		case types.TypeNameWorkflowRun:
+			run, err := decodeSource[types.WorkflowRun](source)
+			if err != nil {
+				return fmt.Errorf("unable to decode workflow run: %w", err)
+			}
+			b.AddWorkflowRun(run)
+		case types.TypeNameTestcase:
+			tc, err := decodeSource[types.Testcase](source)
+			if err != nil {
+				return fmt.Errorf("unable to decode testcase: %w", err)
+			}
+			b.AddTestcase(tc)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to load documents for breakages: %w", err)
+	}
+
+	return b.Build(), nil
+}
@@ -0,0 +1,209 @@
+package report
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+
+	"github.com/isovalent/corgi/pkg/junit"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// attemptFailures are the names of the tests which failed in a workflow run attempt.
+type attemptFailures struct {
+	workflow string
+	tests    map[string]struct{}
+}
+
+type testPair struct {
+	workflow string
+	a, b     string
+}
+
+// CooccurrenceBuilder finds tests which fail together in the same workflow run attempt.
+type CooccurrenceBuilder struct {
+	since      time.Time
+	until      time.Time
+	repository string
+	branch     string
+
+	// runs are the completed run attempts of each workflow.
+	runs     map[string]map[runAttempt]struct{}
+	failures map[runAttempt]*attemptFailures
+}
+
+// NewCooccurrenceBuilder creates a new CooccurrenceBuilder for the given time window.
+func NewCooccurrenceBuilder(since, until time.Time, repository, branch string) *CooccurrenceBuilder {
+	return &CooccurrenceBuilder{
+		since:      since,
+		until:      until,
+		repository: repository,
+		branch:     branch,
+		runs:       map[string]map[runAttempt]struct{}{},
+		failures:   map[runAttempt]*attemptFailures{},
+	}
+}
+
+// AddWorkflowRun adds a workflow run attempt, so that it counts towards the runs of its
+// workflow.
+func (b *CooccurrenceBuilder) AddWorkflowRun(run *types.WorkflowRun) {
+	if run.Status != "completed" {
+		return
+	}
+
+	runs, ok := b.runs[run.Name]
+	if !ok {
+		runs = map[runAttempt]struct{}{}
+		b.runs[run.Name] = runs
+	}
+	runs[runAttempt{id: run.ID, attempt: run.RunAttempt}] = struct{}{}
+}
+
+// AddTestcase adds a testcase. Only failed testcases are kept.
+func (b *CooccurrenceBuilder) AddTestcase(tc *types.Testcase) {
+	if !isFailed(tc.Status) || tc.Testsuite == nil || tc.WorkflowRun == nil {
+		return
+	}
+
+	key := runAttempt{id: tc.WorkflowRun.ID, attempt: tc.WorkflowRun.RunAttempt}
+	f, ok := b.failures[key]
+	if !ok {
+		f = &attemptFailures{workflow: tc.WorkflowRun.Name, tests: map[string]struct{}{}}
+		b.failures[key] = f
+	}
+	f.tests[tc.Name] = struct{}{}
+}
+
+// Build returns the pairs of tests which failed together in at least minTogether run
+// attempts, highest lift first. Attempts in which more than maxFailures tests failed are
+// left out, since they are usually broken as a whole and would pair up every test.
+func (b *CooccurrenceBuilder) Build(minTogether, maxFailures int) []types.FailureCooccurrence {
+	failures := map[string]map[string]int{}
+	together := map[testPair]int{}
+
+	for _, f := range b.failures {
+		if maxFailures > 0 && len(f.tests) > maxFailures {
+			continue
+		}
+
+		tests := make([]string, 0, len(f.tests))
+		for test := range f.tests {
+			tests = append(tests, test)
+		}
+		slices.Sort(tests)
+
+		counts, ok := failures[f.workflow]
+		if !ok {
+			counts = map[string]int{}
+			failures[f.workflow] = counts
+		}
+		for i, a := range tests {
+			counts[a]++
+			for _, other := range tests[i+1:] {
+				together[testPair{workflow: f.workflow, a: a, b: other}]++
+			}
+		}
+	}
+
+	result := []types.FailureCooccurrence{}
+	for pair, n := range together {
+		if n < max(minTogether, 1) {
+			continue
+		}
+
+		// The lift can't be computed without the runs of the workflow, such as when
+		// they are kept in an index which wasn't searched.
+		runs := len(b.runs[pair.workflow])
+		if runs == 0 {
+			continue
+		}
+
+		failuresA, failuresB := failures[pair.workflow][pair.a], failures[pair.workflow][pair.b]
+		result = append(result, types.FailureCooccurrence{
+			Type:         types.TypeNameFailureCooccurrence,
+			Repository:   b.repository,
+			HeadBranch:   b.branch,
+			Workflow:     pair.workflow,
+			TestA:        pair.a,
+			TestB:        pair.b,
+			Since:        b.since,
+			Until:        b.until,
+			Runs:         runs,
+			FailuresA:    failuresA,
+			FailuresB:    failuresB,
+			Together:     n,
+			ConfidenceAB: float64(n) / float64(failuresA),
+			ConfidenceBA: float64(n) / float64(failuresB),
+			Lift:         float64(n) * float64(runs) / (float64(failuresA) * float64(failuresB)),
+		})
+	}
+
+	slices.SortFunc(result, func(a, b types.FailureCooccurrence) int {
+		return cmp.Or(
+			cmp.Compare(b.Lift, a.Lift),
+			cmp.Compare(b.Together, a.Together),
+			cmp.Compare(a.Workflow, b.Workflow),
+			cmp.Compare(a.TestA, b.TestA),
+			cmp.Compare(a.TestB, b.TestB),
+		)
+	})
+
+	return result
+}
+
+// LoadCooccurrenceBuilder adds the workflow runs and failed testcases in the given index
+// to a new CooccurrenceBuilder.
+func LoadCooccurrenceBuilder(
+	ctx context.Context,
+	client *opensearchgo.Client,
+	index string,
+	since, until time.Time,
+	repository, branch string,
+) (*CooccurrenceBuilder, error) {
+	b := NewCooccurrenceBuilder(since, until, repository, branch)
+
+	filters := append(
+		windowFilters(since, until, repository, types.TypeNameWorkflowRun, types.TypeNameTestcase),
+		map[string]any{"bool": map[string]any{
+			"should": []any{
+				map[string]any{"term": map[string]any{"type.keyword": types.TypeNameWorkflowRun}},
+				map[string]any{"terms": map[string]any{
+					"test_case_status.keyword": []string{junit.StatusFailed, junit.StatusError},
+				}},
+			},
+			"minimum_should_match": 1,
+		}},
+	)
+	if branch != "" {
+		filters = append(filters, map[string]any{"term": map[string]any{"head_branch.keyword": branch}})
+	}
+
+	err := opensearch.SearchAll(ctx, client, index, filterQuery(filters), searchPageSize, func(source map[string]any) error {
+		switch types.TypeName(fmt.Sprint(source["type"])) {
+		case types.TypeNameWorkflowRun:
+			run, err := decodeSource[types.WorkflowRun](source)
+			if err != nil {
+				return fmt.Errorf("unable to decode workflow run: %w", err)
+			}
+			b.AddWorkflowRun(run)
+		case types.TypeNameTestcase:
+			tc, err := decodeSource[types.Testcase](source)
+			if err != nil {
+				return fmt.Errorf("unable to decode testcase: %w", err)
+			}
+			b.AddTestcase(tc)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to load documents for failure co-occurrence: %w", err)
+	}
+
+	return b, nil
+}
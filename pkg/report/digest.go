@@ -0,0 +1,97 @@
+package report
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/isovalent/corgi/pkg/embedding"
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// DigestEntry is a unique failure, that is a test failing in the same way, along with
+// how often it occurred.
+type DigestEntry struct {
+	Test string
+	// Signature identifies the way the test failed. It is the name of the infrastructure
+	// signature the failure matched, or else its normalized failure message.
+	Signature   string
+	Occurrences int
+	// Runs is the number of distinct workflow runs the failure occurred in.
+	Runs   int
+	Owners []string
+	// Last is the most recent occurrence of the failure.
+	Last *types.Testcase
+
+	runs map[int64]struct{}
+}
+
+// failureSignature returns the signature identifying the way the given testcase failed.
+func failureSignature(tc *types.Testcase) string {
+	if tc.FailureSignature != "" {
+		return tc.FailureSignature
+	}
+	if tc.FailureNormalized != "" {
+		return firstLine(tc.FailureNormalized)
+	}
+
+	message := tc.FailureMessage
+	if message == "" {
+		message = tc.FailureText
+	}
+
+	return embedding.Normalize(firstLine(message))
+}
+
+func firstLine(text string) string {
+	text = strings.TrimSpace(text)
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		return strings.TrimSpace(text[:i])
+	}
+
+	return text
+}
+
+// NewDigest collapses the given failed testcases, sorted oldest first, into unique pairs
+// of test and failure signature, most frequent first. Test names are used as they were
+// ingested, with redactions already applied.
+func NewDigest(cases []*types.Testcase) []*DigestEntry {
+	type key struct{ test, signature string }
+
+	entries := map[key]*DigestEntry{}
+	for _, tc := range cases {
+		k := key{tc.Name, failureSignature(tc)}
+		entry, ok := entries[k]
+		if !ok {
+			entry = &DigestEntry{Test: k.test, Signature: k.signature, runs: map[int64]struct{}{}}
+			entries[k] = entry
+		}
+
+		entry.Occurrences++
+		entry.Last = tc
+		if tc.WorkflowRun != nil {
+			entry.runs[tc.WorkflowRun.ID] = struct{}{}
+		}
+		for _, owner := range tc.Owners {
+			if !slices.Contains(entry.Owners, owner) {
+				entry.Owners = append(entry.Owners, owner)
+			}
+		}
+	}
+
+	result := make([]*DigestEntry, 0, len(entries))
+	for _, entry := range entries {
+		entry.Runs = len(entry.runs)
+		slices.Sort(entry.Owners)
+		result = append(result, entry)
+	}
+	slices.SortFunc(result, func(a, b *DigestEntry) int {
+		return cmp.Or(
+			cmp.Compare(b.Occurrences, a.Occurrences),
+			cmp.Compare(a.Test, b.Test),
+			cmp.Compare(a.Signature, b.Signature),
+		)
+	})
+
+	return result
+}
@@ -0,0 +1,115 @@
+package report
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+
+	"github.com/isovalent/corgi/pkg/junit"
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// LoadPassedTestcases returns the passed testcases whose workflow run was created within
+// the given time window, optionally limited to the given repository and branch, oldest
+// first.
+func LoadPassedTestcases(
+	ctx context.Context,
+	client *opensearchgo.Client,
+	index string,
+	since, until time.Time,
+	repository, branch string,
+) ([]*types.Testcase, error) {
+	cases, err := loadTestcases(ctx, client, index, since, until, repository, branch, map[string]any{
+		"term": map[string]any{"test_case_status.keyword": junit.StatusPassed},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to load passed testcases: %w", err)
+	}
+
+	return cases, nil
+}
+
+// percentile returns the p-th percentile of the given sorted durations, using the
+// nearest-rank method.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1]
+}
+
+type testDurationKey struct {
+	repository string
+	workflow   string
+	test       string
+}
+
+// NewTestDurations returns the duration percentiles of each test per workflow and
+// repository within the given time window, for tests with at least minSamples passed
+// runs. Only passed testcases are counted, since failed tests often end early or time
+// out, and so are testcases whose duration was clamped. The given testcases are expected
+// to be sorted oldest first.
+func NewTestDurations(
+	cases []*types.Testcase,
+	since, until time.Time,
+	branch string,
+	minSamples int,
+) []types.TestDuration {
+	samples := map[testDurationKey][]time.Duration{}
+	owners := map[testDurationKey][]string{}
+	for _, tc := range cases {
+		if tc.Status != junit.StatusPassed || tc.TimeSuspect || tc.WorkflowRun == nil {
+			continue
+		}
+
+		key := testDurationKey{
+			repository: tc.WorkflowRun.Repository.FullName,
+			workflow:   tc.WorkflowRun.Name,
+			test:       tc.Name,
+		}
+		samples[key] = append(samples[key], tc.Duration)
+		owners[key] = tc.Owners
+	}
+
+	durations := []types.TestDuration{}
+	for key, sorted := range samples {
+		if len(sorted) < max(minSamples, 1) {
+			continue
+		}
+		slices.Sort(sorted)
+
+		durations = append(durations, types.TestDuration{
+			Type:       types.TypeNameTestDuration,
+			Repository: key.repository,
+			HeadBranch: branch,
+			Workflow:   key.workflow,
+			TestName:   key.test,
+			Owners:     owners[key],
+			Since:      since,
+			Until:      until,
+			Samples:    len(sorted),
+			P50:        percentile(sorted, 50),
+			P90:        percentile(sorted, 90),
+			P99:        percentile(sorted, 99),
+			Max:        sorted[len(sorted)-1],
+		})
+	}
+
+	// Slowest tests first.
+	slices.SortFunc(durations, func(a, b types.TestDuration) int {
+		return cmp.Or(
+			cmp.Compare(b.P50, a.P50),
+			cmp.Compare(a.Repository, b.Repository),
+			cmp.Compare(a.Workflow, b.Workflow),
+			cmp.Compare(a.TestName, b.TestName),
+		)
+	})
+
+	return durations
+}
@@ -0,0 +1,76 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// BranchHealth is the state of the workflow runs on a branch within a time window.
+type BranchHealth struct {
+	// PassRates are the pass rates of the completed workflow runs, by workflow.
+	PassRates []PassRate
+	// FailedTests are the names of the distinct failed tests, by workflow.
+	FailedTests map[string][]string
+	// ResultMismatches are the URLs of the runs whose conclusion disagrees with their
+	// JUnit results, by workflow.
+	ResultMismatches map[string][]string
+}
+
+// LoadBranchHealth loads the pass rates and failed tests of the workflow runs on the given
+// branch within the given time window, optionally limited to the given repository.
+func LoadBranchHealth(
+	ctx context.Context,
+	client *opensearchgo.Client,
+	index string,
+	since, until time.Time,
+	repository, branch string,
+) (*BranchHealth, error) {
+	b := NewBuilder(since, until, repository)
+	health := &BranchHealth{FailedTests: map[string][]string{}, ResultMismatches: map[string][]string{}}
+
+	query := filterQuery(append(
+		windowFilters(since, until, repository, types.TypeNameWorkflowRun, types.TypeNameTestcase),
+		map[string]any{"term": map[string]any{"head_branch.keyword": branch}},
+	))
+
+	err := opensearch.SearchAll(ctx, client, index, query, searchPageSize, func(source map[string]any) error {
+		switch types.TypeName(fmt.Sprint(source["type"])) {
+		case types.TypeNameWorkflowRun:
+			run, err := decodeSource[types.WorkflowRun](source)
+			if err != nil {
+				return fmt.Errorf("unable to decode workflow run: %w", err)
+			}
+			b.AddWorkflowRun(run)
+			if mismatches := health.ResultMismatches[run.Name]; run.ResultMismatch != "" && !slices.Contains(mismatches, run.URL) {
+				health.ResultMismatches[run.Name] = append(mismatches, run.URL)
+			}
+		case types.TypeNameTestcase:
+			tc, err := decodeSource[types.Testcase](source)
+			if err != nil {
+				return fmt.Errorf("unable to decode testcase: %w", err)
+			}
+			if !isFailed(tc.Status) || tc.Testsuite == nil || tc.WorkflowRun == nil {
+				return nil
+			}
+			if failed := health.FailedTests[tc.WorkflowRun.Name]; !slices.Contains(failed, tc.Name) {
+				health.FailedTests[tc.WorkflowRun.Name] = append(failed, tc.Name)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to load documents for branch health: %w", err)
+	}
+
+	health.PassRates = b.Build(0).PassRates
+
+	return health, nil
+}
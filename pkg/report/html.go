@@ -0,0 +1,133 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// HTMLTemplateText renders a Report as a self-contained HTML page, without any
+// external stylesheets or scripts.
+const HTMLTemplateText = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>CI report{{ with .Repository }} for {{ . }}{{ end }}</title>
+<style>
+body { font-family: sans-serif; margin: 2em auto; max-width: 72em; color: #222; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { text-align: left; padding: 0.3em 0.6em; border-bottom: 1px solid #ddd; }
+td.num { text-align: right; white-space: nowrap; }
+.bar { background: #eee; width: 12em; }
+.bar div { height: 0.9em; }
+.pass { background: #2da44e; }
+.fail { background: #cf222e; }
+.duration { background: #0969da; }
+</style>
+</head>
+<body>
+<h1>CI report{{ with .Repository }} for {{ . }}{{ end }}</h1>
+<p>{{ .Since | date }} to {{ .Until | date }}</p>
+
+<h2>Pass rates</h2>
+<table>
+<tr><th>Workflow</th><th>Runs</th><th>Passed</th><th>Pass rate</th><th></th><th>Cancelled</th></tr>
+{{- range .PassRates }}
+<tr><td>{{ .Workflow }}</td><td class="num">{{ .Total }}</td><td class="num">{{ .Passed }}</td>
+<td class="num">{{ .Rate | percent }}</td><td class="bar"><div class="pass" style="width: {{ .Rate | percent }}"></div></td>
+<td class="num">{{ .Cancelled }}</td></tr>
+{{- else }}
+<tr><td colspan="6">No workflow runs</td></tr>
+{{- end }}
+</table>
+
+<h2>Top flakes</h2>
+<table>
+<tr><th>Test</th><th>Owners</th><th>Failures</th><th>Passes</th><th>Failure rate</th></tr>
+{{- range .Flakes }}
+<tr><td>{{ .Name }}</td><td>{{ join .Owners }}</td><td class="num">{{ .Failures }}</td><td class="num">{{ .Passes }}</td>
+<td class="num">{{ .FailureRate | percent }}</td></tr>
+{{- else }}
+<tr><td colspan="5">No flaky tests</td></tr>
+{{- end }}
+</table>
+
+<h2>Failures by owner</h2>
+<table>
+<tr><th>Owner</th><th>Failed tests</th><th>Tests</th><th>Failure rate</th><th></th></tr>
+{{- range .Owners }}
+<tr><td>{{ .Owner }}</td><td class="num">{{ printf "%.1f" .Failures }}</td><td class="num">{{ printf "%.1f" .Tests }}</td>
+<td class="num">{{ ratio .Failures .Tests | percent }}</td><td class="bar"><div class="fail" style="width: {{ ratio .Failures .Tests | percent }}"></div></td></tr>
+{{- else }}
+<tr><td colspan="5">No tests with owners</td></tr>
+{{- end }}
+</table>
+
+{{- with .Patterns }}
+<h2>Failure patterns</h2>
+<table>
+<tr><th>Segment</th><th>Jobs</th><th>Failures</th><th>Failure rate</th><th>Compared to all jobs</th></tr>
+{{- range . }}
+<tr><td>{{ .Dimension }} {{ segment . }}</td><td class="num">{{ .Jobs }}</td><td class="num">{{ .Failures }}</td>
+<td class="num">{{ .FailureRate | percent }}</td><td class="num">{{ printf "%.1fx" .Lift }}</td></tr>
+{{- end }}
+</table>
+{{- end }}
+
+<h2>Duration trend</h2>
+<table>
+<tr><th>Day</th><th>Runs</th><th>Average duration</th><th></th></tr>
+{{- $max := maxDuration .Durations }}
+{{- range .Durations }}
+<tr><td>{{ .Day | date }}</td><td class="num">{{ .Runs }}</td><td class="num">{{ .Average | duration }}</td>
+<td class="bar"><div class="duration" style="width: {{ ratio .Average.Seconds $max.Seconds | percent }}"></div></td></tr>
+{{- else }}
+<tr><td colspan="4">No workflow runs</td></tr>
+{{- end }}
+</table>
+</body>
+</html>
+`
+
+// templateFuncs are the functions available to both the HTML and Markdown templates.
+var templateFuncs = map[string]any{
+	"date": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02")
+	},
+	"percent": func(f float64) string {
+		return fmt.Sprintf("%.1f%%", f*100)
+	},
+	"ratio": func(a, b float64) float64 {
+		if b == 0 {
+			return 0
+		}
+		return a / b
+	},
+	"duration": func(d time.Duration) string {
+		return d.Round(time.Second).String()
+	},
+	"join": func(s []string) string {
+		return strings.Join(s, ", ")
+	},
+	"segment": PatternSegmentLabel,
+	"maxDuration": func(durations []DailyDuration) time.Duration {
+		m := time.Duration(0)
+		for _, d := range durations {
+			m = max(m, d.Average)
+		}
+		return m
+	},
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(templateFuncs).Parse(HTMLTemplateText))
+
+// RenderHTML writes the given report as a self-contained HTML page.
+func RenderHTML(w io.Writer, r *Report) error {
+	if err := htmlTemplate.Execute(w, r); err != nil {
+		return fmt.Errorf("unable to render HTML report: %w", err)
+	}
+
+	return nil
+}
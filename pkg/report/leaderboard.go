@@ -0,0 +1,122 @@
+package report
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// TeamScore is the failures and flake debt attributed to an owning team.
+type TeamScore struct {
+	Owner string
+	// Failures and Tests are split between the owners of each test, as in OwnerFailures.
+	Failures float64
+	Tests    float64
+	// FlakyTests is the number of flaky tests owned, split between their owners.
+	FlakyTests float64
+	// FlakeScore is the number of failures of flaky tests owned, split between their
+	// owners. Failures of tests which never passed aren't flakes, so they don't count.
+	FlakeScore float64
+	// TestsOwned is the number of distinct tests owned, split between their owners.
+	TestsOwned float64
+	// Retries is the number of workflow run attempts which were retried after tests
+	// failed in them. Each retry is split between the owners of the failed tests.
+	Retries float64
+}
+
+// Leaderboard returns the failures and flake scores per owner, highest flake score first.
+func (b *Builder) Leaderboard() []TeamScore {
+	scores := map[string]*TeamScore{}
+	score := func(owner string) *TeamScore {
+		s, ok := scores[owner]
+		if !ok {
+			s = &TeamScore{Owner: owner}
+			scores[owner] = s
+		}
+		return s
+	}
+
+	for _, o := range b.owners {
+		s := score(o.Owner)
+		s.Failures, s.Tests = o.Failures, o.Tests
+	}
+
+	for _, counts := range b.tests {
+		for _, owner := range counts.owners {
+			score(owner).TestsOwned += 1 / float64(len(counts.owners))
+		}
+
+		if counts.failures == 0 || counts.passes == 0 {
+			continue
+		}
+
+		for _, owner := range counts.owners {
+			s := score(owner)
+			s.FlakyTests += 1 / float64(len(counts.owners))
+			s.FlakeScore += float64(counts.failures) / float64(len(counts.owners))
+		}
+	}
+
+	for key, f := range b.failedAttempts {
+		if b.lastAttempts[key.id] <= key.attempt {
+			continue
+		}
+
+		for _, w := range f.owners {
+			score(w.Owner).Retries += w.Weight / float64(f.testcases)
+		}
+	}
+
+	result := make([]TeamScore, 0, len(scores))
+	for _, s := range scores {
+		result = append(result, *s)
+	}
+	slices.SortFunc(result, func(a, b TeamScore) int {
+		return cmp.Or(
+			cmp.Compare(b.FlakeScore, a.FlakeScore),
+			cmp.Compare(b.Failures, a.Failures),
+			cmp.Compare(a.Owner, b.Owner),
+		)
+	})
+
+	return result
+}
+
+// NewTeamFlakiness returns the flakiness documents of the teams scored within the given
+// time window, compared to their scores in the previous window.
+func NewTeamFlakiness(
+	scores, previous []TeamScore,
+	since, until time.Time,
+	repository string,
+) []types.TeamFlakiness {
+	previousScores := map[string]TeamScore{}
+	for _, s := range previous {
+		previousScores[s.Owner] = s
+	}
+
+	result := make([]types.TeamFlakiness, 0, len(scores))
+	for _, s := range scores {
+		p := previousScores[s.Owner]
+		result = append(result, types.TeamFlakiness{
+			Type:               types.TypeNameTeamFlakiness,
+			Owner:              s.Owner,
+			Repository:         repository,
+			Since:              since,
+			Until:              until,
+			TestsOwned:         s.TestsOwned,
+			Testcases:          s.Tests,
+			Failures:           s.Failures,
+			FlakyTests:         s.FlakyTests,
+			FlakeScore:         s.FlakeScore,
+			Retries:            s.Retries,
+			PreviousFlakyTests: p.FlakyTests,
+			PreviousFlakeScore: p.FlakeScore,
+			PreviousRetries:    p.Retries,
+			FlakeScoreChange:   s.FlakeScore - p.FlakeScore,
+		})
+	}
+
+	return result
+}
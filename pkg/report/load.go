@@ -0,0 +1,113 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// searchPageSize is the number of documents fetched per page when loading reports.
+const searchPageSize = 1000
+
+// decodeSource decodes the source of a document into the given type.
+func decodeSource[T any](source map[string]any) (*T, error) {
+	data, err := json.Marshal(source)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := new(T)
+	if err := json.Unmarshal(data, obj); err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+// windowFilters returns the filters matching documents of the given types, whose workflow
+// run was created within the given time window, optionally limited to the given repository.
+func windowFilters(since, until time.Time, repository string, typs ...types.TypeName) []any {
+	filters := []any{
+		map[string]any{"terms": map[string]any{"type.keyword": typs}},
+		map[string]any{"range": map[string]any{"workflow_created_at": map[string]any{
+			"gte": since.Format(time.RFC3339),
+			"lt":  until.Format(time.RFC3339),
+		}}},
+	}
+	if repository != "" {
+		filters = append(filters, map[string]any{"term": map[string]any{"repository.full_name.keyword": repository}})
+	}
+
+	return filters
+}
+
+func filterQuery(filters []any) map[string]any {
+	return map[string]any{"bool": map[string]any{"filter": filters}}
+}
+
+// WindowQuery returns a query for documents of the given types, whose workflow run was
+// created within the given time window, optionally limited to the given repository.
+func WindowQuery(since, until time.Time, repository string, typs ...types.TypeName) map[string]any {
+	return filterQuery(windowFilters(since, until, repository, typs...))
+}
+
+// Load builds a report from the workflow runs and testcases in the given index,
+// which may be a comma-separated list of index patterns.
+func Load(
+	ctx context.Context,
+	client *opensearchgo.Client,
+	index string,
+	since, until time.Time,
+	repository string,
+	maxFlakes int,
+) (*Report, error) {
+	b, err := LoadBuilder(ctx, client, index, since, until, repository)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.Build(maxFlakes), nil
+}
+
+// LoadBuilder adds the workflow runs and testcases in the given index to a new Builder.
+func LoadBuilder(
+	ctx context.Context,
+	client *opensearchgo.Client,
+	index string,
+	since, until time.Time,
+	repository string,
+) (*Builder, error) {
+	b := NewBuilder(since, until, repository)
+
+	query := WindowQuery(since, until, repository, types.TypeNameWorkflowRun, types.TypeNameTestcase)
+
+	err := opensearch.SearchAll(ctx, client, index, query, searchPageSize, func(source map[string]any) error {
+		switch types.TypeName(fmt.Sprint(source["type"])) {
+		case types.TypeNameWorkflowRun:
+			run, err := decodeSource[types.WorkflowRun](source)
+			if err != nil {
+				return fmt.Errorf("unable to decode workflow run: %w", err)
+			}
+			b.AddWorkflowRun(run)
+		case types.TypeNameTestcase:
+			tc, err := decodeSource[types.Testcase](source)
+			if err != nil {
+				return fmt.Errorf("unable to decode testcase: %w", err)
+			}
+			b.AddTestcase(tc)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to load documents for report: %w", err)
+	}
+
+	return b, nil
+}
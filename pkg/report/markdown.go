@@ -0,0 +1,186 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// SummaryCommentMarker is a hidden marker included in rendered summaries, used to
+// find and update a previously posted summary rather than posting a new one.
+const SummaryCommentMarker = "<!-- corgi-failure-summary -->"
+
+// unownedGroup is the owner group of failures of tests without owners.
+const unownedGroup = "unowned"
+
+// OwnerGroup holds the failures of a FailureSummary attributed to a single owner.
+type OwnerGroup struct {
+	Owner    string
+	Failures []SummaryFailure
+}
+
+// ByOwner groups the failures of the summary by owner. Failures of tests with
+// several owners are listed under each of them, failures of tests without owners
+// are grouped last.
+func (s *FailureSummary) ByOwner() []OwnerGroup {
+	groups := map[string][]SummaryFailure{}
+	for _, f := range s.Failures {
+		owners := f.Owners
+		if len(owners) == 0 {
+			owners = []string{unownedGroup}
+		}
+		for _, o := range owners {
+			groups[o] = append(groups[o], f)
+		}
+	}
+
+	result := make([]OwnerGroup, 0, len(groups))
+	for o, failures := range groups {
+		result = append(result, OwnerGroup{Owner: o, Failures: failures})
+	}
+	slices.SortFunc(result, func(a, b OwnerGroup) int {
+		if (a.Owner == unownedGroup) != (b.Owner == unownedGroup) {
+			if a.Owner == unownedGroup {
+				return 1
+			}
+			return -1
+		}
+		return strings.Compare(a.Owner, b.Owner)
+	})
+
+	return result
+}
+
+// KnownFlakes returns how many of the failures are known flakes.
+func (s *FailureSummary) KnownFlakes() int {
+	n := 0
+	for _, f := range s.Failures {
+		if f.KnownFlake {
+			n++
+		}
+	}
+
+	return n
+}
+
+// CheckConclusion returns the conclusion of a check run presenting the summary. As
+// the analysis is informational, failures are reported as neutral rather than failing
+// the check.
+func (s *FailureSummary) CheckConclusion() string {
+	if len(s.Failures) == 0 {
+		return "success"
+	}
+
+	return "neutral"
+}
+
+// CheckTitle returns a one line title of the summary, for check runs.
+func (s *FailureSummary) CheckTitle() string {
+	if len(s.Failures) == 0 {
+		return fmt.Sprintf("All %d tests passed", s.TotalTests)
+	}
+
+	return fmt.Sprintf("%d failed tests, %d known flakes", len(s.Failures), s.KnownFlakes())
+}
+
+// MarkdownTemplateText renders a FailureSummary as GitHub flavored Markdown, for
+// example to be posted as a comment on a pull request.
+const MarkdownTemplateText = SummaryCommentMarker + `
+### CI failure summary for [{{ .Workflow }}]({{ .RunURL }}) (attempt {{ .RunAttempt }})
+{{ if not .Failures }}
+All {{ .TotalTests }} tests passed.
+{{- else }}
+{{ len .Failures }} of {{ .TotalTests }} tests failed, {{ .KnownFlakes }} of which are known flakes.
+{{- range .ByOwner }}
+
+#### {{ .Owner }}
+
+| Test | Status | Known flake | Message |
+| --- | --- | --- | --- |
+{{- range .Failures }}
+| {{ if .ArtifactURL }}[{{ .Test | cell }}]({{ .ArtifactURL }}){{ else }}{{ .Test | cell }}{{ end }} | {{ .Status }} | {{ if .KnownFlake }}yes{{ else }}**new**{{ end }} | {{ .Message | cell }} |
+{{- end }}
+{{- end }}
+{{- end }}
+`
+
+// markdownCell escapes a value to fit into a single Markdown table cell.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+var markdownTemplate = template.Must(template.New("summary").Funcs(template.FuncMap{
+	"cell": markdownCell,
+}).Parse(MarkdownTemplateText))
+
+// RenderMarkdown renders the given summary as Markdown into w.
+func RenderMarkdown(w io.Writer, s *FailureSummary) error {
+	if err := markdownTemplate.Execute(w, s); err != nil {
+		return fmt.Errorf("unable to render Markdown summary: %w", err)
+	}
+
+	return nil
+}
+
+// ReportMarkdownTemplateText renders the pass rates, flakes and owners of a Report as
+// GitHub flavored Markdown, for example to be committed to a repository.
+const ReportMarkdownTemplateText = `# CI report{{ with .Repository }} for {{ . }}{{ end }}
+
+{{ .Since | date }} to {{ .Until | date }}
+
+## Pass rates
+
+| Workflow | Runs | Passed | Pass rate | Cancelled |
+| --- | ---: | ---: | ---: | ---: |
+{{- range .PassRates }}
+| {{ .Workflow | cell }} | {{ .Total }} | {{ .Passed }} | {{ .Rate | percent }} | {{ .Cancelled }} |
+{{- else }}
+| No workflow runs | | | | |
+{{- end }}
+
+## Top flakes
+
+| Test | Owners | Failures | Passes | Failure rate |
+| --- | --- | ---: | ---: | ---: |
+{{- range .Flakes }}
+| {{ .Name | cell }} | {{ join .Owners | cell }} | {{ .Failures }} | {{ .Passes }} | {{ .FailureRate | percent }} |
+{{- else }}
+| No flaky tests | | | | |
+{{- end }}
+
+## Failures by owner
+
+| Owner | Failed tests | Tests | Failure rate |
+| --- | ---: | ---: | ---: |
+{{- range .Owners }}
+| {{ .Owner | cell }} | {{ printf "%.1f" .Failures }} | {{ printf "%.1f" .Tests }} | {{ ratio .Failures .Tests | percent }} |
+{{- else }}
+| No tests with owners | | | |
+{{- end }}
+{{- with .Patterns }}
+
+## Failure patterns
+
+| Segment | Jobs | Failures | Failure rate | Compared to all jobs |
+| --- | ---: | ---: | ---: | ---: |
+{{- range . }}
+| {{ .Dimension }} {{ segment . | cell }} | {{ .Jobs }} | {{ .Failures }} | {{ .FailureRate | percent }} | {{ printf "%.1fx" .Lift }} |
+{{- end }}
+{{- end }}
+`
+
+var reportMarkdownTemplate = template.Must(template.New("report").Funcs(templateFuncs).Funcs(template.FuncMap{
+	"cell": markdownCell,
+}).Parse(ReportMarkdownTemplateText))
+
+// RenderReportMarkdown writes the given report as Markdown.
+func RenderReportMarkdown(w io.Writer, r *Report) error {
+	if err := reportMarkdownTemplate.Execute(w, r); err != nil {
+		return fmt.Errorf("unable to render Markdown report: %w", err)
+	}
+
+	return nil
+}
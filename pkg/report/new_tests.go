@@ -0,0 +1,126 @@
+package report
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// NewTest is a test of a workflow which ran for the first time within a time window.
+type NewTest struct {
+	Workflow string
+	Test     string
+	// Owners are the owners of the first testcase.
+	Owners []string
+	// FirstSeen is when the workflow run the test first ran in was created, and FirstRun
+	// a link to it.
+	FirstSeen time.Time
+	FirstRun  string
+	// Runs is the number of times the test ran until the end of the time window.
+	Runs int
+}
+
+// newTestBucket is a bucket of the aggregation in LoadNewTests.
+type newTestBucket struct {
+	Key struct {
+		Workflow string `json:"workflow"`
+		Test     string `json:"test"`
+	} `json:"key"`
+	DocCount int `json:"doc_count"`
+	First    struct {
+		Hits struct {
+			Hits []struct {
+				Source types.Testcase `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	} `json:"first"`
+}
+
+// parseNewTestBucket returns the test of the given aggregation bucket, along with when it
+// first ran.
+func parseNewTestBucket(bucket map[string]any) (*NewTest, error) {
+	b, err := decodeSource[newTestBucket](bucket)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode bucket: %w", err)
+	}
+	if len(b.First.Hits.Hits) == 0 {
+		return nil, fmt.Errorf("bucket of %s in %s has no testcase", b.Key.Test, b.Key.Workflow)
+	}
+
+	t := &NewTest{Workflow: b.Key.Workflow, Test: b.Key.Test, Runs: b.DocCount}
+
+	first := b.First.Hits.Hits[0].Source
+	t.Owners = first.Owners
+	if first.Testsuite != nil && first.WorkflowRun != nil {
+		t.FirstSeen = first.WorkflowRun.CreatedAt
+		t.FirstRun = first.WorkflowRun.Link
+	}
+
+	return t, nil
+}
+
+// LoadNewTests returns the tests of each workflow which ran for the first time within
+// the given time window, optionally limited to the given repository and branch, oldest
+// first. Tests are only known from the indices, so the window needs to be within their
+// retention.
+func LoadNewTests(
+	ctx context.Context,
+	client *opensearchgo.Client,
+	index string,
+	since, until time.Time,
+	repository, branch string,
+) ([]NewTest, error) {
+	filters := []any{
+		map[string]any{"term": map[string]any{"type.keyword": types.TypeNameTestcase}},
+		map[string]any{"range": map[string]any{"workflow_created_at": map[string]any{
+			"lt": until.Format(time.RFC3339),
+		}}},
+	}
+	if repository != "" {
+		filters = append(filters, map[string]any{"term": map[string]any{"repository.full_name.keyword": repository}})
+	}
+	if branch != "" {
+		filters = append(filters, map[string]any{"term": map[string]any{"head_branch.keyword": branch}})
+	}
+
+	sources := []any{
+		map[string]any{"workflow": map[string]any{"terms": map[string]any{"field": "workflow_name.keyword"}}},
+		map[string]any{"test": map[string]any{"terms": map[string]any{"field": "test_case_name.keyword"}}},
+	}
+	aggs := map[string]any{"first": map[string]any{"top_hits": map[string]any{
+		"size":    1,
+		"sort":    []any{map[string]any{"workflow_created_at": "asc"}},
+		"_source": []string{"test_case_owners", "workflow_created_at", "workflow_link"},
+	}}}
+
+	tests := []NewTest{}
+	err := opensearch.AggregateAll(ctx, client, index, filterQuery(filters), sources, aggs, searchPageSize,
+		func(bucket map[string]any) error {
+			t, err := parseNewTestBucket(bucket)
+			if err != nil {
+				return err
+			}
+			if !t.FirstSeen.Before(since) {
+				tests = append(tests, *t)
+			}
+
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load new tests: %w", err)
+	}
+
+	slices.SortFunc(tests, func(a, b NewTest) int {
+		return cmp.Or(a.FirstSeen.Compare(b.FirstSeen), cmp.Compare(a.Workflow, b.Workflow), cmp.Compare(a.Test, b.Test))
+	})
+
+	return tests, nil
+}
@@ -0,0 +1,215 @@
+package report
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// Dimensions failure patterns are segmented by.
+const (
+	PatternDimensionHour    = "hour"
+	PatternDimensionWeekday = "weekday"
+	PatternDimensionRegion  = "region"
+)
+
+// unknownRegion is the region of jobs without a runner group.
+const unknownRegion = "unknown"
+
+// patternDimensions are the dimensions in the order patterns are returned in.
+var patternDimensions = []string{PatternDimensionHour, PatternDimensionWeekday, PatternDimensionRegion}
+
+type patternKey struct {
+	dimension string
+	segment   string
+	// order sorts the segments of a dimension, such as Monday before Tuesday.
+	order int
+}
+
+type patternCounts struct {
+	jobs     int
+	failures int
+}
+
+// PatternBuilder segments the failure rate of jobs by the hour of the day and weekday
+// they started at, in UTC, and by the region of the runner they ran on. The region is
+// the runner group of the job, since self-hosted runners are grouped by where they run.
+type PatternBuilder struct {
+	since      time.Time
+	until      time.Time
+	repository string
+	branch     string
+
+	total    patternCounts
+	segments map[patternKey]*patternCounts
+}
+
+// NewPatternBuilder creates a new PatternBuilder for the given time window.
+func NewPatternBuilder(since, until time.Time, repository, branch string) *PatternBuilder {
+	return &PatternBuilder{
+		since:      since,
+		until:      until,
+		repository: repository,
+		branch:     branch,
+		segments:   map[patternKey]*patternCounts{},
+	}
+}
+
+// AddJobRun adds a job which succeeded or failed. Cancelled and skipped jobs don't tell
+// whether the environment is healthy, so they are left out.
+func (b *PatternBuilder) AddJobRun(job *types.JobRun) {
+	if job.Status != "completed" || (job.Conclusion != "success" && job.Conclusion != "failure") {
+		return
+	}
+
+	started := job.StartedAt
+	if started.IsZero() {
+		started = job.CreatedAt
+	}
+	started = started.UTC()
+
+	region := job.RunnerGroupName
+	if region == "" {
+		region = unknownRegion
+	}
+
+	failed := job.Conclusion == "failure"
+	for _, key := range []patternKey{
+		{dimension: PatternDimensionHour, segment: fmt.Sprintf("%02d", started.Hour()), order: started.Hour()},
+		{dimension: PatternDimensionWeekday, segment: started.Weekday().String(), order: int(started.Weekday())},
+		{dimension: PatternDimensionRegion, segment: region},
+	} {
+		counts, ok := b.segments[key]
+		if !ok {
+			counts = &patternCounts{}
+			b.segments[key] = counts
+		}
+		counts.jobs++
+		if failed {
+			counts.failures++
+		}
+	}
+
+	b.total.jobs++
+	if failed {
+		b.total.failures++
+	}
+}
+
+// Build returns the failure pattern of every segment with at least minJobs jobs, ordered
+// by dimension and segment.
+func (b *PatternBuilder) Build(minJobs int) []types.FailurePattern {
+	keys := []patternKey{}
+	for key, counts := range b.segments {
+		if counts.jobs >= max(minJobs, 1) {
+			keys = append(keys, key)
+		}
+	}
+	slices.SortFunc(keys, func(a, b patternKey) int {
+		return cmp.Or(
+			cmp.Compare(slices.Index(patternDimensions, a.dimension), slices.Index(patternDimensions, b.dimension)),
+			cmp.Compare(a.order, b.order),
+			cmp.Compare(a.segment, b.segment),
+		)
+	})
+
+	totalRate := 0.0
+	if b.total.jobs > 0 {
+		totalRate = float64(b.total.failures) / float64(b.total.jobs)
+	}
+
+	result := make([]types.FailurePattern, 0, len(keys))
+	for _, key := range keys {
+		counts := b.segments[key]
+		p := types.FailurePattern{
+			Type:        types.TypeNameFailurePattern,
+			Repository:  b.repository,
+			HeadBranch:  b.branch,
+			Since:       b.since,
+			Until:       b.until,
+			Dimension:   key.dimension,
+			Segment:     key.segment,
+			Jobs:        counts.jobs,
+			Failures:    counts.failures,
+			FailureRate: float64(counts.failures) / float64(counts.jobs),
+		}
+		if totalRate > 0 {
+			p.Lift = p.FailureRate / totalRate
+		}
+		result = append(result, p)
+	}
+
+	return result
+}
+
+// NotablePatterns returns the patterns whose failure rate is at least minLift times that
+// of all jobs, highest lift first.
+func NotablePatterns(patterns []types.FailurePattern, minLift float64) []types.FailurePattern {
+	result := []types.FailurePattern{}
+	for _, p := range patterns {
+		if p.Lift >= minLift {
+			result = append(result, p)
+		}
+	}
+
+	slices.SortStableFunc(result, func(a, b types.FailurePattern) int {
+		return cmp.Compare(b.Lift, a.Lift)
+	})
+
+	return result
+}
+
+// LoadPatterns segments the failure rate of the jobs in the given index whose workflow
+// run was created within the given time window, see PatternBuilder.
+func LoadPatterns(
+	ctx context.Context,
+	client *opensearchgo.Client,
+	index string,
+	since, until time.Time,
+	repository, branch string,
+	minJobs int,
+) ([]types.FailurePattern, error) {
+	b := NewPatternBuilder(since, until, repository, branch)
+
+	filters := windowFilters(since, until, repository, types.TypeNameJobRun)
+	if branch != "" {
+		filters = append(filters, map[string]any{"term": map[string]any{"head_branch.keyword": branch}})
+	}
+
+	err := opensearch.SearchAll(ctx, client, index, filterQuery(filters), searchPageSize, func(source map[string]any) error {
+		job, err := decodeSource[types.JobRun](source)
+		if err != nil {
+			return fmt.Errorf("unable to decode job run: %w", err)
+		}
+		b.AddJobRun(job)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to load documents for failure patterns: %w", err)
+	}
+
+	return b.Build(minJobs), nil
+}
+
+// PatternSegmentLabel returns a human readable label of the segment of a pattern.
+func PatternSegmentLabel(p types.FailurePattern) string {
+	if p.Dimension != PatternDimensionHour {
+		return p.Segment
+	}
+
+	hour, err := strconv.Atoi(p.Segment)
+	if err != nil {
+		return p.Segment
+	}
+
+	return fmt.Sprintf("%02d:00-%02d:00 UTC", hour, (hour+1)%24)
+}
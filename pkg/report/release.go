@@ -0,0 +1,248 @@
+package report
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+
+	"github.com/isovalent/corgi/pkg/junit"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// BranchProfile is the pass rate, failures and flakes of a branch within the time window.
+type BranchProfile struct {
+	Branch string
+	// Runs are the completed runs which weren't cancelled or skipped.
+	Runs     int
+	Passed   int
+	PassRate float64
+	// Testcases and Failures are the testcases which passed or failed, and those which
+	// failed.
+	Testcases int
+	Failures  int
+	// FailedTests is the number of distinct tests which failed, and FlakyTests those of
+	// them which passed too.
+	FailedTests int
+	FlakyTests  int
+}
+
+// BranchFailures are the failures and passes of a test on each of the compared branches.
+type BranchFailures struct {
+	Test   string
+	Owners []string
+	// Failures and Passes are indexed like the branches of the ReleaseReport.
+	Failures []int
+	Passes   []int
+	// UniqueTo is the branch the test failed on, if it failed on only one of the branches
+	// while running on at least one other branch without failing.
+	UniqueTo string
+}
+
+// ReleaseReport compares the failure and flake profiles of several branches.
+type ReleaseReport struct {
+	Since      time.Time
+	Until      time.Time
+	Repository string
+
+	Branches []BranchProfile
+	// Failures are the tests which failed on any branch, those unique to a branch first.
+	Failures []BranchFailures
+}
+
+// ReleaseBuilder aggregates workflow runs and testcases of several branches into a
+// ReleaseReport.
+type ReleaseBuilder struct {
+	since      time.Time
+	until      time.Time
+	repository string
+
+	branches []string
+	profiles []*BranchProfile
+	// tests are the counts of each test, indexed like the branches.
+	tests map[string][]*testCounts
+}
+
+// NewReleaseBuilder creates a new ReleaseBuilder comparing the given branches.
+func NewReleaseBuilder(since, until time.Time, repository string, branches []string) *ReleaseBuilder {
+	b := &ReleaseBuilder{
+		since:      since,
+		until:      until,
+		repository: repository,
+		branches:   branches,
+		tests:      map[string][]*testCounts{},
+	}
+	for _, branch := range branches {
+		b.profiles = append(b.profiles, &BranchProfile{Branch: branch})
+	}
+
+	return b
+}
+
+// AddWorkflowRun adds a completed workflow run on one of the branches.
+func (b *ReleaseBuilder) AddWorkflowRun(run *types.WorkflowRun) {
+	i := slices.Index(b.branches, run.HeadBranch)
+	if i < 0 || run.Status != "completed" || isCancelled(run) {
+		return
+	}
+
+	b.profiles[i].Runs++
+	if run.Conclusion == "success" {
+		b.profiles[i].Passed++
+	}
+}
+
+// AddTestcase adds a testcase of a workflow run on one of the branches.
+func (b *ReleaseBuilder) AddTestcase(tc *types.Testcase) {
+	if tc.Testsuite == nil || tc.WorkflowRun == nil {
+		return
+	}
+
+	i := slices.Index(b.branches, tc.WorkflowRun.HeadBranch)
+	failed := isFailed(tc.Status)
+	if i < 0 || (!failed && tc.Status != junit.StatusPassed) {
+		return
+	}
+
+	counts, ok := b.tests[tc.Name]
+	if !ok {
+		counts = make([]*testCounts, len(b.branches))
+		for j := range counts {
+			counts[j] = &testCounts{}
+		}
+		b.tests[tc.Name] = counts
+	}
+	counts[i].owners = tc.Owners
+
+	b.profiles[i].Testcases++
+	if failed {
+		counts[i].failures++
+		b.profiles[i].Failures++
+	} else {
+		counts[i].passes++
+	}
+}
+
+// Build returns the report.
+func (b *ReleaseBuilder) Build() *ReleaseReport {
+	r := &ReleaseReport{
+		Since:      b.since,
+		Until:      b.until,
+		Repository: b.repository,
+		Failures:   []BranchFailures{},
+	}
+
+	for name, counts := range b.tests {
+		f := BranchFailures{
+			Test:     name,
+			Failures: make([]int, len(counts)),
+			Passes:   make([]int, len(counts)),
+		}
+
+		failedOn := []int{}
+		ranOn := 0
+		for i, c := range counts {
+			f.Failures[i], f.Passes[i] = c.failures, c.passes
+			if c.owners != nil {
+				f.Owners = c.owners
+			}
+			if c.failures+c.passes > 0 {
+				ranOn++
+			}
+			if c.failures == 0 {
+				continue
+			}
+
+			failedOn = append(failedOn, i)
+			b.profiles[i].FailedTests++
+			if c.passes > 0 {
+				b.profiles[i].FlakyTests++
+			}
+		}
+
+		if len(failedOn) == 0 {
+			continue
+		}
+		if len(failedOn) == 1 && ranOn > 1 {
+			f.UniqueTo = b.branches[failedOn[0]]
+		}
+		r.Failures = append(r.Failures, f)
+	}
+
+	for _, p := range b.profiles {
+		if p.Runs > 0 {
+			p.PassRate = float64(p.Passed) / float64(p.Runs)
+		}
+		r.Branches = append(r.Branches, *p)
+	}
+
+	// shared sorts tests which failed on a single branch first.
+	shared := func(f BranchFailures) int {
+		if f.UniqueTo != "" {
+			return 0
+		}
+		return 1
+	}
+	total := func(f BranchFailures) int {
+		n := 0
+		for _, failures := range f.Failures {
+			n += failures
+		}
+		return n
+	}
+	slices.SortFunc(r.Failures, func(a, b BranchFailures) int {
+		return cmp.Or(
+			cmp.Compare(shared(a), shared(b)),
+			cmp.Compare(total(b), total(a)),
+			cmp.Compare(a.Test, b.Test),
+		)
+	})
+
+	return r
+}
+
+// LoadRelease builds a release report comparing the given branches from the workflow runs
+// and testcases in the given index.
+func LoadRelease(
+	ctx context.Context,
+	client *opensearchgo.Client,
+	index string,
+	since, until time.Time,
+	repository string,
+	branches []string,
+) (*ReleaseReport, error) {
+	b := NewReleaseBuilder(since, until, repository, branches)
+
+	filters := append(
+		windowFilters(since, until, repository, types.TypeNameWorkflowRun, types.TypeNameTestcase),
+		map[string]any{"terms": map[string]any{"head_branch.keyword": branches}},
+	)
+
+	err := opensearch.SearchAll(ctx, client, index, filterQuery(filters), searchPageSize, func(source map[string]any) error {
+		switch types.TypeName(fmt.Sprint(source["type"])) {
+		case types.TypeNameWorkflowRun:
+			run, err := decodeSource[types.WorkflowRun](source)
+			if err != nil {
+				return fmt.Errorf("unable to decode workflow run: %w", err)
+			}
+			b.AddWorkflowRun(run)
+		case types.TypeNameTestcase:
+			tc, err := decodeSource[types.Testcase](source)
+			if err != nil {
+				return fmt.Errorf("unable to decode testcase: %w", err)
+			}
+			b.AddTestcase(tc)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to load documents for release report: %w", err)
+	}
+
+	return b.Build(), nil
+}
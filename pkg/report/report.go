@@ -0,0 +1,299 @@
+package report
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/isovalent/corgi/pkg/junit"
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// Report holds the figures of a time window shown in reports.
+type Report struct {
+	Since      time.Time
+	Until      time.Time
+	Repository string
+
+	PassRates []PassRate
+	Flakes    []Flake
+	Owners    []OwnerFailures
+	Durations []DailyDuration
+	// Patterns are the segments of jobs failing notably more often than the rest, if
+	// loaded, see NotablePatterns.
+	Patterns []types.FailurePattern
+}
+
+// PassRate is the share of successful runs of a workflow.
+type PassRate struct {
+	Workflow string
+	Total    int
+	Passed   int
+	Rate     float64
+	// Cancelled is the number of runs which were cancelled, such as when a newer run
+	// superseded them, or skipped. They aren't part of Total.
+	Cancelled int
+}
+
+// Flake is a test which both failed and passed within the time window.
+type Flake struct {
+	Name   string
+	Owners []string
+	// Teams and JiraComponents describe the teams behind the owners, if the testcases
+	// were ingested with a team mapping.
+	Teams          []string
+	JiraComponents []string
+	Failures       int
+	Passes         int
+	// FailureRate is the share of the test's runs which failed.
+	FailureRate float64
+}
+
+// OwnerFailures is the number of failed tests attributed to an owner, split
+// between the owners of each test according to their weights.
+type OwnerFailures struct {
+	Owner    string
+	Failures float64
+	Tests    float64
+}
+
+// DailyDuration is the average duration of the workflow runs created on a day, leaving out cancelled
+// and skipped runs.
+type DailyDuration struct {
+	Day     time.Time
+	Runs    int
+	Average time.Duration
+}
+
+type testCounts struct {
+	owners         []string
+	teams          []string
+	jiraComponents []string
+	failures       int
+	passes         int
+}
+
+// runAttempt identifies an attempt of a workflow run.
+type runAttempt struct {
+	id      int64
+	attempt int
+}
+
+// failedAttempt are the failed testcases of an attempt of a workflow run.
+type failedAttempt struct {
+	testcases int
+	owners    []types.OwnerWeight
+}
+
+type durationSum struct {
+	runs  int
+	total time.Duration
+}
+
+// Builder aggregates workflow runs and testcases into a Report.
+type Builder struct {
+	since      time.Time
+	until      time.Time
+	repository string
+
+	workflows map[string]*PassRate
+	tests     map[string]*testCounts
+	owners    map[string]*OwnerFailures
+	days      map[time.Time]*durationSum
+
+	// lastAttempts are the latest attempts of each workflow run, and failedAttempts the
+	// failed testcases of each attempt, to attribute retries.
+	lastAttempts   map[int64]int
+	failedAttempts map[runAttempt]*failedAttempt
+}
+
+// NewBuilder creates a new Builder for a report of the given time window.
+func NewBuilder(since, until time.Time, repository string) *Builder {
+	return &Builder{
+		since:      since,
+		until:      until,
+		repository: repository,
+		workflows:  map[string]*PassRate{},
+		tests:      map[string]*testCounts{},
+		owners:     map[string]*OwnerFailures{},
+		days:       map[time.Time]*durationSum{},
+
+		lastAttempts:   map[int64]int{},
+		failedAttempts: map[runAttempt]*failedAttempt{},
+	}
+}
+
+// AddWorkflowRun adds a completed workflow run to the report.
+func (b *Builder) AddWorkflowRun(run *types.WorkflowRun) {
+	b.lastAttempts[run.ID] = max(b.lastAttempts[run.ID], run.RunAttempt)
+
+	if run.Status != "completed" {
+		return
+	}
+
+	rate, ok := b.workflows[run.Name]
+	if !ok {
+		rate = &PassRate{Workflow: run.Name}
+		b.workflows[run.Name] = rate
+	}
+	if isCancelled(run) {
+		// Cancelled runs stopped early, so their duration would skew the averages too.
+		rate.Cancelled++
+		return
+	}
+	rate.Total++
+	if run.Conclusion == "success" {
+		rate.Passed++
+	}
+
+	day := run.CreatedAt.UTC().Truncate(24 * time.Hour)
+	sum, ok := b.days[day]
+	if !ok {
+		sum = &durationSum{}
+		b.days[day] = sum
+	}
+	sum.runs++
+	sum.total += run.WorkflowDuration
+}
+
+// isCancelled returns true if the given completed run didn't conclude on its own, such as
+// when it was cancelled or superseded by a newer run, or was skipped, so that it says
+// nothing about whether the workflow passes.
+func isCancelled(run *types.WorkflowRun) bool {
+	return run.Conclusion == "cancelled" || run.Conclusion == "skipped"
+}
+
+// isFailed returns true if the given testcase status is a failure.
+func isFailed(status string) bool {
+	return status == junit.StatusFailed || status == junit.StatusError
+}
+
+// ownerWeights returns the weights of the owners of the given testcase, splitting
+// it evenly if no weights were recorded.
+func ownerWeights(tc *types.Testcase) []types.OwnerWeight {
+	if len(tc.OwnerWeights) > 0 {
+		return tc.OwnerWeights
+	}
+
+	weights := make([]types.OwnerWeight, 0, len(tc.Owners))
+	for _, owner := range tc.Owners {
+		weights = append(weights, types.OwnerWeight{Owner: owner, Weight: 1 / float64(len(tc.Owners))})
+	}
+
+	return weights
+}
+
+// AddTestcase adds a testcase to the report.
+func (b *Builder) AddTestcase(tc *types.Testcase) {
+	failed := isFailed(tc.Status)
+	if !failed && tc.Status != junit.StatusPassed {
+		return
+	}
+
+	counts, ok := b.tests[tc.Name]
+	if !ok {
+		counts = &testCounts{}
+		b.tests[tc.Name] = counts
+	}
+	counts.owners = tc.Owners
+	counts.teams = tc.Teams
+	counts.jiraComponents = tc.JiraComponents
+	if failed {
+		counts.failures++
+	} else {
+		counts.passes++
+	}
+
+	if failed && tc.Testsuite != nil && tc.WorkflowRun != nil {
+		key := runAttempt{id: tc.WorkflowRun.ID, attempt: tc.WorkflowRun.RunAttempt}
+		f, ok := b.failedAttempts[key]
+		if !ok {
+			f = &failedAttempt{}
+			b.failedAttempts[key] = f
+		}
+		f.testcases++
+		f.owners = append(f.owners, ownerWeights(tc)...)
+	}
+
+	for _, w := range ownerWeights(tc) {
+		owner, ok := b.owners[w.Owner]
+		if !ok {
+			owner = &OwnerFailures{Owner: w.Owner}
+			b.owners[w.Owner] = owner
+		}
+		owner.Tests += w.Weight
+		if failed {
+			owner.Failures += w.Weight
+		}
+	}
+}
+
+// Flakes returns the tests which both failed and passed, most failures first.
+func (b *Builder) Flakes() []Flake {
+	flakes := []Flake{}
+	for name, counts := range b.tests {
+		if counts.failures == 0 || counts.passes == 0 {
+			continue
+		}
+
+		flakes = append(flakes, Flake{
+			Name:           name,
+			Owners:         counts.owners,
+			Teams:          counts.teams,
+			JiraComponents: counts.jiraComponents,
+			Failures:       counts.failures,
+			Passes:         counts.passes,
+			FailureRate:    float64(counts.failures) / float64(counts.failures+counts.passes),
+		})
+	}
+
+	slices.SortFunc(flakes, func(a, b Flake) int {
+		return cmp.Or(cmp.Compare(b.Failures, a.Failures), cmp.Compare(a.Name, b.Name))
+	})
+
+	return flakes
+}
+
+// Build returns the report, including up to maxFlakes flakes.
+func (b *Builder) Build(maxFlakes int) *Report {
+	r := &Report{
+		Since:      b.since,
+		Until:      b.until,
+		Repository: b.repository,
+	}
+
+	for _, rate := range b.workflows {
+		if rate.Total > 0 {
+			rate.Rate = float64(rate.Passed) / float64(rate.Total)
+		}
+		r.PassRates = append(r.PassRates, *rate)
+	}
+	slices.SortFunc(r.PassRates, func(a, b PassRate) int {
+		return cmp.Or(cmp.Compare(a.Rate, b.Rate), cmp.Compare(a.Workflow, b.Workflow))
+	})
+
+	r.Flakes = b.Flakes()
+	if len(r.Flakes) > maxFlakes {
+		r.Flakes = r.Flakes[:maxFlakes]
+	}
+
+	for _, owner := range b.owners {
+		r.Owners = append(r.Owners, *owner)
+	}
+	slices.SortFunc(r.Owners, func(a, b OwnerFailures) int {
+		return cmp.Or(cmp.Compare(b.Failures, a.Failures), cmp.Compare(a.Owner, b.Owner))
+	})
+
+	for day, sum := range b.days {
+		r.Durations = append(r.Durations, DailyDuration{
+			Day:     day,
+			Runs:    sum.runs,
+			Average: sum.total / time.Duration(sum.runs),
+		})
+	}
+	slices.SortFunc(r.Durations, func(a, b DailyDuration) int {
+		return a.Day.Compare(b.Day)
+	})
+
+	return r
+}
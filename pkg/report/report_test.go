@@ -0,0 +1,532 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+func TestBuilder(t *testing.T) {
+	day := time.Date(2024, time.March, 7, 0, 0, 0, 0, time.UTC)
+	b := NewBuilder(day, day.Add(24*time.Hour), "cilium/cilium")
+
+	b.AddWorkflowRun(&types.WorkflowRun{
+		Name: "ci", Status: "completed", Conclusion: "success", CreatedAt: day.Add(time.Hour), WorkflowDuration: time.Hour,
+	})
+	b.AddWorkflowRun(&types.WorkflowRun{
+		Name: "ci", Status: "completed", Conclusion: "failure", CreatedAt: day.Add(2 * time.Hour), WorkflowDuration: 3 * time.Hour,
+	})
+	b.AddWorkflowRun(&types.WorkflowRun{Name: "ci", Status: "in_progress"})
+	// Cancelled runs, such as those superseded by a force-push, don't lower the pass rate.
+	b.AddWorkflowRun(&types.WorkflowRun{
+		Name: "ci", Status: "completed", Conclusion: "cancelled", CreatedAt: day.Add(3 * time.Hour),
+	})
+
+	b.AddTestcase(&types.Testcase{Name: "flaky", Status: "failed", Owners: []string{"a", "b"}})
+	b.AddTestcase(&types.Testcase{Name: "flaky", Status: "passed", Owners: []string{"a", "b"}})
+	b.AddTestcase(&types.Testcase{Name: "broken", Status: "error", Owners: []string{"a"}})
+	b.AddTestcase(&types.Testcase{Name: "skipped", Status: "skipped", Owners: []string{"a"}})
+
+	r := b.Build(10)
+
+	assert.Equal(t, []PassRate{{Workflow: "ci", Total: 2, Passed: 1, Rate: 0.5, Cancelled: 1}}, r.PassRates)
+	assert.Equal(t, []Flake{
+		{Name: "flaky", Owners: []string{"a", "b"}, Failures: 1, Passes: 1, FailureRate: 0.5},
+	}, r.Flakes)
+	assert.Equal(t, []OwnerFailures{
+		{Owner: "a", Failures: 1.5, Tests: 2},
+		{Owner: "b", Failures: 0.5, Tests: 1},
+	}, r.Owners)
+	assert.Equal(t, []DailyDuration{{Day: day, Runs: 2, Average: 2 * time.Hour}}, r.Durations)
+	assert.Equal(t, []TeamScore{
+		{Owner: "a", Failures: 1.5, Tests: 2, FlakyTests: 0.5, FlakeScore: 0.5, TestsOwned: 1.5},
+		{Owner: "b", Failures: 0.5, Tests: 1, FlakyTests: 0.5, FlakeScore: 0.5, TestsOwned: 0.5},
+	}, b.Leaderboard())
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, RenderHTML(buf, r))
+	assert.Contains(t, buf.String(), "CI report for cilium/cilium")
+	assert.Contains(t, buf.String(), "<td>flaky</td>")
+
+	buf.Reset()
+	assert.NoError(t, RenderReportMarkdown(buf, r))
+	assert.Contains(t, buf.String(), "# CI report for cilium/cilium")
+	assert.Contains(t, buf.String(), "| flaky | a, b | 1 | 1 | 50.0% |")
+}
+
+func TestLeaderboardRetries(t *testing.T) {
+	day := time.Date(2024, time.March, 7, 0, 0, 0, 0, time.UTC)
+	b := NewBuilder(day, day.Add(24*time.Hour), "cilium/cilium")
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		b.AddWorkflowRun(&types.WorkflowRun{ID: 1, RunAttempt: attempt, Name: "ci", Status: "completed"})
+	}
+	b.AddWorkflowRun(&types.WorkflowRun{ID: 2, RunAttempt: 1, Name: "ci", Status: "completed"})
+
+	failed := func(id int64, attempt int, name string, owners ...string) *types.Testcase {
+		return &types.Testcase{
+			Testsuite: &types.Testsuite{WorkflowRun: &types.WorkflowRun{ID: id, RunAttempt: attempt}},
+			Name:      name,
+			Status:    "failed",
+			Owners:    owners,
+		}
+	}
+
+	// The first attempt of run 1 was retried after tests of a and b failed in it. The
+	// failures of its last attempt and of run 2 weren't retried.
+	b.AddTestcase(failed(1, 1, "x", "a"))
+	b.AddTestcase(failed(1, 1, "y", "a", "b"))
+	b.AddTestcase(failed(1, 2, "x", "a"))
+	b.AddTestcase(failed(2, 1, "z", "b"))
+
+	retries := map[string]float64{}
+	for _, s := range b.Leaderboard() {
+		retries[s.Owner] = s.Retries
+	}
+	assert.Equal(t, map[string]float64{"a": 0.75, "b": 0.25}, retries)
+
+	previous := []TeamScore{{Owner: "a", FlakyTests: 2, FlakeScore: 3, Retries: 1}}
+	flakiness := NewTeamFlakiness(b.Leaderboard()[:1], previous, day, day.Add(24*time.Hour), "cilium/cilium")
+	assert.Len(t, flakiness, 1)
+	assert.Equal(t, types.TypeNameTeamFlakiness, flakiness[0].Type)
+	assert.Equal(t, 3.0, flakiness[0].PreviousFlakeScore)
+	assert.Equal(t, flakiness[0].FlakeScore-3, flakiness[0].FlakeScoreChange)
+	assert.Equal(t, 1.0, flakiness[0].PreviousRetries)
+}
+
+func TestFailureSummary(t *testing.T) {
+	run := &types.WorkflowRun{
+		ID: 1, RunAttempt: 2, Name: "ci", Conclusion: "failure",
+		PullRequest: &types.PullRequest{Number: 42},
+	}
+	run.Repository.FullName = "cilium/cilium"
+
+	s := NewFailureSummary(run, []types.Testcase{
+		{Name: "passing", Status: "passed"},
+		{Name: "flaky", Status: "failed", Owners: []string{"b"}, FailureText: "boom\nstack"},
+		{Name: "broken", Status: "error", Owners: []string{"a"}, FailureMessage: "bad"},
+	})
+	s.MarkKnownFlakes([]Flake{{Name: "flaky"}})
+
+	assert.Equal(t, "cilium/cilium", s.Repository)
+	assert.Equal(t, 42, s.PullRequest)
+	assert.Equal(t, 3, s.TotalTests)
+	assert.Equal(t, []string{"broken", "flaky"}, s.FailedTests())
+	assert.Equal(t, []SummaryFailure{
+		{Test: "broken", Status: "error", Owners: []string{"a"}, Message: "bad"},
+		{Test: "flaky", Status: "failed", Owners: []string{"b"}, Message: "boom", KnownFlake: true},
+	}, s.Failures)
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	s := &FailureSummary{
+		Workflow: "ci", RunURL: "https://example.com/run", RunAttempt: 1, TotalTests: 10,
+		Failures: []SummaryFailure{
+			{Test: "orphan", Status: "failed"},
+			{Test: "shared", Status: "failed", Owners: []string{"b", "a"}, Message: "a | b", KnownFlake: true},
+		},
+	}
+
+	groups := s.ByOwner()
+	assert.Equal(t, []string{"a", "b", "unowned"}, []string{groups[0].Owner, groups[1].Owner, groups[2].Owner})
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, RenderMarkdown(buf, s))
+	assert.Contains(t, buf.String(), SummaryCommentMarker)
+	assert.Contains(t, buf.String(), "2 of 10 tests failed, 1 of which are known flakes.")
+	assert.Contains(t, buf.String(), "| shared | failed | yes | a \\| b |")
+	assert.Contains(t, buf.String(), "| orphan | failed | **new** |  |")
+}
+
+func TestTestOwnership(t *testing.T) {
+	run := func(day int) *types.WorkflowRun {
+		return &types.WorkflowRun{CreatedAt: time.Date(2024, time.March, day, 0, 0, 0, 0, time.UTC)}
+	}
+	tc := func(day int, status string, owners ...string) *types.Testcase {
+		return &types.Testcase{Testsuite: &types.Testsuite{WorkflowRun: run(day)}, Status: status, Owners: owners}
+	}
+
+	o := NewTestOwnership("t", []*types.Testcase{
+		tc(1, "failed", "@a", "@b"),
+		tc(2, "passed", "@a"),
+		tc(3, "error", "@a"),
+		tc(4, "skipped"),
+	})
+
+	assert.Equal(t, []OwnerCount{{Owner: "@a", Testcases: 3}, {Owner: "@b", Testcases: 1}}, o.Owners)
+	assert.Equal(t, 3, o.Runs)
+	assert.Equal(t, 2, o.Failures)
+	assert.Equal(t, 3, o.LastFailure.WorkflowRun.CreatedAt.Day())
+}
+
+func TestNewTriageItems(t *testing.T) {
+	run := &types.WorkflowRun{}
+	tc := func(name string, owners ...string) *types.Testcase {
+		return &types.Testcase{Testsuite: &types.Testsuite{WorkflowRun: run}, Name: name, Status: "failed", Owners: owners}
+	}
+	last := tc("a", "@y")
+
+	items := NewTriageItems(
+		[]*types.Testcase{tc("b"), tc("a", "@x"), last},
+		map[string]*types.Triage{"b": {TestName: "b", State: types.TriageStateKnown}},
+	)
+
+	assert.Len(t, items, 2)
+	assert.Equal(t, "a", items[0].Test)
+	assert.Equal(t, 2, items[0].Occurrences)
+	assert.Equal(t, []string{"@x", "@y"}, items[0].Owners)
+	assert.Same(t, last, items[0].Last)
+	assert.Equal(t, types.TriageStateOpen, items[0].State())
+	assert.Equal(t, types.TriageStateKnown, items[1].State())
+
+	member := tc("c", "@cilium/sig-foo")
+	member.OwnerMembers = []string{"@alice", "@bob"}
+	items = NewTriageItems([]*types.Testcase{member}, nil)
+	assert.True(t, items[0].OwnedBy("@cilium/sig-foo"))
+	assert.True(t, items[0].OwnedBy("@bob"))
+	assert.False(t, items[0].OwnedBy("@carol"))
+}
+
+func TestNewDigest(t *testing.T) {
+	tc := func(run int64, name, signature, message string, owners ...string) *types.Testcase {
+		return &types.Testcase{
+			Testsuite:        &types.Testsuite{WorkflowRun: &types.WorkflowRun{ID: run}},
+			Name:             name,
+			Status:           "failed",
+			FailureSignature: signature,
+			FailureMessage:   message,
+			Owners:           owners,
+		}
+	}
+
+	digest := NewDigest([]*types.Testcase{
+		tc(1, "a", "", "timeout after 30s", "@y"),
+		tc(1, "a", "", "timeout after 45s", "@x"),
+		tc(2, "a", "", "timeout after 10s\nat line 3"),
+		tc(2, "a", "", "connection refused"),
+		tc(3, "b", "node-not-ready", "anything"),
+	})
+
+	assert.Len(t, digest, 3)
+	assert.Equal(t, "a", digest[0].Test)
+	assert.Equal(t, "timeout after <n>s", digest[0].Signature)
+	assert.Equal(t, 3, digest[0].Occurrences)
+	assert.Equal(t, 2, digest[0].Runs)
+	assert.Equal(t, []string{"@x", "@y"}, digest[0].Owners)
+	assert.Equal(t, "connection refused", digest[1].Signature)
+	assert.Equal(t, "node-not-ready", digest[2].Signature)
+}
+
+func TestNewTestDurations(t *testing.T) {
+	since := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(14 * 24 * time.Hour)
+	run := &types.WorkflowRun{Name: "ci", Repository: types.Repository{FullName: "cilium/cilium"}}
+
+	cases := []*types.Testcase{}
+	for i := 1; i <= 100; i++ {
+		cases = append(cases, &types.Testcase{
+			Testsuite: &types.Testsuite{WorkflowRun: run},
+			Name:      "slow",
+			Status:    "passed",
+			Duration:  time.Duration(i) * time.Second,
+			Owners:    []string{"@cilium/sig-foo"},
+		})
+	}
+	cases = append(cases,
+		&types.Testcase{Testsuite: &types.Testsuite{WorkflowRun: run}, Name: "slow", Status: "failed", Duration: time.Hour},
+		&types.Testcase{Testsuite: &types.Testsuite{WorkflowRun: run}, Name: "slow", Status: "passed", Duration: time.Hour, TimeSuspect: true},
+		&types.Testcase{Testsuite: &types.Testsuite{WorkflowRun: run}, Name: "rare", Status: "passed", Duration: time.Second},
+	)
+
+	durations := NewTestDurations(cases, since, until, "main", 2)
+
+	assert.Equal(t, []types.TestDuration{{
+		Type:       types.TypeNameTestDuration,
+		Repository: "cilium/cilium",
+		HeadBranch: "main",
+		Workflow:   "ci",
+		TestName:   "slow",
+		Owners:     []string{"@cilium/sig-foo"},
+		Since:      since,
+		Until:      until,
+		Samples:    100,
+		P50:        50 * time.Second,
+		P90:        90 * time.Second,
+		P99:        99 * time.Second,
+		Max:        100 * time.Second,
+	}}, durations)
+}
+
+func TestCooccurrenceBuilder(t *testing.T) {
+	day := time.Date(2024, time.March, 7, 0, 0, 0, 0, time.UTC)
+	b := NewCooccurrenceBuilder(day, day.Add(24*time.Hour), "cilium/cilium", "main")
+
+	for id := int64(1); id <= 10; id++ {
+		b.AddWorkflowRun(&types.WorkflowRun{ID: id, RunAttempt: 1, Name: "ci", Status: "completed"})
+	}
+	b.AddWorkflowRun(&types.WorkflowRun{ID: 11, RunAttempt: 1, Name: "ci", Status: "in_progress"})
+
+	failed := func(id int64, names ...string) {
+		for _, name := range names {
+			b.AddTestcase(&types.Testcase{
+				Testsuite: &types.Testsuite{WorkflowRun: &types.WorkflowRun{ID: id, RunAttempt: 1, Name: "ci"}},
+				Name:      name,
+				Status:    "failed",
+			})
+		}
+	}
+	failed(1, "a", "b")
+	failed(2, "b", "a")
+	failed(3, "a", "c")
+	failed(4, "c")
+	// Attempts with too many failures are left out.
+	failed(5, "a", "b", "c", "d")
+
+	pairs := b.Build(2, 3)
+
+	assert.Equal(t, []types.FailureCooccurrence{{
+		Type:         types.TypeNameFailureCooccurrence,
+		Repository:   "cilium/cilium",
+		HeadBranch:   "main",
+		Workflow:     "ci",
+		TestA:        "a",
+		TestB:        "b",
+		Since:        day,
+		Until:        day.Add(24 * time.Hour),
+		Runs:         10,
+		FailuresA:    3,
+		FailuresB:    2,
+		Together:     2,
+		ConfidenceAB: 2.0 / 3,
+		ConfidenceBA: 1,
+		Lift:         20.0 / 6,
+	}}, pairs)
+}
+
+func TestTestCountBuilder(t *testing.T) {
+	day := time.Date(2024, time.March, 7, 0, 0, 0, 0, time.UTC)
+	b := NewTestCountBuilder(day)
+
+	run := func(id int64, created time.Time, conclusion string, suites map[string]int) *types.WorkflowRun {
+		r := &types.WorkflowRun{
+			ID: id, RunAttempt: 1, Name: "ci", Status: "completed", Conclusion: conclusion, CreatedAt: created,
+		}
+		b.AddWorkflowRun(r)
+		for name, n := range suites {
+			b.AddTestsuite(&types.Testsuite{WorkflowRun: r, Name: name, TotalTests: n})
+		}
+		return r
+	}
+
+	for i := 1; i <= 3; i++ {
+		run(int64(i), day.Add(-time.Duration(i)*time.Hour), "success", map[string]int{"unit": 100, "e2e": 10})
+	}
+	// The latest attempt reporting each suite is checked. The e2e suite of the latest
+	// attempt lost most of its tests and its unit suite is gone, but as it failed, its
+	// total isn't checked, so the total of the attempt before it is.
+	previous := run(4, day.Add(time.Hour), "success", map[string]int{"unit": 100, "e2e": 10})
+	latest := run(5, day.Add(2*time.Hour), "failure", map[string]int{"e2e": 2})
+
+	counts := b.Build(3, 0.5)
+
+	assert.Equal(t, []TestCount{
+		{Run: previous, Suite: "", Expected: 110, Actual: 110, BaselineRuns: 3},
+		{Run: latest, Suite: "e2e", Expected: 10, Actual: 2, BaselineRuns: 3, Dropped: true},
+		{Run: previous, Suite: "unit", Expected: 100, Actual: 100, BaselineRuns: 3},
+	}, counts)
+	assert.Equal(t, 0.8, counts[1].Drop())
+
+	// Successful attempts which reported no tests at all are checked too.
+	empty := run(6, day.Add(3*time.Hour), "success", nil)
+	counts = b.Build(3, 0.5)
+	assert.Equal(t, TestCount{Run: empty, Expected: 110, BaselineRuns: 3, Dropped: true}, counts[0])
+}
+
+func TestParseNewTestBucket(t *testing.T) {
+	bucket := map[string]any{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"key": {"workflow": "ci", "test": "TestFoo"},
+		"doc_count": 4,
+		"first": {"hits": {"hits": [{"_source": {
+			"test_case_owners": ["@cilium/sig-foo"],
+			"workflow_created_at": "2024-03-07T10:00:00Z",
+			"workflow_link": "https://github.com/cilium/cilium/actions/runs/1"
+		}}]}}
+	}`), &bucket))
+
+	test, err := parseNewTestBucket(bucket)
+	assert.NoError(t, err)
+	assert.Equal(t, &NewTest{
+		Workflow:  "ci",
+		Test:      "TestFoo",
+		Owners:    []string{"@cilium/sig-foo"},
+		FirstSeen: time.Date(2024, time.March, 7, 10, 0, 0, 0, time.UTC),
+		FirstRun:  "https://github.com/cilium/cilium/actions/runs/1",
+		Runs:      4,
+	}, test)
+
+	_, err = parseNewTestBucket(map[string]any{"key": map[string]any{"workflow": "ci", "test": "TestFoo"}})
+	assert.ErrorContains(t, err, "no testcase")
+}
+
+func TestStabilityBuilder(t *testing.T) {
+	day := time.Date(2024, time.March, 7, 0, 0, 0, 0, time.UTC)
+	b := NewStabilityBuilder(day, day.Add(24*time.Hour), "cilium/cilium", "main")
+
+	runs := []*types.WorkflowRun{}
+	for i, conclusion := range []string{"success", "success", "failure", "failure"} {
+		run := &types.WorkflowRun{
+			ID: int64(i + 1), RunAttempt: 1, Name: "ci", Status: "completed", Conclusion: conclusion,
+			WorkflowDuration: time.Duration(1+i%2) * time.Hour,
+		}
+		runs = append(runs, run)
+		b.AddWorkflowRun(run)
+	}
+	b.AddWorkflowRun(&types.WorkflowRun{ID: 9, Name: "ci", Status: "in_progress"})
+	b.AddWorkflowRun(&types.WorkflowRun{ID: 10, Name: "rare", Status: "completed", Conclusion: "success"})
+
+	tc := func(run *types.WorkflowRun, name, status, class string) *types.Testcase {
+		return &types.Testcase{
+			Testsuite: &types.Testsuite{WorkflowRun: run}, Name: name, Status: status, FailureClass: class,
+		}
+	}
+	b.AddTestcase(tc(runs[0], "flaky", "passed", ""))
+	b.AddTestcase(tc(runs[2], "flaky", "failed", "product"))
+	b.AddTestcase(tc(runs[0], "stable", "passed", ""))
+	b.AddTestcase(tc(runs[3], "stable", "passed", ""))
+	b.AddTestcase(tc(runs[3], "setup", "failed", "infrastructure"))
+
+	stability := b.Build(2)
+
+	assert.Len(t, stability, 1)
+	s := stability[0]
+	assert.Equal(t, "ci", s.Workflow)
+	assert.Equal(t, 4, s.Runs)
+	assert.Equal(t, 0.5, s.PassRate)
+	assert.InDelta(t, 1.0/3, s.FlakeRate, 1e-9)
+	assert.Equal(t, 0.25, s.InfraFailureRate)
+	assert.Equal(t, 90*time.Minute, s.DurationMean)
+	assert.Equal(t, 30*time.Minute, s.DurationStdDev)
+	assert.InDelta(t, 1.0/3, s.DurationCV, 1e-9)
+	assert.InDelta(t, 100*(0.4*0.5+0.2*(2.0/3)+0.2*0.75+0.2*(2.0/3)), s.Score, 1e-9)
+}
+
+func TestReleaseBuilder(t *testing.T) {
+	since := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	b := NewReleaseBuilder(since, since.AddDate(0, 0, 14), "cilium/cilium", []string{"v1.15", "main"})
+
+	release := &types.WorkflowRun{ID: 1, Name: "ci", HeadBranch: "v1.15", Status: "completed", Conclusion: "failure"}
+	main := &types.WorkflowRun{ID: 2, Name: "ci", HeadBranch: "main", Status: "completed", Conclusion: "success"}
+	other := &types.WorkflowRun{ID: 3, Name: "ci", HeadBranch: "v1.14", Status: "completed", Conclusion: "failure"}
+	for _, run := range []*types.WorkflowRun{release, main, other} {
+		b.AddWorkflowRun(run)
+	}
+
+	tc := func(run *types.WorkflowRun, name, status string) *types.Testcase {
+		return &types.Testcase{
+			Testsuite: &types.Testsuite{WorkflowRun: run}, Name: name, Status: status, Owners: []string{"@team"},
+		}
+	}
+	b.AddTestcase(tc(release, "backport", "failed"))
+	b.AddTestcase(tc(release, "backport", "passed"))
+	b.AddTestcase(tc(main, "backport", "passed"))
+	b.AddTestcase(tc(release, "shared", "failed"))
+	b.AddTestcase(tc(main, "shared", "failed"))
+	b.AddTestcase(tc(main, "shared", "failed"))
+	b.AddTestcase(tc(main, "new", "failed"))
+	b.AddTestcase(tc(other, "backport", "failed"))
+
+	r := b.Build()
+
+	assert.Equal(t, []BranchProfile{
+		{Branch: "v1.15", Runs: 1, PassRate: 0, Testcases: 3, Failures: 2, FailedTests: 2, FlakyTests: 1},
+		{Branch: "main", Runs: 1, Passed: 1, PassRate: 1, Testcases: 4, Failures: 3, FailedTests: 2},
+	}, r.Branches)
+	assert.Equal(t, []BranchFailures{
+		{Test: "backport", Owners: []string{"@team"}, Failures: []int{1, 0}, Passes: []int{1, 1}, UniqueTo: "v1.15"},
+		{Test: "shared", Owners: []string{"@team"}, Failures: []int{1, 2}, Passes: []int{0, 0}},
+		{Test: "new", Owners: []string{"@team"}, Failures: []int{0, 1}, Passes: []int{0, 0}},
+	}, r.Failures)
+}
+
+func TestPatternBuilder(t *testing.T) {
+	monday := time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC)
+	b := NewPatternBuilder(monday, monday.AddDate(0, 0, 7), "cilium/cilium", "main")
+
+	job := func(started time.Time, group, conclusion string) *types.JobRun {
+		return &types.JobRun{Status: "completed", Conclusion: conclusion, StartedAt: started, RunnerGroupName: group}
+	}
+	b.AddJobRun(job(monday.Add(14*time.Hour), "eu", "failure"))
+	b.AddJobRun(job(monday.Add(14*time.Hour+time.Minute), "eu", "failure"))
+	b.AddJobRun(job(monday.Add(3*time.Hour), "eu", "success"))
+	b.AddJobRun(job(monday.AddDate(0, 0, 1).Add(3*time.Hour), "", "success"))
+	b.AddJobRun(job(monday.Add(3*time.Hour), "eu", "cancelled"))
+
+	patterns := b.Build(2)
+
+	segments := []string{}
+	for _, p := range patterns {
+		segments = append(segments, p.Dimension+"/"+p.Segment)
+	}
+	assert.Equal(t, []string{"hour/03", "hour/14", "weekday/Monday", "region/eu"}, segments)
+	assert.Equal(t, 2, patterns[1].Failures)
+	assert.Equal(t, 1.0, patterns[1].FailureRate)
+	assert.Equal(t, 2.0, patterns[1].Lift)
+	assert.InDelta(t, 2.0/3, patterns[2].FailureRate, 1e-9)
+
+	notable := NotablePatterns(patterns, 1.5)
+	assert.Len(t, notable, 1)
+	assert.Equal(t, "14:00-15:00 UTC", PatternSegmentLabel(notable[0]))
+
+	r := &Report{Patterns: notable}
+	buf := &bytes.Buffer{}
+	assert.NoError(t, RenderReportMarkdown(buf, r))
+	assert.Contains(t, buf.String(), "| hour 14:00-15:00 UTC | 2 | 2 | 100.0% | 2.0x |")
+}
+
+func TestBreakageBuilder(t *testing.T) {
+	day := time.Date(2024, time.March, 7, 0, 0, 0, 0, time.UTC)
+	b := NewBreakageBuilder()
+
+	run := func(id int64, attempt int, hour int, sha string) *types.WorkflowRun {
+		return &types.WorkflowRun{
+			ID: id, RunAttempt: attempt, Name: "ci", Event: "push", Status: "completed",
+			CreatedAt: day.Add(time.Duration(hour) * time.Hour), HeadSHA: sha,
+			PushCommits: []types.Commit{{SHA: sha}},
+		}
+	}
+	first, second, third := run(1, 1, 1, "a"), run(2, 1, 2, "b"), run(3, 1, 3, "c")
+	retried, retry := run(4, 1, 4, "d"), run(4, 2, 4, "d")
+	for _, r := range []*types.WorkflowRun{first, second, third, retried, retry} {
+		b.AddWorkflowRun(r)
+	}
+	b.AddWorkflowRun(&types.WorkflowRun{ID: 5, Name: "ci", Event: "pull_request", Status: "completed"})
+
+	tc := func(r *types.WorkflowRun, name, status string) *types.Testcase {
+		return &types.Testcase{
+			Testsuite: &types.Testsuite{WorkflowRun: r}, Name: name, Status: status, Owners: []string{"@team"},
+		}
+	}
+	b.AddTestcase(tc(first, "broken", "passed"))
+	b.AddTestcase(tc(third, "broken", "failed"))
+	b.AddTestcase(tc(retried, "broken", "failed"))
+	b.AddTestcase(tc(first, "always", "failed"))
+	b.AddTestcase(tc(second, "always", "failed"))
+	b.AddTestcase(tc(first, "flaky", "passed"))
+	b.AddTestcase(tc(retried, "flaky", "failed"))
+	b.AddTestcase(tc(retry, "flaky", "passed"))
+
+	breakages := b.Build()
+
+	assert.Len(t, breakages, 1)
+	assert.Equal(t, "broken", breakages[0].Test)
+	assert.Equal(t, []string{"@team"}, breakages[0].Owners)
+	assert.Equal(t, third, breakages[0].Run)
+	assert.Equal(t, first, breakages[0].LastPassed)
+	assert.Equal(t, []types.Commit{{SHA: "b"}, {SHA: "c"}}, breakages[0].Commits)
+}
@@ -0,0 +1,242 @@
+package report
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+
+	"github.com/isovalent/corgi/pkg/junit"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// Weights of the components of the stability score, summing up to one. The pass rate
+// weighs the most, as it is what developers experience.
+const (
+	stabilityWeightPassRate  = 0.4
+	stabilityWeightFlakes    = 0.2
+	stabilityWeightInfra     = 0.2
+	stabilityWeightDurations = 0.2
+)
+
+// workflowStats are the figures of a workflow the stability score is computed from.
+type workflowStats struct {
+	runs      map[runAttempt]struct{}
+	passed    int
+	durations []time.Duration
+	tests     map[string]*testCounts
+	infraRuns map[runAttempt]struct{}
+}
+
+// StabilityBuilder aggregates workflow runs and testcases into the stability of each
+// workflow.
+type StabilityBuilder struct {
+	since      time.Time
+	until      time.Time
+	repository string
+	branch     string
+
+	workflows map[string]*workflowStats
+}
+
+// NewStabilityBuilder creates a new StabilityBuilder for the given time window.
+func NewStabilityBuilder(since, until time.Time, repository, branch string) *StabilityBuilder {
+	return &StabilityBuilder{
+		since:      since,
+		until:      until,
+		repository: repository,
+		branch:     branch,
+		workflows:  map[string]*workflowStats{},
+	}
+}
+
+func (b *StabilityBuilder) workflow(name string) *workflowStats {
+	w, ok := b.workflows[name]
+	if !ok {
+		w = &workflowStats{
+			runs:      map[runAttempt]struct{}{},
+			tests:     map[string]*testCounts{},
+			infraRuns: map[runAttempt]struct{}{},
+		}
+		b.workflows[name] = w
+	}
+
+	return w
+}
+
+// AddWorkflowRun adds a completed workflow run attempt.
+func (b *StabilityBuilder) AddWorkflowRun(run *types.WorkflowRun) {
+	if run.Status != "completed" {
+		return
+	}
+
+	w := b.workflow(run.Name)
+	key := runAttempt{id: run.ID, attempt: run.RunAttempt}
+	if _, ok := w.runs[key]; ok {
+		return
+	}
+	w.runs[key] = struct{}{}
+
+	// Failed runs often end early, so only successful runs tell how long a run takes.
+	if run.Conclusion == "success" {
+		w.passed++
+		w.durations = append(w.durations, run.WorkflowDuration)
+	}
+}
+
+// AddTestcase adds a testcase.
+func (b *StabilityBuilder) AddTestcase(tc *types.Testcase) {
+	if tc.Testsuite == nil || tc.WorkflowRun == nil {
+		return
+	}
+
+	failed := isFailed(tc.Status)
+	if !failed && tc.Status != junit.StatusPassed {
+		return
+	}
+
+	w := b.workflow(tc.WorkflowRun.Name)
+	counts, ok := w.tests[tc.Name]
+	if !ok {
+		counts = &testCounts{}
+		w.tests[tc.Name] = counts
+	}
+	if failed {
+		counts.failures++
+	} else {
+		counts.passes++
+	}
+
+	if failed && tc.FailureClass == junit.FailureClassInfrastructure {
+		w.infraRuns[runAttempt{id: tc.WorkflowRun.ID, attempt: tc.WorkflowRun.RunAttempt}] = struct{}{}
+	}
+}
+
+// durationStats returns the mean and standard deviation of the given durations.
+func durationStats(durations []time.Duration) (mean, stdDev time.Duration) {
+	if len(durations) == 0 {
+		return 0, 0
+	}
+
+	sum := 0.0
+	for _, d := range durations {
+		sum += float64(d)
+	}
+	m := sum / float64(len(durations))
+
+	variance := 0.0
+	for _, d := range durations {
+		variance += (float64(d) - m) * (float64(d) - m)
+	}
+	variance /= float64(len(durations))
+
+	return time.Duration(m), time.Duration(math.Sqrt(variance))
+}
+
+// Build returns the stability of each workflow with at least minRuns completed run
+// attempts, least stable first. The score is a weighted sum of the pass rate, the share
+// of tests which aren't flaky, the share of runs without infrastructure failures and one
+// minus the coefficient of variation of the durations, capped at one, scaled to 100.
+func (b *StabilityBuilder) Build(minRuns int) []types.WorkflowStability {
+	result := []types.WorkflowStability{}
+	for name, w := range b.workflows {
+		runs := len(w.runs)
+		if runs == 0 || runs < minRuns {
+			continue
+		}
+
+		s := types.WorkflowStability{
+			Type:       types.TypeNameWorkflowStability,
+			Repository: b.repository,
+			HeadBranch: b.branch,
+			Workflow:   name,
+			Since:      b.since,
+			Until:      b.until,
+			Runs:       runs,
+			PassRate:   float64(w.passed) / float64(runs),
+		}
+
+		flaky := 0
+		for _, counts := range w.tests {
+			if counts.failures > 0 && counts.passes > 0 {
+				flaky++
+			}
+		}
+		if len(w.tests) > 0 {
+			s.FlakeRate = float64(flaky) / float64(len(w.tests))
+		}
+
+		// Testcases of runs which weren't found, such as in-progress runs, aren't counted.
+		infraRuns := 0
+		for key := range w.infraRuns {
+			if _, ok := w.runs[key]; ok {
+				infraRuns++
+			}
+		}
+		s.InfraFailureRate = float64(infraRuns) / float64(runs)
+
+		s.DurationMean, s.DurationStdDev = durationStats(w.durations)
+		if s.DurationMean > 0 {
+			s.DurationCV = float64(s.DurationStdDev) / float64(s.DurationMean)
+		}
+
+		s.Score = 100 * (stabilityWeightPassRate*s.PassRate +
+			stabilityWeightFlakes*(1-s.FlakeRate) +
+			stabilityWeightInfra*(1-s.InfraFailureRate) +
+			stabilityWeightDurations*(1-min(s.DurationCV, 1)))
+
+		result = append(result, s)
+	}
+
+	slices.SortFunc(result, func(a, b types.WorkflowStability) int {
+		return cmp.Or(cmp.Compare(a.Score, b.Score), cmp.Compare(a.Workflow, b.Workflow))
+	})
+
+	return result
+}
+
+// LoadStabilityBuilder adds the workflow runs and testcases in the given index to a new
+// StabilityBuilder.
+func LoadStabilityBuilder(
+	ctx context.Context,
+	client *opensearchgo.Client,
+	index string,
+	since, until time.Time,
+	repository, branch string,
+) (*StabilityBuilder, error) {
+	b := NewStabilityBuilder(since, until, repository, branch)
+
+	filters := windowFilters(since, until, repository, types.TypeNameWorkflowRun, types.TypeNameTestcase)
+	if branch != "" {
+		filters = append(filters, map[string]any{"term": map[string]any{"head_branch.keyword": branch}})
+	}
+
+	err := opensearch.SearchAll(ctx, client, index, filterQuery(filters), searchPageSize, func(source map[string]any) error {
+		switch types.TypeName(fmt.Sprint(source["type"])) {
+		case types.TypeNameWorkflowRun:
+			run, err := decodeSource[types.WorkflowRun](source)
+			if err != nil {
+				return fmt.Errorf("unable to decode workflow run: %w", err)
+			}
+			b.AddWorkflowRun(run)
+		case types.TypeNameTestcase:
+			tc, err := decodeSource[types.Testcase](source)
+			if err != nil {
+				return fmt.Errorf("unable to decode testcase: %w", err)
+			}
+			b.AddTestcase(tc)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to load documents for workflow stability: %w", err)
+	}
+
+	return b, nil
+}
@@ -0,0 +1,165 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// maxSummaryMessageBytes caps the failure messages included in summaries, to keep
+// them small enough to be posted as comments.
+const maxSummaryMessageBytes = 300
+
+// FailureSummary is a compact summary of the failed tests of a workflow run, meant
+// to be consumed by other tools, for example to comment on pull requests.
+type FailureSummary struct {
+	Repository  string `json:"repository"`
+	Workflow    string `json:"workflow"`
+	RunID       int64  `json:"run_id"`
+	RunAttempt  int    `json:"run_attempt"`
+	RunURL      string `json:"run_url"`
+	HeadSHA     string `json:"head_sha"`
+	HeadBranch  string `json:"head_branch"`
+	PullRequest int    `json:"pull_request,omitempty"`
+	Conclusion  string `json:"conclusion"`
+	TotalTests  int    `json:"total_tests"`
+	// Failures are sorted by owner and test name.
+	Failures []SummaryFailure `json:"failures"`
+}
+
+// SummaryFailure is a failed test within a FailureSummary.
+type SummaryFailure struct {
+	Test        string   `json:"test"`
+	Suite       string   `json:"suite"`
+	Status      string   `json:"status"`
+	Owners      []string `json:"owners,omitempty"`
+	Message     string   `json:"message,omitempty"`
+	ArtifactURL string   `json:"artifact_url,omitempty"`
+	// FailureClass is either "infrastructure" or "product", if classified.
+	FailureClass string `json:"failure_class,omitempty"`
+	// KnownFlake is true if the test both failed and passed within the history
+	// which was looked at.
+	KnownFlake bool `json:"known_flake"`
+}
+
+// NewFailureSummary summarizes the failed testcases of the given workflow run.
+func NewFailureSummary(run *types.WorkflowRun, cases []types.Testcase) *FailureSummary {
+	s := &FailureSummary{
+		Repository: run.Repository.FullName,
+		Workflow:   run.Name,
+		RunID:      run.ID,
+		RunAttempt: run.RunAttempt,
+		RunURL:     run.Link,
+		HeadSHA:    run.HeadSHA,
+		HeadBranch: run.HeadBranch,
+		Conclusion: run.Conclusion,
+		TotalTests: len(cases),
+		Failures:   []SummaryFailure{},
+	}
+	if run.PullRequest != nil {
+		s.PullRequest = run.PullRequest.Number
+	}
+
+	for _, tc := range cases {
+		if !isFailed(tc.Status) {
+			continue
+		}
+
+		message := tc.FailureMessage
+		if message == "" {
+			message, _, _ = strings.Cut(strings.TrimSpace(tc.FailureText), "\n")
+		}
+		if len(message) > maxSummaryMessageBytes {
+			message = strings.ToValidUTF8(message[:maxSummaryMessageBytes], "") + "..."
+		}
+
+		suite, artifactURL := "", ""
+		if tc.Testsuite != nil {
+			suite, artifactURL = tc.Testsuite.Name, tc.ArtifactURL
+		}
+
+		s.Failures = append(s.Failures, SummaryFailure{
+			Test:         tc.Name,
+			Suite:        suite,
+			Status:       tc.Status,
+			Owners:       tc.Owners,
+			Message:      message,
+			ArtifactURL:  artifactURL,
+			FailureClass: tc.FailureClass,
+		})
+	}
+
+	slices.SortFunc(s.Failures, func(a, b SummaryFailure) int {
+		return strings.Compare(strings.Join(a.Owners, ",")+"\x00"+a.Test, strings.Join(b.Owners, ",")+"\x00"+b.Test)
+	})
+
+	return s
+}
+
+// FailedTests returns the names of the failed tests in the summary.
+func (s *FailureSummary) FailedTests() []string {
+	names := make([]string, 0, len(s.Failures))
+	for _, f := range s.Failures {
+		if !slices.Contains(names, f.Test) {
+			names = append(names, f.Test)
+		}
+	}
+
+	return names
+}
+
+// MarkKnownFlakes flags the failures of the given flaky tests as known flakes.
+func (s *FailureSummary) MarkKnownFlakes(flakes []Flake) {
+	flaky := map[string]struct{}{}
+	for _, f := range flakes {
+		flaky[f.Name] = struct{}{}
+	}
+
+	for i := range s.Failures {
+		_, s.Failures[i].KnownFlake = flaky[s.Failures[i].Test]
+	}
+}
+
+// LoadFlakes returns which of the given tests both failed and passed within the given
+// time window, according to the testcases in the given index.
+func LoadFlakes(
+	ctx context.Context,
+	client *opensearchgo.Client,
+	index string,
+	since, until time.Time,
+	repository string,
+	tests []string,
+) ([]Flake, error) {
+	if len(tests) == 0 {
+		return nil, nil
+	}
+
+	b := NewBuilder(since, until, repository)
+
+	query := filterQuery(append(
+		windowFilters(since, until, repository, types.TypeNameTestcase),
+		map[string]any{"terms": map[string]any{"test_case_name.keyword": tests}},
+	))
+
+	err := opensearch.SearchAll(ctx, client, index, query, searchPageSize, func(source map[string]any) error {
+		tc, err := decodeSource[types.Testcase](source)
+		if err != nil {
+			return fmt.Errorf("unable to decode testcase: %w", err)
+		}
+		b.AddTestcase(tc)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to load test history: %w", err)
+	}
+
+	return b.Flakes(), nil
+}
@@ -0,0 +1,228 @@
+package report
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// TestCount is the number of tests a workflow run attempt reported, in total or for one
+// of its suites, compared to the usual number of tests.
+type TestCount struct {
+	Run *types.WorkflowRun
+	// Suite is the name of the test suite, or empty for the tests of the whole run attempt.
+	Suite string
+	// Expected is the median number of tests of the run attempts in the baseline.
+	Expected     int
+	Actual       int
+	BaselineRuns int
+	// Dropped is set if the number of tests dropped by more than the maximum share.
+	Dropped bool
+}
+
+// Drop returns the share of the expected tests which are missing.
+func (c TestCount) Drop() float64 {
+	if c.Expected == 0 || c.Actual >= c.Expected {
+		return 0
+	}
+
+	return float64(c.Expected-c.Actual) / float64(c.Expected)
+}
+
+// Document returns the test_count_drop document for the count.
+func (c TestCount) Document() types.TestCountDrop {
+	return types.TestCountDrop{
+		WorkflowRun:  c.Run,
+		Type:         types.TypeNameTestCountDrop,
+		Suite:        c.Suite,
+		Expected:     c.Expected,
+		Actual:       c.Actual,
+		BaselineRuns: c.BaselineRuns,
+		Drop:         c.Drop(),
+	}
+}
+
+type suiteKey struct {
+	workflow string
+	suite    string
+}
+
+// attemptTests are the numbers of tests a workflow run attempt reported.
+type attemptTests struct {
+	run    *types.WorkflowRun
+	total  int
+	suites map[string]int
+}
+
+// TestCountBuilder compares the number of tests reported by the latest run attempts of
+// each workflow and suite to a rolling baseline of the run attempts before them.
+type TestCountBuilder struct {
+	// checkSince is the start of the time window of the run attempts which are checked.
+	// Earlier run attempts form the baseline.
+	checkSince time.Time
+	attempts   map[runAttempt]*attemptTests
+}
+
+// NewTestCountBuilder creates a new TestCountBuilder checking the run attempts created
+// from checkSince on.
+func NewTestCountBuilder(checkSince time.Time) *TestCountBuilder {
+	return &TestCountBuilder{checkSince: checkSince, attempts: map[runAttempt]*attemptTests{}}
+}
+
+// attempt returns the tests of the attempt of the given workflow run.
+func (b *TestCountBuilder) attempt(run *types.WorkflowRun) *attemptTests {
+	key := runAttempt{id: run.ID, attempt: run.RunAttempt}
+	a, ok := b.attempts[key]
+	if !ok {
+		a = &attemptTests{run: run, suites: map[string]int{}}
+		b.attempts[key] = a
+	}
+
+	return a
+}
+
+// AddWorkflowRun adds a completed workflow run attempt, so that attempts which reported
+// no tests at all are counted too.
+func (b *TestCountBuilder) AddWorkflowRun(run *types.WorkflowRun) {
+	if run.Status != "completed" {
+		return
+	}
+
+	b.attempt(run).run = run
+}
+
+// AddTestsuite adds the tests of a test suite to the run attempt it belongs to. Suites
+// with the same name, such as those of matrix jobs, are added up.
+func (b *TestCountBuilder) AddTestsuite(suite *types.Testsuite) {
+	if suite.WorkflowRun == nil {
+		return
+	}
+
+	a := b.attempt(suite.WorkflowRun)
+	a.total += suite.TotalTests
+	a.suites[suite.Name] += suite.TotalTests
+}
+
+// median returns the median of the given counts, which are sorted in place.
+func median(counts []int) int {
+	slices.Sort(counts)
+
+	return counts[len(counts)/2]
+}
+
+// Build returns the counts of the latest run attempt of each workflow and suite created
+// from checkSince on, for those with at least minBaselineRuns run attempts in the
+// baseline. Counts are marked as dropped if more than maxDrop of the expected tests are
+// missing. Whole run attempts are only checked if they succeeded, since failed runs
+// often end before running every test.
+func (b *TestCountBuilder) Build(minBaselineRuns int, maxDrop float64) []TestCount {
+	attempts := make([]*attemptTests, 0, len(b.attempts))
+	for _, a := range b.attempts {
+		attempts = append(attempts, a)
+	}
+	slices.SortFunc(attempts, func(a, b *attemptTests) int {
+		return cmp.Or(a.run.CreatedAt.Compare(b.run.CreatedAt), cmp.Compare(a.run.ID, b.run.ID))
+	})
+
+	baselines := map[suiteKey][]int{}
+	latest := map[suiteKey]*attemptTests{}
+	for _, a := range attempts {
+		keys := map[suiteKey]int{}
+		if a.run.Conclusion == "success" {
+			keys[suiteKey{workflow: a.run.Name}] = a.total
+		}
+		for suite, n := range a.suites {
+			keys[suiteKey{workflow: a.run.Name, suite: suite}] = n
+		}
+
+		for key, n := range keys {
+			if a.run.CreatedAt.Before(b.checkSince) {
+				baselines[key] = append(baselines[key], n)
+			} else {
+				latest[key] = a
+			}
+		}
+	}
+
+	counts := []TestCount{}
+	for key, a := range latest {
+		baseline := baselines[key]
+		if len(baseline) < max(minBaselineRuns, 1) {
+			continue
+		}
+
+		c := TestCount{
+			Run:          a.run,
+			Suite:        key.suite,
+			Expected:     median(baseline),
+			Actual:       a.total,
+			BaselineRuns: len(baseline),
+		}
+		if key.suite != "" {
+			c.Actual = a.suites[key.suite]
+		}
+		c.Dropped = c.Drop() > maxDrop
+		counts = append(counts, c)
+	}
+
+	slices.SortFunc(counts, func(a, b TestCount) int {
+		return cmp.Or(cmp.Compare(a.Run.Name, b.Run.Name), cmp.Compare(a.Suite, b.Suite))
+	})
+
+	return counts
+}
+
+// LoadTestCounts loads the workflow runs and their test suites on the given branch created
+// within the baseline window before checkSince and from then until the given time, and
+// compares the number of tests of the latest run attempts to the baseline, see
+// TestCountBuilder.Build.
+func LoadTestCounts(
+	ctx context.Context,
+	client *opensearchgo.Client,
+	index string,
+	baseline time.Duration,
+	checkSince, until time.Time,
+	repository, branch string,
+	minBaselineRuns int,
+	maxDrop float64,
+) ([]TestCount, error) {
+	b := NewTestCountBuilder(checkSince)
+
+	filters := windowFilters(
+		checkSince.Add(-baseline), until, repository, types.TypeNameWorkflowRun, types.TypeNameTestsuite,
+	)
+	if branch != "" {
+		filters = append(filters, map[string]any{"term": map[string]any{"head_branch.keyword": branch}})
+	}
+
+	err := opensearch.SearchAll(ctx, client, index, filterQuery(filters), searchPageSize, func(source map[string]any) error {
+		switch types.TypeName(fmt.Sprint(source["type"])) {
+		case types.TypeNameWorkflowRun:
+			run, err := decodeSource[types.WorkflowRun](source)
+			if err != nil {
+				return fmt.Errorf("unable to decode workflow run: %w", err)
+			}
+			b.AddWorkflowRun(run)
+		case types.TypeNameTestsuite:
+			suite, err := decodeSource[types.Testsuite](source)
+			if err != nil {
+				return fmt.Errorf("unable to decode test suite: %w", err)
+			}
+			b.AddTestsuite(suite)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to load documents for test counts: %w", err)
+	}
+
+	return b.Build(minBaselineRuns, maxDrop), nil
+}
@@ -0,0 +1,139 @@
+package report
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+
+	"github.com/isovalent/corgi/pkg/junit"
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// LoadTestcases returns the testcases with the given name whose workflow run was created
+// within the given time window, optionally limited to the given repository and branch,
+// oldest first.
+func LoadTestcases(
+	ctx context.Context,
+	client *opensearchgo.Client,
+	index string,
+	since, until time.Time,
+	repository, branch, name string,
+) ([]*types.Testcase, error) {
+	cases, err := loadTestcases(ctx, client, index, since, until, repository, branch, map[string]any{
+		"term": map[string]any{"test_case_name.keyword": name},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to load testcases of %s: %w", name, err)
+	}
+
+	return cases, nil
+}
+
+// LoadFailures returns the failed testcases whose workflow run was created within the given
+// time window, optionally limited to the given repository and branch, oldest first.
+func LoadFailures(
+	ctx context.Context,
+	client *opensearchgo.Client,
+	index string,
+	since, until time.Time,
+	repository, branch string,
+) ([]*types.Testcase, error) {
+	cases, err := loadTestcases(ctx, client, index, since, until, repository, branch, map[string]any{
+		"terms": map[string]any{"test_case_status.keyword": []string{junit.StatusFailed, junit.StatusError}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to load failed testcases: %w", err)
+	}
+
+	return cases, nil
+}
+
+func loadTestcases(
+	ctx context.Context,
+	client *opensearchgo.Client,
+	index string,
+	since, until time.Time,
+	repository, branch string,
+	filter any,
+) ([]*types.Testcase, error) {
+	filters := append(windowFilters(since, until, repository, types.TypeNameTestcase), filter)
+	if branch != "" {
+		filters = append(filters, map[string]any{"term": map[string]any{"head_branch.keyword": branch}})
+	}
+
+	cases := []*types.Testcase{}
+	err := opensearch.SearchAll(ctx, client, index, filterQuery(filters), searchPageSize, func(source map[string]any) error {
+		tc, err := decodeSource[types.Testcase](source)
+		if err != nil {
+			return fmt.Errorf("unable to decode testcase: %w", err)
+		}
+		if tc.Testsuite == nil || tc.WorkflowRun == nil {
+			return nil
+		}
+		cases = append(cases, tc)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(cases, func(a, b *types.Testcase) int {
+		return a.WorkflowRun.CreatedAt.Compare(b.WorkflowRun.CreatedAt)
+	})
+
+	return cases, nil
+}
+
+// OwnerCount is how many testcases listed an owner.
+type OwnerCount struct {
+	Owner     string
+	Testcases int
+}
+
+// TestOwnership summarizes the owners and results recorded for a single test.
+type TestOwnership struct {
+	Test string
+	// Owners are sorted by how many testcases listed them, most first.
+	Owners   []OwnerCount
+	Runs     int
+	Failures int
+	// LastFailure is the most recent failed testcase, if any.
+	LastFailure *types.Testcase
+}
+
+// NewTestOwnership summarizes the given testcases of a test, which are expected to be
+// sorted oldest first.
+func NewTestOwnership(test string, cases []*types.Testcase) *TestOwnership {
+	o := &TestOwnership{Test: test}
+
+	counts := map[string]int{}
+	for _, tc := range cases {
+		for _, owner := range tc.Owners {
+			counts[owner]++
+		}
+
+		if !isFailed(tc.Status) && tc.Status != junit.StatusPassed {
+			continue
+		}
+		o.Runs++
+		if isFailed(tc.Status) {
+			o.Failures++
+			o.LastFailure = tc
+		}
+	}
+
+	for owner, n := range counts {
+		o.Owners = append(o.Owners, OwnerCount{Owner: owner, Testcases: n})
+	}
+	slices.SortFunc(o.Owners, func(a, b OwnerCount) int {
+		return cmp.Or(cmp.Compare(b.Testcases, a.Testcases), cmp.Compare(a.Owner, b.Owner))
+	})
+
+	return o
+}
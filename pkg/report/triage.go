@@ -0,0 +1,108 @@
+package report
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	opensearchgo "github.com/opensearch-project/opensearch-go"
+
+	"github.com/isovalent/corgi/pkg/opensearch"
+	"github.com/isovalent/corgi/pkg/types"
+)
+
+// LoadTriage returns the latest triage of each test in the given index, optionally limited
+// to the given repository, by test name.
+func LoadTriage(
+	ctx context.Context,
+	client *opensearchgo.Client,
+	index string,
+	repository string,
+) (map[string]*types.Triage, error) {
+	filters := []any{map[string]any{"term": map[string]any{"type.keyword": types.TypeNameTriage}}}
+	if repository != "" {
+		filters = append(filters, map[string]any{"term": map[string]any{"triage_repository.keyword": repository}})
+	}
+
+	triage := map[string]*types.Triage{}
+	err := opensearch.SearchAll(ctx, client, index, filterQuery(filters), searchPageSize, func(source map[string]any) error {
+		t, err := decodeSource[types.Triage](source)
+		if err != nil {
+			return fmt.Errorf("unable to decode triage: %w", err)
+		}
+		if latest, ok := triage[t.TestName]; !ok || t.Timestamp.After(latest.Timestamp) {
+			triage[t.TestName] = t
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to load triage: %w", err)
+	}
+
+	return triage, nil
+}
+
+// TriageItem is a failing test to be triaged.
+type TriageItem struct {
+	Test   string
+	Owners []string
+	// OwnerMembers are the members of the teams among the owners, if known.
+	OwnerMembers []string
+	Occurrences  int
+	// Last is the most recent failure of the test.
+	Last *types.Testcase
+	// Triage is the latest triage of the test, if it was triaged.
+	Triage *types.Triage
+}
+
+// OwnedBy returns true if the given handle is one of the owners of the item, or a member
+// of one of the teams owning it.
+func (i *TriageItem) OwnedBy(handle string) bool {
+	return slices.Contains(i.Owners, handle) || slices.Contains(i.OwnerMembers, handle)
+}
+
+// State returns the triage state of the item.
+func (i *TriageItem) State() string {
+	if i.Triage == nil {
+		return types.TriageStateOpen
+	}
+
+	return i.Triage.State
+}
+
+// NewTriageItems groups the given failed testcases, sorted oldest first, by test, most
+// frequent failures first.
+func NewTriageItems(cases []*types.Testcase, triage map[string]*types.Triage) []*TriageItem {
+	items := map[string]*TriageItem{}
+	for _, tc := range cases {
+		item, ok := items[tc.Name]
+		if !ok {
+			item = &TriageItem{Test: tc.Name, Triage: triage[tc.Name]}
+			items[tc.Name] = item
+		}
+		item.Occurrences++
+		item.Last = tc
+		for _, owner := range tc.Owners {
+			if !slices.Contains(item.Owners, owner) {
+				item.Owners = append(item.Owners, owner)
+			}
+		}
+		for _, member := range tc.OwnerMembers {
+			if !slices.Contains(item.OwnerMembers, member) {
+				item.OwnerMembers = append(item.OwnerMembers, member)
+			}
+		}
+	}
+
+	result := make([]*TriageItem, 0, len(items))
+	for _, item := range items {
+		result = append(result, item)
+	}
+	slices.SortFunc(result, func(a, b *TriageItem) int {
+		return cmp.Or(cmp.Compare(b.Occurrences, a.Occurrences), cmp.Compare(a.Test, b.Test))
+	})
+
+	return result
+}
@@ -0,0 +1,115 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/isovalent/corgi/pkg/opensearch"
+)
+
+// AWSSecretsManager fetches secrets from AWS Secrets Manager.
+type AWSSecretsManager struct {
+	// Endpoint is the URL of the Secrets Manager API.
+	Endpoint string
+	// Signer signs requests with the credentials to access the secrets.
+	Signer *opensearch.AWSSigner
+
+	httpClient *http.Client
+}
+
+// NewAWSSecretsManagerFromEnv configures Secrets Manager with the credentials in
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN. The region is taken
+// from the given reference if it is an ARN, and from AWS_REGION or AWS_DEFAULT_REGION
+// otherwise.
+func NewAWSSecretsManagerFromEnv(ref string) *AWSSecretsManager {
+	region := ""
+	if parts := strings.Split(ref, ":"); len(parts) > 3 && parts[0] == "arn" {
+		region = parts[3]
+	}
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+
+	return &AWSSecretsManager{
+		Endpoint: fmt.Sprintf("https://secretsmanager.%s.amazonaws.com/", region),
+		Signer: &opensearch.AWSSigner{
+			Region:       region,
+			Service:      "secretsmanager",
+			AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		},
+		httpClient: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Fetch returns the value of the secret with the given reference, of the form
+// <secret-id>[#<key>], where the secret ID is the name or ARN of the secret. If a key is
+// given, the secret is expected to be a JSON object and the value of the key is returned.
+func (m *AWSSecretsManager) Fetch(ref string) (string, error) {
+	id, key := splitRef(ref)
+	if m.Signer.Region == "" {
+		return "", fmt.Errorf("unable to determine region of secret '%s', set AWS_REGION", id)
+	}
+
+	body, err := json.Marshal(map[string]string{"SecretId": id})
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, m.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("unable to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+
+	if err := m.Signer.SignRequest(req); err != nil {
+		return "", fmt.Errorf("unable to sign request: %w", err)
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("unable to get secret %s: %w", id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("unable to get secret %s, bad http code %s: %s", id, resp.Status, msg)
+	}
+
+	secret := struct {
+		SecretString *string `json:"SecretString"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return "", fmt.Errorf("unable to decode secret %s: %w", id, err)
+	}
+	if secret.SecretString == nil {
+		return "", fmt.Errorf("secret %s is binary, only string secrets are supported", id)
+	}
+
+	if key == "" {
+		return *secret.SecretString, nil
+	}
+
+	values := map[string]any{}
+	if err := json.Unmarshal([]byte(*secret.SecretString), &values); err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object, unable to get key '%s': %w", id, key, err)
+	}
+
+	value, ok := values[key].(string)
+	if !ok {
+		return "", fmt.Errorf("secret %s has no string key '%s'", id, key)
+	}
+
+	return value, nil
+}
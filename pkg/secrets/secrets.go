@@ -0,0 +1,38 @@
+package secrets
+
+import (
+	"strings"
+	"time"
+
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+const (
+	// VaultSuffix is the suffix of the environment variables referencing secrets in
+	// HashiCorp Vault, such as GITHUB_TOKEN_VAULT=secret/data/corgi#github-token.
+	VaultSuffix = "_VAULT"
+	// AWSSuffix is the suffix of the environment variables referencing secrets in AWS
+	// Secrets Manager, such as GITHUB_TOKEN_AWS_SECRET=corgi#github-token.
+	AWSSuffix = "_AWS_SECRET"
+
+	// requestTimeout bounds each request to a secrets manager.
+	requestTimeout = 30 * time.Second
+)
+
+// Register registers HashiCorp Vault and AWS Secrets Manager as sources of secrets, so
+// that every secret can be fetched from them instead of being given in plain text.
+func Register() {
+	util.RegisterSecretSource(VaultSuffix, func(ref string) (string, error) {
+		return NewVaultFromEnv().Fetch(ref)
+	})
+	util.RegisterSecretSource(AWSSuffix, func(ref string) (string, error) {
+		return NewAWSSecretsManagerFromEnv(ref).Fetch(ref)
+	})
+}
+
+// splitRef splits a reference of the form <path>#<key> into its path and key. The key
+// is empty if the reference doesn't contain one.
+func splitRef(ref string) (path, key string) {
+	path, key, _ = strings.Cut(ref, "#")
+	return path, key
+}
@@ -0,0 +1,109 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/isovalent/corgi/pkg/util"
+)
+
+func TestVault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/secret/data/corgi":
+			fmt.Fprint(w, `{"data": {"data": {"github-token": "v2"}, "metadata": {"version": 3}}}`)
+		case "/v1/kv/corgi":
+			fmt.Fprint(w, `{"data": {"github-token": "v1"}}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("VAULT_ADDR", server.URL)
+	t.Setenv("VAULT_TOKEN", "root")
+	t.Setenv("GITHUB_TEST_TOKEN", "plain")
+	t.Setenv("GITHUB_TEST_TOKEN_VAULT", "secret/data/corgi#github-token")
+
+	Register()
+
+	token, err := util.NewSecret("GITHUB_TEST_TOKEN")
+	assert.NoError(t, err)
+	assert.Equal(t, "v2", token.Value())
+
+	v := NewVaultFromEnv()
+
+	value, err := v.Fetch("kv/corgi#github-token")
+	assert.NoError(t, err)
+	assert.Equal(t, "v1", value)
+
+	_, err = v.Fetch("kv/corgi#missing")
+	assert.ErrorContains(t, err, "no string key 'missing'")
+
+	_, err = v.Fetch("kv/corgi")
+	assert.ErrorContains(t, err, "expected reference")
+
+	v.Token = "wrong"
+	_, err = v.Fetch("kv/corgi#github-token")
+	assert.ErrorContains(t, err, "403")
+}
+
+func TestAWSSecretsManager(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secretsmanager.GetSecretValue", r.Header.Get("X-Amz-Target"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/eu-central-1/secretsmanager/aws4_request")
+
+		req := struct {
+			SecretID string `json:"SecretId"`
+		}{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		switch req.SecretID {
+		case "corgi":
+			fmt.Fprint(w, `{"SecretString": "{\"github-token\": \"from-json\"}"}`)
+		case "arn:aws:secretsmanager:eu-central-1:123456789012:secret:corgi-pass":
+			fmt.Fprint(w, `{"SecretString": "plain"}`)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"__type": "ResourceNotFoundException"}`)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("AWS_REGION", "eu-central-1")
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	for ref, expected := range map[string]string{
+		"corgi#github-token": "from-json",
+		"arn:aws:secretsmanager:eu-central-1:123456789012:secret:corgi-pass": "plain",
+	} {
+		m := NewAWSSecretsManagerFromEnv(ref)
+		assert.Equal(t, "eu-central-1", m.Signer.Region)
+		m.Endpoint = server.URL
+
+		value, err := m.Fetch(ref)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, value)
+	}
+
+	m := NewAWSSecretsManagerFromEnv("missing")
+	m.Endpoint = server.URL
+	_, err := m.Fetch("missing")
+	assert.ErrorContains(t, err, "ResourceNotFoundException")
+
+	// The region of ARNs takes precedence over AWS_REGION.
+	m = NewAWSSecretsManagerFromEnv("arn:aws:secretsmanager:us-west-2:123456789012:secret:corgi")
+	assert.Equal(t, "us-west-2", m.Signer.Region)
+	assert.Equal(t, "https://secretsmanager.us-west-2.amazonaws.com/", m.Endpoint)
+}
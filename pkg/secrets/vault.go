@@ -0,0 +1,168 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// kubernetesTokenPath is where the token of the service account of a pod is mounted.
+const kubernetesTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// Vault fetches secrets from the KV secrets engine of HashiCorp Vault, supporting both
+// version 1 and 2 of the engine.
+type Vault struct {
+	// Addr is the address of Vault, such as https://vault.example.com:8200.
+	Addr string
+	// Token authenticates requests. If empty, a token is requested by logging in as
+	// KubernetesRole with the token of the service account of the pod.
+	Token string
+	// Namespace is the Vault Enterprise namespace of the secrets, if any.
+	Namespace string
+	// KubernetesRole is the role to log in as with the Kubernetes auth method.
+	KubernetesRole string
+	// KubernetesMount is the path the Kubernetes auth method is mounted at.
+	KubernetesMount string
+
+	httpClient *http.Client
+}
+
+// NewVaultFromEnv configures Vault from the standard VAULT_ADDR, VAULT_TOKEN and
+// VAULT_NAMESPACE variables. The token may also be read from the file named by
+// VAULT_TOKEN_FILE. Without a token, VAULT_KUBERNETES_ROLE and, optionally,
+// VAULT_KUBERNETES_MOUNT configure logging in with the Kubernetes auth method.
+func NewVaultFromEnv() *Vault {
+	token := os.Getenv("VAULT_TOKEN")
+	if path := os.Getenv("VAULT_TOKEN_FILE"); path != "" {
+		if data, err := os.ReadFile(path); err == nil {
+			token = strings.TrimSpace(string(data))
+		}
+	}
+
+	mount := os.Getenv("VAULT_KUBERNETES_MOUNT")
+	if mount == "" {
+		mount = "kubernetes"
+	}
+
+	return &Vault{
+		Addr:            os.Getenv("VAULT_ADDR"),
+		Token:           token,
+		Namespace:       os.Getenv("VAULT_NAMESPACE"),
+		KubernetesRole:  os.Getenv("VAULT_KUBERNETES_ROLE"),
+		KubernetesMount: mount,
+		httpClient:      &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Fetch returns the value of the secret with the given reference, of the form
+// <path>#<key>, such as secret/data/corgi#github-token.
+func (v *Vault) Fetch(ref string) (string, error) {
+	path, key := splitRef(ref)
+	if path == "" || key == "" {
+		return "", fmt.Errorf("expected reference in the form of '<path>#<key>', got '%s'", ref)
+	}
+	if v.Addr == "" {
+		return "", fmt.Errorf("VAULT_ADDR is not set")
+	}
+
+	token := v.Token
+	if token == "" {
+		var err error
+		if token, err = v.kubernetesLogin(); err != nil {
+			return "", err
+		}
+	}
+
+	resp := struct {
+		Data map[string]any `json:"data"`
+	}{}
+	if err := v.do(http.MethodGet, path, token, nil, &resp); err != nil {
+		return "", err
+	}
+
+	// Version 2 of the KV engine nests the secret in another data field.
+	data := resp.Data
+	if nested, ok := data["data"].(map[string]any); ok {
+		if _, versioned := data["metadata"]; versioned {
+			data = nested
+		}
+	}
+
+	value, ok := data[key].(string)
+	if !ok {
+		return "", fmt.Errorf("secret %s has no string key '%s'", path, key)
+	}
+
+	return value, nil
+}
+
+// kubernetesLogin logs in with the token of the service account of the pod, returning
+// a Vault token.
+func (v *Vault) kubernetesLogin() (string, error) {
+	if v.KubernetesRole == "" {
+		return "", fmt.Errorf("neither VAULT_TOKEN nor VAULT_KUBERNETES_ROLE is set")
+	}
+
+	jwt, err := os.ReadFile(kubernetesTokenPath)
+	if err != nil {
+		return "", fmt.Errorf("unable to read service account token: %w", err)
+	}
+
+	resp := struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}{}
+	err = v.do(http.MethodPost, "auth/"+strings.Trim(v.KubernetesMount, "/")+"/login", "", map[string]string{
+		"role": v.KubernetesRole,
+		"jwt":  strings.TrimSpace(string(jwt)),
+	}, &resp)
+	if err != nil {
+		return "", fmt.Errorf("unable to log in to Vault as role %s: %w", v.KubernetesRole, err)
+	}
+
+	return resp.Auth.ClientToken, nil
+}
+
+func (v *Vault) do(method, path, token string, body, result any) error {
+	reqBody := []byte{}
+	if body != nil {
+		var err error
+		if reqBody, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("unable to marshal request: %w", err)
+		}
+	}
+
+	url := strings.TrimSuffix(v.Addr, "/") + "/v1/" + strings.TrimPrefix(path, "/")
+	req, err := http.NewRequest(method, url, bytes.NewReader(reqBody))
+	if err != nil {
+		return fmt.Errorf("unable to create request for %s: %w", url, err)
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if v.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to send request to %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("bad http code %s from %s: %s", resp.Status, url, msg)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("unable to decode response from %s: %w", url, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,295 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// exportBatchSpans is the number of ended spans which triggers an export.
+	exportBatchSpans = 512
+	// exportInterval is how often ended spans are exported otherwise.
+	exportInterval = 5 * time.Second
+)
+
+var (
+	exporterMu sync.RWMutex
+	exporter   *Exporter
+)
+
+func current() *Exporter {
+	exporterMu.RLock()
+	defer exporterMu.RUnlock()
+
+	return exporter
+}
+
+// Exporter sends ended spans in batches to an OTLP/HTTP endpoint, encoded as JSON.
+type Exporter struct {
+	url     string
+	service string
+	headers map[string]string
+	client  *http.Client
+	logger  *slog.Logger
+
+	mu    sync.Mutex
+	spans []*Span
+
+	flush chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// EndpointFromEnv returns the URL to export spans to according to the standard
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT and OTEL_EXPORTER_OTLP_ENDPOINT variables, or an
+// empty string if neither is set.
+func EndpointFromEnv() string {
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); endpoint != "" {
+		return endpoint
+	}
+
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		return strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+	}
+
+	return ""
+}
+
+// headersFromEnv parses the headers to send with exports from the standard
+// OTEL_EXPORTER_OTLP_HEADERS variable, given as comma-separated key=value pairs.
+func headersFromEnv() map[string]string {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		if key, value, ok := strings.Cut(pair, "="); ok {
+			headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	return headers
+}
+
+// Init enables tracing, exporting spans to the OTLP/HTTP traces endpoint at the given
+// URL, such as http://localhost:4318/v1/traces, on behalf of the given service. Headers
+// given in OTEL_EXPORTER_OTLP_HEADERS are sent with each export. Spans ended before
+// Shutdown is called are exported.
+func Init(url, service string, logger *slog.Logger) *Exporter {
+	e := &Exporter{
+		url:     url,
+		service: service,
+		headers: headersFromEnv(),
+		client:  &http.Client{Timeout: 30 * time.Second},
+		logger:  logger,
+		flush:   make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go e.run()
+
+	exporterMu.Lock()
+	exporter = e
+	exporterMu.Unlock()
+
+	return e
+}
+
+// Shutdown disables tracing and exports the remaining spans.
+func (e *Exporter) Shutdown() error {
+	exporterMu.Lock()
+	if exporter == e {
+		exporter = nil
+	}
+	exporterMu.Unlock()
+
+	close(e.stop)
+	<-e.done
+
+	return e.export()
+}
+
+func (e *Exporter) add(s *Span) {
+	e.mu.Lock()
+	e.spans = append(e.spans, s)
+	full := len(e.spans) >= exportBatchSpans
+	e.mu.Unlock()
+
+	if full {
+		select {
+		case e.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (e *Exporter) run() {
+	defer close(e.done)
+
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+		case <-e.flush:
+		}
+
+		// Failing to export spans must not fail the ingest they describe.
+		if err := e.export(); err != nil {
+			e.logger.Warn("Unable to export trace spans", "err", err)
+		}
+	}
+}
+
+// export sends the spans which ended since the last export.
+func (e *Exporter) export() error {
+	e.mu.Lock()
+	spans := e.spans
+	e.spans = nil
+	e.mu.Unlock()
+
+	if len(spans) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(e.encode(spans))
+	if err != nil {
+		return fmt.Errorf("unable to marshal spans: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to create request for %s: %w", e.url, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to export %d spans to %s: %w", len(spans), e.url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unable to export %d spans to %s, bad http code %s: %s", len(spans), e.url, resp.Status, msg)
+	}
+
+	return nil
+}
+
+// The following types are the JSON encoding of OTLP export requests, see
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding.
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string         `json:"key"`
+	Value map[string]any `json:"value"`
+}
+
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusCodeError  = 2
+)
+
+func (e *Exporter) encode(spans []*Span) otlpRequest {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, s := range spans {
+		span := otlpSpan{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		}
+		if s.parentID != [8]byte{} {
+			span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		for key, value := range s.attributes() {
+			span.Attributes = append(span.Attributes, otlpAttribute{Key: key, Value: encodeValue(value)})
+		}
+		if s.err != nil {
+			span.Status = otlpStatus{Code: otlpStatusCodeError, Message: s.err.Error()}
+		}
+
+		encoded = append(encoded, span)
+	}
+
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpAttribute{
+			{Key: "service.name", Value: encodeValue(e.service)},
+		}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: e.service}, Spans: encoded}},
+	}}}
+}
+
+// encodeValue encodes an attribute value as an OTLP AnyValue. 64 bit integers are
+// encoded as strings, as required by the JSON encoding.
+func encodeValue(value any) map[string]any {
+	switch v := value.(type) {
+	case string:
+		return map[string]any{"stringValue": v}
+	case bool:
+		return map[string]any{"boolValue": v}
+	case int:
+		return map[string]any{"intValue": strconv.Itoa(v)}
+	case int64:
+		return map[string]any{"intValue": strconv.FormatInt(v, 10)}
+	case float64:
+		return map[string]any{"doubleValue": v}
+	default:
+		return map[string]any{"stringValue": fmt.Sprint(v)}
+	}
+}
@@ -0,0 +1,129 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Attributes of spans, named after the OpenTelemetry semantic conventions where
+// there is one.
+const (
+	AttrWorkflowID   = "cicd.pipeline.run.id"
+	AttrRunAttempt   = "corgi.run_attempt"
+	AttrArtifact     = "corgi.artifact"
+	AttrFile         = "corgi.file"
+	AttrDocuments    = "corgi.documents"
+	AttrIngestErrors = "corgi.ingest_errors"
+)
+
+// Span is a timed operation of the ingest pipeline. Spans started while tracing is
+// disabled are nil, and all of their methods are no-ops.
+type Span struct {
+	name     string
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	start    time.Time
+	end      time.Time
+
+	mu    sync.Mutex
+	attrs []any
+	err   error
+}
+
+type spanKey struct{}
+
+// Start starts a span with the given name and attributes, given as alternating keys and
+// values as for slog, as a child of the span in the given context, if there is one.
+// The returned context carries the new span. The span needs to be ended with End.
+func Start(ctx context.Context, name string, attrs ...any) (context.Context, *Span) {
+	if current() == nil {
+		return ctx, nil
+	}
+
+	s := &Span{name: name, start: time.Now(), attrs: attrs}
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok && parent != nil {
+		s.traceID = parent.traceID
+		s.parentID = parent.spanID
+	} else {
+		_, _ = rand.Read(s.traceID[:])
+	}
+	_, _ = rand.Read(s.spanID[:])
+
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// SetAttributes adds the given attributes, given as alternating keys and values.
+func (s *Span) SetAttributes(attrs ...any) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attrs = append(s.attrs, attrs...)
+}
+
+// RecordError marks the span as failed with the given error, if it isn't nil.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.err = err
+}
+
+// End ends the span and queues it for export. Further calls are no-ops, which allows
+// it to be deferred while still ending the span early in the happy path.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	ended := !s.end.IsZero()
+	if !ended {
+		s.end = time.Now()
+	}
+	s.mu.Unlock()
+
+	if ended {
+		return
+	}
+
+	if e := current(); e != nil {
+		e.add(s)
+	}
+}
+
+// TraceID returns the hex encoded ID of the trace of the span, to correlate logs with
+// traces. It is empty if tracing is disabled.
+func (s *Span) TraceID() string {
+	if s == nil {
+		return ""
+	}
+
+	return hex.EncodeToString(s.traceID[:])
+}
+
+// attributes returns the attributes of the span as a map, ignoring a trailing key
+// without a value.
+func (s *Span) attributes() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attrs := make(map[string]any, len(s.attrs)/2)
+	for i := 0; i+1 < len(s.attrs); i += 2 {
+		attrs[fmt.Sprint(s.attrs[i])] = s.attrs[i+1]
+	}
+
+	return attrs
+}
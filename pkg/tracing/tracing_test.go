@@ -0,0 +1,62 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExport(t *testing.T) {
+	received := otlpRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	}))
+	defer server.Close()
+
+	// Spans started while tracing is disabled are no-ops.
+	_, disabled := Start(context.Background(), "disabled")
+	assert.Nil(t, disabled)
+	disabled.SetAttributes("key", "value")
+	disabled.End()
+
+	e := Init(server.URL+"/v1/traces", "corgi", slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	ctx, parent := Start(context.Background(), "pull-run", AttrWorkflowID, int64(123))
+	_, child := Start(ctx, "parse-file", AttrFile, "a.xml")
+	child.RecordError(errors.New("malformed"))
+	child.End()
+	child.End()
+	parent.End()
+
+	assert.NoError(t, e.Shutdown())
+
+	_, after := Start(context.Background(), "after-shutdown")
+	assert.Nil(t, after)
+
+	if !assert.Len(t, received.ResourceSpans, 1) || !assert.Len(t, received.ResourceSpans[0].ScopeSpans, 1) {
+		return
+	}
+	spans := received.ResourceSpans[0].ScopeSpans[0].Spans
+	if !assert.Len(t, spans, 2) {
+		return
+	}
+
+	assert.Equal(t, "parse-file", spans[0].Name)
+	assert.Equal(t, parent.TraceID(), spans[0].TraceID)
+	assert.Equal(t, spans[1].SpanID, spans[0].ParentSpanID)
+	assert.Equal(t, otlpStatus{Code: otlpStatusCodeError, Message: "malformed"}, spans[0].Status)
+	assert.Equal(t, []otlpAttribute{{Key: AttrFile, Value: map[string]any{"stringValue": "a.xml"}}}, spans[0].Attributes)
+
+	assert.Equal(t, "pull-run", spans[1].Name)
+	assert.Empty(t, spans[1].ParentSpanID)
+	assert.Equal(t, []otlpAttribute{{Key: AttrWorkflowID, Value: map[string]any{"intValue": "123"}}}, spans[1].Attributes)
+}
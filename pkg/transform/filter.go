@@ -0,0 +1,110 @@
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+
+	"github.com/isovalent/corgi/pkg/metrics"
+)
+
+// Filter drops documents of Type for which Expression is true.
+type Filter struct {
+	// Type is the type of documents to filter, such as test_case. Empty means all.
+	Type string `json:"type"`
+	// Expression is a boolean CEL expression evaluated with the document as doc, such
+	// as "doc.test_case_status == 'passed'", see newEnv.
+	Expression string `json:"expression"`
+
+	program cel.Program
+}
+
+// Filters drop the documents matching any of them.
+type Filters struct {
+	filters []Filter
+	logger  *slog.Logger
+}
+
+// LoadFilters reads and compiles the filters at the given path, such as:
+//
+//	[
+//	  {"type": "test_case", "expression": "doc.test_case_status == 'passed' && doc.workflow_name.glob('*-smoke')"},
+//	  {"type": "test_suite", "expression": "!has(doc.test_suite_total_tests)"}
+//	]
+//
+// Fields with zero values, such as suites without tests, are omitted from documents.
+func LoadFilters(path string, logger *slog.Logger) (*Filters, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read filters: %w", err)
+	}
+
+	filters := []Filter{}
+	if err := json.Unmarshal(data, &filters); err != nil {
+		return nil, fmt.Errorf("unable to parse filters %s: %w", path, err)
+	}
+
+	return NewFilters(filters, logger)
+}
+
+// NewFilters compiles the given filters.
+func NewFilters(filters []Filter, logger *slog.Logger) (*Filters, error) {
+	env, err := newEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range filters {
+		f := &filters[i]
+
+		ast, issues := env.Compile(f.Expression)
+		if err := issues.Err(); err != nil {
+			return nil, fmt.Errorf("unable to compile expression of filter %d: %w", i+1, err)
+		}
+		if t := ast.OutputType(); t != cel.BoolType && t != cel.DynType {
+			return nil, fmt.Errorf("expression of filter %d results in %s, expected bool", i+1, t)
+		}
+
+		f.program, err = env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create program of filter %d: %w", i+1, err)
+		}
+	}
+
+	return &Filters{filters: filters, logger: logger}, nil
+}
+
+// Apply implements opensearch.DocumentHook. Documents for which the expression of a filter
+// fails, such as since a field it uses isn't set, are kept.
+func (f *Filters) Apply(index string, docs [][]byte) ([][]byte, error) {
+	for i, d := range docs {
+		doc := map[string]any{}
+		if err := json.Unmarshal(d, &doc); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal document to filter it: %w", err)
+		}
+
+		for j, filter := range f.filters {
+			if filter.Type != "" && doc["type"] != filter.Type {
+				continue
+			}
+
+			out, _, err := filter.program.Eval(map[string]any{"doc": doc})
+			if err != nil {
+				f.logger.Debug("Unable to evaluate filter of document", "filter", j+1, "err", err)
+				continue
+			}
+
+			if out == types.True {
+				docs[i] = nil
+				metrics.Add(metrics.CounterDocumentsFiltered, 1)
+				break
+			}
+		}
+	}
+
+	return docs, nil
+}
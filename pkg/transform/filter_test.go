@@ -0,0 +1,52 @@
+package transform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilters(t *testing.T) {
+	f, err := NewFilters([]Filter{
+		{Type: "test_case", Expression: "doc.test_case_status == 'passed' && doc.workflow_name.glob('*-smoke')"},
+		{Type: "test_suite", Expression: "!has(doc.test_suite_total_tests)"},
+		{Expression: "doc.no_such_field == 'x'"},
+	}, logger)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	docs, err := f.Apply("index", [][]byte{
+		[]byte(`{"type":"test_case","test_case_status":"passed","workflow_name":"e2e-smoke"}`),
+		[]byte(`{"type":"test_case","test_case_status":"failed","workflow_name":"e2e-smoke"}`),
+		[]byte(`{"type":"test_case","test_case_status":"passed","workflow_name":"e2e"}`),
+		[]byte(`{"type":"test_suite","test_suite_name":"empty"}`),
+		[]byte(`{"type":"test_suite","test_suite_name":"e2e","test_suite_total_tests":3}`),
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, docs[0])
+	assert.NotNil(t, docs[1])
+	assert.NotNil(t, docs[2])
+	assert.Nil(t, docs[3])
+	assert.NotNil(t, docs[4])
+
+	_, err = NewFilters([]Filter{{Expression: "doc.("}}, logger)
+	assert.ErrorContains(t, err, "unable to compile")
+
+	_, err = NewFilters([]Filter{{Expression: "'passed'"}}, logger)
+	assert.ErrorContains(t, err, "expected bool")
+}
+
+func TestLoadFilters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filters.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`[{"type":"job_run","expression":"true"}]`), 0o600))
+
+	f, err := LoadFilters(path, logger)
+	assert.NoError(t, err)
+	docs, err := f.Apply("index", [][]byte{[]byte(`{"type":"test_case"}`), []byte(`{"type":"job_run"}`)})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"type":"test_case"}`, string(docs[0]))
+	assert.Nil(t, docs[1])
+}
@@ -0,0 +1,163 @@
+// Package transform derives fields of documents from CEL expressions, so that simple
+// derivations don't require changes to corgi.
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"reflect"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/common/types/ref"
+	"github.com/google/cel-go/ext"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Transform sets Field of documents of Type to the result of Expression.
+type Transform struct {
+	// Type is the type of documents to transform, such as test_case. Empty means all.
+	Type string `json:"type"`
+	// Field is the top-level field to set.
+	Field string `json:"field"`
+	// Expression is a CEL expression evaluated with the document as doc, such as
+	// "doc.test_suite_name.startsWith('gke-')", see newEnv.
+	Expression string `json:"expression"`
+
+	program cel.Program
+}
+
+// Transforms are applied to documents in order, so that transforms may use the fields
+// set by earlier ones.
+type Transforms struct {
+	transforms []Transform
+	logger     *slog.Logger
+}
+
+// LoadConfig reads and compiles the transforms at the given path, such as:
+//
+//	[
+//	  {"type": "test_suite", "field": "provider", "expression": "doc.test_suite_name.split('-')[0]"},
+//	  {"type": "test_case", "field": "is_upgrade_test", "expression": "doc.test_case_name.contains('upgrade')"}
+//	]
+func LoadConfig(path string, logger *slog.Logger) (*Transforms, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read transforms: %w", err)
+	}
+
+	transforms := []Transform{}
+	if err := json.Unmarshal(data, &transforms); err != nil {
+		return nil, fmt.Errorf("unable to parse transforms %s: %w", path, err)
+	}
+
+	return New(transforms, logger)
+}
+
+// newEnv returns the CEL environment expressions are compiled in, with the document as
+// doc, the string extensions of CEL, and glob to match strings against patterns in
+// path.Match syntax, such as doc.workflow_name.glob('*-smoke').
+func newEnv() (*cel.Env, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("doc", cel.MapType(cel.StringType, cel.DynType)),
+		ext.Strings(),
+		cel.Function("glob",
+			cel.MemberOverload("string_glob_string", []*cel.Type{cel.StringType, cel.StringType}, cel.BoolType,
+				cel.BinaryBinding(func(s, pattern ref.Val) ref.Val {
+					ok, err := path.Match(string(pattern.(types.String)), string(s.(types.String)))
+					if err != nil {
+						return types.WrapErr(err)
+					}
+					return types.Bool(ok)
+				}),
+			),
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create CEL environment: %w", err)
+	}
+
+	return env, nil
+}
+
+// New compiles the given transforms.
+func New(transforms []Transform, logger *slog.Logger) (*Transforms, error) {
+	env, err := newEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range transforms {
+		t := &transforms[i]
+		if t.Field == "" {
+			return nil, fmt.Errorf("field of transform %d is empty", i+1)
+		}
+
+		ast, issues := env.Compile(t.Expression)
+		if err := issues.Err(); err != nil {
+			return nil, fmt.Errorf("unable to compile expression of transform of %s: %w", t.Field, err)
+		}
+
+		t.program, err = env.Program(ast)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create program of transform of %s: %w", t.Field, err)
+		}
+	}
+
+	return &Transforms{transforms: transforms, logger: logger}, nil
+}
+
+// Apply implements opensearch.DocumentHook. Fields whose expression fails for a document,
+// such as since a field it uses isn't set, are left as they are. Expressions may check
+// whether fields are set with has(doc.<field>). Fields whose expression results in null
+// are removed.
+func (t *Transforms) Apply(index string, docs [][]byte) ([][]byte, error) {
+	for i, d := range docs {
+		doc := map[string]any{}
+		if err := json.Unmarshal(d, &doc); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal document to transform it: %w", err)
+		}
+
+		changed := false
+		for _, tr := range t.transforms {
+			if tr.Type != "" && doc["type"] != tr.Type {
+				continue
+			}
+
+			out, _, err := tr.program.Eval(map[string]any{"doc": doc})
+			if err != nil {
+				t.logger.Debug("Unable to evaluate transform of document", "field", tr.Field, "err", err)
+				continue
+			}
+
+			if out == types.NullValue {
+				delete(doc, tr.Field)
+				changed = true
+				continue
+			}
+
+			v, err := out.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+			if err != nil {
+				t.logger.Debug("Unable to convert result of transform of document", "field", tr.Field, "err", err)
+				continue
+			}
+			doc[tr.Field] = v.(*structpb.Value).AsInterface()
+			changed = true
+		}
+
+		if !changed {
+			continue
+		}
+
+		transformed, err := json.Marshal(doc)
+		if err != nil {
+			return nil, fmt.Errorf("unable to marshal transformed document: %w", err)
+		}
+		docs[i] = transformed
+	}
+
+	return docs, nil
+}
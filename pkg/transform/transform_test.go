@@ -0,0 +1,57 @@
+package transform
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{}))
+
+func TestTransforms(t *testing.T) {
+	tr, err := New([]Transform{
+		{Type: "test_suite", Field: "provider", Expression: "doc.test_suite_name.split('-')[0]"},
+		{Type: "test_case", Field: "is_upgrade_test", Expression: "doc.test_case_name.contains('upgrade')"},
+		{Type: "test_case", Field: "slow", Expression: "has(doc.test_case_duration) && doc.test_case_duration > 60"},
+		{Field: "labels", Expression: "has(doc.provider) ? [doc.provider, doc.type] : dyn(null)"},
+		{Field: "missing", Expression: "doc.no_such_field"},
+	}, logger)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	docs, err := tr.Apply("index", [][]byte{
+		[]byte(`{"type":"test_suite","test_suite_name":"gke-conformance"}`),
+		[]byte(`{"type":"test_case","test_case_name":"upgrade/downgrade","test_case_duration":120,"labels":["x"]}`),
+	})
+	assert.NoError(t, err)
+	assert.JSONEq(t,
+		`{"type":"test_suite","test_suite_name":"gke-conformance","provider":"gke","labels":["gke","test_suite"]}`,
+		string(docs[0]),
+	)
+	assert.JSONEq(t,
+		`{"type":"test_case","test_case_name":"upgrade/downgrade","test_case_duration":120,"is_upgrade_test":true,"slow":true}`,
+		string(docs[1]),
+	)
+
+	_, err = New([]Transform{{Field: "x", Expression: "doc.("}}, logger)
+	assert.ErrorContains(t, err, "unable to compile")
+
+	_, err = New([]Transform{{Expression: "true"}}, logger)
+	assert.ErrorContains(t, err, "is empty")
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transforms.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`[{"type":"test_case","field":"flaky","expression":"false"}]`), 0o600))
+
+	tr, err := LoadConfig(path, logger)
+	assert.NoError(t, err)
+	docs, err := tr.Apply("index", [][]byte{[]byte(`{"type":"test_case"}`), []byte(`{"type":"job_run"}`)})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"type":"test_case","flaky":false}`, string(docs[0]))
+	assert.Equal(t, `{"type":"job_run"}`, string(docs[1]))
+}
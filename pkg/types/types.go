@@ -2,6 +2,7 @@ package types
 
 import (
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/google/go-github/v60/github"
@@ -10,12 +11,26 @@ import (
 type TypeName string
 
 const (
-	TypeNameWorkflowRun TypeName = "workflow_run"
-	TypeNameJobRun      TypeName = "job_run"
-	TypeNameStepRun     TypeName = "step_run"
-	TypeNameTestcase    TypeName = "test_case"
-	TypeNameTestsuite   TypeName = "test_suite"
-	TypeNameFailureRate TypeName = "failure_rate"
+	TypeNameWorkflowRun         TypeName = "workflow_run"
+	TypeNameJobRun              TypeName = "job_run"
+	TypeNameStepRun             TypeName = "step_run"
+	TypeNameTestcase            TypeName = "test_case"
+	TypeNameTestsuite           TypeName = "test_suite"
+	TypeNameFailureRate         TypeName = "failure_rate"
+	TypeNameIngestError         TypeName = "ingest_error"
+	TypeNameArtifact            TypeName = "artifact"
+	TypeNameCacheUsage          TypeName = "cache_usage"
+	TypeNameTriage              TypeName = "triage"
+	TypeNameIngestStats         TypeName = "ingest_stats"
+	TypeNameAuditEvent          TypeName = "audit_event"
+	TypeNameTestDuration        TypeName = "test_duration"
+	TypeNameTeamFlakiness       TypeName = "team_flakiness"
+	TypeNameFailureCooccurrence TypeName = "failure_cooccurrence"
+	TypeNameTestCountDrop       TypeName = "test_count_drop"
+	TypeNameWorkflowStability   TypeName = "workflow_stability"
+	TypeNameFailurePattern      TypeName = "failure_pattern"
+	TypeNameSysdump             TypeName = "sysdump"
+	TypeNameLogSignature        TypeName = "log_signature"
 )
 
 type User struct {
@@ -58,6 +73,7 @@ func NewRepositoryFromRaw(repoRaw *github.Repository) *Repository {
 }
 
 type Commit struct {
+	SHA     string `json:"sha,omitempty"`
 	Message string `json:"message,omitempty"`
 	Author  User   `json:"author,omitempty"`
 	URL     string `json:"url,omitempty"`
@@ -83,6 +99,7 @@ type WorkflowRun struct {
 	ArtifactsURL           string            `json:"workflow_artifacts_url,omitempty"`
 	Link                   string            `json:"workflow_link,omitempty"`
 	Event                  string            `json:"event,omitempty"`
+	EventSummary           *EventSummary     `json:"event_summary,omitempty"`
 	Actor                  User              `json:"actor,omitempty"`
 	TriggeringActor        User              `json:"triggering_actor,omitempty"`
 	Repository             Repository        `json:"repository,omitempty"`
@@ -94,6 +111,149 @@ type WorkflowRun struct {
 	HeadCommit             Commit            `json:"head_commit,omitempty"`
 	WorkflowDispatchInputs map[string]string `json:"workflow_dispatch_inputs,omitempty"`
 	WorkflowDuration       time.Duration     `json:"workflow_duration,omitempty"`
+	// PullRequest is set for runs triggered by pull requests, if enabled.
+	PullRequest *PullRequest `json:"pull_request,omitempty"`
+	// PushCommits are the commits of the push which triggered the run, or if its event
+	// isn't available, those pushed since the previous push run of the workflow on the
+	// same branch, for runs triggered by pushes, if enabled. PushBaseSHA is the commit
+	// they were compared against.
+	// Failures which are new in the run are attributed to these commits.
+	PushBaseSHA string   `json:"push_base_sha,omitempty"`
+	PushCommits []Commit `json:"push_commits,omitempty"`
+	// TerminationReason is set if the run was terminated before it completed.
+	TerminationReason string `json:"workflow_termination_reason,omitempty"`
+	// Required is true if any job of the run is a required check of the target branch,
+	// if enabled.
+	Required bool `json:"workflow_required,omitempty"`
+	// ResultMismatch is set if the conclusion of the run disagrees with the results of its
+	// JUnit files, which indicates that the workflow doesn't propagate test results.
+	ResultMismatch string `json:"workflow_result_mismatch,omitempty"`
+	// ArtifactsTotalBytes is the combined size of all artifacts uploaded by the run.
+	ArtifactsTotalBytes int64 `json:"workflow_artifacts_total_bytes,omitempty"`
+	// ArtifactsExpireAt is the earliest expiry date of the run's unexpired artifacts.
+	ArtifactsExpireAt *time.Time `json:"workflow_artifacts_expire_at,omitempty"`
+}
+
+// Kinds of mismatches between the conclusion of a workflow run and its JUnit results.
+const (
+	// ResultMismatchFailedTests is used for runs which succeeded although tests failed.
+	ResultMismatchFailedTests = "failed-tests"
+	// ResultMismatchNoFailedTests is used for runs which failed although they ran tests
+	// and none of them failed.
+	ResultMismatchNoFailedTests = "no-failed-tests"
+)
+
+// Artifact describes an artifact uploaded by a workflow run.
+type Artifact struct {
+	*WorkflowRun
+	Type      TypeName  `json:"type,omitempty"`
+	ID        int64     `json:"artifact_id,omitempty"`
+	Name      string    `json:"artifact_name,omitempty"`
+	SizeBytes int64     `json:"artifact_size_bytes"`
+	Expired   bool      `json:"artifact_expired"`
+	CreatedAt time.Time `json:"artifact_created_at,omitempty"`
+	ExpiresAt time.Time `json:"artifact_expires_at,omitempty"`
+}
+
+func NewArtifactFromRaw(parent *WorkflowRun, artifactRaw *github.Artifact) Artifact {
+	return Artifact{
+		WorkflowRun: parent,
+		Type:        TypeNameArtifact,
+		ID:          artifactRaw.GetID(),
+		Name:        artifactRaw.GetName(),
+		SizeBytes:   artifactRaw.GetSizeInBytes(),
+		Expired:     artifactRaw.GetExpired(),
+		CreatedAt:   artifactRaw.GetCreatedAt().Time,
+		ExpiresAt:   artifactRaw.GetExpiresAt().Time,
+	}
+}
+
+// SetArtifactTotals records the combined size of the given artifacts on the run, along
+// with the earliest expiry date of the ones which have not expired yet.
+func (run *WorkflowRun) SetArtifactTotals(artifacts []Artifact) {
+	run.ArtifactsTotalBytes = 0
+	run.ArtifactsExpireAt = nil
+
+	for _, artifact := range artifacts {
+		run.ArtifactsTotalBytes += artifact.SizeBytes
+
+		if !artifact.Expired && !artifact.ExpiresAt.IsZero() &&
+			(run.ArtifactsExpireAt == nil || artifact.ExpiresAt.Before(*run.ArtifactsExpireAt)) {
+			run.ArtifactsExpireAt = &artifact.ExpiresAt
+		}
+	}
+}
+
+// SetResultMismatch records whether the conclusion of the run disagrees with the results
+// of the given test suites parsed from its JUnit files. Runs without tests and runs
+// which didn't complete are never a mismatch.
+func (run *WorkflowRun) SetResultMismatch(suites []Testsuite) {
+	run.ResultMismatch = ""
+
+	tests, failed := 0, 0
+	for _, suite := range suites {
+		tests += suite.TotalTests
+		failed += suite.TotalFailures + suite.TotalErrors
+	}
+	if tests == 0 {
+		return
+	}
+
+	switch {
+	case run.Conclusion == "success" && failed > 0:
+		run.ResultMismatch = ResultMismatchFailedTests
+	case run.Conclusion == "failure" && failed == 0:
+		run.ResultMismatch = ResultMismatchNoFailedTests
+	}
+}
+
+// Reasons for runs and jobs to be terminated before they completed.
+const (
+	// TerminationReasonCancelled is used for runs and jobs which were cancelled,
+	// for example manually, for any other reason.
+	TerminationReasonCancelled = "cancelled"
+	// TerminationReasonSuperseded is used for runs and jobs which were cancelled by a
+	// newer run in the same concurrency group, for example after a force-push.
+	TerminationReasonSuperseded = "superseded"
+	// TerminationReasonTimeout is used for runs and jobs which exceeded their timeout.
+	TerminationReasonTimeout = "timeout"
+	// TerminationReasonStale is used for runs and jobs which GitHub marked as stale.
+	TerminationReasonStale = "stale"
+)
+
+// Review states of a pull request.
+const (
+	ReviewStateApproved         = "approved"
+	ReviewStateChangesRequested = "changes_requested"
+	ReviewStatePending          = "pending"
+)
+
+// EventSummary is a compact summary of the event which triggered a workflow run, so that
+// runs can be analyzed by how they were triggered.
+type EventSummary struct {
+	// Type is the type of the event, such as push, pull_request or schedule.
+	Type string `json:"type,omitempty"`
+	// Sender is the user whose action triggered the event.
+	Sender string `json:"sender,omitempty"`
+	// Label is the label whose addition triggered a pull request run, if enabled.
+	Label string `json:"label,omitempty"`
+	// Schedule is the cron expression which triggered a scheduled run, if enabled.
+	Schedule string `json:"schedule,omitempty"`
+}
+
+// PullRequest holds the review state of a pull request as of when a workflow run started.
+type PullRequest struct {
+	Number int    `json:"number,omitempty"`
+	URL    string `json:"url,omitempty"`
+	Author string `json:"author,omitempty"`
+	// ReviewState is one of ReviewStateApproved, ReviewStateChangesRequested
+	// or ReviewStatePending.
+	ReviewState      string `json:"review_state,omitempty"`
+	Approvals        int    `json:"approvals"`
+	ChangesRequested int    `json:"changes_requested"`
+	// RequestedReviewers are the users and teams whose review is requested. These
+	// reflect the time of ingestion rather than when the run started.
+	RequestedReviewers []string `json:"requested_reviewers,omitempty"`
 }
 
 func NewWorkflowRunFromRaw(runRaw *github.WorkflowRun) *WorkflowRun {
@@ -143,6 +303,10 @@ func NewWorkflowRunFromRaw(runRaw *github.WorkflowRun) *WorkflowRun {
 	}
 
 	rawRunTriggeringActor := runRaw.GetTriggeringActor()
+	run.EventSummary = &EventSummary{
+		Type:   runRaw.GetEvent(),
+		Sender: rawRunTriggeringActor.GetLogin(),
+	}
 	run.TriggeringActor = User{
 		Login:   rawRunTriggeringActor.GetLogin(),
 		ID:      rawRunTriggeringActor.GetID(),
@@ -192,6 +356,24 @@ type JobRun struct {
 	ErrorLogs   []string      `json:"job_error_logs,omitempty"`
 	Link        string        `json:"job_link,omitempty"`
 	JobDuration time.Duration `json:"job_duration,omitempty"`
+	// CacheHits and CacheMisses count the caches restored and not found by
+	// actions/cache, if enabled.
+	CacheHits   int `json:"job_cache_hits,omitempty"`
+	CacheMisses int `json:"job_cache_misses,omitempty"`
+	// TerminationReason is set if the job was terminated before it completed.
+	TerminationReason string `json:"job_termination_reason,omitempty"`
+	// Required is true if the job is a required check of the target branch, if enabled.
+	Required bool `json:"job_required,omitempty"`
+	// Runner fields describe the runner and runner group which executed the job.
+	RunnerID        int64    `json:"job_runner_id,omitempty"`
+	RunnerName      string   `json:"job_runner_name,omitempty"`
+	RunnerGroupID   int64    `json:"job_runner_group_id,omitempty"`
+	RunnerGroupName string   `json:"job_runner_group_name,omitempty"`
+	Labels          []string `json:"job_labels,omitempty"`
+	// SelfHosted is true if the job requested a self-hosted runner.
+	SelfHosted bool `json:"job_self_hosted,omitempty"`
+	// QueueDuration is the time the job waited for a runner.
+	QueueDuration time.Duration `json:"job_queue_duration,omitempty"`
 }
 
 func NewJobRunFromRaw(parent *WorkflowRun, jobRaw *github.WorkflowJob) *JobRun {
@@ -210,6 +392,17 @@ func NewJobRunFromRaw(parent *WorkflowRun, jobRaw *github.WorkflowJob) *JobRun {
 		CompletedAt: jobRaw.GetCompletedAt().Time,
 		Name:        jobRaw.GetName(),
 		JobDuration: jobRaw.CompletedAt.Sub(jobRaw.StartedAt.Time),
+
+		RunnerID:        jobRaw.GetRunnerID(),
+		RunnerName:      jobRaw.GetRunnerName(),
+		RunnerGroupID:   jobRaw.GetRunnerGroupID(),
+		RunnerGroupName: jobRaw.GetRunnerGroupName(),
+		Labels:          jobRaw.Labels,
+		SelfHosted:      slices.Contains(jobRaw.Labels, "self-hosted"),
+	}
+
+	if !job.StartedAt.IsZero() && !job.CreatedAt.IsZero() {
+		job.QueueDuration = job.StartedAt.Sub(job.CreatedAt)
 	}
 	job.Link = fmt.Sprintf(
 		"https://github.com/%s/%s/actions/runs/%d/job/%d",
@@ -260,6 +453,24 @@ type Testsuite struct {
 	Duration      time.Duration `json:"test_suite_duration,omitempty"`
 	EndTime       time.Time     `json:"test_suite_end_time,omitempty"`
 	Owners        []string      `json:"test_suite_owners,omitempty"`
+	// JUnitPath is the path of the JUnit file within the artifact.
+	JUnitPath    string `json:"test_suite_junit_path,omitempty"`
+	ArtifactID   int64  `json:"test_suite_artifact_id,omitempty"`
+	ArtifactName string `json:"test_suite_artifact_name,omitempty"`
+	// ArtifactURL is a browsable link to download the artifact.
+	ArtifactURL string `json:"test_suite_artifact_url,omitempty"`
+	// SystemErrSummary is the first error line in the suite's system-err output,
+	// set for failed suites whose testcases have no failure details.
+	SystemErrSummary string `json:"test_suite_system_err_summary,omitempty"`
+	// NameRaw is the name of the suite before redaction, set if it was redacted.
+	NameRaw string `json:"test_suite_name_raw,omitempty"`
+	// FailureClass is either "infrastructure" or "product", set for failed suites.
+	FailureClass string `json:"test_suite_failure_class,omitempty"`
+	// FailureSignature is the name of the infrastructure signature the suite matched.
+	FailureSignature string `json:"test_suite_failure_signature,omitempty"`
+	// TimeSuspect is set if the end time or duration reported by the suite was
+	// implausible, such as due to runner clock skew, and was clamped.
+	TimeSuspect bool `json:"test_suite_time_suspect,omitempty"`
 }
 
 type Testcase struct {
@@ -269,6 +480,337 @@ type Testcase struct {
 	Duration time.Duration `json:"test_case_duration,omitempty"`
 	Status   string        `json:"test_case_status,omitempty"`
 	Owners   []string      `json:"test_case_owners,omitempty"`
+	// File is the source file of the testcase, from the file attribute of the JUnit
+	// testcase, if set.
+	File string `json:"test_case_file,omitempty"`
+	// Teams, SlackChannels and JiraComponents describe the teams behind the owners,
+	// according to the team mapping the testcase was ingested with.
+	Teams          []string `json:"test_case_teams,omitempty"`
+	SlackChannels  []string `json:"test_case_slack_channels,omitempty"`
+	JiraComponents []string `json:"test_case_jira_components,omitempty"`
+	// OwnerMembers are the owners with GitHub teams expanded into their members, if
+	// the testcase was ingested with team expansion enabled.
+	OwnerMembers []string `json:"test_case_owner_members,omitempty"`
+	// OwnerWeights splits the testcase between its owners, with the weights
+	// summing up to one, so that per-owner aggregations don't double-count.
+	OwnerWeights []OwnerWeight `json:"test_case_owner_weights,omitempty"`
+	// FailureMessage is the message attribute of the testcase's failure or error.
+	FailureMessage string `json:"test_case_failure_message,omitempty"`
+	// FailureText is the body of the testcase's failure or error. Bodies larger
+	// than the configured maximum are replaced with an excerpt of their head and tail.
+	FailureText string `json:"test_case_failure_text,omitempty"`
+	// FailureTextSize is the size of the full failure body in bytes.
+	FailureTextSize int `json:"test_case_failure_text_size,omitempty"`
+	// FailureTextHash is the SHA256 of the full failure body, set when FailureText
+	// is an excerpt.
+	FailureTextHash string `json:"test_case_failure_text_hash,omitempty"`
+	// FailureTextGzip is the gzip compressed and base64 encoded full failure body,
+	// set when FailureText is an excerpt and compression is enabled.
+	FailureTextGzip string `json:"test_case_failure_text_gzip,omitempty"`
+	// NameRaw is the name of the testcase before redaction, set if it was redacted.
+	NameRaw string `json:"test_case_name_raw,omitempty"`
+	// FailureTextRaw is FailureText before redaction, set if it was redacted.
+	FailureTextRaw string `json:"test_case_failure_text_raw,omitempty"`
+	// FailureClass is either "infrastructure" or "product", set for failed testcases.
+	FailureClass string `json:"test_case_failure_class,omitempty"`
+	// FailureSignature is the name of the infrastructure signature the testcase matched.
+	FailureSignature string `json:"test_case_failure_signature,omitempty"`
+	// FailureNormalized is the failure message and text with the parts which differ
+	// between occurrences of the same failure replaced, set when embeddings are enabled.
+	FailureNormalized string `json:"test_case_failure_normalized,omitempty"`
+	// FailureEmbedding is the embedding of FailureNormalized, for similarity search.
+	FailureEmbedding []float32 `json:"test_case_failure_embedding,omitempty"`
+	// TimeSuspect is set if the duration reported by the testcase was implausible
+	// and was clamped.
+	TimeSuspect bool `json:"test_case_time_suspect,omitempty"`
+}
+
+// IngestError records a JUnit file which was skipped because it couldn't be parsed,
+// to keep track of how much data is being lost.
+type IngestError struct {
+	*WorkflowRun
+	Type         TypeName `json:"type,omitempty"`
+	ArtifactID   int64    `json:"ingest_error_artifact_id,omitempty"`
+	ArtifactName string   `json:"ingest_error_artifact_name,omitempty"`
+	JUnitPath    string   `json:"ingest_error_junit_path,omitempty"`
+	Reason       string   `json:"ingest_error_reason,omitempty"`
+	Message      string   `json:"ingest_error_message,omitempty"`
+}
+
+// Sysdump describes a sysdump found in an artifact of a workflow run. Only its metadata
+// is indexed, not its contents.
+type Sysdump struct {
+	*WorkflowRun
+	Type         TypeName `json:"type,omitempty"`
+	ArtifactID   int64    `json:"sysdump_artifact_id,omitempty"`
+	ArtifactName string   `json:"sysdump_artifact_name,omitempty"`
+	// Path is the path of the sysdump archive or directory within the artifact.
+	Path string `json:"sysdump_path,omitempty"`
+	// Files and Bytes are the number and uncompressed size of the files in the sysdump.
+	Files int   `json:"sysdump_files"`
+	Bytes int64 `json:"sysdump_bytes"`
+}
+
+// LogSignature is a failure signature matched in a log file found in an artifact of a
+// workflow run.
+type LogSignature struct {
+	*WorkflowRun
+	Type         TypeName `json:"type,omitempty"`
+	ArtifactID   int64    `json:"log_signature_artifact_id,omitempty"`
+	ArtifactName string   `json:"log_signature_artifact_name,omitempty"`
+	// Path is the path of the log file within the artifact.
+	Path string `json:"log_signature_path,omitempty"`
+	// Signature is the name of the failure signature which matched.
+	Signature string `json:"log_signature_name,omitempty"`
+	// Matches is the number of lines of the log file which matched.
+	Matches int `json:"log_signature_matches"`
+	// FirstLine is the first line which matched, with secrets redacted, and
+	// FirstLineNumber its number, starting at one.
+	FirstLine       string `json:"log_signature_first_line,omitempty"`
+	FirstLineNumber int    `json:"log_signature_first_line_number,omitempty"`
+}
+
+// OwnerWeight is the share of a testcase attributed to one of its owners.
+type OwnerWeight struct {
+	Owner  string  `json:"owner,omitempty"`
+	Weight float64 `json:"weight"`
+}
+
+// CacheUsage is a snapshot of the GitHub Actions cache usage of a repository.
+type CacheUsage struct {
+	Type                    TypeName   `json:"type,omitempty"`
+	Repository              Repository `json:"repository,omitempty"`
+	ActiveCachesSizeInBytes int64      `json:"cache_usage_active_size_bytes"`
+	ActiveCachesCount       int        `json:"cache_usage_active_count"`
+	Timestamp               time.Time  `json:"cache_usage_timestamp,omitempty"`
+}
+
+const (
+	TriageStateOpen    = "open"
+	TriageStateKnown   = "known"
+	TriageStateIgnored = "ignored"
+)
+
+// Triage records how a failing test was triaged. The latest triage of a test wins.
+type Triage struct {
+	Type       TypeName `json:"type,omitempty"`
+	Repository string   `json:"triage_repository,omitempty"`
+	TestName   string   `json:"triage_test_name,omitempty"`
+	// State is one of TriageStateOpen, TriageStateKnown or TriageStateIgnored.
+	State     string    `json:"triage_state,omitempty"`
+	Note      string    `json:"triage_note,omitempty"`
+	User      string    `json:"triage_user,omitempty"`
+	Timestamp time.Time `json:"triage_timestamp,omitempty"`
+}
+
+// IngestStats are the operational stats of a single invocation of corgi which ingested
+// workflow runs. Counts don't have the `omitempty` specifier, so that an invocation
+// which ingested nothing is recorded as such.
+type IngestStats struct {
+	Type       TypeName      `json:"type,omitempty"`
+	Command    string        `json:"ingest_stats_command,omitempty"`
+	Host       string        `json:"ingest_stats_host,omitempty"`
+	StartedAt  time.Time     `json:"ingest_stats_started_at,omitempty"`
+	FinishedAt time.Time     `json:"ingest_stats_finished_at,omitempty"`
+	Duration   time.Duration `json:"ingest_stats_duration"`
+
+	RunsIngested      int64 `json:"ingest_stats_runs_ingested"`
+	RunsAborted       int64 `json:"ingest_stats_runs_aborted"`
+	DocumentsWritten  int64 `json:"ingest_stats_documents_written"`
+	DocumentsRejected int64 `json:"ingest_stats_documents_rejected"`
+	IngestErrors      int64 `json:"ingest_stats_ingest_errors"`
+
+	// Stages maps the stages of the pipeline to the operations and time spent in them.
+	Stages map[string]IngestStage `json:"ingest_stats_stages,omitempty"`
+
+	// GitHubRequests is the number of requests made to the GitHub API. The rate limit
+	// fields are as of the last response, and are omitted if there was none.
+	GitHubRequests           int64 `json:"ingest_stats_github_requests"`
+	GitHubRateLimit          int64 `json:"ingest_stats_github_rate_limit,omitempty"`
+	GitHubRateLimitRemaining int64 `json:"ingest_stats_github_rate_limit_remaining,omitempty"`
+	GitHubRateLimitUsed      int64 `json:"ingest_stats_github_rate_limit_used,omitempty"`
+}
+
+// IngestStage is the number of operations and time spent in a stage of the pipeline.
+type IngestStage struct {
+	Operations int64         `json:"operations"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// AuditEvent records an invocation of corgi which changed documents: who or what
+// triggered it, with which configuration, which workflow runs it touched and how many
+// documents it wrote and deleted.
+type AuditEvent struct {
+	Type    TypeName `json:"type,omitempty"`
+	Command string   `json:"audit_event_command,omitempty"`
+	// Actor is the user who ran the command, or who triggered the workflow it ran in.
+	Actor string `json:"audit_event_actor,omitempty"`
+	// Trigger describes how the command was run, such as from a GitHub workflow run.
+	Trigger string `json:"audit_event_trigger,omitempty"`
+	Host    string `json:"audit_event_host,omitempty"`
+	// Flags are the names of the flags which were given. ConfigHash is a hash of the
+	// values of every flag and of the arguments, so that invocations with the same
+	// configuration can be grouped without recording credentials.
+	Flags      []string  `json:"audit_event_flags,omitempty"`
+	ConfigHash string    `json:"audit_event_config_hash,omitempty"`
+	StartedAt  time.Time `json:"audit_event_started_at,omitempty"`
+	FinishedAt time.Time `json:"audit_event_finished_at,omitempty"`
+
+	WorkflowIDs      []int64 `json:"audit_event_workflow_ids,omitempty"`
+	DocumentsWritten int64   `json:"audit_event_documents_written"`
+	DocumentsDeleted int64   `json:"audit_event_documents_deleted"`
+}
+
+// TestDuration holds the percentiles of the duration of a test in a workflow over a
+// time window, computed from its passed testcases. Percentiles use the nearest-rank
+// method, so that they are durations the test actually took.
+type TestDuration struct {
+	Type       TypeName `json:"type,omitempty"`
+	Repository string   `json:"test_duration_repository,omitempty"`
+	HeadBranch string   `json:"test_duration_head_branch,omitempty"`
+	Workflow   string   `json:"test_duration_workflow,omitempty"`
+	TestName   string   `json:"test_duration_test_name,omitempty"`
+	// Owners are the owners of the most recent testcase.
+	Owners []string  `json:"test_duration_owners,omitempty"`
+	Since  time.Time `json:"test_duration_since,omitempty"`
+	Until  time.Time `json:"test_duration_until,omitempty"`
+
+	Samples int           `json:"test_duration_samples"`
+	P50     time.Duration `json:"test_duration_p50"`
+	P90     time.Duration `json:"test_duration_p90"`
+	P99     time.Duration `json:"test_duration_p99"`
+	Max     time.Duration `json:"test_duration_max"`
+}
+
+// TeamFlakiness is the flake debt of an owning team over a time window, along with that
+// of the window before it to show the trend. Tests with several owners are split between
+// them, so the figures are fractional. Figures don't have the `omitempty` specifier, so
+// that teams without flakes are recorded as such.
+type TeamFlakiness struct {
+	Type       TypeName  `json:"type,omitempty"`
+	Owner      string    `json:"team_flakiness_owner,omitempty"`
+	Repository string    `json:"team_flakiness_repository,omitempty"`
+	Since      time.Time `json:"team_flakiness_since,omitempty"`
+	Until      time.Time `json:"team_flakiness_until,omitempty"`
+
+	// TestsOwned is the number of distinct tests owned, and Testcases the number of
+	// their runs.
+	TestsOwned float64 `json:"team_flakiness_tests_owned"`
+	Testcases  float64 `json:"team_flakiness_testcases"`
+	Failures   float64 `json:"team_flakiness_failures"`
+	// FlakyTests is the number of tests which both failed and passed, and FlakeScore
+	// the number of their failures.
+	FlakyTests float64 `json:"team_flakiness_flaky_tests"`
+	FlakeScore float64 `json:"team_flakiness_flake_score"`
+	// Retries is the number of workflow run attempts retried after tests failed.
+	Retries float64 `json:"team_flakiness_retries"`
+
+	PreviousFlakyTests float64 `json:"team_flakiness_previous_flaky_tests"`
+	PreviousFlakeScore float64 `json:"team_flakiness_previous_flake_score"`
+	PreviousRetries    float64 `json:"team_flakiness_previous_retries"`
+	// FlakeScoreChange is FlakeScore minus PreviousFlakeScore, positive if the team's
+	// tests got flakier.
+	FlakeScoreChange float64 `json:"team_flakiness_flake_score_change"`
+}
+
+// FailureCooccurrence records how often two tests of a workflow failed in the same
+// workflow run attempt within a time window. TestA is ordered before TestB. A lift well
+// above one means the tests fail together more often than if their failures were
+// independent, hinting at shared infrastructure or an ordering dependency.
+type FailureCooccurrence struct {
+	Type       TypeName  `json:"type,omitempty"`
+	Repository string    `json:"failure_cooccurrence_repository,omitempty"`
+	HeadBranch string    `json:"failure_cooccurrence_head_branch,omitempty"`
+	Workflow   string    `json:"failure_cooccurrence_workflow,omitempty"`
+	TestA      string    `json:"failure_cooccurrence_test_a,omitempty"`
+	TestB      string    `json:"failure_cooccurrence_test_b,omitempty"`
+	Since      time.Time `json:"failure_cooccurrence_since,omitempty"`
+	Until      time.Time `json:"failure_cooccurrence_until,omitempty"`
+
+	// Runs is the number of run attempts of the workflow, FailuresA and FailuresB the
+	// number of them each test failed in, and Together the number both failed in.
+	Runs      int `json:"failure_cooccurrence_runs"`
+	FailuresA int `json:"failure_cooccurrence_failures_a"`
+	FailuresB int `json:"failure_cooccurrence_failures_b"`
+	Together  int `json:"failure_cooccurrence_together"`
+	// ConfidenceAB is the share of failures of TestA in which TestB failed too, and
+	// ConfidenceBA the other way around.
+	ConfidenceAB float64 `json:"failure_cooccurrence_confidence_ab"`
+	ConfidenceBA float64 `json:"failure_cooccurrence_confidence_ba"`
+	// Lift is the share of runs both tests failed in, divided by the share expected if
+	// their failures were independent.
+	Lift float64 `json:"failure_cooccurrence_lift"`
+}
+
+// TestCountDrop records a workflow run attempt which reported far fewer tests than the
+// workflow or one of its suites usually does, since tests which silently stopped running
+// otherwise look like tests which all pass.
+type TestCountDrop struct {
+	*WorkflowRun
+	Type TypeName `json:"type,omitempty"`
+	// Suite is the name of the test suite, or empty if the tests of the whole workflow
+	// run attempt dropped.
+	Suite string `json:"test_count_drop_suite,omitempty"`
+	// Expected is the median number of tests in the baseline, out of BaselineRuns run
+	// attempts, and Actual the number of tests reported by this attempt.
+	Expected     int `json:"test_count_drop_expected"`
+	Actual       int `json:"test_count_drop_actual"`
+	BaselineRuns int `json:"test_count_drop_baseline_runs"`
+	// Drop is the share of the expected tests which are missing.
+	Drop float64 `json:"test_count_drop_drop"`
+}
+
+// WorkflowStability is the stability of a workflow over a time window, combined into a
+// single score between 0 and 100 which can be trended. Figures don't have the
+// `omitempty` specifier, so that perfectly stable workflows are recorded as such.
+type WorkflowStability struct {
+	Type       TypeName  `json:"type,omitempty"`
+	Repository string    `json:"workflow_stability_repository,omitempty"`
+	HeadBranch string    `json:"workflow_stability_head_branch,omitempty"`
+	Workflow   string    `json:"workflow_stability_workflow,omitempty"`
+	Since      time.Time `json:"workflow_stability_since,omitempty"`
+	Until      time.Time `json:"workflow_stability_until,omitempty"`
+
+	// Runs is the number of completed run attempts.
+	Runs int `json:"workflow_stability_runs"`
+	// PassRate is the share of run attempts which succeeded.
+	PassRate float64 `json:"workflow_stability_pass_rate"`
+	// FlakeRate is the share of the tests which ran that both failed and passed.
+	FlakeRate float64 `json:"workflow_stability_flake_rate"`
+	// InfraFailureRate is the share of run attempts with a failure caused by the CI
+	// environment rather than the code under test.
+	InfraFailureRate float64 `json:"workflow_stability_infra_failure_rate"`
+	// DurationMean and DurationStdDev are those of the successful run attempts, and
+	// DurationCV the ratio between them.
+	DurationMean   time.Duration `json:"workflow_stability_duration_mean"`
+	DurationStdDev time.Duration `json:"workflow_stability_duration_stddev"`
+	DurationCV     float64       `json:"workflow_stability_duration_cv"`
+	// Score combines the above into a number between 0 and 100, higher being more stable.
+	Score float64 `json:"workflow_stability_score"`
+}
+
+// FailurePattern is the failure rate of the jobs which ran within a segment of a time
+// window, such as an hour of the day, compared to the failure rate of all of them, to
+// surface failures driven by the environment rather than the code. Figures don't have
+// the `omitempty` specifier, so that segments without failures are recorded as such.
+type FailurePattern struct {
+	Type       TypeName  `json:"type,omitempty"`
+	Repository string    `json:"failure_pattern_repository,omitempty"`
+	HeadBranch string    `json:"failure_pattern_head_branch,omitempty"`
+	Since      time.Time `json:"failure_pattern_since,omitempty"`
+	Until      time.Time `json:"failure_pattern_until,omitempty"`
+	// Dimension is what the jobs are segmented by, one of "hour", "weekday" or "region",
+	// and Segment the value of this segment, such as "14", "Monday" or a runner group.
+	Dimension string `json:"failure_pattern_dimension,omitempty"`
+	Segment   string `json:"failure_pattern_segment,omitempty"`
+
+	// Jobs is the number of completed jobs within the segment, and Failures those which failed.
+	Jobs        int     `json:"failure_pattern_jobs"`
+	Failures    int     `json:"failure_pattern_failures"`
+	FailureRate float64 `json:"failure_pattern_failure_rate"`
+	// Lift is the failure rate of the segment divided by that of all jobs, so that
+	// segments failing more often than usual have a lift above one.
+	Lift float64 `json:"failure_pattern_lift"`
 }
 
 // FailureRate holds information regarding the rate of failure for a particular
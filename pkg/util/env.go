@@ -0,0 +1,106 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// EnvPrefix is the prefix of the environment variables flags can be set with.
+const EnvPrefix = "CORGI_"
+
+// FlagEnvName returns the name of the environment variable the flag with the given name
+// can be set with, such as CORGI_SEND_BULK for --send-bulk.
+func FlagEnvName(flag string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// SetFlagsFromEnv sets the flags which weren't given on the command line from their
+// environment variables, so that flags take precedence over the environment, which in
+// turn takes precedence over the defaults. Values are parsed like on the command line,
+// with slices given as comma-separated lists.
+func SetFlagsFromEnv(flags *pflag.FlagSet) error {
+	var err error
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed || flag.Name == "help" {
+			return
+		}
+
+		value, ok := os.LookupEnv(FlagEnvName(flag.Name))
+		if !ok {
+			return
+		}
+
+		if setErr := flags.Set(flag.Name, value); setErr != nil {
+			err = fmt.Errorf("invalid value of %s for --%s: %w", FlagEnvName(flag.Name), flag.Name, setErr)
+		}
+	})
+
+	return err
+}
+
+// SetFlagsFromConfig sets the flags which weren't given on the command line or through
+// the environment from the given JSON config file, an object mapping flag names to their
+// values, such as {"send-bulk": true, "events": ["push", "schedule"]}. Called after
+// SetFlagsFromEnv, flags take precedence over the environment, which in turn takes
+// precedence over the config file. Names which aren't flags are ignored, so that one
+// file can configure multiple commands.
+func SetFlagsFromConfig(flags *pflag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read config: %w", err)
+	}
+
+	// Numbers are kept as given, so that large integers aren't formatted as floats.
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	config := map[string]any{}
+	if err := decoder.Decode(&config); err != nil {
+		return fmt.Errorf("unable to parse config %s: %w", path, err)
+	}
+
+	for name, value := range config {
+		flag := flags.Lookup(name)
+		if flag == nil || flag.Changed {
+			continue
+		}
+
+		switch v := value.(type) {
+		case []any:
+			slice, ok := flag.Value.(pflag.SliceValue)
+			if !ok {
+				return fmt.Errorf("invalid value in config for --%s: expected %s, got a list", name, flag.Value.Type())
+			}
+			values := make([]string, 0, len(v))
+			for _, item := range v {
+				values = append(values, fmt.Sprint(item))
+			}
+			if err := slice.Replace(values); err != nil {
+				return fmt.Errorf("invalid value in config for --%s: %w", name, err)
+			}
+			flag.Changed = true
+		default:
+			if err := flags.Set(name, fmt.Sprint(v)); err != nil {
+				return fmt.Errorf("invalid value in config for --%s: %w", name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// DocumentFlagEnv appends the environment variable each flag can be set with to its usage.
+func DocumentFlagEnv(flags *pflag.FlagSet) {
+	flags.VisitAll(func(flag *pflag.Flag) {
+		suffix := " [$" + FlagEnvName(flag.Name) + "]"
+		if flag.Name == "help" || strings.HasSuffix(flag.Usage, suffix) {
+			return
+		}
+
+		flag.Usage += suffix
+	})
+}
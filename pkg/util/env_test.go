@@ -0,0 +1,62 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlagPrecedence(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	fromFlag := flags.String("from-flag", "default", "")
+	fromEnv := flags.String("from-env", "default", "")
+	fromConfig := flags.String("from-config", "default", "")
+	fromDefault := flags.String("from-default", "default", "")
+	events := flags.StringSlice("events", []string{"push"}, "")
+	sendBulk := flags.Bool("send-bulk", false, "")
+	maxBytes := flags.Int64("max-bytes", 0, "")
+
+	assert.NoError(t, flags.Parse([]string{"--from-flag", "flag"}))
+
+	t.Setenv("CORGI_FROM_FLAG", "env")
+	t.Setenv("CORGI_FROM_ENV", "env")
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{
+		"from-flag": "config", "from-env": "config", "from-config": "config",
+		"events": ["push", "schedule"], "send-bulk": true, "max-bytes": 10000000, "not-a-flag": 1
+	}`), 0o600))
+
+	assert.NoError(t, SetFlagsFromEnv(flags))
+	assert.NoError(t, SetFlagsFromConfig(flags, path))
+
+	assert.Equal(t, "flag", *fromFlag)
+	assert.Equal(t, "env", *fromEnv)
+	assert.Equal(t, "config", *fromConfig)
+	assert.Equal(t, "default", *fromDefault)
+	assert.Equal(t, []string{"push", "schedule"}, *events)
+	assert.True(t, *sendBulk)
+	assert.Equal(t, int64(10000000), *maxBytes)
+}
+
+func TestSetFlagsFromConfigInvalid(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.Int("workers", 1, "")
+	flags.String("index", "", "")
+
+	dir := t.TempDir()
+	for config, expected := range map[string]string{
+		`{"workers": "many"}`: "invalid value in config for --workers",
+		`{"index": ["a"]}`:    "got a list",
+		`["workers"]`:         "unable to parse config",
+	} {
+		path := filepath.Join(dir, "config.json")
+		assert.NoError(t, os.WriteFile(path, []byte(config), 0o600))
+		assert.ErrorContains(t, SetFlagsFromConfig(flags, path), expected, config)
+	}
+
+	assert.Error(t, SetFlagsFromConfig(flags, filepath.Join(dir, "missing.json")))
+}
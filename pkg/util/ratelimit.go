@@ -0,0 +1,48 @@
+package util
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenBucket is a token bucket rate limiter. Tokens are added at a fixed rate,
+// up to a maximum burst size.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a new TokenBucket which allows rate tokens per second,
+// starting out full with burst tokens.
+func NewTokenBucket(rate, burst float64) *TokenBucket {
+	return &TokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// Take removes n tokens from the bucket, blocking until they are available.
+// Requests for more tokens than the burst size are let through, with the
+// bucket going into debt that following callers will need to wait out.
+func (b *TokenBucket) Take(n float64) {
+	b.mu.Lock()
+
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens -= n
+
+	var wait time.Duration
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+
+	b.mu.Unlock()
+
+	time.Sleep(wait)
+}
@@ -0,0 +1,117 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Secret is a credential read from an environment variable, or from the file named
+// by the same variable with a _FILE suffix, such as a mounted Kubernetes secret, or
+// from a registered SecretSource. Secrets can be reloaded, so rotated credentials are
+// picked up without a restart.
+type Secret struct {
+	name string
+
+	mu    sync.RWMutex
+	value string
+}
+
+// SecretSource fetches the secret with the given reference from a secrets manager,
+// such as the path of a secret in HashiCorp Vault.
+type SecretSource func(ref string) (string, error)
+
+var (
+	secretsMu sync.Mutex
+	secrets   = map[string]*Secret{}
+
+	secretSourcesMu sync.RWMutex
+	secretSources   = map[string]SecretSource{}
+)
+
+// RegisterSecretSource registers a source of secrets. A secret is fetched from it if the
+// environment variable named after the secret with the given suffix is set, such as
+// GITHUB_TOKEN_VAULT, whose value is passed to the source as reference.
+func RegisterSecretSource(suffix string, source SecretSource) {
+	secretSourcesMu.Lock()
+	defer secretSourcesMu.Unlock()
+
+	secretSources[suffix] = source
+}
+
+// NewSecret loads the secret with the given environment variable name, registering
+// it to be reloaded by ReloadSecrets. Secrets are registered once per name, so clients
+// created on every periodic cycle reload and share the same secret.
+func NewSecret(name string) (*Secret, error) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	s, ok := secrets[name]
+	if !ok {
+		s = &Secret{name: name}
+	}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	secrets[name] = s
+
+	return s, nil
+}
+
+// Value returns the current value of the secret.
+func (s *Secret) Value() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.value
+}
+
+// Reload reads the secret again. If reading it fails, the previous value is kept.
+func (s *Secret) Reload() error {
+	value := os.Getenv(s.name)
+
+	if path := os.Getenv(s.name + "_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("unable to read %s from %s: %w", s.name, path, err)
+		}
+		value = strings.TrimSpace(string(data))
+	}
+
+	secretSourcesMu.RLock()
+	defer secretSourcesMu.RUnlock()
+
+	for suffix, source := range secretSources {
+		ref := os.Getenv(s.name + suffix)
+		if ref == "" {
+			continue
+		}
+
+		fetched, err := source(ref)
+		if err != nil {
+			return fmt.Errorf("unable to fetch %s from %s: %w", s.name, ref, err)
+		}
+		value = fetched
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.value = value
+
+	return nil
+}
+
+// ReloadSecrets reloads every secret created through NewSecret.
+func ReloadSecrets() error {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	errs := []error{}
+	for _, s := range secrets {
+		errs = append(errs, s.Reload())
+	}
+
+	return errors.Join(errs...)
+}
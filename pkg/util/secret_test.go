@@ -0,0 +1,25 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSecretRegistersOnce(t *testing.T) {
+	t.Setenv("CORGI_TEST_SECRET", "old")
+
+	first, err := NewSecret("CORGI_TEST_SECRET")
+	assert.NoError(t, err)
+	t.Setenv("CORGI_TEST_SECRET", "new")
+	second, err := NewSecret("CORGI_TEST_SECRET")
+	assert.NoError(t, err)
+
+	assert.Same(t, first, second)
+	assert.Equal(t, "new", first.Value())
+
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	assert.Contains(t, secrets, "CORGI_TEST_SECRET")
+	assert.Len(t, secrets, 1)
+}
@@ -0,0 +1,95 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	TimeoutStageRequest   = "request"
+	TimeoutStageDownload  = "download"
+	TimeoutStageParse     = "parse"
+	TimeoutStageBulkWrite = "bulk-write"
+	TimeoutStageExecHook  = "exec-hook"
+)
+
+// DefaultTimeouts are the timeouts used for stages which aren't configured explicitly.
+var DefaultTimeouts = Timeouts{
+	Request:   2 * time.Minute,
+	Download:  10 * time.Minute,
+	Parse:     5 * time.Minute,
+	BulkWrite: 2 * time.Minute,
+	ExecHook:  time.Minute,
+}
+
+// Timeouts bound how long a single operation of each stage of the ingest pipeline may
+// take, so that a hung HTTP call fails that operation instead of wedging the whole
+// ingest. Zero means no timeout.
+type Timeouts struct {
+	// Request bounds each attempt of a GitHub API request, and each lookup in OpenSearch.
+	// Waiting for rate limits to reset between attempts doesn't count towards it.
+	Request time.Duration
+	// Download bounds downloading a single artifact.
+	Download time.Duration
+	// Parse bounds parsing the JUnit files of a single artifact.
+	Parse time.Duration
+	// BulkWrite bounds each bulk request sent to OpenSearch.
+	BulkWrite time.Duration
+	// ExecHook bounds each run of the exec hook enriching documents.
+	ExecHook time.Duration
+}
+
+// TimeoutStages returns the names of the stages accepted by ParseTimeouts.
+func TimeoutStages() []string {
+	return []string{TimeoutStageRequest, TimeoutStageDownload, TimeoutStageParse, TimeoutStageBulkWrite, TimeoutStageExecHook}
+}
+
+// ParseTimeouts parses timeouts given as stage names mapped to durations, such as
+// "download" to "10m". Stages which aren't given keep their default timeout.
+func ParseTimeouts(stages map[string]string) (Timeouts, error) {
+	t := DefaultTimeouts
+
+	for stage, value := range stages {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return Timeouts{}, fmt.Errorf("invalid timeout for stage %s: %w", stage, err)
+		}
+		if d < 0 {
+			return Timeouts{}, fmt.Errorf("invalid timeout for stage %s: must not be negative", stage)
+		}
+
+		switch stage {
+		case TimeoutStageRequest:
+			t.Request = d
+		case TimeoutStageDownload:
+			t.Download = d
+		case TimeoutStageParse:
+			t.Parse = d
+		case TimeoutStageBulkWrite:
+			t.BulkWrite = d
+		case TimeoutStageExecHook:
+			t.ExecHook = d
+		default:
+			stages := TimeoutStages()
+			slices.Sort(stages)
+			return Timeouts{}, fmt.Errorf(
+				"unknown timeout stage %s, valid stages are: %s", stage, strings.Join(stages, ", "),
+			)
+		}
+	}
+
+	return t, nil
+}
+
+// WithTimeout is like context.WithTimeout, except that a zero timeout leaves the
+// context without a deadline.
+func WithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
@@ -2,7 +2,9 @@ package util
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 )
 
 func Contains(options []string, target string) bool {
@@ -38,3 +40,19 @@ func traverseUnstructured(path, breadcrumbs string, unstructured map[string]any)
 func TraverseUnstructured(path string, unstructured map[string]any) (any, error) {
 	return traverseUnstructured(path, "", unstructured)
 }
+
+// ParseDuration parses a duration like time.ParseDuration, additionally accepting
+// durations in days and weeks, such as "7d" or "2w".
+func ParseDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			count, err := strconv.ParseFloat(n, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration '%s': %w", s, err)
+			}
+			return time.Duration(count * float64(unit)), nil
+		}
+	}
+
+	return time.ParseDuration(s)
+}